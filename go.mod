@@ -5,6 +5,7 @@ go 1.26
 require (
 	github.com/ethanrous/agno/bindings/go/agno v0.0.15
 	github.com/ethanrous/weblens/api v0.0.0
+	github.com/fsnotify/fsnotify v1.7.0
 	github.com/go-chi/chi/v5 v5.2.0
 	github.com/golang-jwt/jwt/v5 v5.2.1
 	github.com/google/uuid v1.6.0
@@ -21,6 +22,7 @@ require (
 	github.com/viccon/sturdyc v1.1.5
 	go.mongodb.org/mongo-driver v1.17.2
 	golang.org/x/crypto v0.32.0
+	golang.org/x/net v0.25.0
 	golang.org/x/sync v0.10.0
 )
 
@@ -0,0 +1,47 @@
+// Package album provides albums, ordered collections of media that are independent of the folders the media live in.
+package album
+
+import (
+	"context"
+
+	"github.com/ethanrous/weblens/models/db"
+	"github.com/ethanrous/weblens/modules/config"
+	"github.com/ethanrous/weblens/modules/startup"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const ownerIndexKey = "owner_index"
+const mediasIndexKey = "medias_index"
+
+// IndexModels defines MongoDB indexes for the albums collection.
+var IndexModels = []mongo.IndexModel{
+	{
+		Keys:    bson.D{{Key: "owner", Value: 1}},
+		Options: options.Index().SetName(ownerIndexKey),
+	},
+	{
+		Keys:    bson.D{{Key: "medias", Value: 1}},
+		Options: options.Index().SetName(mediasIndexKey),
+	},
+}
+
+func init() {
+	startup.RegisterHook(registerAlbumIndexes)
+}
+
+func registerAlbumIndexes(ctx context.Context, _ config.Provider) error {
+	col, err := db.GetCollection[any](ctx, AlbumCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	for _, idx := range IndexModels {
+		if err := col.NewIndex(idx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
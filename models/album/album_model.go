@@ -0,0 +1,280 @@
+package album
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/ethanrous/weblens/models/db"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AlbumCollectionKey is the MongoDB collection name for albums.
+const AlbumCollectionKey = "albums"
+
+// ErrInvalidMediaOrder is returned when a new media order is not a reordering of the media already in the album.
+var ErrInvalidMediaOrder = wlerrors.Statusf(http.StatusBadRequest, "media order must contain exactly the media in the album")
+
+// ErrCoverNotInAlbum is returned when the cover of an album is set to media that is not in the album.
+var ErrCoverNotInAlbum = wlerrors.Statusf(http.StatusBadRequest, "album cover must be in the album")
+
+// Album is an ordered collection of media. An album only references media by content ID, so the files behind the
+// media can live in any folder, and no bytes are copied when media is added.
+type Album struct {
+	AlbumID     primitive.ObjectID `bson:"_id"`
+	Title       string             `bson:"title"`
+	Description string             `bson:"description"`
+	Owner       string             `bson:"owner"`
+	// Cover is the content ID of the media shown for the album, or empty to use the first media in the album
+	Cover string `bson:"cover"`
+	// Medias are the content IDs of the media in the album, in the order they are shown
+	Medias  []string  `bson:"medias"`
+	Created time.Time `bson:"created"`
+	Updated time.Time `bson:"updated"`
+}
+
+// CreateAlbum creates a new, empty, album with the given title, description, and owner.
+func CreateAlbum(ctx context.Context, title, description, owner string) (*Album, error) {
+	col, err := db.GetCollection[any](ctx, AlbumCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	album := &Album{
+		AlbumID:     primitive.NewObjectID(),
+		Title:       title,
+		Description: description,
+		Owner:       owner,
+		Medias:      []string{},
+		Created:     now,
+		Updated:     now,
+	}
+
+	_, err = col.InsertOne(ctx, album)
+	if err != nil {
+		return nil, db.WrapError(err, "failed to create album")
+	}
+
+	return album, nil
+}
+
+// GetAlbumByID retrieves an album by its MongoDB ObjectID.
+func GetAlbumByID(ctx context.Context, albumID primitive.ObjectID) (*Album, error) {
+	col, err := db.GetCollection[any](ctx, AlbumCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var album Album
+	if err := col.FindOne(ctx, bson.M{"_id": albumID}).Decode(&album); err != nil {
+		return nil, db.WrapError(err, "failed to get album %s", albumID.Hex())
+	}
+
+	return &album, nil
+}
+
+// GetAlbumsByOwner retrieves all albums belonging to the given owner, most recently updated first.
+func GetAlbumsByOwner(ctx context.Context, owner string) ([]*Album, error) {
+	return findAlbums(ctx, bson.M{"owner": owner})
+}
+
+// GetAlbumsByIDs retrieves the albums with the given IDs, most recently updated first. IDs of albums that do not
+// exist are ignored.
+func GetAlbumsByIDs(ctx context.Context, albumIDs []primitive.ObjectID) ([]*Album, error) {
+	if len(albumIDs) == 0 {
+		return []*Album{}, nil
+	}
+
+	return findAlbums(ctx, bson.M{"_id": bson.M{"$in": albumIDs}})
+}
+
+func findAlbums(ctx context.Context, filter bson.M) ([]*Album, error) {
+	col, err := db.GetCollection[any](ctx, AlbumCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := col.Find(ctx, filter, options.Find().SetSort(bson.M{"updated": -1}))
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get albums")
+	}
+
+	albums := []*Album{}
+	if err := cursor.All(ctx, &albums); err != nil {
+		return nil, db.WrapError(err, "failed to decode albums")
+	}
+
+	return albums, nil
+}
+
+// DeleteAlbum permanently removes an album by its ID. The media in the album are not affected.
+func DeleteAlbum(ctx context.Context, albumID primitive.ObjectID) error {
+	col, err := db.GetCollection[any](ctx, AlbumCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	result, err := col.DeleteOne(ctx, bson.M{"_id": albumID})
+	if err != nil {
+		return db.WrapError(err, "failed to delete album")
+	}
+
+	if result.DeletedCount == 0 {
+		return db.WrapError(mongo.ErrNoDocuments, "failed to delete album %s", albumID.Hex())
+	}
+
+	return nil
+}
+
+// RemoveMediaFromAllAlbums removes media from every album that contains it, clearing the cover of any album whose
+// cover was removed.
+func RemoveMediaFromAllAlbums(ctx context.Context, contentIDs ...string) error {
+	if len(contentIDs) == 0 {
+		return nil
+	}
+
+	col, err := db.GetCollection[any](ctx, AlbumCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	_, err = col.UpdateMany(
+		ctx,
+		bson.M{"medias": bson.M{"$in": contentIDs}},
+		bson.M{
+			"$pull": bson.M{"medias": bson.M{"$in": contentIDs}},
+			"$set":  bson.M{"updated": now},
+		},
+	)
+	if err != nil {
+		return db.WrapError(err, "failed to remove media from albums")
+	}
+
+	_, err = col.UpdateMany(ctx, bson.M{"cover": bson.M{"$in": contentIDs}}, bson.M{"$set": bson.M{"cover": ""}})
+	if err != nil {
+		return db.WrapError(err, "failed to clear album covers")
+	}
+
+	return nil
+}
+
+// ID returns the album's ID as a hex string.
+func (a *Album) ID() string { return a.AlbumID.Hex() }
+
+// HasMedia returns true if the media with contentID is in the album.
+func (a *Album) HasMedia(contentID string) bool {
+	return slices.Contains(a.Medias, contentID)
+}
+
+// CoverID returns the content ID of the media shown for the album, or empty if the album has no media.
+func (a *Album) CoverID() string {
+	if a.Cover != "" {
+		return a.Cover
+	}
+
+	if len(a.Medias) != 0 {
+		return a.Medias[0]
+	}
+
+	return ""
+}
+
+// SetInfo sets the title and description of the album.
+func (a *Album) SetInfo(ctx context.Context, title, description string) error {
+	a.Title = title
+	a.Description = description
+
+	return a.update(ctx, bson.M{"title": title, "description": description})
+}
+
+// AddMedias adds media to the end of the album, in the order given. Media already in the album keep their place.
+func (a *Album) AddMedias(ctx context.Context, contentIDs []string) error {
+	added := false
+
+	for _, contentID := range contentIDs {
+		if contentID == "" || a.HasMedia(contentID) {
+			continue
+		}
+
+		a.Medias = append(a.Medias, contentID)
+		added = true
+	}
+
+	if !added {
+		return nil
+	}
+
+	return a.update(ctx, bson.M{"medias": a.Medias})
+}
+
+// RemoveMedias removes media from the album. If the cover of the album is removed, the cover is reset to the first
+// media in the album.
+func (a *Album) RemoveMedias(ctx context.Context, contentIDs []string) error {
+	a.Medias = slices.DeleteFunc(a.Medias, func(contentID string) bool {
+		return slices.Contains(contentIDs, contentID)
+	})
+
+	if slices.Contains(contentIDs, a.Cover) {
+		a.Cover = ""
+	}
+
+	return a.update(ctx, bson.M{"medias": a.Medias, "cover": a.Cover})
+}
+
+// SetOrder changes the order of the media in the album. contentIDs must contain each media in the album exactly once.
+func (a *Album) SetOrder(ctx context.Context, contentIDs []string) error {
+	if len(contentIDs) != len(a.Medias) {
+		return wlerrors.WithStack(ErrInvalidMediaOrder)
+	}
+
+	current := slices.Sorted(slices.Values(a.Medias))
+	proposed := slices.Sorted(slices.Values(contentIDs))
+
+	if !slices.Equal(current, proposed) {
+		return wlerrors.WithStack(ErrInvalidMediaOrder)
+	}
+
+	a.Medias = slices.Clone(contentIDs)
+
+	return a.update(ctx, bson.M{"medias": a.Medias})
+}
+
+// SetCover sets the media shown for the album. An empty contentID resets the cover to the first media in the album.
+func (a *Album) SetCover(ctx context.Context, contentID string) error {
+	if contentID != "" && !a.HasMedia(contentID) {
+		return wlerrors.WithStack(ErrCoverNotInAlbum)
+	}
+
+	a.Cover = contentID
+
+	return a.update(ctx, bson.M{"cover": contentID})
+}
+
+func (a *Album) update(ctx context.Context, set bson.M) error {
+	col, err := db.GetCollection[any](ctx, AlbumCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	a.Updated = time.Now()
+	set["updated"] = a.Updated
+
+	result, err := col.UpdateOne(ctx, bson.M{"_id": a.AlbumID}, bson.M{"$set": set})
+	if err != nil {
+		return db.WrapError(err, "failed to update album %s", a.ID())
+	}
+
+	if result.MatchedCount == 0 {
+		return db.WrapError(mongo.ErrNoDocuments, "failed to update album %s", a.ID())
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package album_test
+
+import (
+	"testing"
+
+	"github.com/ethanrous/weblens/models/album"
+	"github.com/ethanrous/weblens/models/db"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAlbum_CRUD(t *testing.T) {
+	ctx := db.SetupTestDB(t, album.AlbumCollectionKey, album.IndexModels...)
+
+	t.Run("CreateAlbum", func(t *testing.T) {
+		created, err := album.CreateAlbum(ctx, "Summer", "Beach trip", "alice")
+		require.NoError(t, err)
+		assert.Equal(t, "Summer", created.Title)
+		assert.Equal(t, "Beach trip", created.Description)
+		assert.Equal(t, "alice", created.Owner)
+		assert.NotZero(t, created.AlbumID)
+		assert.Empty(t, created.Medias)
+		assert.Empty(t, created.CoverID())
+	})
+
+	t.Run("GetAlbumByID_NotFound", func(t *testing.T) {
+		_, err := album.GetAlbumByID(ctx, primitive.NewObjectID())
+		assert.True(t, db.IsNotFound(err))
+	})
+
+	t.Run("GetAlbumsByOwner", func(t *testing.T) {
+		_, err := album.CreateAlbum(ctx, "One", "", "bob")
+		require.NoError(t, err)
+		second, err := album.CreateAlbum(ctx, "Two", "", "bob")
+		require.NoError(t, err)
+
+		albums, err := album.GetAlbumsByOwner(ctx, "bob")
+		require.NoError(t, err)
+		require.Len(t, albums, 2)
+
+		titles := []string{albums[0].Title, albums[1].Title}
+		assert.Contains(t, titles, "One")
+		assert.Contains(t, titles, "Two")
+
+		albums, err = album.GetAlbumsByIDs(ctx, []primitive.ObjectID{second.AlbumID, primitive.NewObjectID()})
+		require.NoError(t, err)
+		require.Len(t, albums, 1)
+		assert.Equal(t, "Two", albums[0].Title)
+	})
+
+	t.Run("AddMedias_KeepsOrderAndSkipsDuplicates", func(t *testing.T) {
+		a, err := album.CreateAlbum(ctx, "Ordered", "", "alice")
+		require.NoError(t, err)
+
+		require.NoError(t, a.AddMedias(ctx, []string{"c", "a"}))
+		require.NoError(t, a.AddMedias(ctx, []string{"a", "b"}))
+
+		fetched, err := album.GetAlbumByID(ctx, a.AlbumID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"c", "a", "b"}, fetched.Medias)
+		assert.Equal(t, "c", fetched.CoverID())
+	})
+
+	t.Run("SetOrder", func(t *testing.T) {
+		a, err := album.CreateAlbum(ctx, "Reorder", "", "alice")
+		require.NoError(t, err)
+		require.NoError(t, a.AddMedias(ctx, []string{"a", "b", "c"}))
+
+		err = a.SetOrder(ctx, []string{"a", "b"})
+		assert.True(t, wlerrors.Is(err, album.ErrInvalidMediaOrder))
+
+		err = a.SetOrder(ctx, []string{"a", "b", "d"})
+		assert.True(t, wlerrors.Is(err, album.ErrInvalidMediaOrder))
+
+		require.NoError(t, a.SetOrder(ctx, []string{"c", "a", "b"}))
+
+		fetched, err := album.GetAlbumByID(ctx, a.AlbumID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"c", "a", "b"}, fetched.Medias)
+	})
+
+	t.Run("SetCover_And_RemoveMedias", func(t *testing.T) {
+		a, err := album.CreateAlbum(ctx, "Cover", "", "alice")
+		require.NoError(t, err)
+		require.NoError(t, a.AddMedias(ctx, []string{"a", "b"}))
+
+		err = a.SetCover(ctx, "missing")
+		assert.True(t, wlerrors.Is(err, album.ErrCoverNotInAlbum))
+
+		require.NoError(t, a.SetCover(ctx, "b"))
+		assert.Equal(t, "b", a.CoverID())
+
+		require.NoError(t, a.RemoveMedias(ctx, []string{"b"}))
+
+		fetched, err := album.GetAlbumByID(ctx, a.AlbumID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a"}, fetched.Medias)
+		assert.Empty(t, fetched.Cover)
+		assert.Equal(t, "a", fetched.CoverID())
+	})
+
+	t.Run("RemoveMediaFromAllAlbums", func(t *testing.T) {
+		first, err := album.CreateAlbum(ctx, "First", "", "carol")
+		require.NoError(t, err)
+		require.NoError(t, first.AddMedias(ctx, []string{"x", "y"}))
+		require.NoError(t, first.SetCover(ctx, "x"))
+
+		second, err := album.CreateAlbum(ctx, "Second", "", "carol")
+		require.NoError(t, err)
+		require.NoError(t, second.AddMedias(ctx, []string{"x"}))
+
+		require.NoError(t, album.RemoveMediaFromAllAlbums(ctx, "x"))
+
+		fetched, err := album.GetAlbumByID(ctx, first.AlbumID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"y"}, fetched.Medias)
+		assert.Empty(t, fetched.Cover)
+
+		fetched, err = album.GetAlbumByID(ctx, second.AlbumID)
+		require.NoError(t, err)
+		assert.Empty(t, fetched.Medias)
+	})
+
+	t.Run("DeleteAlbum", func(t *testing.T) {
+		a, err := album.CreateAlbum(ctx, "Doomed", "", "alice")
+		require.NoError(t, err)
+
+		require.NoError(t, album.DeleteAlbum(ctx, a.AlbumID))
+
+		_, err = album.GetAlbumByID(ctx, a.AlbumID)
+		assert.True(t, db.IsNotFound(err))
+
+		err = album.DeleteAlbum(ctx, a.AlbumID)
+		assert.True(t, db.IsNotFound(err))
+	})
+}
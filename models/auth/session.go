@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ethanrous/weblens/models/db"
+	"github.com/ethanrous/weblens/modules/config"
+	"github.com/ethanrous/weblens/modules/startup"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SessionCollectionKey is the MongoDB collection name for storing login sessions.
+const SessionCollectionKey = "sessions"
+
+// sessionTouchInterval is how stale the last use of a session may be before it is written again. Sessions are checked
+// on every request, so this keeps most requests from writing to the database.
+const sessionTouchInterval = time.Minute
+
+const sessionUsernameIndexKey = "username_index"
+const sessionExpiresIndexKey = "expires_ttl_index"
+
+// ErrSessionNotFound is returned when a session does not exist, because it was revoked or has expired.
+var ErrSessionNotFound = wlerrors.Statusf(http.StatusUnauthorized, "session not found")
+
+// SessionIndexModels defines MongoDB indexes for the sessions collection. Expired sessions are removed by MongoDB.
+var SessionIndexModels = []mongo.IndexModel{
+	{
+		Keys:    bson.D{{Key: "username", Value: 1}},
+		Options: options.Index().SetName(sessionUsernameIndexKey),
+	},
+	{
+		Keys:    bson.D{{Key: "expires", Value: 1}},
+		Options: options.Index().SetName(sessionExpiresIndexKey).SetExpireAfterSeconds(0),
+	},
+}
+
+func init() {
+	startup.RegisterHook(registerSessionIndexes)
+}
+
+func registerSessionIndexes(ctx context.Context, _ config.Provider) error {
+	col, err := db.GetCollection[any](ctx, SessionCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	for _, idx := range SessionIndexModels {
+		if err := col.NewIndex(idx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Session is a login session of a user. Each session is identified by the ID of the JWT handed out when it was
+// created, and a JWT is only accepted while its session exists.
+type Session struct {
+	CreatedTime time.Time `bson:"createdTime"`
+	LastUsed    time.Time `bson:"lastUsed"`
+	Expires     time.Time `bson:"expires"`
+
+	// ID is the ID of the JWT of the session.
+	ID string `bson:"_id"`
+
+	Username string `bson:"username"`
+
+	// Device is the user agent of the client that logged in.
+	Device string `bson:"device"`
+
+	// IP is the address the session was last used from.
+	IP string `bson:"ip"`
+}
+
+// NewSession creates, but does not save, a session for username with the given JWT ID.
+func NewSession(sessionID, username, device, ip string, expires time.Time) *Session {
+	now := time.Now()
+
+	return &Session{
+		ID:          sessionID,
+		Username:    username,
+		Device:      device,
+		IP:          ip,
+		CreatedTime: now,
+		LastUsed:    now,
+		Expires:     expires,
+	}
+}
+
+// SaveSession persists a new session to the database.
+func SaveSession(ctx context.Context, session *Session) error {
+	if session.ID == "" {
+		return wlerrors.New("session ID is empty")
+	}
+
+	col, err := db.GetCollection[any](ctx, SessionCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = col.InsertOne(ctx, session)
+	if err != nil {
+		return db.WrapError(err, "failed to save session")
+	}
+
+	return nil
+}
+
+// GetSession retrieves a session by the ID of its JWT. Sessions past their expiry are not returned, even if MongoDB has
+// not removed them yet.
+func GetSession(ctx context.Context, sessionID string) (*Session, error) {
+	col, err := db.GetCollection[*Session](ctx, SessionCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := col.FindOneAs(ctx, bson.M{"_id": sessionID, "expires": bson.M{"$gt": time.Now()}})
+	if db.IsNotFound(err) {
+		return nil, wlerrors.WithStack(ErrSessionNotFound)
+	} else if err != nil {
+		return nil, db.WrapError(err, "failed to get session")
+	}
+
+	return session, nil
+}
+
+// GetSessionsByUser retrieves the unexpired sessions of a user, most recently used first.
+func GetSessionsByUser(ctx context.Context, username string) ([]*Session, error) {
+	col, err := db.GetCollection[any](ctx, SessionCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := col.Find(
+		ctx,
+		bson.M{"username": username, "expires": bson.M{"$gt": time.Now()}},
+		options.Find().SetSort(bson.D{{Key: "lastUsed", Value: -1}}),
+	)
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get sessions of user")
+	}
+
+	sessions := []*Session{}
+
+	err = cursor.All(ctx, &sessions)
+	if err != nil {
+		return nil, db.WrapError(err, "failed to decode sessions")
+	}
+
+	return sessions, nil
+}
+
+// Touch records that the session was just used from ip. To spare the database, nothing is written if the session was
+// already used recently from the same address.
+func (s *Session) Touch(ctx context.Context, ip string) error {
+	now := time.Now()
+	if now.Sub(s.LastUsed) < sessionTouchInterval && ip == s.IP {
+		return nil
+	}
+
+	col, err := db.GetCollection[any](ctx, SessionCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = col.UpdateOne(ctx, bson.M{"_id": s.ID}, bson.M{"$set": bson.M{"lastUsed": now, "ip": ip}})
+	if err != nil {
+		return db.WrapError(err, "failed to update session last use")
+	}
+
+	s.LastUsed = now
+	s.IP = ip
+
+	return nil
+}
+
+// DeleteSession revokes a session of username. ErrSessionNotFound is returned if username has no such session.
+func DeleteSession(ctx context.Context, username, sessionID string) error {
+	col, err := db.GetCollection[any](ctx, SessionCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	res, err := col.DeleteOne(ctx, bson.M{"_id": sessionID, "username": username})
+	if err != nil {
+		return db.WrapError(err, "failed to delete session")
+	}
+
+	if res.DeletedCount == 0 {
+		return wlerrors.WithStack(ErrSessionNotFound)
+	}
+
+	return nil
+}
+
+// DeleteSessionsByUser revokes every session of username, except for the sessions whose IDs are in keep. It returns
+// how many sessions were revoked.
+func DeleteSessionsByUser(ctx context.Context, username string, keep ...string) (int64, error) {
+	col, err := db.GetCollection[any](ctx, SessionCollectionKey)
+	if err != nil {
+		return 0, err
+	}
+
+	filter := bson.M{"username": username}
+	if len(keep) != 0 {
+		filter["_id"] = bson.M{"$nin": keep}
+	}
+
+	res, err := col.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, db.WrapError(err, "failed to delete sessions of user")
+	}
+
+	return res.DeletedCount, nil
+}
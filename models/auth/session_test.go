@@ -0,0 +1,92 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethanrous/weblens/models/auth"
+	"github.com/ethanrous/weblens/models/db"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSession(t *testing.T) {
+	ctx := db.SetupTestDB(t, auth.SessionCollectionKey, auth.SessionIndexModels...)
+
+	t.Run("found", func(t *testing.T) {
+		session := auth.NewSession("session1", "owner", "device", "127.0.0.1", time.Now().Add(time.Hour))
+		require.NoError(t, auth.SaveSession(ctx, session))
+
+		found, err := auth.GetSession(ctx, "session1")
+		require.NoError(t, err)
+		assert.Equal(t, "owner", found.Username)
+		assert.Equal(t, "device", found.Device)
+		assert.Equal(t, "127.0.0.1", found.IP)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		session := auth.NewSession("expired", "owner", "device", "127.0.0.1", time.Now().Add(-time.Hour))
+		require.NoError(t, auth.SaveSession(ctx, session))
+
+		_, err := auth.GetSession(ctx, "expired")
+		assert.True(t, wlerrors.Is(err, auth.ErrSessionNotFound))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := auth.GetSession(ctx, "nonexistent")
+		assert.True(t, wlerrors.Is(err, auth.ErrSessionNotFound))
+	})
+}
+
+func TestSessionTouch(t *testing.T) {
+	ctx := db.SetupTestDB(t, auth.SessionCollectionKey, auth.SessionIndexModels...)
+
+	session := auth.NewSession("session1", "owner", "device", "127.0.0.1", time.Now().Add(time.Hour))
+	require.NoError(t, auth.SaveSession(ctx, session))
+
+	require.NoError(t, session.Touch(ctx, "10.0.0.1"))
+
+	found, err := auth.GetSession(ctx, "session1")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", found.IP)
+}
+
+func TestDeleteSessions(t *testing.T) {
+	t.Run("deletes a single session of the user", func(t *testing.T) {
+		ctx := db.SetupTestDB(t, auth.SessionCollectionKey, auth.SessionIndexModels...)
+
+		require.NoError(t, auth.SaveSession(ctx, auth.NewSession("session1", "owner", "", "", time.Now().Add(time.Hour))))
+
+		err := auth.DeleteSession(ctx, "other", "session1")
+		assert.True(t, wlerrors.Is(err, auth.ErrSessionNotFound))
+
+		err = auth.DeleteSession(ctx, "owner", "session1")
+		require.NoError(t, err)
+
+		_, err = auth.GetSession(ctx, "session1")
+		assert.True(t, wlerrors.Is(err, auth.ErrSessionNotFound))
+	})
+
+	t.Run("deletes every session of the user except those kept", func(t *testing.T) {
+		ctx := db.SetupTestDB(t, auth.SessionCollectionKey, auth.SessionIndexModels...)
+
+		for _, id := range []string{"session1", "session2", "session3"} {
+			require.NoError(t, auth.SaveSession(ctx, auth.NewSession(id, "owner", "", "", time.Now().Add(time.Hour))))
+		}
+
+		require.NoError(t, auth.SaveSession(ctx, auth.NewSession("other", "other", "", "", time.Now().Add(time.Hour))))
+
+		revoked, err := auth.DeleteSessionsByUser(ctx, "owner", "session2")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), revoked)
+
+		sessions, err := auth.GetSessionsByUser(ctx, "owner")
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		assert.Equal(t, "session2", sessions[0].ID)
+
+		_, err = auth.GetSession(ctx, "other")
+		assert.NoError(t, err)
+	})
+}
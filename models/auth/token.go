@@ -3,6 +3,8 @@ package auth
 
 import (
 	"context"
+	"net/http"
+	"slices"
 	"time"
 
 	"github.com/ethanrous/weblens/models/db"
@@ -15,9 +17,63 @@ import (
 // TokenCollectionKey is the MongoDB collection name for storing authentication tokens.
 const TokenCollectionKey = "tokens"
 
+// tokenTouchInterval is how stale the last use of a token may be before it is written again. Tokens are checked on
+// every request, so writing each use would double the database load of API clients.
+const tokenTouchInterval = time.Minute
+
 // ErrTokenNotFound is returned when a requested token does not exist in the database.
 var ErrTokenNotFound = wlerrors.New("no token found")
 
+// ErrTokenExpired is returned when a token is used after its expiry.
+var ErrTokenExpired = wlerrors.Statusf(http.StatusUnauthorized, "api key has expired")
+
+// ErrInvalidScope is returned when a token is given a scope that does not exist.
+var ErrInvalidScope = wlerrors.Statusf(http.StatusBadRequest, "invalid api key scope")
+
+// Scope limits what a token may be used for.
+type Scope string
+
+const (
+	// ScopeFilesRead allows browsing and downloading files, and reading tags and shares.
+	ScopeFilesRead Scope = "files:read"
+	// ScopeFilesWrite allows uploading, changing and deleting files, tags and shares. It includes ScopeFilesRead.
+	ScopeFilesWrite Scope = "files:write"
+	// ScopeMediaRead allows viewing and streaming media, and reading albums.
+	ScopeMediaRead Scope = "media:read"
+	// ScopeMediaWrite allows changing media and albums. It includes ScopeMediaRead.
+	ScopeMediaWrite Scope = "media:write"
+	// ScopeAdmin allows everything the owner of the token may do, including admin endpoints and managing users and
+	// api keys.
+	ScopeAdmin Scope = "admin"
+)
+
+// AllScopes lists every scope a token may be given.
+var AllScopes = []Scope{ScopeFilesRead, ScopeFilesWrite, ScopeMediaRead, ScopeMediaWrite, ScopeAdmin}
+
+// scopeImpliedBy maps read scopes to the write scopes that include them.
+var scopeImpliedBy = map[Scope]Scope{
+	ScopeFilesRead: ScopeFilesWrite,
+	ScopeMediaRead: ScopeMediaWrite,
+}
+
+// ParseScopes converts scope names into scopes, returning ErrInvalidScope if any of them does not exist.
+func ParseScopes(names []string) ([]Scope, error) {
+	scopes := make([]Scope, 0, len(names))
+
+	for _, name := range names {
+		scope := Scope(name)
+		if !slices.Contains(AllScopes, scope) {
+			return nil, wlerrors.Errorf("%w: %s", ErrInvalidScope, name)
+		}
+
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	return scopes, nil
+}
+
 // Token represents an authentication token with metadata about its creation, ownership, and usage.
 type Token struct {
 	CreatedTime time.Time          `bson:"createdTime"`
@@ -28,10 +84,32 @@ type Token struct {
 	CreatedBy   string             `bson:"createdBy"`
 	Token       [32]byte           `bson:"token"`
 	ID          primitive.ObjectID `bson:"_id"`
+
+	// Expires is when the token stops working. The zero time means it never expires.
+	Expires time.Time `bson:"expires,omitempty"`
+
+	// Scopes limits what the token may be used for. A token with no scopes has the full power of its owner, as all
+	// tokens did before scopes existed, and as tokens used between towers still do.
+	Scopes []Scope `bson:"scopes,omitempty"`
+
+	// FolderID, if set, limits the token to the files inside this folder.
+	FolderID string `bson:"folderID,omitempty"`
+}
+
+// TokenRestrictions are the limits placed on a new token.
+type TokenRestrictions struct {
+	Expires  time.Time
+	Scopes   []Scope
+	FolderID string
 }
 
 // GenerateNewToken creates and saves a new authentication token with the specified nickname, owner, and creator.
 func GenerateNewToken(ctx context.Context, nickname, owner, createdBy string) (*Token, error) {
+	return GenerateNewRestrictedToken(ctx, nickname, owner, createdBy, TokenRestrictions{})
+}
+
+// GenerateNewRestrictedToken creates and saves a new authentication token, limited by restrictions.
+func GenerateNewRestrictedToken(ctx context.Context, nickname, owner, createdBy string, restrictions TokenRestrictions) (*Token, error) {
 	tok, err := cryptography.RandomBytes(32)
 	if err != nil {
 		return nil, err
@@ -48,6 +126,9 @@ func GenerateNewToken(ctx context.Context, nickname, owner, createdBy string) (*
 		CreatedBy:   createdBy,
 		ID:          primitive.NewObjectID(),
 		Token:       tokenBytes,
+		Expires:     restrictions.Expires,
+		Scopes:      restrictions.Scopes,
+		FolderID:    restrictions.FolderID,
 	}
 
 	col, err := db.GetCollection[Token](ctx, TokenCollectionKey)
@@ -63,6 +144,51 @@ func GenerateNewToken(ctx context.Context, nickname, owner, createdBy string) (*
 	return token, nil
 }
 
+// IsExpired returns true if the token has an expiry, and it has passed.
+func (t *Token) IsExpired() bool {
+	return !t.Expires.IsZero() && time.Now().After(t.Expires)
+}
+
+// IsScoped returns true if the token is limited to some scopes, rather than having the full power of its owner.
+func (t *Token) IsScoped() bool {
+	return len(t.Scopes) != 0
+}
+
+// HasScope returns true if the token may be used for scope. Tokens with no scopes, and tokens with ScopeAdmin, may be
+// used for anything.
+func (t *Token) HasScope(scope Scope) bool {
+	if !t.IsScoped() || slices.Contains(t.Scopes, ScopeAdmin) || slices.Contains(t.Scopes, scope) {
+		return true
+	}
+
+	implied, ok := scopeImpliedBy[scope]
+
+	return ok && slices.Contains(t.Scopes, implied)
+}
+
+// Touch records that the token was just used. To spare the database, nothing is written if the token was already used
+// recently.
+func (t *Token) Touch(ctx context.Context) error {
+	now := time.Now()
+	if now.Sub(t.LastUsed) < tokenTouchInterval {
+		return nil
+	}
+
+	col, err := db.GetCollection[any](ctx, TokenCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = col.UpdateOne(ctx, bson.M{"_id": t.ID}, bson.M{"$set": bson.M{"lastUsed": now}})
+	if err != nil {
+		return db.WrapError(err, "failed to update token last use")
+	}
+
+	t.LastUsed = now
+
+	return nil
+}
+
 // SaveToken persists an authentication token to the database.
 func SaveToken(ctx context.Context, token *Token) error {
 	if token.Token == [32]byte{} {
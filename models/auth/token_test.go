@@ -235,3 +235,84 @@ func TestDeleteToken(t *testing.T) {
 		assert.Equal(t, auth.ErrTokenNotFound, err)
 	})
 }
+
+func TestGenerateNewRestrictedToken(t *testing.T) {
+	ctx := db.SetupTestDB(t, auth.TokenCollectionKey)
+
+	expires := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+
+	token, err := auth.GenerateNewRestrictedToken(ctx, "nickname", "owner", "createdBy", auth.TokenRestrictions{
+		Expires:  expires,
+		Scopes:   []auth.Scope{auth.ScopeFilesRead},
+		FolderID: "folderID",
+	})
+	assert.NoError(t, err)
+
+	fetched, err := auth.GetToken(ctx, token.Token)
+	assert.NoError(t, err)
+	assert.True(t, expires.Equal(fetched.Expires))
+	assert.Equal(t, []auth.Scope{auth.ScopeFilesRead}, fetched.Scopes)
+	assert.Equal(t, "folderID", fetched.FolderID)
+	assert.False(t, fetched.IsExpired())
+}
+
+func TestTokenScopes(t *testing.T) {
+	t.Run("unscoped token may do anything", func(t *testing.T) {
+		token := &auth.Token{}
+		assert.False(t, token.IsScoped())
+		assert.True(t, token.HasScope(auth.ScopeAdmin))
+		assert.True(t, token.HasScope(auth.ScopeFilesWrite))
+	})
+
+	t.Run("write scope includes read scope", func(t *testing.T) {
+		token := &auth.Token{Scopes: []auth.Scope{auth.ScopeFilesWrite}}
+		assert.True(t, token.HasScope(auth.ScopeFilesRead))
+		assert.True(t, token.HasScope(auth.ScopeFilesWrite))
+		assert.False(t, token.HasScope(auth.ScopeMediaRead))
+		assert.False(t, token.HasScope(auth.ScopeAdmin))
+	})
+
+	t.Run("read scope does not include write scope", func(t *testing.T) {
+		token := &auth.Token{Scopes: []auth.Scope{auth.ScopeMediaRead}}
+		assert.True(t, token.HasScope(auth.ScopeMediaRead))
+		assert.False(t, token.HasScope(auth.ScopeMediaWrite))
+	})
+
+	t.Run("admin scope includes everything", func(t *testing.T) {
+		token := &auth.Token{Scopes: []auth.Scope{auth.ScopeAdmin}}
+		assert.True(t, token.HasScope(auth.ScopeFilesWrite))
+		assert.True(t, token.HasScope(auth.ScopeMediaWrite))
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		assert.False(t, (&auth.Token{}).IsExpired())
+		assert.True(t, (&auth.Token{Expires: time.Now().Add(-time.Minute)}).IsExpired())
+		assert.False(t, (&auth.Token{Expires: time.Now().Add(time.Minute)}).IsExpired())
+	})
+
+	t.Run("parse scopes", func(t *testing.T) {
+		scopes, err := auth.ParseScopes([]string{"files:read", "admin", "files:read"})
+		assert.NoError(t, err)
+		assert.Equal(t, []auth.Scope{auth.ScopeFilesRead, auth.ScopeAdmin}, scopes)
+
+		_, err = auth.ParseScopes([]string{"files:everything"})
+		assert.ErrorIs(t, err, auth.ErrInvalidScope)
+	})
+}
+
+func TestTokenTouch(t *testing.T) {
+	ctx := db.SetupTestDB(t, auth.TokenCollectionKey)
+
+	token, err := auth.GenerateNewToken(ctx, "nickname", "owner", "createdBy")
+	assert.NoError(t, err)
+
+	stale := time.Now().Add(-time.Hour)
+	token.LastUsed = stale
+
+	assert.NoError(t, token.Touch(ctx))
+	assert.True(t, token.LastUsed.After(stale))
+
+	fetched, err := auth.GetToken(ctx, token.Token)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, token.LastUsed, fetched.LastUsed, time.Second)
+}
@@ -0,0 +1,158 @@
+// Package backup stores, on a backup tower, how the files of each core it backs up are organized.
+package backup
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethanrous/weblens/models/db"
+	"github.com/ethanrous/weblens/modules/config"
+	"github.com/ethanrous/weblens/modules/startup"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RecordCollectionKey is the MongoDB collection name for backup records.
+const RecordCollectionKey = "backupRecords"
+
+const recordKeyIndexKey = "towerID_kind_key_unique_index"
+
+// IndexModels defines MongoDB indexes for the backup records collection.
+var IndexModels = []mongo.IndexModel{
+	{
+		Keys:    bson.D{{Key: "towerID", Value: 1}, {Key: "kind", Value: 1}, {Key: "key", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName(recordKeyIndexKey),
+	},
+}
+
+// Kind is the kind of data a record holds.
+type Kind string
+
+const (
+	// KindShare records hold a wlstructs.ShareInfoArchive, keyed by share ID.
+	KindShare Kind = "share"
+	// KindTag records hold a wlstructs.TagInfo, keyed by tag ID.
+	KindTag Kind = "tag"
+	// KindCover records hold a wlstructs.CoverInfo, keyed by folder ID.
+	KindCover Kind = "cover"
+	// KindMedia records hold a wlstructs.MediaInfo, keyed by content ID.
+	KindMedia Kind = "media"
+	// KindToken records hold a wlstructs.TokenInfo, keyed by token ID.
+	KindToken Kind = "token"
+)
+
+// Record is a single share, tag, cover, media or API token of a core, as it was last backed up. Records are kept apart
+// from the backup tower's own data, so the API tokens of a core can not be used to access its backup, and records of
+// different cores never collide.
+type Record struct {
+	// TowerID is the ID of the core the record was backed up from
+	TowerID string   `bson:"towerID"`
+	Kind    Kind     `bson:"kind"`
+	Key     string   `bson:"key"`
+	Data    bson.Raw `bson:"data"`
+
+	Updated time.Time `bson:"updated"`
+}
+
+func init() {
+	startup.RegisterHook(registerIndexes)
+}
+
+// SaveRecord creates or replaces the record of kind with key, backed up from the core with towerID.
+func SaveRecord(ctx context.Context, towerID string, kind Kind, key string, data any) error {
+	raw, err := bson.Marshal(data)
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	col, err := db.GetCollection[any](ctx, RecordCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	record := Record{
+		TowerID: towerID,
+		Kind:    kind,
+		Key:     key,
+		Data:    raw,
+		Updated: time.Now(),
+	}
+
+	_, err = col.ReplaceOne(ctx, bson.M{"towerID": towerID, "kind": kind, "key": key}, record, options.Replace().SetUpsert(true))
+	if err != nil {
+		return db.WrapError(err, "failed to save backup %s [%s] of tower [%s]", kind, key, towerID)
+	}
+
+	return nil
+}
+
+// PruneRecords deletes every record of kind backed up from the core with towerID, other than those with keys in keep.
+func PruneRecords(ctx context.Context, towerID string, kind Kind, keep []string) error {
+	col, err := db.GetCollection[any](ctx, RecordCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	if keep == nil {
+		keep = []string{}
+	}
+
+	_, err = col.DeleteMany(ctx, bson.M{"towerID": towerID, "kind": kind, "key": bson.M{"$nin": keep}})
+	if err != nil {
+		return db.WrapError(err, "failed to prune backup %s records of tower [%s]", kind, towerID)
+	}
+
+	return nil
+}
+
+// GetRecords retrieves the data of every record of kind backed up from the core with towerID, decoded as T.
+func GetRecords[T any](ctx context.Context, towerID string, kind Kind) ([]T, error) {
+	col, err := db.GetCollection[any](ctx, RecordCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := col.Find(ctx, bson.M{"towerID": towerID, "kind": kind}, options.Find().SetSort(bson.M{"key": 1}))
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get backup %s records of tower [%s]", kind, towerID)
+	}
+
+	var records []Record
+
+	err = cursor.All(ctx, &records)
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get backup %s records of tower [%s]", kind, towerID)
+	}
+
+	data := make([]T, 0, len(records))
+
+	for _, r := range records {
+		var d T
+
+		err = bson.Unmarshal(r.Data, &d)
+		if err != nil {
+			return nil, wlerrors.Wrapf(err, "failed to decode backup %s [%s] of tower [%s]", kind, r.Key, towerID)
+		}
+
+		data = append(data, d)
+	}
+
+	return data, nil
+}
+
+func registerIndexes(ctx context.Context, _ config.Provider) error {
+	col, err := db.GetCollection[any](ctx, RecordCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	for _, idx := range IndexModels {
+		if err := col.NewIndex(idx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
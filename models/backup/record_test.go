@@ -0,0 +1,70 @@
+package backup_test
+
+import (
+	"testing"
+
+	"github.com/ethanrous/weblens/models/backup"
+	"github.com/ethanrous/weblens/models/db"
+	"github.com/ethanrous/weblens/modules/wlstructs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupRecords(t *testing.T) {
+	ctx := db.SetupTestDB(t, backup.RecordCollectionKey, backup.IndexModels...)
+
+	t.Run("save replaces by key", func(t *testing.T) {
+		require.NoError(t, backup.SaveRecord(ctx, "core-a", backup.KindTag, "t1", wlstructs.TagInfo{ID: "t1", Name: "old"}))
+		require.NoError(t, backup.SaveRecord(ctx, "core-a", backup.KindTag, "t1", wlstructs.TagInfo{ID: "t1", Name: "new", FileIDs: []string{"f1"}}))
+
+		tags, err := backup.GetRecords[wlstructs.TagInfo](ctx, "core-a", backup.KindTag)
+		require.NoError(t, err)
+		require.Len(t, tags, 1)
+		assert.Equal(t, "new", tags[0].Name)
+		assert.Equal(t, []string{"f1"}, tags[0].FileIDs)
+	})
+
+	t.Run("namespaced by tower and kind", func(t *testing.T) {
+		require.NoError(t, backup.SaveRecord(ctx, "core-b", backup.KindTag, "t1", wlstructs.TagInfo{ID: "t1", Name: "other core"}))
+		require.NoError(t, backup.SaveRecord(ctx, "core-a", backup.KindCover, "t1", wlstructs.CoverInfo{FolderID: "t1", CoverPhotoID: "c1"}))
+
+		tags, err := backup.GetRecords[wlstructs.TagInfo](ctx, "core-a", backup.KindTag)
+		require.NoError(t, err)
+		require.Len(t, tags, 1)
+		assert.Equal(t, "new", tags[0].Name)
+
+		covers, err := backup.GetRecords[wlstructs.CoverInfo](ctx, "core-a", backup.KindCover)
+		require.NoError(t, err)
+		require.Len(t, covers, 1)
+		assert.Equal(t, "c1", covers[0].CoverPhotoID)
+	})
+
+	t.Run("prune keeps listed keys", func(t *testing.T) {
+		require.NoError(t, backup.SaveRecord(ctx, "core-a", backup.KindTag, "t2", wlstructs.TagInfo{ID: "t2"}))
+		require.NoError(t, backup.SaveRecord(ctx, "core-a", backup.KindTag, "t3", wlstructs.TagInfo{ID: "t3"}))
+
+		require.NoError(t, backup.PruneRecords(ctx, "core-a", backup.KindTag, []string{"t2"}))
+
+		tags, err := backup.GetRecords[wlstructs.TagInfo](ctx, "core-a", backup.KindTag)
+		require.NoError(t, err)
+		require.Len(t, tags, 1)
+		assert.Equal(t, "t2", tags[0].ID)
+
+		// Records of other cores and kinds are left alone
+		other, err := backup.GetRecords[wlstructs.TagInfo](ctx, "core-b", backup.KindTag)
+		require.NoError(t, err)
+		assert.Len(t, other, 1)
+
+		covers, err := backup.GetRecords[wlstructs.CoverInfo](ctx, "core-a", backup.KindCover)
+		require.NoError(t, err)
+		assert.Len(t, covers, 1)
+	})
+
+	t.Run("prune with nothing to keep", func(t *testing.T) {
+		require.NoError(t, backup.PruneRecords(ctx, "core-b", backup.KindTag, nil))
+
+		tags, err := backup.GetRecords[wlstructs.TagInfo](ctx, "core-b", backup.KindTag)
+		require.NoError(t, err)
+		assert.Empty(t, tags)
+	})
+}
@@ -3,6 +3,7 @@ package cover
 
 import (
 	"context"
+	"time"
 
 	"github.com/ethanrous/weblens/models/db"
 	"go.mongodb.org/mongo-driver/bson"
@@ -16,6 +17,8 @@ var CoverPhotoCollectionKey = "coverPhoto"
 type Photo struct {
 	FolderID     string `bson:"folderID"`
 	CoverPhotoID string `bson:"coverPhotoID"`
+	// Updated is when the cover was last set. Covers set before it was recorded have the zero time.
+	Updated time.Time `bson:"updated"`
 }
 
 // GetCoverByFolderID retrieves the cover photo for a folder by its ID.
@@ -43,6 +46,7 @@ func SetCoverPhoto(ctx context.Context, folderID string, coverPhotoID string) (*
 	coverPhoto := &Photo{
 		FolderID:     folderID,
 		CoverPhotoID: coverPhotoID,
+		Updated:      time.Now(),
 	}
 
 	_, err = col.ReplaceOne(ctx, bson.M{"folderID": folderID}, coverPhoto, options.Replace().SetUpsert(true))
@@ -83,6 +87,7 @@ func UpsertCoverByFolderID(ctx context.Context, folderID, coverPhotoID string) e
 		"$set": bson.M{
 			"folderID":     folderID,
 			"coverPhotoID": coverPhotoID,
+			"updated":      time.Now(),
 		},
 	}
 
@@ -118,3 +123,49 @@ func GetCoversByFolderIDs(ctx context.Context, folderIDs ...string) ([]Photo, er
 
 	return covers, nil
 }
+
+// GetCoversUpdatedSince retrieves all cover photos set after since. A zero since retrieves every cover photo, including
+// those set before it was recorded when they were.
+func GetCoversUpdatedSince(ctx context.Context, since time.Time) ([]Photo, error) {
+	col, err := db.GetCollection[any](ctx, CoverPhotoCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := col.Find(ctx, db.UpdatedSinceFilter(since))
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get covers updated since %s", since)
+	}
+
+	covers := []Photo{}
+	if err := cursor.All(ctx, &covers); err != nil {
+		return nil, db.WrapError(err, "failed to decode cover photos")
+	}
+
+	return covers, nil
+}
+
+// GetAllCoverFolderIDs retrieves the IDs of every folder that has a cover photo.
+func GetAllCoverFolderIDs(ctx context.Context) ([]string, error) {
+	col, err := db.GetCollection[any](ctx, CoverPhotoCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := col.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"folderID": 1}))
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get cover folder IDs")
+	}
+
+	var covers []Photo
+	if err := cursor.All(ctx, &covers); err != nil {
+		return nil, db.WrapError(err, "failed to decode cover photos")
+	}
+
+	folderIDs := make([]string, 0, len(covers))
+	for _, c := range covers {
+		folderIDs = append(folderIDs, c.FolderID)
+	}
+
+	return folderIDs, nil
+}
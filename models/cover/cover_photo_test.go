@@ -2,6 +2,7 @@ package cover_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/ethanrous/weblens/models/cover"
 	"github.com/ethanrous/weblens/models/db"
@@ -82,3 +83,28 @@ func TestGetCoversByFolderIDs(t *testing.T) {
 	require.NoError(t, err)
 	require.Empty(t, covers)
 }
+
+func TestGetCoversUpdatedSince(t *testing.T) {
+	ctx := db.SetupTestDB(t, cover.CoverPhotoCollectionKey)
+
+	_, err := cover.SetCoverPhoto(ctx, "folder-a", "photo-a")
+	require.NoError(t, err)
+
+	since := time.Now()
+
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, cover.UpsertCoverByFolderID(ctx, "folder-b", "photo-b"))
+
+	covers, err := cover.GetCoversUpdatedSince(ctx, since)
+	require.NoError(t, err)
+	require.Len(t, covers, 1)
+	require.Equal(t, "folder-b", covers[0].FolderID)
+
+	covers, err = cover.GetCoversUpdatedSince(ctx, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, covers, 2)
+
+	folderIDs, err := cover.GetAllCoverFolderIDs(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"folder-a", "folder-b"}, folderIDs)
+}
@@ -0,0 +1,17 @@
+package db
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// UpdatedSinceFilter matches documents with an "updated" time after since. A zero since matches every document, even
+// those with no "updated" time at all.
+func UpdatedSinceFilter(since time.Time) bson.M {
+	if since.IsZero() {
+		return bson.M{}
+	}
+
+	return bson.M{"updated": bson.M{"$gt": since}}
+}
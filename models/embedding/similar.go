@@ -0,0 +1,70 @@
+package embedding
+
+import (
+	"slices"
+	"strings"
+)
+
+// GroupSimilar groups the source IDs of vectors whose cosine similarity is at least threshold. Similarity is treated
+// as transitive, so two vectors may share a group through a third one even if they are not similar to each other.
+// Groups of a single source are left out. Each group is sorted, and groups are ordered by their first source ID.
+//
+// Every pair of vectors is compared, so this is meant for background work over at most a few tens of thousands of
+// vectors, not for serving requests.
+func GroupSimilar(vectors map[string][]float64, threshold float64) [][]string {
+	ids := make([]string, 0, len(vectors))
+	for id := range vectors {
+		ids = append(ids, id)
+	}
+
+	slices.Sort(ids)
+
+	// Union-find over the indexes of ids
+	parents := make([]int, len(ids))
+	for i := range parents {
+		parents[i] = i
+	}
+
+	var find func(i int) int
+
+	find = func(i int) int {
+		if parents[i] != i {
+			parents[i] = find(parents[i])
+		}
+
+		return parents[i]
+	}
+
+	for i := range ids {
+		for j := i + 1; j < len(ids); j++ {
+			if cosine(vectors[ids[i]], vectors[ids[j]]) < threshold {
+				continue
+			}
+
+			rootI, rootJ := find(i), find(j)
+			if rootI != rootJ {
+				parents[max(rootI, rootJ)] = min(rootI, rootJ)
+			}
+		}
+	}
+
+	members := make(map[int][]string)
+	for i, id := range ids {
+		root := find(i)
+		members[root] = append(members[root], id)
+	}
+
+	groups := make([][]string, 0, len(members))
+
+	for _, group := range members {
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+
+	slices.SortFunc(groups, func(a, b []string) int {
+		return strings.Compare(a[0], b[0])
+	})
+
+	return groups
+}
@@ -0,0 +1,40 @@
+package embedding_test
+
+import (
+	"testing"
+
+	"github.com/ethanrous/weblens/models/embedding"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupSimilar_GroupsVectorsAboveThreshold(t *testing.T) {
+	vectors := map[string][]float64{
+		"a": {1, 0, 0},
+		"b": {0.99, 0.05, 0},
+		"c": {0, 1, 0},
+		"d": {0, 0.98, 0.1},
+		"e": {0, 0, 1},
+	}
+
+	groups := embedding.GroupSimilar(vectors, 0.95)
+
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "d"}}, groups)
+}
+
+func TestGroupSimilar_IsTransitive(t *testing.T) {
+	// a and c are not similar enough on their own, but both are similar to b
+	vectors := map[string][]float64{
+		"a": {1, 0},
+		"b": {0.95, 0.31},
+		"c": {0.81, 0.59},
+	}
+
+	groups := embedding.GroupSimilar(vectors, 0.94)
+
+	assert.Equal(t, [][]string{{"a", "b", "c"}}, groups)
+}
+
+func TestGroupSimilar_NoGroups(t *testing.T) {
+	assert.Empty(t, embedding.GroupSimilar(nil, 0.9))
+	assert.Empty(t, embedding.GroupSimilar(map[string][]float64{"a": {1, 0}, "b": {0, 1}}, 0.9))
+}
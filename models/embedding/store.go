@@ -200,3 +200,37 @@ func CountForChunk(ctx context.Context, kind Kind, sourceID, modelName string, c
 		"chunkIndex": chunkIndex,
 	})
 }
+
+// GetImageVectors returns the vector of the first page of the image embedding of each of sourceIDs that has one,
+// keyed by source ID.
+func GetImageVectors(ctx context.Context, sourceIDs []string) (map[string][]float64, error) {
+	vectors := make(map[string][]float64, len(sourceIDs))
+	if len(sourceIDs) == 0 {
+		return vectors, nil
+	}
+
+	col, err := db.GetCollection[Embedding](ctx, CollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := col.Find(ctx, bson.M{
+		"kind":       string(KindImage),
+		"sourceId":   bson.M{"$in": sourceIDs},
+		"chunkIndex": 0,
+	}, options.Find().SetProjection(bson.M{"sourceId": 1, "vector": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []Embedding
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		vectors[row.SourceID] = row.Vector
+	}
+
+	return vectors, nil
+}
@@ -25,19 +25,48 @@ const (
 	AllowRegistrations FlagKey = "auth.allow_registrations"
 	// EnableEmbed controls whether the embedding service is called for image and file processing.
 	EnableEmbed FlagKey = "embed.processing_enabled"
+	// TrashRetentionDays controls how many days items stay in a user's trash before they are purged, unless the user
+	// has set their own retention. 0 keeps items until they are deleted by hand.
+	TrashRetentionDays FlagKey = "trash.retention_days"
+	// QuotaWarningPercent controls how much of their storage quota, in percent, a user may use before they are warned
+	// that they are running out of space. 0 disables the warning.
+	QuotaWarningPercent FlagKey = "storage.quota_warning_percent"
+	// RequireAdminTOTP controls whether admin and owner accounts must use TOTP two-factor authentication to log in.
+	RequireAdminTOTP FlagKey = "auth.require_admin_totp"
+	// HistoryFullRetentionDays controls how many days every action in the file history is kept. 0 keeps all history
+	// forever.
+	HistoryFullRetentionDays FlagKey = "history.full_retention_days"
+	// HistoryVersionRetentionDays controls how many days the latest version at each path is kept, once it is older than
+	// the full history retention. 0 keeps the latest version at each path forever.
+	HistoryVersionRetentionDays FlagKey = "history.version_retention_days"
+	// EnableFileWatch controls whether changes made to the users tree on disk by something other than Weblens are picked
+	// up and journaled. Turning it on takes effect the next time the server starts, turning it off takes effect right away.
+	EnableFileWatch FlagKey = "files.watch_enabled"
 )
 
 // Bundle represents the application feature flag document.
 type Bundle struct {
-	AllowRegistrations bool `bson:"auth.allow_registrations" json:"auth.allow_registrations"`
-	EnableEmbed        bool `bson:"embed.processing_enabled" json:"embed.processing_enabled"`
+	AllowRegistrations          bool `bson:"auth.allow_registrations" json:"auth.allow_registrations"`
+	EnableEmbed                 bool `bson:"embed.processing_enabled" json:"embed.processing_enabled"`
+	TrashRetentionDays          int  `bson:"trash.retention_days" json:"trash.retention_days"`
+	QuotaWarningPercent         int  `bson:"storage.quota_warning_percent" json:"storage.quota_warning_percent"`
+	RequireAdminTOTP            bool `bson:"auth.require_admin_totp" json:"auth.require_admin_totp"`
+	HistoryFullRetentionDays    int  `bson:"history.full_retention_days" json:"history.full_retention_days"`
+	HistoryVersionRetentionDays int  `bson:"history.version_retention_days" json:"history.version_retention_days"`
+	EnableFileWatch             bool `bson:"files.watch_enabled" json:"files.watch_enabled"`
 } //	@name	Bundle
 
 // Default returns the default flags
 func Default() Bundle {
 	return Bundle{
-		AllowRegistrations: true,
-		EnableEmbed:        false,
+		AllowRegistrations:          true,
+		EnableEmbed:                 false,
+		TrashRetentionDays:          30,
+		QuotaWarningPercent:         90,
+		RequireAdminTOTP:            false,
+		HistoryFullRetentionDays:    30,
+		HistoryVersionRetentionDays: 365,
+		EnableFileWatch:             false,
 	}
 }
 
@@ -25,6 +25,8 @@ const (
 	ThumbsDirName = "thumbs/"
 	// ZipsDirName is the directory name for zip file storage.
 	ZipsDirName = "zips/"
+	// UploadsDirName is the directory name for partially received resumable uploads.
+	UploadsDirName = "uploads/"
 )
 
 // UsersRootPath is the filepath to the users file tree root.
@@ -42,6 +44,9 @@ var ZipsDirPath = wlfs.Filepath{RootAlias: CachesTreeKey, RelPath: ZipsDirName}
 // ThumbsDirPath is the filepath to the thumbnails storage directory.
 var ThumbsDirPath = wlfs.Filepath{RootAlias: CachesTreeKey, RelPath: ThumbsDirName}
 
+// UploadsDirPath is the filepath to the partial uploads storage directory.
+var UploadsDirPath = wlfs.Filepath{RootAlias: CachesTreeKey, RelPath: UploadsDirName}
+
 // RestoreDirPath is the filepath to the restore directory.
 var RestoreDirPath = wlfs.Filepath{RootAlias: RestoreTreeKey}
 
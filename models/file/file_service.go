@@ -2,6 +2,7 @@ package file
 
 import (
 	"context"
+	"io"
 	"time"
 
 	tower_model "github.com/ethanrous/weblens/models/tower"
@@ -26,6 +27,9 @@ type Service interface {
 	// CreateFile creates a new file
 	CreateFile(ctx context.Context, parent *WeblensFileImpl, filename string, data ...[]byte) (*WeblensFileImpl, error)
 
+	// CreateFileFromPath creates a new file whose content is moved from the file at contentPath
+	CreateFileFromPath(ctx context.Context, parent *WeblensFileImpl, filename, contentPath string) (*WeblensFileImpl, error)
+
 	// CreateFolder creates a new folder
 	CreateFolder(ctx context.Context, parent *WeblensFileImpl, folderName string) (*WeblensFileImpl, error)
 
@@ -49,18 +53,30 @@ type Service interface {
 	// MoveFiles moves files to a new location
 	MoveFiles(ctx context.Context, files []*WeblensFileImpl, destFolder *WeblensFileImpl) error
 
+	// CopyFiles copies files, and the contents of folders among them, into destFolder, calling onCopied after each file is copied
+	CopyFiles(ctx context.Context, files []*WeblensFileImpl, destFolder *WeblensFileImpl, onCopied func(copied *WeblensFileImpl)) ([]*WeblensFileImpl, error)
+
 	// RenameFile renames a file
 	RenameFile(ctx context.Context, file *WeblensFileImpl, newName string) error
 
+	// ReplaceFileContent moves the file at newContentPath over the content of file, journaling it as a new revision
+	ReplaceFileContent(ctx context.Context, file *WeblensFileImpl, newContentPath string) error
+
 	// ReturnFilesFromTrash restores files from the trash
 	ReturnFilesFromTrash(ctx context.Context, trashFiles []*WeblensFileImpl) error
 
+	// TrashFiles moves files into the trash of their owners, as a single journaled event
+	TrashFiles(ctx context.Context, files ...*WeblensFileImpl) error
+
 	// DeleteFiles permanently deletes files
 	DeleteFiles(ctx context.Context, files ...*WeblensFileImpl) error
 
 	// RestoreFiles restores files from history
 	RestoreFiles(ctx context.Context, ids []string, newParent *WeblensFileImpl, restoreTime time.Time) error
 
+	// RestoreFilesFromBackup restores past files from the journal of a backup tower, reading their contents with openContent
+	RestoreFilesFromBackup(ctx context.Context, pastFiles []*WeblensFileImpl, newParent *WeblensFileImpl, openContent func(contentID string) (io.ReadCloser, error)) error
+
 	// RestoreHistory restores file history
 	// RestoreHistory(lifetimes []*fileTree.Lifetime) error
 
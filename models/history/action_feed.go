@@ -0,0 +1,123 @@
+package history
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethanrous/weblens/models/db"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrInvalidActionCursor is returned when an action cursor can not be parsed.
+var ErrInvalidActionCursor = wlerrors.Statusf(http.StatusBadRequest, "invalid action cursor")
+
+// ActionCursor marks a position in the journal, which is read in order of timestamp, and then of ID for actions with
+// the same timestamp. The zero ActionCursor is the start of the journal.
+type ActionCursor struct {
+	Timestamp time.Time
+	// ID is the last action read at Timestamp. If it is zero, every action at Timestamp has been read.
+	ID primitive.ObjectID
+}
+
+// IsZero returns true if c is the start of the journal.
+func (c ActionCursor) IsZero() bool {
+	return c.Timestamp.IsZero() && c.ID.IsZero()
+}
+
+// String encodes c as "<timestamp in ms>" or "<timestamp in ms>-<action id>", or "" for the start of the journal.
+func (c ActionCursor) String() string {
+	if c.IsZero() {
+		return ""
+	}
+
+	millis := strconv.FormatInt(c.Timestamp.UnixMilli(), 10)
+	if c.ID.IsZero() {
+		return millis
+	}
+
+	return millis + "-" + c.ID.Hex()
+}
+
+// ParseActionCursor decodes a cursor encoded by ActionCursor.String.
+func ParseActionCursor(s string) (ActionCursor, error) {
+	if s == "" {
+		return ActionCursor{}, nil
+	}
+
+	millisStr, idStr, hasID := strings.Cut(s, "-")
+
+	millis, err := strconv.ParseInt(millisStr, 10, 64)
+	if err != nil || millis < 0 {
+		return ActionCursor{}, wlerrors.Wrapf(ErrInvalidActionCursor, "[%s]", s)
+	}
+
+	cursor := ActionCursor{Timestamp: time.UnixMilli(millis)}
+
+	if hasID {
+		cursor.ID, err = primitive.ObjectIDFromHex(idStr)
+		if err != nil {
+			return ActionCursor{}, wlerrors.Wrapf(ErrInvalidActionCursor, "[%s]", s)
+		}
+	}
+
+	return cursor, nil
+}
+
+// GetActionFeedPage returns the actions in the journal after cursor, in the order they were taken, along with the
+// cursor to read the next page from and whether there are more actions after it. About limit actions are returned, but
+// a page never ends part way through an event, so the actions of a single event can always be applied together.
+func GetActionFeedPage(ctx context.Context, cursor ActionCursor, limit int) (actions []FileAction, next ActionCursor, hasMore bool, err error) {
+	col, err := db.GetCollection[any](ctx, FileHistoryCollectionKey)
+	if err != nil {
+		return nil, cursor, false, err
+	}
+
+	filter := bson.M{}
+
+	if !cursor.IsZero() {
+		filter["$or"] = bson.A{
+			bson.M{"timestamp": bson.M{"$gt": cursor.Timestamp}},
+			bson.M{"timestamp": cursor.Timestamp, "_id": bson.M{"$gt": cursor.ID}},
+		}
+	}
+
+	findOpts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}}).SetBatchSize(int32(max(limit, 1)))
+
+	dbCursor, err := col.Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, cursor, false, db.WrapError(err, "get action feed after [%s]", cursor)
+	}
+
+	defer dbCursor.Close(ctx) //nolint:errcheck
+
+	actions = []FileAction{}
+	next = cursor
+
+	for dbCursor.Next(ctx) {
+		var action FileAction
+
+		err = dbCursor.Decode(&action)
+		if err != nil {
+			return nil, cursor, false, db.WrapError(err, "decode action feed")
+		}
+
+		if len(actions) >= limit && action.EventID != actions[len(actions)-1].EventID {
+			return actions, next, true, nil
+		}
+
+		actions = append(actions, action)
+		next = ActionCursor{Timestamp: action.Timestamp, ID: action.ID}
+	}
+
+	if err = dbCursor.Err(); err != nil {
+		return nil, cursor, false, db.WrapError(err, "get action feed after [%s]", cursor)
+	}
+
+	return actions, next, false, nil
+}
@@ -0,0 +1,130 @@
+package history_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethanrous/weblens/models/db"
+	"github.com/ethanrous/weblens/models/history"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestActionCursor(t *testing.T) {
+	t.Run("start of journal", func(t *testing.T) {
+		assert.Equal(t, "", history.ActionCursor{}.String())
+
+		cursor, err := history.ParseActionCursor("")
+		require.NoError(t, err)
+		assert.True(t, cursor.IsZero())
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		cursor := history.ActionCursor{Timestamp: time.UnixMilli(1700000000123), ID: primitive.NewObjectID()}
+
+		parsed, err := history.ParseActionCursor(cursor.String())
+		require.NoError(t, err)
+		assert.True(t, cursor.Timestamp.Equal(parsed.Timestamp))
+		assert.Equal(t, cursor.ID, parsed.ID)
+	})
+
+	t.Run("timestamp only", func(t *testing.T) {
+		parsed, err := history.ParseActionCursor("1700000000123")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1700000000123), parsed.Timestamp.UnixMilli())
+		assert.True(t, parsed.ID.IsZero())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, s := range []string{"abc", "-1", "1700000000123-nothex"} {
+			_, err := history.ParseActionCursor(s)
+			assert.True(t, wlerrors.Is(err, history.ErrInvalidActionCursor), s)
+		}
+	})
+}
+
+func TestGetActionFeedPage(t *testing.T) {
+	ctx := db.SetupTestDB(t, history.FileHistoryCollectionKey, history.IndexModels...)
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+
+	// Two single action events, then a move event of three files that all share a timestamp, then one more action
+	eventIDs := []string{"e1", "e2", "move", "move", "move", "e3"}
+	saved := make([]history.FileAction, 0, len(eventIDs))
+
+	for i, eventID := range eventIDs {
+		offset := time.Duration(min(i, 2)) * time.Second
+		if i == len(eventIDs)-1 {
+			offset = 3 * time.Second
+		}
+
+		action := history.FileAction{
+			ID:         primitive.NewObjectID(),
+			ActionType: history.FileCreate,
+			FileID:     primitive.NewObjectID().Hex(),
+			Filepath:   wlfs.BuildFilePath("USERS", "alice/file.txt"),
+			EventID:    eventID,
+			TowerID:    "test-tower",
+			Timestamp:  start.Add(offset),
+		}
+		require.NoError(t, history.SaveAction(ctx, &action))
+
+		saved = append(saved, action)
+	}
+
+	t.Run("reads every action once in order", func(t *testing.T) {
+		var (
+			cursor history.ActionCursor
+			read   []primitive.ObjectID
+		)
+
+		for {
+			actions, next, hasMore, err := history.GetActionFeedPage(ctx, cursor, 2)
+			require.NoError(t, err)
+
+			for _, a := range actions {
+				read = append(read, a.ID)
+			}
+
+			cursor = next
+			if !hasMore {
+				break
+			}
+		}
+
+		require.Len(t, read, len(saved))
+
+		for i, a := range saved {
+			assert.Equal(t, a.ID, read[i])
+		}
+	})
+
+	t.Run("does not split events", func(t *testing.T) {
+		cursor := history.ActionCursor{Timestamp: saved[1].Timestamp, ID: saved[1].ID}
+
+		actions, next, hasMore, err := history.GetActionFeedPage(ctx, cursor, 1)
+		require.NoError(t, err)
+		require.Len(t, actions, 3)
+		assert.True(t, hasMore)
+
+		for _, a := range actions {
+			assert.Equal(t, "move", a.EventID)
+		}
+
+		assert.Equal(t, saved[4].ID, next.ID)
+	})
+
+	t.Run("end of journal", func(t *testing.T) {
+		last := saved[len(saved)-1]
+		cursor := history.ActionCursor{Timestamp: last.Timestamp, ID: last.ID}
+
+		actions, next, hasMore, err := history.GetActionFeedPage(ctx, cursor, 10)
+		require.NoError(t, err)
+		assert.Empty(t, actions)
+		assert.False(t, hasMore)
+		assert.Equal(t, cursor.String(), next.String())
+	})
+}
@@ -45,8 +45,9 @@ type FileAction struct {
 	TowerID         string         `bson:"towerID" json:"towerID"`
 	ContentID       string         `bson:"contentID,omitempty" json:"contentID"`
 	FileID          string         `bson:"fileID" json:"fileID"`
-	OldFileID       string         `bson:"oldFileID,omitempty" json:"oldFileID"` // Used for restore actions to reference the file being restored
-	Doer            string         `bson:"doer" json:"doer"`                     // The user or system that performed the action
+	OldFileID       string         `bson:"oldFileID,omitempty" json:"oldFileID"`       // Used for restore actions to reference the file being restored
+	OldContentID    string         `bson:"oldContentID,omitempty" json:"oldContentID"` // Used for revision actions to reference the content that was replaced
+	Doer            string         `bson:"doer" json:"doer"`                           // The user or system that performed the action
 
 	Size int64 `bson:"size" json:"size"`
 
@@ -59,6 +60,22 @@ type FileLifetime struct {
 	Actions []FileAction `bson:"actions"`
 }
 
+// ContentID returns the content the file of lt has now, which is that of its latest revision, or the content it was
+// created with if it has never been revised.
+func (lt FileLifetime) ContentID() string {
+	for i := len(lt.Actions) - 1; i >= 0; i-- {
+		if lt.Actions[i].ActionType == FileSizeChange {
+			return lt.Actions[i].ContentID
+		}
+	}
+
+	if len(lt.Actions) == 0 {
+		return ""
+	}
+
+	return lt.Actions[0].ContentID
+}
+
 // NewCreateAction creates a new FileAction representing a file creation event.
 func NewCreateAction(ctx context.Context, file *file_model.WeblensFileImpl) FileAction {
 	towerID := ctx.Value("towerID").(string)
@@ -151,6 +168,38 @@ func NewDeleteAction(ctx context.Context, file *file_model.WeblensFileImpl) File
 	}
 }
 
+// NewRevisionAction creates a new FileAction representing new content being written to an existing file, replacing
+// the content with oldContentID.
+func NewRevisionAction(ctx context.Context, file *file_model.WeblensFileImpl, oldContentID string) FileAction {
+	towerID := ctx.Value("towerID").(string)
+
+	eventID := ""
+	eventTime := time.Now()
+
+	event, ok := FileEventFromContext(ctx)
+	if ok {
+		eventID = event.EventID
+		eventTime = event.StartTime
+	} else {
+		eventID = primitive.NewObjectID().Hex()
+	}
+
+	return FileAction{
+		ActionType:   FileSizeChange,
+		ContentID:    file.GetContentID(),
+		OldContentID: oldContentID,
+		EventID:      eventID,
+		FileID:       file.ID(),
+		Filepath:     file.GetPortablePath(),
+		Size:         file.Size(),
+		Timestamp:    eventTime,
+		TowerID:      towerID,
+		Doer:         event.Doer,
+
+		file: file,
+	}
+}
+
 // NewRestoreAction creates a new FileAction representing a file restoration event.
 func NewRestoreAction(ctx context.Context, file *file_model.WeblensFileImpl, oldFileID string) FileAction {
 	towerID := ctx.Value("towerID").(string)
@@ -248,6 +297,8 @@ func (fa *FileAction) GetRelevantPath() wlfs.Filepath {
 		return fa.OriginPath
 	case FileRestore:
 		return fa.Filepath
+	case FileSizeChange:
+		return fa.Filepath
 	default:
 		return wlfs.Filepath{}
 	}
@@ -394,6 +445,21 @@ func GetActionsByTowerID(ctx context_mod.DatabaseContext, towerID string) ([]*Fi
 	return actions, nil
 }
 
+// DeleteActionsByTowerID removes every FileAction recorded for a specific towerID.
+func DeleteActionsByTowerID(ctx context.Context, towerID string) error {
+	col, err := db.GetCollection[any](ctx, FileHistoryCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = col.DeleteMany(ctx, bson.M{"towerID": towerID})
+	if err != nil {
+		return db.WrapError(err, "failed to delete actions for tower [%s]", towerID)
+	}
+
+	return nil
+}
+
 // GetActionAtFilepath retrieves the most recent FileAction for a given filepath.
 func GetActionAtFilepath(ctx context.Context, filepath wlfs.Filepath) (*FileAction, error) {
 	col, err := db.GetCollection[any](ctx, FileHistoryCollectionKey)
@@ -429,6 +495,52 @@ func GetLastActionByFileIDBefore(ctx context.Context, fileID string, ts time.Tim
 	return
 }
 
+// revisionActionTypes are the actions that give a file its content.
+var revisionActionTypes = bson.A{FileCreate, FileRestore, FileSizeChange}
+
+// GetRevisionActions returns the actions that gave the file with fileID each of its contents, newest first.
+func GetRevisionActions(ctx context.Context, fileID string) ([]FileAction, error) {
+	col, err := db.GetCollection[any](ctx, FileHistoryCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"fileID": fileID, "actionType": bson.M{"$in": revisionActionTypes}}
+
+	cursor, err := col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}}))
+	if err != nil {
+		return nil, db.WrapError(err, "get revisions of file [%s]", fileID)
+	}
+
+	actions := []FileAction{}
+
+	err = cursor.All(ctx, &actions)
+	if err != nil {
+		return nil, db.WrapError(err, "get revisions of file [%s]", fileID)
+	}
+
+	return actions, nil
+}
+
+// GetRevisionAction returns the action with actionID, if it gave the file with fileID new content.
+func GetRevisionAction(ctx context.Context, fileID string, actionID primitive.ObjectID) (FileAction, error) {
+	col, err := db.GetCollection[any](ctx, FileHistoryCollectionKey)
+	if err != nil {
+		return FileAction{}, err
+	}
+
+	filter := bson.M{"_id": actionID, "fileID": fileID, "actionType": bson.M{"$in": revisionActionTypes}}
+
+	var action FileAction
+
+	err = col.FindOne(ctx, filter).Decode(&action)
+	if err != nil {
+		return FileAction{}, db.WrapError(err, "get revision [%s] of file [%s]", actionID.Hex(), fileID)
+	}
+
+	return action, nil
+}
+
 // UpdateAction updates an existing FileAction in the database.
 func UpdateAction(ctx context.Context, action *FileAction) error {
 	if action.ID.IsZero() {
@@ -454,6 +566,8 @@ func UpdateAction(ctx context.Context, action *FileAction) error {
 type GetActionsOptions struct {
 	IncludeChildren bool
 	ActionTypes     []FileActionType
+	// TowerID limits the actions to those recorded by one tower, such as one of the cores kept on a backup
+	TowerID string
 }
 
 // GetActionsAtPathBefore retrieves FileActions at a path before a given timestamp, optionally including child paths. Only the first option struct is considered if multiple are provided.
@@ -498,6 +612,10 @@ func getActionsAtPath(ctx context.Context, path wlfs.Filepath, timestamp time.Ti
 		filter["actionType"] = bson.M{"$in": o.ActionTypes}
 	}
 
+	if o.TowerID != "" {
+		filter["towerID"] = o.TowerID
+	}
+
 	mongoOpts := options.Find().SetSort(bson.M{"timestamp": -1})
 
 	cursor, err := col.Find(ctx, filter, mongoOpts)
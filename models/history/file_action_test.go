@@ -0,0 +1,62 @@
+package history_test
+
+import (
+	"testing"
+
+	"github.com/ethanrous/weblens/models/db"
+	"github.com/ethanrous/weblens/models/history"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFileLifetimeContentID(t *testing.T) {
+	t.Run("never revised", func(t *testing.T) {
+		lt := history.FileLifetime{Actions: []history.FileAction{
+			{ActionType: history.FileCreate, ContentID: "v1"},
+			{ActionType: history.FileMove, ContentID: "v1"},
+		}}
+		assert.Equal(t, "v1", lt.ContentID())
+	})
+
+	t.Run("latest revision", func(t *testing.T) {
+		lt := history.FileLifetime{Actions: []history.FileAction{
+			{ActionType: history.FileCreate, ContentID: "v1"},
+			{ActionType: history.FileSizeChange, ContentID: "v2", OldContentID: "v1"},
+			{ActionType: history.FileSizeChange, ContentID: "v3", OldContentID: "v2"},
+			{ActionType: history.FileMove, ContentID: "v1"},
+		}}
+		assert.Equal(t, "v3", lt.ContentID())
+	})
+}
+
+func TestGetRevisionActions(t *testing.T) {
+	ctx := db.SetupTestDB(t, history.FileHistoryCollectionKey, history.IndexModels...)
+
+	fileID := primitive.NewObjectID().Hex()
+	create := saveAgedAction(ctx, t, history.FileCreate, fileID, "alice/doc.txt", "v1", 3*day)
+	saveAgedAction(ctx, t, history.FileMove, fileID, "alice/moved.txt", "v1", 2*day)
+	revision := saveAgedAction(ctx, t, history.FileSizeChange, fileID, "alice/moved.txt", "v2", day)
+
+	otherRevision := saveAgedAction(ctx, t, history.FileSizeChange, primitive.NewObjectID().Hex(), "alice/other.txt", "other", day)
+
+	t.Run("newest first", func(t *testing.T) {
+		revisions, err := history.GetRevisionActions(ctx, fileID)
+		require.NoError(t, err)
+		require.Len(t, revisions, 2)
+
+		assert.Equal(t, revision.ID, revisions[0].ID)
+		assert.Equal(t, create.ID, revisions[1].ID)
+	})
+
+	t.Run("by id", func(t *testing.T) {
+		got, err := history.GetRevisionAction(ctx, fileID, create.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "v1", got.ContentID)
+	})
+
+	t.Run("revision of another file", func(t *testing.T) {
+		_, err := history.GetRevisionAction(ctx, fileID, otherRevision.ID)
+		assert.True(t, db.IsNotFound(err))
+	})
+}
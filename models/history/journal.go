@@ -17,6 +17,7 @@ var fileIDIndexKey = "fileID_index"
 var filepathIndexKey = "filepath_index"
 var originPathIndexKey = "originPath_index"
 var destinationPathIndexKey = "destinationPath_index"
+var timestampIndexKey = "timestamp_index"
 
 // IndexModels defines MongoDB indexes for the fileHistory collection.
 var IndexModels = []mongo.IndexModel{
@@ -36,6 +37,10 @@ var IndexModels = []mongo.IndexModel{
 		Keys:    bson.D{{Key: "destinationPath", Value: 1}},
 		Options: options.Index().SetName(destinationPathIndexKey),
 	},
+	{
+		Keys:    bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}},
+		Options: options.Index().SetName(timestampIndexKey),
+	},
 }
 
 func init() {
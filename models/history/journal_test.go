@@ -186,3 +186,30 @@ func TestDoesFileExistInHistory_NoMatch(t *testing.T) {
 	require.NoError(t, err)
 	assert.Nil(t, action, "should return nil when no history exists")
 }
+
+func TestDeleteActionsByTowerID(t *testing.T) {
+	ctx := db.SetupTestDB(t, history.FileHistoryCollectionKey)
+
+	now := time.Now()
+	actions := []history.FileAction{
+		{ActionType: history.FileCreate, FileID: primitive.NewObjectID().Hex(), Filepath: wlfs.BuildFilePath("USERS", "testuser/a.txt"), TowerID: "restored-tower", Timestamp: now},
+		{ActionType: history.FileCreate, FileID: primitive.NewObjectID().Hex(), Filepath: wlfs.BuildFilePath("USERS", "testuser/b.txt"), TowerID: "restored-tower", Timestamp: now},
+		{ActionType: history.FileCreate, FileID: primitive.NewObjectID().Hex(), Filepath: wlfs.BuildFilePath("USERS", "testuser/c.txt"), TowerID: "other-tower", Timestamp: now},
+	}
+
+	require.NoError(t, history.SaveActions(ctx, actions))
+
+	err := history.DeleteActionsByTowerID(ctx, "restored-tower")
+	require.NoError(t, err)
+
+	col, err := db.GetCollection[any](ctx, history.FileHistoryCollectionKey)
+	require.NoError(t, err)
+
+	remaining, err := col.CountDocuments(ctx, bson.M{"towerID": "restored-tower"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), remaining)
+
+	other, err := col.CountDocuments(ctx, bson.M{"towerID": "other-tower"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), other)
+}
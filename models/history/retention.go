@@ -0,0 +1,186 @@
+package history
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethanrous/weblens/models/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RetentionPolicy describes how long the journal keeps the history of files.
+type RetentionPolicy struct {
+	// FullHistory is how long every action is kept. 0 keeps every action forever.
+	FullHistory time.Duration
+	// VersionHistory is how long the latest action at each path is kept, once it is older than FullHistory. It is
+	// never shorter than FullHistory. 0 keeps the latest action at each path forever.
+	VersionHistory time.Duration
+}
+
+// IsEnabled returns true if p ever lets actions expire.
+func (p RetentionPolicy) IsEnabled() bool {
+	return p.FullHistory > 0
+}
+
+// Cutoffs returns the time before which actions are no longer all kept, and the time before which not even the latest
+// action at a path is kept. versionCutoff is the zero time if the latest actions are kept forever.
+func (p RetentionPolicy) Cutoffs(now time.Time) (fullCutoff, versionCutoff time.Time) {
+	fullCutoff = now.Add(-p.FullHistory)
+
+	if p.VersionHistory > 0 {
+		versionCutoff = now.Add(-max(p.VersionHistory, p.FullHistory))
+	}
+
+	return fullCutoff, versionCutoff
+}
+
+// WalkExpiredActions calls fn with the actions that have outlived policy, at most batchSize at a time, so the expired
+// actions never have to be held in memory all at once. fn may delete the actions it is given. Actions newer than the
+// full history cutoff are always kept, and so is the latest action at each path newer than the version history cutoff.
+// The latest action of a file that still exists is never expired, however old, so the journal can always account for
+// every live file.
+func WalkExpiredActions(ctx context.Context, policy RetentionPolicy, now time.Time, batchSize int, fn func(batch []FileAction) error) error {
+	if !policy.IsEnabled() {
+		return nil
+	}
+
+	fullCutoff, versionCutoff := policy.Cutoffs(now)
+
+	col, err := db.GetCollection[any](ctx, FileHistoryCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	// Files with newer actions are accounted for by those, so none of their old actions has to be kept for them
+	recentFileIDs, err := col.GetCollection().Distinct(ctx, "fileID", bson.M{"timestamp": bson.M{"$gte": fullCutoff}})
+	if err != nil {
+		return db.WrapError(err, "get recently changed files")
+	}
+
+	seenFiles := make(map[string]struct{}, len(recentFileIDs))
+
+	for _, fileID := range recentFileIDs {
+		if id, ok := fileID.(string); ok {
+			seenFiles[id] = struct{}{}
+		}
+	}
+
+	cursor, err := col.Find(ctx, bson.M{"timestamp": bson.M{"$lt": fullCutoff}}, options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}}))
+	if err != nil {
+		return db.WrapError(err, "get expired actions")
+	}
+
+	defer cursor.Close(ctx) //nolint:errcheck
+
+	seenPaths := map[string]struct{}{}
+	batch := make([]FileAction, 0, batchSize)
+
+	for cursor.Next(ctx) {
+		var action FileAction
+
+		err = cursor.Decode(&action)
+		if err != nil {
+			return db.WrapError(err, "decode expired action")
+		}
+
+		_, seenFile := seenFiles[action.FileID]
+		seenFiles[action.FileID] = struct{}{}
+
+		if !seenFile && action.IsCreateIsh() {
+			continue
+		}
+
+		if !action.Timestamp.Before(versionCutoff) {
+			pathKey := action.TowerID + ":" + action.GetDestinationPath().ToPortable()
+
+			if _, seenPath := seenPaths[pathKey]; !seenPath {
+				seenPaths[pathKey] = struct{}{}
+
+				continue
+			}
+		}
+
+		batch = append(batch, action)
+
+		// The cursor has already moved past every action in the batch, so they can be removed without it skipping any
+		if len(batch) == batchSize {
+			err = fn(batch)
+			if err != nil {
+				return err
+			}
+
+			batch = batch[:0]
+		}
+	}
+
+	if err = cursor.Err(); err != nil {
+		return db.WrapError(err, "get expired actions")
+	}
+
+	if len(batch) != 0 {
+		return fn(batch)
+	}
+
+	return nil
+}
+
+// DeleteActions removes the given actions from the journal, and returns how many were removed.
+func DeleteActions(ctx context.Context, actions []FileAction) (int64, error) {
+	if len(actions) == 0 {
+		return 0, nil
+	}
+
+	col, err := db.GetCollection[any](ctx, FileHistoryCollectionKey)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(actions))
+	for _, a := range actions {
+		ids = append(ids, a.ID)
+	}
+
+	res, err := col.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, db.WrapError(err, "delete expired actions")
+	}
+
+	return res.DeletedCount, nil
+}
+
+// CountContentIDReferences returns how many actions in the journal reference each content ID.
+func CountContentIDReferences(ctx context.Context) (map[string]int, error) {
+	col, err := db.GetCollection[any](ctx, FileHistoryCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := bson.A{
+		bson.M{"$match": bson.M{"contentID": bson.M{"$exists": true, "$ne": ""}}},
+		bson.M{"$group": bson.M{"_id": "$contentID", "count": bson.M{"$sum": 1}}},
+	}
+
+	cursor, err := col.Aggregate(ctx, pipe)
+	if err != nil {
+		return nil, db.WrapError(err, "count content id references")
+	}
+
+	var counts []struct {
+		ContentID string `bson:"_id"`
+		Count     int    `bson:"count"`
+	}
+
+	err = cursor.All(ctx, &counts)
+	if err != nil {
+		return nil, db.WrapError(err, "count content id references")
+	}
+
+	refs := make(map[string]int, len(counts))
+	for _, c := range counts {
+		refs[c.ContentID] = c.Count
+	}
+
+	return refs, nil
+}
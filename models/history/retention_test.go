@@ -0,0 +1,143 @@
+package history_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethanrous/weblens/models/db"
+	"github.com/ethanrous/weblens/models/history"
+	"github.com/ethanrous/weblens/modules/wlfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const day = 24 * time.Hour
+
+// saveAgedAction saves an action on the file at path, taken age ago.
+func saveAgedAction(ctx context.Context, t *testing.T, actionType history.FileActionType, fileID, path, contentID string, age time.Duration) history.FileAction {
+	t.Helper()
+
+	action := history.FileAction{
+		ID:         primitive.NewObjectID(),
+		ActionType: actionType,
+		FileID:     fileID,
+		Filepath:   wlfs.BuildFilePath("USERS", path),
+		ContentID:  contentID,
+		EventID:    primitive.NewObjectID().Hex(),
+		TowerID:    "test-tower",
+		Timestamp:  time.Now().Add(-age),
+	}
+	require.NoError(t, history.SaveAction(ctx, &action))
+
+	return action
+}
+
+// getExpiredIDs returns the IDs of the actions that have outlived policy, walking them in small batches.
+func getExpiredIDs(ctx context.Context, t *testing.T, policy history.RetentionPolicy) []primitive.ObjectID {
+	t.Helper()
+
+	ids := []primitive.ObjectID{}
+
+	err := history.WalkExpiredActions(ctx, policy, time.Now(), 2, func(batch []history.FileAction) error {
+		assert.LessOrEqual(t, len(batch), 2)
+
+		for _, a := range batch {
+			ids = append(ids, a.ID)
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	return ids
+}
+
+func TestRetentionPolicyCutoffs(t *testing.T) {
+	now := time.Now()
+
+	t.Run("version history is never shorter than full history", func(t *testing.T) {
+		full, version := history.RetentionPolicy{FullHistory: 30 * day, VersionHistory: 7 * day}.Cutoffs(now)
+		assert.Equal(t, full, version)
+	})
+
+	t.Run("no version history keeps latest versions forever", func(t *testing.T) {
+		_, version := history.RetentionPolicy{FullHistory: 30 * day}.Cutoffs(now)
+		assert.True(t, version.IsZero())
+	})
+}
+
+func TestWalkExpiredActions(t *testing.T) {
+	ctx := db.SetupTestDB(t, history.FileHistoryCollectionKey, history.IndexModels...)
+
+	policy := history.RetentionPolicy{FullHistory: 30 * day, VersionHistory: 365 * day}
+
+	// A file that still exists, created long ago and moved recently
+	liveID := primitive.NewObjectID().Hex()
+	liveCreate := saveAgedAction(ctx, t, history.FileCreate, liveID, "alice/live.txt", "live-content", 400*day)
+	saveAgedAction(ctx, t, history.FileMove, liveID, "alice/moved.txt", "live-content", day)
+
+	// A file that still exists, untouched since long before the version history cutoff
+	oldID := primitive.NewObjectID().Hex()
+	oldCreate := saveAgedAction(ctx, t, history.FileCreate, oldID, "alice/old.txt", "old-content", 500*day)
+
+	// Two versions of a path, both deleted within the version history
+	v1ID := primitive.NewObjectID().Hex()
+	v1Create := saveAgedAction(ctx, t, history.FileCreate, v1ID, "alice/doc.txt", "doc-v1", 200*day)
+	v1Delete := saveAgedAction(ctx, t, history.FileDelete, v1ID, "alice/doc.txt", "doc-v1", 100*day)
+	v2ID := primitive.NewObjectID().Hex()
+	v2Create := saveAgedAction(ctx, t, history.FileCreate, v2ID, "alice/doc.txt", "doc-v2", 90*day)
+	v2Delete := saveAgedAction(ctx, t, history.FileDelete, v2ID, "alice/doc.txt", "doc-v2", 60*day)
+
+	// A file deleted before the version history cutoff
+	goneID := primitive.NewObjectID().Hex()
+	goneCreate := saveAgedAction(ctx, t, history.FileCreate, goneID, "alice/gone.txt", "gone", 450*day)
+	goneDelete := saveAgedAction(ctx, t, history.FileDelete, goneID, "alice/gone.txt", "gone", 400*day)
+
+	// A file deleted within the full history
+	recentID := primitive.NewObjectID().Hex()
+	saveAgedAction(ctx, t, history.FileCreate, recentID, "alice/recent.txt", "recent", 20*day)
+	saveAgedAction(ctx, t, history.FileDelete, recentID, "alice/recent.txt", "recent", 10*day)
+
+	t.Run("disabled policy expires nothing", func(t *testing.T) {
+		assert.Empty(t, getExpiredIDs(ctx, t, history.RetentionPolicy{}))
+	})
+
+	t.Run("compacts old history", func(t *testing.T) {
+		ids := getExpiredIDs(ctx, t, policy)
+		assert.ElementsMatch(t, []primitive.ObjectID{
+			liveCreate.ID, v1Create.ID, v1Delete.ID, v2Create.ID, goneCreate.ID, goneDelete.ID,
+		}, ids)
+
+		assert.NotContains(t, ids, oldCreate.ID, "latest action of a live file must be kept")
+		assert.NotContains(t, ids, v2Delete.ID, "latest version of a path must be kept")
+	})
+
+	t.Run("keeps latest versions forever without version history", func(t *testing.T) {
+		ids := getExpiredIDs(ctx, t, history.RetentionPolicy{FullHistory: 30 * day})
+		assert.Contains(t, ids, goneCreate.ID)
+		assert.NotContains(t, ids, goneDelete.ID)
+	})
+
+	t.Run("content references drop with the removed actions", func(t *testing.T) {
+		var removed int64
+
+		// Each batch is removed as it is walked, which must not cause any expired action to be skipped
+		err := history.WalkExpiredActions(ctx, policy, time.Now(), 2, func(batch []history.FileAction) error {
+			n, err := history.DeleteActions(ctx, batch)
+			removed += n
+
+			return err
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(6), removed)
+
+		refs, err := history.CountContentIDReferences(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, refs["gone"])
+		assert.Zero(t, refs["doc-v1"])
+		assert.Equal(t, 1, refs["doc-v2"])
+		assert.Equal(t, 1, refs["old-content"])
+	})
+}
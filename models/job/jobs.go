@@ -24,4 +24,12 @@ const (
 	RestoreCoreTask = "restore_core"
 	// ExtractAndEmbedTask is the task identifier for extracting file text and writing per-chunk embeddings.
 	ExtractAndEmbedTask = "extract_and_embed"
+	// PurgeTrashTask is the task identifier for permanently deleting items that have been in the trash past their retention.
+	PurgeTrashTask = "purge_trash"
+	// GCHistoryTask is the task identifier for compacting the file history and removing restore content it no longer references.
+	GCHistoryTask = "gc_history"
+	// CopyFilesTask is the task identifier for copying files and folders.
+	CopyFilesTask = "copy_files"
+	// FindDuplicatesTask is the task identifier for grouping files with the same, or similar looking, content.
+	FindDuplicatesTask = "find_duplicates"
 )
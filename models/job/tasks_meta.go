@@ -85,6 +85,11 @@ func (m IndexMeta) Verify() error {
 	return nil
 }
 
+// Serialize saves the scan metadata as a FileMetaRecord, so a directory scan can be resumed after a restart.
+func (m IndexMeta) Serialize() ([]byte, error) {
+	return serializeRecord(FileMetaRecord{FileID: m.File.ID(), ForceReIndex: m.ForceReIndex})
+}
+
 // ZipMeta holds metadata for zip file creation tasks.
 type ZipMeta struct {
 	Share     *share_model.FileShare
@@ -152,6 +157,140 @@ func (m ZipMeta) Verify() error {
 	return nil
 }
 
+// CopyFilesMeta holds metadata for file copy tasks.
+type CopyFilesMeta struct {
+	Requester   *user_model.User
+	Destination *file_model.WeblensFileImpl
+
+	Files []*file_model.WeblensFileImpl
+}
+
+// MetaString returns a string representation of the copy metadata.
+func (m CopyFilesMeta) MetaString() string {
+	ids := slices_mod.Map(
+		m.Files, func(f *file_model.WeblensFileImpl) string {
+			return f.ID()
+		},
+	)
+
+	slices.Sort(ids)
+
+	data := map[string]any{
+		"JobName":       CopyFilesTask,
+		"Files":         ids,
+		"DestinationID": m.Destination.ID(),
+		"Requester":     m.Requester.GetUsername(),
+	}
+
+	bs, err := json.Marshal(data)
+	if err != nil {
+		err = wlerrors.WithStack(err)
+		log.Error().Stack().Err(err).Msg("could not marshal copy files metadata")
+
+		return ""
+	}
+
+	return string(bs)
+}
+
+// FormatToResult converts the copy metadata to a task result.
+func (m CopyFilesMeta) FormatToResult() task.Result {
+	return task.Result{
+		"filenames": slices_mod.Map(
+			m.Files, func(f *file_model.WeblensFileImpl) string {
+				return f.GetPortablePath().Filename()
+			},
+		),
+		"destinationID": m.Destination.ID(),
+	}
+}
+
+// JobName returns the job name for file copy tasks.
+func (m CopyFilesMeta) JobName() string {
+	return CopyFilesTask
+}
+
+// Verify checks that the copy metadata contains all required fields.
+func (m CopyFilesMeta) Verify() error {
+	if len(m.Files) == 0 {
+		return wlerrors.New("no files in copy metadata")
+	} else if m.Destination == nil {
+		return wlerrors.New("no destination in copy metadata")
+	} else if m.Requester == nil {
+		return wlerrors.New("no requester in copy metadata")
+	}
+
+	return nil
+}
+
+// FindDuplicatesMeta holds metadata for duplicate finding tasks.
+type FindDuplicatesMeta struct {
+	Requester *user_model.User
+
+	// Roots are the folders searched for duplicates
+	Roots []*file_model.WeblensFileImpl
+
+	// Similar groups photos that look alike, rather than files with identical content
+	Similar bool
+
+	// Threshold is the cosine similarity at or above which two photos are alike. Only used if Similar is set.
+	Threshold float64
+}
+
+// MetaString returns a string representation of the duplicate finding metadata.
+func (m FindDuplicatesMeta) MetaString() string {
+	ids := slices_mod.Map(
+		m.Roots, func(f *file_model.WeblensFileImpl) string {
+			return f.ID()
+		},
+	)
+
+	slices.Sort(ids)
+
+	data := map[string]any{
+		"JobName":   FindDuplicatesTask,
+		"Roots":     ids,
+		"Similar":   m.Similar,
+		"Threshold": m.Threshold,
+		"Requester": m.Requester.GetUsername(),
+	}
+
+	bs, err := json.Marshal(data)
+	if err != nil {
+		err = wlerrors.WithStack(err)
+		log.Error().Stack().Err(err).Msg("could not marshal find duplicates metadata")
+
+		return ""
+	}
+
+	return string(bs)
+}
+
+// FormatToResult converts the duplicate finding metadata to a task result.
+func (m FindDuplicatesMeta) FormatToResult() task.Result {
+	return task.Result{
+		"similar": m.Similar,
+	}
+}
+
+// JobName returns the job name for duplicate finding tasks.
+func (m FindDuplicatesMeta) JobName() string {
+	return FindDuplicatesTask
+}
+
+// Verify checks that the duplicate finding metadata contains all required fields.
+func (m FindDuplicatesMeta) Verify() error {
+	if len(m.Roots) == 0 {
+		return wlerrors.New("no roots in find duplicates metadata")
+	} else if m.Requester == nil {
+		return wlerrors.New("no requester in find duplicates metadata")
+	} else if m.Similar && (m.Threshold <= 0 || m.Threshold > 1) {
+		return wlerrors.New("similarity threshold must be greater than 0, and at most 1")
+	}
+
+	return nil
+}
+
 // MoveMeta holds metadata for file move tasks.
 type MoveMeta struct {
 	User                *user_model.User
@@ -284,6 +423,84 @@ func (m FsStatMeta) Verify() error {
 	return nil
 }
 
+// PurgeTrashMeta holds metadata for trash purge tasks.
+type PurgeTrashMeta struct {
+	// Username limits the purge to the trash of a single user. If empty, the trash of every user is purged.
+	Username string
+}
+
+// MetaString returns a JSON string representation of the trash purge metadata.
+func (m PurgeTrashMeta) MetaString() string {
+	data := map[string]any{
+		"JobName":  PurgeTrashTask,
+		"Username": m.Username,
+	}
+
+	bs, err := json.Marshal(data)
+	if err != nil {
+		err = wlerrors.WithStack(err)
+		log.Error().Stack().Err(err).Msg("could not marshal trash purge metadata")
+
+		return ""
+	}
+
+	return string(bs)
+}
+
+// FormatToResult converts the trash purge metadata to a task result.
+func (m PurgeTrashMeta) FormatToResult() task.Result {
+	return task.Result{"username": m.Username}
+}
+
+// JobName returns the job name for trash purge tasks.
+func (m PurgeTrashMeta) JobName() string {
+	return PurgeTrashTask
+}
+
+// Verify checks that the trash purge metadata contains all required fields.
+func (m PurgeTrashMeta) Verify() error {
+	return nil
+}
+
+// GCHistoryMeta holds metadata for history garbage collection tasks.
+type GCHistoryMeta struct {
+	// DryRun reports what would be removed, without removing anything.
+	DryRun bool
+}
+
+// MetaString returns a JSON string representation of the history garbage collection metadata.
+func (m GCHistoryMeta) MetaString() string {
+	data := map[string]any{
+		"JobName": GCHistoryTask,
+		"DryRun":  m.DryRun,
+	}
+
+	bs, err := json.Marshal(data)
+	if err != nil {
+		err = wlerrors.WithStack(err)
+		log.Error().Stack().Err(err).Msg("could not marshal history gc metadata")
+
+		return ""
+	}
+
+	return string(bs)
+}
+
+// FormatToResult converts the history garbage collection metadata to a task result.
+func (m GCHistoryMeta) FormatToResult() task.Result {
+	return task.Result{"dryRun": m.DryRun}
+}
+
+// JobName returns the job name for history garbage collection tasks.
+func (m GCHistoryMeta) JobName() string {
+	return GCHistoryTask
+}
+
+// Verify checks that the history garbage collection metadata contains all required fields.
+func (m GCHistoryMeta) Verify() error {
+	return nil
+}
+
 // FileUploadProgress tracks the progress of a file upload operation.
 type FileUploadProgress struct {
 	Hash          hash.Hash
@@ -334,6 +551,11 @@ func (m BackupMeta) Verify() error {
 	return nil
 }
 
+// Serialize saves the backup metadata as a BackupMetaRecord, so a backup can be resumed after a restart.
+func (m BackupMeta) Serialize() ([]byte, error) {
+	return serializeRecord(BackupMetaRecord{CoreID: m.Core.TowerID})
+}
+
 // LoadFilesystemMeta holds metadata for filesystem loading tasks.
 type LoadFilesystemMeta struct {
 	File *file_model.WeblensFileImpl
@@ -511,6 +733,31 @@ func (m ExtractAndEmbedMeta) Verify() error {
 	return nil
 }
 
+// Serialize saves the extract-and-embed metadata as a FileMetaRecord, so the task can be resumed after a restart.
+func (m ExtractAndEmbedMeta) Serialize() ([]byte, error) {
+	return serializeRecord(FileMetaRecord{FileID: m.File.ID(), ForceReIndex: m.ForceReIndex})
+}
+
+// FileMetaRecord is the saved form of the metadata of durable tasks which work on a single file.
+type FileMetaRecord struct {
+	FileID       string `json:"fileID"`
+	ForceReIndex bool   `json:"forceReIndex"`
+}
+
+// BackupMetaRecord is the saved form of BackupMeta.
+type BackupMetaRecord struct {
+	CoreID string `json:"coreID"`
+}
+
+func serializeRecord(record any) ([]byte, error) {
+	bs, err := json.Marshal(record)
+	if err != nil {
+		return nil, wlerrors.WithStack(err)
+	}
+
+	return bs, nil
+}
+
 // TaskStage represents a single stage in a multi-stage task.
 type TaskStage struct {
 	Key      string `json:"key"`
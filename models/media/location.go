@@ -0,0 +1,301 @@
+package media
+
+import (
+	"context"
+	"math"
+	"net/http"
+
+	"github.com/ethanrous/weblens/models/db"
+	"github.com/ethanrous/weblens/modules/config"
+	"github.com/ethanrous/weblens/modules/startup"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlog"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// earthRadiusMeters is the radius MongoDB uses to convert distances to radians for spherical queries.
+const earthRadiusMeters = 6_378_100
+
+// clusterCellsPerTile is how many clusters fit across one map tile, at any zoom level.
+const clusterCellsPerTile = 4
+
+// MaxMapZoom is the deepest zoom level media can be clustered at.
+const MaxMapZoom = 22
+
+// ErrInvalidGeoFilter is returned when a location filter is not a valid area.
+var ErrInvalidGeoFilter = wlerrors.Statusf(http.StatusBadRequest, "invalid location filter")
+
+func init() {
+	startup.RegisterHook(backfillGeoLocations)
+}
+
+// GeoPoint is a GeoJSON point. Unlike Media.Location, its coordinates are in [longitude, latitude] order.
+type GeoPoint struct {
+	Type        string     `bson:"type"`
+	Coordinates [2]float64 `bson:"coordinates"`
+}
+
+// newGeoPoint returns the GeoJSON point of a [latitude, longitude] location, or nil if the media has no valid location.
+func newGeoPoint(location [2]float64) *GeoPoint {
+	lat, lng := location[0], location[1]
+	if (lat == 0 && lng == 0) || !isValidLatLng(lat, lng) {
+		return nil
+	}
+
+	return &GeoPoint{Type: "Point", Coordinates: [2]float64{lng, lat}}
+}
+
+// GeoBounds is a box between two latitudes and two longitudes. West may be greater than East for a box that crosses
+// the antimeridian.
+type GeoBounds struct {
+	South float64
+	West  float64
+	North float64
+	East  float64
+}
+
+// Validate returns ErrInvalidGeoFilter if b is not a valid box.
+func (b GeoBounds) Validate() error {
+	if !isValidLatLng(b.South, b.West) || !isValidLatLng(b.North, b.East) || b.South > b.North {
+		return wlerrors.Errorf("%w: bounds [%v, %v, %v, %v] are not a valid box", ErrInvalidGeoFilter, b.South, b.West, b.North, b.East)
+	}
+
+	return nil
+}
+
+func (b GeoBounds) filter() bson.M {
+	lat := bson.M{"geoLocation.coordinates.1": bson.M{"$gte": b.South, "$lte": b.North}}
+
+	if b.West <= b.East {
+		return bson.M{"$and": bson.A{lat, bson.M{"geoLocation.coordinates.0": bson.M{"$gte": b.West, "$lte": b.East}}}}
+	}
+
+	return bson.M{"$and": bson.A{lat, bson.M{"$or": bson.A{
+		bson.M{"geoLocation.coordinates.0": bson.M{"$gte": b.West}},
+		bson.M{"geoLocation.coordinates.0": bson.M{"$lte": b.East}},
+	}}}}
+}
+
+// GeoFilter limits media to those taken within an area, either a circle or a polygon. The zero value matches all media,
+// with or without a location.
+type GeoFilter struct {
+	// Center and RadiusMeters describe a circle. Center is a [latitude, longitude] pair, like Media.Location.
+	Center       [2]float64
+	RadiusMeters float64
+
+	// Polygon is a list of [latitude, longitude] vertices. It is closed automatically.
+	Polygon [][2]float64
+}
+
+// IsEmpty returns true if f does not limit media to an area.
+func (f GeoFilter) IsEmpty() bool {
+	return f.RadiusMeters == 0 && len(f.Polygon) == 0
+}
+
+// Validate returns ErrInvalidGeoFilter if f is not a valid circle or polygon.
+func (f GeoFilter) Validate() error {
+	if f.RadiusMeters != 0 && len(f.Polygon) != 0 {
+		return wlerrors.Errorf("%w: radius and polygon cannot be combined", ErrInvalidGeoFilter)
+	}
+
+	if f.RadiusMeters < 0 || (f.RadiusMeters > 0 && !isValidLatLng(f.Center[0], f.Center[1])) {
+		return wlerrors.Errorf("%w: radius of %vm around %v", ErrInvalidGeoFilter, f.RadiusMeters, f.Center)
+	}
+
+	if len(f.Polygon) == 0 {
+		return nil
+	}
+
+	if len(f.Polygon) < 3 {
+		return wlerrors.Errorf("%w: polygon needs at least 3 points", ErrInvalidGeoFilter)
+	}
+
+	for _, point := range f.Polygon {
+		if !isValidLatLng(point[0], point[1]) {
+			return wlerrors.Errorf("%w: polygon point %v", ErrInvalidGeoFilter, point)
+		}
+	}
+
+	return nil
+}
+
+// geoWithin returns the query on the geoLocation field that matches media inside f.
+func (f GeoFilter) geoWithin() bson.M {
+	if f.RadiusMeters > 0 {
+		return bson.M{"$geoWithin": bson.M{
+			"$centerSphere": bson.A{bson.A{f.Center[1], f.Center[0]}, f.RadiusMeters / earthRadiusMeters},
+		}}
+	}
+
+	ring := make(bson.A, 0, len(f.Polygon)+1)
+	for _, point := range f.Polygon {
+		ring = append(ring, bson.A{point[1], point[0]})
+	}
+
+	if f.Polygon[0] != f.Polygon[len(f.Polygon)-1] {
+		ring = append(ring, bson.A{f.Polygon[0][1], f.Polygon[0][0]})
+	}
+
+	return bson.M{"$geoWithin": bson.M{
+		"$geometry": bson.M{"type": "Polygon", "coordinates": bson.A{ring}},
+	}}
+}
+
+// MediaCluster is a group of media taken close to each other, as seen at one map zoom level.
+type MediaCluster struct {
+	// Lat and Lng are the average location of the media in the cluster.
+	Lat   float64 `bson:"lat"`
+	Lng   float64 `bson:"lng"`
+	Count int     `bson:"count"`
+	// CoverID is the content ID of the newest media in the cluster.
+	CoverID ContentID `bson:"coverID"`
+}
+
+// MediaClusterOptions specifies which media are clustered by GetMediaClusters.
+type MediaClusterOptions struct {
+	Bounds GeoBounds
+	Zoom   int
+
+	// Owner limits the clusters to media owned by this user, if set.
+	Owner string
+	// ContentIDs limits the clusters to these media, if not nil.
+	ContentIDs []ContentID
+
+	IncludeHidden bool
+	// HiddenOwner, if set, limits the hidden media included by IncludeHidden to those owned by this user.
+	HiddenOwner string
+	IncludeRaw  bool
+}
+
+// ClusterCellDegrees returns the size, in degrees, of the grid cells media are clustered into at a map zoom level.
+func ClusterCellDegrees(zoom int) float64 {
+	zoom = max(0, min(zoom, MaxMapZoom))
+
+	return 360 / (math.Exp2(float64(zoom)) * clusterCellsPerTile)
+}
+
+// GetMediaClusters groups the media with a location inside the bounds of opts into a grid sized for the zoom level of
+// opts, and returns one cluster for each cell of the grid that has media in it.
+func GetMediaClusters(ctx context.Context, opts MediaClusterOptions) ([]MediaCluster, error) {
+	if err := opts.Bounds.Validate(); err != nil {
+		return nil, err
+	}
+
+	match := bson.M{
+		"$and": bson.A{
+			opts.Bounds.filter(),
+			mediaFilter(opts.IncludeRaw, opts.IncludeHidden),
+		},
+	}
+
+	if opts.Owner != "" {
+		match["owner"] = opts.Owner
+	}
+
+	if opts.ContentIDs != nil {
+		match["contentID"] = bson.M{"$in": opts.ContentIDs}
+	}
+
+	if opts.IncludeHidden && opts.HiddenOwner != "" {
+		match["$or"] = bson.A{bson.M{"hidden": false}, bson.M{"owner": opts.HiddenOwner}}
+	}
+
+	cellDegrees := ClusterCellDegrees(opts.Zoom)
+	lng := bson.M{"$arrayElemAt": bson.A{"$geoLocation.coordinates", 0}}
+	lat := bson.M{"$arrayElemAt": bson.A{"$geoLocation.coordinates", 1}}
+
+	pipe := bson.A{
+		bson.M{"$match": match},
+		bson.M{"$sort": bson.M{"createDate": -1}},
+		bson.M{"$group": bson.M{
+			"_id": bson.M{
+				"x": bson.M{"$floor": bson.M{"$divide": bson.A{lng, cellDegrees}}},
+				"y": bson.M{"$floor": bson.M{"$divide": bson.A{lat, cellDegrees}}},
+			},
+			"lat":     bson.M{"$avg": lat},
+			"lng":     bson.M{"$avg": lng},
+			"count":   bson.M{"$sum": 1},
+			"coverID": bson.M{"$first": "$contentID"},
+		}},
+		bson.M{"$sort": bson.M{"count": -1}},
+	}
+
+	col, err := db.GetCollection[any](ctx, MediaCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := col.Aggregate(ctx, pipe)
+	if err != nil {
+		return nil, db.WrapError(err, "cluster media")
+	}
+
+	clusters := []MediaCluster{}
+
+	err = cur.All(ctx, &clusters)
+	if err != nil {
+		return nil, db.WrapError(err, "cluster media")
+	}
+
+	return clusters, nil
+}
+
+// mediaFilter matches media that have a file, leaving out raws and hidden media unless asked for.
+func mediaFilter(includeRaw, includeHidden bool) bson.M {
+	filter := bson.M{"fileIDs": bson.M{"$exists": true, "$ne": bson.A{}}}
+
+	if !includeRaw {
+		filter["mimeType"] = bson.M{"$not": bson.M{"$in": rawMimes()}}
+	}
+
+	if !includeHidden {
+		filter["hidden"] = false
+	}
+
+	return filter
+}
+
+func isValidLatLng(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
+
+// backfillGeoLocations runs BackfillGeoLocations on startup, so media saved before the map existed can be shown on it.
+func backfillGeoLocations(ctx context.Context, _ config.Provider) error {
+	backfilled, err := BackfillGeoLocations(ctx)
+	if err != nil {
+		return err
+	}
+
+	if backfilled != 0 {
+		wlog.FromContext(ctx).Info().Msgf("Backfilled the geo location of %d media", backfilled)
+	}
+
+	return nil
+}
+
+// BackfillGeoLocations gives media saved before they were indexed by location a GeoJSON point to be indexed by, set
+// from their existing location, and returns how many were updated. Once every media has one, it does nothing.
+func BackfillGeoLocations(ctx context.Context) (int64, error) {
+	col, err := db.GetCollection[any](ctx, MediaCollectionKey)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := col.UpdateMany(ctx,
+		bson.M{
+			"geoLocation": bson.M{"$exists": false},
+			"location":    bson.M{"$ne": bson.A{0.0, 0.0}},
+			"location.0":  bson.M{"$gte": -90, "$lte": 90},
+			"location.1":  bson.M{"$gte": -180, "$lte": 180},
+		},
+		bson.A{bson.M{"$set": bson.M{"geoLocation": bson.M{
+			"type":        "Point",
+			"coordinates": bson.A{bson.M{"$arrayElemAt": bson.A{"$location", 1}}, bson.M{"$arrayElemAt": bson.A{"$location", 0}}},
+		}}}},
+	)
+	if err != nil {
+		return 0, db.WrapError(err, "backfill media geo locations")
+	}
+
+	return res.ModifiedCount, nil
+}
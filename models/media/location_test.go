@@ -0,0 +1,237 @@
+package media_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethanrous/weblens/models/db"
+	"github.com/ethanrous/weblens/models/media"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// saveGeoMedia saves an image with a file, taken at a [latitude, longitude] location.
+func saveGeoMedia(ctx context.Context, t *testing.T, contentID, owner string, location [2]float64) *media.Media {
+	t.Helper()
+
+	m := newTestMedia(contentID, owner)
+	m.MimeType = "image/jpeg"
+	m.FileIDs = []string{contentID + "-file"}
+	m.Location = location
+	require.NoError(t, media.SaveMedia(ctx, m))
+
+	return m
+}
+
+func TestSaveMediaGeoLocation(t *testing.T) {
+	ctx := db.SetupTestDB(t, media.MediaCollectionKey, media.IndexModels...)
+
+	t.Run("stores longitude first", func(t *testing.T) {
+		m := saveGeoMedia(ctx, t, "nyc", "alice", [2]float64{40.7128, -74.006})
+
+		require.NotNil(t, m.GeoLocation)
+		assert.Equal(t, "Point", m.GeoLocation.Type)
+		assert.Equal(t, [2]float64{-74.006, 40.7128}, m.GeoLocation.Coordinates)
+	})
+
+	t.Run("no location", func(t *testing.T) {
+		m := saveGeoMedia(ctx, t, "nowhere", "alice", [2]float64{0, 0})
+		assert.Nil(t, m.GeoLocation)
+	})
+
+	t.Run("invalid location is not indexed", func(t *testing.T) {
+		m := saveGeoMedia(ctx, t, "invalid", "alice", [2]float64{140.7128, -74.006})
+		assert.Nil(t, m.GeoLocation)
+	})
+}
+
+func TestGeoFilterValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  media.GeoFilter
+		wantErr bool
+	}{
+		{name: "empty", filter: media.GeoFilter{}},
+		{name: "radius", filter: media.GeoFilter{Center: [2]float64{40.7, -74}, RadiusMeters: 1000}},
+		{name: "triangle", filter: media.GeoFilter{Polygon: [][2]float64{{40, -74}, {41, -74}, {41, -73}}}},
+		{name: "negative radius", filter: media.GeoFilter{RadiusMeters: -1}, wantErr: true},
+		{name: "center out of range", filter: media.GeoFilter{Center: [2]float64{91, 0}, RadiusMeters: 1000}, wantErr: true},
+		{name: "too few points", filter: media.GeoFilter{Polygon: [][2]float64{{40, -74}, {41, -74}}}, wantErr: true},
+		{
+			name:    "radius and polygon",
+			filter:  media.GeoFilter{RadiusMeters: 1000, Polygon: [][2]float64{{40, -74}, {41, -74}, {41, -73}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.filter.Validate()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, media.ErrInvalidGeoFilter)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGetPagedMediasInArea(t *testing.T) {
+	ctx := db.SetupTestDB(t, media.MediaCollectionKey, media.IndexModels...)
+
+	saveGeoMedia(ctx, t, "manhattan", "alice", [2]float64{40.7831, -73.9712})
+	saveGeoMedia(ctx, t, "brooklyn", "alice", [2]float64{40.6782, -73.9442})
+	saveGeoMedia(ctx, t, "paris", "alice", [2]float64{48.8566, 2.3522})
+	saveGeoMedia(ctx, t, "nowhere", "alice", [2]float64{0, 0})
+
+	allIDs := []string{"manhattan", "brooklyn", "paris", "nowhere"}
+
+	t.Run("radius", func(t *testing.T) {
+		geo := media.GeoFilter{Center: [2]float64{40.7831, -73.9712}, RadiusMeters: 5_000}
+
+		got, err := media.GetPagedMediasInArea(ctx, geo, 100, 0, 1, true, allIDs...)
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		assert.Equal(t, "manhattan", got[0].ID())
+
+		count, err := media.CountMediasInArea(ctx, geo, true, allIDs...)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("polygon", func(t *testing.T) {
+		// Around New York City
+		geo := media.GeoFilter{Polygon: [][2]float64{{40.5, -74.3}, {40.9, -74.3}, {40.9, -73.7}, {40.5, -73.7}}}
+
+		got, err := media.GetPagedMediasInArea(ctx, geo, 100, 0, 1, true, allIDs...)
+		require.NoError(t, err)
+		assert.Len(t, got, 2)
+	})
+
+	t.Run("no filter includes media without location", func(t *testing.T) {
+		got, err := media.GetPagedMediasInArea(ctx, media.GeoFilter{}, 100, 0, 1, true, allIDs...)
+		require.NoError(t, err)
+		assert.Len(t, got, 4)
+	})
+}
+
+func TestBackfillGeoLocations(t *testing.T) {
+	ctx := db.SetupTestDB(t, media.MediaCollectionKey, media.IndexModels...)
+
+	saveGeoMedia(ctx, t, "before-map", "alice", [2]float64{40.7831, -73.9712})
+	saveGeoMedia(ctx, t, "no-location", "alice", [2]float64{0, 0})
+
+	// Media saved before the map existed have a location, but no GeoJSON point
+	col, err := db.GetCollection[any](ctx, media.MediaCollectionKey)
+	require.NoError(t, err)
+
+	_, err = col.UpdateMany(ctx, bson.M{}, bson.M{"$unset": bson.M{"geoLocation": ""}})
+	require.NoError(t, err)
+
+	geo := media.GeoFilter{Center: [2]float64{40.7831, -73.9712}, RadiusMeters: 5_000}
+
+	count, err := media.CountMediasInArea(ctx, geo, true, "before-map", "no-location")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	backfilled, err := media.BackfillGeoLocations(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), backfilled)
+
+	count, err = media.CountMediasInArea(ctx, geo, true, "before-map", "no-location")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	got, err := media.GetMediaByContentID(ctx, "no-location")
+	require.NoError(t, err)
+	assert.Nil(t, got.GeoLocation)
+
+	backfilled, err = media.BackfillGeoLocations(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, backfilled)
+}
+
+func TestGetMediaClusters(t *testing.T) {
+	ctx := db.SetupTestDB(t, media.MediaCollectionKey, media.IndexModels...)
+
+	saveGeoMedia(ctx, t, "manhattan", "alice", [2]float64{40.7831, -73.9712})
+	saveGeoMedia(ctx, t, "brooklyn", "alice", [2]float64{40.6782, -73.9442})
+	saveGeoMedia(ctx, t, "paris", "alice", [2]float64{48.8566, 2.3522})
+	saveGeoMedia(ctx, t, "bob-nyc", "bob", [2]float64{40.7, -74})
+
+	hidden := saveGeoMedia(ctx, t, "hidden", "alice", [2]float64{40.7, -74})
+	hidden.Hidden = true
+	require.NoError(t, media.SaveMedia(ctx, hidden))
+
+	world := media.GeoBounds{South: -90, West: -180, North: 90, East: 180}
+
+	t.Run("zoomed out groups nearby media", func(t *testing.T) {
+		clusters, err := media.GetMediaClusters(ctx, media.MediaClusterOptions{Bounds: world, Zoom: 2, Owner: "alice"})
+		require.NoError(t, err)
+		require.Len(t, clusters, 2)
+
+		assert.Equal(t, 2, clusters[0].Count)
+		assert.InDelta(t, 40.73, clusters[0].Lat, 0.01)
+		assert.Equal(t, 1, clusters[1].Count)
+		assert.Equal(t, "paris", clusters[1].CoverID)
+	})
+
+	t.Run("zoomed in separates media", func(t *testing.T) {
+		clusters, err := media.GetMediaClusters(ctx, media.MediaClusterOptions{Bounds: world, Zoom: 14, Owner: "alice"})
+		require.NoError(t, err)
+		assert.Len(t, clusters, 3)
+	})
+
+	t.Run("bounds", func(t *testing.T) {
+		europe := media.GeoBounds{South: 35, West: -10, North: 60, East: 30}
+
+		clusters, err := media.GetMediaClusters(ctx, media.MediaClusterOptions{Bounds: europe, Zoom: 2, Owner: "alice"})
+		require.NoError(t, err)
+		require.Len(t, clusters, 1)
+		assert.Equal(t, "paris", clusters[0].CoverID)
+	})
+
+	t.Run("bounds across antimeridian", func(t *testing.T) {
+		pacific := media.GeoBounds{South: -90, West: 170, North: 90, East: -70}
+
+		clusters, err := media.GetMediaClusters(ctx, media.MediaClusterOptions{Bounds: pacific, Zoom: 2, Owner: "alice"})
+		require.NoError(t, err)
+		require.Len(t, clusters, 1)
+		assert.Equal(t, 2, clusters[0].Count)
+	})
+
+	t.Run("hidden media", func(t *testing.T) {
+		clusters, err := media.GetMediaClusters(ctx, media.MediaClusterOptions{Bounds: world, Zoom: 2, Owner: "alice", IncludeHidden: true})
+		require.NoError(t, err)
+		require.NotEmpty(t, clusters)
+		assert.Equal(t, 3, clusters[0].Count)
+	})
+
+	t.Run("hidden media of other users through a share", func(t *testing.T) {
+		// A share gives access to media by content ID, and only the owner may see what they have hidden
+		shared := []string{"hidden", "bob-nyc"}
+
+		clusters, err := media.GetMediaClusters(ctx, media.MediaClusterOptions{Bounds: world, Zoom: 2, ContentIDs: shared, IncludeHidden: true, HiddenOwner: "bob"})
+		require.NoError(t, err)
+		require.Len(t, clusters, 1)
+		assert.Equal(t, 1, clusters[0].Count)
+		assert.Equal(t, "bob-nyc", clusters[0].CoverID)
+
+		clusters, err = media.GetMediaClusters(ctx, media.MediaClusterOptions{Bounds: world, Zoom: 2, ContentIDs: shared, IncludeHidden: true, HiddenOwner: "alice"})
+		require.NoError(t, err)
+		require.Len(t, clusters, 1)
+		assert.Equal(t, 2, clusters[0].Count)
+	})
+
+	t.Run("content ids", func(t *testing.T) {
+		clusters, err := media.GetMediaClusters(ctx, media.MediaClusterOptions{Bounds: world, Zoom: 2, ContentIDs: []string{"bob-nyc", "paris"}})
+		require.NoError(t, err)
+		assert.Len(t, clusters, 2)
+	})
+
+	t.Run("invalid bounds", func(t *testing.T) {
+		_, err := media.GetMediaClusters(ctx, media.MediaClusterOptions{Bounds: media.GeoBounds{South: 10, North: -10}})
+		assert.ErrorIs(t, err, media.ErrInvalidGeoFilter)
+	})
+}
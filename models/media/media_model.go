@@ -21,6 +21,7 @@ import (
 var contentIDIndexKey = "contentID_unique_index"
 var ownerIndexKey = "owner_index"
 var fileIDsIndex = "fileIDs_index"
+var geoLocationIndexKey = "geoLocation_2dsphere_index"
 
 // IndexModels defines MongoDB indexes for the media collection.
 // Excludes the vector search index which requires the `search` index method instead.
@@ -37,6 +38,10 @@ var IndexModels = []mongo.IndexModel{
 		Keys:    bson.D{{Key: "fileIDs", Value: 1}},
 		Options: options.Index().SetName(fileIDsIndex),
 	},
+	{
+		Keys:    bson.D{{Key: "geoLocation", Value: "2dsphere"}},
+		Options: options.Index().SetName(geoLocationIndexKey),
+	},
 }
 
 func init() {
@@ -65,6 +70,9 @@ var ErrInvalidQuality = wlerrors.Errorf("invalid media quality")
 type Media struct {
 	CreateDate time.Time `bson:"createDate"`
 
+	// When the media was last saved. Changes to which files the media is made from alone do not count.
+	Updated time.Time `bson:"updated"`
+
 	// WEBP thumbnail cache fileId
 	lowresCacheFile *file_model.WeblensFileImpl
 
@@ -83,6 +91,10 @@ type Media struct {
 	// Location of the media, if available. This is a slice of two floats, representing the coordinates of the media.
 	Location [2]float64 `bson:"location"`
 
+	// GeoLocation is Location as a GeoJSON point, for geospatial queries. It is set from Location when the media is saved,
+	// and is nil if the media has no location.
+	GeoLocation *GeoPoint `bson:"geoLocation,omitempty"`
+
 	// Slices of files whos content hash to the contentId
 	FileIDs []string `bson:"fileIDs"`
 
@@ -127,6 +139,9 @@ func SaveMedia(ctx context.Context, media *Media) error {
 		media.MediaID = primitive.NewObjectID()
 	}
 
+	media.GeoLocation = newGeoPoint(media.Location)
+	media.Updated = time.Now()
+
 	col, err := db.GetCollection[any](ctx, MediaCollectionKey)
 	if err != nil {
 		return err
@@ -159,6 +174,12 @@ func GetMediaByContentID(ctx context.Context, contentID ContentID) (*Media, erro
 
 // GetPagedMedias retrieves multiple media items by their content IDs with pagination.
 func GetPagedMedias(ctx context.Context, limit, page, sortDirection int, includeRaw bool, contentIDs ...ContentID) ([]*Media, error) {
+	return GetPagedMediasInArea(ctx, GeoFilter{}, limit, page, sortDirection, includeRaw, contentIDs...)
+}
+
+// GetPagedMediasInArea retrieves multiple media items by their content IDs with pagination, keeping only those taken
+// inside the area of geo.
+func GetPagedMediasInArea(ctx context.Context, geo GeoFilter, limit, page, sortDirection int, includeRaw bool, contentIDs ...ContentID) ([]*Media, error) {
 	col, err := db.GetCollection[any](ctx, MediaCollectionKey)
 	if err != nil {
 		return nil, err
@@ -166,10 +187,7 @@ func GetPagedMedias(ctx context.Context, limit, page, sortDirection int, include
 
 	media := []*Media{}
 
-	filter := bson.M{"contentID": bson.M{"$in": contentIDs}, "duration": bson.M{"$eq": 0}}
-	if !includeRaw {
-		filter["mimeType"] = bson.M{"$not": bson.M{"$in": rawMimes()}}
-	}
+	filter := pagedMediaFilter(geo, includeRaw, contentIDs)
 
 	cur, err := col.Find(ctx, filter, options.Find().SetLimit(int64(limit)).SetSkip(int64(page*limit)).SetSort(bson.D{{Key: "createDate", Value: sortDirection}}))
 	if err != nil {
@@ -184,6 +202,34 @@ func GetPagedMedias(ctx context.Context, limit, page, sortDirection int, include
 	return media, nil
 }
 
+// CountMediasInArea counts the media GetPagedMediasInArea would page through.
+func CountMediasInArea(ctx context.Context, geo GeoFilter, includeRaw bool, contentIDs ...ContentID) (int, error) {
+	col, err := db.GetCollection[any](ctx, MediaCollectionKey)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := col.CountDocuments(ctx, pagedMediaFilter(geo, includeRaw, contentIDs))
+	if err != nil {
+		return 0, db.WrapError(err, "count media by contentIds")
+	}
+
+	return int(count), nil
+}
+
+func pagedMediaFilter(geo GeoFilter, includeRaw bool, contentIDs []ContentID) bson.M {
+	filter := bson.M{"contentID": bson.M{"$in": contentIDs}, "duration": bson.M{"$eq": 0}}
+	if !includeRaw {
+		filter["mimeType"] = bson.M{"$not": bson.M{"$in": rawMimes()}}
+	}
+
+	if !geo.IsEmpty() {
+		filter["geoLocation"] = geo.geoWithin()
+	}
+
+	return filter
+}
+
 // GetMediasByContentIDs retrieves multiple media items by their content IDs.
 func GetMediasByContentIDs(ctx context.Context, contentIDs ...ContentID) ([]*Media, error) {
 	col, err := db.GetCollection[*Media](ctx, MediaCollectionKey)
@@ -254,6 +300,58 @@ func GetMediaByOwner(ctx context.Context, owner string) ([]*Media, error) {
 	return media, nil
 }
 
+// GetMediaUpdatedSince retrieves media items saved after since. A zero since retrieves every media item, including those
+// saved before it was recorded when they were.
+func GetMediaUpdatedSince(ctx context.Context, since time.Time) ([]*Media, error) {
+	col, err := db.GetCollection[any](ctx, MediaCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	media := []*Media{}
+
+	cur, err := col.Find(ctx, db.UpdatedSinceFilter(since))
+	if err != nil {
+		return nil, db.WrapError(err, "get media updated since %s", since)
+	}
+
+	err = cur.All(ctx, &media)
+	if err != nil {
+		return nil, db.WrapError(err, "get media updated since %s", since)
+	}
+
+	return media, nil
+}
+
+// GetAllContentIDs retrieves the content IDs of every media item.
+func GetAllContentIDs(ctx context.Context) ([]ContentID, error) {
+	col, err := db.GetCollection[any](ctx, MediaCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := col.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"contentID": 1}))
+	if err != nil {
+		return nil, db.WrapError(err, "get all content ids")
+	}
+
+	var media []struct {
+		ContentID ContentID `bson:"contentID"`
+	}
+
+	err = cur.All(ctx, &media)
+	if err != nil {
+		return nil, db.WrapError(err, "get all content ids")
+	}
+
+	contentIDs := make([]ContentID, 0, len(media))
+	for _, m := range media {
+		contentIDs = append(contentIDs, m.ContentID)
+	}
+
+	return contentIDs, nil
+}
+
 // GetMediaByPath retrieves media items by file path.
 func GetMediaByPath(_ context.Context, _ string) ([]*Media, error) {
 	// col, err := db.GetCollection[any](ctx, MediaCollectionKey)
@@ -273,6 +371,12 @@ func GetMediaByPath(_ context.Context, _ string) ([]*Media, error) {
 
 // GetMedia retrieves media items for a user with filtering and sorting options.
 func GetMedia(ctx context.Context, username string, sort string, sortDirection int, excludeIDs []ContentID,
+	raw bool, allowHidden bool) ([]*Media, error) {
+	return GetMediaInArea(ctx, GeoFilter{}, username, sort, sortDirection, excludeIDs, raw, allowHidden)
+}
+
+// GetMediaInArea retrieves media items for a user like GetMedia, keeping only those taken inside the area of geo.
+func GetMediaInArea(ctx context.Context, geo GeoFilter, username string, sort string, sortDirection int, excludeIDs []ContentID,
 	_ bool, allowHidden bool) ([]*Media, error) {
 	wlslices.Sort(excludeIDs)
 
@@ -291,6 +395,10 @@ func GetMedia(ctx context.Context, username string, sort string, sortDirection i
 		pipe = append(pipe, bson.D{{Key: "$match", Value: bson.D{{Key: "hidden", Value: false}}}})
 	}
 
+	if !geo.IsEmpty() {
+		pipe = append(pipe, bson.D{{Key: "$match", Value: bson.D{{Key: "geoLocation", Value: geo.geoWithin()}}}})
+	}
+
 	pipe = append(pipe, bson.D{{Key: "$sort", Value: bson.D{{Key: sort, Value: sortDirection}}}})
 	// pipe = append(pipe, bson.D{{Key: "$project", Value: bson.D{{Key: "_id", Value: false}, {Key: "contentID", Value: true}}}})
 
@@ -489,6 +489,16 @@ func TestTextEmbedEligible(t *testing.T) {
 	})
 }
 
+func TestMTypeIsText(t *testing.T) {
+	for _, ext := range []string{"txt", "md", "csv", "go"} {
+		assert.True(t, media.ParseExtension(ext).IsText(), "%s is text", ext)
+	}
+
+	for _, ext := range []string{"jpg", "pdf", "zip", ""} {
+		assert.False(t, media.ParseExtension(ext).IsText(), "%s is not text", ext)
+	}
+}
+
 func TestFmtCacheFileName(t *testing.T) {
 	tests := []struct {
 		name     string
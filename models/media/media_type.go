@@ -1,6 +1,9 @@
 package media
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"strings"
+)
 
 // MediaTypeJSON contains the JSON definition of all supported media types.
 const MediaTypeJSON = `{
@@ -536,6 +539,11 @@ func (mt MType) IsEmbeddable() bool {
 	return mt.Embeddable
 }
 
+// IsText reports whether files of this type are plain text, and can be read and edited as such.
+func (mt MType) IsText() bool {
+	return strings.HasPrefix(mt.Mime, "text/")
+}
+
 func rawMimes() []string {
 	rawMimes := []string{}
 
@@ -3,10 +3,14 @@ package media
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"math"
+	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,77 +18,370 @@ import (
 	file_model "github.com/ethanrous/weblens/models/file"
 	"github.com/ethanrous/weblens/modules/wlerrors"
 	"github.com/ethanrous/weblens/modules/wlfs"
-	"github.com/ethanrous/weblens/modules/wlog"
 	"github.com/rs/zerolog"
 	ffmpeg "github.com/u2takey/ffmpeg-go"
 )
 
 // ErrChunkNotFound is returned when a requested video chunk does not exist.
-var ErrChunkNotFound = wlerrors.New("chunk not found")
+var ErrChunkNotFound = wlerrors.Statusf(http.StatusNotFound, "chunk not found")
 
-// VideoStreamer manages the transcoding and streaming of video files in HLS format.
+// SourceRenditionName is the name of the rendition at the resolution of the source video.
+const SourceRenditionName = "source"
+
+// StreamDirSuffix is appended to the content ID of a video to name the directory its renditions are cached in.
+const StreamDirSuffix = "-stream"
+
+// maxVideoBitrate caps the bitrate of the source rendition when it has to be re-encoded.
+const maxVideoBitrate = 40_000_000
+
+// segmentSeconds is the target length of each HLS segment. Keyframes are forced at this interval, so segments of
+// different renditions line up and players can switch between them at any segment.
+const segmentSeconds = 6
+
+// touchInterval is how often streaming a video updates the last use time of its stream directory.
+const touchInterval = time.Minute
+
+// chunkNameRegex matches the names of rendition playlists, like "720p.m3u8", and their segments, like "720p_004.ts".
+var chunkNameRegex = regexp.MustCompile(`^([a-z0-9]+)(?:\.m3u8|_\d+\.ts)$`)
+
+// Rendition is one quality a video is offered at in its HLS master playlist.
+type Rendition struct {
+	Name string
+	// Height is the length of the short side of the video, so portrait videos get renditions of the same quality.
+	Height       int
+	VideoBitrate int64
+	AudioBitrate int64
+}
+
+// PlaylistName returns the name of the media playlist of the rendition.
+func (r Rendition) PlaylistName() string {
+	return r.Name + ".m3u8"
+}
+
+// scaledRenditions are the renditions offered below the resolution of the source video, from smallest to largest.
+var scaledRenditions = []Rendition{
+	{Name: "360p", Height: 360, VideoBitrate: 800_000, AudioBitrate: 96_000},
+	{Name: "720p", Height: 720, VideoBitrate: 2_800_000, AudioBitrate: 128_000},
+	{Name: "1080p", Height: 1080, VideoBitrate: 5_000_000, AudioBitrate: 192_000},
+}
+
+// VideoProbe describes the source of a video, as found by ffprobe.
+type VideoProbe struct {
+	// Width and Height are the size of the video as it is displayed, after any rotation.
+	Width        int
+	Height       int
+	VideoCodec   string
+	AudioCodec   string
+	VideoBitrate int64
+	AudioBitrate int64
+}
+
+// ParseVideoProbe reads the JSON output of ffprobe.
+func ParseVideoProbe(probeJSON []byte) (VideoProbe, error) {
+	var probeResult struct {
+		Format struct {
+			BitRate string `json:"bit_rate"`
+		} `json:"format"`
+		Streams []struct {
+			CodecType string `json:"codec_type"`
+			CodecName string `json:"codec_name"`
+			Width     int    `json:"width"`
+			Height    int    `json:"height"`
+			BitRate   string `json:"bit_rate"`
+			Tags      struct {
+				Rotate string `json:"rotate"`
+			} `json:"tags"`
+			SideDataList []struct {
+				Rotation float64 `json:"rotation"`
+			} `json:"side_data_list"`
+		} `json:"streams"`
+	}
+
+	err := json.Unmarshal(probeJSON, &probeResult)
+	if err != nil {
+		return VideoProbe{}, wlerrors.WithStack(err)
+	}
+
+	probe := VideoProbe{}
+
+	for _, stream := range probeResult.Streams {
+		switch stream.CodecType {
+		case "video":
+			if probe.VideoCodec != "" {
+				continue
+			}
+
+			probe.VideoCodec = stream.CodecName
+			probe.Width = stream.Width
+			probe.Height = stream.Height
+
+			rotation, _ := strconv.ParseFloat(stream.Tags.Rotate, 64)
+			for _, sideData := range stream.SideDataList {
+				if sideData.Rotation != 0 {
+					rotation = sideData.Rotation
+				}
+			}
+
+			// Videos recorded in portrait on phones are often stored in landscape and rotated when played
+			if int(math.Abs(rotation))%180 == 90 {
+				probe.Width, probe.Height = probe.Height, probe.Width
+			}
+		case "audio":
+			if probe.AudioCodec != "" {
+				continue
+			}
+
+			probe.AudioCodec = stream.CodecName
+			probe.AudioBitrate, _ = strconv.ParseInt(stream.BitRate, 10, 64)
+		}
+	}
+
+	if probe.VideoCodec == "" || probe.Width == 0 || probe.Height == 0 {
+		return VideoProbe{}, wlerrors.New("invalid movie format: no video stream")
+	}
+
+	if probeResult.Format.BitRate == "" {
+		return VideoProbe{}, wlerrors.New("bitrate does not exist or is not a string")
+	}
+
+	probe.VideoBitrate, err = strconv.ParseInt(probeResult.Format.BitRate, 10, 64)
+	if err != nil {
+		return VideoProbe{}, wlerrors.WithStack(err)
+	}
+
+	if probe.AudioCodec != "" && probe.AudioBitrate == 0 {
+		probe.AudioBitrate = 320_000
+	}
+
+	return probe, nil
+}
+
+// CanPassthrough returns true if the source video is already H.264/AAC, and can be segmented without re-encoding.
+func (p VideoProbe) CanPassthrough() bool {
+	return p.VideoCodec == "h264" && (p.AudioCodec == "" || p.AudioCodec == "aac")
+}
+
+// Renditions returns the renditions the video is offered at, from smallest to largest. Scaled renditions are only
+// offered below the resolution of the source, which is always offered last.
+func (p VideoProbe) Renditions() []Rendition {
+	shortSide := min(p.Width, p.Height)
+
+	renditions := make([]Rendition, 0, len(scaledRenditions)+1)
+
+	for _, r := range scaledRenditions {
+		if r.Height >= shortSide {
+			break
+		}
+
+		// There is nothing to gain from encoding a smaller video at a higher bitrate than its source
+		r.VideoBitrate = min(r.VideoBitrate, p.VideoBitrate)
+		renditions = append(renditions, r)
+	}
+
+	source := Rendition{
+		Name:         SourceRenditionName,
+		Height:       shortSide,
+		VideoBitrate: p.VideoBitrate,
+		AudioBitrate: p.AudioBitrate,
+	}
+
+	if !p.CanPassthrough() {
+		source.VideoBitrate = min(source.VideoBitrate, maxVideoBitrate)
+	}
+
+	return append(renditions, source)
+}
+
+// Resolution returns the width and height of the video encoded at rendition r.
+func (p VideoProbe) Resolution(r Rendition) (width, height int) {
+	shortSide := min(p.Width, p.Height)
+	if r.Height >= shortSide {
+		return p.Width, p.Height
+	}
+
+	scale := float64(r.Height) / float64(shortSide)
+
+	width = evenRound(float64(p.Width) * scale)
+	height = evenRound(float64(p.Height) * scale)
+
+	if p.Width < p.Height {
+		width = r.Height
+	} else {
+		height = r.Height
+	}
+
+	return width, height
+}
+
+// MasterPlaylist returns the HLS master playlist listing every rendition of the video.
+func (p VideoProbe) MasterPlaylist() []byte {
+	buf := bytes.NewBufferString("#EXTM3U\n#EXT-X-VERSION:3\n")
+
+	for _, r := range p.Renditions() {
+		width, height := p.Resolution(r)
+
+		fmt.Fprintf(buf, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d\n%s\n", r.VideoBitrate+r.AudioBitrate, width, height, r.PlaylistName())
+	}
+
+	return buf.Bytes()
+}
+
+// ParseChunkName returns the name of the rendition a playlist or segment name belongs to.
+func ParseChunkName(chunkName string) (rendition string, err error) {
+	match := chunkNameRegex.FindStringSubmatch(chunkName)
+	if match == nil {
+		return "", wlerrors.Errorf("%w: invalid chunk name [%s]", ErrChunkNotFound, chunkName)
+	}
+
+	return match[1], nil
+}
+
+// renditionStream tracks the transcoding of one rendition of a video.
+type renditionStream struct {
+	err      error
+	encoding atomic.Bool
+}
+
+// VideoStreamer manages the transcoding and streaming of video files in HLS format. Each rendition of the video is
+// transcoded the first time one of its chunks is requested, and cached in the stream directory until it is evicted.
 type VideoStreamer struct {
-	err           error
 	file          *file_model.WeblensFileImpl
 	streamDirPath wlfs.Filepath
-	listFileCache []byte
-	modifiedCache time.Time
-	updateMu      sync.RWMutex
-	encodingBegun atomic.Bool
+
+	probe    *VideoProbe
+	probeErr error
+	probeMu  sync.Mutex
+
+	renditions map[string]*renditionStream
+	updateMu   sync.RWMutex
+
+	lastTouch atomic.Int64
 
 	log zerolog.Logger
 }
 
 // NewVideoStreamer creates a new VideoStreamer for the specified file.
 func NewVideoStreamer(file *file_model.WeblensFileImpl, thumbsPath wlfs.Filepath) *VideoStreamer {
-	streamDir := thumbsPath.Child(file.GetContentID()+"-stream", true)
+	streamDir := thumbsPath.Child(file.GetContentID()+StreamDirSuffix, true)
 
 	return &VideoStreamer{
 		file:          file,
 		streamDirPath: streamDir,
+		renditions:    map[string]*renditionStream{},
 	}
 }
 
-// Encode starts the video transcoding process if not already running.
-func (vs *VideoStreamer) Encode(f *file_model.WeblensFileImpl) *VideoStreamer {
-	if !vs.encodingBegun.Load() {
-		vs.encodingBegun.Store(true)
+// GetEncodeDir returns the directory path where encoded video chunks are stored.
+func (vs *VideoStreamer) GetEncodeDir() wlfs.Filepath {
+	return vs.streamDirPath
+}
+
+// Probe returns what ffprobe found out about the source video. The source is only probed once.
+func (vs *VideoStreamer) Probe() (VideoProbe, error) {
+	vs.probeMu.Lock()
+	defer vs.probeMu.Unlock()
 
-		go vs.transcodeChunks(f, "ultrafast")
+	if vs.probe != nil {
+		return *vs.probe, nil
+	} else if vs.probeErr != nil {
+		return VideoProbe{}, vs.probeErr
 	}
 
-	return vs
+	vs.log.Debug().Func(func(e *zerolog.Event) { e.Msgf("Probing %s", vs.file.GetPortablePath().ToAbsolute()) })
+
+	probeJSON, err := ffmpeg.Probe(vs.file.GetPortablePath().ToAbsolute())
+	if err != nil {
+		vs.probeErr = wlerrors.WithStack(err)
+
+		return VideoProbe{}, vs.probeErr
+	}
+
+	probe, err := ParseVideoProbe([]byte(probeJSON))
+	if err != nil {
+		vs.probeErr = err
+
+		return VideoProbe{}, err
+	}
+
+	vs.probe = &probe
+
+	return probe, nil
 }
 
-// GetEncodeDir returns the directory path where encoded video chunks are stored.
-func (vs *VideoStreamer) GetEncodeDir() wlfs.Filepath {
-	return vs.streamDirPath
+// GetMasterPlaylist returns the HLS master playlist of the video, and when the source was last modified.
+func (vs *VideoStreamer) GetMasterPlaylist() ([]byte, time.Time, error) {
+	probe, err := vs.Probe()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	vs.touch()
+
+	return probe.MasterPlaylist(), vs.file.ModTime(), nil
+}
+
+// Encode starts transcoding a rendition of the video if it is not already running.
+func (vs *VideoStreamer) Encode(rendition Rendition) *VideoStreamer {
+	rs := vs.getRenditionStream(rendition.Name)
+
+	if rs.encoding.CompareAndSwap(false, true) {
+		vs.updateMu.Lock()
+		rs.err = nil
+		vs.updateMu.Unlock()
+
+		go vs.transcodeRendition(rendition, rs, "ultrafast")
+	}
+
+	return vs
 }
 
-// GetChunk retrieves a specific video chunk file by name, waiting for encoding if necessary.
+// GetChunk retrieves a rendition playlist or segment by name, transcoding its rendition and waiting for the chunk
+// if necessary.
 func (vs *VideoStreamer) GetChunk(chunkName string) (*os.File, error) {
+	rendition, err := vs.getRendition(chunkName)
+	if err != nil {
+		return nil, err
+	}
+
+	vs.touch()
+
 	chunkPath := vs.GetEncodeDir().Child(chunkName, false)
 	if _, err := os.Stat(chunkPath.ToAbsolute()); err != nil {
-		vs.Encode(vs.file)
+		vs.Encode(rendition)
 
-		for vs.IsTranscoding() {
+		for vs.IsTranscoding(rendition.Name) {
 			if _, err := os.Stat(chunkPath.ToAbsolute()); err == nil {
 				break
 			}
 
-			if vs.Err() != nil {
-				return nil, vs.Err()
+			if err := vs.Err(rendition.Name); err != nil {
+				return nil, err
 			}
 
 			time.Sleep(time.Second)
 		}
+
+		if err := vs.Err(rendition.Name); err != nil {
+			return nil, err
+		}
 	}
 
-	return os.Open(chunkPath.ToAbsolute())
+	f, err := os.Open(chunkPath.ToAbsolute())
+	if os.IsNotExist(err) {
+		return nil, wlerrors.Errorf("%w: [%s]", ErrChunkNotFound, chunkName)
+	} else if err != nil {
+		return nil, wlerrors.WithStack(err)
+	}
+
+	return f, nil
 }
 
 // GetChunkModified returns the last modified time of a video chunk.
 func (vs *VideoStreamer) GetChunkModified(chunkName string) (time.Time, error) {
+	if _, err := ParseChunkName(chunkName); err != nil {
+		return time.Time{}, err
+	}
+
 	chunkPath := vs.GetEncodeDir().Child(chunkName, false)
 
 	stat, err := os.Stat(chunkPath.ToAbsolute())
@@ -99,123 +396,93 @@ func (vs *VideoStreamer) GetChunkModified(chunkName string) (time.Time, error) {
 	return stat.ModTime(), nil
 }
 
-// GetListFile retrieves the HLS playlist file, waiting for encoding if necessary.
-func (vs *VideoStreamer) GetListFile() ([]byte, time.Time, error) {
-	if vs.listFileCache != nil {
-		return vs.listFileCache, vs.modifiedCache, nil
-	}
-
-	listPath := vs.GetEncodeDir().Child("list.m3u8", false)
-	if _, err := os.Stat(listPath.ToAbsolute()); err != nil {
-		vs.Encode(vs.file)
-
-		for vs.IsTranscoding() {
-			if _, err := os.Stat(listPath.ToAbsolute()); err == nil {
-				break
-			}
-
-			if vs.Err() != nil {
-				return nil, time.Time{}, vs.Err()
-			}
-
-			time.Sleep(time.Second)
-		}
-	}
-
-	listFile, err := os.ReadFile(listPath.ToAbsolute())
-	if err != nil {
-		return nil, time.Time{}, wlerrors.WithStack(err)
-	}
-
-	stat, err := os.Stat(listPath.ToAbsolute())
-	if err != nil {
-		return nil, time.Time{}, wlerrors.WithStack(err)
-	}
+// Err returns any error that occurred while transcoding the named rendition.
+func (vs *VideoStreamer) Err(rendition string) error {
+	vs.updateMu.RLock()
+	defer vs.updateMu.RUnlock()
 
-	// Cache the list file only if transcoding is finished and no errors
-	if !vs.IsTranscoding() && vs.Err() == nil {
-		vs.listFileCache = listFile
-		vs.modifiedCache = stat.ModTime()
+	rs, ok := vs.renditions[rendition]
+	if !ok {
+		return nil
 	}
 
-	return listFile, stat.ModTime(), nil
+	return rs.err
 }
 
-// Err returns any error that occurred during transcoding.
-func (vs *VideoStreamer) Err() error {
+// IsTranscoding checks if the named rendition of the video is currently being transcoded. If no rendition is named,
+// it checks if any rendition is.
+func (vs *VideoStreamer) IsTranscoding(rendition ...string) bool {
 	vs.updateMu.RLock()
 	defer vs.updateMu.RUnlock()
 
-	return vs.err
-}
+	for name, rs := range vs.renditions {
+		if (len(rendition) == 0 || name == rendition[0]) && rs.encoding.Load() {
+			return true
+		}
+	}
 
-// IsTranscoding checks if the video is currently being transcoded.
-func (vs *VideoStreamer) IsTranscoding() bool {
-	return vs.encodingBegun.Load()
+	return false
 }
 
-func (vs *VideoStreamer) probeSourceBitrate(f *file_model.WeblensFileImpl) (videoBitrate int64, audioBitrate int64, err error) {
-	vs.log.Debug().Func(func(e *zerolog.Event) { e.Msgf("Probing %s", f.GetPortablePath().ToAbsolute()) })
-
-	probeJSON, err := ffmpeg.Probe(f.GetPortablePath().ToAbsolute())
+// getRendition finds the rendition a chunk belongs to, among those offered for this video.
+func (vs *VideoStreamer) getRendition(chunkName string) (Rendition, error) {
+	name, err := ParseChunkName(chunkName)
 	if err != nil {
-		return 0, 0, err
+		return Rendition{}, err
 	}
 
-	probeResult := map[string]any{}
-
-	err = json.Unmarshal([]byte(probeJSON), &probeResult)
+	probe, err := vs.Probe()
 	if err != nil {
-		return 0, 0, err
+		return Rendition{}, err
 	}
 
-	formatChunk, ok := probeResult["format"].(map[string]any)
-	if !ok {
-		return 0, 0, wlerrors.New("invalid movie format")
+	for _, r := range probe.Renditions() {
+		if r.Name == name {
+			return r, nil
+		}
 	}
 
-	streamsChunk, ok := probeResult["streams"].([]any)
-	if !ok {
-		return 0, 0, wlerrors.New("invalid movie format")
-	}
+	return Rendition{}, wlerrors.Errorf("%w: video has no [%s] rendition", ErrChunkNotFound, name)
+}
 
-	bitRateStr, ok := formatChunk["bit_rate"].(string)
-	if !ok {
-		return 0, 0, wlerrors.New("bitrate does not exist or is not a string")
-	}
+func (vs *VideoStreamer) getRenditionStream(name string) *renditionStream {
+	vs.updateMu.Lock()
+	defer vs.updateMu.Unlock()
 
-	videoBitrate, err = strconv.ParseInt(bitRateStr, 10, 64)
-	if err != nil {
-		return 0, 0, err
+	rs, ok := vs.renditions[name]
+	if !ok {
+		rs = &renditionStream{}
+		vs.renditions[name] = rs
 	}
 
-	audioBitrate = 320_000
-
-	for _, stream := range streamsChunk {
-		streamMap := stream.(map[string]any)
-		if streamMap["codec_type"].(string) == "audio" {
-			bitRate, ok := streamMap["bit_rate"].(string)
-			if !ok {
-				continue
-			}
+	return rs
+}
 
-			audioBitrate, err = strconv.ParseInt(bitRate, 10, 64)
-			if err != nil {
-				return 0, 0, err
-			}
+// touch marks the stream directory as recently used, so it is evicted after those that have not been streamed for
+// longer.
+func (vs *VideoStreamer) touch() {
+	now := time.Now()
 
-			break
-		}
+	last := vs.lastTouch.Load()
+	if now.Sub(time.Unix(0, last)) < touchInterval || !vs.lastTouch.CompareAndSwap(last, now.UnixNano()) {
+		return
 	}
 
-	videoBitrate = int64(math.Min(float64(videoBitrate), 40_000_000))
-
-	return videoBitrate, audioBitrate, nil
+	err := os.Chtimes(vs.streamDirPath.ToAbsolute(), now, now)
+	if err != nil && !os.IsNotExist(err) {
+		vs.log.Warn().Err(err).Msgf("Failed to update last use of %s", vs.streamDirPath)
+	}
 }
 
-func (vs *VideoStreamer) transcodeChunks(f *file_model.WeblensFileImpl, speed string) {
+func (vs *VideoStreamer) transcodeRendition(rendition Rendition, rs *renditionStream, speed string) {
+	setErr := func(err error) {
+		vs.updateMu.Lock()
+		rs.err = err
+		vs.updateMu.Unlock()
+	}
+
 	defer func() {
-		vs.encodingBegun.Store(false)
+		rs.encoding.Store(false)
 
 		e := recover()
 		if e == nil {
@@ -224,59 +491,86 @@ func (vs *VideoStreamer) transcodeChunks(f *file_model.WeblensFileImpl, speed st
 
 		err, ok := e.(error)
 		if !ok {
-			vs.log.Error().Msgf("transcodeChunks panicked and got non-error error: %v", e)
-
-			return
+			err = wlerrors.Errorf("transcodeRendition panicked: %v", e)
 		}
 
 		vs.log.Error().Stack().Err(err).Msg("")
+		setErr(err)
 	}()
 
 	vs.log.Debug().Func(func(e *zerolog.Event) {
-		e.Msgf("Transcoding video %s => %s", f.GetPortablePath().ToAbsolute(), vs.streamDirPath)
+		e.Msgf("Transcoding video %s rendition %s => %s", vs.file.GetPortablePath().ToAbsolute(), rendition.Name, vs.streamDirPath)
 	})
 
-	err := os.Mkdir(vs.streamDirPath.ToAbsolute(), os.ModePerm)
-	if err != nil && !os.IsExist(err) {
-		vs.updateMu.Lock()
-		vs.err = err
-		vs.updateMu.Unlock()
+	err := os.MkdirAll(vs.streamDirPath.ToAbsolute(), os.ModePerm)
+	if err != nil {
+		setErr(wlerrors.WithStack(err))
 
 		return
 	}
 
-	videoBitrate, _, err := vs.probeSourceBitrate(f)
+	probe, err := vs.Probe()
 	if err != nil {
-		vs.updateMu.Lock()
-		vs.err = err
-		vs.updateMu.Unlock()
+		setErr(err)
 
 		return
 	}
 
-	wlog.GlobalLogger().Debug().Msgf("Bitrate: %d", videoBitrate)
+	streamDir := vs.streamDirPath.ToAbsolute()
 
 	outputArgs := ffmpeg.KwArgs{
-		"c:v":                "libx264",
-		"b:v":                int(videoBitrate),
-		"b:a":                320_000,
-		"c:a":                "aac",
 		"segment_list_flags": "+live",
 		"format":             "segment",
 		"segment_format":     "mpegts",
-		"hls_time":           6,
-		"hls_list_size":      0,
-		"segment_list":       filepath.Join(vs.streamDirPath.ToAbsolute(), "list.m3u8"),
-		"preset":             speed,
+		"segment_time":       segmentSeconds,
+		"segment_list":       filepath.Join(streamDir, rendition.PlaylistName()),
+	}
+
+	if rendition.Name == SourceRenditionName && probe.CanPassthrough() {
+		outputArgs["c:v"] = "copy"
+		outputArgs["c:a"] = "copy"
+	} else {
+		outputArgs["c:v"] = "libx264"
+		outputArgs["b:v"] = int(rendition.VideoBitrate)
+		outputArgs["maxrate"] = int(rendition.VideoBitrate)
+		outputArgs["bufsize"] = int(rendition.VideoBitrate * 2)
+		outputArgs["c:a"] = "aac"
+		outputArgs["b:a"] = int(rendition.AudioBitrate)
+		outputArgs["preset"] = speed
+		outputArgs["force_key_frames"] = fmt.Sprintf("expr:gte(t,n_forced*%d)", segmentSeconds)
+
+		if rendition.Name != SourceRenditionName {
+			outputArgs["vf"] = scaleFilter(probe, rendition)
+		}
 	}
 
 	outErr := bytes.NewBuffer(nil)
 
-	err = ffmpeg.Input(f.GetPortablePath().ToAbsolute(), ffmpeg.KwArgs{"ss": 0}).Output(vs.streamDirPath.ToAbsolute()+"%03d.ts", outputArgs).WithErrorOutput(outErr).Run()
+	err = ffmpeg.Input(vs.file.GetPortablePath().ToAbsolute(), ffmpeg.KwArgs{"ss": 0}).
+		Output(filepath.Join(streamDir, rendition.Name+"_%03d.ts"), outputArgs).
+		WithErrorOutput(outErr).
+		Run()
 	if err != nil {
 		vs.log.Error().Msg(outErr.String())
-		vs.updateMu.Lock()
-		vs.err = err
-		vs.updateMu.Unlock()
+		setErr(wlerrors.WithStack(err))
+	}
+}
+
+// scaleFilter returns the ffmpeg filter that scales the short side of the video down to the height of rendition r.
+func scaleFilter(probe VideoProbe, r Rendition) string {
+	if probe.Width < probe.Height {
+		return fmt.Sprintf("scale=%d:-2", r.Height)
 	}
+
+	return fmt.Sprintf("scale=-2:%d", r.Height)
+}
+
+// evenRound rounds to the nearest even number, as H.264 needs even dimensions.
+func evenRound(f float64) int {
+	return int(math.Round(f/2)) * 2
+}
+
+// IsStreamDir returns true if name is the name of a directory renditions of a video are cached in.
+func IsStreamDir(name string) bool {
+	return strings.HasSuffix(name, StreamDirSuffix)
 }
@@ -0,0 +1,145 @@
+package media_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ethanrous/weblens/models/media"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testProbeJSON = `{
+	"streams": [
+		{"codec_type": "video", "codec_name": "%s", "width": 3840, "height": 2160, "side_data_list": [{"rotation": %d}]},
+		{"codec_type": "audio", "codec_name": "aac", "bit_rate": "256000"}
+	],
+	"format": {"bit_rate": "45000000"}
+}`
+
+func TestParseVideoProbe(t *testing.T) {
+	t.Run("reads source", func(t *testing.T) {
+		probe, err := media.ParseVideoProbe(fmtProbe("hevc", 0))
+		require.NoError(t, err)
+
+		assert.Equal(t, 3840, probe.Width)
+		assert.Equal(t, 2160, probe.Height)
+		assert.Equal(t, "hevc", probe.VideoCodec)
+		assert.Equal(t, "aac", probe.AudioCodec)
+		assert.Equal(t, int64(45_000_000), probe.VideoBitrate)
+		assert.Equal(t, int64(256_000), probe.AudioBitrate)
+		assert.False(t, probe.CanPassthrough())
+	})
+
+	t.Run("swaps rotated dimensions", func(t *testing.T) {
+		probe, err := media.ParseVideoProbe(fmtProbe("h264", -90))
+		require.NoError(t, err)
+
+		assert.Equal(t, 2160, probe.Width)
+		assert.Equal(t, 3840, probe.Height)
+		assert.True(t, probe.CanPassthrough())
+	})
+
+	t.Run("rejects file without video", func(t *testing.T) {
+		_, err := media.ParseVideoProbe([]byte(`{"streams": [{"codec_type": "audio", "codec_name": "mp3"}], "format": {"bit_rate": "320000"}}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestVideoProbeRenditions(t *testing.T) {
+	t.Run("4k source gets every rendition", func(t *testing.T) {
+		probe := media.VideoProbe{Width: 3840, Height: 2160, VideoCodec: "hevc", VideoBitrate: 60_000_000}
+
+		names := renditionNames(probe.Renditions())
+		assert.Equal(t, []string{"360p", "720p", "1080p", media.SourceRenditionName}, names)
+
+		source := probe.Renditions()[3]
+		assert.Equal(t, int64(40_000_000), source.VideoBitrate, "re-encoded source bitrate should be capped")
+	})
+
+	t.Run("passthrough source keeps its bitrate", func(t *testing.T) {
+		probe := media.VideoProbe{Width: 3840, Height: 2160, VideoCodec: "h264", AudioCodec: "aac", VideoBitrate: 60_000_000}
+
+		renditions := probe.Renditions()
+		assert.Equal(t, int64(60_000_000), renditions[len(renditions)-1].VideoBitrate)
+	})
+
+	t.Run("no renditions at or above source", func(t *testing.T) {
+		probe := media.VideoProbe{Width: 1280, Height: 720, VideoCodec: "h264", VideoBitrate: 2_000_000}
+
+		renditions := probe.Renditions()
+		assert.Equal(t, []string{"360p", media.SourceRenditionName}, renditionNames(renditions))
+		assert.Equal(t, int64(800_000), renditions[0].VideoBitrate)
+	})
+
+	t.Run("scaled bitrate never exceeds source", func(t *testing.T) {
+		probe := media.VideoProbe{Width: 1920, Height: 1080, VideoCodec: "h264", VideoBitrate: 1_000_000}
+
+		renditions := probe.Renditions()
+		assert.Equal(t, int64(1_000_000), renditions[1].VideoBitrate)
+	})
+
+	t.Run("portrait video uses short side", func(t *testing.T) {
+		probe := media.VideoProbe{Width: 1080, Height: 1920, VideoCodec: "h264", VideoBitrate: 8_000_000}
+
+		renditions := probe.Renditions()
+		assert.Equal(t, []string{"360p", "720p", media.SourceRenditionName}, renditionNames(renditions))
+
+		width, height := probe.Resolution(renditions[1])
+		assert.Equal(t, 720, width)
+		assert.Equal(t, 1280, height)
+	})
+}
+
+func TestVideoProbeMasterPlaylist(t *testing.T) {
+	probe := media.VideoProbe{Width: 1280, Height: 720, VideoCodec: "h264", AudioCodec: "aac", VideoBitrate: 2_000_000, AudioBitrate: 128_000}
+
+	expected := "#EXTM3U\n#EXT-X-VERSION:3\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=896000,RESOLUTION=640x360\n360p.m3u8\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=2128000,RESOLUTION=1280x720\nsource.m3u8\n"
+
+	assert.Equal(t, expected, string(probe.MasterPlaylist()))
+}
+
+func TestParseChunkName(t *testing.T) {
+	tests := []struct {
+		chunkName string
+		rendition string
+		wantErr   bool
+	}{
+		{chunkName: "720p.m3u8", rendition: "720p"},
+		{chunkName: "720p_004.ts", rendition: "720p"},
+		{chunkName: "source_1234.ts", rendition: "source"},
+		{chunkName: "000.ts", wantErr: true},
+		{chunkName: "list.m3u8.bak", wantErr: true},
+		{chunkName: "../720p.m3u8", wantErr: true},
+		{chunkName: "720p_abc.ts", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.chunkName, func(t *testing.T) {
+			rendition, err := media.ParseChunkName(tt.chunkName)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, media.ErrChunkNotFound)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.rendition, rendition)
+		})
+	}
+}
+
+func fmtProbe(codec string, rotation int) []byte {
+	return []byte(fmt.Sprintf(testProbeJSON, codec, rotation))
+}
+
+func renditionNames(renditions []media.Rendition) []string {
+	names := make([]string, 0, len(renditions))
+	for _, r := range renditions {
+		names = append(names, r.Name)
+	}
+
+	return names
+}
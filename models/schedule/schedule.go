@@ -0,0 +1,284 @@
+// Package schedule provides persistence for jobs that are run on a recurring, cron-like, schedule.
+package schedule
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethanrous/weblens/models/db"
+	"github.com/ethanrous/weblens/modules/cron"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ScheduleCollectionKey is the MongoDB collection name for job schedules.
+const ScheduleCollectionKey = "schedules"
+
+// ErrScheduleNeverRuns indicates a schedule was given a cron expression that never fires.
+var ErrScheduleNeverRuns = wlerrors.New("schedule never runs")
+
+// Schedule is a job that is dispatched each time its cron expression fires.
+type Schedule struct {
+	ID      primitive.ObjectID `bson:"_id"`
+	Name    string             `bson:"name"`
+	JobName string             `bson:"jobName"`
+	Cron    string             `bson:"cron"`
+	// Params configure the job that is run, such as the folder to scan or the core to back up. Which params are
+	// used depends on the job.
+	Params    map[string]string `bson:"params"`
+	Enabled   bool              `bson:"enabled"`
+	CreatedBy string            `bson:"createdBy"`
+	Created   time.Time         `bson:"created"`
+
+	// NextRun is when the schedule is next due. A schedule that was due while the server was down is run once as
+	// soon as it starts again.
+	NextRun time.Time `bson:"nextRun"`
+
+	LastRun    time.Time `bson:"lastRun"`
+	LastTaskID string    `bson:"lastTaskID"`
+	// LastStatus is the exit status of the last task, or empty if it has not finished
+	LastStatus string `bson:"lastStatus"`
+	LastError  string `bson:"lastError"`
+}
+
+// NewSchedule creates a new, enabled, Schedule that runs jobName whenever cronExpr fires.
+func NewSchedule(name, jobName, cronExpr string, params map[string]string, createdBy string) (*Schedule, error) {
+	s := &Schedule{
+		ID:        primitive.NewObjectID(),
+		Name:      name,
+		JobName:   jobName,
+		Params:    params,
+		Enabled:   true,
+		CreatedBy: createdBy,
+		Created:   time.Now(),
+	}
+
+	if s.Params == nil {
+		s.Params = map[string]string{}
+	}
+
+	err := s.SetCron(cronExpr, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SetCron changes the cron expression of the schedule, and sets its next run to the first time it fires after now.
+func (s *Schedule) SetCron(cronExpr string, now time.Time) error {
+	expr, err := cron.Parse(cronExpr)
+	if err != nil {
+		return err
+	}
+
+	next := expr.Next(now)
+	if next.IsZero() {
+		return wlerrors.Errorf("%w: [%s]", ErrScheduleNeverRuns, cronExpr)
+	}
+
+	s.Cron = expr.String()
+	s.NextRun = next
+
+	return nil
+}
+
+// IsDue returns true if the schedule is enabled and its next run is at or before now.
+func (s *Schedule) IsDue(now time.Time) bool {
+	return s.Enabled && !s.NextRun.After(now)
+}
+
+// SaveSchedule saves a new Schedule to the database.
+func SaveSchedule(ctx context.Context, s *Schedule) error {
+	collection, err := db.GetCollection[*Schedule](ctx, ScheduleCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	if s.ID.IsZero() {
+		s.ID = primitive.NewObjectID()
+	}
+
+	_, err = collection.InsertOne(ctx, s)
+	if err != nil {
+		return db.WrapError(err, "failed to save schedule [%s]", s.Name)
+	}
+
+	return nil
+}
+
+// UpdateSchedule replaces the stored Schedule with s.
+func UpdateSchedule(ctx context.Context, s *Schedule) error {
+	collection, err := db.GetCollection[*Schedule](ctx, ScheduleCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	res, err := collection.ReplaceOne(ctx, bson.M{"_id": s.ID}, s)
+	if err != nil {
+		return db.WrapError(err, "failed to update schedule [%s]", s.ID.Hex())
+	}
+
+	if res.MatchedCount == 0 {
+		return db.WrapError(mongo.ErrNoDocuments, "failed to update schedule [%s]", s.ID.Hex())
+	}
+
+	return nil
+}
+
+// GetScheduleByID retrieves a Schedule by its ID.
+func GetScheduleByID(ctx context.Context, scheduleID primitive.ObjectID) (*Schedule, error) {
+	collection, err := db.GetCollection[*Schedule](ctx, ScheduleCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schedule{}
+
+	err = collection.FindOne(ctx, bson.M{"_id": scheduleID}).Decode(s)
+	if err != nil {
+		return nil, db.WrapError(wlerrors.WithStack(err), "failed to get schedule [%s]", scheduleID.Hex())
+	}
+
+	return s, nil
+}
+
+// GetAllSchedules retrieves every Schedule, ordered by name.
+func GetAllSchedules(ctx context.Context) ([]*Schedule, error) {
+	return findSchedules(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+}
+
+// GetSchedulesByJobName retrieves every Schedule that runs jobName, ordered by name.
+func GetSchedulesByJobName(ctx context.Context, jobName string) ([]*Schedule, error) {
+	return findSchedules(ctx, bson.M{"jobName": jobName}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+}
+
+// GetDueSchedules retrieves every enabled Schedule whose next run is at or before now.
+func GetDueSchedules(ctx context.Context, now time.Time) ([]*Schedule, error) {
+	return findSchedules(ctx, bson.M{"enabled": true, "nextRun": bson.M{"$lte": now}}, options.Find().SetSort(bson.D{{Key: "nextRun", Value: 1}}))
+}
+
+// RecordRun records that the schedule dispatched the task with taskID at now, and moves its next run to the first
+// time its cron expression fires after now.
+func (s *Schedule) RecordRun(ctx context.Context, taskID string, now time.Time) error {
+	expr, err := cron.Parse(s.Cron)
+	if err != nil {
+		return err
+	}
+
+	s.LastRun = now
+	s.LastTaskID = taskID
+	s.LastStatus = ""
+	s.LastError = ""
+	s.NextRun = expr.Next(now)
+
+	// A schedule that can no longer fire stays disabled rather than being due forever
+	if s.NextRun.IsZero() {
+		s.Enabled = false
+	}
+
+	return s.setRunFields(ctx, bson.M{
+		"lastRun":    s.LastRun,
+		"lastTaskID": s.LastTaskID,
+		"lastStatus": s.LastStatus,
+		"lastError":  s.LastError,
+		"nextRun":    s.NextRun,
+		"enabled":    s.Enabled,
+	})
+}
+
+// RecordSkipped records that the schedule could not be run at now, and moves its next run along as RecordRun does.
+func (s *Schedule) RecordSkipped(ctx context.Context, reason error, now time.Time) error {
+	err := s.RecordRun(ctx, "", now)
+	if err != nil {
+		return err
+	}
+
+	s.LastError = reason.Error()
+
+	return s.setRunFields(ctx, bson.M{"lastError": s.LastError})
+}
+
+// RecordResult records how the task last dispatched by the schedule exited. Nothing is recorded if the schedule has
+// dispatched another task since.
+func (s *Schedule) RecordResult(ctx context.Context, status string, taskErr error) error {
+	collection, err := db.GetCollection[*Schedule](ctx, ScheduleCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	s.LastStatus = status
+	s.LastError = ""
+
+	if taskErr != nil {
+		s.LastError = taskErr.Error()
+	}
+
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": s.ID, "lastTaskID": s.LastTaskID},
+		bson.M{"$set": bson.M{"lastStatus": s.LastStatus, "lastError": s.LastError}},
+	)
+	if err != nil {
+		return db.WrapError(err, "failed to update run status of schedule [%s]", s.ID.Hex())
+	}
+
+	return nil
+}
+
+// DeleteSchedule deletes a Schedule from the database.
+func DeleteSchedule(ctx context.Context, scheduleID primitive.ObjectID) error {
+	collection, err := db.GetCollection[*Schedule](ctx, ScheduleCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	res, err := collection.DeleteOne(ctx, bson.M{"_id": scheduleID})
+	if err != nil {
+		return db.WrapError(err, "failed to delete schedule [%s]", scheduleID.Hex())
+	}
+
+	if res.DeletedCount == 0 {
+		return db.WrapError(mongo.ErrNoDocuments, "failed to delete schedule [%s]", scheduleID.Hex())
+	}
+
+	return nil
+}
+
+func (s *Schedule) setRunFields(ctx context.Context, fields bson.M) error {
+	collection, err := db.GetCollection[*Schedule](ctx, ScheduleCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": s.ID}, bson.M{"$set": fields})
+	if err != nil {
+		return db.WrapError(err, "failed to update run status of schedule [%s]", s.ID.Hex())
+	}
+
+	return nil
+}
+
+func findSchedules(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*Schedule, error) {
+	collection, err := db.GetCollection[*Schedule](ctx, ScheduleCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get schedules")
+	}
+
+	var schedules []*Schedule
+
+	err = cursor.All(ctx, &schedules)
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get schedules")
+	}
+
+	return schedules, nil
+}
@@ -0,0 +1,170 @@
+package schedule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethanrous/weblens/models/db"
+	schedule_model "github.com/ethanrous/weblens/models/schedule"
+	"github.com/ethanrous/weblens/modules/cron"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNewSchedule(t *testing.T) {
+	t.Run("sets the next run", func(t *testing.T) {
+		s, err := schedule_model.NewSchedule("nightly", "scan_directory", "0 3 * * *", nil, "admin")
+		require.NoError(t, err)
+
+		assert.True(t, s.Enabled)
+		assert.NotNil(t, s.Params)
+		assert.True(t, s.NextRun.After(time.Now()))
+		assert.Equal(t, 3, s.NextRun.Hour())
+		assert.Equal(t, 0, s.NextRun.Minute())
+	})
+
+	t.Run("rejects invalid cron expressions", func(t *testing.T) {
+		_, err := schedule_model.NewSchedule("bad", "scan_directory", "every day", nil, "admin")
+		assert.ErrorIs(t, err, cron.ErrInvalidExpression)
+	})
+
+	t.Run("rejects cron expressions that never fire", func(t *testing.T) {
+		_, err := schedule_model.NewSchedule("never", "scan_directory", "0 0 31 2 *", nil, "admin")
+		assert.True(t, wlerrors.Is(err, schedule_model.ErrScheduleNeverRuns))
+	})
+}
+
+func TestSchedule_IsDue(t *testing.T) {
+	s, err := schedule_model.NewSchedule("hourly", "scan_directory", "@hourly", nil, "admin")
+	require.NoError(t, err)
+
+	assert.False(t, s.IsDue(time.Now()))
+	assert.True(t, s.IsDue(s.NextRun))
+
+	s.Enabled = false
+	assert.False(t, s.IsDue(s.NextRun))
+}
+
+func TestSchedules(t *testing.T) {
+	ctx := db.SetupTestDB(t, schedule_model.ScheduleCollectionKey)
+
+	t.Run("SaveAndGet", func(t *testing.T) {
+		s, err := schedule_model.NewSchedule("weekly stats", "gather_filesystem_stats", "@weekly", map[string]string{"folderID": "abc"}, "admin")
+		require.NoError(t, err)
+		require.NoError(t, schedule_model.SaveSchedule(ctx, s))
+
+		saved, err := schedule_model.GetScheduleByID(ctx, s.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "weekly stats", saved.Name)
+		assert.Equal(t, "@weekly", saved.Cron)
+		assert.Equal(t, "abc", saved.Params["folderID"])
+		assert.WithinDuration(t, s.NextRun, saved.NextRun, time.Millisecond)
+	})
+
+	t.Run("GetByJobName", func(t *testing.T) {
+		s, err := schedule_model.NewSchedule("purge", "purge_trash", "@daily", nil, "admin")
+		require.NoError(t, err)
+		require.NoError(t, schedule_model.SaveSchedule(ctx, s))
+
+		found, err := schedule_model.GetSchedulesByJobName(ctx, "purge_trash")
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		assert.Equal(t, s.ID, found[0].ID)
+
+		found, err = schedule_model.GetSchedulesByJobName(ctx, "create_zip")
+		require.NoError(t, err)
+		assert.Empty(t, found)
+	})
+
+	t.Run("GetMissing", func(t *testing.T) {
+		_, err := schedule_model.GetScheduleByID(ctx, primitive.NewObjectID())
+		assert.True(t, db.IsNotFound(err))
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		s, err := schedule_model.NewSchedule("update me", "scan_directory", "@daily", nil, "admin")
+		require.NoError(t, err)
+		require.NoError(t, schedule_model.SaveSchedule(ctx, s))
+
+		s.Enabled = false
+		require.NoError(t, s.SetCron("30 1 * * *", time.Now()))
+		require.NoError(t, schedule_model.UpdateSchedule(ctx, s))
+
+		saved, err := schedule_model.GetScheduleByID(ctx, s.ID)
+		require.NoError(t, err)
+		assert.False(t, saved.Enabled)
+		assert.Equal(t, "30 1 * * *", saved.Cron)
+
+		missing, err := schedule_model.NewSchedule("missing", "scan_directory", "@daily", nil, "admin")
+		require.NoError(t, err)
+		assert.True(t, db.IsNotFound(schedule_model.UpdateSchedule(ctx, missing)))
+	})
+
+	t.Run("DueAndRecordRun", func(t *testing.T) {
+		now := time.Now()
+
+		s, err := schedule_model.NewSchedule("due", "scan_directory", "@hourly", nil, "admin")
+		require.NoError(t, err)
+
+		s.NextRun = now.Add(-time.Minute)
+		require.NoError(t, schedule_model.SaveSchedule(ctx, s))
+
+		due, err := schedule_model.GetDueSchedules(ctx, now)
+		require.NoError(t, err)
+		require.Len(t, due, 1)
+		assert.Equal(t, s.ID, due[0].ID)
+
+		require.NoError(t, due[0].RecordRun(ctx, "task-1", now))
+
+		due, err = schedule_model.GetDueSchedules(ctx, now)
+		require.NoError(t, err)
+		assert.Empty(t, due)
+
+		saved, err := schedule_model.GetScheduleByID(ctx, s.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "task-1", saved.LastTaskID)
+		assert.True(t, saved.NextRun.After(now))
+		assert.Empty(t, saved.LastStatus)
+
+		require.NoError(t, saved.RecordResult(ctx, "success", nil))
+
+		saved, err = schedule_model.GetScheduleByID(ctx, s.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "success", saved.LastStatus)
+	})
+
+	t.Run("RecordResultOfStaleTask", func(t *testing.T) {
+		now := time.Now()
+
+		s, err := schedule_model.NewSchedule("stale", "scan_directory", "@hourly", nil, "admin")
+		require.NoError(t, err)
+		require.NoError(t, schedule_model.SaveSchedule(ctx, s))
+		require.NoError(t, s.RecordRun(ctx, "task-old", now))
+
+		stale := *s
+
+		require.NoError(t, s.RecordRun(ctx, "task-new", now))
+		require.NoError(t, stale.RecordResult(ctx, "error", wlerrors.New("boom")))
+
+		saved, err := schedule_model.GetScheduleByID(ctx, s.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "task-new", saved.LastTaskID)
+		assert.Empty(t, saved.LastStatus)
+		assert.Empty(t, saved.LastError)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		s, err := schedule_model.NewSchedule("delete me", "scan_directory", "@daily", nil, "admin")
+		require.NoError(t, err)
+		require.NoError(t, schedule_model.SaveSchedule(ctx, s))
+
+		require.NoError(t, schedule_model.DeleteSchedule(ctx, s.ID))
+
+		_, err = schedule_model.GetScheduleByID(ctx, s.ID)
+		assert.True(t, db.IsNotFound(err))
+
+		assert.True(t, db.IsNotFound(schedule_model.DeleteSchedule(ctx, s.ID)))
+	})
+}
@@ -3,10 +3,12 @@ package share
 
 import (
 	"context"
+	"net/http"
 	"time"
 
 	"github.com/ethanrous/weblens/models/db"
 	user_model "github.com/ethanrous/weblens/models/usermodel"
+	"github.com/ethanrous/weblens/modules/cryptography"
 	"github.com/ethanrous/weblens/modules/wlerrors"
 	"github.com/ethanrous/weblens/modules/wlog"
 	"github.com/ethanrous/weblens/modules/wlslices"
@@ -25,6 +27,9 @@ var ErrShareNotFound = wlerrors.New("share not found")
 // ErrShareAlreadyExists is returned when attempting to create a share that already exists.
 var ErrShareAlreadyExists = wlerrors.New("share already exists")
 
+// ErrShareExpired is returned when a share is accessed after its expiration time.
+var ErrShareExpired = wlerrors.Statusf(http.StatusGone, "share has expired")
+
 // FileShare represents a file share configuration.
 type FileShare struct {
 	// Accessors is a list of users that have access to the share
@@ -41,6 +46,11 @@ type FileShare struct {
 	Updated      time.Time               `bson:"updated"`
 	Wormhole     bool                    `bson:"wormhole"`
 	TimelineOnly bool                    `bson:"timelineOnly"`
+	// PasswordHash is the bcrypt hash of the password required to access a public share, empty if there is none
+	PasswordHash string `bson:"passwordHash,omitempty"`
+	// AlbumID is the ID of the album shared by an album share. Album shares have no FileID, and grant access to the
+	// files behind the media in the album instead.
+	AlbumID string `bson:"albumID,omitempty"`
 }
 
 // IndexModels defines MongoDB indexes for the shares collection.
@@ -49,7 +59,8 @@ var IndexModels = []mongo.IndexModel{
 		Keys: bson.M{
 			"fileID": -1,
 		},
-		Options: options.Index().SetUnique(true),
+		// Album shares have no file, so only shares of files must have a unique file ID
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"fileID": bson.M{"$gt": ""}}),
 	},
 }
 
@@ -93,6 +104,18 @@ func NewFileShare(_ context.Context, fileID string, owner *user_model.User, acce
 	}, nil
 }
 
+// NewAlbumShare creates a new FileShare that shares the album with albumID.
+func NewAlbumShare(ctx context.Context, albumID string, owner *user_model.User, accessors []*user_model.User, public bool, timelineOnly bool) (*FileShare, error) {
+	share, err := NewFileShare(ctx, "", owner, accessors, public, false, timelineOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	share.AlbumID = albumID
+
+	return share, nil
+}
+
 // SaveFileShare saves a FileShare to the database.
 func SaveFileShare(ctx context.Context, share *FileShare) error {
 	collection, err := db.GetCollection[*FileShare](ctx, ShareCollectionKey)
@@ -154,6 +177,23 @@ func GetShareByFileID(ctx context.Context, fileID string) (*FileShare, error) {
 	return &share, nil
 }
 
+// GetShareByAlbumID retrieves the FileShare of the album with albumID.
+func GetShareByAlbumID(ctx context.Context, albumID string) (*FileShare, error) {
+	collection, err := db.GetCollection[*FileShare](ctx, ShareCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var share FileShare
+
+	err = collection.FindOne(ctx, bson.M{"albumID": albumID}).Decode(&share)
+	if err != nil {
+		return nil, db.WrapError(wlerrors.WithStack(err), "failed to get share by albumID [%s]", albumID)
+	}
+
+	return &share, nil
+}
+
 // GetSharedWithUser retrieves all FileShares that are shared with a specific user.
 func GetSharedWithUser(ctx context.Context, username string) ([]FileShare, error) {
 	collection, err := db.GetCollection[*FileShare](ctx, ShareCollectionKey)
@@ -176,6 +216,80 @@ func GetSharedWithUser(ctx context.Context, username string) ([]FileShare, error
 	return shares, nil
 }
 
+// GetExpiredShares retrieves all enabled FileShares whose expiration time has passed.
+func GetExpiredShares(ctx context.Context) ([]*FileShare, error) {
+	collection, err := db.GetCollection[*FileShare](ctx, ShareCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Shares that never expire have a zero expiration time, so it must be strictly after the zero time to count.
+	cursor, err := collection.Find(ctx, bson.M{"enabled": true, "expires": bson.M{"$gt": time.Time{}, "$lte": time.Now()}})
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get expired shares")
+	}
+
+	var shares []*FileShare
+
+	err = cursor.All(ctx, &shares)
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get expired shares")
+	}
+
+	return shares, nil
+}
+
+// GetSharesUpdatedSince retrieves all FileShares created or changed after since. A zero since retrieves every FileShare.
+func GetSharesUpdatedSince(ctx context.Context, since time.Time) ([]*FileShare, error) {
+	collection, err := db.GetCollection[*FileShare](ctx, ShareCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(ctx, db.UpdatedSinceFilter(since))
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get shares updated since [%s]", since)
+	}
+
+	shares := []*FileShare{}
+
+	err = cursor.All(ctx, &shares)
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get shares updated since [%s]", since)
+	}
+
+	return shares, nil
+}
+
+// GetAllShareIDs retrieves the IDs of every FileShare.
+func GetAllShareIDs(ctx context.Context) ([]string, error) {
+	collection, err := db.GetCollection[*FileShare](ctx, ShareCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get share ids")
+	}
+
+	var shares []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+
+	err = cursor.All(ctx, &shares)
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get share ids")
+	}
+
+	ids := make([]string, 0, len(shares))
+	for _, share := range shares {
+		ids = append(ids, share.ID.Hex())
+	}
+
+	return ids, nil
+}
+
 // DeleteShare deletes a FileShare from the database.
 func DeleteShare(ctx context.Context, shareID primitive.ObjectID) error {
 	collection, err := db.GetCollection[*FileShare](ctx, ShareCollectionKey)
@@ -213,8 +327,106 @@ func (s *FileShare) SetPublic(ctx context.Context, pub bool) error {
 
 	s.Permissions[user_model.PublicUserName] = newPublicPermissions
 	publicUserPermissionsPath := "permissions." + user_model.PublicUserName
+	s.UpdatedNow()
 
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": s.ShareID}, bson.M{"$set": bson.M{"public": pub, publicUserPermissionsPath: newPublicPermissions}})
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": s.ShareID}, bson.M{"$set": bson.M{"public": pub, publicUserPermissionsPath: newPublicPermissions, "updated": s.Updated}})
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	return nil
+}
+
+// IsExpired returns true if the share has an expiration time and it has passed.
+func (s *FileShare) IsExpired() bool {
+	return !s.Expires.IsZero() && !time.Now().Before(s.Expires)
+}
+
+// SetExpires sets the time after which the share can no longer be accessed. A zero time means the share never expires.
+func (s *FileShare) SetExpires(ctx context.Context, expires time.Time) error {
+	if s.Expires.Equal(expires) {
+		return nil
+	}
+
+	collection, err := db.GetCollection[*FileShare](ctx, ShareCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	s.Expires = expires
+	s.UpdatedNow()
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": s.ShareID}, bson.M{"$set": bson.M{"expires": expires, "updated": s.Updated}})
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	return nil
+}
+
+// HasPassword returns true if the share requires a password to be accessed publicly.
+func (s *FileShare) HasPassword() bool {
+	return s.PasswordHash != ""
+}
+
+// CheckPassword verifies an attempted share password against the stored hash.
+func (s *FileShare) CheckPassword(attempt string) bool {
+	if !s.HasPassword() {
+		return false
+	}
+
+	return cryptography.VerifyUserPassword(attempt, s.PasswordHash) == nil
+}
+
+// SetPassword hashes and sets the password required to access the share publicly. An empty password removes it.
+func (s *FileShare) SetPassword(ctx context.Context, password string) error {
+	passwordHash := ""
+
+	if password != "" {
+		var err error
+
+		passwordHash, err = cryptography.HashUserPassword(ctx, password)
+		if err != nil {
+			return wlerrors.WithStack(err)
+		}
+	}
+
+	s.PasswordHash = passwordHash
+	s.UpdatedNow()
+
+	// The share may not have been saved yet, in which case the hash will be written when it is
+	if s.ShareID.IsZero() {
+		return nil
+	}
+
+	collection, err := db.GetCollection[*FileShare](ctx, ShareCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": s.ShareID}, bson.M{"$set": bson.M{"passwordHash": passwordHash, "updated": s.Updated}})
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	return nil
+}
+
+// UpdateEnabled sets whether the share is enabled, and persists the change.
+func (s *FileShare) UpdateEnabled(ctx context.Context, enabled bool) error {
+	if s.Enabled == enabled {
+		return nil
+	}
+
+	collection, err := db.GetCollection[*FileShare](ctx, ShareCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	s.Enabled = enabled
+	s.UpdatedNow()
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": s.ShareID}, bson.M{"$set": bson.M{"enabled": enabled, "updated": s.Updated}})
 	if err != nil {
 		return wlerrors.WithStack(err)
 	}
@@ -234,8 +446,9 @@ func (s *FileShare) SetTimelineOnly(ctx context.Context, timelineOnly bool) erro
 	}
 
 	s.TimelineOnly = timelineOnly
+	s.UpdatedNow()
 
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": s.ShareID}, bson.M{"$set": bson.M{"timelineOnly": timelineOnly}})
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": s.ShareID}, bson.M{"$set": bson.M{"timelineOnly": timelineOnly, "updated": s.Updated}})
 	if err != nil {
 		return wlerrors.WithStack(err)
 	}
@@ -267,8 +480,10 @@ func (s *FileShare) AddUser(ctx context.Context, username string, perms *Permiss
 		s.Permissions[username] = perms
 	}
 
+	s.UpdatedNow()
+
 	// Update the database with the new Accessors list and permissions
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": s.ShareID}, bson.M{"$set": bson.M{"accessors": s.Accessors, "permissions": s.Permissions}})
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": s.ShareID}, bson.M{"$set": bson.M{"accessors": s.Accessors, "permissions": s.Permissions, "updated": s.Updated}})
 	if err != nil {
 		return db.WrapError(err, "failed to add users to share [%s]", s.ShareID)
 	}
@@ -298,8 +513,10 @@ func (s *FileShare) SetUserPerms(ctx context.Context, perms map[string]*Permissi
 		}
 	}
 
+	s.UpdatedNow()
+
 	// Update the database with the new Accessors list and permissions
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": s.ShareID}, bson.M{"$set": bson.M{"accessors": s.Accessors, "permissions": s.Permissions}})
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": s.ShareID}, bson.M{"$set": bson.M{"accessors": s.Accessors, "permissions": s.Permissions, "updated": s.Updated}})
 	if err != nil {
 		return db.WrapError(err, "failed to add users to share [%s]", s.ShareID)
 	}
@@ -335,8 +552,10 @@ func (s *FileShare) RemoveUsers(ctx context.Context, usernames []string) error {
 		delete(s.Permissions, u)
 	}
 
+	s.UpdatedNow()
+
 	// Update the database with the new Accessors list and permissions
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": s.ShareID}, bson.M{"$set": bson.M{"accessors": s.Accessors, "permissions": s.Permissions}})
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": s.ShareID}, bson.M{"$set": bson.M{"accessors": s.Accessors, "permissions": s.Permissions, "updated": s.Updated}})
 	if err != nil {
 		return wlerrors.WithStack(err)
 	}
@@ -353,6 +572,9 @@ func (s *FileShare) GetItemID() string { return string(s.FileID) }
 // SetItemID sets the file ID associated with this share.
 func (s *FileShare) SetItemID(fileID string) { s.FileID = fileID }
 
+// IsAlbumShare returns true if the share shares an album, rather than a file.
+func (s *FileShare) IsAlbumShare() bool { return s.AlbumID != "" }
+
 // GetAccessors returns the list of users who have access to this share.
 func (s *FileShare) GetAccessors() []string { return s.Accessors }
 
@@ -363,13 +585,14 @@ func (s *FileShare) SetUserPermissions(ctx context.Context, username string, per
 	}
 
 	s.Permissions[username] = perms
+	s.UpdatedNow()
 
 	collection, err := db.GetCollection[*FileShare](ctx, ShareCollectionKey)
 	if err != nil {
 		return err
 	}
 
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": s.ShareID}, bson.M{"$set": bson.M{"permissions": s.Permissions}})
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": s.ShareID}, bson.M{"$set": bson.M{"permissions": s.Permissions, "updated": s.Updated}})
 
 	return err
 }
@@ -1,11 +1,14 @@
 package share_test
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/ethanrous/weblens/models/db"
 	share_model "github.com/ethanrous/weblens/models/share"
 	user_model "github.com/ethanrous/weblens/models/usermodel"
+	"github.com/ethanrous/weblens/modules/cryptography"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -89,6 +92,28 @@ func TestFileShare_Creation(t *testing.T) {
 		assert.Error(t, err)
 		assert.True(t, db.IsAlreadyExists(err), "Expected AlreadyExistsError, got: %v", err)
 	})
+
+	t.Run("CreateAlbumShares", func(t *testing.T) {
+		owner := createTestUser("album")
+		albumIDs := []string{primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex()}
+
+		// Album shares have no file ID, so several of them must not collide on the unique file ID index
+		for _, albumID := range albumIDs {
+			share, err := share_model.NewAlbumShare(ctx, albumID, owner, nil, true, true)
+			require.NoError(t, err)
+			assert.True(t, share.IsAlbumShare())
+
+			err = share_model.SaveFileShare(ctx, share)
+			require.NoError(t, err)
+		}
+
+		savedShare, err := share_model.GetShareByAlbumID(ctx, albumIDs[1])
+		require.NoError(t, err)
+		assert.Equal(t, albumIDs[1], savedShare.AlbumID)
+		assert.Empty(t, savedShare.FileID)
+		assert.True(t, savedShare.Public)
+		assert.True(t, savedShare.TimelineOnly)
+	})
 }
 
 func TestFileShare_Retrieval(t *testing.T) {
@@ -181,6 +206,63 @@ func TestFileShare_Updates(t *testing.T) {
 		assert.True(t, updated.Public)
 	})
 
+	t.Run("SetExpires", func(t *testing.T) {
+		fileID := primitive.NewObjectID().Hex()
+		owner := createTestUser("expires_update")
+		share, err := share_model.NewFileShare(ctx, fileID, owner, nil, true, false, false)
+		require.NoError(t, err)
+		err = share_model.SaveFileShare(ctx, share)
+		require.NoError(t, err)
+
+		assert.False(t, share.IsExpired())
+
+		err = share.SetExpires(ctx, time.Now().Add(-time.Minute))
+		assert.NoError(t, err)
+
+		updated, err := share_model.GetShareByID(ctx, share.ShareID)
+		assert.NoError(t, err)
+		assert.True(t, updated.IsExpired())
+
+		expired, err := share_model.GetExpiredShares(ctx)
+		assert.NoError(t, err)
+
+		found := false
+
+		for _, s := range expired {
+			if s.ShareID == share.ShareID {
+				found = true
+			}
+		}
+
+		assert.True(t, found, "expired share should be returned by GetExpiredShares")
+
+		err = updated.UpdateEnabled(ctx, false)
+		assert.NoError(t, err)
+
+		expired, err = share_model.GetExpiredShares(ctx)
+		assert.NoError(t, err)
+
+		for _, s := range expired {
+			assert.NotEqual(t, share.ShareID, s.ShareID, "disabled share should not be returned by GetExpiredShares")
+		}
+	})
+
+	t.Run("NoExpiry", func(t *testing.T) {
+		fileID := primitive.NewObjectID().Hex()
+		owner := createTestUser("no_expiry")
+		share, err := share_model.NewFileShare(ctx, fileID, owner, nil, true, false, false)
+		require.NoError(t, err)
+		err = share_model.SaveFileShare(ctx, share)
+		require.NoError(t, err)
+
+		expired, err := share_model.GetExpiredShares(ctx)
+		assert.NoError(t, err)
+
+		for _, s := range expired {
+			assert.NotEqual(t, share.ShareID, s.ShareID, "share without expiry should never be expired")
+		}
+	})
+
 	t.Run("AddUsers", func(t *testing.T) {
 		fileID := primitive.NewObjectID().Hex()
 		owner := createTestUser("add_users")
@@ -264,3 +346,65 @@ func TestFileShare_Updates(t *testing.T) {
 		assert.False(t, final.HasPermission(user.Username, share_model.SharePermissionEdit))
 	})
 }
+
+func TestFileShare_Password(t *testing.T) {
+	ctx := context.WithValue(context.Background(), cryptography.BcryptDifficultyCtxKey, 4)
+
+	// An unsaved share has no ID, so the password is only set in memory
+	share, err := share_model.NewFileShare(ctx, primitive.NewObjectID().Hex(), createTestUser("password"), nil, true, false, false)
+	require.NoError(t, err)
+
+	assert.False(t, share.HasPassword())
+	assert.False(t, share.CheckPassword(""))
+
+	err = share.SetPassword(ctx, "hunter2")
+	require.NoError(t, err)
+
+	assert.True(t, share.HasPassword())
+	assert.True(t, share.CheckPassword("hunter2"))
+	assert.False(t, share.CheckPassword("wrong"))
+
+	err = share.SetPassword(ctx, "")
+	require.NoError(t, err)
+
+	assert.False(t, share.HasPassword())
+}
+
+func TestGetSharesUpdatedSince(t *testing.T) {
+	ctx := db.SetupTestDB(t, share_model.ShareCollectionKey, share_model.IndexModels...)
+
+	owner := createTestUser("updated_since")
+
+	unchanged, err := share_model.NewFileShare(ctx, primitive.NewObjectID().Hex(), owner, nil, false, false, false)
+	require.NoError(t, err)
+	require.NoError(t, share_model.SaveFileShare(ctx, unchanged))
+
+	changed, err := share_model.NewFileShare(ctx, primitive.NewObjectID().Hex(), owner, nil, false, false, false)
+	require.NoError(t, err)
+	require.NoError(t, share_model.SaveFileShare(ctx, changed))
+
+	since := time.Now()
+
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, changed.UpdateEnabled(ctx, false))
+
+	t.Run("only changed shares", func(t *testing.T) {
+		shares, err := share_model.GetSharesUpdatedSince(ctx, since)
+		require.NoError(t, err)
+		require.Len(t, shares, 1)
+		assert.Equal(t, changed.ShareID, shares[0].ShareID)
+		assert.False(t, shares[0].Enabled)
+	})
+
+	t.Run("zero since gets every share", func(t *testing.T) {
+		shares, err := share_model.GetSharesUpdatedSince(ctx, time.Time{})
+		require.NoError(t, err)
+		assert.Len(t, shares, 2)
+	})
+
+	t.Run("all ids", func(t *testing.T) {
+		ids, err := share_model.GetAllShareIDs(ctx)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{unchanged.ShareID.Hex(), changed.ShareID.Hex()}, ids)
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/ethanrous/weblens/modules/wlerrors"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // TagCollectionKey is the MongoDB collection name for tags.
@@ -54,6 +55,25 @@ func CreateTag(ctx context.Context, name, color, owner string) (*Tag, error) {
 	return tag, nil
 }
 
+// SaveTag inserts tag as it is, keeping its ID and timestamps.
+func SaveTag(ctx context.Context, tag *Tag) error {
+	col, err := db.GetCollection[any](ctx, TagCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	if tag.FileIDs == nil {
+		tag.FileIDs = []string{}
+	}
+
+	_, err = col.InsertOne(ctx, tag)
+	if err != nil {
+		return db.WrapError(err, "failed to save tag %s", tag.TagID.Hex())
+	}
+
+	return nil
+}
+
 // GetTagByID retrieves a tag by its MongoDB ObjectID.
 func GetTagByID(ctx context.Context, tagID primitive.ObjectID) (*Tag, error) {
 	col, err := db.GetCollection[any](ctx, TagCollectionKey)
@@ -127,6 +147,55 @@ func GetTagsForFile(ctx context.Context, fileID string) ([]*Tag, error) {
 	return tags, nil
 }
 
+// GetTagsUpdatedSince retrieves all tags created or changed after since. A zero since retrieves every tag.
+func GetTagsUpdatedSince(ctx context.Context, since time.Time) ([]*Tag, error) {
+	col, err := db.GetCollection[any](ctx, TagCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []*Tag{}
+
+	cursor, err := col.Find(ctx, db.UpdatedSinceFilter(since))
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get tags updated since %s", since)
+	}
+
+	if err := cursor.All(ctx, &tags); err != nil {
+		return nil, db.WrapError(err, "failed to decode tags")
+	}
+
+	return tags, nil
+}
+
+// GetAllTagIDs retrieves the IDs of every tag.
+func GetAllTagIDs(ctx context.Context) ([]string, error) {
+	col, err := db.GetCollection[any](ctx, TagCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []struct {
+		TagID primitive.ObjectID `bson:"_id"`
+	}
+
+	cursor, err := col.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get tag ids")
+	}
+
+	if err := cursor.All(ctx, &tags); err != nil {
+		return nil, db.WrapError(err, "failed to decode tag ids")
+	}
+
+	ids := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		ids = append(ids, tag.TagID.Hex())
+	}
+
+	return ids, nil
+}
+
 // UpdateTag updates a tag's name and/or color.
 func UpdateTag(ctx context.Context, tagID primitive.ObjectID, name, color string) error {
 	col, err := db.GetCollection[any](ctx, TagCollectionKey)
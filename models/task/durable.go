@@ -0,0 +1,129 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlog"
+)
+
+// ErrNotDurable indicates a task record belongs to a job that is not registered as durable.
+var ErrNotDurable = wlerrors.New("job is not durable")
+
+// ResumeTasks dispatches every durable task that was queued or executing when the server last stopped, with the
+// checkpoint it last saved. Tasks that cannot be resumed, because their job is no longer durable or their metadata
+// can no longer be loaded, are recorded as failed so they still show up in the task history.
+func (wp *WorkerPool) ResumeTasks(ctx context.Context) error {
+	records, err := GetUnfinishedRecords(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if wp.GetTask(record.TaskID) != nil {
+			continue
+		}
+
+		meta, err := wp.loadRecordMeta(ctx, record)
+		if err == nil {
+			_, err = wp.dispatchJob(ctx, record.JobName, meta, nil, record)
+		}
+
+		if err != nil {
+			wlog.FromContext(ctx).Warn().Err(err).Msgf("Could not resume [%s] task [%s]", record.JobName, record.TaskID)
+
+			record.State = Exited
+			record.ExitStatus = TaskError
+			record.Error = err.Error()
+			record.FinishTime = time.Now()
+
+			err = SaveRecord(ctx, record)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		wlog.FromContext(ctx).Info().Msgf("Resumed [%s] task [%s]", record.JobName, record.TaskID)
+	}
+
+	return nil
+}
+
+func (wp *WorkerPool) loadRecordMeta(ctx context.Context, record *Record) (Metadata, error) {
+	job := wp.getRegisteredJob(record.JobName)
+	if job.opts.LoadMeta == nil {
+		return nil, wlerrors.Wrapf(ErrNotDurable, "cannot resume [%s] task", record.JobName)
+	}
+
+	return job.opts.LoadMeta(ctx, record.Metadata)
+}
+
+// isDurable reports whether the job of the task saves its state, so it can be resumed after a restart.
+func (t *Task) isDurable() bool {
+	return t.work.opts.LoadMeta != nil
+}
+
+// saveRecord writes the current state of a durable task. Failing to save does not fail the task, it only means the
+// task will not be resumed if the server stops before the task finishes.
+func (t *Task) saveRecord() {
+	if !t.isDurable() {
+		return
+	}
+
+	record, err := t.toRecord()
+	if err == nil {
+		err = SaveRecord(context.WithoutCancel(t.Ctx), record)
+	}
+
+	if err != nil {
+		t.Log().Error().Stack().Err(err).Msg("Failed to save task record")
+	}
+}
+
+func (t *Task) toRecord() (*Record, error) {
+	meta, ok := t.GetMeta().(DurableMetadata)
+	if !ok {
+		return nil, wlerrors.Errorf("metadata of durable job [%s] cannot be serialized", t.JobName())
+	}
+
+	metaBytes, err := meta.Serialize()
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint, err := encodeResult(t.GetCheckpoint())
+	if err != nil {
+		return nil, err
+	}
+
+	// The result is only kept for the task history, so a result that cannot be encoded is left out rather than
+	// losing the rest of the record
+	result, err := encodeResult(t.GetResults())
+	if err != nil {
+		t.Log().Warn().Err(err).Msg("Could not encode task result for its record")
+	}
+
+	_, exitStatus := t.Status()
+
+	errMsg := ""
+	if err := t.ReadError(); err != nil {
+		errMsg = err.Error()
+	}
+
+	return &Record{
+		TaskID:     t.ID(),
+		JobName:    t.JobName(),
+		Metadata:   metaBytes,
+		Checkpoint: checkpoint,
+		Result:     result,
+		Error:      errMsg,
+		State:      t.QueueState(),
+		ExitStatus: exitStatus,
+		QueueTime:  t.GetQueueTime(),
+		StartTime:  t.GetStartTime(),
+		FinishTime: t.GetFinishTime(),
+	}, nil
+}
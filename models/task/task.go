@@ -35,6 +35,10 @@ type Task struct {
 	work          job
 	result        Result
 
+	// Progress saved by the task with Checkpoint, restored from its record when a durable task is resumed
+	checkpoint   Result
+	checkpointMu sync.Mutex
+
 	// Function to be run to clean up when the task completes, only if the task is successful
 	postAction func(result Result)
 
@@ -105,6 +109,11 @@ type Options struct {
 	// Priority indicates the priority of the task in the queue. Higher priority tasks are executed before lower priority ones. Larger numbers indicate higher priority.
 	// 0 is reserved to mean "unspecified": RegisterJob resolves it to PriorityDefault, so a literal priority of 0 cannot be assigned.
 	Priority int
+
+	// LoadMeta, if set, makes the job durable: its tasks are saved while they are queued and executing, and dispatched
+	// again with the metadata rebuilt by LoadMeta when the server restarts. The metadata of a durable job must
+	// implement DurableMetadata. Finished durable tasks are kept as task history for RecordRetention.
+	LoadMeta MetaLoader
 }
 
 // QueueState represents the current state of a task in the queue.
@@ -511,6 +520,43 @@ func (t *Task) AtomicSetResult(fn func(Result) Result) {
 	t.resultsMu.Unlock()
 }
 
+// Checkpoint records progress the task has made, merged into any earlier checkpoint. For durable jobs the checkpoint
+// is saved, so a task resumed after a restart can read it back with GetCheckpoint and skip the work it already did.
+func (t *Task) Checkpoint(progress Result) {
+	t.checkpointMu.Lock()
+
+	if t.checkpoint == nil {
+		t.checkpoint = Result{}
+	}
+
+	maps.Copy(t.checkpoint, progress)
+	checkpoint := maps.Clone(t.checkpoint)
+
+	t.checkpointMu.Unlock()
+
+	if !t.isDurable() {
+		return
+	}
+
+	err := SetRecordCheckpoint(context.WithoutCancel(t.Ctx), t.taskID, checkpoint)
+	if err != nil {
+		t.Log().Error().Stack().Err(err).Msg("Failed to save task checkpoint")
+	}
+}
+
+// GetCheckpoint returns a copy of the progress the task has recorded with Checkpoint, including progress restored
+// from before a restart. It is empty if the task has not made a checkpoint.
+func (t *Task) GetCheckpoint() Result {
+	t.checkpointMu.Lock()
+	defer t.checkpointMu.Unlock()
+
+	if t.checkpoint == nil {
+		return Result{}
+	}
+
+	return maps.Clone(t.checkpoint)
+}
+
 // ExeTime returns the execution duration of the task.
 func (t *Task) ExeTime() time.Duration {
 	if t.FinishTime.Load().IsZero() {
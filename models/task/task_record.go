@@ -0,0 +1,199 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/ethanrous/weblens/models/db"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RecordCollectionKey is the MongoDB collection name for the saved state of durable tasks.
+const RecordCollectionKey = "taskRecords"
+
+// RecordRetention is how long the records of finished durable tasks are kept as task history.
+const RecordRetention = time.Hour * 24 * 30
+
+// maxFinishedRecords caps how many finished records are returned as task history at once.
+const maxFinishedRecords = 500
+
+// MetaLoader rebuilds the metadata of a durable task from the bytes saved by its DurableMetadata.
+type MetaLoader func(ctx context.Context, data []byte) (Metadata, error)
+
+// DurableMetadata is Metadata that can be saved, so the task it belongs to can be dispatched again after a restart.
+type DurableMetadata interface {
+	Metadata
+
+	// Serialize returns the bytes the MetaLoader of the job uses to rebuild the metadata.
+	Serialize() ([]byte, error)
+}
+
+// Record is the saved state of a durable task. Records of tasks that have not exited are used to dispatch the
+// tasks again when the server starts, and records of finished tasks are kept as task history.
+type Record struct {
+	TaskID   string `bson:"_id"`
+	JobName  string `bson:"jobName"`
+	Metadata []byte `bson:"metadata"`
+	// Checkpoint and Result are JSON encoded, so any result a task sets can be saved regardless of its types
+	Checkpoint string     `bson:"checkpoint,omitempty"`
+	Result     string     `bson:"result,omitempty"`
+	Error      string     `bson:"error,omitempty"`
+	State      QueueState `bson:"state"`
+	ExitStatus ExitStatus `bson:"exitStatus"`
+	QueueTime  time.Time  `bson:"queueTime"`
+	StartTime  time.Time  `bson:"startTime"`
+	FinishTime time.Time  `bson:"finishTime"`
+}
+
+// GetCheckpoint returns the last progress the task saved with Task.Checkpoint.
+func (r *Record) GetCheckpoint() Result {
+	return decodeResult(r.Checkpoint)
+}
+
+// GetResult returns the result the task had when the record was last saved.
+func (r *Record) GetResult() Result {
+	return decodeResult(r.Result)
+}
+
+// SaveRecord saves the record, replacing any earlier record of the same task.
+func SaveRecord(ctx context.Context, record *Record) error {
+	collection, err := db.GetCollection[*Record](ctx, RecordCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.ReplaceOne(ctx, bson.M{"_id": record.TaskID}, record, options.Replace().SetUpsert(true))
+	if err != nil {
+		return db.WrapError(err, "failed to save record of task [%s]", record.TaskID)
+	}
+
+	return nil
+}
+
+// SetRecordCheckpoint updates the saved checkpoint of the task with taskID.
+func SetRecordCheckpoint(ctx context.Context, taskID string, checkpoint Result) error {
+	collection, err := db.GetCollection[*Record](ctx, RecordCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := encodeResult(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": taskID}, bson.M{"$set": bson.M{"checkpoint": encoded}})
+	if err != nil {
+		return db.WrapError(err, "failed to save checkpoint of task [%s]", taskID)
+	}
+
+	return nil
+}
+
+// GetRecordByID retrieves the record of the task with taskID.
+func GetRecordByID(ctx context.Context, taskID string) (*Record, error) {
+	collection, err := db.GetCollection[*Record](ctx, RecordCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &Record{}
+
+	err = collection.FindOne(ctx, bson.M{"_id": taskID}).Decode(record)
+	if err != nil {
+		return nil, db.WrapError(wlerrors.WithStack(err), "failed to get record of task [%s]", taskID)
+	}
+
+	return record, nil
+}
+
+// GetUnfinishedRecords retrieves the records of every task that was queued or executing when it was last saved.
+func GetUnfinishedRecords(ctx context.Context) ([]*Record, error) {
+	collection, err := db.GetCollection[*Record](ctx, RecordCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "queueTime", Value: 1}})
+
+	cursor, err := collection.Find(ctx, bson.M{"state": bson.M{"$ne": Exited}}, opts)
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get unfinished task records")
+	}
+
+	var records []*Record
+
+	err = cursor.All(ctx, &records)
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get unfinished task records")
+	}
+
+	return records, nil
+}
+
+// GetFinishedRecords retrieves the records of tasks that finished at or after since, most recent first.
+func GetFinishedRecords(ctx context.Context, since time.Time) ([]*Record, error) {
+	collection, err := db.GetCollection[*Record](ctx, RecordCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "finishTime", Value: -1}}).SetLimit(maxFinishedRecords)
+
+	cursor, err := collection.Find(ctx, bson.M{"state": Exited, "finishTime": bson.M{"$gte": since}}, opts)
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get finished task records")
+	}
+
+	var records []*Record
+
+	err = cursor.All(ctx, &records)
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get finished task records")
+	}
+
+	return records, nil
+}
+
+// DeleteFinishedRecords deletes the records of tasks that finished before the given time.
+func DeleteFinishedRecords(ctx context.Context, before time.Time) error {
+	collection, err := db.GetCollection[*Record](ctx, RecordCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.DeleteMany(ctx, bson.M{"state": Exited, "finishTime": bson.M{"$lt": before}})
+	if err != nil {
+		return db.WrapError(err, "failed to delete finished task records")
+	}
+
+	return nil
+}
+
+func encodeResult(r Result) (string, error) {
+	if len(r) == 0 {
+		return "", nil
+	}
+
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return "", wlerrors.WithStack(err)
+	}
+
+	return string(bs), nil
+}
+
+func decodeResult(s string) Result {
+	r := Result{}
+	if s == "" {
+		return r
+	}
+
+	// Records are only ever written by encodeResult, so a record that cannot be decoded is treated as empty
+	_ = json.Unmarshal([]byte(s), &r)
+
+	return r
+}
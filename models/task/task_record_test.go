@@ -0,0 +1,214 @@
+package task_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ethanrous/weblens/models/db"
+	"github.com/ethanrous/weblens/models/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// durableMeta is a task.DurableMetadata that serializes to its ID.
+type durableMeta struct {
+	jobName string
+	ID      string `json:"id"`
+}
+
+func (m durableMeta) JobName() string             { return m.jobName }
+func (m durableMeta) MetaString() string          { return m.jobName + ":" + m.ID }
+func (m durableMeta) FormatToResult() task.Result { return task.Result{} }
+func (m durableMeta) Verify() error               { return nil }
+
+func (m durableMeta) Serialize() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+func loadDurableMeta(jobName string) task.MetaLoader {
+	return func(_ context.Context, data []byte) (task.Metadata, error) {
+		meta := durableMeta{jobName: jobName}
+
+		err := json.Unmarshal(data, &meta)
+
+		return meta, err
+	}
+}
+
+func TestRecords(t *testing.T) {
+	ctx := db.SetupTestDB(t, task.RecordCollectionKey)
+
+	t.Run("SaveAndGet", func(t *testing.T) {
+		record := &task.Record{TaskID: "save-and-get", JobName: "test-job", Metadata: []byte(`{"id":"1"}`), State: task.InQueue}
+		require.NoError(t, task.SaveRecord(ctx, record))
+
+		saved, err := task.GetRecordByID(ctx, record.TaskID)
+		require.NoError(t, err)
+		assert.Equal(t, "test-job", saved.JobName)
+		assert.Equal(t, task.InQueue, saved.State)
+		assert.JSONEq(t, `{"id":"1"}`, string(saved.Metadata))
+		assert.Empty(t, saved.GetCheckpoint())
+	})
+
+	t.Run("GetMissing", func(t *testing.T) {
+		_, err := task.GetRecordByID(ctx, "not-a-task")
+		assert.True(t, db.IsNotFound(err))
+	})
+
+	t.Run("SetCheckpoint", func(t *testing.T) {
+		record := &task.Record{TaskID: "set-checkpoint", JobName: "test-job", State: task.Executing}
+		require.NoError(t, task.SaveRecord(ctx, record))
+
+		require.NoError(t, task.SetRecordCheckpoint(ctx, record.TaskID, task.Result{"done": 3}))
+
+		saved, err := task.GetRecordByID(ctx, record.TaskID)
+		require.NoError(t, err)
+		assert.InDelta(t, 3, saved.GetCheckpoint()["done"], 0)
+	})
+
+	t.Run("UnfinishedAndFinished", func(t *testing.T) {
+		now := time.Now()
+
+		require.NoError(t, task.SaveRecord(ctx, &task.Record{TaskID: "queued", JobName: "test-job", State: task.InQueue}))
+		require.NoError(t, task.SaveRecord(ctx, &task.Record{TaskID: "recent", JobName: "test-job", State: task.Exited, ExitStatus: task.TaskSuccess, FinishTime: now}))
+		require.NoError(t, task.SaveRecord(ctx, &task.Record{TaskID: "old", JobName: "test-job", State: task.Exited, ExitStatus: task.TaskError, FinishTime: now.Add(-time.Hour)}))
+
+		unfinished, err := task.GetUnfinishedRecords(ctx)
+		require.NoError(t, err)
+
+		unfinishedIDs := make([]string, 0, len(unfinished))
+		for _, r := range unfinished {
+			unfinishedIDs = append(unfinishedIDs, r.TaskID)
+		}
+
+		assert.Contains(t, unfinishedIDs, "queued")
+		assert.NotContains(t, unfinishedIDs, "recent")
+		assert.NotContains(t, unfinishedIDs, "old")
+
+		finished, err := task.GetFinishedRecords(ctx, now.Add(-time.Minute))
+		require.NoError(t, err)
+		require.Len(t, finished, 1)
+		assert.Equal(t, "recent", finished[0].TaskID)
+
+		require.NoError(t, task.DeleteFinishedRecords(ctx, now.Add(-time.Minute)))
+
+		_, err = task.GetRecordByID(ctx, "old")
+		assert.True(t, db.IsNotFound(err))
+
+		_, err = task.GetRecordByID(ctx, "recent")
+		assert.NoError(t, err)
+	})
+}
+
+func TestWorkerPool_DurableJobs(t *testing.T) {
+	ctx := db.SetupTestDB(t, task.RecordCollectionKey)
+
+	t.Run("rejects metadata that cannot be serialized", func(t *testing.T) {
+		wp := task.NewTestWorkerPool(1)
+		wp.Run(task.NewTestContext())
+		wp.RegisterJob("durable-job", func(tsk *task.Task) { tsk.Success() }, task.Options{LoadMeta: loadDurableMeta("durable-job")})
+
+		_, err := wp.DispatchJob(ctx, "durable-job", task.NewTestMetadata("durable-job"), nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("saves the checkpoint and outcome of a task", func(t *testing.T) {
+		wp := task.NewTestWorkerPool(1)
+		wp.Run(task.NewTestContext())
+		wp.RegisterJob("checkpoint-job", func(tsk *task.Task) {
+			tsk.Checkpoint(task.Result{"step": "first"})
+			tsk.Checkpoint(task.Result{"count": 2})
+			tsk.SetResult(task.Result{"finished": true})
+			tsk.Success()
+		}, task.Options{LoadMeta: loadDurableMeta("checkpoint-job")})
+
+		tsk, err := wp.DispatchJob(ctx, "checkpoint-job", durableMeta{jobName: "checkpoint-job", ID: "a"}, nil)
+		require.NoError(t, err)
+
+		tsk.Wait()
+
+		record, err := task.GetRecordByID(ctx, tsk.ID())
+		require.NoError(t, err)
+		assert.Equal(t, task.Exited, record.State)
+		assert.Equal(t, task.TaskSuccess, record.ExitStatus)
+		assert.Equal(t, "first", record.GetCheckpoint()["step"])
+		assert.InDelta(t, 2, record.GetCheckpoint()["count"], 0)
+		assert.Equal(t, true, record.GetResult()["finished"])
+		assert.False(t, record.FinishTime.IsZero())
+	})
+
+	t.Run("does not save tasks of jobs that are not durable", func(t *testing.T) {
+		wp := task.NewTestWorkerPool(1)
+		wp.Run(task.NewTestContext())
+		wp.RegisterJob("plain-job", func(tsk *task.Task) { tsk.Success() })
+
+		tsk, err := wp.DispatchJob(ctx, "plain-job", durableMeta{jobName: "plain-job", ID: "b"}, nil)
+		require.NoError(t, err)
+
+		tsk.Wait()
+
+		_, err = task.GetRecordByID(ctx, tsk.ID())
+		assert.True(t, db.IsNotFound(err))
+	})
+
+	t.Run("resumes unfinished tasks from their checkpoint", func(t *testing.T) {
+		require.NoError(t, task.SaveRecord(ctx, &task.Record{
+			TaskID:     "resume-me",
+			JobName:    "resume-job",
+			Metadata:   []byte(`{"id":"c"}`),
+			Checkpoint: `{"done":5}`,
+			State:      task.Executing,
+		}))
+
+		var (
+			gotMeta       durableMeta
+			gotCheckpoint task.Result
+		)
+
+		wp := task.NewTestWorkerPool(1)
+		wp.Run(task.NewTestContext())
+		wp.RegisterJob("resume-job", func(tsk *task.Task) {
+			gotMeta = tsk.GetMeta().(durableMeta)
+			gotCheckpoint = tsk.GetCheckpoint()
+			tsk.Success()
+		}, task.Options{LoadMeta: loadDurableMeta("resume-job")})
+
+		require.NoError(t, wp.ResumeTasks(ctx))
+
+		tsk := wp.GetTask("resume-me")
+		require.NotNil(t, tsk)
+
+		tsk.Wait()
+
+		assert.Equal(t, "c", gotMeta.ID)
+		assert.InDelta(t, 5, gotCheckpoint["done"], 0)
+
+		record, err := task.GetRecordByID(ctx, "resume-me")
+		require.NoError(t, err)
+		assert.Equal(t, task.Exited, record.State)
+		assert.Equal(t, task.TaskSuccess, record.ExitStatus)
+	})
+
+	t.Run("records tasks that cannot be resumed as failed", func(t *testing.T) {
+		require.NoError(t, task.SaveRecord(ctx, &task.Record{
+			TaskID:  "orphaned",
+			JobName: "unregistered-job",
+			State:   task.InQueue,
+		}))
+
+		wp := task.NewTestWorkerPool(1)
+		wp.Run(task.NewTestContext())
+
+		require.NoError(t, wp.ResumeTasks(ctx))
+
+		assert.Nil(t, wp.GetTask("orphaned"))
+
+		record, err := task.GetRecordByID(ctx, "orphaned")
+		require.NoError(t, err)
+		assert.Equal(t, task.Exited, record.State)
+		assert.Equal(t, task.TaskError, record.ExitStatus)
+		assert.NotEmpty(t, record.Error)
+	})
+}
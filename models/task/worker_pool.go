@@ -220,6 +220,12 @@ func (wp *WorkerPool) RegisterJob(jobName string, fn HandlerFunc, opts ...Option
 
 // DispatchJob creates and queues a new task for the specified registered job.
 func (wp *WorkerPool) DispatchJob(ctx context.Context, jobName string, meta Metadata, pool *Pool) (*Task, error) {
+	return wp.dispatchJob(ctx, jobName, meta, pool, nil)
+}
+
+// dispatchJob creates and queues a new task for the specified registered job. If resume is set, the task is a
+// durable task being dispatched again after a restart, and takes its ID and checkpoint from the record.
+func (wp *WorkerPool) dispatchJob(ctx context.Context, jobName string, meta Metadata, pool *Pool, resume *Record) (*Task, error) {
 	if meta.JobName() != jobName {
 		return nil, wlerrors.Errorf("job name does not match task metadata")
 	}
@@ -244,7 +250,14 @@ func (wp *WorkerPool) DispatchJob(ctx context.Context, jobName string, meta Meta
 
 	job := wp.getRegisteredJob(jobName)
 
+	if _, ok := meta.(DurableMetadata); job.opts.LoadMeta != nil && !ok {
+		return nil, wlerrors.Errorf("metadata of durable job [%s] cannot be serialized", jobName)
+	}
+
 	taskID := makeTaskID(meta, job.opts.Unique)
+	if resume != nil {
+		taskID = resume.TaskID
+	}
 
 	t := wp.GetTask(taskID)
 
@@ -307,6 +320,10 @@ func (wp *WorkerPool) DispatchJob(ctx context.Context, jobName string, meta Meta
 		stopShutdownLink: stopShutdownLink,
 	}
 
+	if resume != nil {
+		t.checkpoint = resume.GetCheckpoint()
+	}
+
 	t.Log().Trace().Stack().Msgf("Task [%s] created", taskID)
 
 	// Checked synchronously against wp.ctx because the global-pool detachment above
@@ -422,6 +439,9 @@ func (wp *WorkerPool) queueTask(t *Task, pool *Pool) {
 	// Set the queue time before the task becomes visible to the scheduler; it is used for status reporting and timeout checks
 	t.QueueTime.Set(time.Now())
 
+	// Save durable tasks before the scheduler can see them, so they are resumed if the server stops while they are queued
+	t.saveRecord()
+
 	// Add task to queue in sorted order by priority (higher priority tasks should be closer to the front of the queue).
 	// Upper-bound insert: after all tasks with priority >= ours, so equal-priority tasks keep FIFO order.
 	wp.taskQueueMu.Lock()
@@ -585,6 +605,12 @@ func (wp *WorkerPool) completeTask(t *Task, workerID int64, isReplacement bool)
 
 	t.updateMu.Unlock()
 
+	// A durable task stopped by the worker pool going down keeps the record of where it was, so it is resumed when
+	// the server starts again. Otherwise the record is updated to become part of the task history.
+	if t.isDurable() && (wp.ctx.Err() == nil || t.exitStatus.Load() == TaskSuccess) {
+		t.saveRecord()
+	}
+
 	// Wake any waiters on this task
 	t.closeWaitChan()
 
@@ -855,6 +881,7 @@ func (wp *WorkerPool) safetyWork(task *Task, workerID int64) {
 		task.Log().Trace().Msgf("Task [%s] already has exit status [%s], not running", task.taskID, task.exitStatus.Load())
 	} else {
 		task.StartTime.Set(time.Now())
+		task.saveRecord()
 
 		task.work.handler(task)
 
@@ -69,6 +69,14 @@ type Instance struct {
 	// The time of the latest backup, in milliseconds since epoch
 	LastBackup int64 `bson:"lastBackup"`
 
+	// The position in the file history of the core that has been backed up so far, only set on backup towers.
+	// An interrupted backup resumes from here.
+	BackupCursor string `bson:"backupCursor,omitempty"`
+
+	// The time, in milliseconds since epoch on the core, that the shares, tags, covers and media of the core were last
+	// backed up at, only set on backup towers.
+	BackupMetadataTime int64 `bson:"backupMetadataTime,omitempty"`
+
 	// The private ID of the tower only in the local database
 	DbID primitive.ObjectID `bson:"_id"`
 
@@ -235,6 +243,36 @@ func SetLastBackup(ctx context.Context, towerID string, lastBackup time.Time, ba
 	return nil
 }
 
+// SetBackupCursor records how far into the file history of the core with towerID the local tower has backed up.
+func SetBackupCursor(ctx context.Context, towerID string, cursor string) error {
+	col, err := db.GetCollection[any](ctx, TowerCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = col.UpdateOne(ctx, bson.M{"towerID": towerID}, bson.M{"$set": bson.M{"backupCursor": cursor}})
+	if err != nil {
+		return db.WrapError(err, "failed to set backup cursor of tower [%s]", towerID)
+	}
+
+	return nil
+}
+
+// SetBackupMetadataTime records when the shares, tags, covers and media of the core with towerID were last backed up.
+func SetBackupMetadataTime(ctx context.Context, towerID string, metadataTime int64) error {
+	col, err := db.GetCollection[any](ctx, TowerCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = col.UpdateOne(ctx, bson.M{"towerID": towerID}, bson.M{"$set": bson.M{"backupMetadataTime": metadataTime}})
+	if err != nil {
+		return db.WrapError(err, "failed to set backup metadata time of tower [%s]", towerID)
+	}
+
+	return nil
+}
+
 // UpdateTower updates a tower instance in the database.
 func UpdateTower(ctx context.Context, tower *Instance) error {
 	if tower.DbID.IsZero() {
@@ -0,0 +1,154 @@
+// Package tus provides persistence for resumable uploads made with the tus protocol.
+package tus
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethanrous/weblens/models/db"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UploadCollectionKey is the MongoDB collection name for resumable uploads.
+const UploadCollectionKey = "tusUploads"
+
+// UploadLifetime is how long an upload may sit without receiving any data before it is abandoned.
+const UploadLifetime = time.Hour * 24 * 7
+
+// Upload tracks the progress of a single resumable file upload. The bytes received so far are kept in the uploads
+// cache directory until the upload is complete, at which point the file is created in its destination folder.
+type Upload struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	Owner    string             `bson:"owner"`
+	ParentID string             `bson:"parentID"`
+	FileName string             `bson:"fileName"`
+	// FileID is the existing file the upload will become the new content of, or empty if the upload creates a new file
+	FileID string `bson:"fileID,omitempty"`
+	// ShareID is the share the upload was created through, if any, which access to its destination is checked with
+	ShareID string `bson:"shareID,omitempty"`
+	// Metadata is the raw, decoded, Upload-Metadata sent by the client when the upload was created
+	Metadata map[string]string `bson:"metadata"`
+	Size     int64             `bson:"size"`
+	Offset   int64             `bson:"offset"`
+	Created  time.Time         `bson:"created"`
+	Updated  time.Time         `bson:"updated"`
+}
+
+// NewUpload creates a new Upload of size bytes, to be written as fileName inside the folder parentID.
+func NewUpload(owner, parentID, fileName string, size int64, metadata map[string]string) *Upload {
+	now := time.Now()
+
+	return &Upload{
+		ID:       primitive.NewObjectID(),
+		Owner:    owner,
+		ParentID: parentID,
+		FileName: fileName,
+		Metadata: metadata,
+		Size:     size,
+		Created:  now,
+		Updated:  now,
+	}
+}
+
+// Expires returns the time after which the upload will be abandoned if it receives no more data.
+func (u *Upload) Expires() time.Time {
+	return u.Updated.Add(UploadLifetime)
+}
+
+// IsComplete returns true if every byte of the upload has been received.
+func (u *Upload) IsComplete() bool {
+	return u.Offset >= u.Size
+}
+
+// SaveUpload saves a new Upload to the database.
+func SaveUpload(ctx context.Context, upload *Upload) error {
+	collection, err := db.GetCollection[*Upload](ctx, UploadCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	if upload.ID.IsZero() {
+		upload.ID = primitive.NewObjectID()
+	}
+
+	_, err = collection.InsertOne(ctx, upload)
+	if err != nil {
+		return db.WrapError(err, "failed to save upload [%s]", upload.ID.Hex())
+	}
+
+	return nil
+}
+
+// GetUploadByID retrieves an Upload by its ID.
+func GetUploadByID(ctx context.Context, uploadID primitive.ObjectID) (*Upload, error) {
+	collection, err := db.GetCollection[*Upload](ctx, UploadCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	upload := &Upload{}
+
+	err = collection.FindOne(ctx, bson.M{"_id": uploadID}).Decode(upload)
+	if err != nil {
+		return nil, db.WrapError(wlerrors.WithStack(err), "failed to get upload [%s]", uploadID.Hex())
+	}
+
+	return upload, nil
+}
+
+// GetStaleUploads retrieves all Uploads that have not received any data within UploadLifetime.
+func GetStaleUploads(ctx context.Context) ([]*Upload, error) {
+	collection, err := db.GetCollection[*Upload](ctx, UploadCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(ctx, bson.M{"updated": bson.M{"$lt": time.Now().Add(-UploadLifetime)}})
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get stale uploads")
+	}
+
+	var uploads []*Upload
+
+	err = cursor.All(ctx, &uploads)
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get stale uploads")
+	}
+
+	return uploads, nil
+}
+
+// SetOffset records how many bytes of the upload have been received.
+func (u *Upload) SetOffset(ctx context.Context, offset int64) error {
+	collection, err := db.GetCollection[*Upload](ctx, UploadCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	u.Offset = offset
+	u.Updated = time.Now()
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": u.ID}, bson.M{"$set": bson.M{"offset": u.Offset, "updated": u.Updated}})
+	if err != nil {
+		return db.WrapError(err, "failed to update offset of upload [%s]", u.ID.Hex())
+	}
+
+	return nil
+}
+
+// DeleteUpload deletes an Upload from the database.
+func DeleteUpload(ctx context.Context, uploadID primitive.ObjectID) error {
+	collection, err := db.GetCollection[*Upload](ctx, UploadCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.DeleteOne(ctx, bson.M{"_id": uploadID})
+	if err != nil {
+		return db.WrapError(err, "failed to delete upload [%s]", uploadID.Hex())
+	}
+
+	return nil
+}
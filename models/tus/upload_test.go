@@ -0,0 +1,82 @@
+package tus_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethanrous/weblens/models/db"
+	tus_model "github.com/ethanrous/weblens/models/tus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUpload(t *testing.T) {
+	ctx := db.SetupTestDB(t, tus_model.UploadCollectionKey)
+
+	t.Run("SaveAndGet", func(t *testing.T) {
+		upload := tus_model.NewUpload("testuser", primitive.NewObjectID().Hex(), "file.txt", 100, map[string]string{"filename": "file.txt"})
+
+		err := tus_model.SaveUpload(ctx, upload)
+		require.NoError(t, err)
+
+		saved, err := tus_model.GetUploadByID(ctx, upload.ID)
+		require.NoError(t, err)
+		assert.Equal(t, upload.Owner, saved.Owner)
+		assert.Equal(t, upload.FileName, saved.FileName)
+		assert.Equal(t, int64(100), saved.Size)
+		assert.Equal(t, int64(0), saved.Offset)
+		assert.Equal(t, "file.txt", saved.Metadata["filename"])
+		assert.False(t, saved.IsComplete())
+	})
+
+	t.Run("SetOffset", func(t *testing.T) {
+		upload := tus_model.NewUpload("testuser", primitive.NewObjectID().Hex(), "offset.txt", 10, nil)
+		require.NoError(t, tus_model.SaveUpload(ctx, upload))
+
+		require.NoError(t, upload.SetOffset(ctx, 4))
+
+		saved, err := tus_model.GetUploadByID(ctx, upload.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), saved.Offset)
+		assert.False(t, saved.IsComplete())
+
+		require.NoError(t, upload.SetOffset(ctx, 10))
+		assert.True(t, upload.IsComplete())
+	})
+
+	t.Run("GetMissing", func(t *testing.T) {
+		_, err := tus_model.GetUploadByID(ctx, primitive.NewObjectID())
+		assert.True(t, db.IsNotFound(err))
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		upload := tus_model.NewUpload("testuser", primitive.NewObjectID().Hex(), "delete.txt", 10, nil)
+		require.NoError(t, tus_model.SaveUpload(ctx, upload))
+
+		require.NoError(t, tus_model.DeleteUpload(ctx, upload.ID))
+
+		_, err := tus_model.GetUploadByID(ctx, upload.ID)
+		assert.True(t, db.IsNotFound(err))
+	})
+
+	t.Run("GetStaleUploads", func(t *testing.T) {
+		fresh := tus_model.NewUpload("testuser", primitive.NewObjectID().Hex(), "fresh.txt", 10, nil)
+		require.NoError(t, tus_model.SaveUpload(ctx, fresh))
+
+		stale := tus_model.NewUpload("testuser", primitive.NewObjectID().Hex(), "stale.txt", 10, nil)
+		stale.Updated = time.Now().Add(-tus_model.UploadLifetime - time.Hour)
+		require.NoError(t, tus_model.SaveUpload(ctx, stale))
+
+		uploads, err := tus_model.GetStaleUploads(ctx)
+		require.NoError(t, err)
+
+		ids := make([]primitive.ObjectID, 0, len(uploads))
+		for _, u := range uploads {
+			ids = append(ids, u.ID)
+		}
+
+		assert.Contains(t, ids, stale.ID)
+		assert.NotContains(t, ids, fresh.ID)
+	})
+}
@@ -56,6 +56,33 @@ type User struct {
 
 	// Timestamp of when the user was updated last
 	UpdatedAt int64 `bson:"updatedAt"`
+
+	// TrashRetentionDays is how many days items stay in the user's trash before they are purged. 0 uses the
+	// server-wide retention, and a negative value keeps items until they are deleted by hand.
+	TrashRetentionDays int `bson:"trashRetentionDays"`
+
+	// QuotaBytes is how many bytes the files of the user, including those in their trash, may take up. 0 means there
+	// is no limit.
+	QuotaBytes int64 `bson:"quotaBytes"`
+
+	// TOTPSecret is the base32 secret the user's authenticator app generates codes from. It is set as soon as the user
+	// starts enrolling, but is only checked at login once TOTPEnabled is true.
+	TOTPSecret string `bson:"totpSecret,omitempty"`
+
+	// TOTPEnabled is true once the user has confirmed their authenticator app, and must enter a code to log in
+	TOTPEnabled bool `bson:"totpEnabled"`
+
+	// TOTPLastStep is the TOTP period of the last code the user logged in with, so the same code can't be used twice
+	TOTPLastStep int64 `bson:"totpLastStep"`
+
+	// RecoveryCodes are the hashes of the one-time codes the user can log in with if they lose their authenticator
+	RecoveryCodes []string `bson:"recoveryCodes,omitempty"`
+
+	// OIDCIssuer is the OpenID Connect provider the user logs in with, if any
+	OIDCIssuer string `bson:"oidcIssuer,omitempty"`
+
+	// OIDCSubject is the ID of the user at OIDCIssuer, which unlike their username there never changes
+	OIDCSubject string `bson:"oidcSubject,omitempty"`
 }
 
 // GetUsername returns the user's unique username.
@@ -93,6 +120,21 @@ func (u *User) IsSystemUser() bool {
 	return u.UserPerms >= UserPermissionSystem
 }
 
+// HasQuota returns true if the user has a limit on how much storage their files may take up.
+func (u *User) HasQuota() bool {
+	return u.QuotaBytes > 0
+}
+
+// HasTOTP returns true if the user must enter a TOTP code, or a recovery code, to log in.
+func (u *User) HasTOTP() bool {
+	return u.TOTPEnabled && u.TOTPSecret != ""
+}
+
+// HasOIDCSubject returns true if the user is linked to an account at an OpenID Connect provider.
+func (u *User) HasOIDCSubject() bool {
+	return u.OIDCSubject != ""
+}
+
 // IsActive returns true if the user account is activated.
 func (u *User) IsActive() bool {
 	return u.Activated
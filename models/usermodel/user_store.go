@@ -2,6 +2,7 @@ package usermodel
 
 import (
 	"context"
+	"slices"
 	"time"
 
 	"github.com/ethanrous/weblens/models/db"
@@ -38,6 +39,34 @@ func SaveUser(ctx context.Context, u *User) (err error) {
 	return
 }
 
+// RestoreUser saves a user recovered from a backup. The password of an archived user is already hashed, so unlike
+// SaveUser it is stored as-is.
+func RestoreUser(ctx context.Context, u *User) error {
+	if err := validateUsername(ctx, u.Username); err != nil {
+		return err
+	}
+
+	if u.Password == "" {
+		return wlerrors.New("restored user has no password")
+	}
+
+	if u.ID.IsZero() {
+		u.ID = primitive.NewObjectID()
+	}
+
+	col, err := db.GetCollection[any](ctx, UserCollectionKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = col.InsertOne(ctx, u)
+	if err != nil {
+		return db.WrapError(err, "failed to restore user [%s]", u.Username)
+	}
+
+	return nil
+}
+
 // GetUserByUsername retrieves a user from the database by their username.
 func GetUserByUsername(ctx context.Context, username string) (u *User, err error) {
 	col, err := db.GetCollection[any](ctx, UserCollectionKey)
@@ -190,6 +219,199 @@ func (u *User) UpdateTrashID(ctx context.Context, newTrashID string) (err error)
 	return
 }
 
+// UpdateTrashRetention updates how many days items stay in the user's trash before they are purged.
+func (u *User) UpdateTrashRetention(ctx context.Context, days int) (err error) {
+	col, err := db.GetCollection[any](ctx, UserCollectionKey)
+	if err != nil {
+		return
+	}
+
+	u.UpdatedAt = time.Now().UnixMilli()
+
+	_, err = col.UpdateOne(ctx, bson.M{"_id": u.ID}, bson.M{"$set": bson.M{"trashRetentionDays": days}})
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	u.TrashRetentionDays = days
+
+	return
+}
+
+// UpdateQuota updates how many bytes the files of the user may take up. 0 removes the limit.
+func (u *User) UpdateQuota(ctx context.Context, quotaBytes int64) (err error) {
+	col, err := db.GetCollection[any](ctx, UserCollectionKey)
+	if err != nil {
+		return
+	}
+
+	u.UpdatedAt = time.Now().UnixMilli()
+
+	_, err = col.UpdateOne(ctx, bson.M{"_id": u.ID}, bson.M{"$set": bson.M{"quotaBytes": quotaBytes}})
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	u.QuotaBytes = quotaBytes
+
+	return
+}
+
+// SetPendingTOTPSecret stores a new TOTP secret for the user, which is not checked at login until EnableTOTP is called.
+func (u *User) SetPendingTOTPSecret(ctx context.Context, secret string) (err error) {
+	col, err := db.GetCollection[any](ctx, UserCollectionKey)
+	if err != nil {
+		return
+	}
+
+	u.UpdatedAt = time.Now().UnixMilli()
+
+	_, err = col.UpdateOne(ctx, bson.M{"_id": u.ID}, bson.M{"$set": bson.M{"totpSecret": secret, "totpEnabled": false}})
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	u.TOTPSecret = secret
+	u.TOTPEnabled = false
+
+	return
+}
+
+// EnableTOTP turns on TOTP for the user, replacing their recovery codes with recoveryCodeHashes. lastStep is the TOTP
+// period of the code the user confirmed their authenticator with, which may not be used again.
+func (u *User) EnableTOTP(ctx context.Context, lastStep int64, recoveryCodeHashes []string) (err error) {
+	col, err := db.GetCollection[any](ctx, UserCollectionKey)
+	if err != nil {
+		return
+	}
+
+	u.UpdatedAt = time.Now().UnixMilli()
+
+	_, err = col.UpdateOne(
+		ctx,
+		bson.M{"_id": u.ID},
+		bson.M{"$set": bson.M{"totpEnabled": true, "totpLastStep": lastStep, "recoveryCodes": recoveryCodeHashes}},
+	)
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	u.TOTPEnabled = true
+	u.TOTPLastStep = lastStep
+	u.RecoveryCodes = recoveryCodeHashes
+
+	return
+}
+
+// DisableTOTP turns off TOTP for the user, and removes their secret and recovery codes.
+func (u *User) DisableTOTP(ctx context.Context) (err error) {
+	col, err := db.GetCollection[any](ctx, UserCollectionKey)
+	if err != nil {
+		return
+	}
+
+	u.UpdatedAt = time.Now().UnixMilli()
+
+	_, err = col.UpdateOne(
+		ctx,
+		bson.M{"_id": u.ID},
+		bson.M{"$set": bson.M{"totpEnabled": false}, "$unset": bson.M{"totpSecret": "", "recoveryCodes": ""}},
+	)
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	u.TOTPSecret = ""
+	u.TOTPEnabled = false
+	u.RecoveryCodes = nil
+
+	return
+}
+
+// UseTOTPStep records that the user logged in with a TOTP code from the given period. It returns false, without an
+// error, if a code from that period or a later one was already used, so each code is only accepted once even when
+// two logins race.
+func (u *User) UseTOTPStep(ctx context.Context, step int64) (ok bool, err error) {
+	col, err := db.GetCollection[any](ctx, UserCollectionKey)
+	if err != nil {
+		return
+	}
+
+	res, err := col.UpdateOne(ctx, bson.M{"_id": u.ID, "totpLastStep": bson.M{"$lt": step}}, bson.M{"$set": bson.M{"totpLastStep": step}})
+	if err != nil {
+		return false, wlerrors.WithStack(err)
+	}
+
+	if res.ModifiedCount == 0 {
+		return false, nil
+	}
+
+	u.TOTPLastStep = step
+
+	return true, nil
+}
+
+// UseRecoveryCode removes the recovery code with the given hash from the user. It returns false, without an error, if
+// the user has no such code.
+func (u *User) UseRecoveryCode(ctx context.Context, codeHash string) (ok bool, err error) {
+	col, err := db.GetCollection[any](ctx, UserCollectionKey)
+	if err != nil {
+		return
+	}
+
+	res, err := col.UpdateOne(ctx, bson.M{"_id": u.ID, "recoveryCodes": codeHash}, bson.M{"$pull": bson.M{"recoveryCodes": codeHash}})
+	if err != nil {
+		return false, wlerrors.WithStack(err)
+	}
+
+	if res.ModifiedCount == 0 {
+		return false, nil
+	}
+
+	u.RecoveryCodes = slices.DeleteFunc(u.RecoveryCodes, func(h string) bool { return h == codeHash })
+
+	return true, nil
+}
+
+// GetUserByOIDCSubject retrieves the user linked to the given account at an OpenID Connect provider.
+func GetUserByOIDCSubject(ctx context.Context, issuer, subject string) (u *User, err error) {
+	col, err := db.GetCollection[any](ctx, UserCollectionKey)
+	if err != nil {
+		return
+	}
+
+	u = &User{}
+	filter := bson.M{"oidcIssuer": issuer, "oidcSubject": subject}
+
+	err = col.FindOne(ctx, filter).Decode(u)
+	if err != nil {
+		return nil, db.WrapError(err, "failed to get user by oidc subject [%s]", subject)
+	}
+
+	return
+}
+
+// LinkOIDCSubject links the user to an account at an OpenID Connect provider, so they are found by it when they next
+// log in there.
+func (u *User) LinkOIDCSubject(ctx context.Context, issuer, subject string) (err error) {
+	col, err := db.GetCollection[any](ctx, UserCollectionKey)
+	if err != nil {
+		return
+	}
+
+	u.UpdatedAt = time.Now().UnixMilli()
+
+	_, err = col.UpdateOne(ctx, bson.M{"_id": u.ID}, bson.M{"$set": bson.M{"oidcIssuer": issuer, "oidcSubject": subject}})
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	u.OIDCIssuer = issuer
+	u.OIDCSubject = subject
+
+	return
+}
+
 // UpdateActivationStatus updates the user's account activation status in the database.
 func (u *User) UpdateActivationStatus(ctx context.Context, active bool) (err error) {
 	col, err := db.GetCollection[any](ctx, UserCollectionKey)
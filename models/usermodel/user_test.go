@@ -110,6 +110,35 @@ func TestUser_Creation(t *testing.T) {
 			assert.Error(t, err, "Should fail for password: %s", password)
 		}
 	})
+
+	t.Run("RestoreUserKeepsHashedPassword", func(t *testing.T) {
+		hashed, err := cryptography.HashUserPassword(ctx, testPassword)
+		require.NoError(t, err)
+
+		usr := &usermodel.User{
+			Username:    testUsername + "_restored",
+			Password:    hashed,
+			DisplayName: testDisplayName,
+		}
+
+		err = usermodel.RestoreUser(ctx, usr)
+		require.NoError(t, err)
+		assert.False(t, usr.ID.IsZero())
+
+		restored, err := usermodel.GetUserByUsername(ctx, usr.Username)
+		require.NoError(t, err)
+		assert.Equal(t, hashed, restored.Password)
+		assert.NoError(t, cryptography.VerifyUserPassword(testPassword, restored.Password))
+	})
+
+	t.Run("RestoreUserWithoutPassword", func(t *testing.T) {
+		usr := &usermodel.User{
+			Username: testUsername + "_nopass",
+		}
+
+		err := usermodel.RestoreUser(ctx, usr)
+		assert.Error(t, err)
+	})
 }
 
 func TestUser_Authentication(t *testing.T) {
@@ -488,3 +517,89 @@ func TestUser_HomeAndTrash(t *testing.T) {
 		assert.Equal(t, trashID, retrievedUser.TrashID)
 	})
 }
+
+func TestUser_TOTP(t *testing.T) {
+	ctx := getTestCtx(t)
+
+	usr := &usermodel.User{
+		ID:          primitive.NewObjectID(),
+		Username:    testUsername + "_totp",
+		Password:    testPassword,
+		DisplayName: testDisplayName,
+	}
+
+	err := usermodel.SaveUser(ctx, usr)
+	require.NoError(t, err)
+
+	require.NoError(t, usr.SetPendingTOTPSecret(ctx, "SECRET"))
+	assert.False(t, usr.HasTOTP())
+
+	require.NoError(t, usr.EnableTOTP(ctx, 10, []string{"hash1", "hash2"}))
+	assert.True(t, usr.HasTOTP())
+
+	t.Run("UseTOTPStepOnlyOnce", func(t *testing.T) {
+		ok, err := usr.UseTOTPStep(ctx, 10)
+		require.NoError(t, err)
+		assert.False(t, ok, "the step used to confirm the enrollment must not be accepted again")
+
+		ok, err = usr.UseTOTPStep(ctx, 11)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = usr.UseTOTPStep(ctx, 11)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("UseRecoveryCodeOnlyOnce", func(t *testing.T) {
+		ok, err := usr.UseRecoveryCode(ctx, "hash1")
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = usr.UseRecoveryCode(ctx, "hash1")
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		fetched, err := usermodel.GetUserByUsername(ctx, usr.Username)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"hash2"}, fetched.RecoveryCodes)
+	})
+
+	t.Run("DisableTOTP", func(t *testing.T) {
+		require.NoError(t, usr.DisableTOTP(ctx))
+
+		fetched, err := usermodel.GetUserByUsername(ctx, usr.Username)
+		require.NoError(t, err)
+		assert.False(t, fetched.HasTOTP())
+		assert.Empty(t, fetched.TOTPSecret)
+		assert.Empty(t, fetched.RecoveryCodes)
+	})
+}
+
+func TestUser_OIDC(t *testing.T) {
+	ctx := getTestCtx(t)
+
+	usr := &usermodel.User{
+		ID:          primitive.NewObjectID(),
+		Username:    testUsername + "_oidc",
+		Password:    testPassword,
+		DisplayName: testDisplayName,
+	}
+
+	err := usermodel.SaveUser(ctx, usr)
+	require.NoError(t, err)
+	assert.False(t, usr.HasOIDCSubject())
+
+	_, err = usermodel.GetUserByOIDCSubject(ctx, "https://idp.example.com", "subject")
+	assert.Error(t, err)
+
+	require.NoError(t, usr.LinkOIDCSubject(ctx, "https://idp.example.com", "subject"))
+	assert.True(t, usr.HasOIDCSubject())
+
+	fetched, err := usermodel.GetUserByOIDCSubject(ctx, "https://idp.example.com", "subject")
+	require.NoError(t, err)
+	assert.Equal(t, usr.Username, fetched.Username)
+
+	_, err = usermodel.GetUserByOIDCSubject(ctx, "https://other.example.com", "subject")
+	assert.Error(t, err)
+}
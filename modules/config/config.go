@@ -89,6 +89,37 @@ type Provider struct {
 	// DoAutomaticBackup indicates whether to start the automatic backup daemon on startup.
 	// When false, manual backups via BackupOne still work. Defaults to true in production.
 	DoAutomaticBackup bool
+	// DoFileWatch indicates whether to watch the users tree on disk for changes made outside of Weblens, and record them in the journal.
+	// This is only relevant for Core towers.
+	DoFileWatch bool
+	// FileWatchDebounce is how long the file watcher waits for the filesystem to go quiet before processing the changes it has seen.
+	FileWatchDebounce time.Duration
+	// FileWatchMaxDepth is the deepest folder, counting from the users tree root, that the file watcher will watch.
+	FileWatchMaxDepth int
+	// VideoCacheSize is the most bytes of transcoded video renditions kept in the cache. The least recently streamed videos are evicted first.
+	VideoCacheSize int64
+	// VideoCacheMaxAge is how long transcoded video renditions are kept in the cache after they were last streamed.
+	VideoCacheMaxAge time.Duration
+
+	// OpenID Connect single sign-on settings //
+	// OIDCIssuer is the issuer URL of the OpenID Connect provider. Single sign-on is disabled when it is empty.
+	OIDCIssuer string
+	// OIDCClientID is the client ID Weblens is registered with at the provider.
+	OIDCClientID string
+	// OIDCClientSecret is the client secret Weblens is registered with at the provider. It may be empty for public clients.
+	OIDCClientSecret string
+	// OIDCRedirectURL is the callback URL registered with the provider. Defaults to the OIDC callback route under ProxyAddress.
+	OIDCRedirectURL string
+	// OIDCScopes are the scopes requested from the provider. "openid" is always requested.
+	OIDCScopes []string
+	// OIDCUsernameClaim is the ID token claim used as the Weblens username.
+	OIDCUsernameClaim string
+	// OIDCAdminClaim is the ID token claim checked for OIDCAdminValue, such as "groups". If empty, logging in through the provider never changes whether a user is an admin.
+	OIDCAdminClaim string
+	// OIDCAdminValue is the value of OIDCAdminClaim, or one of its values if it is a list, that makes a user an admin.
+	OIDCAdminValue string
+	// OIDCAutoProvision indicates whether users logging in through the provider for the first time are created automatically.
+	OIDCAutoProvision bool
 }
 
 // Merge merges another Provider into the current one, overriding any non-zero values.
@@ -157,11 +188,61 @@ func (c Provider) Merge(o Provider) Provider {
 		c.CoreAddress = o.CoreAddress
 	}
 
+	if o.FileWatchDebounce != 0 {
+		c.FileWatchDebounce = o.FileWatchDebounce
+	}
+
+	if o.FileWatchMaxDepth != 0 {
+		c.FileWatchMaxDepth = o.FileWatchMaxDepth
+	}
+
+	if o.VideoCacheSize != 0 {
+		c.VideoCacheSize = o.VideoCacheSize
+	}
+
+	if o.VideoCacheMaxAge != 0 {
+		c.VideoCacheMaxAge = o.VideoCacheMaxAge
+	}
+
+	if o.OIDCIssuer != "" {
+		c.OIDCIssuer = o.OIDCIssuer
+	}
+
+	if o.OIDCClientID != "" {
+		c.OIDCClientID = o.OIDCClientID
+	}
+
+	if o.OIDCClientSecret != "" {
+		c.OIDCClientSecret = o.OIDCClientSecret
+	}
+
+	if o.OIDCRedirectURL != "" {
+		c.OIDCRedirectURL = o.OIDCRedirectURL
+	}
+
+	if len(o.OIDCScopes) != 0 {
+		c.OIDCScopes = o.OIDCScopes
+	}
+
+	if o.OIDCUsernameClaim != "" {
+		c.OIDCUsernameClaim = o.OIDCUsernameClaim
+	}
+
+	if o.OIDCAdminClaim != "" {
+		c.OIDCAdminClaim = o.OIDCAdminClaim
+	}
+
+	if o.OIDCAdminValue != "" {
+		c.OIDCAdminValue = o.OIDCAdminValue
+	}
+
 	c.DoCache = o.DoCache
 	c.DoProfile = o.DoProfile
 	c.GenerateAdminAPIToken = o.GenerateAdminAPIToken
 	c.DoFileDiscovery = o.DoFileDiscovery
 	c.DoAutomaticBackup = o.DoAutomaticBackup
+	c.DoFileWatch = o.DoFileWatch
+	c.OIDCAutoProvision = o.OIDCAutoProvision
 
 	return c
 }
@@ -201,6 +282,14 @@ func getDefaultConfig() Provider {
 		DoCache:           true,
 		DoProfile:         false,
 		DoAutomaticBackup: true,
+		DoFileWatch:       true,
+		FileWatchDebounce: time.Millisecond * 500,
+		FileWatchMaxDepth: 32,
+		VideoCacheSize:    20 * 1024 * 1024 * 1024, // 20 GiB
+		VideoCacheMaxAge:  7 * 24 * time.Hour,
+
+		OIDCScopes:        []string{"openid", "profile", "email"},
+		OIDCUsernameClaim: "preferred_username",
 	}
 }
 
@@ -336,6 +425,29 @@ func getEnvOverride(config *Provider) {
 		config.DoProfile = doProfile
 	}
 
+	if doFileWatch, ok := envBool("WEBLENS_DO_FILE_WATCH"); ok {
+		log.Trace().Msgf("Overriding DoFileWatch with WEBLENS_DO_FILE_WATCH: %v", doFileWatch)
+		config.DoFileWatch = doFileWatch
+	}
+
+	if debounce := os.Getenv("WEBLENS_FILE_WATCH_DEBOUNCE"); debounce != "" {
+		if d, err := time.ParseDuration(debounce); err == nil && d > 0 {
+			log.Trace().Msgf("Overriding FileWatchDebounce with WEBLENS_FILE_WATCH_DEBOUNCE: %s", d)
+			config.FileWatchDebounce = d
+		} else {
+			log.Warn().Msgf("Invalid WEBLENS_FILE_WATCH_DEBOUNCE value: %s, using default debounce: %s", debounce, config.FileWatchDebounce)
+		}
+	}
+
+	if maxDepth := os.Getenv("WEBLENS_FILE_WATCH_MAX_DEPTH"); maxDepth != "" {
+		if depth, err := strconv.Atoi(maxDepth); err == nil && depth > 0 {
+			log.Trace().Msgf("Overriding FileWatchMaxDepth with WEBLENS_FILE_WATCH_MAX_DEPTH: %d", depth)
+			config.FileWatchMaxDepth = depth
+		} else {
+			log.Warn().Msgf("Invalid WEBLENS_FILE_WATCH_MAX_DEPTH value: %s, using default max depth: %d", maxDepth, config.FileWatchMaxDepth)
+		}
+	}
+
 	if embedURI, ok := os.LookupEnv("WEBLENS_EMBED_URI"); ok && embedURI != "" {
 		log.Trace().Msgf("Overriding EmbedURI with WEBLENS_EMBED_URI: %v", embedURI)
 		config.EmbedURI = embedURI
@@ -354,6 +466,69 @@ func getEnvOverride(config *Provider) {
 		}
 	}
 
+	if cacheSize, ok := os.LookupEnv("WEBLENS_VIDEO_CACHE_SIZE"); ok {
+		if n, err := strconv.ParseInt(cacheSize, 10, 64); err == nil && n > 0 {
+			log.Trace().Msgf("Overriding VideoCacheSize with WEBLENS_VIDEO_CACHE_SIZE: %d", n)
+			config.VideoCacheSize = n
+		} else {
+			log.Warn().Msgf("Invalid WEBLENS_VIDEO_CACHE_SIZE value %q; keeping default", cacheSize)
+		}
+	}
+
+	if maxAge := os.Getenv("WEBLENS_VIDEO_CACHE_MAX_AGE"); maxAge != "" {
+		if d, err := time.ParseDuration(maxAge); err == nil && d > 0 {
+			log.Trace().Msgf("Overriding VideoCacheMaxAge with WEBLENS_VIDEO_CACHE_MAX_AGE: %s", d)
+			config.VideoCacheMaxAge = d
+		} else {
+			log.Warn().Msgf("Invalid WEBLENS_VIDEO_CACHE_MAX_AGE value: %s, using default max age: %s", maxAge, config.VideoCacheMaxAge)
+		}
+	}
+
+	if oidcIssuer := os.Getenv("WEBLENS_OIDC_ISSUER"); oidcIssuer != "" {
+		log.Trace().Msgf("Overriding OIDCIssuer with WEBLENS_OIDC_ISSUER: %s", oidcIssuer)
+		config.OIDCIssuer = oidcIssuer
+	}
+
+	if oidcClientID := os.Getenv("WEBLENS_OIDC_CLIENT_ID"); oidcClientID != "" {
+		log.Trace().Msgf("Overriding OIDCClientID with WEBLENS_OIDC_CLIENT_ID: %s", oidcClientID)
+		config.OIDCClientID = oidcClientID
+	}
+
+	if oidcClientSecret := os.Getenv("WEBLENS_OIDC_CLIENT_SECRET"); oidcClientSecret != "" {
+		log.Trace().Msg("Overriding OIDCClientSecret with WEBLENS_OIDC_CLIENT_SECRET")
+		config.OIDCClientSecret = oidcClientSecret
+	}
+
+	if oidcRedirectURL := os.Getenv("WEBLENS_OIDC_REDIRECT_URL"); oidcRedirectURL != "" {
+		log.Trace().Msgf("Overriding OIDCRedirectURL with WEBLENS_OIDC_REDIRECT_URL: %s", oidcRedirectURL)
+		config.OIDCRedirectURL = oidcRedirectURL
+	}
+
+	if oidcScopes := os.Getenv("WEBLENS_OIDC_SCOPES"); oidcScopes != "" {
+		log.Trace().Msgf("Overriding OIDCScopes with WEBLENS_OIDC_SCOPES: %s", oidcScopes)
+		config.OIDCScopes = strings.FieldsFunc(oidcScopes, func(r rune) bool { return r == ' ' || r == ',' })
+	}
+
+	if oidcUsernameClaim := os.Getenv("WEBLENS_OIDC_USERNAME_CLAIM"); oidcUsernameClaim != "" {
+		log.Trace().Msgf("Overriding OIDCUsernameClaim with WEBLENS_OIDC_USERNAME_CLAIM: %s", oidcUsernameClaim)
+		config.OIDCUsernameClaim = oidcUsernameClaim
+	}
+
+	if oidcAdminClaim := os.Getenv("WEBLENS_OIDC_ADMIN_CLAIM"); oidcAdminClaim != "" {
+		log.Trace().Msgf("Overriding OIDCAdminClaim with WEBLENS_OIDC_ADMIN_CLAIM: %s", oidcAdminClaim)
+		config.OIDCAdminClaim = oidcAdminClaim
+	}
+
+	if oidcAdminValue := os.Getenv("WEBLENS_OIDC_ADMIN_VALUE"); oidcAdminValue != "" {
+		log.Trace().Msgf("Overriding OIDCAdminValue with WEBLENS_OIDC_ADMIN_VALUE: %s", oidcAdminValue)
+		config.OIDCAdminValue = oidcAdminValue
+	}
+
+	if oidcAutoProvision, ok := envBool("WEBLENS_OIDC_AUTO_PROVISION"); ok {
+		log.Trace().Msgf("Overriding OIDCAutoProvision with WEBLENS_OIDC_AUTO_PROVISION: %v", oidcAutoProvision)
+		config.OIDCAutoProvision = oidcAutoProvision
+	}
+
 	if doQuickPassHashing, ok := envBool("WEBLENS_USE_DANGEROUSLY_INSECURE_PASSWORD_HASHING"); ok && doQuickPassHashing {
 		log.Trace().Msgf("Overriding DangerouslyInsecurePasswordHashing with WEBLENS_USE_DANGEROUSLY_INSECURE_PASSWORD_HASHING: %v", doQuickPassHashing)
 		config.DangerouslyInsecurePasswordHashing = doQuickPassHashing
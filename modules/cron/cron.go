@@ -0,0 +1,229 @@
+// Package cron parses cron expressions and computes the times they fire.
+package cron
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethanrous/weblens/modules/wlerrors"
+)
+
+// ErrInvalidExpression indicates a cron expression could not be parsed.
+var ErrInvalidExpression = wlerrors.New("invalid cron expression")
+
+// searchLimit is how far ahead Next looks for a matching time before deciding an expression never fires,
+// e.g. "0 0 30 2 *". Every valid expression that can fire does so within this window, leap days included.
+const searchLimit = 5 * 365 * 24 * time.Hour
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var dayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// Expression is a parsed cron expression. Each field is a bitset of the values it matches.
+type Expression struct {
+	expr string
+
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	// Following cron, when both the day of month and day of week are restricted a day matching either one fires
+	domRestricted bool
+	dowRestricted bool
+}
+
+// Parse parses a standard five field cron expression: minute, hour, day of month, month and day of week.
+// Fields accept "*", single values, ranges ("1-5"), steps ("*/15", "0-30/10"), and comma separated lists of those.
+// Months and days of the week may also be given by their three letter English names. The descriptors
+// @yearly, @annually, @monthly, @weekly, @daily, @midnight and @hourly are also accepted.
+func Parse(expr string) (Expression, error) {
+	e := Expression{expr: strings.TrimSpace(expr)}
+
+	fieldsExpr := strings.ToLower(e.expr)
+	if d, ok := descriptors[fieldsExpr]; ok {
+		fieldsExpr = d
+	}
+
+	fields := strings.Fields(fieldsExpr)
+	if len(fields) != 5 {
+		return Expression{}, wlerrors.Errorf("%w [%s]: expected 5 fields, got %d", ErrInvalidExpression, expr, len(fields))
+	}
+
+	var err error
+
+	if e.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return Expression{}, wlerrors.Errorf("%w [%s]: minute: %w", ErrInvalidExpression, expr, err)
+	}
+
+	if e.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return Expression{}, wlerrors.Errorf("%w [%s]: hour: %w", ErrInvalidExpression, expr, err)
+	}
+
+	if e.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return Expression{}, wlerrors.Errorf("%w [%s]: day of month: %w", ErrInvalidExpression, expr, err)
+	}
+
+	if e.month, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return Expression{}, wlerrors.Errorf("%w [%s]: month: %w", ErrInvalidExpression, expr, err)
+	}
+
+	// 7 is accepted as Sunday, as many crons do
+	if e.dow, err = parseField(fields[4], 0, 7, dayNames); err != nil {
+		return Expression{}, wlerrors.Errorf("%w [%s]: day of week: %w", ErrInvalidExpression, expr, err)
+	}
+
+	if e.dow&(1<<7) != 0 {
+		e.dow = e.dow&^(1<<7) | 1
+	}
+
+	e.domRestricted = fields[2] != "*"
+	e.dowRestricted = fields[4] != "*"
+
+	return e, nil
+}
+
+// String returns the expression as it was given to Parse.
+func (e Expression) String() string {
+	return e.expr
+}
+
+// Next returns the first time after the given time that the expression fires, in the location of after.
+// A zero time is returned if the expression never fires, such as on the 30th of February.
+func (e Expression) Next(after time.Time) time.Time {
+	loc := after.Location()
+
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(searchLimit)
+
+	for t.Before(limit) {
+		if !has(e.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+
+			continue
+		}
+
+		if !e.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+
+			continue
+		}
+
+		if !has(e.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+
+			continue
+		}
+
+		if !has(e.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (e Expression) dayMatches(t time.Time) bool {
+	domMatch := has(e.dom, t.Day())
+	dowMatch := has(e.dow, int(t.Weekday()))
+
+	if e.domRestricted && e.dowRestricted {
+		return domMatch || dowMatch
+	}
+
+	return domMatch && dowMatch
+}
+
+func has(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
+
+func parseField(field string, low, high int, names map[string]int) (uint64, error) {
+	var bits uint64
+
+	for part := range strings.SplitSeq(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+
+		if hasStep {
+			var err error
+
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step < 1 {
+				return 0, wlerrors.Errorf("invalid step [%s]", stepPart)
+			}
+		}
+
+		start, end := low, high
+
+		if rangePart != "*" {
+			startPart, endPart, isRange := strings.Cut(rangePart, "-")
+
+			var err error
+
+			start, err = parseValue(startPart, low, high, names)
+			if err != nil {
+				return 0, err
+			}
+
+			switch {
+			case isRange:
+				end, err = parseValue(endPart, low, high, names)
+				if err != nil {
+					return 0, err
+				}
+			case !hasStep:
+				end = start
+			}
+
+			if start > end {
+				return 0, wlerrors.Errorf("range [%s] ends before it starts", rangePart)
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+func parseValue(s string, low, high int, names map[string]int) (int, error) {
+	if v, ok := names[s]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, wlerrors.Errorf("invalid value [%s]", s)
+	}
+
+	if v < low || v > high {
+		return 0, wlerrors.Errorf("value [%d] is outside of %d-%d", v, low, high)
+	}
+
+	return v, nil
+}
@@ -0,0 +1,104 @@
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethanrous/weblens/modules/cron"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	t.Run("accepts valid expressions", func(t *testing.T) {
+		for _, expr := range []string{
+			"* * * * *",
+			"0 3 * * *",
+			"*/15 * * * *",
+			"0 9-17/2 * * mon-fri",
+			"30 4 1,15 * *",
+			"0 0 * jan,jul sun",
+			"0 0 * * 7",
+			"@daily",
+			"@Weekly",
+		} {
+			_, err := cron.Parse(expr)
+			assert.NoError(t, err, expr)
+		}
+	})
+
+	t.Run("rejects invalid expressions", func(t *testing.T) {
+		for _, expr := range []string{
+			"",
+			"* * * *",
+			"* * * * * *",
+			"60 * * * *",
+			"* 24 * * *",
+			"* * 0 * *",
+			"* * * 13 *",
+			"* * * * 8",
+			"*/0 * * * *",
+			"5-1 * * * *",
+			"a * * * *",
+			"@sometimes",
+		} {
+			_, err := cron.Parse(expr)
+			assert.ErrorIs(t, err, cron.ErrInvalidExpression, expr)
+		}
+	})
+
+	t.Run("keeps the original expression", func(t *testing.T) {
+		e, err := cron.Parse("@hourly")
+		require.NoError(t, err)
+		assert.Equal(t, "@hourly", e.String())
+	})
+}
+
+func TestExpression_Next(t *testing.T) {
+	tests := []struct {
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{"* * * * *", date(2026, 3, 10, 12, 30), date(2026, 3, 10, 12, 31)},
+		{"0 3 * * *", date(2026, 3, 10, 12, 30), date(2026, 3, 11, 3, 0)},
+		{"0 3 * * *", date(2026, 3, 10, 2, 59), date(2026, 3, 10, 3, 0)},
+		{"*/15 * * * *", date(2026, 3, 10, 12, 31), date(2026, 3, 10, 12, 45)},
+		{"0 0 1 * *", date(2026, 12, 15, 0, 0), date(2027, 1, 1, 0, 0)},
+		{"@weekly", date(2026, 3, 10, 12, 0), date(2026, 3, 15, 0, 0)},
+		{"0 0 * * 7", date(2026, 3, 10, 12, 0), date(2026, 3, 15, 0, 0)},
+		{"0 9 * * mon-fri", date(2026, 3, 13, 10, 0), date(2026, 3, 16, 9, 0)},
+		{"0 0 29 2 *", date(2026, 3, 1, 0, 0), date(2028, 2, 29, 0, 0)},
+		// Day of month and day of week both restricted fires on either
+		{"0 0 1 * mon", date(2026, 3, 10, 12, 0), date(2026, 3, 16, 0, 0)},
+		{"0 0 11 * sun", date(2026, 3, 10, 12, 0), date(2026, 3, 11, 0, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := cron.Parse(tt.expr)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, e.Next(tt.after))
+		})
+	}
+
+	t.Run("never fires on a day that does not exist", func(t *testing.T) {
+		e, err := cron.Parse("0 0 30 2 *")
+		require.NoError(t, err)
+
+		assert.True(t, e.Next(date(2026, 1, 1, 0, 0)).IsZero())
+	})
+
+	t.Run("is always after the given time", func(t *testing.T) {
+		e, err := cron.Parse("30 12 * * *")
+		require.NoError(t, err)
+
+		after := time.Date(2026, 3, 10, 12, 30, 15, 0, time.UTC)
+		assert.Equal(t, date(2026, 3, 11, 12, 30), e.Next(after))
+	})
+}
@@ -191,6 +191,33 @@ func TestGenerateJWT(t *testing.T) {
 		require.NoError(t, err)
 		assert.NotEqual(t, t1, t2)
 	})
+
+	t.Run("each token gets its own session ID", func(t *testing.T) {
+		t1, _, err := cryptography.GenerateJWT("testuser")
+		require.NoError(t, err)
+		t2, _, err := cryptography.GenerateJWT("testuser")
+		require.NoError(t, err)
+
+		claims1, err := cryptography.GetClaimsFromToken(t1)
+		require.NoError(t, err)
+		claims2, err := cryptography.GetClaimsFromToken(t2)
+		require.NoError(t, err)
+
+		assert.NotEmpty(t, claims1.ID)
+		assert.NotEqual(t, claims1.ID, claims2.ID)
+	})
+}
+
+func TestGenerateSessionJWT(t *testing.T) {
+	t.Run("uses the session ID as the token ID", func(t *testing.T) {
+		token, _, err := cryptography.GenerateSessionJWT("testuser", "session-id")
+		require.NoError(t, err)
+
+		claims, err := cryptography.GetClaimsFromToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, "session-id", claims.ID)
+		assert.Equal(t, "testuser", claims.Username)
+	})
 }
 
 func TestGetUsernameFromToken(t *testing.T) {
@@ -0,0 +1,85 @@
+package cryptography
+
+import (
+	"time"
+
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OIDCStateCookie is the name of the HTTP cookie that remembers an OpenID Connect login while the user is away at
+// the identity provider.
+const OIDCStateCookie = "weblens-oidc-state"
+
+const oidcStateAudience = "weblens-oidc-state"
+
+// oidcStateLifetime is how long a user has to log in at the identity provider before they must start over.
+const oidcStateLifetime = 10 * time.Minute
+
+// OIDCStateClaims represents the JWT claims of an OpenID Connect login that is in progress.
+type OIDCStateClaims struct {
+	jwt.RegisteredClaims
+
+	// State is sent to the identity provider and must come back unchanged, to tie the callback to this browser.
+	State string `json:"state"`
+
+	// Nonce is sent to the identity provider and must be in the ID token, to tie the token to this login.
+	Nonce string `json:"nonce"`
+
+	// Verifier is the PKCE code verifier, which proves to the identity provider that we asked for the code.
+	Verifier string `json:"verifier"`
+
+	// Redirect is the path the user is sent to once they are logged in.
+	Redirect string `json:"redirect"`
+}
+
+// GenerateOIDCStateJWT generates a short-lived token holding the secrets of an OpenID Connect login, to be stored in a
+// cookie until the identity provider sends the user back.
+func GenerateOIDCStateJWT(state, nonce, verifier, redirect string) (string, time.Time, error) {
+	expires := time.Now().Add(oidcStateLifetime).In(time.UTC)
+	claims := OIDCStateClaims{
+		jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{oidcStateAudience},
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+
+		state,
+		nonce,
+		verifier,
+		redirect,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	signedToken, err := token.SignedString(jwtSigningKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return signedToken, expires, nil
+}
+
+// GetOIDCStateFromToken validates an OpenID Connect login token, and returns its claims.
+func GetOIDCStateFromToken(tokenStr string) (*OIDCStateClaims, error) {
+	if tokenStr == "" {
+		return nil, wlerrors.New("no oidc state provided")
+	}
+
+	jwtToken, err := jwt.ParseWithClaims(
+		tokenStr,
+		&OIDCStateClaims{},
+		func(_ *jwt.Token) (any, error) {
+			return jwtSigningKey, nil
+		},
+		jwt.WithAudience(oidcStateAudience),
+	)
+	if err != nil {
+		if wlerrors.Is(err, jwt.ErrTokenExpired) {
+			return nil, wlerrors.New("oidc login expired")
+		}
+
+		return nil, wlerrors.WithStack(err)
+	}
+
+	return jwtToken.Claims.(*OIDCStateClaims), nil
+}
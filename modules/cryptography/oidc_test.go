@@ -0,0 +1,38 @@
+package cryptography_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethanrous/weblens/modules/cryptography"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDCStateJWT(t *testing.T) {
+	t.Run("round trips the login state", func(t *testing.T) {
+		token, expires, err := cryptography.GenerateOIDCStateJWT("state", "nonce", "verifier", "/files/home")
+		require.NoError(t, err)
+		assert.True(t, expires.After(time.Now()))
+
+		claims, err := cryptography.GetOIDCStateFromToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, "state", claims.State)
+		assert.Equal(t, "nonce", claims.Nonce)
+		assert.Equal(t, "verifier", claims.Verifier)
+		assert.Equal(t, "/files/home", claims.Redirect)
+	})
+
+	t.Run("rejects session tokens", func(t *testing.T) {
+		token, _, err := cryptography.GenerateJWT("testuser")
+		require.NoError(t, err)
+
+		_, err = cryptography.GetOIDCStateFromToken(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects empty token", func(t *testing.T) {
+		_, err := cryptography.GetOIDCStateFromToken("")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,105 @@
+package cryptography
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"time"
+
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ShareTokenCookiePrefix is the prefix of the HTTP cookies that store share unlock tokens. Each unlocked share gets
+// its own cookie, named by ShareTokenCookieName, so unlocking one share does not lock another.
+const ShareTokenCookiePrefix = "weblens-share-token-"
+
+// shareTokenAudience keeps share unlock tokens and session tokens, which are signed with the same key, from being
+// accepted in place of each other.
+const shareTokenAudience = "weblens-share"
+
+// shareTokenLifetime is how long an unlocked share stays unlocked before the password must be entered again.
+const shareTokenLifetime = time.Hour * 2
+
+// ShareClaims represents the JWT claims for a share unlock token.
+type ShareClaims struct {
+	jwt.RegisteredClaims
+
+	ShareID string `json:"shareID"`
+
+	// PasswordFingerprint identifies the password the share was unlocked with, so that changing or removing the
+	// password locks the share again for everyone holding an older token.
+	PasswordFingerprint string `json:"pwd"`
+}
+
+// sharePasswordFingerprint derives a short, non-reversible identifier from a share's password hash. Bcrypt hashes are
+// salted, so setting the same password again still yields a new fingerprint.
+func sharePasswordFingerprint(passwordHash string) string {
+	sum := sha256.Sum256([]byte(passwordHash))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:16])
+}
+
+// ShareTokenCookieName returns the name of the cookie that stores the unlock token for the given share.
+func ShareTokenCookieName(shareID string) string {
+	return ShareTokenCookiePrefix + shareID
+}
+
+// GenerateShareJWT generates a short-lived JWT token that grants access to the specified password protected share,
+// for as long as the share's password hash stays the same.
+func GenerateShareJWT(shareID, passwordHash string) (string, time.Time, error) {
+	expires := time.Now().Add(shareTokenLifetime).In(time.UTC)
+	claims := ShareClaims{
+		jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{shareTokenAudience},
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+
+		shareID,
+		sharePasswordFingerprint(passwordHash),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	signedToken, err := token.SignedString(jwtSigningKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return signedToken, expires, nil
+}
+
+// GetShareIDFromToken extracts and validates the share ID from a share unlock JWT token string. Tokens issued before
+// the share's password was last changed are rejected.
+func GetShareIDFromToken(tokenStr, passwordHash string) (string, error) {
+	if tokenStr == "" {
+		return "", wlerrors.New("no jwt provided")
+	}
+
+	jwtToken, err := jwt.ParseWithClaims(
+		tokenStr,
+		&ShareClaims{},
+		func(_ *jwt.Token) (any, error) {
+			return jwtSigningKey, nil
+		},
+		jwt.WithAudience(shareTokenAudience),
+	)
+	if err != nil {
+		if wlerrors.Is(err, jwt.ErrTokenExpired) {
+			return "", wlerrors.New("jwt expired")
+		}
+
+		return "", wlerrors.WithStack(err)
+	}
+
+	claims := jwtToken.Claims.(*ShareClaims)
+	if claims.ShareID == "" {
+		return "", wlerrors.New("jwt is not a share token")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(claims.PasswordFingerprint), []byte(sharePasswordFingerprint(passwordHash))) != 1 {
+		return "", wlerrors.New("share password has changed since jwt was issued")
+	}
+
+	return claims.ShareID, nil
+}
@@ -0,0 +1,59 @@
+package cryptography_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethanrous/weblens/modules/cryptography"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShareJWT(t *testing.T) {
+	t.Run("round trips share id", func(t *testing.T) {
+		token, expires, err := cryptography.GenerateShareJWT("share123", "hash")
+		require.NoError(t, err)
+		assert.NotEmpty(t, token)
+		assert.True(t, expires.After(time.Now()))
+
+		shareID, err := cryptography.GetShareIDFromToken(token, "hash")
+		require.NoError(t, err)
+		assert.Equal(t, "share123", shareID)
+	})
+
+	t.Run("rejects session tokens", func(t *testing.T) {
+		token, _, err := cryptography.GenerateJWT("testuser")
+		require.NoError(t, err)
+
+		_, err = cryptography.GetShareIDFromToken(token, "hash")
+		assert.Error(t, err)
+	})
+
+	t.Run("is not accepted as a session token", func(t *testing.T) {
+		token, _, err := cryptography.GenerateShareJWT("share123", "hash")
+		require.NoError(t, err)
+
+		_, err = cryptography.GetClaimsFromToken(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects empty token", func(t *testing.T) {
+		_, err := cryptography.GetShareIDFromToken("", "hash")
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects tokens issued for another password", func(t *testing.T) {
+		token, _, err := cryptography.GenerateShareJWT("share123", "hash")
+		require.NoError(t, err)
+
+		_, err = cryptography.GetShareIDFromToken(token, "newhash")
+		assert.Error(t, err)
+
+		_, err = cryptography.GetShareIDFromToken(token, "")
+		assert.Error(t, err)
+	})
+
+	t.Run("cookie name is scoped to share", func(t *testing.T) {
+		assert.NotEqual(t, cryptography.ShareTokenCookieName("a"), cryptography.ShareTokenCookieName("b"))
+	})
+}
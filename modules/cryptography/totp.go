@@ -0,0 +1,171 @@
+package cryptography
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // RFC 6238 TOTP uses HMAC-SHA1, which is what authenticator apps expect
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TOTPPeriod is how long each TOTP code is valid for.
+const TOTPPeriod = 30 * time.Second
+
+// TOTPDigits is the number of digits in a TOTP code.
+const TOTPDigits = 6
+
+// TOTPSkew is how many periods before or after the current one a TOTP code is still accepted from, to allow for
+// clocks that have drifted apart.
+const TOTPSkew = 1
+
+const totpSecretBytes = 20
+
+const recoveryCodeLength = 10
+
+const loginChallengeAudience = "weblens-login-challenge"
+
+const loginChallengeLifetime = 5 * time.Minute
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret generates a new random TOTP secret, encoded as base32 as authenticator apps expect.
+func GenerateTOTPSecret() (string, error) {
+	secret, err := RandomBytes(totpSecretBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// TOTPProvisioningURI builds the otpauth:// URI that authenticator apps read, usually from a QR code, to enroll secret
+// for account.
+func TOTPProvisioningURI(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(TOTPDigits))
+	params.Set("period", fmt.Sprint(int(TOTPPeriod.Seconds())))
+
+	label := url.PathEscape(issuer + ":" + account)
+
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// TOTPStep returns the number of the TOTP period that t falls in.
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / int64(TOTPPeriod.Seconds())
+}
+
+// TOTPCode computes the TOTP code of secret for the given period, as described in RFC 6238.
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", wlerrors.Wrap(err, "invalid totp secret")
+	}
+
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step)) //nolint:gosec // steps are never negative
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", TOTPDigits, value%1_000_000), nil
+}
+
+// VerifyTOTP checks code against secret at time t, accepting codes from up to TOTPSkew periods away. It returns the
+// period the code matched, so callers can refuse to accept the same code twice.
+func VerifyTOTP(secret, code string, t time.Time) (int64, bool) {
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+
+	current := TOTPStep(t)
+	for step := current - TOTPSkew; step <= current+TOTPSkew; step++ {
+		expected, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// GenerateRecoveryCodes generates count random one-time recovery codes.
+func GenerateRecoveryCodes(count int) ([]string, error) {
+	codes := make([]string, 0, count)
+
+	for range count {
+		code, err := RandomString(recoveryCodeLength)
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, strings.ToLower(code))
+	}
+
+	return codes, nil
+}
+
+// HashRecoveryCode hashes a recovery code for storage. Recovery codes are random, so a fast hash is enough.
+func HashRecoveryCode(code string) string {
+	return HashString(strings.ToLower(strings.TrimSpace(code)))
+}
+
+// GenerateLoginChallengeJWT generates a short-lived token proving that username has entered their password, to be
+// exchanged for a session once they have also passed their second factor. It cannot be used as a session token.
+func GenerateLoginChallengeJWT(username string) (string, error) {
+	claims := WlClaims{
+		jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{loginChallengeAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(loginChallengeLifetime)),
+		},
+
+		username,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString(jwtSigningKey)
+}
+
+// GetUsernameFromLoginChallenge validates a login challenge token, and returns the username it was issued to.
+func GetUsernameFromLoginChallenge(tokenStr string) (string, error) {
+	if tokenStr == "" {
+		return "", wlerrors.New("no login token provided")
+	}
+
+	jwtToken, err := jwt.ParseWithClaims(
+		tokenStr,
+		&WlClaims{},
+		func(_ *jwt.Token) (any, error) {
+			return jwtSigningKey, nil
+		},
+		jwt.WithAudience(loginChallengeAudience),
+	)
+	if err != nil {
+		if wlerrors.Is(err, jwt.ErrTokenExpired) {
+			return "", wlerrors.New("login token expired")
+		}
+
+		return "", wlerrors.WithStack(err)
+	}
+
+	return jwtToken.Claims.(*WlClaims).Username, nil
+}
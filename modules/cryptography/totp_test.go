@@ -0,0 +1,118 @@
+package cryptography_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethanrous/weblens/modules/cryptography"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The secret "12345678901234567890" from RFC 6238, encoded as base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	t.Run("matches RFC 6238 test vectors", func(t *testing.T) {
+		vectors := []struct {
+			unix int64
+			code string
+		}{
+			{59, "287082"},
+			{1111111109, "081804"},
+			{1234567890, "005924"},
+			{2000000000, "279037"},
+		}
+
+		for _, v := range vectors {
+			code, err := cryptography.TOTPCode(rfcSecret, cryptography.TOTPStep(time.Unix(v.unix, 0)))
+			require.NoError(t, err)
+			assert.Equal(t, v.code, code, "at unix time %d", v.unix)
+		}
+	})
+
+	t.Run("rejects invalid secret", func(t *testing.T) {
+		_, err := cryptography.TOTPCode("not base32!", 1)
+		assert.Error(t, err)
+	})
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := cryptography.TOTPStep(now)
+
+	t.Run("accepts the current code", func(t *testing.T) {
+		matched, ok := cryptography.VerifyTOTP(rfcSecret, "005924", now)
+		assert.True(t, ok)
+		assert.Equal(t, step, matched)
+	})
+
+	t.Run("accepts codes within the skew window", func(t *testing.T) {
+		previous, err := cryptography.TOTPCode(rfcSecret, step-1)
+		require.NoError(t, err)
+
+		matched, ok := cryptography.VerifyTOTP(rfcSecret, previous, now)
+		assert.True(t, ok)
+		assert.Equal(t, step-1, matched)
+	})
+
+	t.Run("rejects codes outside the skew window", func(t *testing.T) {
+		old, err := cryptography.TOTPCode(rfcSecret, step-2)
+		require.NoError(t, err)
+
+		_, ok := cryptography.VerifyTOTP(rfcSecret, old, now)
+		assert.False(t, ok)
+	})
+
+	t.Run("rejects malformed codes", func(t *testing.T) {
+		_, ok := cryptography.VerifyTOTP(rfcSecret, "12345", now)
+		assert.False(t, ok)
+	})
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	secret, err := cryptography.GenerateTOTPSecret()
+	require.NoError(t, err)
+
+	uri := cryptography.TOTPProvisioningURI("Weblens", "testuser", secret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Weblens:testuser?"))
+	assert.Contains(t, uri, "secret="+secret)
+	assert.Contains(t, uri, "issuer=Weblens")
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := cryptography.GenerateRecoveryCodes(10)
+	require.NoError(t, err)
+	require.Len(t, codes, 10)
+	assert.NotEqual(t, codes[0], codes[1])
+
+	assert.Equal(t, cryptography.HashRecoveryCode(codes[0]), cryptography.HashRecoveryCode(" "+strings.ToUpper(codes[0])+" "))
+}
+
+func TestLoginChallengeJWT(t *testing.T) {
+	t.Run("round trips the username", func(t *testing.T) {
+		token, err := cryptography.GenerateLoginChallengeJWT("testuser")
+		require.NoError(t, err)
+
+		username, err := cryptography.GetUsernameFromLoginChallenge(token)
+		require.NoError(t, err)
+		assert.Equal(t, "testuser", username)
+	})
+
+	t.Run("is not a session token", func(t *testing.T) {
+		token, err := cryptography.GenerateLoginChallengeJWT("testuser")
+		require.NoError(t, err)
+
+		_, err = cryptography.GetClaimsFromToken(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("session tokens are not login challenges", func(t *testing.T) {
+		token, _, err := cryptography.GenerateJWT("testuser")
+		require.NoError(t, err)
+
+		_, err = cryptography.GetUsernameFromLoginChallenge(token)
+		assert.Error(t, err)
+	})
+}
@@ -48,6 +48,10 @@ const SessionTokenCookie = "weblens-session-token"
 // UserCrumbCookie is the name of the HTTP cookie that stores the username.
 const UserCrumbCookie = "weblens-user-name"
 
+// sessionTokenAudience sets session tokens apart from the other tokens signed with jwtSigningKey, such as share unlock
+// tokens, so none of them can be used as a session.
+const sessionTokenAudience = "weblens-session"
+
 var jwtSigningKey []byte
 
 func init() {
@@ -92,11 +96,23 @@ func ValidateFilename(name string) error {
 	return nil
 }
 
-// GenerateJWT generates a JWT token for the specified username.
+// GenerateJWT generates a JWT token for the specified username, with a new random session ID.
 func GenerateJWT(username string) (string, time.Time, error) {
+	sessionID, err := RandomString(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return GenerateSessionJWT(username, sessionID)
+}
+
+// GenerateSessionJWT generates a JWT token for the specified username, using sessionID as the ID of the token.
+func GenerateSessionJWT(username, sessionID string) (string, time.Time, error) {
 	expires := time.Now().Add(time.Hour * 24 * 7).In(time.UTC)
 	claims := WlClaims{
 		jwt.RegisteredClaims{
+			ID:        sessionID,
+			Audience:  jwt.ClaimStrings{sessionTokenAudience},
 			ExpiresAt: jwt.NewNumericDate(expires),
 		},
 
@@ -113,10 +129,10 @@ func GenerateJWT(username string) (string, time.Time, error) {
 	return signedToken, expires, nil
 }
 
-// GetUsernameFromToken extracts and validates the username from a JWT token string.
-func GetUsernameFromToken(tokenStr string) (string, error) {
+// GetClaimsFromToken parses and validates a JWT token string, returning its claims.
+func GetClaimsFromToken(tokenStr string) (*WlClaims, error) {
 	if tokenStr == "" {
-		return "", wlerrors.New("no jwt provided")
+		return nil, wlerrors.New("no jwt provided")
 	}
 
 	jwtToken, err := jwt.ParseWithClaims(
@@ -125,16 +141,25 @@ func GetUsernameFromToken(tokenStr string) (string, error) {
 		func(_ *jwt.Token) (any, error) {
 			return jwtSigningKey, nil
 		},
+		jwt.WithAudience(sessionTokenAudience),
 	)
 	if err != nil {
 		if wlerrors.Is(err, jwt.ErrTokenExpired) {
-			return "", wlerrors.New("jwt expired")
+			return nil, wlerrors.New("jwt expired")
 		}
 
-		return "", wlerrors.WithStack(err)
+		return nil, wlerrors.WithStack(err)
 	}
 
-	username := jwtToken.Claims.(*WlClaims).Username
+	return jwtToken.Claims.(*WlClaims), nil
+}
+
+// GetUsernameFromToken extracts and validates the username from a JWT token string.
+func GetUsernameFromToken(tokenStr string) (string, error) {
+	claims, err := GetClaimsFromToken(tokenStr)
+	if err != nil {
+		return "", err
+	}
 
-	return username, nil
+	return claims.Username, nil
 }
@@ -0,0 +1,343 @@
+// Package oidc implements the parts of OpenID Connect that Weblens needs to log users in through an external identity
+// provider: discovery, the authorization code flow with PKCE, and ID token validation.
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethanrous/weblens/modules/cryptography"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidIDToken is returned when an ID token fails validation.
+var ErrInvalidIDToken = wlerrors.Statusf(http.StatusUnauthorized, "invalid id token")
+
+const discoveryPath = "/.well-known/openid-configuration"
+
+// keysRefreshInterval is how often the signing keys may be fetched again when a token is signed by a key we don't know,
+// which happens after the provider rotates its keys.
+const keysRefreshInterval = time.Minute
+
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// Config describes how Weblens is registered with an OpenID Connect provider.
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	// HTTPClient is used to talk to the provider. http.DefaultClient is used if it is nil.
+	HTTPClient *http.Client
+}
+
+// Metadata is the subset of the discovery document of a provider that Weblens uses.
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// TokenResponse is the response of the token endpoint of a provider.
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// IDToken is a validated ID token.
+type IDToken struct {
+	// Subject is the identifier of the user at the provider. It never changes, unlike the username.
+	Subject string
+
+	// Claims are all claims of the token.
+	Claims jwt.MapClaims
+}
+
+// Provider is an OpenID Connect provider whose discovery document has been fetched.
+type Provider struct {
+	config   Config
+	metadata Metadata
+
+	keys          map[string]any
+	keysFetchedAt time.Time
+	keysMu        sync.Mutex
+}
+
+// Discover fetches the discovery document of the provider at cfg.Issuer.
+func Discover(ctx context.Context, cfg Config) (*Provider, error) {
+	if cfg.Issuer == "" || cfg.ClientID == "" {
+		return nil, wlerrors.New("oidc issuer and client id are required")
+	}
+
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+
+	p := &Provider{config: cfg}
+
+	err := p.getJSON(ctx, strings.TrimSuffix(cfg.Issuer, "/")+discoveryPath, &p.metadata)
+	if err != nil {
+		return nil, wlerrors.Wrap(err, "failed to fetch oidc discovery document")
+	}
+
+	if strings.TrimSuffix(p.metadata.Issuer, "/") != strings.TrimSuffix(cfg.Issuer, "/") {
+		return nil, wlerrors.Errorf("oidc discovery document is for issuer [%s], expected [%s]", p.metadata.Issuer, cfg.Issuer)
+	}
+
+	if p.metadata.AuthorizationEndpoint == "" || p.metadata.TokenEndpoint == "" || p.metadata.JWKSURI == "" {
+		return nil, wlerrors.New("oidc discovery document is missing a required endpoint")
+	}
+
+	return p, nil
+}
+
+// Metadata returns the discovery document of the provider.
+func (p *Provider) Metadata() Metadata {
+	return p.metadata
+}
+
+// NewPKCEVerifier generates a new random PKCE code verifier.
+func NewPKCEVerifier() (string, error) {
+	verifier, err := cryptography.RandomBytes(32)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(verifier), nil
+}
+
+// PKCEChallenge derives the S256 PKCE code challenge of verifier.
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL builds the URL of the authorization endpoint that the user is sent to in order to log in.
+func (p *Provider) AuthCodeURL(state, nonce, codeChallenge string) string {
+	scopes := p.config.Scopes
+	if !slices.Contains(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.config.ClientID)
+	params.Set("redirect_uri", p.config.RedirectURL)
+	params.Set("scope", strings.Join(scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", codeChallenge)
+	params.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(p.metadata.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return p.metadata.AuthorizationEndpoint + sep + params.Encode()
+}
+
+// Exchange trades an authorization code, and the PKCE verifier it was requested with, for tokens.
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (*TokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("code_verifier", verifier)
+
+	if p.config.ClientSecret == "" {
+		form.Set("client_id", p.config.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, wlerrors.WithStack(err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, wlerrors.WithStack(err)
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, wlerrors.WithStack(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, wlerrors.Errorf("oidc token endpoint returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	tokens := &TokenResponse{}
+
+	err = json.Unmarshal(body, tokens)
+	if err != nil {
+		return nil, wlerrors.Wrap(err, "failed to decode oidc token response")
+	}
+
+	if tokens.IDToken == "" {
+		return nil, wlerrors.New("oidc token response has no id token")
+	}
+
+	return tokens, nil
+}
+
+// VerifyIDToken checks the signature, issuer, audience, expiry and nonce of an ID token.
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*IDToken, error) {
+	claims := jwt.MapClaims{}
+
+	_, err := jwt.ParseWithClaims(
+		rawIDToken,
+		claims,
+		func(token *jwt.Token) (any, error) {
+			kid, _ := token.Header["kid"].(string)
+
+			return p.getKey(ctx, kid)
+		},
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(p.metadata.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, wlerrors.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, wlerrors.Errorf("%w: nonce does not match", ErrInvalidIDToken)
+	}
+
+	// When the token names several audiences, the party it was issued to must be us
+	if azp, ok := claims["azp"].(string); ok && azp != p.config.ClientID {
+		return nil, wlerrors.Errorf("%w: token was issued to [%s]", ErrInvalidIDToken, azp)
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, wlerrors.Errorf("%w: token has no subject", ErrInvalidIDToken)
+	}
+
+	return &IDToken{Subject: subject, Claims: claims}, nil
+}
+
+// StringClaim returns the claim with the given name if it is a string, or an empty string otherwise.
+func (t *IDToken) StringClaim(name string) string {
+	value, _ := t.Claims[name].(string)
+
+	return value
+}
+
+// ClaimContains returns true if the claim with the given name is value, or is a list that contains value.
+func (t *IDToken) ClaimContains(name, value string) bool {
+	switch claim := t.Claims[name].(type) {
+	case string:
+		return claim == value
+	case []any:
+		for _, v := range claim {
+			if s, ok := v.(string); ok && s == value {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// getKey returns the signing key with the given ID, fetching the keys of the provider if we don't have it yet.
+func (p *Provider) getKey(ctx context.Context, kid string) (any, error) {
+	p.keysMu.Lock()
+	defer p.keysMu.Unlock()
+
+	key, ok := p.lookupKey(kid)
+	if ok {
+		return key, nil
+	}
+
+	if !p.keysFetchedAt.IsZero() && time.Since(p.keysFetchedAt) < keysRefreshInterval {
+		return nil, wlerrors.Errorf("unknown signing key [%s]", kid)
+	}
+
+	jwks := jsonWebKeySet{}
+
+	err := p.getJSON(ctx, p.metadata.JWKSURI, &jwks)
+	if err != nil {
+		return nil, wlerrors.Wrap(err, "failed to fetch oidc signing keys")
+	}
+
+	p.keys = jwks.publicKeys()
+	p.keysFetchedAt = time.Now()
+
+	key, ok = p.lookupKey(kid)
+	if !ok {
+		return nil, wlerrors.Errorf("unknown signing key [%s]", kid)
+	}
+
+	return key, nil
+}
+
+// lookupKey finds a key by its ID. Tokens without a key ID are accepted if the provider has only one key.
+func (p *Provider) lookupKey(kid string) (any, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+
+	key, ok := p.keys[kid]
+
+	return key, ok
+}
+
+func (p *Provider) getJSON(ctx context.Context, target string, dest any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.config.HTTPClient.Do(req)
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return wlerrors.Errorf("GET %s returned %d", target, resp.StatusCode)
+	}
+
+	err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(dest)
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	return nil
+}
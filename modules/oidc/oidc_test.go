@@ -0,0 +1,274 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ethanrous/weblens/modules/oidc"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testClientID     = "weblens"
+	testClientSecret = "secret"
+	testRedirectURL  = "https://weblens.example.com/api/v1/users/auth/oidc/callback"
+	testKeyID        = "test-key"
+)
+
+// mockIssuer is a minimal OpenID Connect provider that issues ID tokens for a single authorization code.
+type mockIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	code          string
+	codeChallenge string
+	claims        jwt.MapClaims
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	m := &mockIssuer{key: key, code: "test-code"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": testKeyID,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok || clientID != testClientID || clientSecret != testClientSecret {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		if r.FormValue("code") != m.code || oidc.PKCEChallenge(r.FormValue("code_verifier")) != m.codeChallenge {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     m.sign(t, m.claims),
+		})
+	})
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	return m
+}
+
+func (m *mockIssuer) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testKeyID
+
+	signed, err := token.SignedString(m.key)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func (m *mockIssuer) validClaims(nonce string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":                m.server.URL,
+		"sub":                "user-1234",
+		"aud":                testClientID,
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"iat":                time.Now().Unix(),
+		"nonce":              nonce,
+		"preferred_username": "alice",
+		"groups":             []string{"users", "weblens-admins"},
+	}
+}
+
+func (m *mockIssuer) config() oidc.Config {
+	return oidc.Config{
+		Issuer:       m.server.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"profile", "email"},
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	m := newMockIssuer(t)
+
+	t.Run("fetches discovery document", func(t *testing.T) {
+		p, err := oidc.Discover(context.Background(), m.config())
+		require.NoError(t, err)
+		assert.Equal(t, m.server.URL+"/token", p.Metadata().TokenEndpoint)
+	})
+
+	t.Run("rejects mismatched issuer", func(t *testing.T) {
+		cfg := m.config()
+		cfg.Issuer = m.server.URL + "/other"
+
+		_, err := oidc.Discover(context.Background(), cfg)
+		assert.Error(t, err)
+	})
+
+	t.Run("requires client id", func(t *testing.T) {
+		cfg := m.config()
+		cfg.ClientID = ""
+
+		_, err := oidc.Discover(context.Background(), cfg)
+		assert.Error(t, err)
+	})
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	m := newMockIssuer(t)
+
+	p, err := oidc.Discover(context.Background(), m.config())
+	require.NoError(t, err)
+
+	authURL, err := url.Parse(p.AuthCodeURL("state", "nonce", "challenge"))
+	require.NoError(t, err)
+
+	query := authURL.Query()
+	assert.Equal(t, "/authorize", authURL.Path)
+	assert.Equal(t, "code", query.Get("response_type"))
+	assert.Equal(t, testClientID, query.Get("client_id"))
+	assert.Equal(t, testRedirectURL, query.Get("redirect_uri"))
+	assert.Equal(t, "openid profile email", query.Get("scope"))
+	assert.Equal(t, "state", query.Get("state"))
+	assert.Equal(t, "nonce", query.Get("nonce"))
+	assert.Equal(t, "challenge", query.Get("code_challenge"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+}
+
+func TestExchangeAndVerify(t *testing.T) {
+	m := newMockIssuer(t)
+
+	p, err := oidc.Discover(context.Background(), m.config())
+	require.NoError(t, err)
+
+	verifier, err := oidc.NewPKCEVerifier()
+	require.NoError(t, err)
+
+	m.codeChallenge = oidc.PKCEChallenge(verifier)
+	m.claims = m.validClaims("nonce")
+
+	t.Run("exchanges code and verifies id token", func(t *testing.T) {
+		tokens, err := p.Exchange(context.Background(), m.code, verifier)
+		require.NoError(t, err)
+
+		idToken, err := p.VerifyIDToken(context.Background(), tokens.IDToken, "nonce")
+		require.NoError(t, err)
+		assert.Equal(t, "user-1234", idToken.Subject)
+		assert.Equal(t, "alice", idToken.StringClaim("preferred_username"))
+		assert.True(t, idToken.ClaimContains("groups", "weblens-admins"))
+		assert.False(t, idToken.ClaimContains("groups", "other"))
+	})
+
+	t.Run("rejects wrong pkce verifier", func(t *testing.T) {
+		_, err := p.Exchange(context.Background(), m.code, "wrong-verifier")
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects wrong code", func(t *testing.T) {
+		_, err := p.Exchange(context.Background(), "wrong-code", verifier)
+		assert.Error(t, err)
+	})
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	m := newMockIssuer(t)
+
+	p, err := oidc.Discover(context.Background(), m.config())
+	require.NoError(t, err)
+
+	t.Run("rejects wrong nonce", func(t *testing.T) {
+		_, err := p.VerifyIDToken(context.Background(), m.sign(t, m.validClaims("nonce")), "other")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	})
+
+	t.Run("rejects wrong audience", func(t *testing.T) {
+		claims := m.validClaims("nonce")
+		claims["aud"] = "someone-else"
+
+		_, err := p.VerifyIDToken(context.Background(), m.sign(t, claims), "nonce")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	})
+
+	t.Run("rejects wrong issuer", func(t *testing.T) {
+		claims := m.validClaims("nonce")
+		claims["iss"] = "https://evil.example.com"
+
+		_, err := p.VerifyIDToken(context.Background(), m.sign(t, claims), "nonce")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	})
+
+	t.Run("rejects expired token", func(t *testing.T) {
+		claims := m.validClaims("nonce")
+		claims["exp"] = time.Now().Add(-time.Hour).Unix()
+
+		_, err := p.VerifyIDToken(context.Background(), m.sign(t, claims), "nonce")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	})
+
+	t.Run("rejects token signed by another key", func(t *testing.T) {
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, m.validClaims("nonce"))
+		token.Header["kid"] = testKeyID
+
+		signed, err := token.SignedString(otherKey)
+		require.NoError(t, err)
+
+		_, err = p.VerifyIDToken(context.Background(), signed, "nonce")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	})
+
+	t.Run("rejects unsigned token", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodNone, m.validClaims("nonce"))
+
+		signed, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+		require.NoError(t, err)
+
+		_, err = p.VerifyIDToken(context.Background(), signed, "nonce")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	})
+}
+
+func TestPKCEChallenge(t *testing.T) {
+	// Example from RFC 7636 appendix B
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", oidc.PKCEChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+}
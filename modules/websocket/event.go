@@ -52,6 +52,9 @@ const (
 	CopyFileCompleteEvent        WsEvent = "copyFileComplete"
 	CopyFileFailedEvent          WsEvent = "copyFileFailed"
 	CopyFileStartedEvent         WsEvent = "copyFileStarted"
+	CopyFilesCompleteEvent       WsEvent = "copyFilesComplete"
+	CopyFilesProgressEvent       WsEvent = "copyFilesProgress"
+	DuplicatesFoundEvent         WsEvent = "duplicatesFound"
 	ErrorEvent                   WsEvent = "error"
 	FileCreatedEvent             WsEvent = "fileCreated"
 	FileDeletedEvent             WsEvent = "fileDeleted"
@@ -68,6 +71,7 @@ const (
 	PoolCancelledEvent           WsEvent = "poolCancelled"
 	PoolCompleteEvent            WsEvent = "poolComplete"
 	PoolCreatedEvent             WsEvent = "poolCreated"
+	QuotaWarningEvent            WsEvent = "quotaWarning"
 	RemoteConnectionChangedEvent WsEvent = "remoteConnectionChanged"
 	RestoreCompleteEvent         WsEvent = "restoreComplete"
 	RestoreFailedEvent           WsEvent = "restoreFailed"
@@ -75,12 +79,14 @@ const (
 	RestoreStartedEvent          WsEvent = "restoreStarted"
 	ScanDirectoryProgressEvent   WsEvent = "scanDirectoryProgress"
 	ServerGoingDownEvent         WsEvent = "goingDown"
+	ShareExpiredEvent            WsEvent = "shareExpired"
 	ShareUpdatedEvent            WsEvent = "shareUpdated"
 	StartupProgressEvent         WsEvent = "startupProgress"
 	TaskCanceledEvent            WsEvent = "taskCanceled"
 	TaskCompleteEvent            WsEvent = "taskComplete"
 	TaskCreatedEvent             WsEvent = "taskCreated"
 	TaskFailedEvent              WsEvent = "taskFailure"
+	TrashPurgedEvent             WsEvent = "trashPurged"
 	WeblensLoadedEvent           WsEvent = "weblensLoaded"
 	ZipCompleteEvent             WsEvent = "zipComplete"
 	ZipProgressEvent             WsEvent = "createZipProgress"
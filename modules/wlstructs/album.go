@@ -0,0 +1,17 @@
+package wlstructs
+
+// AlbumInfo represents an album for API responses.
+type AlbumInfo struct {
+	AlbumID     string `json:"albumID"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Owner       string `json:"owner"`
+	// Cover is the content ID of the media shown for the album, or empty if the album has no media
+	Cover string `json:"cover"`
+	// MediaIDs are the content IDs of the media in the album, in the order they are shown
+	MediaIDs []string `json:"mediaIDs"`
+	// ShareID is the ID of the share of the album, or empty if it is not shared
+	ShareID string `json:"shareID"`
+	Created int64  `json:"created" swaggertype:"integer" format:"int64"`
+	Updated int64  `json:"updated" swaggertype:"integer" format:"int64"`
+} //	@name	AlbumInfo
@@ -7,5 +7,76 @@ type BackupInfo struct {
 	Users          []UserInfoArchive
 	Instances      []TowerInfo
 	Tokens         []TokenInfo
+	Shares         []ShareInfoArchive
+	Tags           []TagInfo
+	Covers         []CoverInfo
+	Media          []MediaInfo
 	LifetimesCount int
+
+	// Keys lists every share, tag, cover and media the tower has, not only the ones sent, so those deleted since the
+	// last backup can be deleted from the backup as well. Not set in restore archives.
+	Keys *BackupKeys `json:",omitempty"`
+
+	// Timestamp is when the shares, tags, covers and media were read, in milliseconds since epoch. Those updated after it
+	// are sent by the next backup.
+	Timestamp int64 `format:"int64"`
 } //	@name	BackupInfo
+
+// BackupKeys identifies every share, tag, cover and media a tower has.
+type BackupKeys struct {
+	ShareIDs        []string
+	TagIDs          []string
+	CoverFolderIDs  []string
+	MediaContentIDs []string
+} //	@name	BackupKeys
+
+// ShareInfoArchive is a share as it is backed up, with everything needed to restore it.
+type ShareInfoArchive struct {
+	ShareID      string                            `json:"shareID"`
+	FileID       string                            `json:"fileID"`
+	AlbumID      string                            `json:"albumID"`
+	ShareName    string                            `json:"shareName"`
+	Owner        string                            `json:"owner"`
+	Accessors    []string                          `json:"accessors"`
+	Permissions  map[string]PermissionsInfoArchive `json:"permissions"`
+	Expires      int64                             `json:"expires" format:"int64"`
+	Updated      int64                             `json:"updated" format:"int64"`
+	Public       bool                              `json:"public"`
+	Wormhole     bool                              `json:"wormhole"`
+	TimelineOnly bool                              `json:"timelineOnly"`
+	Enabled      bool                              `json:"enabled"`
+	PasswordHash string                            `json:"passwordHash,omitempty"`
+} //	@name	ShareInfoArchive
+
+// PermissionsInfoArchive is the permissions of a user on a share as they are backed up.
+type PermissionsInfoArchive struct {
+	PermissionsInfo
+
+	CanViewMedia bool `json:"canViewMedia"`
+} //	@name	PermissionsInfoArchive
+
+// TagInfo represents a tag and the files it is on.
+type TagInfo struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Color   string   `json:"color"`
+	Owner   string   `json:"owner"`
+	FileIDs []string `json:"fileIDs"`
+	Created int64    `json:"created" format:"int64"`
+	Updated int64    `json:"updated" format:"int64"`
+} //	@name	TagInfo
+
+// CoverInfo represents the cover photo of a folder.
+type CoverInfo struct {
+	FolderID     string `json:"folderID"`
+	CoverPhotoID string `json:"coverPhotoID"`
+} //	@name	CoverInfo
+
+// BackupActionsPage is a page of the file history a backup tower reads from its core.
+type BackupActionsPage struct {
+	Actions []FileActionInfo `json:"actions" validate:"required"`
+	// NextCursor is the cursor to read the page after this one from. It is also the cursor to resume from once there are
+	// no more actions, so it is set even if HasMore is false.
+	NextCursor string `json:"nextCursor"`
+	HasMore    bool   `json:"hasMore" validate:"required"`
+} //	@name	BackupActionsPage
@@ -68,3 +68,11 @@ type SearchResult struct {
 	MatchPage    int      `json:"matchPage,omitempty"`
 	Score        float64  `json:"score"`
 } //	@name	SearchResult
+
+// DuplicateGroupInfo is a set of files that hold the same content, or that hold photos which look alike.
+type DuplicateGroupInfo struct {
+	// ContentID is shared by every file in the group. It is empty for a group of near-duplicate photos.
+	ContentID        string     `json:"contentID,omitempty"`
+	Files            []FileInfo `json:"files"`
+	ReclaimableBytes int64      `json:"reclaimableBytes" swaggertype:"integer" format:"int64"`
+} //	@name	DuplicateGroupInfo
@@ -12,5 +12,19 @@ type FileActionInfo struct {
 	Timestamp       int64  `json:"timestamp" validate:"required" format:"int64"`
 	Size            int64  `json:"size" validate:"required" format:"int64"`
 	ContentID       string `json:"contentID,omitempty"`
+	OldContentID    string `json:"oldContentID,omitempty"`
 	LiveParentID    string `json:"liveParentID,omitempty"`
 } //	@name	FileActionInfo
+
+// FileRevisionInfo describes one of the contents a file has had over its lifetime.
+type FileRevisionInfo struct {
+	RevisionID string `json:"revisionID" validate:"required"`
+	ContentID  string `json:"contentID"`
+	Size       int64  `json:"size" validate:"required" format:"int64"`
+	Timestamp  int64  `json:"timestamp" validate:"required" format:"int64"`
+	Doer       string `json:"doer,omitempty"`
+	// IsCurrent is true for the revision that gave the file the content it has now
+	IsCurrent bool `json:"isCurrent" validate:"required"`
+	// Available is false if the content of the revision is no longer kept, and can not be downloaded or restored
+	Available bool `json:"available" validate:"required"`
+} //	@name	FileRevisionInfo
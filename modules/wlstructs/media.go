@@ -71,3 +71,22 @@ type MediaBatchInfo struct {
 	MediaCount      int         `json:"mediaCount"`
 	TotalMediaCount int         `json:"totalMediaCount"`
 } //	@name	MediaBatchInfo
+
+// MediaClusterInfo represents a group of media taken close to each other, as shown on a map.
+type MediaClusterInfo struct {
+	// Average location of the media in the cluster
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+
+	// Number of media in the cluster
+	Count int `json:"count"`
+
+	// Content ID of the newest media in the cluster, to show as its thumbnail
+	CoverID string `json:"coverID"`
+} //	@name	MediaClusterInfo
+
+// MediaMapInfo represents the clustered media inside the bounds of a map.
+type MediaMapInfo struct {
+	Clusters   []MediaClusterInfo `json:"clusters"`
+	MediaCount int                `json:"mediaCount"`
+} //	@name	MediaMapInfo
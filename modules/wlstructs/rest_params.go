@@ -16,12 +16,50 @@ type UpdateFileParams struct {
 	NewParentID string `json:"newParentID"`
 } //	@name	UpdateFileParams
 
+// UpdateFileTextParams represents parameters for replacing the text of a text file.
+type UpdateFileTextParams struct {
+	// ContentID is the content ID of the text the edit was based on. The edit is rejected if the file has changed since.
+	ContentID string `json:"contentID"`
+	Text      string `json:"text"`
+} //	@name	UpdateFileTextParams
+
 // MoveFilesParams represents parameters for moving multiple files to a new parent folder.
 type MoveFilesParams struct {
 	NewParentID string   `json:"newParentID"`
 	Files       []string `json:"fileIDs"`
 } //	@name	MoveFilesParams
 
+// CopyFilesParams represents parameters for copying multiple files into a folder.
+type CopyFilesParams struct {
+	NewParentID string   `json:"newParentID"`
+	Files       []string `json:"fileIDs"`
+} //	@name	CopyFilesParams
+
+// DuplicateScope is how much of the server is searched for duplicate files.
+type DuplicateScope string
+
+const (
+	// DuplicateScopeUser searches the files of the requesting user.
+	DuplicateScopeUser DuplicateScope = "user"
+	// DuplicateScopeServer searches the files of every user. Only admins may use it.
+	DuplicateScopeServer DuplicateScope = "server"
+)
+
+// FindDuplicatesParams represents parameters for searching for duplicate files.
+type FindDuplicatesParams struct {
+	Scope DuplicateScope `json:"scope" enums:"user,server"`
+	// Similar groups photos that look alike according to their image embeddings, instead of files with identical content.
+	Similar bool `json:"similar"`
+	// Threshold is the cosine similarity at or above which two photos are alike. 0 uses the server default.
+	Threshold float64 `json:"threshold"`
+} //	@name	FindDuplicatesParams
+
+// ResolveDuplicatesParams represents parameters for keeping one file of a group of duplicates, and trashing the rest.
+type ResolveDuplicatesParams struct {
+	KeepFileID string   `json:"keepFileID"`
+	FileIDs    []string `json:"fileIDs"`
+} //	@name	ResolveDuplicatesParams
+
 // FilesListParams represents a list of file IDs for batch operations.
 type FilesListParams struct {
 	FileIDs []string `json:"fileIDs"`
@@ -71,13 +109,31 @@ type FileShareParams struct {
 	Public       bool     `json:"public"`
 	Wormhole     bool     `json:"wormhole"`
 	TimelineOnly bool     `json:"timelineOnly"`
+	// Expires is the time, in unix milliseconds, after which the share can no longer be accessed. 0 means never. When
+	// updating a share, leaving it out keeps the current expiry.
+	Expires *int64 `json:"expires,omitempty" swaggertype:"integer" format:"int64"`
+	// Enabled turns the share on or off when updating it, leaving it out keeps the share as it is.
+	Enabled *bool `json:"enabled,omitempty"`
+	// Password, if set, must be entered before the share can be accessed publicly.
+	Password string `json:"password"`
 } //	@name	FileShareParams
 
+// SharePasswordParams represents parameters for setting, or entering, a share password.
+type SharePasswordParams struct {
+	Password string `json:"password"`
+} //	@name	SharePasswordParams
+
 // AlbumShareParams represents parameters for sharing an album with users.
 type AlbumShareParams struct {
 	AlbumID string   `json:"albumID"`
 	Users   []string `json:"users"`
 	Public  bool     `json:"public"`
+	// TimelineOnly shares only the media in the album, without access to the files behind it.
+	TimelineOnly bool `json:"timelineOnly"`
+	// Expires is the time, in unix milliseconds, after which the share can no longer be accessed. 0 means never.
+	Expires int64 `json:"expires" swaggertype:"integer" format:"int64"`
+	// Password, if set, must be entered before the share can be accessed publicly.
+	Password string `json:"password"`
 } //	@name	AlbumShareParams
 
 // DeleteKeyBody represents the request body for deleting an API key.
@@ -108,20 +164,25 @@ type CreateFolderBody struct {
 	Children       []string `json:"children" validate:"optional"`
 } //	@name	CreateFolderBody
 
-// UpdateAlbumParams represents parameters for updating an album's content and settings.
+// UpdateAlbumParams represents parameters for updating an album's content and settings. Fields left empty are not
+// changed. Media are added, then removed, then reordered.
 type UpdateAlbumParams struct {
-	AddMedia    []string `json:"newMedia"`
+	AddMedia []string `json:"newMedia"`
+	// AddFolders adds the media of every file inside of each folder, in the order they were created
 	AddFolders  []string `json:"newFolders"`
 	RemoveMedia []string `json:"removeMedia"`
-	Cover       string   `json:"cover"`
-	NewName     string   `json:"newName"`
-	Users       []string `json:"users"`
-	RemoveUsers []string `json:"removeUsers"`
+	// MediaOrder is the new order of the media in the album, and must contain each media in the album exactly once
+	MediaOrder []string `json:"mediaOrder"`
+	// Cover is the content ID of the media to show for the album
+	Cover          string  `json:"cover"`
+	NewTitle       string  `json:"newTitle"`
+	NewDescription *string `json:"newDescription"`
 } //	@name	UpdateAlbumParams
 
 // CreateAlbumParams represents parameters for creating a new album.
 type CreateAlbumParams struct {
-	Name string `json:"name"`
+	Title       string `json:"title" validate:"required"`
+	Description string `json:"description"`
 } //	@name	CreateAlbumParams
 
 // PermissionsParams represents permission settings for a user or share.
@@ -163,6 +224,14 @@ type RestoreFilesParams struct {
 	Timestamp   int64    `json:"timestamp"`
 } //	@name	RestoreFilesBody
 
+// RestoreBackupFilesParams represents parameters for restoring files from the journal of a backup tower.
+type RestoreBackupFilesParams struct {
+	Path        string   `json:"path" validate:"required"`
+	NewParentID string   `json:"newParentID"`
+	FileIDs     []string `json:"fileIDs"`
+	Timestamp   int64    `json:"timestamp"`
+} //	@name	RestoreBackupFilesParams
+
 // RestoreCoreParams represents parameters for restoring core server configuration.
 type RestoreCoreParams struct {
 	HostURL  string `json:"restoreUrl"`
@@ -172,17 +241,31 @@ type RestoreCoreParams struct {
 // APIKeyParams represents parameters for creating an API key.
 type APIKeyParams struct {
 	Name string `json:"name" validate:"required"`
+
+	// Scopes limits what the key may be used for, such as "files:read", "files:write", "media:read", "media:write" or
+	// "admin". A key with no scopes has the full power of its owner.
+	Scopes []string `json:"scopes,omitempty"`
+
+	// Expires is when the key stops working, in unix milliseconds. 0 means it never expires.
+	Expires int64 `json:"expires,omitempty" format:"int64"`
+
+	// FolderID, if set, limits the key to the files inside this folder.
+	FolderID string `json:"folderID,omitempty"`
 } //	@name	APIKeyParams
 
 // MediaBatchParams represents parameters for retrieving a batch of media items.
 type MediaBatchParams struct {
-	Raw           bool     `json:"raw" query:"raw" example:"false" enums:"true,false"`
-	Hidden        bool     `json:"hidden" query:"hidden" example:"false" enums:"true,false"`
-	Sort          string   `json:"sort" query:"sort" example:"createDate" enums:"createDate"`
-	SortDirection int      `json:"sortDirection" example:"1" enums:"1,-1"`
-	Search        string   `json:"search" query:"search" example:""`
-	Page          int      `json:"page" query:"page" example:"1"`
-	Limit         int      `json:"limit" query:"limit" example:"20"`
-	FolderIDs     []string `json:"folderIDs" query:"folderIDs" example:"[fID1,fID2]"`
-	MediaIDs      []string `json:"mediaIDs" query:"mediaIDs" example:"[mID1,mID2]"`
+	Raw           bool      `json:"raw" query:"raw" example:"false" enums:"true,false"`
+	Hidden        bool      `json:"hidden" query:"hidden" example:"false" enums:"true,false"`
+	Sort          string    `json:"sort" query:"sort" example:"createDate" enums:"createDate"`
+	SortDirection int       `json:"sortDirection" example:"1" enums:"1,-1"`
+	Search        string    `json:"search" query:"search" example:""`
+	Page          int       `json:"page" query:"page" example:"1"`
+	Limit         int       `json:"limit" query:"limit" example:"20"`
+	FolderIDs     []string  `json:"folderIDs" query:"folderIDs" example:"[fID1,fID2]"`
+	MediaIDs      []string  `json:"mediaIDs" query:"mediaIDs" example:"[mID1,mID2]"`
+	Lat           float64   `json:"lat" query:"lat" example:"40.7128"`
+	Lng           float64   `json:"lng" query:"lng" example:"-74.006"`
+	Radius        float64   `json:"radius" query:"radius" example:"1000"`
+	Polygon       []float64 `json:"polygon" query:"polygon" example:"[40.7,-74.0,40.8,-74.0,40.8,-73.9]"`
 } //	@name	MediaBatchParams
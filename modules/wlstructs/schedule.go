@@ -0,0 +1,33 @@
+package wlstructs
+
+// ScheduleParams represents parameters for creating or updating a job schedule. When updating, fields left empty
+// are not changed.
+type ScheduleParams struct {
+	Name    string `json:"name"`
+	JobName string `json:"jobName"`
+	// Cron is a five field cron expression, or one of @yearly, @monthly, @weekly, @daily or @hourly
+	Cron string `json:"cron"`
+	// Params configure the job, such as "folderID" for scans, or "coreID" for backups
+	Params  map[string]string `json:"params"`
+	Enabled *bool             `json:"enabled"`
+} //	@name	ScheduleParams
+
+// ScheduleInfo represents a job schedule, and the status of its runs, for API responses.
+type ScheduleInfo struct {
+	ScheduleID string            `json:"scheduleID"`
+	Name       string            `json:"name"`
+	JobName    string            `json:"jobName"`
+	Cron       string            `json:"cron"`
+	Params     map[string]string `json:"params"`
+	Enabled    bool              `json:"enabled"`
+	CreatedBy  string            `json:"createdBy"`
+	Created    int64             `json:"created" swaggertype:"integer" format:"int64"`
+	// NextRun is 0 if the schedule will not run again
+	NextRun int64 `json:"nextRun" swaggertype:"integer" format:"int64"`
+	// LastRun is 0 if the schedule has never run
+	LastRun    int64  `json:"lastRun" swaggertype:"integer" format:"int64"`
+	LastTaskID string `json:"lastTaskID"`
+	// LastStatus is the exit status of the last task, or empty if it is still running
+	LastStatus string `json:"lastStatus"`
+	LastError  string `json:"lastError"`
+} //	@name	ScheduleInfo
@@ -0,0 +1,20 @@
+package wlstructs
+
+// SessionInfo represents a login session of a user, as identified by the JWT handed out when they logged in.
+type SessionInfo struct {
+	ID          string `json:"id" validate:"required"`
+	Username    string `json:"username" validate:"required"`
+	Device      string `json:"device" validate:"required"`
+	IP          string `json:"ip" validate:"required"`
+	CreatedTime int64  `json:"createdTime" validate:"required" format:"int64"`
+	LastUsed    int64  `json:"lastUsed" validate:"required" format:"int64"`
+	Expires     int64  `json:"expires" validate:"required" format:"int64"`
+
+	// Current is true for the session the request listing the sessions was made with.
+	Current bool `json:"current" validate:"required"`
+} //	@name	SessionInfo
+
+// RevokedSessionsInfo reports how many sessions were revoked.
+type RevokedSessionsInfo struct {
+	Revoked int64 `json:"revoked" validate:"required" format:"int64"`
+} //	@name	RevokedSessionsInfo
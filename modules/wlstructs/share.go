@@ -16,6 +16,10 @@ type ShareInfo struct {
 	Wormhole     bool                       `json:"wormhole"`
 	TimelineOnly bool                       `json:"timelineOnly"`
 	Enabled      bool                       `json:"enabled"`
+	// PasswordProtected is true if the share must be unlocked with a password before it can be accessed publicly
+	PasswordProtected bool `json:"passwordProtected"`
+	// AlbumID is the ID of the shared album, for album shares
+	AlbumID string `json:"albumID"`
 } //	@name	ShareInfo
 
 // PermissionsInfo represents permission settings for API responses.
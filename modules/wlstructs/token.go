@@ -10,6 +10,15 @@ type TokenInfo struct {
 	RemoteUsing string `json:"remoteUsing" validate:"required"`
 	CreatedBy   string `json:"createdBy" validate:"required"`
 	Token       string `json:"token" validate:"required"`
+
+	// Expires is when the token stops working, in unix milliseconds. 0 means it never expires.
+	Expires int64 `json:"expires" validate:"required" format:"int64"`
+
+	// Scopes limits what the token may be used for. A token with no scopes has the full power of its owner.
+	Scopes []string `json:"scopes" validate:"required"`
+
+	// FolderID, if set, limits the token to the files inside this folder.
+	FolderID string `json:"folderID,omitempty"`
 } //	@name	TokenInfo
 
 // type APIKeyInfo struct {
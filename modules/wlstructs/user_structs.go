@@ -26,11 +26,70 @@ type UserInfo struct {
 	Username        string `json:"username" validate:"required"`
 	IsOnline        bool   `json:"isOnline"`
 	UpdatedAt       int64  `json:"updatedAt" validate:"required" swaggertype:"integer" format:"int64"`
+	// TrashRetentionDays is how many days items stay in the trash. 0 uses the server-wide retention, and a negative
+	// value keeps items until they are deleted by hand.
+	TrashRetentionDays int `json:"trashRetentionDays"`
+	// QuotaBytes is how many bytes the files of the user may take up. 0 means there is no limit.
+	QuotaBytes int64 `json:"quotaBytes" swaggertype:"integer" format:"int64"`
+	// UsedBytes is how many bytes the files of the user take up, including those in their trash. Only sent to the
+	// user themselves.
+	UsedBytes int64 `json:"usedBytes,omitempty" swaggertype:"integer" format:"int64"`
+	// TOTPEnabled is true if the user must enter a TOTP code to log in.
+	TOTPEnabled bool `json:"totpEnabled"`
 } //	@name	UserInfo
 
 // UserInfoArchive extends UserInfo with password for backup/restore operations.
 type UserInfoArchive struct {
 	UserInfo
 
-	Password string `json:"password" omitEmpty:"true"`
+	Password      string   `json:"password" omitEmpty:"true"`
+	TOTPSecret    string   `json:"totpSecret,omitempty"`
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
 } //	@name	UserInfoArchive
+
+// TOTPParams contains a TOTP code, or a recovery code in its place.
+type TOTPParams struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+} //	@name	TOTPParams
+
+// LoginTokenParams contains the token returned by the first step of a login, once the password was accepted.
+type LoginTokenParams struct {
+	LoginToken string `json:"loginToken" validate:"required"`
+} //	@name	LoginTokenParams
+
+// TOTPLoginParams contains the second step of a login, for users who use TOTP.
+type TOTPLoginParams struct {
+	LoginTokenParams
+	TOTPParams
+} //	@name	TOTPLoginParams
+
+// LoginChallengeInfo is returned in place of the user when a password was accepted, but a second factor is needed.
+type LoginChallengeInfo struct {
+	// LoginToken must be sent with the second step of the login. It expires after a few minutes.
+	LoginToken string `json:"loginToken" validate:"required"`
+	// TOTPRequired is true if the user must enter a TOTP code, or a recovery code.
+	TOTPRequired bool `json:"totpRequired"`
+	// TOTPSetupRequired is true if the user must enroll an authenticator app before they may log in.
+	TOTPSetupRequired bool `json:"totpSetupRequired"`
+} //	@name	LoginChallengeInfo
+
+// TOTPSetupInfo contains a new TOTP secret for the user to add to their authenticator app.
+type TOTPSetupInfo struct {
+	Secret string `json:"secret" validate:"required"`
+	// ProvisioningURI is the otpauth:// URI of the secret, to be shown as a QR code.
+	ProvisioningURI string `json:"provisioningURI" validate:"required"`
+} //	@name	TOTPSetupInfo
+
+// TOTPLoginInfo is returned by a successful second step of a login.
+type TOTPLoginInfo struct {
+	User UserInfo `json:"user" validate:"required"`
+	// RecoveryCodes are only set if the login also confirmed the enrollment of an authenticator app. They are shown
+	// only once.
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
+} //	@name	TOTPLoginInfo
+
+// RecoveryCodesInfo contains newly generated recovery codes. They are shown only once.
+type RecoveryCodesInfo struct {
+	RecoveryCodes []string `json:"recoveryCodes" validate:"required"`
+} //	@name	RecoveryCodesInfo
@@ -37,6 +37,7 @@ func Routes(_ context_service.AppContext) *router.Router {
 	// Media
 	r.Group("/media", func() {
 		r.Get("/types", media_api.GetMediaTypes)
+		r.Get("/map", media_api.GetMediaMap)
 
 		r.Group("/{mediaID}", func() {
 			r.Get("/info", router.RequirePermissionsMedia, media_api.GetMediaInfo)
@@ -72,6 +73,8 @@ func Routes(_ context_service.AppContext) *router.Router {
 			r.Get("/stats", router.RequireFilePermissions(), file_api.GetFileStats)
 			r.Get("/download", router.RequireFilePermissions(share_model.SharePermissionDownload), file_api.DownloadFile)
 			r.Get("/history", router.RequireFilePermissions(share_model.SharePermissionView), file_api.GetFolderHistory)
+			r.Get("/revisions", router.RequireFilePermissions(share_model.SharePermissionView), file_api.GetFileRevisions)
+			r.Get("/revisions/{revisionID}/download", router.RequireFilePermissions(share_model.SharePermissionDownload), file_api.DownloadFileRevision)
 		})
 	})
 
@@ -83,10 +86,15 @@ func Routes(_ context_service.AppContext) *router.Router {
 		r.Get("/autocomplete", file_api.AutocompletePath)
 		r.Patch("/untrash", file_api.UnTrashFiles)
 		r.Post("/restore", file_api.RestoreFiles)
+		r.Post("/copy", file_api.CopyFiles)
+		r.Post("/duplicates", file_api.FindDuplicates)
+		r.Post("/duplicates/resolve", file_api.ResolveDuplicates)
 		r.Get("/shared", file_api.GetSharedFiles)
 
 		r.Group("/{fileID}", func() {
 			r.Patch("", router.RequireFilePermissions(), file_api.UpdateFile)
+			r.Put("/text", router.RequireFilePermissions(share_model.SharePermissionEdit), file_api.UpdateFileText)
+			r.Post("/revisions/{revisionID}/restore", router.RequireFilePermissions(share_model.SharePermissionEdit), file_api.RestoreFileRevision)
 		})
 	}, router.RequireSignIn)
 
@@ -123,9 +131,31 @@ func Routes(_ context_service.AppContext) *router.Router {
 		r.Get("/file/{fileID}", file_api.GetTagsForFile)
 	}, router.RequireSignIn)
 
+	// Albums - read endpoints support share-based access, only the owner of an album can change it
+	r.Group("/albums/{albumID}", func() {
+		r.Get("", media_api.GetAlbum)
+		r.Get("/media", media_api.GetAlbumMedia)
+	})
+
+	r.Group("/albums", func() {
+		r.Get("", media_api.GetAlbums)
+		r.Post("", media_api.CreateAlbum)
+		r.Patch("/{albumID}", media_api.UpdateAlbum)
+		r.Delete("/{albumID}", media_api.DeleteAlbum)
+	}, router.RequireSignIn)
+
 	// Upload
 	r.Group("/upload", func() {
 		r.Post("", file_api.NewUploadTask)
+
+		// Resumable uploads, see https://tus.io/protocols/resumable-upload
+		r.Group("/tus", func() {
+			r.Post("", file_api.CreateTusUpload)
+			r.Head("/{tusUploadID}", file_api.GetTusUploadOffset)
+			r.Patch("/{tusUploadID}", file_api.WriteTusUploadChunk)
+			r.Delete("/{tusUploadID}", file_api.TerminateTusUpload)
+		})
+
 		r.Group("/{uploadID}", func() {
 			r.Get("", file_api.GetUploadResult)
 			r.Post("", file_api.NewFileUpload)
@@ -141,6 +171,10 @@ func Routes(_ context_service.AppContext) *router.Router {
 	r.Group("/users", func() {
 		// Must not use weblens auth here, as the user is not logged in yet
 		r.Post("/auth", user_api.Login)
+		r.Post("/auth/totp", user_api.LoginTOTP)
+		r.Post("/auth/totp/setup", user_api.SetupLoginTOTP)
+		r.Get("/auth/oidc/login", user_api.LoginOIDC)
+		r.Get("/auth/oidc/callback", user_api.OIDCCallback)
 		r.Head("/{username}", user_api.CheckExists)
 
 		r.Group("", func() {
@@ -149,12 +183,24 @@ func Routes(_ context_service.AppContext) *router.Router {
 			r.Get("/me", user_api.GetMe)
 			r.Get("/search", user_api.Search)
 			r.Post("/logout", user_api.Logout)
+			r.Get("/sessions", user_api.GetSessions)
+			r.Delete("/sessions/{sessionID}", user_api.RevokeSession)
+
+			r.Group("/totp", func() {
+				r.Post("", user_api.BeginTOTPSetup)
+				r.Delete("", user_api.DisableTOTP)
+				r.Post("/confirm", user_api.ConfirmTOTPSetup)
+				r.Post("/recoveryCodes", user_api.RegenerateRecoveryCodes)
+			})
 
 			r.Group("/{username}", func() {
 				r.Patch("/password", user_api.UpdatePassword)
 				r.Patch("/admin", user_api.SetAdmin)
 				r.Patch("/active", user_api.Activate)
 				r.Patch("/fullName", user_api.ChangeDisplayName)
+				r.Patch("/trashRetention", user_api.SetTrashRetention)
+				r.Patch("/quota", user_api.SetQuota)
+				r.Delete("/sessions", user_api.RevokeUserSessions)
 				r.Delete("", user_api.Delete)
 			})
 		}, router.RequireSignIn)
@@ -164,13 +210,16 @@ func Routes(_ context_service.AppContext) *router.Router {
 	r.Group("/share", func() {
 		// Reading a share must work without auth for public share browsing
 		r.Get("/{shareID}", file_api.GetFileShare)
+		r.Post("/{shareID}/unlock", file_api.UnlockFileShare)
 
 		// All mutation endpoints require authentication
 		r.Group("", func() {
 			r.Post("/file", file_api.CreateFileShare)
+			r.Post("/album", file_api.CreateAlbumShare)
 			r.Group("/{shareID}", func() {
 				r.Patch("", file_api.UpdateFileShare)
 				r.Delete("", file_api.DeleteShare)
+				r.Put("/password", file_api.SetSharePassword)
 
 				r.Group("/accessors", func() {
 					r.Post("", file_api.AddUserToShare)
@@ -191,17 +240,39 @@ func Routes(_ context_service.AppContext) *router.Router {
 	// Servers
 	r.Group("/tower", func() {
 		r.Post("/init", tower_api.InitializeTower)
+		r.Group("/restore", func() {
+			r.Post("", tower_api.RestoreTower)
+			r.Post("/files/{fileID}", tower_api.RestoreTowerFile)
+			r.Post("/complete", tower_api.CompleteTowerRestore)
+		}, router.RequireRestoringTower)
 		r.Group("", func() {
 			r.Get("/history", history_api.GetPagedHistoryActions)
+			r.Get("/history/gc", router.RequireCoreTower, history_api.GetHistoryGCReport)
 			r.Get("/tasks", tower_api.GetRunningTasks)
 
+			r.Group("/schedules", func() {
+				r.Get("", tower_api.GetSchedules)
+				r.Post("", tower_api.CreateSchedule)
+				r.Get("/jobs", tower_api.GetSchedulableJobs)
+				r.Patch("/{scheduleID}", tower_api.UpdateSchedule)
+				r.Delete("/{scheduleID}", tower_api.DeleteSchedule)
+				r.Post("/{scheduleID}/run", tower_api.RunSchedule)
+			})
+
 			r.Post("/reset", router.RequireOwner, tower_api.ResetServer)
 			r.Post("/remote", tower_api.AttachRemote)
 			r.Get("", tower_api.GetRemotes)
 
 			r.Get("/backup", history_api.DoFullBackup)
+			r.Get("/backup/metadata", history_api.GetBackupMetadata)
+			r.Get("/backup/actions", history_api.GetBackupActions)
+			r.Get("/backup/history", backup_api.GetBackupHistory)
+			r.Get("/backup/content/{contentID}", backup_api.GetBackupContent)
 
 			r.Post("/{serverID}/backup", backup_api.LaunchBackup)
+			r.Post("/{serverID}/restore", backup_api.LaunchRestore)
+			r.Get("/{serverID}/backup/history", backup_api.GetRemoteBackupHistory)
+			r.Post("/{serverID}/backup/restore", backup_api.RestoreFromBackup)
 
 			r.Post("/trace", tower_api.EnableTraceLogging)
 			r.Delete("/{serverID}", tower_api.DeleteRemote)
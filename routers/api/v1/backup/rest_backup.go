@@ -6,8 +6,10 @@ import (
 	"time"
 
 	tower_model "github.com/ethanrous/weblens/models/tower"
+	"github.com/ethanrous/weblens/modules/netwrk"
 	"github.com/ethanrous/weblens/modules/websocket"
 	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlstructs"
 	"github.com/ethanrous/weblens/services/ctxservice"
 	"github.com/ethanrous/weblens/services/jobs"
 	"github.com/ethanrous/weblens/services/proxy"
@@ -114,3 +116,86 @@ func LaunchBackup(ctx ctxservice.RequestContext) {
 
 	ctx.Status(http.StatusAccepted)
 }
+
+// LaunchRestore godoc
+//
+//	@ID			LaunchRestore
+//
+//	@Summary	Restore a core tower from its backup on this tower
+//	@Tags		Towers
+//
+//	@Security	SessionAuth[admin]
+//	@Security	ApiKeyAuth[admin]
+//
+//	@Param		serverID	path	string						true	"Server ID of the core to restore"
+//	@Param		request		body	wlstructs.RestoreCoreParams	false	"Address of the new core, if it has moved"
+//
+//	@Success	202
+//	@Failure	400
+//	@Failure	404
+//	@Failure	500
+//	@Router		/tower/{serverID}/restore [post]
+func LaunchRestore(ctx ctxservice.RequestContext) {
+	local, err := tower_model.GetLocal(ctx)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	if local.Role != tower_model.RoleBackup {
+		ctx.Error(http.StatusBadRequest, tower_model.ErrTowerNotBackup)
+
+		return
+	}
+
+	core, err := tower_model.GetTowerByID(ctx, ctx.Path("serverID"))
+	if err != nil {
+		ctx.Error(http.StatusNotFound, err)
+
+		return
+	}
+
+	if core.Role != tower_model.RoleCore {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("Only a core tower can be restored"))
+
+		return
+	}
+
+	params := wlstructs.RestoreCoreParams{}
+	if ctx.Req.ContentLength != 0 {
+		params, err = netwrk.ReadRequestBody[wlstructs.RestoreCoreParams](ctx.Req)
+		if err != nil {
+			ctx.Error(http.StatusBadRequest, err)
+
+			return
+		}
+	}
+
+	// The core is usually restored onto a new host, so remember where to find it from now on
+	if params.HostURL != "" && params.HostURL != core.Address {
+		core.Address = params.HostURL
+
+		err = tower_model.UpdateTower(ctx, &core)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return
+		}
+	}
+
+	t, err := jobs.RestoreOne(ctx, core, local)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	err = ctx.ClientService.SubscribeToTask(ctx, ctx.Client(), t, time.Now())
+	if err != nil {
+		// Log the error but do not fail the request, as the restore task has been created successfully
+		ctx.Log().Warn().Err(err).Msg("Failed to subscribe client to restore task")
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
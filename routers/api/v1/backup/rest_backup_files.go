@@ -0,0 +1,291 @@
+package backup
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	file_model "github.com/ethanrous/weblens/models/file"
+	share_model "github.com/ethanrous/weblens/models/share"
+	tower_model "github.com/ethanrous/weblens/models/tower"
+	"github.com/ethanrous/weblens/modules/netwrk"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlfs"
+	"github.com/ethanrous/weblens/modules/wlstructs"
+	"github.com/ethanrous/weblens/services/auth"
+	"github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/ethanrous/weblens/services/journal"
+	"github.com/ethanrous/weblens/services/reshape"
+	tower_service "github.com/ethanrous/weblens/services/tower"
+)
+
+// GetBackupHistory godoc
+//
+//	@ID			GetBackupHistory
+//
+//	@Summary	Get the files in a folder at a point in time, from the journal a backup keeps of the requesting core
+//	@Tags		Towers
+//	@Produce	json
+//
+//	@Security	ApiKeyAuth[admin]
+//
+//	@Param		path		query	string	true	"Portable path of the folder"
+//	@Param		timestamp	query	int		true	"Timestamp in milliseconds since epoch"
+//
+//	@Success	200	{array}	wlstructs.FileActionInfo	"Latest action of each file in the folder"
+//	@Failure	400
+//	@Failure	500
+//	@Router		/tower/backup/history [get]
+func GetBackupHistory(ctx ctxservice.RequestContext) {
+	core, ok := requireBackedUpCore(ctx)
+	if !ok {
+		return
+	}
+
+	path, timestamp, ok := readHistoryQuery(ctx)
+	if !ok {
+		return
+	}
+
+	actions, err := journal.GetPastFolderActions(ctx, path, timestamp, core.TowerID)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	actionInfos := make([]wlstructs.FileActionInfo, 0, len(actions))
+	for _, a := range actions {
+		actionInfos = append(actionInfos, reshape.FileActionToFileActionInfo(a, ""))
+	}
+
+	ctx.JSON(http.StatusOK, actionInfos)
+}
+
+// GetBackupContent godoc
+//
+//	@ID			GetBackupContent
+//
+//	@Summary	Download file contents a backup keeps for the requesting core
+//	@Tags		Towers
+//	@Produce	application/octet-stream
+//
+//	@Security	ApiKeyAuth[admin]
+//
+//	@Param		contentID	path	string	true	"Content ID of the file"
+//
+//	@Success	200
+//	@Failure	400
+//	@Failure	404
+//	@Router		/tower/backup/content/{contentID} [get]
+func GetBackupContent(ctx ctxservice.RequestContext) {
+	core, ok := requireBackedUpCore(ctx)
+	if !ok {
+		return
+	}
+
+	// Content IDs are plain file names in the restore tree, so anything that could leave it is rejected
+	contentID := ctx.Path("contentID")
+	if contentID == "" || strings.HasPrefix(contentID, ".") || strings.ContainsAny(contentID, `/\`) {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("invalid content ID"))
+
+		return
+	}
+
+	contentPath := file_model.RestoreDirPath.Child(core.TowerID, true).Child(contentID, false).ToAbsolute()
+
+	if _, err := os.Stat(contentPath); err != nil {
+		ctx.Error(http.StatusNotFound, wlerrors.Errorf("content [%s] is not in the backup", contentID))
+
+		return
+	}
+
+	http.ServeFile(ctx.W, ctx.Req, contentPath)
+}
+
+// GetRemoteBackupHistory godoc
+//
+//	@ID			GetRemoteBackupHistory
+//
+//	@Summary	Get the files in a folder at a point in time, from the journal of a backup tower
+//	@Tags		Towers
+//	@Produce	json
+//
+//	@Security	SessionAuth[admin]
+//	@Security	ApiKeyAuth[admin]
+//
+//	@Param		serverID	path	string	true	"Server ID of the backup tower"
+//	@Param		path		query	string	true	"Portable path of the folder"
+//	@Param		timestamp	query	int		true	"Timestamp in milliseconds since epoch"
+//
+//	@Success	200	{array}	wlstructs.FileActionInfo	"Latest action of each file in the folder"
+//	@Failure	400
+//	@Failure	404
+//	@Failure	500
+//	@Router		/tower/{serverID}/backup/history [get]
+func GetRemoteBackupHistory(ctx ctxservice.RequestContext) {
+	backup, ok := requireBackupTower(ctx)
+	if !ok {
+		return
+	}
+
+	path, timestamp, ok := readHistoryQuery(ctx)
+	if !ok {
+		return
+	}
+
+	actionInfos, err := tower_service.GetBackupHistory(ctx, backup, path, timestamp)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, actionInfos)
+}
+
+// RestoreFromBackup godoc
+//
+//	@ID			RestoreFromBackup
+//
+//	@Summary	Restore files from some time in the past, using the journal and contents kept by a backup tower
+//	@Tags		Towers
+//	@Accept		json
+//	@Produce	json
+//
+//	@Security	SessionAuth[admin]
+//	@Security	ApiKeyAuth[admin]
+//
+//	@Param		serverID	path		string								true	"Server ID of the backup tower"
+//	@Param		request		body		wlstructs.RestoreBackupFilesParams	true	"Restore files request body"
+//
+//	@Success	200			{object}	wlstructs.RestoreFilesInfo			"Restore files info"
+//	@Failure	400
+//	@Failure	404
+//	@Failure	500
+//	@Router		/tower/{serverID}/backup/restore [post]
+func RestoreFromBackup(ctx ctxservice.RequestContext) {
+	backup, ok := requireBackupTower(ctx)
+	if !ok {
+		return
+	}
+
+	body, err := netwrk.ReadRequestBody[wlstructs.RestoreBackupFilesParams](ctx.Req)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	if body.Timestamp == 0 {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("Missing body parameter 'timestamp'"))
+
+		return
+	}
+
+	if len(body.FileIDs) == 0 {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("Missing body parameter 'fileIDs'"))
+
+		return
+	}
+
+	path, err := wlfs.ParsePortable(body.Path)
+	if err != nil || !path.IsDir() {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("Body parameter 'path' must be a folder"))
+
+		return
+	}
+
+	var newParent *file_model.WeblensFileImpl
+
+	if body.NewParentID != "" {
+		newParent, err = auth.RequireFileAccessOne(ctx, body.NewParentID, share_model.SharePermissionEdit)
+		if err != nil {
+			return
+		}
+	} else {
+		newParent, err = ctx.FileService.GetFileByID(ctx, ctx.Requester.HomeID)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, wlerrors.Wrap(err, "failed to get user home"))
+
+			return
+		}
+	}
+
+	if !newParent.IsDir() {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("New parent must be a directory"))
+
+		return
+	}
+
+	err = tower_service.RestoreFromBackup(ctx, backup, path, time.UnixMilli(body.Timestamp), body.FileIDs, newParent)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, wlerrors.Wrap(err, "failed to restore files from backup"))
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wlstructs.RestoreFilesInfo{NewParentID: newParent.ID()})
+}
+
+// requireBackedUpCore checks that the local tower is a backup, and the request comes from one of the cores it backs up.
+func requireBackedUpCore(ctx ctxservice.RequestContext) (tower_model.Instance, bool) {
+	local, err := tower_model.GetLocal(ctx)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return tower_model.Instance{}, false
+	}
+
+	if !local.IsBackup() {
+		ctx.Error(http.StatusBadRequest, tower_model.ErrTowerNotBackup)
+
+		return tower_model.Instance{}, false
+	}
+
+	if !ctx.Remote.IsCore() {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("Request must come from a core tower"))
+
+		return tower_model.Instance{}, false
+	}
+
+	return ctx.Remote, true
+}
+
+// requireBackupTower looks up the backup tower named by the serverID path parameter.
+func requireBackupTower(ctx ctxservice.RequestContext) (tower_model.Instance, bool) {
+	backup, err := tower_model.GetTowerByID(ctx, ctx.Path("serverID"))
+	if err != nil {
+		ctx.Error(http.StatusNotFound, err)
+
+		return backup, false
+	}
+
+	if !backup.IsBackup() {
+		ctx.Error(http.StatusBadRequest, wlerrors.Errorf("Tower [%s] is not a backup", backup.TowerID))
+
+		return backup, false
+	}
+
+	return backup, true
+}
+
+func readHistoryQuery(ctx ctxservice.RequestContext) (wlfs.Filepath, time.Time, bool) {
+	path, err := wlfs.ParsePortable(ctx.Query("path"))
+	if err != nil || !path.IsDir() {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("Query parameter 'path' must be a folder"))
+
+		return path, time.Time{}, false
+	}
+
+	millis, err := strconv.ParseInt(ctx.Query("timestamp"), 10, 64)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("invalid timestamp"))
+
+		return path, time.Time{}, false
+	}
+
+	return path, time.UnixMilli(millis), true
+}
@@ -0,0 +1,179 @@
+package file
+
+import (
+	"net/http"
+	"slices"
+
+	file_model "github.com/ethanrous/weblens/models/file"
+	"github.com/ethanrous/weblens/models/job"
+	share_model "github.com/ethanrous/weblens/models/share"
+	"github.com/ethanrous/weblens/modules/netwrk"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlstructs"
+	"github.com/ethanrous/weblens/services/auth"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+	file_service "github.com/ethanrous/weblens/services/file"
+	"github.com/ethanrous/weblens/services/reshape"
+)
+
+// FindDuplicates godoc
+//
+//	@ID	FindDuplicates
+//
+//	@Security
+//	@Security	SessionAuth
+//
+//	@Summary	Search the files of the user, or of the whole server, for duplicates. The groups of duplicates are sent over the websocket once found
+//	@Tags		Files
+//	@Produce	json
+//	@Param		request	body		wlstructs.FindDuplicatesParams	true	"Find duplicates request body"
+//	@Success	202		{object}	wlstructs.TaskInfo				"Find Duplicates Task"
+//	@Failure	400
+//	@Failure	403
+//	@Failure	500
+//	@Router		/files/duplicates [post]
+func FindDuplicates(ctx context_service.RequestContext) {
+	params, err := netwrk.ReadRequestBody[wlstructs.FindDuplicatesParams](ctx.Req)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	threshold := params.Threshold
+	if threshold == 0 {
+		threshold = file_service.DefaultSimilarityThreshold
+	}
+
+	var roots []*file_model.WeblensFileImpl
+
+	switch params.Scope {
+	case wlstructs.DuplicateScopeUser, "":
+		home, err := ctx.FileService.GetFileByFilepath(ctx, file_model.UsersRootPath.Child(ctx.Requester.GetUsername(), true))
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return
+		}
+
+		roots = append(roots, home)
+	case wlstructs.DuplicateScopeServer:
+		if !ctx.Requester.IsAdmin() {
+			ctx.Error(http.StatusForbidden, wlerrors.New("only admins can search the whole server for duplicates"))
+
+			return
+		}
+
+		usersRoot, err := ctx.FileService.GetFileByID(ctx, file_model.UsersTreeKey)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return
+		}
+
+		roots = append(roots, usersRoot)
+	default:
+		ctx.Error(http.StatusBadRequest, wlerrors.Errorf("unknown duplicate scope [%s]", params.Scope))
+
+		return
+	}
+
+	meta := job.FindDuplicatesMeta{
+		Requester: ctx.Requester,
+		Roots:     roots,
+		Similar:   params.Similar,
+		Threshold: threshold,
+	}
+
+	if err := meta.Verify(); err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	t, err := ctx.TaskService.DispatchJob(ctx, job.FindDuplicatesTask, meta, nil)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, reshape.TaskToTaskInfo(t))
+}
+
+// ResolveDuplicates godoc
+//
+//	@ID	ResolveDuplicates
+//
+//	@Security
+//	@Security	SessionAuth
+//
+//	@Summary	Keep one file of a group of duplicates, and move the rest to the trash as a single journaled event
+//	@Tags		Files
+//	@Param		request	body	wlstructs.ResolveDuplicatesParams	true	"Resolve duplicates request body"
+//	@Success	200
+//	@Failure	400
+//	@Failure	403
+//	@Failure	404
+//	@Failure	500
+//	@Router		/files/duplicates/resolve [post]
+func ResolveDuplicates(ctx context_service.RequestContext) {
+	params, err := netwrk.ReadRequestBody[wlstructs.ResolveDuplicatesParams](ctx.Req)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	if !slices.Contains(params.FileIDs, params.KeepFileID) {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("the file to keep must be one of the duplicates"))
+
+		return
+	}
+
+	keep, err := auth.RequireFileAccessOne(ctx, params.KeepFileID, share_model.SharePermissionView)
+	if err != nil {
+		return
+	}
+
+	if file_model.IsFileInTrash(keep) {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("the file to keep is in the trash"))
+
+		return
+	}
+
+	duplicateIDs := slices.DeleteFunc(slices.Clone(params.FileIDs), func(fileID string) bool {
+		return fileID == params.KeepFileID
+	})
+
+	slices.Sort(duplicateIDs)
+	duplicateIDs = slices.Compact(duplicateIDs)
+
+	if len(duplicateIDs) == 0 {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("no duplicates to remove"))
+
+		return
+	}
+
+	duplicates, err := auth.RequireFileAccess(ctx, duplicateIDs, share_model.SharePermissionDelete)
+	if err != nil {
+		return
+	}
+
+	for _, f := range duplicates {
+		if f.IsDir() {
+			ctx.Error(http.StatusBadRequest, wlerrors.Errorf("[%s] is a folder, not a duplicate file", f.GetPortablePath().Filename()))
+
+			return
+		}
+	}
+
+	err = ctx.FileService.TrashFiles(ctx, duplicates...)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
@@ -92,18 +92,84 @@ func GetFile(ctx context_service.RequestContext) {
 func GetFileText(ctx context_service.RequestContext) {
 	file := ctx.File
 
-	filename := file.GetPortablePath().Filename()
-
-	dotIndex := strings.LastIndex(filename, ".")
-	if filename[dotIndex:] != ".txt" {
-		ctx.Error(http.StatusBadRequest, wlerrors.New("file is not a text file"))
+	if !file_service.IsTextFile(file) {
+		ctx.Error(http.StatusBadRequest, file_service.ErrNotTextFile)
 
 		return
 	}
 
+	// The content ID can be sent back as the base of an edit, see UpdateFileText
+	ctx.SetHeader("ETag", strconv.Quote(file.GetContentID()))
 	http.ServeFile(ctx.W, ctx.Req, file.GetPortablePath().ToAbsolute())
 }
 
+// UpdateFileText godoc
+//
+//	@ID	UpdateFileText
+//
+//	@Security
+//	@Security	SessionAuth
+//
+//	@Summary	Replace the text of a text file
+//	@Tags		Files
+//	@Accept		json
+//	@Produce	json
+//	@Param		fileID	path		string							true	"File ID"
+//	@Param		shareID	query		string							false	"Share ID"
+//	@Param		request	body		wlstructs.UpdateFileTextParams	true	"New text, and the content ID of the text it was based on"
+//	@Success	200		{object}	wlstructs.FileInfo				"File Info"
+//	@Failure	400
+//	@Failure	403
+//	@Failure	404
+//	@Failure	409		{object}	wlstructs.WeblensErrorInfo	"The file was changed since it was read"
+//	@Failure	413
+//	@Router		/files/{fileID}/text [put]
+func UpdateFileText(ctx context_service.RequestContext) {
+	file := ctx.File
+
+	if file_model.IsFileInTrash(file) {
+		ctx.Error(http.StatusForbidden, wlerrors.New("cannot edit file in trash"))
+
+		return
+	}
+
+	ctx.Req.Body = http.MaxBytesReader(ctx.W, ctx.Req.Body, file_service.MaxTextSize)
+
+	params, err := netwrk.ReadRequestBody[wlstructs.UpdateFileTextParams](ctx.Req)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if wlerrors.As(err, &maxErr) {
+			ctx.Error(http.StatusRequestEntityTooLarge, wlerrors.Errorf("text must be at most %d bytes", file_service.MaxTextSize))
+		} else {
+			ctx.Error(http.StatusBadRequest, err)
+		}
+
+		return
+	}
+
+	err = file_service.WriteFileText(ctx.AppCtx(), file, params.ContentID, []byte(params.Text))
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	if media_model.EmbedEligible(file.GetPortablePath().Ext()) {
+		if _, err := ctx.TaskService.DispatchJob(ctx, job.ExtractAndEmbedTask, job.ExtractAndEmbedMeta{File: file}, nil); err != nil {
+			ctx.Log().Warn().Err(err).Msgf("Failed to dispatch ExtractAndEmbedTask for %s", file.GetPortablePath())
+		}
+	}
+
+	fileInfo, err := reshape.WeblensFileToFileInfo(ctx, file)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, fileInfo)
+}
+
 // GetFileStats godoc
 //
 //	@ID	GetFileStats
@@ -544,6 +610,11 @@ func GetSharedFiles(ctx context_service.RequestContext) {
 	sharesMap := make(map[string]*share_model.FileShare, len(shares))
 
 	for _, share := range shares {
+		// Album shares are listed with the albums of the user, not with their files
+		if share.IsAlbumShare() {
+			continue
+		}
+
 		sharesMap[share.FileID] = &share
 
 		f, err := ctx.FileService.GetFileByID(ctx, share.FileID)
@@ -1033,6 +1104,36 @@ func MoveFiles(ctx context_service.RequestContext) {
 		files = append(files, f)
 	}
 
+	// Files moved between the trees of two different users change who is charged for them
+	newOwner, err := file_model.GetFileOwnerName(ctx, newParent)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	var movedBytes int64
+
+	for _, f := range files {
+		owner, err := file_model.GetFileOwnerName(ctx, f)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return
+		}
+
+		if owner != newOwner {
+			movedBytes += f.Size()
+		}
+	}
+
+	err = file_service.CheckQuota(ctx, newParent, movedBytes)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
 	err = db.WithTransaction(ctx, func(sessCtx context.Context) error {
 		return ctx.FileService.MoveFiles(sessCtx, files, newParent)
 	})
@@ -1045,6 +1146,100 @@ func MoveFiles(ctx context_service.RequestContext) {
 	ctx.Status(http.StatusOK)
 }
 
+// CopyFiles godoc
+//
+//	@ID	CopyFiles
+//
+//	@Security
+//	@Security	SessionAuth
+//
+//	@Summary	Copy a list of files, and the contents of any folders among them, into a folder
+//	@Tags		Files
+//	@Produce	json
+//	@Param		request	body		wlstructs.CopyFilesParams	true	"Copy files request body"
+//	@Param		shareID	query		string						false	"Share ID"
+//	@Success	202		{object}	wlstructs.TaskInfo			"Copy Task"
+//	@Failure	400
+//	@Failure	403
+//	@Failure	404
+//	@Failure	500
+//	@Router		/files/copy [post]
+func CopyFiles(ctx context_service.RequestContext) {
+	params, err := netwrk.ReadRequestBody[wlstructs.CopyFilesParams](ctx.Req)
+	if err != nil {
+		return
+	}
+
+	if len(params.Files) == 0 {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("No file ids provided"))
+
+		return
+	}
+
+	newParent, err := auth.RequireFileAccessOne(ctx, params.NewParentID, share_model.SharePermissionEdit)
+	if err != nil {
+		return
+	}
+
+	if !newParent.IsDir() {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("New parent is not a directory"))
+
+		return
+	}
+
+	if file_model.IsFileInTrash(newParent) {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("Cannot copy files into the trash"))
+
+		return
+	}
+
+	// A copy made through a share takes the content out of it, so the share must allow downloading
+	sourcePerms := []share_model.Permission{share_model.SharePermissionView}
+	if ctx.Share != nil {
+		sourcePerms = append(sourcePerms, share_model.SharePermissionDownload)
+	}
+
+	files := make([]*file_model.WeblensFileImpl, 0, len(params.Files))
+
+	for _, fileID := range params.Files {
+		f, err := ctx.FileService.GetFileByID(ctx, fileID)
+		if err != nil {
+			ctx.Error(http.StatusNotFound, wlerrors.New("Could not find file with id "+fileID))
+
+			return
+		}
+
+		if _, err = auth.CanUserAccessFile(ctx, ctx.Requester, f, ctx.Share, sourcePerms...); err != nil {
+			ctx.Error(http.StatusForbidden, err)
+
+			return
+		}
+
+		if f.IsDir() && f.GetPortablePath().IsParentOf(newParent.GetPortablePath()) {
+			ctx.Error(http.StatusBadRequest, file_service.ErrCopyIntoSelf)
+
+			return
+		}
+
+		files = append(files, f)
+	}
+
+	meta := job.CopyFilesMeta{
+		Requester:   ctx.Requester,
+		Destination: newParent,
+		Files:       files,
+	}
+
+	t, err := ctx.TaskService.DispatchJob(ctx, job.CopyFilesTask, meta, nil)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, reshape.TaskToTaskInfo(t))
+}
+
 // UnTrashFiles godoc
 //
 //	@ID			UnTrashFiles
@@ -1070,6 +1265,7 @@ func UnTrashFiles(ctx context_service.RequestContext) {
 		return
 	}
 
+	// Files in the trash still count towards the quota of their owner, so returning them needs no quota check
 	err = ctx.FileService.ReturnFilesFromTrash(ctx, files)
 	if err != nil {
 		ctx.Error(http.StatusInternalServerError, wlerrors.Wrap(err, "Failed to un-trash files"))
@@ -1182,7 +1378,15 @@ func NewUploadTask(ctx context_service.RequestContext) {
 		return
 	}
 
-	if _, err := auth.RequireFileAccessOne(ctx, upInfo.RootFolderID, share_model.SharePermissionEdit); err != nil {
+	root, err := auth.RequireFileAccessOne(ctx, upInfo.RootFolderID, share_model.SharePermissionEdit)
+	if err != nil {
+		return
+	}
+
+	err = file_service.CheckQuota(ctx, root, 0)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
 		return
 	}
 
@@ -1256,6 +1460,21 @@ func NewFileUpload(ctx context_service.RequestContext) {
 		return
 	}
 
+	// Every file of an upload is placed under the same root, so its owner is charged for all of them
+	var uploadBytes int64
+	for _, newFInfo := range params.NewFiles {
+		uploadBytes += newFInfo.FileSize
+	}
+
+	if len(parents) != 0 {
+		err = file_service.CheckQuota(ctx, parents[0], uploadBytes)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return
+		}
+	}
+
 	var ids []string
 
 	for i, newFInfo := range params.NewFiles {
@@ -1373,13 +1592,29 @@ func HandleUploadChunk(ctx context_service.RequestContext) {
 		chunk = chunk[bytes.Index(chunk, []byte("\r\n\r\n"))+4:]
 	}
 
-	_, _, _, err = ctx.ContentRange()
+	_, _, total, err := ctx.ContentRange()
 	if err != nil {
 		ctx.Error(http.StatusRequestedRangeNotSatisfiable, err)
 
 		return
 	}
 
+	// The sizes declared when files are added to the upload can't be trusted, so check the quota again as content
+	// arrives. The whole file is reserved, so chunks of other uploads that are still on their way are counted too.
+	f, err := ctx.FileService.GetFileByID(ctx, fileID)
+	if err != nil {
+		ctx.Error(http.StatusNotFound, err)
+
+		return
+	}
+
+	err = file_service.ReserveQuota(ctx, f.GetParent(), f.ID(), int64(total), f)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
 	err = t.Manipulate(
 		func(meta task.Metadata) error {
 			chunkData := job.FileChunk{FileID: fileID, Chunk: chunk, ContentRange: ctx.Header("Content-Range")}
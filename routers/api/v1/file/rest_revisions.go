@@ -0,0 +1,156 @@
+package file
+
+import (
+	"net/http"
+
+	"github.com/ethanrous/weblens/models/db"
+	"github.com/ethanrous/weblens/models/history"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlstructs"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+	file_service "github.com/ethanrous/weblens/services/file"
+	"github.com/ethanrous/weblens/services/reshape"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetFileRevisions godoc
+//
+//	@ID	GetFileRevisions
+//
+//	@Security
+//	@Security	SessionAuth
+//
+//	@Summary	Get the past contents of a file
+//	@Tags		Files
+//	@Produce	json
+//	@Param		fileID	path		string						true	"File ID"
+//	@Param		shareID	query		string						false	"Share ID"
+//	@Success	200		{array}		wlstructs.FileRevisionInfo	"File revisions, newest first"
+//	@Failure	400
+//	@Failure	404
+//	@Router		/files/{fileID}/revisions [get]
+func GetFileRevisions(ctx context_service.RequestContext) {
+	file := ctx.File
+
+	if file.IsDir() {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("folders do not have revisions"))
+
+		return
+	}
+
+	revisions, err := history.GetRevisionActions(ctx, file.ID())
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	revisionInfos := make([]wlstructs.FileRevisionInfo, 0, len(revisions))
+
+	for i, r := range revisions {
+		isCurrent := i == 0
+		revisionInfos = append(revisionInfos, reshape.FileActionToFileRevisionInfo(r, isCurrent, file_service.IsRevisionAvailable(file, r)))
+	}
+
+	ctx.JSON(http.StatusOK, revisionInfos)
+}
+
+// DownloadFileRevision godoc
+//
+//	@ID	DownloadFileRevision
+//
+//	@Security
+//	@Security	SessionAuth
+//
+//	@Summary	Download a past content of a file
+//	@Tags		Files
+//	@Produce	octet-stream
+//	@Param		fileID		path		string						true	"File ID"
+//	@Param		revisionID	path		string						true	"Revision ID"
+//	@Param		shareID		query		string						false	"Share ID"
+//	@Success	200			{string}	binary						"File content"
+//	@Failure	404			{object}	wlstructs.WeblensErrorInfo	"Error Info"
+//	@Failure	410			{object}	wlstructs.WeblensErrorInfo	"Error Info"
+//	@Router		/files/{fileID}/revisions/{revisionID}/download [get]
+func DownloadFileRevision(ctx context_service.RequestContext) {
+	file := ctx.File
+
+	revision, ok := getFileRevision(ctx)
+	if !ok {
+		return
+	}
+
+	content, err := file_service.OpenRevision(file, revision)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+	defer content.Close() //nolint:errcheck
+
+	http.ServeContent(ctx.W, ctx.Req, file.GetPortablePath().Filename(), revision.Timestamp, content)
+}
+
+// RestoreFileRevision godoc
+//
+//	@ID	RestoreFileRevision
+//
+//	@Security
+//	@Security	SessionAuth
+//
+//	@Summary	Make a past content of a file its current content again
+//	@Tags		Files
+//	@Produce	json
+//	@Param		fileID		path		string				true	"File ID"
+//	@Param		revisionID	path		string				true	"Revision ID"
+//	@Success	200			{object}	wlstructs.FileInfo	"File Info"
+//	@Failure	404
+//	@Failure	410
+//	@Router		/files/{fileID}/revisions/{revisionID}/restore [post]
+func RestoreFileRevision(ctx context_service.RequestContext) {
+	file := ctx.File
+
+	revision, ok := getFileRevision(ctx)
+	if !ok {
+		return
+	}
+
+	err := file_service.RestoreFileRevision(ctx.AppCtx(), file, revision)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	fileInfo, err := reshape.WeblensFileToFileInfo(ctx, file)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, fileInfo)
+}
+
+// getFileRevision loads the revision of ctx.File named in the request path.
+func getFileRevision(ctx context_service.RequestContext) (history.FileAction, bool) {
+	revisionID, err := primitive.ObjectIDFromHex(ctx.Path("revisionID"))
+	if err != nil {
+		ctx.Error(http.StatusNotFound, wlerrors.New("revision not found"))
+
+		return history.FileAction{}, false
+	}
+
+	revision, err := history.GetRevisionAction(ctx, ctx.File.ID(), revisionID)
+	if err != nil {
+		if db.IsNotFound(err) {
+			ctx.Error(http.StatusNotFound, err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, err)
+		}
+
+		return history.FileAction{}, false
+	}
+
+	return revision, true
+}
@@ -2,7 +2,9 @@ package file
 
 import (
 	"net/http"
+	"time"
 
+	album_model "github.com/ethanrous/weblens/models/album"
 	"github.com/ethanrous/weblens/models/db"
 	share_model "github.com/ethanrous/weblens/models/share"
 	user_model "github.com/ethanrous/weblens/models/usermodel"
@@ -13,6 +15,7 @@ import (
 	"github.com/ethanrous/weblens/services/ctxservice"
 	file_service "github.com/ethanrous/weblens/services/file"
 	"github.com/ethanrous/weblens/services/reshape"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // CreateFileShare godoc
@@ -24,6 +27,7 @@ import (
 //	@Produce	json
 //	@Param		request	body		wlstructs.FileShareParams	true	"New File Share Params"
 //	@Success	200		{object}	wlstructs.ShareInfo			"New File Share"
+//	@Failure	400
 //	@Failure	409
 //	@Router		/share/file [post]
 func CreateFileShare(ctx ctxservice.RequestContext) {
@@ -66,17 +70,23 @@ func CreateFileShare(ctx ctxservice.RequestContext) {
 		return
 	}
 
-	accessors := make([]*user_model.User, 0, len(shareParams.Users))
+	var expiresMillis int64
+	if shareParams.Expires != nil {
+		expiresMillis = *shareParams.Expires
+	}
 
-	for _, un := range shareParams.Users {
-		u, err := user_model.GetUserByUsername(ctx, un)
-		if err != nil {
-			ctx.Error(http.StatusNotFound, err)
+	expires, err := parseShareExpiry(expiresMillis)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
 
-			return
-		}
+		return
+	}
 
-		accessors = append(accessors, u)
+	accessors, err := getShareAccessors(ctx, shareParams.Users)
+	if err != nil {
+		ctx.Error(http.StatusNotFound, err)
+
+		return
 	}
 
 	newShare, err := share_model.NewFileShare(ctx, file.ID(), ctx.Requester, accessors, shareParams.Public, shareParams.Wormhole, shareParams.TimelineOnly)
@@ -86,6 +96,15 @@ func CreateFileShare(ctx ctxservice.RequestContext) {
 		return
 	}
 
+	newShare.Expires = expires
+
+	err = newShare.SetPassword(ctx, shareParams.Password)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
 	err = share_model.SaveFileShare(ctx, newShare)
 	if err != nil {
 		ctx.Error(http.StatusInternalServerError, err)
@@ -97,6 +116,102 @@ func CreateFileShare(ctx ctxservice.RequestContext) {
 	ctx.JSON(http.StatusCreated, newShareInfo)
 }
 
+// CreateAlbumShare godoc
+//
+//	@ID			CreateAlbumShare
+//
+//	@Security	SessionAuth
+//
+//	@Summary	Share an album
+//	@Tags		Share
+//	@Produce	json
+//	@Param		request	body		wlstructs.AlbumShareParams	true	"New Album Share Params"
+//	@Success	201		{object}	wlstructs.ShareInfo			"New Album Share"
+//	@Failure	400
+//	@Failure	404
+//	@Failure	409
+//	@Router		/share/album [post]
+func CreateAlbumShare(ctx ctxservice.RequestContext) {
+	shareParams, err := netwrk.ReadRequestBody[wlstructs.AlbumShareParams](ctx.Req)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	albumID, err := primitive.ObjectIDFromHex(shareParams.AlbumID)
+	if err != nil {
+		ctx.Error(http.StatusNotFound, wlerrors.New("album not found"))
+
+		return
+	}
+
+	album, err := album_model.GetAlbumByID(ctx, albumID)
+	if err != nil {
+		ctx.Error(http.StatusNotFound, err)
+
+		return
+	}
+
+	if album.Owner != ctx.Requester.GetUsername() {
+		ctx.Error(http.StatusNotFound, wlerrors.New("you are not the owner of this album"))
+
+		return
+	}
+
+	_, err = share_model.GetShareByAlbumID(ctx, album.ID())
+	if err == nil {
+		ctx.Error(http.StatusConflict, share_model.ErrShareAlreadyExists)
+
+		return
+	}
+
+	if !db.IsNotFound(err) {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	expires, err := parseShareExpiry(shareParams.Expires)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	accessors, err := getShareAccessors(ctx, shareParams.Users)
+	if err != nil {
+		ctx.Error(http.StatusNotFound, err)
+
+		return
+	}
+
+	newShare, err := share_model.NewAlbumShare(ctx, album.ID(), ctx.Requester, accessors, shareParams.Public, shareParams.TimelineOnly)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	newShare.Expires = expires
+
+	err = newShare.SetPassword(ctx, shareParams.Password)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	err = share_model.SaveFileShare(ctx, newShare)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, reshape.ShareToShareInfo(ctx, newShare, false))
+}
+
 // UpdateFileShare godoc
 //
 //	@ID			UpdateFileShare
@@ -107,6 +222,7 @@ func CreateFileShare(ctx ctxservice.RequestContext) {
 //	@Param		shareID	path		string						true	"Share ID"
 //	@Param		request	body		wlstructs.FileShareParams	true	"Updated File Share Params"
 //	@Success	200		{object}	wlstructs.ShareInfo			"Updated File Share"
+//	@Failure	400
 //	@Failure	409
 //	@Router		/share/{shareID} [patch]
 func UpdateFileShare(ctx ctxservice.RequestContext) {
@@ -152,14 +268,39 @@ func UpdateFileShare(ctx ctxservice.RequestContext) {
 		return
 	}
 
-	f, err := ctx.FileService.GetFileByID(ctx, share.FileID)
+	if shareParams.Expires != nil {
+		expires, err := parseShareExpiry(*shareParams.Expires)
+		if err != nil {
+			ctx.Error(http.StatusBadRequest, err)
+
+			return
+		}
+
+		err = share.SetExpires(ctx, expires)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return
+		}
+	}
+
+	if shareParams.Enabled != nil {
+		err = share.UpdateEnabled(ctx, *shareParams.Enabled)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return
+		}
+	}
+
+	shareInfo, err := getShareInfo(ctx, share)
 	if err != nil {
 		ctx.Error(http.StatusNotFound, err)
 
 		return
 	}
 
-	ctx.JSON(http.StatusOK, reshape.ShareToShareInfo(ctx, share, f.IsDir()))
+	ctx.JSON(http.StatusOK, shareInfo)
 }
 
 // GetFileShare godoc
@@ -171,7 +312,9 @@ func UpdateFileShare(ctx ctxservice.RequestContext) {
 //	@Produce	json
 //	@Param		shareID	path		string				true	"Share ID"
 //	@Success	200		{object}	wlstructs.ShareInfo	"File Share"
+//	@Failure	401
 //	@Failure	404
+//	@Failure	410
 //	@Router		/share/{shareID} [get]
 func GetFileShare(ctx ctxservice.RequestContext) {
 	shareID := share_model.IDFromString(ctx.Path("shareID"))
@@ -183,6 +326,20 @@ func GetFileShare(ctx ctxservice.RequestContext) {
 		return
 	}
 
+	// The owner can still see an expired share, so that they can extend or delete it
+	if share.IsExpired() && share.GetOwner() != ctx.Requester.GetUsername() {
+		ctx.Error(http.StatusGone, share_model.ErrShareExpired)
+
+		return
+	}
+
+	err = auth.CheckShareUnlocked(ctx, ctx.Requester, share)
+	if err != nil {
+		ctx.Error(http.StatusUnauthorized, err)
+
+		return
+	}
+
 	// Public shares can be viewed by anyone; private shares require ownership or accessor status
 	if !share.IsPublic() {
 		if ctx.Requester.IsPublic() {
@@ -196,17 +353,123 @@ func GetFileShare(ctx ctxservice.RequestContext) {
 		}
 	}
 
-	file, err := ctx.FileService.GetFileByID(ctx, share.FileID)
+	shareInfo, err := getShareInfo(ctx, share)
 	if err != nil {
 		ctx.Error(http.StatusNotFound, err)
 
 		return
 	}
 
-	shareInfo := reshape.ShareToShareInfo(ctx, share, file.IsDir())
 	ctx.JSON(http.StatusOK, shareInfo)
 }
 
+// SetSharePassword godoc
+//
+//	@ID			SetSharePassword
+//
+//	@Summary	Set or remove the password of a file share
+//	@Tags		Share
+//	@Produce	json
+//	@Param		shareID	path		string							true	"Share ID"
+//	@Param		request	body		wlstructs.SharePasswordParams	true	"New share password, empty to remove it"
+//	@Success	200		{object}	wlstructs.ShareInfo
+//	@Failure	403
+//	@Failure	404
+//	@Router		/share/{shareID}/password [put]
+func SetSharePassword(ctx ctxservice.RequestContext) {
+	shareID := share_model.IDFromString(ctx.Path("shareID"))
+
+	share, err := share_model.GetShareByID(ctx, shareID)
+	if err != nil {
+		ctx.Error(http.StatusNotFound, err)
+
+		return
+	}
+
+	if !auth.CanUserModifyShare(ctx.Requester, *share) {
+		ctx.Error(http.StatusForbidden, wlerrors.New("not authorized to modify this share"))
+
+		return
+	}
+
+	passwordBody, err := netwrk.ReadRequestBody[wlstructs.SharePasswordParams](ctx.Req)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	err = share.SetPassword(ctx, passwordBody.Password)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	shareInfo, err := getShareInfo(ctx, share)
+	if err != nil {
+		ctx.Error(http.StatusNotFound, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, shareInfo)
+}
+
+// UnlockFileShare godoc
+//
+//	@ID			UnlockFileShare
+//
+//	@Summary	Unlock a password protected file share
+//	@Tags		Share
+//	@Produce	json
+//	@Param		shareID	path	string							true	"Share ID"
+//	@Param		request	body	wlstructs.SharePasswordParams	true	"Share password"
+//	@Success	200
+//	@Failure	401
+//	@Failure	404
+//	@Failure	429
+//	@Router		/share/{shareID}/unlock [post]
+func UnlockFileShare(ctx ctxservice.RequestContext) {
+	shareID := share_model.IDFromString(ctx.Path("shareID"))
+
+	share, err := share_model.GetShareByID(ctx, shareID)
+	if err != nil {
+		ctx.Error(http.StatusNotFound, err)
+
+		return
+	}
+
+	if share.IsExpired() {
+		ctx.Error(http.StatusGone, share_model.ErrShareExpired)
+
+		return
+	}
+
+	// Only public shares can be unlocked with a password, private shares are only open to their accessors
+	if !share.IsPublic() || !share.HasPassword() {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("share is not password protected"))
+
+		return
+	}
+
+	passwordBody, err := netwrk.ReadRequestBody[wlstructs.SharePasswordParams](ctx.Req)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	err = auth.UnlockShare(ctx, share, passwordBody.Password)
+	if err != nil {
+		ctx.Error(http.StatusUnauthorized, err)
+
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
 // AddUserToShare godoc
 //
 //	@ID			AddUserToShare
@@ -267,14 +530,13 @@ func AddUserToShare(ctx ctxservice.RequestContext) {
 		return
 	}
 
-	file, err := ctx.FileService.GetFileByID(ctx, share.FileID)
+	shareInfo, err := getShareInfo(ctx, share)
 	if err != nil {
 		ctx.Error(http.StatusNotFound, err)
 
 		return
 	}
 
-	shareInfo := reshape.ShareToShareInfo(ctx, share, file.IsDir())
 	ctx.JSON(http.StatusOK, shareInfo)
 }
 
@@ -322,14 +584,13 @@ func RemoveUserFromShare(ctx ctxservice.RequestContext) {
 		return
 	}
 
-	file, err := ctx.FileService.GetFileByID(ctx, share.FileID)
+	shareInfo, err := getShareInfo(ctx, share)
 	if err != nil {
 		ctx.Error(http.StatusNotFound, err)
 
 		return
 	}
 
-	shareInfo := reshape.ShareToShareInfo(ctx, share, file.IsDir())
 	ctx.JSON(http.StatusOK, shareInfo)
 }
 
@@ -398,14 +659,13 @@ func SetShareAccessors(ctx ctxservice.RequestContext) {
 		return
 	}
 
-	file, err := ctx.FileService.GetFileByID(ctx, share.FileID)
+	shareInfo, err := getShareInfo(ctx, share)
 	if err != nil {
 		ctx.Error(http.StatusNotFound, err)
 
 		return
 	}
 
-	shareInfo := reshape.ShareToShareInfo(ctx, share, file.IsDir())
 	ctx.JSON(http.StatusOK, shareInfo)
 }
 
@@ -447,3 +707,48 @@ func DeleteShare(ctx ctxservice.RequestContext) {
 
 	ctx.Status(http.StatusOK)
 }
+
+// getShareInfo builds the ShareInfo of share, looking up the shared file to tell if it is a folder.
+func getShareInfo(ctx ctxservice.RequestContext, share *share_model.FileShare) (wlstructs.ShareInfo, error) {
+	if share.IsAlbumShare() {
+		return reshape.ShareToShareInfo(ctx, share, false), nil
+	}
+
+	f, err := ctx.FileService.GetFileByID(ctx, share.FileID)
+	if err != nil {
+		return wlstructs.ShareInfo{}, err
+	}
+
+	return reshape.ShareToShareInfo(ctx, share, f.IsDir()), nil
+}
+
+// getShareAccessors looks up the users named by usernames, to be added to a new share.
+func getShareAccessors(ctx ctxservice.RequestContext, usernames []string) ([]*user_model.User, error) {
+	accessors := make([]*user_model.User, 0, len(usernames))
+
+	for _, un := range usernames {
+		u, err := user_model.GetUserByUsername(ctx, un)
+		if err != nil {
+			return nil, err
+		}
+
+		accessors = append(accessors, u)
+	}
+
+	return accessors, nil
+}
+
+// parseShareExpiry converts an expiry time in unix milliseconds, as sent by the client, into a time. 0 means the
+// share never expires, and is returned as the zero time.
+func parseShareExpiry(expiresMillis int64) (time.Time, error) {
+	if expiresMillis == 0 {
+		return time.Time{}, nil
+	}
+
+	expires := time.UnixMilli(expiresMillis)
+	if !expires.After(time.Now()) {
+		return time.Time{}, wlerrors.New("share expiry must be in the future")
+	}
+
+	return expires, nil
+}
@@ -0,0 +1,339 @@
+package file
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ethanrous/weblens/models/db"
+	share_model "github.com/ethanrous/weblens/models/share"
+	tus_model "github.com/ethanrous/weblens/models/tus"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/services/auth"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/ethanrous/weblens/services/tus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const tusContentType = "application/offset+octet-stream"
+
+// CreateTusUpload godoc
+//
+//	@ID	CreateTusUpload
+//
+//	@Security
+//	@Security	SessionAuth
+//
+//	@Summary	Create a resumable upload using the tus protocol
+//	@Tags		Files
+//	@Param		Tus-Resumable	header	string	true	"tus protocol version"
+//	@Param		Upload-Length	header	integer	true	"Size of the file in bytes"
+//	@Param		Upload-Metadata	header	string	true	"tus metadata, must include either fileID to upload new content to an existing file, or filename and parentID"
+//	@Param		shareID			query	string	false	"Share ID"
+//	@Success	201
+//	@Failure	400
+//	@Failure	401
+//	@Failure	409
+//	@Failure	412
+//	@Router		/upload/tus [post]
+func CreateTusUpload(ctx context_service.RequestContext) {
+	if !checkTusVersion(ctx) {
+		return
+	}
+
+	size, err := strconv.ParseInt(ctx.Header("Upload-Length"), 10, 64)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("missing or invalid Upload-Length header"))
+
+		return
+	}
+
+	metadata, err := parseTusMetadata(ctx.Header("Upload-Metadata"))
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	var upload *tus_model.Upload
+
+	var shareID string
+	if ctx.Share != nil {
+		shareID = ctx.Share.ShareID.Hex()
+	}
+
+	// An upload to an existing file replaces its content, keeping what it replaces as a past revision
+	if metadata["fileID"] != "" {
+		file, err := auth.RequireFileAccessOne(ctx, metadata["fileID"], share_model.SharePermissionEdit)
+		if err != nil {
+			return
+		}
+
+		upload, err = tus.CreateRevisionUpload(ctx, ctx.Requester.GetUsername(), shareID, file, size, metadata)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return
+		}
+	} else {
+		if metadata["filename"] == "" || metadata["parentID"] == "" {
+			ctx.Error(http.StatusBadRequest, wlerrors.New("Upload-Metadata must include either fileID, or filename and parentID"))
+
+			return
+		}
+
+		parent, err := auth.RequireFileAccessOne(ctx, metadata["parentID"], share_model.SharePermissionEdit)
+		if err != nil {
+			return
+		}
+
+		upload, err = tus.CreateUpload(ctx, ctx.Requester.GetUsername(), shareID, parent, metadata["filename"], size, metadata)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return
+		}
+	}
+
+	ctx.SetHeader("Location", "/api/v1/upload/tus/"+upload.ID.Hex())
+
+	// Empty files are complete as soon as they are created, and the creation-with-upload extension
+	// allows the first chunk to be sent along with the creation request.
+	if size == 0 || (ctx.Header("Content-Type") == tusContentType && ctx.Req.ContentLength != 0) {
+		if _, err := tus.WriteChunk(ctx, upload, 0, ctx.Req.Body); err != nil {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return
+		}
+	}
+
+	setTusUploadHeaders(ctx, upload)
+	ctx.Status(http.StatusCreated)
+}
+
+// GetTusUploadOffset godoc
+//
+//	@ID	GetTusUploadOffset
+//
+//	@Security
+//	@Security	SessionAuth
+//
+//	@Summary	Get the number of bytes received for a resumable upload
+//	@Tags		Files
+//	@Param		tusUploadID		path	string	true	"Upload ID"
+//	@Param		Tus-Resumable	header	string	true	"tus protocol version"
+//	@Success	200
+//	@Failure	404
+//	@Router		/upload/tus/{tusUploadID} [head]
+func GetTusUploadOffset(ctx context_service.RequestContext) {
+	if !checkTusVersion(ctx) {
+		return
+	}
+
+	upload, ok := getTusUpload(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.SetHeader("Cache-Control", "no-store")
+	setTusUploadHeaders(ctx, upload)
+	ctx.Status(http.StatusOK)
+}
+
+// WriteTusUploadChunk godoc
+//
+//	@ID	WriteTusUploadChunk
+//
+//	@Security
+//	@Security	SessionAuth
+//
+//	@Summary	Append a chunk of data to a resumable upload
+//	@Tags		Files
+//	@Param		tusUploadID		path	string	true	"Upload ID"
+//	@Param		Tus-Resumable	header	string	true	"tus protocol version"
+//	@Param		Upload-Offset	header	integer	true	"Offset of the chunk within the file"
+//	@Success	204
+//	@Failure	404
+//	@Failure	409
+//	@Failure	415
+//	@Failure	423
+//	@Router		/upload/tus/{tusUploadID} [patch]
+func WriteTusUploadChunk(ctx context_service.RequestContext) {
+	if !checkTusVersion(ctx) {
+		return
+	}
+
+	if ctx.Header("Content-Type") != tusContentType {
+		ctx.Error(http.StatusUnsupportedMediaType, wlerrors.Errorf("Content-Type must be %s", tusContentType))
+
+		return
+	}
+
+	offset, err := strconv.ParseInt(ctx.Header("Upload-Offset"), 10, 64)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("missing or invalid Upload-Offset header"))
+
+		return
+	}
+
+	upload, ok := getTusUpload(ctx)
+	if !ok {
+		return
+	}
+
+	// Access may have been taken away since the upload was created, and must be checked before the upload can finish
+	if !requireTusUploadAccess(ctx, upload) {
+		return
+	}
+
+	_, err = tus.WriteChunk(ctx, upload, offset, ctx.Req.Body)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	setTusUploadHeaders(ctx, upload)
+	ctx.Status(http.StatusNoContent)
+}
+
+// TerminateTusUpload godoc
+//
+//	@ID	TerminateTusUpload
+//
+//	@Security
+//	@Security	SessionAuth
+//
+//	@Summary	Cancel a resumable upload and discard any data received
+//	@Tags		Files
+//	@Param		tusUploadID		path	string	true	"Upload ID"
+//	@Param		Tus-Resumable	header	string	true	"tus protocol version"
+//	@Success	204
+//	@Failure	404
+//	@Router		/upload/tus/{tusUploadID} [delete]
+func TerminateTusUpload(ctx context_service.RequestContext) {
+	if !checkTusVersion(ctx) {
+		return
+	}
+
+	upload, ok := getTusUpload(ctx)
+	if !ok {
+		return
+	}
+
+	err := tus.TerminateUpload(ctx, upload)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.SetHeader("Tus-Resumable", tus.Version)
+	ctx.Status(http.StatusNoContent)
+}
+
+// checkTusVersion rejects requests made with a version of the tus protocol we do not speak.
+func checkTusVersion(ctx context_service.RequestContext) bool {
+	if ctx.Header("Tus-Resumable") != tus.Version {
+		ctx.SetHeader("Tus-Version", tus.Version)
+		ctx.Error(http.StatusPreconditionFailed, wlerrors.Errorf("unsupported tus version, expected %s", tus.Version))
+
+		return false
+	}
+
+	return true
+}
+
+// getTusUpload loads the upload named in the request path. Uploads belonging to other users are reported
+// as not found, so upload IDs cannot be probed.
+func getTusUpload(ctx context_service.RequestContext) (*tus_model.Upload, bool) {
+	uploadID, err := primitive.ObjectIDFromHex(ctx.Path("tusUploadID"))
+	if err != nil {
+		ctx.Error(http.StatusNotFound, wlerrors.New("upload not found"))
+
+		return nil, false
+	}
+
+	upload, err := tus_model.GetUploadByID(ctx, uploadID)
+	if err != nil {
+		if db.IsNotFound(err) {
+			ctx.Error(http.StatusNotFound, err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, err)
+		}
+
+		return nil, false
+	}
+
+	if upload.Owner != ctx.Requester.GetUsername() {
+		ctx.Error(http.StatusNotFound, wlerrors.New("upload not found"))
+
+		return nil, false
+	}
+
+	return upload, true
+}
+
+// requireTusUploadAccess checks that the requester may still edit the destination of upload, through the share the upload
+// was created with if any.
+func requireTusUploadAccess(ctx context_service.RequestContext, upload *tus_model.Upload) bool {
+	ctx.Share = nil
+
+	if upload.ShareID != "" {
+		share, err := share_model.GetShareByID(ctx, share_model.IDFromString(upload.ShareID))
+		if err != nil && !db.IsNotFound(err) {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return false
+		}
+
+		// A deleted share grants nothing, so access falls back to the requester's own
+		ctx.Share = share
+	}
+
+	targetID := upload.ParentID
+	if upload.FileID != "" {
+		targetID = upload.FileID
+	}
+
+	_, err := auth.RequireFileAccessOne(ctx, targetID, share_model.SharePermissionEdit)
+
+	return err == nil
+}
+
+func setTusUploadHeaders(ctx context_service.RequestContext, upload *tus_model.Upload) {
+	ctx.SetHeader("Tus-Resumable", tus.Version)
+	ctx.SetHeader("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	ctx.SetHeader("Upload-Length", strconv.FormatInt(upload.Size, 10))
+
+	if !upload.IsComplete() {
+		ctx.SetHeader("Upload-Expires", upload.Expires().UTC().Format(http.TimeFormat))
+	}
+}
+
+// parseTusMetadata decodes an Upload-Metadata header, a comma separated list of keys each followed by a space
+// and a base64 encoded value. The value may be omitted.
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+
+	if header == "" {
+		return metadata, nil
+	}
+
+	for pair := range strings.SplitSeq(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, wlerrors.New("invalid Upload-Metadata header")
+		}
+
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, wlerrors.Errorf("invalid Upload-Metadata value for key %s: %w", key, err)
+		}
+
+		metadata[key] = string(value)
+	}
+
+	return metadata, nil
+}
@@ -6,16 +6,21 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/ethanrous/weblens/models/auth"
+	"github.com/ethanrous/weblens/models/featureflags"
 	"github.com/ethanrous/weblens/models/history"
-	"github.com/ethanrous/weblens/models/tower"
-	"github.com/ethanrous/weblens/models/usermodel"
 	"github.com/ethanrous/weblens/modules/wlerrors"
 	"github.com/ethanrous/weblens/modules/wlfs"
 	"github.com/ethanrous/weblens/modules/wlstructs"
 	"github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/ethanrous/weblens/services/jobs"
 	"github.com/ethanrous/weblens/services/journal"
 	"github.com/ethanrous/weblens/services/reshape"
+	tower_service "github.com/ethanrous/weblens/services/tower"
+)
+
+const (
+	defaultBackupPageSize = 1000
+	maxBackupPageSize     = 10000
 )
 
 // DoFullBackup godoc
@@ -24,6 +29,8 @@ import (
 //	@Security	ApiKeyAuth[admin]
 //
 //	@Summary	Get information about a file
+//	@Description	Sends every action since timestamp in a single response. Kept for backup towers that do not yet read the
+//	@Description	paged feed from /tower/backup/metadata and /tower/backup/actions.
 //	@Tags		Towers
 //	@Produce	json
 //	@Param		timestamp	query		string					true	"Timestamp in milliseconds since epoch"
@@ -31,6 +38,7 @@ import (
 //	@Failure	400
 //	@Failure	404
 //	@Failure	500
+//	@Deprecated
 //	@Router		/tower/backup [get]
 func DoFullBackup(ctx ctxservice.RequestContext) {
 	if ctx.Remote.TowerID == "" {
@@ -64,40 +72,156 @@ func DoFullBackup(ctx ctxservice.RequestContext) {
 		return
 	}
 
-	users, err := usermodel.GetAllUsers(ctx)
+	res, err := tower_service.CollectBackupMetadata(ctx, ctx.LocalTowerID, since)
 	if err != nil {
-		ctx.Error(http.StatusInternalServerError, wlerrors.Wrap(err, "failed to get users"))
+		ctx.Error(http.StatusInternalServerError, err)
 
 		return
 	}
 
-	towers, err := tower.GetAllTowersByTowerID(ctx, ctx.LocalTowerID)
-	if err != nil {
-		ctx.Error(http.StatusInternalServerError, wlerrors.Wrap(err, "failed to get towers"))
+	res.FileHistory = make([]wlstructs.FileActionInfo, 0, len(fileActions))
+	for _, a := range fileActions {
+		res.FileHistory = append(res.FileHistory, reshape.FileActionToFileActionInfo(a, ""))
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// GetBackupMetadata godoc
+//
+//	@ID			GetBackupMetadata
+//	@Security	ApiKeyAuth[admin]
+//
+//	@Summary	Get the users, towers, tokens, shares, tags, covers and media a backup tower keeps for this tower
+//	@Tags		Towers
+//	@Produce	json
+//	@Param		since	query		string					false	"Timestamp in milliseconds since epoch of the last metadata backup. Shares, tags, covers and media are only sent if updated after it"
+//	@Success	200		{object}	wlstructs.BackupInfo	"Backup Info, without file history"
+//	@Failure	400
+//	@Failure	401
+//	@Failure	500
+//	@Router		/tower/backup/metadata [get]
+func GetBackupMetadata(ctx ctxservice.RequestContext) {
+	if ctx.Remote.TowerID == "" {
+		ctx.Error(http.StatusUnauthorized, wlerrors.New("missing tower in request context"))
 
 		return
 	}
 
-	// tokenID, err := primitive.ObjectIDFromHex(ctx.Remote.IncomingKey)
-	// if err != nil {
-	// 	ctx.Error(http.StatusBadRequest, errors.Wrap(err, "invalid token id"))
-	// 	return
-	// }
-	// auth.GetTokenByID(ctx, tokenID)
-	// TODO: Get tokens from the database
-	tokens := make([]*auth.Token, 0)
+	var since time.Time
+
+	if sinceStr := ctx.Query("since"); sinceStr != "" && sinceStr != "0" {
+		millis, err := strconv.ParseInt(sinceStr, 10, 64)
+		if err != nil || millis < 0 {
+			ctx.Error(http.StatusBadRequest, wlerrors.New("invalid since timestamp"))
 
-	res := reshape.NewBackupInfo(
-		ctx,
-		fileActions,
-		users,
-		towers,
-		tokens,
-	)
+			return
+		}
+
+		since = time.UnixMilli(millis)
+	}
+
+	res, err := tower_service.CollectBackupMetadata(ctx, ctx.LocalTowerID, since)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
 
 	ctx.JSON(http.StatusOK, res)
 }
 
+// GetBackupActions godoc
+//
+//	@ID			GetBackupActions
+//	@Security	ApiKeyAuth[admin]
+//
+//	@Summary	Get a page of the file history to back up
+//	@Tags		Towers
+//	@Produce	json
+//	@Param		cursor	query		string						false	"Cursor to read the page from, as returned by the previous page. Empty reads from the start"
+//	@Param		limit	query		int							false	"Number of actions to read"	default(1000)	maximum(10000)
+//	@Success	200		{object}	wlstructs.BackupActionsPage	"Backup Actions Page"
+//	@Failure	400
+//	@Failure	401
+//	@Failure	500
+//	@Router		/tower/backup/actions [get]
+func GetBackupActions(ctx ctxservice.RequestContext) {
+	if ctx.Remote.TowerID == "" {
+		ctx.Error(http.StatusUnauthorized, wlerrors.New("missing tower in request context"))
+
+		return
+	}
+
+	cursor, err := history.ParseActionCursor(ctx.Query("cursor"))
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	limit := defaultBackupPageSize
+
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxBackupPageSize {
+			ctx.Error(http.StatusBadRequest, wlerrors.Errorf("limit must be between 1 and %d", maxBackupPageSize))
+
+			return
+		}
+	}
+
+	actions, next, hasMore, err := history.GetActionFeedPage(ctx, cursor, limit)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	page := wlstructs.BackupActionsPage{
+		Actions:    make([]wlstructs.FileActionInfo, 0, len(actions)),
+		NextCursor: next.String(),
+		HasMore:    hasMore,
+	}
+
+	for _, a := range actions {
+		page.Actions = append(page.Actions, reshape.FileActionToFileActionInfo(a, ""))
+	}
+
+	ctx.JSON(http.StatusOK, page)
+}
+
+// GetHistoryGCReport godoc
+//
+//	@ID			GetHistoryGCReport
+//
+//	@Security	SessionAuth[admin]
+//	@Security	ApiKeyAuth[admin]
+//
+//	@Summary	Get what compacting the file history with the current retention would remove, without removing it
+//	@Tags		Towers
+//	@Produce	json
+//	@Success	200	{object}	jobs.HistoryGCReport	"History GC Report"
+//	@Failure	500
+//	@Router		/tower/history/gc [get]
+func GetHistoryGCReport(ctx ctxservice.RequestContext) {
+	flags, err := featureflags.GetFlags(ctx)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	report, err := jobs.CollectHistoryGarbage(ctx.AppContext, jobs.HistoryRetention(flags), time.Now(), true)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
+
 // GetPagedHistoryActions godoc
 //
 //	@ID			GetPagedHistoryActions
@@ -0,0 +1,487 @@
+package media
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	album_model "github.com/ethanrous/weblens/models/album"
+	"github.com/ethanrous/weblens/models/db"
+	file_model "github.com/ethanrous/weblens/models/file"
+	media_model "github.com/ethanrous/weblens/models/media"
+	"github.com/ethanrous/weblens/models/share"
+	"github.com/ethanrous/weblens/modules/netwrk"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlstructs"
+	"github.com/ethanrous/weblens/services/auth"
+	"github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/ethanrous/weblens/services/reshape"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetAlbums godoc
+//
+//	@ID			GetAlbums
+//
+//	@Security	SessionAuth
+//
+//	@Summary	Get the albums of the user, and the albums shared with them
+//	@Tags		Albums
+//	@Produce	json
+//	@Success	200	{array}	wlstructs.AlbumInfo	"Albums"
+//	@Failure	401
+//	@Failure	500
+//	@Router		/albums [get]
+func GetAlbums(ctx ctxservice.RequestContext) {
+	owned, err := album_model.GetAlbumsByOwner(ctx, ctx.Requester.GetUsername())
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	shares, err := share.GetSharedWithUser(ctx, ctx.Requester.GetUsername())
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	sharesByAlbum := make(map[string]*share.FileShare)
+	sharedAlbumIDs := make([]primitive.ObjectID, 0, len(shares))
+
+	for i := range shares {
+		s := &shares[i]
+		if !s.IsAlbumShare() || !s.IsEnabled() || s.IsExpired() {
+			continue
+		}
+
+		albumID, err := primitive.ObjectIDFromHex(s.AlbumID)
+		if err != nil {
+			continue
+		}
+
+		sharesByAlbum[s.AlbumID] = s
+		sharedAlbumIDs = append(sharedAlbumIDs, albumID)
+	}
+
+	shared, err := album_model.GetAlbumsByIDs(ctx, sharedAlbumIDs)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	albumInfos := make([]wlstructs.AlbumInfo, 0, len(owned)+len(shared))
+
+	for _, a := range owned {
+		albumShare, err := share.GetShareByAlbumID(ctx, a.ID())
+		if err != nil && !db.IsNotFound(err) {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return
+		}
+
+		albumInfos = append(albumInfos, reshape.AlbumToAlbumInfo(a, albumShare))
+	}
+
+	for _, a := range shared {
+		albumInfos = append(albumInfos, reshape.AlbumToAlbumInfo(a, sharesByAlbum[a.ID()]))
+	}
+
+	ctx.JSON(http.StatusOK, albumInfos)
+}
+
+// CreateAlbum godoc
+//
+//	@ID			CreateAlbum
+//
+//	@Security	SessionAuth
+//
+//	@Summary	Create a new album
+//	@Tags		Albums
+//	@Accept		json
+//	@Produce	json
+//	@Param		request	body		wlstructs.CreateAlbumParams	true	"Create album request body"
+//	@Success	201		{object}	wlstructs.AlbumInfo			"Created album"
+//	@Failure	400
+//	@Failure	401
+//	@Failure	500
+//	@Router		/albums [post]
+func CreateAlbum(ctx ctxservice.RequestContext) {
+	params, err := netwrk.ReadRequestBody[wlstructs.CreateAlbumParams](ctx.Req)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	title := strings.TrimSpace(params.Title)
+	if title == "" {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("album title is required"))
+
+		return
+	}
+
+	a, err := album_model.CreateAlbum(ctx, title, params.Description, ctx.Requester.GetUsername())
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, reshape.AlbumToAlbumInfo(a, nil))
+}
+
+// GetAlbum godoc
+//
+//	@ID			GetAlbum
+//
+//	@Summary	Get an album
+//	@Tags		Albums
+//	@Produce	json
+//	@Param		albumID	path		string				true	"Album ID"
+//	@Param		shareID	query		string				false	"Share ID"
+//	@Success	200		{object}	wlstructs.AlbumInfo	"Album"
+//	@Failure	401
+//	@Failure	403
+//	@Failure	404
+//	@Router		/albums/{albumID} [get]
+func GetAlbum(ctx ctxservice.RequestContext) {
+	a, ok := getAlbum(ctx, share.SharePermissionViewMedia)
+	if !ok {
+		return
+	}
+
+	albumShare := ctx.Share
+
+	if a.Owner == ctx.Requester.GetUsername() {
+		var err error
+
+		albumShare, err = share.GetShareByAlbumID(ctx, a.ID())
+		if err != nil && !db.IsNotFound(err) {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, reshape.AlbumToAlbumInfo(a, albumShare))
+}
+
+// GetAlbumMedia godoc
+//
+//	@ID			GetAlbumMedia
+//
+//	@Summary	Get the media in an album, in album order
+//	@Tags		Albums
+//	@Produce	json
+//	@Param		albumID	path		string						true	"Album ID"
+//	@Param		shareID	query		string						false	"Share ID"
+//	@Success	200		{object}	wlstructs.MediaBatchInfo	"Album media"
+//	@Failure	401
+//	@Failure	403
+//	@Failure	404
+//	@Router		/albums/{albumID}/media [get]
+func GetAlbumMedia(ctx ctxservice.RequestContext) {
+	a, ok := getAlbum(ctx, share.SharePermissionViewMedia)
+	if !ok {
+		return
+	}
+
+	medias, err := media_model.GetMediasByContentIDs(ctx, a.Medias...)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	mediaByID := make(map[string]*media_model.Media, len(medias))
+	for _, m := range medias {
+		mediaByID[m.ID()] = m
+	}
+
+	isOwner := a.Owner == ctx.Requester.GetUsername()
+	ordered := make([]*media_model.Media, 0, len(a.Medias))
+
+	for _, contentID := range a.Medias {
+		m, ok := mediaByID[contentID]
+		if !ok {
+			continue
+		}
+
+		// An album share only grants access to files of the album owner, so other media could not be viewed anyway
+		if !isOwner && !hasOwnerFile(ctx, m, a.Owner) {
+			continue
+		}
+
+		ordered = append(ordered, m)
+	}
+
+	batch := reshape.NewMediaBatchInfo(ordered)
+	batch.TotalMediaCount = len(ordered)
+	ctx.JSON(http.StatusOK, batch)
+}
+
+// UpdateAlbum godoc
+//
+//	@ID			UpdateAlbum
+//
+//	@Security	SessionAuth
+//
+//	@Summary	Update the details and media of an album
+//	@Tags		Albums
+//	@Accept		json
+//	@Produce	json
+//	@Param		albumID	path		string						true	"Album ID"
+//	@Param		request	body		wlstructs.UpdateAlbumParams	true	"Update album request body"
+//	@Success	200		{object}	wlstructs.AlbumInfo			"Updated album"
+//	@Failure	400
+//	@Failure	403
+//	@Failure	404
+//	@Failure	500
+//	@Router		/albums/{albumID} [patch]
+func UpdateAlbum(ctx ctxservice.RequestContext) {
+	a, ok := getOwnedAlbum(ctx)
+	if !ok {
+		return
+	}
+
+	params, err := netwrk.ReadRequestBody[wlstructs.UpdateAlbumParams](ctx.Req)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	if strings.TrimSpace(params.NewTitle) != "" || params.NewDescription != nil {
+		title := a.Title
+		if newTitle := strings.TrimSpace(params.NewTitle); newTitle != "" {
+			title = newTitle
+		}
+
+		description := a.Description
+		if params.NewDescription != nil {
+			description = *params.NewDescription
+		}
+
+		err = a.SetInfo(ctx, title, description)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return
+		}
+	}
+
+	newMedia := make([]string, 0, len(params.AddMedia))
+
+	for _, contentID := range params.AddMedia {
+		m, err := media_model.GetMediaByContentID(ctx, contentID)
+		if err != nil {
+			ctx.Error(http.StatusNotFound, err)
+
+			return
+		}
+
+		if _, err := auth.RequireAnyFileAccess(ctx, m.GetFiles(), share.SharePermissionViewMedia); err != nil {
+			return
+		}
+
+		newMedia = append(newMedia, m.ID())
+	}
+
+	for _, folderID := range params.AddFolders {
+		folder, err := auth.RequireFileAccessOne(ctx, folderID, share.SharePermissionViewMedia)
+		if err != nil {
+			return
+		}
+
+		folderMedia, err := getFolderMediaIDs(ctx, folder)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return
+		}
+
+		newMedia = append(newMedia, folderMedia...)
+	}
+
+	err = a.AddMedias(ctx, newMedia)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	if len(params.RemoveMedia) != 0 {
+		err = a.RemoveMedias(ctx, params.RemoveMedia)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return
+		}
+	}
+
+	if params.MediaOrder != nil {
+		err = a.SetOrder(ctx, params.MediaOrder)
+		if err != nil {
+			ctx.Error(http.StatusBadRequest, err)
+
+			return
+		}
+	}
+
+	if params.Cover != "" {
+		err = a.SetCover(ctx, params.Cover)
+		if err != nil {
+			ctx.Error(http.StatusBadRequest, err)
+
+			return
+		}
+	}
+
+	albumShare, err := share.GetShareByAlbumID(ctx, a.ID())
+	if err != nil && !db.IsNotFound(err) {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reshape.AlbumToAlbumInfo(a, albumShare))
+}
+
+// DeleteAlbum godoc
+//
+//	@ID			DeleteAlbum
+//
+//	@Security	SessionAuth
+//
+//	@Summary	Delete an album, and its share. The media in the album are not affected
+//	@Tags		Albums
+//	@Param		albumID	path	string	true	"Album ID"
+//	@Success	200
+//	@Failure	403
+//	@Failure	404
+//	@Failure	500
+//	@Router		/albums/{albumID} [delete]
+func DeleteAlbum(ctx ctxservice.RequestContext) {
+	a, ok := getOwnedAlbum(ctx)
+	if !ok {
+		return
+	}
+
+	albumShare, err := share.GetShareByAlbumID(ctx, a.ID())
+	if err == nil {
+		err = share.DeleteShare(ctx, albumShare.ShareID)
+	} else if db.IsNotFound(err) {
+		err = nil
+	}
+
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	err = album_model.DeleteAlbum(ctx, a.AlbumID)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// getAlbum loads the album named in the request path, writing an error response if it cannot be loaded, or the
+// requester cannot access it with perms.
+func getAlbum(ctx ctxservice.RequestContext, perms ...share.Permission) (*album_model.Album, bool) {
+	albumID, err := primitive.ObjectIDFromHex(ctx.Path("albumID"))
+	if err != nil {
+		ctx.Error(http.StatusNotFound, wlerrors.New("album not found"))
+
+		return nil, false
+	}
+
+	a, err := album_model.GetAlbumByID(ctx, albumID)
+	if err != nil {
+		if db.IsNotFound(err) {
+			ctx.Error(http.StatusNotFound, err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, err)
+		}
+
+		return nil, false
+	}
+
+	_, err = auth.CanUserAccessAlbum(ctx, ctx.Requester, a, ctx.Share, perms...)
+	if err != nil {
+		ctx.Error(http.StatusForbidden, err)
+
+		return nil, false
+	}
+
+	return a, true
+}
+
+// getOwnedAlbum loads the album named in the request path, writing an error response if the requester does not own it.
+// Albums can be viewed through a share, but only their owner can change them.
+func getOwnedAlbum(ctx ctxservice.RequestContext) (*album_model.Album, bool) {
+	a, ok := getAlbum(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	if a.Owner != ctx.Requester.GetUsername() {
+		ctx.Error(http.StatusForbidden, wlerrors.New("only the owner of an album can change it"))
+
+		return nil, false
+	}
+
+	return a, true
+}
+
+// getFolderMediaIDs returns the content IDs of the media of every file inside of folder, oldest first.
+func getFolderMediaIDs(ctx ctxservice.RequestContext, folder *file_model.WeblensFileImpl) ([]string, error) {
+	contentIDs, err := collectContentIDs(ctx, folder)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(contentIDs) == 0 {
+		return nil, nil
+	}
+
+	medias, err := media_model.GetMediasByContentIDs(ctx, contentIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(medias, func(a, b *media_model.Media) int {
+		return a.GetCreateDate().Compare(b.GetCreateDate())
+	})
+
+	mediaIDs := make([]string, 0, len(medias))
+	for _, m := range medias {
+		mediaIDs = append(mediaIDs, m.ID())
+	}
+
+	return mediaIDs, nil
+}
+
+// hasOwnerFile returns true if m is the media of at least one file of owner that is not in the trash.
+func hasOwnerFile(ctx ctxservice.RequestContext, m *media_model.Media, owner string) bool {
+	for _, fileID := range m.GetFiles() {
+		f, err := ctx.FileService.GetFileByID(ctx, fileID)
+		if err != nil || file_model.IsFileInTrash(f) {
+			continue
+		}
+
+		ownerName, err := file_model.GetFileOwnerName(ctx, f)
+		if err == nil && ownerName == owner {
+			return true
+		}
+	}
+
+	return false
+}
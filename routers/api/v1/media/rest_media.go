@@ -5,6 +5,7 @@ import (
 	"io"
 	"mime"
 	"net/http"
+	"path/filepath"
 	"slices"
 	"strconv"
 	"time"
@@ -46,6 +47,10 @@ const (
 //	@Param		limit			query		int							false	"Page size"
 //	@Param		folderIDs		query		[]string					false	"Folder IDs to filter by"
 //	@Param		mediaIDs		query		[]string					false	"Media IDs to fetch"
+//	@Param		lat				query		number						false	"Latitude of the center of the area to filter by"
+//	@Param		lng				query		number						false	"Longitude of the center of the area to filter by"
+//	@Param		radius			query		number						false	"Radius in meters of the area to filter by"
+//	@Param		polygon			query		[]number					false	"Alternating latitudes and longitudes of the polygon to filter by"
 //	@Success	200				{object}	wlstructs.MediaBatchInfo	"Media Batch"
 //	@Success	400
 //	@Success	500
@@ -96,8 +101,15 @@ func GetMediaBatch(ctx ctxservice.RequestContext) {
 		return
 	}
 
+	geo, err := parseGeoFilter(ctx)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
 	if len(folderIDs) != 0 {
-		getMediaByFolders(ctx, folderIDs, search, int(sortDirection), int(page), int(limit), raw)
+		getMediaByFolders(ctx, folderIDs, search, geo, int(sortDirection), int(page), int(limit), raw)
 
 		return
 	}
@@ -116,10 +128,10 @@ func GetMediaBatch(ctx ctxservice.RequestContext) {
 		return
 	}
 
-	getMediaPaginated(ctx, sort, raw, hidden, int(page), int(limit))
+	getMediaPaginated(ctx, sort, geo, raw, hidden, int(page), int(limit))
 }
 
-func getMediaByFolders(ctx ctxservice.RequestContext, folderIDs []string, search string, sortDirection, page, limit int, raw bool) {
+func getMediaByFolders(ctx ctxservice.RequestContext, folderIDs []string, search string, geo media_model.GeoFilter, sortDirection, page, limit int, raw bool) {
 	if _, err := auth.RequireFileAccess(ctx, folderIDs, share.SharePermissionViewMedia); err != nil {
 		return
 	}
@@ -133,7 +145,7 @@ func getMediaByFolders(ctx ctxservice.RequestContext, folderIDs []string, search
 		limit = maxSearchResults
 	}
 
-	media, totalMediaCount, err := getMediaInFolders(ctx, folderIDs, limit, page, sortDirection, raw)
+	media, totalMediaCount, err := getMediaInFolders(ctx, folderIDs, geo, limit, page, sortDirection, raw)
 	if err != nil {
 		ctx.Error(http.StatusInternalServerError, err)
 
@@ -207,10 +219,10 @@ func getMediaByIDs(ctx ctxservice.RequestContext, mediaIDs []string) {
 	ctx.JSON(http.StatusOK, batch)
 }
 
-func getMediaPaginated(ctx ctxservice.RequestContext, sort string, raw, hidden bool, page, limit int) {
+func getMediaPaginated(ctx ctxservice.RequestContext, sort string, geo media_model.GeoFilter, raw, hidden bool, page, limit int) {
 	var mediaFilter []media_model.ContentID
 
-	ms, err := media_model.GetMedia(ctx, ctx.Requester.Username, sort, 1, mediaFilter, raw, hidden)
+	ms, err := media_model.GetMediaInArea(ctx, geo, ctx.Requester.Username, sort, 1, mediaFilter, raw, hidden)
 	if err != nil {
 		ctx.Error(http.StatusInternalServerError, err)
 
@@ -261,6 +273,103 @@ func GetMediaTypes(ctx ctxservice.RequestContext) {
 	ctx.JSON(http.StatusOK, typesInfo)
 }
 
+// GetMediaMap godoc
+//
+//	@ID			GetMediaMap
+//
+//	@Summary	Get clustered media counts for an area of a map
+//	@Tags		Media
+//	@Produce	json
+//	@Param		shareID		query		string					false	"File ShareID"
+//	@Param		south		query		number					true	"Southern latitude of the map bounds"
+//	@Param		west		query		number					true	"Western longitude of the map bounds"
+//	@Param		north		query		number					true	"Northern latitude of the map bounds"
+//	@Param		east		query		number					true	"Eastern longitude of the map bounds"
+//	@Param		zoom		query		int						false	"Map zoom level, from 0 to 22"
+//	@Param		raw			query		bool					false	"Include raw media"		Enums(true, false)
+//	@Param		hidden		query		bool					false	"Include hidden media"	Enums(true, false)
+//	@Param		folderIDs	query		[]string				false	"Folder IDs to limit the map to"
+//	@Success	200			{object}	wlstructs.MediaMapInfo	"Media clusters"
+//	@Success	400
+//	@Success	401
+//	@Success	500
+//	@Router		/media/map [get]
+func GetMediaMap(ctx ctxservice.RequestContext) {
+	opts := media_model.MediaClusterOptions{
+		IncludeRaw: ctx.QueryBool("raw"),
+	}
+
+	hidden := ctx.QueryBool("hidden")
+
+	bounds := []*float64{&opts.Bounds.South, &opts.Bounds.West, &opts.Bounds.North, &opts.Bounds.East}
+	for i, name := range []string{"south", "west", "north", "east"} {
+		if ctx.Query(name) == "" {
+			ctx.Error(http.StatusBadRequest, wlerrors.Errorf("%w: missing [%s] bound", media_model.ErrInvalidGeoFilter, name))
+
+			return
+		}
+
+		bound, err := ctx.QueryFloat(name)
+		if err != nil {
+			ctx.Error(http.StatusBadRequest, err)
+
+			return
+		}
+
+		*bounds[i] = bound
+	}
+
+	zoom, err := ctx.QueryInt("zoom")
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	} else if zoom < 0 || zoom > media_model.MaxMapZoom {
+		ctx.Error(http.StatusBadRequest, wlerrors.Errorf("Zoom must be between 0 and %d", media_model.MaxMapZoom))
+
+		return
+	}
+
+	opts.Zoom = int(zoom)
+
+	folderIDs := ctx.QueryArray("folderIDs")
+	if len(folderIDs) != 0 {
+		if _, err := auth.RequireFileAccess(ctx, folderIDs, share.SharePermissionViewMedia); err != nil {
+			return
+		}
+
+		opts.ContentIDs, err = collectFolderContentIDs(ctx, folderIDs)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return
+		}
+
+		// Folders may be shared, and media their owner has hidden stay hidden from everyone else
+		if hidden && ctx.IsLoggedIn {
+			opts.IncludeHidden = true
+			opts.HiddenOwner = ctx.Requester.Username
+		}
+	} else if !ctx.IsLoggedIn {
+		// Without folders, the map shows the requester's own media, which needs them to be logged in
+		ctx.Error(http.StatusUnauthorized, wlerrors.New("authentication required"))
+
+		return
+	} else {
+		opts.Owner = ctx.Requester.Username
+		opts.IncludeHidden = hidden
+	}
+
+	clusters, err := media_model.GetMediaClusters(ctx, opts)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reshape.NewMediaMapInfo(clusters))
+}
+
 // CleanupMedia godoc
 //
 //	@ID			CleanupMedia
@@ -431,7 +540,7 @@ func streamVideo(ctx ctxservice.RequestContext) {
 	}
 
 	if chunkName != "" {
-		ctx.SetContentType(mime.TypeByExtension(".mp4"))
+		ctx.SetContentType(mime.TypeByExtension(filepath.Ext(chunkName)))
 
 		// Set headers to ensure caching, but require revalidation
 		ctx.W.Header().Add("Cache-Control", "no-cache")
@@ -456,7 +565,11 @@ func streamVideo(ctx ctxservice.RequestContext) {
 		}
 
 		chunkFile, err := streamer.GetChunk(chunkName)
-		if err != nil {
+		if wlerrors.Is(err, media_model.ErrChunkNotFound) {
+			ctx.Error(http.StatusNotFound, err)
+
+			return
+		} else if err != nil {
 			ctx.Error(http.StatusInternalServerError, err)
 
 			return
@@ -472,7 +585,7 @@ func streamVideo(ctx ctxservice.RequestContext) {
 		return
 	}
 
-	listFile, modified, err := streamer.GetListFile()
+	listFile, modified, err := streamer.GetMasterPlaylist()
 	if err != nil {
 		ctx.Error(http.StatusInternalServerError, err)
 
@@ -671,29 +784,95 @@ func GetRandomMedia(ctx ctxservice.RequestContext) {
 }
 
 // Helper function
-func getMediaInFolders(ctx ctxservice.RequestContext, folderIDs []string, limit, page, sortDirection int, includeRaw bool) ([]*media_model.Media, int, error) {
+func getMediaInFolders(ctx ctxservice.RequestContext, folderIDs []string, geo media_model.GeoFilter, limit, page, sortDirection int, includeRaw bool) ([]*media_model.Media, int, error) {
+	allContentIDs, err := collectFolderContentIDs(ctx, folderIDs)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	medias, err := media_model.GetPagedMediasInArea(ctx, geo, limit, page, sortDirection, includeRaw, allContentIDs...)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	if geo.IsEmpty() {
+		return medias, len(allContentIDs), nil
+	}
+
+	// Most of the files in the folders may be outside the area, so they have to be counted
+	totalMediaCount, err := media_model.CountMediasInArea(ctx, geo, includeRaw, allContentIDs...)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	return medias, totalMediaCount, nil
+}
+
+// collectFolderContentIDs collects the content IDs of the files in each folder tree with collectContentIDs.
+func collectFolderContentIDs(ctx ctxservice.RequestContext, folderIDs []string) ([]string, error) {
 	allContentIDs := []string{}
 
 	for _, folderID := range folderIDs {
 		folder, err := ctx.FileService.GetFileByID(ctx, folderID)
 		if err != nil {
-			return nil, -1, err
+			return nil, err
 		}
 
 		contentIDs, err := collectContentIDs(ctx, folder)
 		if err != nil {
-			return nil, -1, err
+			return nil, err
 		}
 
 		allContentIDs = append(allContentIDs, contentIDs...)
 	}
 
-	medias, err := media_model.GetPagedMedias(ctx, limit, page, sortDirection, includeRaw, allContentIDs...)
+	return allContentIDs, nil
+}
+
+// parseGeoFilter reads the area media are filtered by from the query, either a radius around a point or a polygon.
+func parseGeoFilter(ctx ctxservice.RequestContext) (media_model.GeoFilter, error) {
+	geo := media_model.GeoFilter{}
+
+	radius, err := ctx.QueryFloat("radius")
 	if err != nil {
-		return nil, -1, err
+		return geo, err
+	}
+
+	if radius != 0 {
+		lat, err := ctx.QueryFloat("lat")
+		if err != nil {
+			return geo, err
+		}
+
+		lng, err := ctx.QueryFloat("lng")
+		if err != nil {
+			return geo, err
+		}
+
+		geo.Center = [2]float64{lat, lng}
+		geo.RadiusMeters = radius
+	}
+
+	polygon := ctx.QueryArray("polygon")
+	if len(polygon)%2 != 0 {
+		return geo, wlerrors.Errorf("%w: polygon must be pairs of latitude and longitude", media_model.ErrInvalidGeoFilter)
+	}
+
+	for i := 0; i < len(polygon); i += 2 {
+		lat, err := strconv.ParseFloat(polygon[i], 64)
+		if err != nil {
+			return geo, wlerrors.Errorf("%w: invalid polygon latitude [%s]", media_model.ErrInvalidGeoFilter, polygon[i])
+		}
+
+		lng, err := strconv.ParseFloat(polygon[i+1], 64)
+		if err != nil {
+			return geo, wlerrors.Errorf("%w: invalid polygon longitude [%s]", media_model.ErrInvalidGeoFilter, polygon[i+1])
+		}
+
+		geo.Polygon = append(geo.Polygon, [2]float64{lat, lng})
 	}
 
-	return medias, len(allContentIDs), nil
+	return geo, geo.Validate()
 }
 
 // collectContentIDs recursively walks a folder tree, loading children and
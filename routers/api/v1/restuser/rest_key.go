@@ -3,11 +3,13 @@ package restuser
 
 import (
 	"net/http"
+	"time"
 
 	auth_model "github.com/ethanrous/weblens/models/auth"
 	"github.com/ethanrous/weblens/modules/netwrk"
 	"github.com/ethanrous/weblens/modules/wlerrors"
 	"github.com/ethanrous/weblens/modules/wlstructs"
+	access_service "github.com/ethanrous/weblens/services/auth"
 	"github.com/ethanrous/weblens/services/ctxservice"
 	"github.com/ethanrous/weblens/services/reshape"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -19,14 +21,16 @@ import (
 //
 //	@Security	SessionAuth
 //
-//	@Summary	Create a new api key
+//	@Summary	Create a new api key, optionally limited to some scopes, to one folder, or until an expiry
 //	@Tags		APIKeys
 //	@Produce	json
 //
 //	@Param		params	body		wlstructs.APIKeyParams	true	"The new token params"
 //
 //	@Success	200		{object}	wlstructs.TokenInfo		"The new token"
+//	@Failure	400
 //	@Failure	403
+//	@Failure	404
 //	@Failure	500
 //	@Router		/keys [post]
 func CreateAPIKey(ctx ctxservice.RequestContext) {
@@ -35,7 +39,42 @@ func CreateAPIKey(ctx ctxservice.RequestContext) {
 		return
 	}
 
-	token, err := auth_model.GenerateNewToken(ctx, tokenParams.Name, ctx.Requester.Username, ctx.LocalTowerID)
+	scopes, err := auth_model.ParseScopes(tokenParams.Scopes)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	restrictions := auth_model.TokenRestrictions{Scopes: scopes}
+
+	if tokenParams.Expires != 0 {
+		restrictions.Expires = time.UnixMilli(tokenParams.Expires)
+		if restrictions.Expires.Before(time.Now()) {
+			ctx.Error(http.StatusBadRequest, wlerrors.New("api key expiry must be in the future"))
+
+			return
+		}
+	}
+
+	if tokenParams.FolderID != "" {
+		folder, err := access_service.CanUserAccessFileByID(ctx, tokenParams.FolderID)
+		if err != nil {
+			ctx.Error(http.StatusNotFound, err)
+
+			return
+		}
+
+		if !folder.IsDir() {
+			ctx.Error(http.StatusBadRequest, wlerrors.New("api keys can only be limited to a folder"))
+
+			return
+		}
+
+		restrictions.FolderID = folder.ID()
+	}
+
+	token, err := auth_model.GenerateNewRestrictedToken(ctx, tokenParams.Name, ctx.Requester.Username, ctx.LocalTowerID, restrictions)
 	if err != nil {
 		ctx.Error(http.StatusInternalServerError, err)
 
@@ -56,7 +95,7 @@ func CreateAPIKey(ctx ctxservice.RequestContext) {
 //	@Tags		APIKeys
 //	@Produce	json
 //
-//	@Success	200	{array}	wlstructs.TokenInfo	"Tokens"
+//	@Success	200	{array}	wlstructs.TokenInfo	"Tokens, with their scopes and expiry"
 //	@Failure	403
 //	@Failure	500
 //	@Router		/keys [get]
@@ -0,0 +1,65 @@
+package restuser
+
+import (
+	"net/http"
+
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	access_service "github.com/ethanrous/weblens/services/auth"
+	"github.com/ethanrous/weblens/services/ctxservice"
+)
+
+// LoginOIDC godoc
+//
+//	@ID			LoginUserOIDC
+//
+//	@Summary	Start logging in through the single sign-on provider. The user is redirected to the provider, which sends them back to the OIDC callback
+//	@Tags		Users
+//	@Param		redirect	query	string	false	"Path to send the user to once they are logged in"
+//	@Success	302
+//	@Failure	404
+//	@Failure	500
+//	@Router		/users/auth/oidc/login [get]
+func LoginOIDC(ctx ctxservice.RequestContext) {
+	authURL, err := access_service.BeginOIDCLogin(ctx, ctx.Query("redirect"))
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	http.Redirect(ctx.W, ctx.Req, authURL, http.StatusFound)
+}
+
+// OIDCCallback godoc
+//
+//	@ID			OIDCCallback
+//
+//	@Summary	Finish logging in through the single sign-on provider, which sends the user here with an authorization code. The user is given a session, and redirected to where they started
+//	@Tags		Users
+//	@Param		state	query	string	true	"State the login was started with"
+//	@Param		code	query	string	true	"Authorization code from the provider"
+//	@Success	302
+//	@Failure	400
+//	@Failure	401
+//	@Failure	403
+//	@Failure	404
+//	@Failure	500
+//	@Router		/users/auth/oidc/callback [get]
+func OIDCCallback(ctx ctxservice.RequestContext) {
+	if providerErr := ctx.Query("error"); providerErr != "" {
+		ctx.Error(http.StatusUnauthorized, wlerrors.Errorf("single sign-on provider refused login: %s %s", providerErr, ctx.Query("error_description")))
+
+		return
+	}
+
+	u, redirect, err := access_service.FinishOIDCLogin(ctx, ctx.Query("state"), ctx.Query("code"))
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.Log().Debug().Msgf("User [%s] logged in through single sign-on", u.GetUsername())
+
+	http.Redirect(ctx.W, ctx.Req, redirect, http.StatusFound)
+}
@@ -0,0 +1,117 @@
+package restuser
+
+import (
+	"net/http"
+
+	auth_model "github.com/ethanrous/weblens/models/auth"
+	user_model "github.com/ethanrous/weblens/models/usermodel"
+	"github.com/ethanrous/weblens/modules/wlstructs"
+	access_service "github.com/ethanrous/weblens/services/auth"
+	"github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/ethanrous/weblens/services/reshape"
+)
+
+// GetSessions godoc
+//
+//	@ID			GetSessions
+//
+//	@Security	SessionAuth
+//	@Security	ApiKeyAuth
+//
+//	@Summary	Get the login sessions of the logged-in user
+//	@Tags		Users
+//	@Produce	json
+//	@Success	200	{array}	wlstructs.SessionInfo	"Sessions of the user, most recently used first"
+//	@Failure	401
+//	@Failure	500
+//	@Router		/users/sessions [get]
+func GetSessions(ctx ctxservice.RequestContext) {
+	sessions, err := auth_model.GetSessionsByUser(ctx, ctx.Requester.GetUsername())
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	currentSessionID := access_service.GetRequestSessionID(ctx)
+
+	sessionInfos := make([]wlstructs.SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		sessionInfos = append(sessionInfos, reshape.SessionToSessionInfo(ctx, session, currentSessionID))
+	}
+
+	ctx.JSON(http.StatusOK, sessionInfos)
+}
+
+// RevokeSession godoc
+//
+//	@ID			RevokeSession
+//
+//	@Security	SessionAuth
+//	@Security	ApiKeyAuth
+//
+//	@Summary	Revoke a login session of the logged-in user, signing out the device using it
+//	@Tags		Users
+//	@Param		sessionID	path	string	true	"Session ID"
+//	@Success	200
+//	@Failure	401
+//	@Failure	404
+//	@Failure	500
+//	@Router		/users/sessions/{sessionID} [delete]
+func RevokeSession(ctx ctxservice.RequestContext) {
+	err := auth_model.DeleteSession(ctx, ctx.Requester.GetUsername(), ctx.Path("sessionID"))
+	if err != nil {
+		ctx.Error(http.StatusNotFound, err)
+
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// RevokeUserSessions godoc
+//
+//	@ID			RevokeUserSessions
+//
+//	@Security	SessionAuth
+//	@Security	ApiKeyAuth
+//
+//	@Summary	Revoke every login session of a user. Users may revoke their own sessions, which keeps the session making the request. Admins may revoke the sessions of anyone
+//	@Tags		Users
+//	@Produce	json
+//	@Param		username	path		string							true	"Username of user whose sessions to revoke"
+//	@Success	200			{object}	wlstructs.RevokedSessionsInfo	"Number of revoked sessions"
+//	@Failure	401
+//	@Failure	403
+//	@Failure	404
+//	@Failure	500
+//	@Router		/users/{username}/sessions [delete]
+func RevokeUserSessions(ctx ctxservice.RequestContext) {
+	u, err := user_model.GetUserByUsername(ctx, ctx.Path("username"))
+	if err != nil {
+		ctx.Status(http.StatusNotFound)
+
+		return
+	}
+
+	var keep []string
+
+	if u.GetUsername() == ctx.Requester.GetUsername() {
+		if currentSessionID := access_service.GetRequestSessionID(ctx); currentSessionID != "" {
+			keep = append(keep, currentSessionID)
+		}
+	} else if !ctx.Requester.IsAdmin() {
+		ctx.Status(http.StatusForbidden)
+
+		return
+	}
+
+	revoked, err := auth_model.DeleteSessionsByUser(ctx, u.GetUsername(), keep...)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wlstructs.RevokedSessionsInfo{Revoked: revoked})
+}
@@ -0,0 +1,259 @@
+package restuser
+
+import (
+	"net/http"
+
+	user_model "github.com/ethanrous/weblens/models/usermodel"
+	"github.com/ethanrous/weblens/modules/cryptography"
+	"github.com/ethanrous/weblens/modules/netwrk"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlstructs"
+	access_service "github.com/ethanrous/weblens/services/auth"
+	"github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/ethanrous/weblens/services/reshape"
+)
+
+// LoginTOTP godoc
+//
+//	@ID			LoginUserTOTP
+//
+//	@Summary	Finish logging in with a TOTP code or a recovery code. Users who are required to set up TOTP confirm their authenticator app here, and are given their recovery codes
+//	@Tags		Users
+//	@Produce	json
+//	@Param		loginParams	body		wlstructs.TOTPLoginParams	true	"TOTP login params"
+//	@Success	200			{object}	wlstructs.TOTPLoginInfo		"Logged-in users info"
+//	@Failure	400
+//	@Failure	401
+//	@Router		/users/auth/totp [post]
+func LoginTOTP(ctx ctxservice.RequestContext) {
+	params, err := netwrk.ReadRequestBody[wlstructs.TOTPLoginParams](ctx.Req)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	u, err := getLoginChallengeUser(ctx, params.LoginToken)
+	if err != nil {
+		ctx.Error(http.StatusUnauthorized, err)
+
+		return
+	}
+
+	var recoveryCodes []string
+
+	if u.HasTOTP() {
+		err = access_service.VerifySecondFactor(ctx, u, params.Code, params.RecoveryCode)
+	} else {
+		recoveryCodes, err = access_service.ConfirmTOTPEnrollment(ctx, u, params.Code)
+	}
+
+	if err != nil {
+		ctx.Error(http.StatusUnauthorized, err)
+
+		return
+	}
+
+	ctx.Requester = u
+
+	err = access_service.SetSessionToken(ctx)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wlstructs.TOTPLoginInfo{User: reshape.UserToUserInfo(ctx, u), RecoveryCodes: recoveryCodes})
+}
+
+// SetupLoginTOTP godoc
+//
+//	@ID			SetupLoginTOTP
+//
+//	@Summary	Start setting up TOTP while logging in, for users who may not log in without it
+//	@Tags		Users
+//	@Produce	json
+//	@Param		loginParams	body		wlstructs.LoginTokenParams	true	"Login token params"
+//	@Success	200			{object}	wlstructs.TOTPSetupInfo		"New TOTP secret"
+//	@Failure	400
+//	@Failure	401
+//	@Failure	409
+//	@Router		/users/auth/totp/setup [post]
+func SetupLoginTOTP(ctx ctxservice.RequestContext) {
+	params, err := netwrk.ReadRequestBody[wlstructs.LoginTokenParams](ctx.Req)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	u, err := getLoginChallengeUser(ctx, params.LoginToken)
+	if err != nil {
+		ctx.Error(http.StatusUnauthorized, err)
+
+		return
+	}
+
+	required, err := access_service.IsTOTPRequired(ctx, u)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	if !required {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("two-factor authentication can only be set up after logging in"))
+
+		return
+	}
+
+	secret, uri, err := access_service.BeginTOTPEnrollment(ctx, u)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wlstructs.TOTPSetupInfo{Secret: secret, ProvisioningURI: uri})
+}
+
+// BeginTOTPSetup godoc
+//
+//	@ID			BeginTOTPSetup
+//
+//	@Security	SessionAuth
+//
+//	@Summary	Generate a new TOTP secret for the logged-in user. It is not required at login until it is confirmed
+//	@Tags		Users
+//	@Produce	json
+//	@Success	200	{object}	wlstructs.TOTPSetupInfo	"New TOTP secret"
+//	@Failure	401
+//	@Failure	409
+//	@Router		/users/totp [post]
+func BeginTOTPSetup(ctx ctxservice.RequestContext) {
+	secret, uri, err := access_service.BeginTOTPEnrollment(ctx, ctx.Requester)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wlstructs.TOTPSetupInfo{Secret: secret, ProvisioningURI: uri})
+}
+
+// ConfirmTOTPSetup godoc
+//
+//	@ID			ConfirmTOTPSetup
+//
+//	@Security	SessionAuth
+//
+//	@Summary	Enable TOTP for the logged-in user by entering a code from their authenticator app
+//	@Tags		Users
+//	@Produce	json
+//	@Param		params	body		wlstructs.TOTPParams			true	"TOTP code"
+//	@Success	200		{object}	wlstructs.RecoveryCodesInfo	"Recovery codes, shown only once"
+//	@Failure	400
+//	@Failure	401
+//	@Failure	409
+//	@Router		/users/totp/confirm [post]
+func ConfirmTOTPSetup(ctx ctxservice.RequestContext) {
+	params, err := netwrk.ReadRequestBody[wlstructs.TOTPParams](ctx.Req)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	codes, err := access_service.ConfirmTOTPEnrollment(ctx, ctx.Requester, params.Code)
+	if err != nil {
+		ctx.Error(http.StatusUnauthorized, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wlstructs.RecoveryCodesInfo{RecoveryCodes: codes})
+}
+
+// RegenerateRecoveryCodes godoc
+//
+//	@ID			RegenerateRecoveryCodes
+//
+//	@Security	SessionAuth
+//
+//	@Summary	Replace the recovery codes of the logged-in user
+//	@Tags		Users
+//	@Produce	json
+//	@Param		params	body		wlstructs.TOTPParams			true	"TOTP code"
+//	@Success	200		{object}	wlstructs.RecoveryCodesInfo	"Recovery codes, shown only once"
+//	@Failure	400
+//	@Failure	401
+//	@Router		/users/totp/recoveryCodes [post]
+func RegenerateRecoveryCodes(ctx ctxservice.RequestContext) {
+	params, err := netwrk.ReadRequestBody[wlstructs.TOTPParams](ctx.Req)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	codes, err := access_service.RegenerateRecoveryCodes(ctx, ctx.Requester, params.Code)
+	if err != nil {
+		ctx.Error(http.StatusUnauthorized, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wlstructs.RecoveryCodesInfo{RecoveryCodes: codes})
+}
+
+// DisableTOTP godoc
+//
+//	@ID			DisableTOTP
+//
+//	@Security	SessionAuth
+//
+//	@Summary	Turn off TOTP for the logged-in user
+//	@Tags		Users
+//	@Produce	json
+//	@Param		params	body		wlstructs.TOTPParams	true	"TOTP code or recovery code"
+//	@Success	200		{object}	wlstructs.UserInfo
+//	@Failure	400
+//	@Failure	401
+//	@Failure	403
+//	@Router		/users/totp [delete]
+func DisableTOTP(ctx ctxservice.RequestContext) {
+	params, err := netwrk.ReadRequestBody[wlstructs.TOTPParams](ctx.Req)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	err = access_service.DisableTOTP(ctx, ctx.Requester, params.Code, params.RecoveryCode)
+	if err != nil {
+		ctx.Error(http.StatusUnauthorized, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reshape.UserToUserInfo(ctx, ctx.Requester))
+}
+
+// getLoginChallengeUser returns the user a login token was issued to, once their password was accepted.
+func getLoginChallengeUser(ctx ctxservice.RequestContext, loginToken string) (*user_model.User, error) {
+	username, err := cryptography.GetUsernameFromLoginChallenge(loginToken)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := user_model.GetUserByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if !u.IsActive() {
+		return nil, wlerrors.New("user is not activated")
+	}
+
+	return u, nil
+}
@@ -3,7 +3,9 @@ package restuser
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 
+	auth_model "github.com/ethanrous/weblens/models/auth"
 	user_model "github.com/ethanrous/weblens/models/usermodel"
 	"github.com/ethanrous/weblens/modules/cryptography"
 	"github.com/ethanrous/weblens/modules/netwrk"
@@ -11,6 +13,7 @@ import (
 	"github.com/ethanrous/weblens/modules/wlstructs"
 	access_service "github.com/ethanrous/weblens/services/auth"
 	"github.com/ethanrous/weblens/services/ctxservice"
+	file_service "github.com/ethanrous/weblens/services/file"
 	"github.com/ethanrous/weblens/services/reshape"
 )
 
@@ -71,8 +74,9 @@ func Create(ctx ctxservice.RequestContext) {
 //	@Summary	Login User
 //	@Tags		Users
 //	@Produce	json
-//	@Param		loginParams	body		wlstructs.LoginParams	true	"Login params"
-//	@Success	200			{object}	wlstructs.UserInfo		"Logged-in users info"
+//	@Param		loginParams	body		wlstructs.LoginParams			true	"Login params"
+//	@Success	200			{object}	wlstructs.UserInfo				"Logged-in users info"
+//	@Success	202			{object}	wlstructs.LoginChallengeInfo	"Password accepted, but a second factor is needed"
 //	@Failure	401
 //	@Router		/users/auth [post]
 func Login(ctx ctxservice.RequestContext) {
@@ -97,6 +101,35 @@ func Login(ctx ctxservice.RequestContext) {
 		return
 	}
 
+	totpSetupRequired := false
+
+	if !u.HasTOTP() {
+		totpSetupRequired, err = access_service.IsTOTPRequired(ctx, u)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return
+		}
+	}
+
+	// The password alone is not enough, so hand out a login token to be exchanged for a session at /users/auth/totp
+	if u.HasTOTP() || totpSetupRequired {
+		loginToken, err := cryptography.GenerateLoginChallengeJWT(u.GetUsername())
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return
+		}
+
+		ctx.JSON(http.StatusAccepted, wlstructs.LoginChallengeInfo{
+			LoginToken:        loginToken,
+			TOTPRequired:      u.HasTOTP(),
+			TOTPSetupRequired: totpSetupRequired,
+		})
+
+		return
+	}
+
 	ctx.Requester = u
 
 	err = access_service.SetSessionToken(ctx)
@@ -156,6 +189,15 @@ func Logout(ctx ctxservice.RequestContext) {
 		return
 	}
 
+	if sessionID := access_service.GetRequestSessionID(ctx); sessionID != "" {
+		err := auth_model.DeleteSession(ctx, ctx.Requester.GetUsername(), sessionID)
+		if err != nil && !wlerrors.Is(err, auth_model.ErrSessionNotFound) {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return
+		}
+	}
+
 	cookie := fmt.Sprintf("%s=;Path=/;Expires=Thu, 01 Jan 1970 00:00:00 GMT;HttpOnly", cryptography.SessionTokenCookie)
 	ctx.W.Header().Set("Set-Cookie", cookie)
 	ctx.Status(http.StatusOK)
@@ -214,6 +256,16 @@ func GetMe(ctx ctxservice.RequestContext) {
 	}
 
 	newU := reshape.UserToUserInfo(ctx, ctx.Requester)
+
+	usedBytes, err := file_service.GetUsage(ctx, ctx.Requester.GetUsername())
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	newU.UsedBytes = usedBytes
+
 	ctx.JSON(http.StatusOK, newU)
 }
 
@@ -277,6 +329,21 @@ func UpdatePassword(ctx ctxservice.RequestContext) {
 		return
 	}
 
+	// Sign out everywhere the old password was used, except for the session that changed it
+	var keep []string
+	if userToUpdate.Username == ctx.Requester.Username {
+		if sessionID := access_service.GetRequestSessionID(ctx); sessionID != "" {
+			keep = append(keep, sessionID)
+		}
+	}
+
+	_, err = auth_model.DeleteSessionsByUser(ctx, userToUpdate.GetUsername(), keep...)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
 	ctx.Status(http.StatusOK)
 }
 
@@ -391,6 +458,15 @@ func Activate(ctx ctxservice.RequestContext) {
 		return
 	}
 
+	if !active {
+		_, err = auth_model.DeleteSessionsByUser(ctx, user.GetUsername())
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return
+		}
+	}
+
 	ctx.Status(http.StatusOK)
 }
 
@@ -446,6 +522,106 @@ func ChangeDisplayName(ctx ctxservice.RequestContext) {
 	ctx.JSON(http.StatusOK, reshape.UserToUserInfo(ctx, u))
 }
 
+// SetTrashRetention godoc
+//
+//	@ID			SetTrashRetention
+//
+//	@Security	SessionAuth
+//	@Security	ApiKeyAuth
+//
+//	@Summary	Set how many days items stay in a user's trash before they are purged
+//	@Tags		Users
+//	@Produce	json
+//
+//	@Param		username	path		string	true	"Username of user to update"
+//	@Param		days		query		int		true	"Days to keep trashed items. 0 uses the server-wide retention, and a negative value keeps items until they are deleted by hand"
+//	@Success	200			{object}	wlstructs.UserInfo
+//	@Failure	400			{object}	wlstructs.WeblensErrorInfo
+//	@Failure	401			{object}	wlstructs.WeblensErrorInfo
+//	@Failure	404			{object}	wlstructs.WeblensErrorInfo
+//	@Router		/users/{username}/trashRetention [patch]
+func SetTrashRetention(ctx ctxservice.RequestContext) {
+	username := ctx.Path("username")
+
+	days, err := strconv.Atoi(ctx.Query("days"))
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("days must be a whole number"))
+
+		return
+	}
+
+	u, err := user_model.GetUserByUsername(ctx, username)
+	if err != nil {
+		ctx.Status(http.StatusNotFound)
+
+		return
+	}
+
+	if u.Username != ctx.Requester.Username && !ctx.Requester.IsAdmin() {
+		ctx.Status(http.StatusForbidden)
+
+		return
+	}
+
+	err = u.UpdateTrashRetention(ctx, days)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reshape.UserToUserInfo(ctx, u))
+}
+
+// SetQuota godoc
+//
+//	@ID			SetUserQuota
+//
+//	@Security	SessionAuth[Admin]
+//	@Security	ApiKeyAuth[Admin]
+//
+//	@Summary	Set how many bytes a user may store
+//	@Tags		Users
+//	@Produce	json
+//
+//	@Param		username	path		string	true	"Username of user to update"
+//	@Param		bytes		query		int		true	"Storage quota in bytes. 0 removes the quota"
+//	@Success	200			{object}	wlstructs.UserInfo
+//	@Failure	400			{object}	wlstructs.WeblensErrorInfo
+//	@Failure	403			{object}	wlstructs.WeblensErrorInfo
+//	@Failure	404			{object}	wlstructs.WeblensErrorInfo
+//	@Router		/users/{username}/quota [patch]
+func SetQuota(ctx ctxservice.RequestContext) {
+	if !ctx.Requester.IsAdmin() {
+		ctx.Status(http.StatusForbidden)
+
+		return
+	}
+
+	quotaBytes, err := strconv.ParseInt(ctx.Query("bytes"), 10, 64)
+	if err != nil || quotaBytes < 0 {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("bytes must be a whole number of at least 0"))
+
+		return
+	}
+
+	u, err := user_model.GetUserByUsername(ctx, ctx.Path("username"))
+	if err != nil {
+		ctx.Status(http.StatusNotFound)
+
+		return
+	}
+
+	err = u.UpdateQuota(ctx, quotaBytes)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reshape.UserToUserInfo(ctx, u))
+}
+
 // Delete godoc
 //
 //	@ID			DeleteUser
@@ -492,6 +668,13 @@ func Delete(ctx ctxservice.RequestContext) {
 		return
 	}
 
+	_, err = auth_model.DeleteSessionsByUser(ctx, u.GetUsername())
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
 	ctx.Status(http.StatusOK)
 }
 
@@ -4,6 +4,7 @@ package tower
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/ethanrous/weblens/models/featureflags"
 	"github.com/ethanrous/weblens/models/task"
@@ -47,7 +48,7 @@ func FilterTasks(tasks []*task.Task, includeExited bool, sinceMs int64) []*task.
 //	@Tags		Towers
 //	@Produce	json
 //
-//	@Param		includeExited	query	bool	false	"Include tasks that have already finished (still held in memory)"					default(false)
+//	@Param		includeExited	query	bool	false	"Include tasks that have already finished, along with the saved history of durable tasks"	default(false)
 //	@Param		since			query	int64	false	"Only return finished tasks that completed at or after this Unix epoch-ms cursor (incremental polling)"	default(0)
 //
 //	@Success	200	{array}	wlstructs.TaskInfo	"Task Infos"
@@ -66,9 +67,27 @@ func GetRunningTasks(ctx ctxservice.RequestContext) {
 		sinceMs = parsed
 	}
 
-	tasks := FilterTasks(ctx.TaskService.GetTasks(), ctx.QueryBool("includeExited"), sinceMs)
+	includeExited := ctx.QueryBool("includeExited")
+	tasks := FilterTasks(ctx.TaskService.GetTasks(), includeExited, sinceMs)
 
 	taskInfos := reshape.TasksToTaskInfos(tasks)
+
+	if includeExited {
+		records, err := task.GetFinishedRecords(ctx, time.UnixMilli(sinceMs))
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, err)
+
+			return
+		}
+
+		// Tasks still held in memory are already included, with more detail than their records have
+		records = slices_mod.Filter(records, func(r *task.Record) bool {
+			return ctx.TaskService.GetTask(r.TaskID) == nil
+		})
+
+		taskInfos = append(taskInfos, reshape.TaskRecordsToTaskInfos(records)...)
+	}
+
 	ctx.JSON(http.StatusOK, taskInfos)
 }
 
@@ -148,6 +167,49 @@ func SetFlags(ctx ctxservice.RequestContext) {
 			cnf.AllowRegistrations = param.ConfigValue.(bool)
 		case featureflags.EnableEmbed:
 			cnf.EnableEmbed = param.ConfigValue.(bool)
+		case featureflags.TrashRetentionDays, featureflags.HistoryFullRetentionDays, featureflags.HistoryVersionRetentionDays:
+			days, ok := param.ConfigValue.(float64)
+			if !ok || days < 0 || days != float64(int(days)) {
+				ctx.Error(http.StatusBadRequest, wlerrors.Errorf("%s must be a whole number of days", param.ConfigKey))
+
+				return
+			}
+
+			switch param.ConfigKey {
+			case featureflags.TrashRetentionDays:
+				cnf.TrashRetentionDays = int(days)
+			case featureflags.HistoryFullRetentionDays:
+				cnf.HistoryFullRetentionDays = int(days)
+			case featureflags.HistoryVersionRetentionDays:
+				cnf.HistoryVersionRetentionDays = int(days)
+			}
+		case featureflags.QuotaWarningPercent:
+			percent, ok := param.ConfigValue.(float64)
+			if !ok || percent < 0 || percent > 100 || percent != float64(int(percent)) {
+				ctx.Error(http.StatusBadRequest, wlerrors.Errorf("%s must be a whole number between 0 and 100", param.ConfigKey))
+
+				return
+			}
+
+			cnf.QuotaWarningPercent = int(percent)
+		case featureflags.RequireAdminTOTP:
+			require, ok := param.ConfigValue.(bool)
+			if !ok {
+				ctx.Error(http.StatusBadRequest, wlerrors.Errorf("%s must be true or false", param.ConfigKey))
+
+				return
+			}
+
+			cnf.RequireAdminTOTP = require
+		case featureflags.EnableFileWatch:
+			enable, ok := param.ConfigValue.(bool)
+			if !ok {
+				ctx.Error(http.StatusBadRequest, wlerrors.Errorf("%s must be true or false", param.ConfigKey))
+
+				return
+			}
+
+			cnf.EnableFileWatch = enable
 		default:
 			ctx.Error(http.StatusBadRequest, wlerrors.Errorf("Unknown feature flag: %s", param.ConfigKey))
 		}
@@ -0,0 +1,102 @@
+package tower
+
+import (
+	"net/http"
+
+	"github.com/ethanrous/weblens/modules/config"
+	"github.com/ethanrous/weblens/modules/netwrk"
+	"github.com/ethanrous/weblens/modules/wlstructs"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+	tower_service "github.com/ethanrous/weblens/services/tower"
+)
+
+// RestoreTower godoc
+//
+//	@ID			RestoreTower
+//
+//	@Security	ApiKeyAuth
+//
+//	@Summary	Restore the users, API keys, towers and file history of a core from its backup
+//	@Tags		Towers
+//	@Accept		json
+//
+//	@Param		request	body	wlstructs.BackupInfo	true	"Backed up core data"
+//
+//	@Success	200
+//	@Failure	400
+//	@Failure	401
+//	@Failure	409
+//	@Failure	500
+//	@Router		/tower/restore [post]
+func RestoreTower(ctx context_service.RequestContext) {
+	archive, err := netwrk.ReadRequestBody[wlstructs.BackupInfo](ctx.Req)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	err = tower_service.RestoreArchive(ctx, archive)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// RestoreTowerFile godoc
+//
+//	@ID			RestoreTowerFile
+//
+//	@Security	ApiKeyAuth
+//
+//	@Summary	Restore the contents of a file from a backup
+//	@Tags		Towers
+//	@Accept		application/octet-stream
+//
+//	@Param		fileID	path	string	true	"ID of the file to restore"
+//
+//	@Success	200
+//	@Failure	400
+//	@Failure	401
+//	@Failure	404
+//	@Failure	409
+//	@Failure	500
+//	@Router		/tower/restore/files/{fileID} [post]
+func RestoreTowerFile(ctx context_service.RequestContext) {
+	err := tower_service.RestoreFile(ctx, ctx.Path("fileID"), ctx.Req.Body, ctx.Req.ContentLength)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// CompleteTowerRestore godoc
+//
+//	@ID			CompleteTowerRestore
+//
+//	@Security	ApiKeyAuth
+//
+//	@Summary	Finish restoring a core from its backup, and start it as a core
+//	@Tags		Towers
+//
+//	@Success	200
+//	@Failure	401
+//	@Failure	409
+//	@Failure	500
+//	@Router		/tower/restore/complete [post]
+func CompleteTowerRestore(ctx context_service.RequestContext) {
+	err := tower_service.CompleteRestore(ctx, ctx.Remote.TowerID, config.GetConfig())
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
@@ -0,0 +1,289 @@
+package tower
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ethanrous/weblens/models/db"
+	schedule_model "github.com/ethanrous/weblens/models/schedule"
+	"github.com/ethanrous/weblens/modules/cron"
+	"github.com/ethanrous/weblens/modules/netwrk"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlstructs"
+	"github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/ethanrous/weblens/services/jobs"
+	"github.com/ethanrous/weblens/services/reshape"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetSchedules godoc
+//
+//	@ID			GetSchedules
+//
+//	@Security	SessionAuth[admin]
+//	@Security	ApiKeyAuth[admin]
+//
+//	@Summary	Get Job Schedules
+//	@Tags		Towers
+//	@Produce	json
+//
+//	@Success	200	{array}	wlstructs.ScheduleInfo	"Schedules"
+//	@Router		/tower/schedules [get]
+func GetSchedules(ctx ctxservice.RequestContext) {
+	schedules, err := schedule_model.GetAllSchedules(ctx)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reshape.SchedulesToScheduleInfos(schedules))
+}
+
+// GetSchedulableJobs godoc
+//
+//	@ID			GetSchedulableJobs
+//
+//	@Security	SessionAuth[admin]
+//	@Security	ApiKeyAuth[admin]
+//
+//	@Summary	Get the names of the jobs that can be run on a schedule
+//	@Tags		Towers
+//	@Produce	json
+//
+//	@Success	200	{array}	string	"Job Names"
+//	@Router		/tower/schedules/jobs [get]
+func GetSchedulableJobs(ctx ctxservice.RequestContext) {
+	ctx.JSON(http.StatusOK, jobs.SchedulableJobs())
+}
+
+// CreateSchedule godoc
+//
+//	@ID			CreateSchedule
+//
+//	@Security	SessionAuth[admin]
+//	@Security	ApiKeyAuth[admin]
+//
+//	@Summary	Create a schedule that runs a job whenever its cron expression fires
+//	@Tags		Towers
+//	@Accept		json
+//	@Produce	json
+//
+//	@Param		request	body		wlstructs.ScheduleParams	true	"Schedule Params"
+//	@Success	201		{object}	wlstructs.ScheduleInfo		"Created Schedule"
+//	@Failure	400
+//	@Router		/tower/schedules [post]
+func CreateSchedule(ctx ctxservice.RequestContext) {
+	params, err := netwrk.ReadRequestBody[wlstructs.ScheduleParams](ctx.Req)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	if params.Name == "" {
+		ctx.Error(http.StatusBadRequest, wlerrors.New("schedule name is required"))
+
+		return
+	}
+
+	s, err := schedule_model.NewSchedule(params.Name, params.JobName, params.Cron, params.Params, ctx.Requester.GetUsername())
+	if err != nil {
+		ctx.Error(scheduleErrorStatus(err), err)
+
+		return
+	}
+
+	if params.Enabled != nil {
+		s.Enabled = *params.Enabled
+	}
+
+	err = jobs.VerifySchedule(ctx.AppContext, s)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	err = schedule_model.SaveSchedule(ctx, s)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, reshape.ScheduleToScheduleInfo(s))
+}
+
+// UpdateSchedule godoc
+//
+//	@ID			UpdateSchedule
+//
+//	@Security	SessionAuth[admin]
+//	@Security	ApiKeyAuth[admin]
+//
+//	@Summary	Update a job schedule
+//	@Tags		Towers
+//	@Accept		json
+//	@Produce	json
+//
+//	@Param		scheduleID	path		string						true	"Schedule ID"
+//	@Param		request		body		wlstructs.ScheduleParams	true	"Schedule Params"
+//	@Success	200			{object}	wlstructs.ScheduleInfo		"Updated Schedule"
+//	@Failure	400
+//	@Failure	404
+//	@Router		/tower/schedules/{scheduleID} [patch]
+func UpdateSchedule(ctx ctxservice.RequestContext) {
+	s, ok := getSchedule(ctx)
+	if !ok {
+		return
+	}
+
+	params, err := netwrk.ReadRequestBody[wlstructs.ScheduleParams](ctx.Req)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	if params.Name != "" {
+		s.Name = params.Name
+	}
+
+	if params.JobName != "" {
+		s.JobName = params.JobName
+	}
+
+	if params.Params != nil {
+		s.Params = params.Params
+	}
+
+	wasEnabled := s.Enabled
+	if params.Enabled != nil {
+		s.Enabled = *params.Enabled
+	}
+
+	// Re-enabling a schedule moves its next run forward, rather than running the runs it missed while disabled
+	if params.Cron != "" || (s.Enabled && !wasEnabled) {
+		cronExpr := params.Cron
+		if cronExpr == "" {
+			cronExpr = s.Cron
+		}
+
+		err = s.SetCron(cronExpr, time.Now())
+		if err != nil {
+			ctx.Error(scheduleErrorStatus(err), err)
+
+			return
+		}
+	}
+
+	err = jobs.VerifySchedule(ctx.AppContext, s)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, err)
+
+		return
+	}
+
+	err = schedule_model.UpdateSchedule(ctx, s)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reshape.ScheduleToScheduleInfo(s))
+}
+
+// DeleteSchedule godoc
+//
+//	@ID			DeleteSchedule
+//
+//	@Security	SessionAuth[admin]
+//	@Security	ApiKeyAuth[admin]
+//
+//	@Summary	Delete a job schedule
+//	@Tags		Towers
+//
+//	@Param		scheduleID	path	string	true	"Schedule ID"
+//	@Success	200
+//	@Failure	404
+//	@Router		/tower/schedules/{scheduleID} [delete]
+func DeleteSchedule(ctx ctxservice.RequestContext) {
+	s, ok := getSchedule(ctx)
+	if !ok {
+		return
+	}
+
+	err := schedule_model.DeleteSchedule(ctx, s.ID)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// RunSchedule godoc
+//
+//	@ID			RunSchedule
+//
+//	@Security	SessionAuth[admin]
+//	@Security	ApiKeyAuth[admin]
+//
+//	@Summary	Run the job of a schedule now, without waiting for it to be due
+//	@Tags		Towers
+//	@Produce	json
+//
+//	@Param		scheduleID	path		string					true	"Schedule ID"
+//	@Success	202			{object}	wlstructs.TaskInfo		"Dispatched Task"
+//	@Failure	400
+//	@Failure	404
+//	@Router		/tower/schedules/{scheduleID}/run [post]
+func RunSchedule(ctx ctxservice.RequestContext) {
+	s, ok := getSchedule(ctx)
+	if !ok {
+		return
+	}
+
+	tsk, err := jobs.RunSchedule(ctx, s, time.Now())
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, reshape.TaskToTaskInfo(tsk))
+}
+
+// getSchedule loads the schedule named in the request path, writing an error response if it cannot.
+func getSchedule(ctx ctxservice.RequestContext) (*schedule_model.Schedule, bool) {
+	scheduleID, err := primitive.ObjectIDFromHex(ctx.Path("scheduleID"))
+	if err != nil {
+		ctx.Error(http.StatusNotFound, wlerrors.New("schedule not found"))
+
+		return nil, false
+	}
+
+	s, err := schedule_model.GetScheduleByID(ctx, scheduleID)
+	if err != nil {
+		if db.IsNotFound(err) {
+			ctx.Error(http.StatusNotFound, err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, err)
+		}
+
+		return nil, false
+	}
+
+	return s, true
+}
+
+func scheduleErrorStatus(err error) int {
+	if wlerrors.Is(err, cron.ErrInvalidExpression) || wlerrors.Is(err, schedule_model.ErrScheduleNeverRuns) {
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
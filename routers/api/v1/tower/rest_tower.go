@@ -263,7 +263,7 @@ func InitializeTower(ctx context_service.RequestContext) {
 	case tower_model.RoleBackup:
 		err = tower_service.InitializeBackupServer(ctx, initBody, config.GetConfig())
 	case tower_model.RoleRestore:
-		err = errors.New("restore server initialization not implemented")
+		err = tower_service.InitializeRestoreServer(ctx, initBody, config.GetConfig())
 	default:
 		err = errors.New("invalid server role")
 	}
@@ -1,15 +1,178 @@
 package v1
 
-import "net/http"
+import (
+	"net/http"
+	"sync"
+
+	share_model "github.com/ethanrous/weblens/models/share"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/routers/router"
+	"github.com/ethanrous/weblens/services"
+	"github.com/ethanrous/weblens/services/auth"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/net/webdav"
+)
+
+// webdavExtensionMethods are the HTTP methods used by WebDAV that chi does not know about by default.
+var webdavExtensionMethods = []string{"PROPFIND", "PROPPATCH", "MKCOL", "COPY", "MOVE", "LOCK", "UNLOCK"}
+
+// webdavMethods are all methods served by the WebDAV handler, other than OPTIONS.
+var webdavMethods = append([]string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete}, webdavExtensionMethods...)
+
+func init() {
+	// chi responds 405 to any method it has not been told about, so these must be registered before any routes are.
+	for _, method := range webdavExtensionMethods {
+		chi.RegisterMethod(method)
+	}
+}
+
+var (
+	webdavLocks   = map[string]webdav.LockSystem{}
+	webdavLocksMu sync.Mutex
+)
+
+// getWebdavLockSystem returns the lock system for the WebDAV tree rooted at rootID. Lock paths are relative to the
+// root, so each root gets its own lock system to keep locks from different users and shares from colliding.
+func getWebdavLockSystem(rootID string) webdav.LockSystem {
+	webdavLocksMu.Lock()
+	defer webdavLocksMu.Unlock()
+
+	ls, ok := webdavLocks[rootID]
+	if !ok {
+		ls = webdav.NewMemLS()
+		webdavLocks[rootID] = ls
+	}
+
+	return ls
+}
+
+// WebdavRoutes serves the requester's home folder over WebDAV. Clients authenticate with basic auth, using either
+// their account password or an API key as the password.
+func WebdavRoutes(_ context_service.AppContext) *router.Router {
+	r := router.NewRouter()
+
+	r.Use(router.WebdavAuth)
+
+	// OPTIONS is answered without authentication, clients use it to discover WebDAV support before sending credentials.
+	r.Method(http.MethodOptions, "/*", http.HandlerFunc(webdavOptions))
+
+	for _, method := range webdavMethods {
+		r.Method(method, "/*", requireWebdavSignIn, serveWebdavHome)
+	}
+
+	return r
+}
+
+// WebdavShareRoutes serves the contents of a share over WebDAV. Public shares may be accessed without signing in.
+func WebdavShareRoutes(_ context_service.AppContext) *router.Router {
+	r := router.NewRouter()
+
+	r.Use(router.WebdavAuth)
+
+	r.Method(http.MethodOptions, "/{shareID}", http.HandlerFunc(webdavOptions))
+	r.Method(http.MethodOptions, "/{shareID}/*", http.HandlerFunc(webdavOptions))
+
+	for _, method := range webdavMethods {
+		r.Method(method, "/{shareID}", serveWebdavShare)
+		r.Method(method, "/{shareID}/*", serveWebdavShare)
+	}
+
+	return r
+}
 
 // webdavOptions handles OPTIONS requests for WebDAV protocol discovery and capabilities negotiation.
 func webdavOptions(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Set(
 		"Allow",
-		"OPTIONS, GET, HEAD, POST, PUT, DELETE, TRACE, COPY, MOVE, MKCOL, PROPFIND, PROPPATCH, LOCK, UNLOCK, ORDERPATCH",
+		"OPTIONS, GET, HEAD, PUT, DELETE, COPY, MOVE, MKCOL, PROPFIND, PROPPATCH, LOCK, UNLOCK",
 	)
 	w.Header().Set(
 		"DAV",
-		"1, 2, ordered-collections",
+		"1, 2",
 	)
+	w.Header().Set("MS-Author-Via", "DAV")
+}
+
+// requireWebdavSignIn is like router.RequireSignIn, but issues a basic auth challenge so WebDAV clients know to
+// prompt for credentials.
+func requireWebdavSignIn(next router.Handler) router.Handler {
+	return router.HandlerFunc(func(ctx context_service.RequestContext) {
+		if !ctx.IsLoggedIn {
+			ctx.SetHeader("WWW-Authenticate", `Basic realm="Weblens", charset="UTF-8"`)
+			ctx.Error(http.StatusUnauthorized, router.ErrNotAuthenticated)
+
+			return
+		}
+
+		next.ServeHTTP(ctx)
+	})
+}
+
+func serveWebdavHome(ctx context_service.RequestContext) {
+	home, err := ctx.FileService.GetFileByID(ctx, ctx.Requester.HomeID)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, wlerrors.Wrap(err, "failed to get home folder"))
+
+		return
+	}
+
+	serveWebdav(ctx, "/webdav", home.ID(), services.NewWebdavFs(ctx, home))
+}
+
+func serveWebdavShare(ctx context_service.RequestContext) {
+	shareIDStr := ctx.Path("shareID")
+
+	shareID, err := primitive.ObjectIDFromHex(shareIDStr)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, wlerrors.Wrap(err, "invalid share id"))
+
+		return
+	}
+
+	share, err := share_model.GetShareByID(ctx, shareID)
+	if err != nil {
+		ctx.Error(http.StatusNotFound, wlerrors.Wrap(err, "failed to get share"))
+
+		return
+	}
+
+	if share.IsExpired() && share.GetOwner() != ctx.Requester.GetUsername() {
+		ctx.Error(http.StatusGone, share_model.ErrShareExpired)
+
+		return
+	}
+
+	if err := auth.CheckShareUnlocked(ctx, ctx.Requester, share); err != nil {
+		ctx.Error(http.StatusUnauthorized, err)
+
+		return
+	}
+
+	ctx.Share = share
+
+	root, err := ctx.FileService.GetFileByID(ctx, share.FileID)
+	if err != nil {
+		ctx.Error(http.StatusNotFound, wlerrors.Wrap(err, "failed to get shared file"))
+
+		return
+	}
+
+	serveWebdav(ctx, "/webdav-share/"+shareIDStr, root.ID(), services.NewWebdavFs(ctx, root))
+}
+
+func serveWebdav(ctx context_service.RequestContext, prefix, rootID string, fs webdav.FileSystem) {
+	handler := &webdav.Handler{
+		Prefix:     prefix,
+		FileSystem: fs,
+		LockSystem: getWebdavLockSystem(rootID),
+		Logger: func(req *http.Request, err error) {
+			if err != nil {
+				ctx.Log().Debug().Err(err).Msgf("WebDAV %s %s failed", req.Method, req.URL.Path)
+			}
+		},
+	}
+
+	handler.ServeHTTP(ctx.W, ctx.Req)
 }
@@ -1,6 +1,7 @@
 package router
 
 import (
+	"crypto/subtle"
 	"net/http"
 	"strings"
 	"time"
@@ -45,6 +46,9 @@ const (
 // ErrNotAuthenticated indicates that the request lacks valid authentication credentials.
 var ErrNotAuthenticated = wlerrors.New("not authenticated")
 
+// ErrBasicAuthNotAllowed is returned when basic auth credentials are sent to a route other than the WebDAV mounts.
+var ErrBasicAuthNotAllowed = wlerrors.Statusf(http.StatusUnauthorized, "basic auth is only accepted for WebDAV")
+
 // ErrNotAuthorized indicates that the authenticated user lacks permission for the requested action.
 var ErrNotAuthorized = wlerrors.New("not authorized")
 
@@ -167,6 +171,12 @@ func RequireAdmin(next Handler) Handler {
 			return
 		}
 
+		if err := auth_service.CheckAPIKeyAdmin(ctx); err != nil {
+			ctx.Error(http.StatusForbidden, err)
+
+			return
+		}
+
 		next.ServeHTTP(ctx)
 	})
 }
@@ -180,6 +190,12 @@ func RequireOwner(next Handler) Handler {
 			return
 		}
 
+		if err := auth_service.CheckAPIKeyAdmin(ctx); err != nil {
+			ctx.Error(http.StatusForbidden, err)
+
+			return
+		}
+
 		next.ServeHTTP(ctx)
 	})
 }
@@ -204,6 +220,43 @@ func RequireCoreTower(next Handler) Handler {
 	})
 }
 
+// RequireRestoringTower returns a middleware that ensures the local tower is being restored, and the request comes from
+// the backup tower restoring it, before proceeding.
+func RequireRestoringTower(next Handler) Handler {
+	return HandlerFunc(func(ctx context_service.RequestContext) {
+		local, err := tower_model.GetLocal(ctx)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, wlerrors.Wrap(err, "failed to get local instance"))
+
+			return
+		}
+
+		if local.Role != tower_model.RoleRestore {
+			ctx.Error(http.StatusConflict, tower_service.ErrNotRestoring)
+
+			return
+		}
+
+		remote, err := tower_model.GetTowerByID(ctx, ctx.Header(tower_service.TowerIDHeader))
+		if err != nil || remote.Role != tower_model.RoleBackup {
+			ctx.Error(http.StatusUnauthorized, wlerrors.Wrap(ErrNotAuthenticated, "request is not from the restoring backup"))
+
+			return
+		}
+
+		expected := "Bearer " + remote.IncomingKey
+		if subtle.ConstantTimeCompare([]byte(ctx.Header("Authorization")), []byte(expected)) != 1 {
+			ctx.Error(http.StatusUnauthorized, wlerrors.Wrap(ErrNotAuthenticated, "invalid restore key"))
+
+			return
+		}
+
+		ctx.Remote = remote
+
+		next.ServeHTTP(ctx)
+	})
+}
+
 // ShareInjector returns a middleware that loads share information from the shareID query parameter into the request context.
 func ShareInjector(next Handler) Handler {
 	return HandlerFunc(func(ctx context_service.RequestContext) {
@@ -218,6 +271,18 @@ func ShareInjector(next Handler) Handler {
 				return
 			}
 
+			if share.IsExpired() && (ctx.Requester == nil || share.GetOwner() != ctx.Requester.GetUsername()) {
+				ctx.Error(http.StatusGone, share_model.ErrShareExpired)
+
+				return
+			}
+
+			if err := auth_service.CheckShareUnlocked(ctx, ctx.Requester, share); err != nil {
+				ctx.Error(http.StatusUnauthorized, err)
+
+				return
+			}
+
 			ctx.Log().Debug().Msgf("Share found: %s", shareIDStr)
 
 			ctx.Share = share
@@ -229,6 +294,16 @@ func ShareInjector(next Handler) Handler {
 
 // WeblensAuth returns a middleware that handles authentication for Weblens requests using auth headers, session tokens, or tower credentials.
 func WeblensAuth(next Handler) Handler {
+	return weblensAuth(next, false)
+}
+
+// WebdavAuth is like WeblensAuth, but also accepts basic auth credentials, which WebDAV clients send in place of a
+// session. It must only be used for the WebDAV mounts.
+func WebdavAuth(next Handler) Handler {
+	return weblensAuth(next, true)
+}
+
+func weblensAuth(next Handler, allowBasicAuth bool) Handler {
 	return HandlerFunc(func(ctx context_service.RequestContext) {
 		local, err := tower_model.GetLocal(ctx)
 		if err != nil {
@@ -237,7 +312,8 @@ func WeblensAuth(next Handler) Handler {
 			return
 		}
 
-		if local.Role == tower_model.RoleUninitialized {
+		// A tower being restored has no API tokens yet, the backup restoring it is checked by RequireRestoringTower
+		if local.Role == tower_model.RoleUninitialized || local.Role == tower_model.RoleRestore {
 			next.ServeHTTP(ctx)
 
 			return
@@ -258,10 +334,30 @@ func WeblensAuth(next Handler) Handler {
 		}
 
 		authHeader := ctx.Header("Authorization")
-		if authHeader != "" {
+		if username, password, ok := ctx.Req.BasicAuth(); ok {
+			if !allowBasicAuth {
+				ctx.Error(http.StatusUnauthorized, wlerrors.WithStack(ErrBasicAuthNotAllowed))
+
+				return
+			}
+
+			ctx.Log().Trace().Msg("Basic auth credentials found, attempting to authenticate via basic auth")
+
+			usr, apiKey, err := auth_service.GetUserFromBasicAuth(ctx, username, password, auth_service.RequestIP(ctx.Req))
+			if err != nil {
+				ctx.Error(http.StatusUnauthorized, wlerrors.Wrap(err, "failed to validate basic auth credentials"))
+
+				return
+			}
+
+			ctx.Log().Trace().Msgf("Authenticated user via basic auth: %s", usr.Username)
+
+			ctx = ctx.WithRequester(usr)
+			ctx.APIKey = apiKey
+		} else if authHeader != "" {
 			ctx.Log().Trace().Msg("Authorization header found, attempting to authenticate via header")
 
-			usr, err := auth_service.GetUserFromAuthHeader(ctx, authHeader)
+			usr, apiKey, err := auth_service.GetUserFromAuthHeader(ctx, authHeader)
 			if err != nil {
 				ctx.Error(http.StatusUnauthorized, wlerrors.Wrap(err, "failed to validate authorization header"))
 
@@ -271,6 +367,7 @@ func WeblensAuth(next Handler) Handler {
 			ctx.Log().Trace().Msgf("Authenticated user via auth header: %s", usr.Username)
 
 			ctx = ctx.WithRequester(usr)
+			ctx.APIKey = apiKey
 		} else if ctx.Remote.TowerID != "" {
 			ctx.Error(http.StatusUnauthorized, wlerrors.Wrap(ErrNotAuthenticated, "towers must authenticate with a token"))
 
@@ -281,7 +378,7 @@ func WeblensAuth(next Handler) Handler {
 		if !ctx.IsLoggedIn {
 			sessionCookie, err := ctx.GetCookie(SessionTokenCookie)
 			if err == nil {
-				usr, err := auth_service.GetUserFromJWT(ctx, sessionCookie)
+				usr, err := auth_service.GetUserFromJWT(ctx, sessionCookie, auth_service.RequestIP(ctx.Req))
 				if err != nil {
 					ctx.ExpireCookie()
 					ctx.Error(http.StatusUnauthorized, wlerrors.WrapStatus(http.StatusUnauthorized, wlerrors.Wrap(err, "failed to validate sesion token")))
@@ -295,6 +392,15 @@ func WeblensAuth(next Handler) Handler {
 			}
 		}
 
+		// Api keys may be limited to some kinds of requests
+		if ctx.APIKey != nil {
+			if err := auth_service.CheckAPIKeyScope(ctx.APIKey, ctx.Req.Method, ctx.Req.URL.Path); err != nil {
+				ctx.Error(http.StatusForbidden, err)
+
+				return
+			}
+		}
+
 		wlog.FromContext(ctx).UpdateContext(func(c zerolog.Context) zerolog.Context {
 			return c.Str("requester", ctx.Requester.Username)
 		})
@@ -312,9 +418,10 @@ func CORSMiddleware(next Handler) Handler {
 		ctx.SetHeader("Access-Control-Allow-Credentials", "true")
 		ctx.SetHeader(
 			"Access-Control-Allow-Headers",
-			"Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Content-Range, Cookie",
+			"Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Content-Range, Cookie, Tus-Resumable, Upload-Length, Upload-Offset, Upload-Metadata",
 		)
-		ctx.SetHeader("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
+		ctx.SetHeader("Access-Control-Expose-Headers", "Location, Tus-Resumable, Tus-Version, Upload-Length, Upload-Offset, Upload-Expires")
+		ctx.SetHeader("Access-Control-Allow-Methods", "POST, OPTIONS, GET, HEAD, PUT, PATCH, DELETE")
 
 		if ctx.Req.Method == http.MethodOptions {
 			ctx.Status(http.StatusNoContent)
@@ -196,9 +196,7 @@ func startServices(appCtx context_service.AppContext, cnf config.Provider) (cont
 		return appCtx, nil, wlerrors.Errorf("Local tower ID is empty after load")
 	}
 
-	appCtx.LocalTowerID = local.TowerID
-
-	appCtx = appCtx.WithValue("towerID", local.TowerID)
+	appCtx = appCtx.SetLocalTowerID(local.TowerID)
 
 	if local.Role == tower_model.RoleBackup {
 		appCtx = appCtx.WithValue(file_service.SkipJournalKey, true)
@@ -218,9 +216,15 @@ func startServices(appCtx context_service.AppContext, cnf config.Provider) (cont
 	r.Use(
 		context_service.AppContexter(appCtx),
 		router.LoggerMiddlewares(),
-		router.CORSMiddleware,
 	)
 
+	// WebDAV is mounted ahead of the CORS middleware, which would otherwise swallow the OPTIONS requests
+	// WebDAV clients use for discovery.
+	r.Mount("/webdav", router.Recoverer, v1.WebdavRoutes(appCtx))
+	r.Mount("/webdav-share", router.Recoverer, v1.WebdavShareRoutes(appCtx))
+
+	r.Use(router.CORSMiddleware)
+
 	// Install routes
 	r.Mount("/api/v1/", router.Recoverer, v1.Routes(appCtx))
 
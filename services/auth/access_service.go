@@ -5,7 +5,9 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"net"
 	"net/http"
+	"slices"
 	"time"
 
 	auth_model "github.com/ethanrous/weblens/models/auth"
@@ -21,6 +23,16 @@ import (
 // ErrBadAuthHeader is returned when the Authorization header has an invalid format.
 var ErrBadAuthHeader = wlerrors.Statusf(http.StatusBadRequest, "invalid auth header format")
 
+// ErrBadCredentials is returned when basic auth credentials do not match any user.
+var ErrBadCredentials = wlerrors.Statusf(http.StatusUnauthorized, "invalid username or password")
+
+// ErrTooManyLoginAttempts is returned when a user has seen too many wrong passwords from the same address in a short time.
+var ErrTooManyLoginAttempts = wlerrors.Statusf(http.StatusTooManyRequests, "too many failed login attempts, try again later")
+
+// ErrBasicAuthRequiresAPIKey is returned when a user who has, or is required to have, two factor authentication sends
+// their password over basic auth.
+var ErrBasicAuthRequiresAPIKey = wlerrors.Statusf(http.StatusUnauthorized, "two factor authentication is enabled, use an API key as the password")
+
 // ErrMustAuthenticate is returned when authentication is required but not provided.
 var ErrMustAuthenticate = wlerrors.Statusf(http.StatusUnauthorized, "user must authenticate to access this resource")
 
@@ -30,11 +42,24 @@ var ErrFileAccessNotPermitted = wlerrors.Statusf(http.StatusForbidden, "file acc
 // ErrShareDoesNotPermitFile is returned when a share does not grant access to a specific file.
 var ErrShareDoesNotPermitFile = wlerrors.Statusf(http.StatusForbidden, "share does not permit access to this file")
 
-func doesSharePermitFile(_ context.Context, file *file_model.WeblensFileImpl, share *share_model.FileShare) bool {
+const (
+	// maxBasicAuthPasswordFailures is how many wrong passwords a user accepts over basic auth from one address within
+	// basicAuthPasswordWindow, before that address may not try the password again until the window has passed.
+	maxBasicAuthPasswordFailures = 5
+	basicAuthPasswordWindow      = time.Minute * 15
+)
+
+var basicAuthPasswordAttempts = newAttemptLimiter(maxBasicAuthPasswordFailures, basicAuthPasswordWindow)
+
+func doesSharePermitFile(ctx context.Context, file *file_model.WeblensFileImpl, share *share_model.FileShare) bool {
 	if share == nil || !share.Enabled || file.IsPastFile() {
 		return false
 	}
 
+	if share.IsAlbumShare() {
+		return doesAlbumSharePermitFile(ctx, file, share)
+	}
+
 	for {
 		if share.FileID == file.ID() {
 			return true
@@ -56,6 +81,10 @@ func CanUserAccessFile(ctx context.Context, user *user_model.User, file *file_mo
 		return &share_model.Permissions{}, ErrMustAuthenticate
 	}
 
+	if err := checkAPIKeyFolder(ctx, user, file); err != nil {
+		return &share_model.Permissions{}, err
+	}
+
 	if file.GetPortablePath() == file_model.UsersRootPath {
 		if user.IsOwner() {
 			return share_model.NewPermissions(), nil
@@ -85,6 +114,10 @@ func CanUserAccessFile(ctx context.Context, user *user_model.User, file *file_mo
 		return share_model.NewFullPermissions(), nil
 	}
 
+	if share != nil && share.IsExpired() {
+		return &share_model.Permissions{}, wlerrors.ReplaceStack(wlerrors.Errorf("denying user [%s] access to file [%s] using share [%s]: %w", user.Username, file.ID(), share.ShareID.Hex(), share_model.ErrShareExpired))
+	}
+
 	// Check that the share permits access to the specific file we are trying to access
 	if !doesSharePermitFile(ctx, file, share) {
 		if share != nil {
@@ -102,6 +135,20 @@ func CanUserAccessFile(ctx context.Context, user *user_model.User, file *file_mo
 		return share_model.NewFullPermissions(), nil
 	}
 
+	// Timeline-only album shares expose the media in the album, but not the files behind it
+	if share.IsAlbumShare() && share.TimelineOnly && slices.ContainsFunc(requiredPerms, func(p share_model.Permission) bool { return p != share_model.SharePermissionViewMedia }) {
+		return &share_model.Permissions{}, wlerrors.ReplaceStack(wlerrors.Errorf("denying user [%s] access to file [%s] using timeline only share [%s]: %w", user.Username, file.ID(), share.ShareID.Hex(), ErrFileAccessNotPermitted))
+	}
+
+	return getSharePermissions(ctx, user, share, requiredPerms...)
+}
+
+// getSharePermissions returns the permissions share grants user, or an error if it does not grant all of requiredPerms.
+func getSharePermissions(ctx context.Context, user *user_model.User, share *share_model.FileShare, requiredPerms ...share_model.Permission) (*share_model.Permissions, error) {
+	if err := CheckShareUnlocked(ctx, user, share); err != nil {
+		return &share_model.Permissions{}, err
+	}
+
 	allowedPerms := share.GetUserPermissions(user.GetUsername())
 	if allowedPerms == nil && !share.Public {
 		// If the user is not in the accessors list, we cannot access it
@@ -127,7 +174,7 @@ func CanUserAccessFile(ctx context.Context, user *user_model.User, file *file_mo
 		return &share_model.Permissions{}, nil
 	}
 
-	return &share_model.Permissions{}, wlerrors.New("unexpected error in CanUserAccessFile: reached end of permissions check without identifying permissions or an error")
+	return &share_model.Permissions{}, wlerrors.New("unexpected error in getSharePermissions: reached end of permissions check without identifying permissions or an error")
 }
 
 // CanUserModifyShare checks if a user has permission to modify a share.
@@ -145,20 +192,32 @@ func CanUserAccessShare(user *user_model.User, share share_model.FileShare) bool
 	return share.GetUserPermissions(user.GetUsername()) != nil
 }
 
-// SetSessionToken generates and sets session cookies for the authenticated user.
+// SetSessionToken starts a new session for the authenticated user, and sets the session cookies for it.
 func SetSessionToken(ctx context_service.RequestContext) error {
 	if ctx.Requester == nil {
 		return wlerrors.New("requester is nil")
 	}
 
-	secure := ctx.Req.TLS != nil
+	sessionID, err := cryptography.RandomString(32)
+	if err != nil {
+		return err
+	}
 
-	sessionCookie, err := GenerateJWTCookie(ctx.Requester, secure)
+	token, expires, err := cryptography.GenerateSessionJWT(ctx.Requester.GetUsername(), sessionID)
 	if err != nil {
 		return err
 	}
 
-	ctx.SetHeader("Set-Cookie", sessionCookie)
+	session := auth_model.NewSession(sessionID, ctx.Requester.GetUsername(), ctx.Header("User-Agent"), RequestIP(ctx.Req), expires)
+
+	err = auth_model.SaveSession(ctx, session)
+	if err != nil {
+		return err
+	}
+
+	secure := ctx.Req.TLS != nil
+
+	ctx.SetHeader("Set-Cookie", GenerateJWTCookie(token, expires, secure))
 
 	usernameCookie := GenerateUserCookie(ctx.Requester, secure)
 	ctx.AddHeader("Set-Cookie", usernameCookie)
@@ -166,13 +225,8 @@ func SetSessionToken(ctx context_service.RequestContext) error {
 	return nil
 }
 
-// GenerateJWTCookie creates a session cookie containing a JWT for the user.
-func GenerateJWTCookie(user *user_model.User, secure bool) (string, error) {
-	token, expires, err := cryptography.GenerateJWT(user.GetUsername())
-	if err != nil {
-		return "", err
-	}
-
+// GenerateJWTCookie creates a session cookie containing a JWT.
+func GenerateJWTCookie(token string, expires time.Time, secure bool) string {
 	cookie := (&http.Cookie{
 		Name:     cryptography.SessionTokenCookie,
 		Value:    token,
@@ -183,7 +237,7 @@ func GenerateJWTCookie(user *user_model.User, secure bool) (string, error) {
 		SameSite: http.SameSiteLaxMode,
 	}).String()
 
-	return cookie, nil
+	return cookie
 }
 
 // GenerateUserCookie creates a cookie containing the username.
@@ -203,52 +257,153 @@ func GenerateUserCookie(user *user_model.User, secure bool) string {
 	return cookie
 }
 
-// GetUserFromJWT extracts and validates a user from a JWT token string.
-func GetUserFromJWT(ctx context.Context, tokenStr string) (*user_model.User, error) {
-	username, err := cryptography.GetUsernameFromToken(tokenStr)
+// GetUserFromJWT extracts and validates a user from a JWT token string. The session of the token must not have been
+// revoked, and its last use is recorded as coming from ip.
+func GetUserFromJWT(ctx context.Context, tokenStr, ip string) (*user_model.User, error) {
+	session, err := GetSessionFromJWT(ctx, tokenStr)
 	if err != nil {
 		return nil, err
 	}
 
-	u, err := user_model.GetUserByUsername(ctx, username)
+	u, err := user_model.GetUserByUsername(ctx, session.Username)
 	if err != nil {
 		return nil, err
 	}
 
+	err = session.Touch(ctx, ip)
+	if err != nil {
+		wlog.FromContext(ctx).Warn().Err(err).Msgf("Failed to record use of session of [%s]", session.Username)
+	}
+
 	return u, nil
 }
 
-// GetUserFromAuthHeader extracts and validates a user from an Authorization header.
-func GetUserFromAuthHeader(ctx context.Context, authHeader string) (*user_model.User, error) {
+// GetSessionFromJWT validates a JWT token string, and returns the session it belongs to.
+func GetSessionFromJWT(ctx context.Context, tokenStr string) (*auth_model.Session, error) {
+	claims, err := cryptography.GetClaimsFromToken(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.ID == "" {
+		return nil, wlerrors.WithStack(auth_model.ErrSessionNotFound)
+	}
+
+	session, err := auth_model.GetSession(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Username != claims.Username {
+		return nil, wlerrors.Errorf("session [%s] does not belong to [%s]: %w", claims.ID, claims.Username, auth_model.ErrSessionNotFound)
+	}
+
+	return session, nil
+}
+
+// GetRequestSessionID returns the ID of the session the request was made with, or an empty string if it was not
+// authenticated with a session cookie.
+func GetRequestSessionID(ctx context_service.RequestContext) string {
+	sessionCookie, err := ctx.GetCookie(cryptography.SessionTokenCookie)
+	if err != nil {
+		return ""
+	}
+
+	claims, err := cryptography.GetClaimsFromToken(sessionCookie)
+	if err != nil {
+		return ""
+	}
+
+	return claims.ID
+}
+
+// RequestIP returns the address of the client that made req, without its port.
+func RequestIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+
+	return host
+}
+
+// GetUserFromAuthHeader extracts and validates a user from an Authorization header. The api key the header holds is
+// returned along with its owner, so its restrictions can be enforced.
+func GetUserFromAuthHeader(ctx context.Context, authHeader string) (*user_model.User, *auth_model.Token, error) {
 	if len(authHeader) < 7 || authHeader[:7] != "Bearer " {
-		return nil, wlerrors.WrapStatus(http.StatusBadRequest, ErrBadAuthHeader)
+		return nil, nil, wlerrors.WrapStatus(http.StatusBadRequest, ErrBadAuthHeader)
 	}
 
 	var tokenStr string
 
 	_, err := fmt.Sscanf(authHeader, "Bearer %s", &tokenStr)
 	if err != nil {
-		return nil, wlerrors.WrapStatus(http.StatusInternalServerError, err)
+		return nil, nil, wlerrors.WrapStatus(http.StatusInternalServerError, err)
 	}
 
 	tokenByteSlice, err := base64.StdEncoding.DecodeString(tokenStr)
 	if err != nil {
-		return nil, wlerrors.WrapStatus(http.StatusInternalServerError, err)
+		return nil, nil, wlerrors.WrapStatus(http.StatusInternalServerError, err)
 	}
 
 	var tokenBytes [32]byte
 
 	copy(tokenBytes[:], tokenByteSlice)
 
-	token, err := auth_model.GetToken(ctx, tokenBytes)
+	token, err := getAPIKey(ctx, tokenBytes)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	u, err := user_model.GetUserByUsername(ctx, token.Owner)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return u, nil
+	return u, token, nil
+}
+
+// GetUserFromBasicAuth validates HTTP basic auth credentials, as sent by WebDAV clients and other tools that
+// cannot hold a session. The password may be either one of the user's API keys, or the account password of a user
+// who neither has nor is required to have two factor authentication. If it is an API key, the key is returned along
+// with the user. Wrong account passwords are limited per user and clientIP.
+func GetUserFromBasicAuth(ctx context.Context, username, password, clientIP string) (*user_model.User, *auth_model.Token, error) {
+	u, err := user_model.GetUserByUsername(ctx, username)
+	if err != nil {
+		return nil, nil, wlerrors.WithStack(ErrBadCredentials)
+	}
+
+	// API keys are checked first, they are too long to guess and need no limit on attempts
+	tokenByteSlice, err := base64.StdEncoding.DecodeString(password)
+	if err == nil && len(tokenByteSlice) == 32 {
+		token, err := getAPIKey(ctx, [32]byte(tokenByteSlice))
+		if err == nil && token.Owner == u.GetUsername() {
+			return u, token, nil
+		} else if wlerrors.Is(err, auth_model.ErrTokenExpired) {
+			return nil, nil, err
+		}
+	}
+
+	// A password alone is not enough for users with two factor authentication, and there is nowhere to send a code
+	totpRequired, err := IsTOTPRequired(ctx, u)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if u.HasTOTP() || totpRequired {
+		return nil, nil, wlerrors.WithStack(ErrBasicAuthRequiresAPIKey)
+	}
+
+	attemptKey := u.GetUsername() + "@" + clientIP
+	if !basicAuthPasswordAttempts.take(attemptKey) {
+		return nil, nil, wlerrors.WithStack(ErrTooManyLoginAttempts)
+	}
+
+	if !u.CheckLogin(password) {
+		return nil, nil, wlerrors.WithStack(ErrBadCredentials)
+	}
+
+	basicAuthPasswordAttempts.reset(attemptKey)
+
+	return u, nil, nil
 }
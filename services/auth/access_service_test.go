@@ -229,11 +229,8 @@ func TestCanUserModifyShare(t *testing.T) {
 
 func TestGenerateJWTCookie(t *testing.T) {
 	t.Run("generates valid cookie string with security flags", func(t *testing.T) {
-		user := &user_model.User{Username: "testuser"}
-
-		cookie, err := auth.GenerateJWTCookie(user, true)
-		require.NoError(t, err)
-		assert.Contains(t, cookie, "weblens-session-token=")
+		cookie := auth.GenerateJWTCookie("token", time.Now().Add(time.Hour), true)
+		assert.Contains(t, cookie, "weblens-session-token=token")
 		assert.Contains(t, cookie, "Path=/")
 		assert.Contains(t, cookie, "Expires=")
 		assert.Contains(t, cookie, "HttpOnly")
@@ -270,7 +267,7 @@ func TestGetUserFromAuthHeader_InvalidFormat(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := auth.GetUserFromAuthHeader(ctx, tt.header)
+			_, _, err := auth.GetUserFromAuthHeader(ctx, tt.header)
 			assert.Error(t, err)
 		})
 	}
@@ -303,6 +300,45 @@ func TestCanUserAccessFile_DisabledShare(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestCanUserAccessFile_ExpiredShare(t *testing.T) {
+	ctx := context.Background()
+
+	owner := &user_model.User{Username: "testuser", UserPerms: user_model.UserPermissionBasic}
+	otherUser := &user_model.User{Username: "otheruser", UserPerms: user_model.UserPermissionBasic}
+
+	filepath := file_system.BuildFilePath(file_model.UsersTreeKey, "testuser/photos/image.jpg")
+	file := file_model.NewWeblensFile(file_model.NewFileOptions{
+		Path:       filepath,
+		MemOnly:    true,
+		GenerateID: true,
+	})
+
+	share := &share_model.FileShare{
+		ShareID: primitive.NewObjectID(),
+		FileID:  file.ID(),
+		Public:  true,
+		Enabled: true,
+		Expires: time.Now().Add(-time.Minute),
+		Permissions: map[string]*share_model.Permissions{
+			user_model.PublicUserName: share_model.NewPermissions(),
+		},
+	}
+
+	// Expired share should be rejected, and reported as expired
+	_, err := auth.CanUserAccessFile(ctx, otherUser, file, share)
+	assert.ErrorIs(t, err, share_model.ErrShareExpired)
+
+	// The owner can still access their own file
+	perms, err := auth.CanUserAccessFile(ctx, owner, file, share)
+	require.NoError(t, err)
+	assert.True(t, perms.CanView)
+
+	// A share that expires in the future is still honored
+	share.Expires = time.Now().Add(time.Hour)
+	_, err = auth.CanUserAccessFile(ctx, otherUser, file, share)
+	assert.NoError(t, err)
+}
+
 func TestCanUserAccessFile_WrongFileForShare(t *testing.T) {
 	ctx := context.Background()
 
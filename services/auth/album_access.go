@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	album_model "github.com/ethanrous/weblens/models/album"
+	file_model "github.com/ethanrous/weblens/models/file"
+	share_model "github.com/ethanrous/weblens/models/share"
+	user_model "github.com/ethanrous/weblens/models/usermodel"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlog"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrAlbumAccessNotPermitted is returned when a user lacks permission to access an album.
+var ErrAlbumAccessNotPermitted = wlerrors.Statusf(http.StatusForbidden, "album access not permitted")
+
+// CanUserAccessAlbum checks if a user has permission to access an album, either as its owner or through a share of it.
+func CanUserAccessAlbum(ctx context.Context, user *user_model.User, album *album_model.Album, share *share_model.FileShare, requiredPerms ...share_model.Permission) (*share_model.Permissions, error) {
+	if user == nil {
+		return &share_model.Permissions{}, ErrMustAuthenticate
+	}
+
+	if album.Owner == user.GetUsername() {
+		return share_model.NewFullPermissions(), nil
+	}
+
+	if share == nil || !share.Enabled || share.AlbumID != album.ID() {
+		return &share_model.Permissions{}, wlerrors.ReplaceStack(wlerrors.Errorf("denying user [%s] access to album [%s]: %w", user.Username, album.ID(), ErrAlbumAccessNotPermitted))
+	}
+
+	if share.IsExpired() {
+		return &share_model.Permissions{}, wlerrors.WithStack(share_model.ErrShareExpired)
+	}
+
+	return getSharePermissions(ctx, user, share, requiredPerms...)
+}
+
+// doesAlbumSharePermitFile checks that file is behind media in the album shared by share. Only files of the owner of
+// the album are permitted, so that adding media to an album never shares files its owner could not share themselves.
+func doesAlbumSharePermitFile(ctx context.Context, file *file_model.WeblensFileImpl, share *share_model.FileShare) bool {
+	if file.IsDir() || file.GetContentID() == "" || file_model.IsFileInTrash(file) {
+		return false
+	}
+
+	ownerName, err := file_model.GetFileOwnerName(ctx, file)
+	if err != nil || ownerName != share.GetOwner() {
+		return false
+	}
+
+	albumID, err := primitive.ObjectIDFromHex(share.AlbumID)
+	if err != nil {
+		return false
+	}
+
+	album, err := album_model.GetAlbumByID(ctx, albumID)
+	if err != nil {
+		wlog.FromContext(ctx).Error().Stack().Err(err).Msgf("Failed to get album of share [%s]", share.ShareID.Hex())
+
+		return false
+	}
+
+	return album.HasMedia(file.GetContentID())
+}
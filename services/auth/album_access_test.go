@@ -0,0 +1,102 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	album_model "github.com/ethanrous/weblens/models/album"
+	share_model "github.com/ethanrous/weblens/models/share"
+	user_model "github.com/ethanrous/weblens/models/usermodel"
+	"github.com/ethanrous/weblens/services/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTestAlbum(owner string) *album_model.Album {
+	return &album_model.Album{
+		AlbumID: primitive.NewObjectID(),
+		Title:   "Test Album",
+		Owner:   owner,
+		Medias:  []string{"content1"},
+	}
+}
+
+func TestCanUserAccessAlbum_Owner(t *testing.T) {
+	owner := &user_model.User{Username: "albumowner", UserPerms: user_model.UserPermissionBasic}
+	album := newTestAlbum(owner.Username)
+
+	perms, err := auth.CanUserAccessAlbum(context.Background(), owner, album, nil)
+	require.NoError(t, err)
+	assert.True(t, perms.CanEdit)
+	assert.True(t, perms.CanDelete)
+}
+
+func TestCanUserAccessAlbum_NilUser(t *testing.T) {
+	album := newTestAlbum("albumowner")
+
+	_, err := auth.CanUserAccessAlbum(context.Background(), nil, album, nil)
+	assert.ErrorIs(t, err, auth.ErrMustAuthenticate)
+}
+
+func TestCanUserAccessAlbum_NonOwnerNoShare(t *testing.T) {
+	other := &user_model.User{Username: "otheruser", UserPerms: user_model.UserPermissionBasic}
+	album := newTestAlbum("albumowner")
+
+	_, err := auth.CanUserAccessAlbum(context.Background(), other, album, nil)
+	assert.ErrorIs(t, err, auth.ErrAlbumAccessNotPermitted)
+}
+
+func TestCanUserAccessAlbum_ShareOfOtherAlbum(t *testing.T) {
+	owner := &user_model.User{Username: "albumowner", UserPerms: user_model.UserPermissionBasic}
+	other := &user_model.User{Username: "otheruser", UserPerms: user_model.UserPermissionBasic}
+	album := newTestAlbum(owner.Username)
+
+	share, err := share_model.NewAlbumShare(context.Background(), primitive.NewObjectID().Hex(), owner, []*user_model.User{other}, false, false)
+	require.NoError(t, err)
+
+	_, err = auth.CanUserAccessAlbum(context.Background(), other, album, share)
+	assert.ErrorIs(t, err, auth.ErrAlbumAccessNotPermitted)
+}
+
+func TestCanUserAccessAlbum_SharedUser(t *testing.T) {
+	owner := &user_model.User{Username: "albumowner", UserPerms: user_model.UserPermissionBasic}
+	other := &user_model.User{Username: "otheruser", UserPerms: user_model.UserPermissionBasic}
+	stranger := &user_model.User{Username: "stranger", UserPerms: user_model.UserPermissionBasic}
+	album := newTestAlbum(owner.Username)
+
+	share, err := share_model.NewAlbumShare(context.Background(), album.ID(), owner, []*user_model.User{other}, false, false)
+	require.NoError(t, err)
+
+	perms, err := auth.CanUserAccessAlbum(context.Background(), other, album, share, share_model.SharePermissionView)
+	require.NoError(t, err)
+	assert.True(t, perms.CanView)
+	assert.False(t, perms.CanEdit)
+
+	_, err = auth.CanUserAccessAlbum(context.Background(), other, album, share, share_model.SharePermissionEdit)
+	assert.ErrorIs(t, err, auth.ErrFileAccessNotPermitted)
+
+	_, err = auth.CanUserAccessAlbum(context.Background(), stranger, album, share)
+	assert.Error(t, err)
+}
+
+func TestCanUserAccessAlbum_PublicShare(t *testing.T) {
+	owner := &user_model.User{Username: "albumowner", UserPerms: user_model.UserPermissionBasic}
+	album := newTestAlbum(owner.Username)
+
+	share, err := share_model.NewAlbumShare(context.Background(), album.ID(), owner, nil, true, true)
+	require.NoError(t, err)
+
+	share.Permissions[user_model.PublicUserName] = share_model.NewPermissions()
+
+	publicUser := &user_model.User{Username: user_model.PublicUserName}
+
+	_, err = auth.CanUserAccessAlbum(context.Background(), publicUser, album, share, share_model.SharePermissionViewMedia)
+	require.NoError(t, err)
+
+	share.Expires = time.Now().Add(-time.Minute)
+
+	_, err = auth.CanUserAccessAlbum(context.Background(), publicUser, album, share)
+	assert.ErrorIs(t, err, share_model.ErrShareExpired)
+}
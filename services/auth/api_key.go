@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	auth_model "github.com/ethanrous/weblens/models/auth"
+	file_model "github.com/ethanrous/weblens/models/file"
+	user_model "github.com/ethanrous/weblens/models/usermodel"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlog"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+)
+
+// ErrAPIKeyScope is returned when an api key is used for a request its scopes do not allow.
+var ErrAPIKeyScope = wlerrors.Statusf(http.StatusForbidden, "api key does not allow this request")
+
+// ErrAPIKeyFolder is returned when an api key limited to a folder is used to access a file outside of it.
+var ErrAPIKeyFolder = wlerrors.Statusf(http.StatusForbidden, "api key does not allow access to this file")
+
+const apiPrefix = "/api/v1"
+
+// scopeRoute maps the routes under a path prefix to the scopes needed to read and to change them.
+type scopeRoute struct {
+	prefix     string
+	readScope  auth_model.Scope
+	writeScope auth_model.Scope
+}
+
+// apiScopeRoutes are checked in order, so more specific prefixes must come first. Routes not listed here, such as
+// users, api keys and tower management, need ScopeAdmin. A scope of "" means no scope is needed.
+var apiScopeRoutes = []scopeRoute{
+	{"/health", "", ""},
+	{"/info", "", ""},
+	{"/users/auth", "", ""},
+	{"/ws", auth_model.ScopeFilesRead, auth_model.ScopeFilesRead},
+	// Finding duplicates only reads files, but is a POST to fit all of its params
+	{"/files/duplicates/resolve", auth_model.ScopeFilesWrite, auth_model.ScopeFilesWrite},
+	{"/files/duplicates", auth_model.ScopeFilesRead, auth_model.ScopeFilesRead},
+	{"/files", auth_model.ScopeFilesRead, auth_model.ScopeFilesWrite},
+	{"/folder", auth_model.ScopeFilesRead, auth_model.ScopeFilesWrite},
+	{"/upload", auth_model.ScopeFilesWrite, auth_model.ScopeFilesWrite},
+	// Creating a takeout zip only reads the files in it
+	{"/takeout", auth_model.ScopeFilesRead, auth_model.ScopeFilesRead},
+	{"/tags", auth_model.ScopeFilesRead, auth_model.ScopeFilesWrite},
+	{"/share", auth_model.ScopeFilesRead, auth_model.ScopeFilesWrite},
+	{"/media", auth_model.ScopeMediaRead, auth_model.ScopeMediaWrite},
+	{"/albums", auth_model.ScopeMediaRead, auth_model.ScopeMediaWrite},
+}
+
+// webdavScopeRoutes are the scopes of the WebDAV trees, which are served outside of the api.
+var webdavScopeRoutes = []scopeRoute{
+	{"/webdav", auth_model.ScopeFilesRead, auth_model.ScopeFilesWrite},
+	{"/webdav-share", auth_model.ScopeFilesRead, auth_model.ScopeFilesWrite},
+}
+
+// RequiredScope returns the scope an api key needs to make a request with method to path. ok is false if the request
+// needs no scope at all.
+func RequiredScope(method, path string) (scope auth_model.Scope, ok bool) {
+	routes := webdavScopeRoutes
+
+	if apiPath, isAPI := strings.CutPrefix(path, apiPrefix); isAPI {
+		routes = apiScopeRoutes
+		path = apiPath
+	}
+
+	for _, route := range routes {
+		if path != route.prefix && !strings.HasPrefix(path, route.prefix+"/") {
+			continue
+		}
+
+		scope = route.writeScope
+		if isReadMethod(method) {
+			scope = route.readScope
+		}
+
+		return scope, scope != ""
+	}
+
+	return auth_model.ScopeAdmin, true
+}
+
+// CheckAPIKeyScope returns ErrAPIKeyScope if key may not be used for a request with method to path.
+func CheckAPIKeyScope(key *auth_model.Token, method, path string) error {
+	scope, ok := RequiredScope(method, path)
+	if !ok || key.HasScope(scope) {
+		return nil
+	}
+
+	return wlerrors.Errorf("%w: %s %s needs the [%s] scope", ErrAPIKeyScope, method, path, scope)
+}
+
+// CheckAPIKeyAdmin returns ErrAPIKeyScope if the request was made with an api key that may not be used for admin
+// endpoints. Requests made with a session are always allowed, whether the user is an admin is checked separately.
+func CheckAPIKeyAdmin(ctx context_service.RequestContext) error {
+	if ctx.APIKey == nil || ctx.APIKey.HasScope(auth_model.ScopeAdmin) {
+		return nil
+	}
+
+	return wlerrors.Errorf("%w: the [%s] scope is needed", ErrAPIKeyScope, auth_model.ScopeAdmin)
+}
+
+// checkAPIKeyFolder returns ErrAPIKeyFolder if the request in ctx was made by user with an api key limited to a
+// folder, and file is not inside that folder.
+func checkAPIKeyFolder(ctx context.Context, user *user_model.User, file *file_model.WeblensFileImpl) error {
+	reqCtx, ok := context_service.ReqFromContext(ctx)
+	if !ok || reqCtx.APIKey == nil || reqCtx.APIKey.FolderID == "" || reqCtx.APIKey.Owner != user.GetUsername() {
+		return nil
+	}
+
+	for f := file; f != nil; f = f.GetParent() {
+		if f.ID() == reqCtx.APIKey.FolderID {
+			return nil
+		}
+	}
+
+	return wlerrors.Errorf("%w: [%s] is outside of folder [%s]", ErrAPIKeyFolder, file.ID(), reqCtx.APIKey.FolderID)
+}
+
+// getAPIKey finds the api key with the given value, and checks that it has not expired.
+func getAPIKey(ctx context.Context, tokenBytes [32]byte) (*auth_model.Token, error) {
+	token, err := auth_model.GetToken(ctx, tokenBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.IsExpired() {
+		return nil, wlerrors.WithStack(auth_model.ErrTokenExpired)
+	}
+
+	// Failing to record the use of a key should not fail the request made with it
+	if err := token.Touch(ctx); err != nil {
+		wlog.FromContext(ctx).Warn().Err(err).Msgf("Failed to update last use of api key [%s]", token.ID.Hex())
+	}
+
+	return &token, nil
+}
+
+func isReadMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, "PROPFIND":
+		return true
+	default:
+		return false
+	}
+}
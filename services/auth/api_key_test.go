@@ -0,0 +1,58 @@
+package auth_test
+
+import (
+	"net/http"
+	"testing"
+
+	auth_model "github.com/ethanrous/weblens/models/auth"
+	"github.com/ethanrous/weblens/services/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequiredScope(t *testing.T) {
+	tests := []struct {
+		method   string
+		path     string
+		expected auth_model.Scope
+		needed   bool
+	}{
+		{http.MethodGet, "/api/v1/health", "", false},
+		{http.MethodPost, "/api/v1/users/auth", "", false},
+		{http.MethodGet, "/api/v1/files/abc", auth_model.ScopeFilesRead, true},
+		{http.MethodPatch, "/api/v1/files", auth_model.ScopeFilesWrite, true},
+		{http.MethodPost, "/api/v1/files/duplicates", auth_model.ScopeFilesRead, true},
+		{http.MethodPost, "/api/v1/files/duplicates/resolve", auth_model.ScopeFilesWrite, true},
+		{http.MethodGet, "/api/v1/upload/abc", auth_model.ScopeFilesWrite, true},
+		{http.MethodGet, "/api/v1/media/abc/info", auth_model.ScopeMediaRead, true},
+		{http.MethodPatch, "/api/v1/media/abc/liked", auth_model.ScopeMediaWrite, true},
+		{http.MethodGet, "/api/v1/users/me", auth_model.ScopeAdmin, true},
+		{http.MethodPost, "/api/v1/keys", auth_model.ScopeAdmin, true},
+		{http.MethodGet, "/api/v1/tower", auth_model.ScopeAdmin, true},
+		{"PROPFIND", "/webdav/photos", auth_model.ScopeFilesRead, true},
+		{"MKCOL", "/webdav/photos", auth_model.ScopeFilesWrite, true},
+		{http.MethodGet, "/webdav-share/abc/file.txt", auth_model.ScopeFilesRead, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			scope, needed := auth.RequiredScope(tt.method, tt.path)
+			assert.Equal(t, tt.needed, needed)
+
+			if tt.needed {
+				assert.Equal(t, tt.expected, scope)
+			}
+		})
+	}
+}
+
+func TestCheckAPIKeyScope(t *testing.T) {
+	readOnly := &auth_model.Token{Scopes: []auth_model.Scope{auth_model.ScopeFilesRead}}
+
+	assert.NoError(t, auth.CheckAPIKeyScope(readOnly, http.MethodGet, "/api/v1/files/abc"))
+	assert.ErrorIs(t, auth.CheckAPIKeyScope(readOnly, http.MethodDelete, "/api/v1/files"), auth.ErrAPIKeyScope)
+	assert.ErrorIs(t, auth.CheckAPIKeyScope(readOnly, http.MethodGet, "/api/v1/media/abc/info"), auth.ErrAPIKeyScope)
+	assert.ErrorIs(t, auth.CheckAPIKeyScope(readOnly, http.MethodGet, "/api/v1/flags"), auth.ErrAPIKeyScope)
+
+	unscoped := &auth_model.Token{}
+	assert.NoError(t, auth.CheckAPIKeyScope(unscoped, http.MethodGet, "/api/v1/flags"))
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// attemptLimiter counts attempts at guessing a secret, such as a password, per key. Once a key has used up its attempts
+// within the window, no more are accepted until the window has passed.
+type attemptLimiter struct {
+	attempts map[string]*attemptWindow
+	max      int
+	window   time.Duration
+	mu       sync.Mutex
+}
+
+// attemptLimiterSweepSize is how many keys a limiter tracks before it drops those whose window has passed.
+const attemptLimiterSweepSize = 1024
+
+type attemptWindow struct {
+	start time.Time
+	count int
+}
+
+func newAttemptLimiter(maxAttempts int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		attempts: map[string]*attemptWindow{},
+		max:      maxAttempts,
+		window:   window,
+	}
+}
+
+// take counts an attempt for key, and returns false if key has no attempts left. The attempt is counted before the
+// secret is checked, so concurrent attempts can never get past the limit between checking it and recording a failure.
+func (l *attemptLimiter) take(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	attempts, ok := l.attempts[key]
+	if !ok && len(l.attempts) >= attemptLimiterSweepSize {
+		l.sweep()
+	}
+
+	if !ok || time.Since(attempts.start) > l.window {
+		attempts = &attemptWindow{start: time.Now()}
+		l.attempts[key] = attempts
+	}
+
+	if attempts.count >= l.max {
+		return false
+	}
+
+	attempts.count++
+
+	return true
+}
+
+// reset forgets the attempts of key, once it has been used successfully.
+func (l *attemptLimiter) reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.attempts, key)
+}
+
+func (l *attemptLimiter) sweep() {
+	for key, attempts := range l.attempts {
+		if time.Since(attempts.start) > l.window {
+			delete(l.attempts, key)
+		}
+	}
+}
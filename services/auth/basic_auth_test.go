@@ -0,0 +1,98 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	auth_model "github.com/ethanrous/weblens/models/auth"
+	"github.com/ethanrous/weblens/models/db"
+	user_model "github.com/ethanrous/weblens/models/usermodel"
+	"github.com/ethanrous/weblens/modules/cryptography"
+	"github.com/ethanrous/weblens/services/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const basicAuthPassword = "TestP@ssw0rd123"
+
+func newBasicAuthUser(t *testing.T, ctx context.Context, username string) (*user_model.User, string) {
+	t.Helper()
+
+	u := &user_model.User{Username: username, Password: basicAuthPassword, UserPerms: user_model.UserPermissionBasic, Activated: true}
+	require.NoError(t, user_model.SaveUser(ctx, u))
+
+	token, err := auth_model.GenerateNewToken(ctx, "webdav", username, username)
+	require.NoError(t, err)
+
+	return u, base64.StdEncoding.EncodeToString(token.Token[:])
+}
+
+func TestGetUserFromBasicAuth(t *testing.T) {
+	ctx := db.SetupTestDB(t, user_model.UserCollectionKey)
+	ctx = context.WithValue(ctx, cryptography.BcryptDifficultyCtxKey, bcrypt.MinCost)
+
+	_, apiKey := newBasicAuthUser(t, ctx, "basicuser")
+
+	t.Run("accepts account password", func(t *testing.T) {
+		u, token, err := auth.GetUserFromBasicAuth(ctx, "basicuser", basicAuthPassword, "10.0.0.1")
+		require.NoError(t, err)
+		assert.Equal(t, "basicuser", u.Username)
+		assert.Nil(t, token)
+	})
+
+	t.Run("accepts api key", func(t *testing.T) {
+		u, token, err := auth.GetUserFromBasicAuth(ctx, "basicuser", apiKey, "10.0.0.1")
+		require.NoError(t, err)
+		assert.Equal(t, "basicuser", u.Username)
+		assert.NotNil(t, token)
+	})
+
+	t.Run("rejects wrong password", func(t *testing.T) {
+		_, _, err := auth.GetUserFromBasicAuth(ctx, "basicuser", "wrong", "10.0.0.2")
+		assert.ErrorIs(t, err, auth.ErrBadCredentials)
+	})
+
+	t.Run("limits password attempts per address", func(t *testing.T) {
+		for range 5 {
+			_, _, err := auth.GetUserFromBasicAuth(ctx, "basicuser", "wrong", "10.0.0.3")
+			require.ErrorIs(t, err, auth.ErrBadCredentials)
+		}
+
+		_, _, err := auth.GetUserFromBasicAuth(ctx, "basicuser", basicAuthPassword, "10.0.0.3")
+		assert.ErrorIs(t, err, auth.ErrTooManyLoginAttempts)
+
+		// API keys are not limited, nor are other addresses
+		_, _, err = auth.GetUserFromBasicAuth(ctx, "basicuser", apiKey, "10.0.0.3")
+		assert.NoError(t, err)
+
+		_, _, err = auth.GetUserFromBasicAuth(ctx, "basicuser", basicAuthPassword, "10.0.0.4")
+		assert.NoError(t, err)
+	})
+}
+
+func TestGetUserFromBasicAuth_TOTP(t *testing.T) {
+	ctx := db.SetupTestDB(t, user_model.UserCollectionKey)
+	ctx = context.WithValue(ctx, cryptography.BcryptDifficultyCtxKey, bcrypt.MinCost)
+
+	u, apiKey := newBasicAuthUser(t, ctx, "totpuser")
+
+	secret, err := cryptography.GenerateTOTPSecret()
+	require.NoError(t, err)
+
+	require.NoError(t, u.SetPendingTOTPSecret(ctx, secret))
+	require.NoError(t, u.EnableTOTP(ctx, 0, nil))
+
+	t.Run("rejects account password", func(t *testing.T) {
+		_, _, err := auth.GetUserFromBasicAuth(ctx, "totpuser", basicAuthPassword, "10.0.1.1")
+		assert.ErrorIs(t, err, auth.ErrBasicAuthRequiresAPIKey)
+	})
+
+	t.Run("accepts api key", func(t *testing.T) {
+		got, token, err := auth.GetUserFromBasicAuth(ctx, "totpuser", apiKey, "10.0.1.1")
+		require.NoError(t, err)
+		assert.Equal(t, "totpuser", got.Username)
+		assert.NotNil(t, token)
+	})
+}
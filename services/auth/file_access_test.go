@@ -2,6 +2,7 @@ package auth_test
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"sync"
@@ -50,6 +51,10 @@ func (s *stubFileService) CreateFile(_ context.Context, _ *file_model.WeblensFil
 	panic("not implemented")
 }
 
+func (s *stubFileService) CreateFileFromPath(_ context.Context, _ *file_model.WeblensFileImpl, _, _ string) (*file_model.WeblensFileImpl, error) {
+	panic("not implemented")
+}
+
 func (s *stubFileService) CreateFolder(_ context.Context, _ *file_model.WeblensFileImpl, _ string) (*file_model.WeblensFileImpl, error) {
 	panic("not implemented")
 }
@@ -78,14 +83,26 @@ func (s *stubFileService) MoveFiles(_ context.Context, _ []*file_model.WeblensFi
 	panic("not implemented")
 }
 
+func (s *stubFileService) CopyFiles(_ context.Context, _ []*file_model.WeblensFileImpl, _ *file_model.WeblensFileImpl, _ func(*file_model.WeblensFileImpl)) ([]*file_model.WeblensFileImpl, error) {
+	panic("not implemented")
+}
+
 func (s *stubFileService) RenameFile(_ context.Context, _ *file_model.WeblensFileImpl, _ string) error {
 	panic("not implemented")
 }
 
+func (s *stubFileService) ReplaceFileContent(_ context.Context, _ *file_model.WeblensFileImpl, _ string) error {
+	panic("not implemented")
+}
+
 func (s *stubFileService) ReturnFilesFromTrash(_ context.Context, _ []*file_model.WeblensFileImpl) error {
 	panic("not implemented")
 }
 
+func (s *stubFileService) TrashFiles(_ context.Context, _ ...*file_model.WeblensFileImpl) error {
+	panic("not implemented")
+}
+
 func (s *stubFileService) DeleteFiles(_ context.Context, _ ...*file_model.WeblensFileImpl) error {
 	panic("not implemented")
 }
@@ -94,6 +111,10 @@ func (s *stubFileService) RestoreFiles(_ context.Context, _ []string, _ *file_mo
 	panic("not implemented")
 }
 
+func (s *stubFileService) RestoreFilesFromBackup(_ context.Context, _ []*file_model.WeblensFileImpl, _ *file_model.WeblensFileImpl, _ func(string) (io.ReadCloser, error)) error {
+	panic("not implemented")
+}
+
 func (s *stubFileService) GetMediaCacheByFilename(_ context.Context, _ string) (*file_model.WeblensFileImpl, error) {
 	panic("not implemented")
 }
@@ -0,0 +1,349 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethanrous/weblens/models/db"
+	user_model "github.com/ethanrous/weblens/models/usermodel"
+	"github.com/ethanrous/weblens/modules/config"
+	"github.com/ethanrous/weblens/modules/cryptography"
+	"github.com/ethanrous/weblens/modules/oidc"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+)
+
+// OIDCCallbackPath is the route the OpenID Connect provider sends users back to after they log in there.
+const OIDCCallbackPath = "/api/v1/users/auth/oidc/callback"
+
+// ErrOIDCNotConfigured is returned when single sign-on is used on a server that has no OpenID Connect provider.
+var ErrOIDCNotConfigured = wlerrors.Statusf(http.StatusNotFound, "single sign-on is not configured")
+
+// ErrOIDCUserNotFound is returned when a user logs in through the OpenID Connect provider for the first time, and
+// automatic provisioning is turned off.
+var ErrOIDCUserNotFound = wlerrors.Statusf(http.StatusForbidden, "no user is linked to this single sign-on account")
+
+// ErrOIDCUsernameTaken is returned when the username claimed by a provider account belongs to a user already linked
+// to another provider account.
+var ErrOIDCUsernameTaken = wlerrors.Statusf(http.StatusConflict, "username is linked to another single sign-on account")
+
+var invalidUsernameChars = regexp.MustCompile(`[^a-zA-Z0-9_\-]+`)
+
+const maxUsernameLength = 25
+
+var oidcProvider struct {
+	provider *oidc.Provider
+	config   oidc.Config
+	mu       sync.Mutex
+}
+
+// IsOIDCEnabled returns true if the server is configured to log users in through an OpenID Connect provider.
+func IsOIDCEnabled() bool {
+	cnf := config.GetConfig()
+
+	return cnf.OIDCIssuer != "" && cnf.OIDCClientID != ""
+}
+
+// GetOIDCProvider returns the OpenID Connect provider from the server config. Its discovery document is fetched the
+// first time it is needed, and again if the config changes.
+func GetOIDCProvider(ctx context.Context) (*oidc.Provider, error) {
+	if !IsOIDCEnabled() {
+		return nil, wlerrors.WithStack(ErrOIDCNotConfigured)
+	}
+
+	cnf := getOIDCConfig()
+
+	oidcProvider.mu.Lock()
+	defer oidcProvider.mu.Unlock()
+
+	if oidcProvider.provider != nil && oidcConfigEqual(oidcProvider.config, cnf) {
+		return oidcProvider.provider, nil
+	}
+
+	provider, err := oidc.Discover(ctx, cnf)
+	if err != nil {
+		return nil, err
+	}
+
+	oidcProvider.provider = provider
+	oidcProvider.config = cnf
+
+	return provider, nil
+}
+
+// BeginOIDCLogin starts logging a user in through the OpenID Connect provider. The secrets of the login are kept in a
+// cookie until the provider sends the user back, and the URL to send the user to the provider at is returned.
+// redirect is where the user is sent once they are logged in, and must be a path on this server.
+func BeginOIDCLogin(ctx context_service.RequestContext, redirect string) (string, error) {
+	provider, err := GetOIDCProvider(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if !isLocalRedirect(redirect) {
+		redirect = "/"
+	}
+
+	state, err := cryptography.RandomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := cryptography.RandomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	verifier, err := oidc.NewPKCEVerifier()
+	if err != nil {
+		return "", err
+	}
+
+	token, expires, err := cryptography.GenerateOIDCStateJWT(state, nonce, verifier, redirect)
+	if err != nil {
+		return "", err
+	}
+
+	ctx.AddHeader("Set-Cookie", generateOIDCStateCookie(token, expires, ctx.Req.TLS != nil))
+
+	return provider.AuthCodeURL(state, nonce, oidc.PKCEChallenge(verifier)), nil
+}
+
+// FinishOIDCLogin completes a login started by BeginOIDCLogin, once the provider has sent the user back with an
+// authorization code, and gives the user a session like a password login would. It returns the user who logged in, and
+// the path they asked to be sent to.
+func FinishOIDCLogin(ctx context_service.RequestContext, state, code string) (*user_model.User, string, error) {
+	stateToken, err := ctx.GetCookie(cryptography.OIDCStateCookie)
+	if err != nil {
+		return nil, "", wlerrors.Statusf(http.StatusBadRequest, "single sign-on login was not started from this browser")
+	}
+
+	loginState, err := cryptography.GetOIDCStateFromToken(stateToken)
+	if err != nil {
+		return nil, "", wlerrors.Statusf(http.StatusBadRequest, "invalid single sign-on login: %s", err)
+	}
+
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(loginState.State)) != 1 {
+		return nil, "", wlerrors.Statusf(http.StatusBadRequest, "single sign-on state does not match")
+	}
+
+	provider, err := GetOIDCProvider(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tokens, err := provider.Exchange(ctx, code, loginState.Verifier)
+	if err != nil {
+		return nil, "", wlerrors.Statusf(http.StatusUnauthorized, "failed to exchange single sign-on code: %s", err)
+	}
+
+	idToken, err := provider.VerifyIDToken(ctx, tokens.IDToken, loginState.Nonce)
+	if err != nil {
+		return nil, "", err
+	}
+
+	u, err := ResolveOIDCUser(ctx, idToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = SetSessionToken(ctx.WithRequester(u))
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The login cookie is only good for one login
+	ctx.AddHeader("Set-Cookie", generateOIDCStateCookie("", time.Unix(0, 0), ctx.Req.TLS != nil))
+
+	return u, loginState.Redirect, nil
+}
+
+// ResolveOIDCUser finds the user an ID token from the OpenID Connect provider belongs to. Users are matched by their
+// account at the provider, or the first time they log in, by username. If no user matches, one is created when
+// automatic provisioning is turned on. Users who log in this way have already passed any second factor the provider
+// requires, so TOTP is not checked again.
+func ResolveOIDCUser(ctx context_service.RequestContext, idToken *oidc.IDToken) (*user_model.User, error) {
+	cnf := config.GetConfig()
+
+	u, err := user_model.GetUserByOIDCSubject(ctx, cnf.OIDCIssuer, idToken.Subject)
+	if err != nil && !db.IsNotFound(err) {
+		return nil, err
+	}
+
+	if u == nil {
+		u, err = linkOIDCUser(ctx, cnf, idToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = syncOIDCAdmin(ctx, cnf, u, idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if !u.IsActive() {
+		return nil, wlerrors.Statusf(http.StatusForbidden, "user [%s] is not activated", u.GetUsername())
+	}
+
+	return u, nil
+}
+
+// OIDCUsername derives a valid Weblens username from the value of the username claim of an ID token. Email addresses
+// are cut at the "@", and characters not allowed in usernames are replaced.
+func OIDCUsername(claim string) string {
+	username, _, _ := strings.Cut(strings.TrimSpace(claim), "@")
+	username = invalidUsernameChars.ReplaceAllString(username, "_")
+
+	if len(username) > maxUsernameLength {
+		username = username[:maxUsernameLength]
+	}
+
+	return username
+}
+
+// linkOIDCUser links a provider account to the user with the username it claims, creating that user if there is none.
+func linkOIDCUser(ctx context_service.RequestContext, cnf config.Provider, idToken *oidc.IDToken) (*user_model.User, error) {
+	username := OIDCUsername(idToken.StringClaim(cnf.OIDCUsernameClaim))
+	if username == "" {
+		return nil, wlerrors.Statusf(http.StatusBadRequest, "id token has no [%s] claim", cnf.OIDCUsernameClaim)
+	}
+
+	u, err := user_model.GetUserByUsername(ctx, username)
+	if err != nil && !db.IsNotFound(err) {
+		return nil, err
+	}
+
+	if u == nil {
+		if !cnf.OIDCAutoProvision {
+			return nil, wlerrors.WithStack(ErrOIDCUserNotFound)
+		}
+
+		u, err = provisionOIDCUser(ctx, username, idToken)
+		if err != nil {
+			return nil, err
+		}
+	} else if u.HasOIDCSubject() {
+		return nil, wlerrors.WithStack(ErrOIDCUsernameTaken)
+	}
+
+	ctx.Log().Info().Msgf("Linking user [%s] to single sign-on account [%s]", u.GetUsername(), idToken.Subject)
+
+	err = u.LinkOIDCSubject(ctx, cnf.OIDCIssuer, idToken.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// provisionOIDCUser creates a new user for a provider account. The user is given a random password, so they can only
+// log in through the provider until an admin sets one for them.
+func provisionOIDCUser(ctx context_service.RequestContext, username string, idToken *oidc.IDToken) (*user_model.User, error) {
+	password, err := cryptography.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	displayName := idToken.StringClaim("name")
+	if displayName == "" {
+		displayName = username
+	}
+
+	u := &user_model.User{
+		Username:    username,
+		Password:    password + "0", // Passwords must contain a digit
+		DisplayName: displayName,
+		Activated:   true,
+		UserPerms:   user_model.UserPermissionBasic,
+	}
+
+	if err := user_model.ValidateUser(ctx, u); err != nil {
+		return nil, wlerrors.Statusf(http.StatusBadRequest, "cannot create user [%s]: %s", username, err)
+	}
+
+	err = ctx.GetFileService().CreateUserHome(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	err = user_model.SaveUser(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.Log().Info().Msgf("Created user [%s] for single sign-on account [%s]", username, idToken.Subject)
+
+	return u, nil
+}
+
+// syncOIDCAdmin makes u an admin, or a basic user, to match the admin claim of their ID token. Nothing is changed if
+// no admin claim is configured, and the server owner is never demoted.
+func syncOIDCAdmin(ctx context_service.RequestContext, cnf config.Provider, u *user_model.User, idToken *oidc.IDToken) error {
+	if cnf.OIDCAdminClaim == "" || cnf.OIDCAdminValue == "" || u.IsOwner() {
+		return nil
+	}
+
+	perms := user_model.UserPermissionBasic
+	if idToken.ClaimContains(cnf.OIDCAdminClaim, cnf.OIDCAdminValue) {
+		perms = user_model.UserPermissionAdmin
+	}
+
+	if u.UserPerms == perms {
+		return nil
+	}
+
+	err := u.UpdatePermissionLevel(ctx, perms)
+	if err != nil {
+		return err
+	}
+
+	u.UserPerms = perms
+
+	return nil
+}
+
+// isLocalRedirect returns true if redirect is a path on this server, so users can't be sent elsewhere after logging in.
+func isLocalRedirect(redirect string) bool {
+	return strings.HasPrefix(redirect, "/") && !strings.HasPrefix(redirect, "//") && !strings.Contains(redirect, "\\")
+}
+
+func generateOIDCStateCookie(token string, expires time.Time, secure bool) string {
+	return (&http.Cookie{
+		Name:     cryptography.OIDCStateCookie,
+		Value:    token,
+		Path:     "/api/v1/users/auth/oidc",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	}).String()
+}
+
+func getOIDCConfig() oidc.Config {
+	cnf := config.GetConfig()
+
+	redirectURL := cnf.OIDCRedirectURL
+	if redirectURL == "" {
+		redirectURL = strings.TrimSuffix(cnf.ProxyAddress, "/") + OIDCCallbackPath
+	}
+
+	return oidc.Config{
+		Issuer:       cnf.OIDCIssuer,
+		ClientID:     cnf.OIDCClientID,
+		ClientSecret: cnf.OIDCClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       cnf.OIDCScopes,
+	}
+}
+
+func oidcConfigEqual(a, b oidc.Config) bool {
+	return a.Issuer == b.Issuer && a.ClientID == b.ClientID && a.ClientSecret == b.ClientSecret &&
+		a.RedirectURL == b.RedirectURL && slices.Equal(a.Scopes, b.Scopes)
+}
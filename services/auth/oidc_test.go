@@ -0,0 +1,28 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/ethanrous/weblens/services/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOIDCUsername(t *testing.T) {
+	tests := []struct {
+		claim    string
+		expected string
+	}{
+		{"alice", "alice"},
+		{"alice@example.com", "alice"},
+		{" bob.smith ", "bob_smith"},
+		{"jörg", "j_rg"},
+		{"a-very-long-username-that-goes-on-and-on", "a-very-long-username-that"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.claim, func(t *testing.T) {
+			assert.Equal(t, tt.expected, auth.OIDCUsername(tt.claim))
+		})
+	}
+}
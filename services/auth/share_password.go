@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	share_model "github.com/ethanrous/weblens/models/share"
+	user_model "github.com/ethanrous/weblens/models/usermodel"
+	"github.com/ethanrous/weblens/modules/cryptography"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+)
+
+// ErrSharePasswordRequired is returned when a password protected share is accessed before it has been unlocked.
+var ErrSharePasswordRequired = wlerrors.Statusf(http.StatusUnauthorized, "share is password protected")
+
+// ErrSharePasswordIncorrect is returned when an attempt to unlock a share uses the wrong password.
+var ErrSharePasswordIncorrect = wlerrors.Statusf(http.StatusUnauthorized, "incorrect share password")
+
+// ErrTooManyUnlockAttempts is returned when a share has seen too many failed unlock attempts in a short time.
+var ErrTooManyUnlockAttempts = wlerrors.Statusf(http.StatusTooManyRequests, "too many failed attempts to unlock share, try again later")
+
+const (
+	// maxShareUnlockFailures is how many wrong passwords a share accepts within shareUnlockWindow before it stops
+	// accepting attempts at all until the window has passed.
+	maxShareUnlockFailures = 5
+	shareUnlockWindow      = time.Minute * 15
+)
+
+// Unlock attempts are limited per share rather than per client, so that guessing cannot be spread out across many
+// addresses.
+var shareUnlockAttempts = newAttemptLimiter(maxShareUnlockFailures, shareUnlockWindow)
+
+// UnlockShare checks the password for a password protected share, and on success sets a short-lived cookie
+// that grants the requester access to the share.
+func UnlockShare(ctx context_service.RequestContext, share *share_model.FileShare, password string) error {
+	shareID := share.ShareID.Hex()
+
+	if !shareUnlockAttempts.take(shareID) {
+		return wlerrors.WithStack(ErrTooManyUnlockAttempts)
+	}
+
+	if !share.CheckPassword(password) {
+		return wlerrors.WithStack(ErrSharePasswordIncorrect)
+	}
+
+	shareUnlockAttempts.reset(shareID)
+
+	token, expires, err := cryptography.GenerateShareJWT(shareID, share.PasswordHash)
+	if err != nil {
+		return err
+	}
+
+	cookie := (&http.Cookie{
+		Name:     cryptography.ShareTokenCookieName(shareID),
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   ctx.Req.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}).String()
+
+	ctx.AddHeader("Set-Cookie", cookie)
+
+	return nil
+}
+
+// CheckShareUnlocked returns ErrSharePasswordRequired if the share is password protected, and the user would be
+// relying on the share's public access without having unlocked it. The share owner and users the share was
+// explicitly shared with never need the password.
+func CheckShareUnlocked(ctx context.Context, user *user_model.User, share *share_model.FileShare) error {
+	if share == nil || !share.HasPassword() {
+		return nil
+	}
+
+	// The public user is given permissions on every public share, so it must not count as an explicit accessor
+	if user != nil && !user.IsPublic() && (user.GetUsername() == share.GetOwner() || share.GetUserPermissions(user.GetUsername()) != nil) {
+		return nil
+	}
+
+	reqCtx, ok := context_service.ReqFromContext(ctx)
+	if !ok {
+		return wlerrors.WithStack(ErrSharePasswordRequired)
+	}
+
+	tokenStr, err := reqCtx.GetCookie(cryptography.ShareTokenCookieName(share.ShareID.Hex()))
+	if err != nil {
+		return wlerrors.WithStack(ErrSharePasswordRequired)
+	}
+
+	shareID, err := cryptography.GetShareIDFromToken(tokenStr, share.PasswordHash)
+	if err != nil || shareID != share.ShareID.Hex() {
+		return wlerrors.WithStack(ErrSharePasswordRequired)
+	}
+
+	return nil
+}
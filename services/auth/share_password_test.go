@@ -0,0 +1,138 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	file_model "github.com/ethanrous/weblens/models/file"
+	share_model "github.com/ethanrous/weblens/models/share"
+	user_model "github.com/ethanrous/weblens/models/usermodel"
+	"github.com/ethanrous/weblens/modules/cryptography"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	file_system "github.com/ethanrous/weblens/modules/wlfs"
+	"github.com/ethanrous/weblens/services/auth"
+	"github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newPasswordProtectedShare(t *testing.T, fileID string) *share_model.FileShare {
+	t.Helper()
+
+	ctx := context.WithValue(context.Background(), cryptography.BcryptDifficultyCtxKey, 4)
+
+	share := &share_model.FileShare{
+		ShareID: primitive.NewObjectID(),
+		FileID:  fileID,
+		Owner:   "testuser",
+		Public:  true,
+		Enabled: true,
+		Permissions: map[string]*share_model.Permissions{
+			user_model.PublicUserName: share_model.NewPermissions(),
+			"shareduser":              share_model.NewPermissions(),
+		},
+	}
+
+	// Set the hash directly, SetPassword would try to persist it since the share has an ID
+	hash, err := cryptography.HashUserPassword(ctx, "hunter2")
+	require.NoError(t, err)
+
+	share.PasswordHash = hash
+
+	return share
+}
+
+func TestCheckShareUnlocked(t *testing.T) {
+	ctx := context.Background()
+
+	share := newPasswordProtectedShare(t, primitive.NewObjectID().Hex())
+
+	t.Run("share without password is always unlocked", func(t *testing.T) {
+		open := &share_model.FileShare{ShareID: primitive.NewObjectID(), Public: true, Enabled: true}
+		assert.NoError(t, auth.CheckShareUnlocked(ctx, &user_model.User{Username: user_model.PublicUserName, UserPerms: user_model.UserPermissionPublic}, open))
+	})
+
+	t.Run("public user must unlock", func(t *testing.T) {
+		publicUser := &user_model.User{Username: user_model.PublicUserName, UserPerms: user_model.UserPermissionPublic}
+		assert.ErrorIs(t, auth.CheckShareUnlocked(ctx, publicUser, share), auth.ErrSharePasswordRequired)
+	})
+
+	t.Run("owner and accessors do not need the password", func(t *testing.T) {
+		owner := &user_model.User{Username: "testuser", UserPerms: user_model.UserPermissionBasic}
+		accessor := &user_model.User{Username: "shareduser", UserPerms: user_model.UserPermissionBasic}
+
+		assert.NoError(t, auth.CheckShareUnlocked(ctx, owner, share))
+		assert.NoError(t, auth.CheckShareUnlocked(ctx, accessor, share))
+	})
+
+	t.Run("other signed in users must unlock", func(t *testing.T) {
+		otherUser := &user_model.User{Username: "otheruser", UserPerms: user_model.UserPermissionBasic}
+		assert.ErrorIs(t, auth.CheckShareUnlocked(ctx, otherUser, share), auth.ErrSharePasswordRequired)
+	})
+}
+
+func TestUnlockShare_LimitsConcurrentAttempts(t *testing.T) {
+	share := newPasswordProtectedShare(t, primitive.NewObjectID().Hex())
+
+	newReqCtx := func() ctxservice.RequestContext {
+		return ctxservice.RequestContext{Req: httptest.NewRequest(http.MethodPost, "/", nil), W: httptest.NewRecorder()}
+	}
+
+	var (
+		wg                 sync.WaitGroup
+		mu                 sync.Mutex
+		incorrect, limited int
+	)
+
+	for range 20 {
+		wg.Go(func() {
+			err := auth.UnlockShare(newReqCtx(), share, "wrong")
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch {
+			case wlerrors.Is(err, auth.ErrSharePasswordIncorrect):
+				incorrect++
+			case wlerrors.Is(err, auth.ErrTooManyUnlockAttempts):
+				limited++
+			}
+		})
+	}
+
+	wg.Wait()
+
+	// Only as many passwords as the limit allows are ever checked, no matter how many attempts race each other
+	assert.Equal(t, 5, incorrect)
+	assert.Equal(t, 15, limited)
+
+	assert.ErrorIs(t, auth.UnlockShare(newReqCtx(), share, "hunter2"), auth.ErrTooManyUnlockAttempts)
+}
+
+func TestCanUserAccessFile_PasswordProtectedShare(t *testing.T) {
+	ctx := context.Background()
+
+	filepath := file_system.BuildFilePath(file_model.UsersTreeKey, "testuser/photos/image.jpg")
+	file := file_model.NewWeblensFile(file_model.NewFileOptions{
+		Path:       filepath,
+		MemOnly:    true,
+		GenerateID: true,
+	})
+
+	share := newPasswordProtectedShare(t, file.ID())
+
+	publicUser := &user_model.User{Username: user_model.PublicUserName, UserPerms: user_model.UserPermissionPublic}
+
+	_, err := auth.CanUserAccessFile(ctx, publicUser, file, share, share_model.SharePermissionView)
+	assert.ErrorIs(t, err, auth.ErrSharePasswordRequired)
+
+	accessor := &user_model.User{Username: "shareduser", UserPerms: user_model.UserPermissionBasic}
+
+	perms, err := auth.CanUserAccessFile(ctx, accessor, file, share, share_model.SharePermissionView)
+	require.NoError(t, err)
+	assert.True(t, perms.CanView)
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ethanrous/weblens/models/featureflags"
+	user_model "github.com/ethanrous/weblens/models/usermodel"
+	"github.com/ethanrous/weblens/modules/cryptography"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+)
+
+// TOTPIssuer is the name authenticator apps show next to the codes of Weblens accounts.
+const TOTPIssuer = "Weblens"
+
+// RecoveryCodeCount is how many recovery codes a user is given when they enable TOTP.
+const RecoveryCodeCount = 10
+
+// ErrInvalidTOTPCode is returned when a TOTP or recovery code is wrong, or was already used.
+var ErrInvalidTOTPCode = wlerrors.Statusf(http.StatusUnauthorized, "invalid two-factor code")
+
+// ErrTOTPAlreadyEnabled is returned when a user who already uses TOTP tries to enroll again.
+var ErrTOTPAlreadyEnabled = wlerrors.Statusf(http.StatusConflict, "two-factor authentication is already enabled")
+
+// ErrTOTPNotEnrolling is returned when a user confirms an enrollment they have not started.
+var ErrTOTPNotEnrolling = wlerrors.Statusf(http.StatusBadRequest, "two-factor authentication enrollment has not been started")
+
+// ErrTOTPNotEnabled is returned when a user who does not use TOTP tries to change it.
+var ErrTOTPNotEnabled = wlerrors.Statusf(http.StatusBadRequest, "two-factor authentication is not enabled")
+
+// ErrTOTPRequired is returned when an admin tries to turn off TOTP while the server requires admins to use it.
+var ErrTOTPRequired = wlerrors.Statusf(http.StatusForbidden, "two-factor authentication is required for this account")
+
+// IsTOTPRequired returns true if the server requires u to use TOTP to log in.
+func IsTOTPRequired(ctx context.Context, u *user_model.User) (bool, error) {
+	if !u.IsAdmin() {
+		return false, nil
+	}
+
+	flags, err := featureflags.GetFlags(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return flags.RequireAdminTOTP, nil
+}
+
+// BeginTOTPEnrollment generates a new TOTP secret for u, and returns it along with its provisioning URI. The secret is
+// not checked at login until the enrollment is confirmed with ConfirmTOTPEnrollment.
+func BeginTOTPEnrollment(ctx context.Context, u *user_model.User) (secret string, uri string, err error) {
+	if u.HasTOTP() {
+		return "", "", wlerrors.WithStack(ErrTOTPAlreadyEnabled)
+	}
+
+	secret, err = cryptography.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	err = u.SetPendingTOTPSecret(ctx, secret)
+	if err != nil {
+		return "", "", err
+	}
+
+	return secret, cryptography.TOTPProvisioningURI(TOTPIssuer, u.GetUsername(), secret), nil
+}
+
+// ConfirmTOTPEnrollment enables TOTP for u once they have entered a code from the secret given to them by
+// BeginTOTPEnrollment. The user's new recovery codes are returned, and are not stored anywhere in plain text.
+func ConfirmTOTPEnrollment(ctx context.Context, u *user_model.User, code string) ([]string, error) {
+	if u.HasTOTP() {
+		return nil, wlerrors.WithStack(ErrTOTPAlreadyEnabled)
+	}
+
+	if u.TOTPSecret == "" {
+		return nil, wlerrors.WithStack(ErrTOTPNotEnrolling)
+	}
+
+	step, ok := cryptography.VerifyTOTP(u.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, wlerrors.WithStack(ErrInvalidTOTPCode)
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.EnableTOTP(ctx, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of u, after checking a code from their authenticator app.
+func RegenerateRecoveryCodes(ctx context.Context, u *user_model.User, code string) ([]string, error) {
+	if !u.HasTOTP() {
+		return nil, wlerrors.WithStack(ErrTOTPNotEnabled)
+	}
+
+	err := VerifySecondFactor(ctx, u, code, "")
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.EnableTOTP(ctx, u.TOTPLastStep, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP turns off TOTP for u, after checking a TOTP or recovery code. Admins may not turn it off while the
+// server requires them to use it.
+func DisableTOTP(ctx context.Context, u *user_model.User, code, recoveryCode string) error {
+	if !u.HasTOTP() {
+		return wlerrors.WithStack(ErrTOTPNotEnabled)
+	}
+
+	required, err := IsTOTPRequired(ctx, u)
+	if err != nil {
+		return err
+	}
+
+	if required {
+		return wlerrors.WithStack(ErrTOTPRequired)
+	}
+
+	err = VerifySecondFactor(ctx, u, code, recoveryCode)
+	if err != nil {
+		return err
+	}
+
+	return u.DisableTOTP(ctx)
+}
+
+// VerifySecondFactor checks a TOTP code, or if code is empty, a recovery code, for u. Each TOTP code and each recovery
+// code is only accepted once.
+func VerifySecondFactor(ctx context.Context, u *user_model.User, code, recoveryCode string) error {
+	if !u.HasTOTP() {
+		return wlerrors.WithStack(ErrTOTPNotEnabled)
+	}
+
+	if code != "" {
+		step, ok := cryptography.VerifyTOTP(u.TOTPSecret, code, time.Now())
+		if !ok {
+			return wlerrors.WithStack(ErrInvalidTOTPCode)
+		}
+
+		ok, err := u.UseTOTPStep(ctx, step)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return wlerrors.Wrap(ErrInvalidTOTPCode, "code was already used")
+		}
+
+		return nil
+	}
+
+	if recoveryCode == "" {
+		return wlerrors.WithStack(ErrInvalidTOTPCode)
+	}
+
+	ok, err := u.UseRecoveryCode(ctx, cryptography.HashRecoveryCode(recoveryCode))
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return wlerrors.WithStack(ErrInvalidTOTPCode)
+	}
+
+	return nil
+}
+
+func newRecoveryCodes() (codes []string, hashes []string, err error) {
+	codes, err = cryptography.GenerateRecoveryCodes(RecoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes = make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, cryptography.HashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
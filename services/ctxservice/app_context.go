@@ -48,6 +48,10 @@ type AppContext struct {
 	// LocalTowerID is the id of the tower that the app is running on
 	LocalTowerID string
 
+	// localTowerID is shared by every copy of the context, so a change made with SetLocalTowerID
+	// is picked up by requests started afterwards.
+	localTowerID *sharedTowerID
+
 	FileService   file.Service
 	TaskService   *task.WorkerPool
 	ClientService *notify.ClientManager
@@ -70,6 +74,7 @@ func NewAppContext(ctx BasicContext) AppContext {
 		BasicContext: ctx,
 		Cache:        make(map[string]*sturdyc.Client[any]),
 		cacheLock:    &sync.RWMutex{},
+		localTowerID: &sharedTowerID{},
 		WG:           &sync.WaitGroup{},
 	}
 	newCtx.BasicContext = newCtx.BasicContext.WithValue(appContextKey{}, newCtx)
@@ -201,6 +206,51 @@ func (c AppContext) GetTowerID() string {
 	return c.LocalTowerID
 }
 
+// SetLocalTowerID sets the ID of the local tower. Along with the returned context, every request started after
+// this call will see the new ID. The ID is only changed at startup, and when a core takes back its old ID while
+// being restored from a backup.
+func (c AppContext) SetLocalTowerID(towerID string) AppContext {
+	if c.localTowerID != nil {
+		c.localTowerID.set(towerID)
+	}
+
+	c.LocalTowerID = towerID
+
+	return c.WithValue("towerID", towerID)
+}
+
+// currentTowerID returns the most recent ID given to SetLocalTowerID on any copy of the context.
+func (c AppContext) currentTowerID() string {
+	if c.localTowerID == nil {
+		return c.LocalTowerID
+	}
+
+	if towerID := c.localTowerID.get(); towerID != "" {
+		return towerID
+	}
+
+	return c.LocalTowerID
+}
+
+type sharedTowerID struct {
+	mu sync.RWMutex
+	id string
+}
+
+func (s *sharedTowerID) get() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.id
+}
+
+func (s *sharedTowerID) set(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.id = id
+}
+
 // ReplaceLogger returns a copy of AppContext with the specified logger.
 func (c AppContext) ReplaceLogger(newLogger *zerolog.Logger) AppContext {
 	c.BasicContext = c.BasicContext.ReplaceLogger(newLogger)
@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	auth_model "github.com/ethanrous/weblens/models/auth"
 	"github.com/ethanrous/weblens/models/client"
 	file_model "github.com/ethanrous/weblens/models/file"
 	share_model "github.com/ethanrous/weblens/models/share"
@@ -45,6 +46,10 @@ type RequestContext struct {
 	Remote     tower_model.Instance
 	IsLoggedIn bool
 
+	// APIKey is the api key the request was authenticated with, if any. Its scopes and folder limit what the
+	// requester may do.
+	APIKey *auth_model.Token
+
 	Share *share_model.FileShare
 	File  *file_model.WeblensFileImpl
 
@@ -167,6 +172,21 @@ func (c RequestContext) QueryIntDefault(paramName string, defaultValue int64) (i
 	return c.QueryInt(paramName)
 }
 
+// QueryFloat retrieves a query parameter as a float, or 0 if it is not provided.
+func (c RequestContext) QueryFloat(paramName string) (float64, error) {
+	queryStr := c.Query(paramName)
+	if queryStr == "" {
+		return 0, nil
+	}
+
+	num, err := strconv.ParseFloat(queryStr, 64)
+	if err != nil {
+		return 0, wlerrors.Errorf("Invalid query float for [%s]", paramName)
+	}
+
+	return num, nil
+}
+
 // QueryArray retrieves a query parameter and splits it into an array using commas as separators.
 func (c RequestContext) QueryArray(paramName string) []string {
 	queryStr := c.Query(paramName)
@@ -383,6 +403,7 @@ func AppContexter(ctx AppContext) func(next http.Handler) http.Handler {
 			// the captured ctx parameter.
 			logger := *wlog.GlobalLogger()
 			localCtx := ctx.ReplaceLogger(&logger)
+			localCtx.LocalTowerID = localCtx.currentTowerID()
 
 			reqContext := RequestContext{
 				AppContext: localCtx,
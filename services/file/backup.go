@@ -104,7 +104,7 @@ func loadFsTransactionBackup(ctx context.Context) error {
 				continue
 			}
 
-			a.ContentID = lt.Actions[0].ContentID
+			a.ContentID = lt.ContentID()
 
 			translatedPath, err := TranslateBackupPath(appCtx, a.GetRelevantPath(), remote)
 			if err != nil {
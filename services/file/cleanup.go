@@ -8,6 +8,7 @@ import (
 	file_model "github.com/ethanrous/weblens/models/file"
 	media_model "github.com/ethanrous/weblens/models/media"
 	context_mod "github.com/ethanrous/weblens/modules/wlcontext"
+	"github.com/ethanrous/weblens/modules/wlerrors"
 	file_system "github.com/ethanrous/weblens/modules/wlfs"
 	"github.com/rs/zerolog"
 )
@@ -51,6 +52,41 @@ func linkToRestore(ctx context.Context, file *file_model.WeblensFileImpl) error
 	return nil
 }
 
+// RemoveUnreferencedRestoreContent removes the files in the RESTORE tree whose content ID isReferenced reports as no
+// longer needed, and returns how many files were removed and how many bytes they held. If dryRun is true, the files are
+// only counted. The folders a backup tower keeps the content of each of its cores in are left alone.
+func RemoveUnreferencedRestoreContent(isReferenced func(contentID string) bool, dryRun bool) (removed int, bytes int64, err error) {
+	entries, err := os.ReadDir(file_model.RestoreDirPath.ToAbsolute())
+	if err != nil {
+		return 0, 0, wlerrors.WithStack(err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || isReferenced(entry.Name()) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return removed, bytes, wlerrors.WithStack(err)
+		}
+
+		if !dryRun {
+			err = os.Remove(file_model.RestoreDirPath.Child(entry.Name(), false).ToAbsolute())
+			if err != nil && !os.IsNotExist(err) {
+				return removed, bytes, wlerrors.WithStack(err)
+			}
+		}
+
+		removed++
+		bytes += info.Size()
+	}
+
+	return removed, bytes, nil
+}
+
 func rmFileMedia(ctx context.Context, file *file_model.WeblensFileImpl) error {
 	contentID := file.GetContentID()
 	if contentID == "" {
@@ -0,0 +1,252 @@
+package file
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/ethanrous/weblens/models/db"
+	file_model "github.com/ethanrous/weblens/models/file"
+	"github.com/ethanrous/weblens/models/history"
+	media_model "github.com/ethanrous/weblens/models/media"
+	context_mod "github.com/ethanrous/weblens/modules/wlcontext"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	file_system "github.com/ethanrous/weblens/modules/wlfs"
+)
+
+// ErrCopyIntoSelf is returned when a folder is copied into itself, or into one of its own descendants.
+var ErrCopyIntoSelf = wlerrors.Statusf(http.StatusBadRequest, "cannot copy a folder into itself")
+
+// copyPair tracks a file to be copied along with the parent its copy is created in.
+type copyPair struct {
+	parent *file_model.WeblensFileImpl
+	source *file_model.WeblensFileImpl
+}
+
+// CopyFiles copies files, and everything inside of any folders among them, into destFolder. A copy whose name is
+// already taken in destFolder is given a unique name. Copies keep the content ID of the file they were copied from, so
+// they share its media rather than being indexed again. onCopied, if not nil, is called after each file is copied.
+// The copies made directly in destFolder are returned.
+func (fs *ServiceImpl) CopyFiles(ctx context.Context, files []*file_model.WeblensFileImpl, destFolder *file_model.WeblensFileImpl, onCopied func(copied *file_model.WeblensFileImpl)) ([]*file_model.WeblensFileImpl, error) {
+	if !destFolder.IsDir() {
+		return nil, wlerrors.WithStack(file_model.ErrDirectoryRequired)
+	}
+
+	for _, f := range files {
+		if f.IsDir() && f.GetPortablePath().IsParentOf(destFolder.GetPortablePath()) {
+			return nil, wlerrors.Wrapf(ErrCopyIntoSelf, "[%s] into [%s]", f.GetPortablePath(), destFolder.GetPortablePath())
+		}
+	}
+
+	var copyBytes int64
+	for _, f := range files {
+		copyBytes += f.Size()
+	}
+
+	err := CheckQuota(ctx, destFolder, copyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	defer fs.beginDiskChange(destFolder.GetPortablePath())()
+
+	// All the copies are journaled as a single event, attributed to the doer of ctx
+	if _, ok := history.FileEventFromContext(ctx); !ok {
+		ctx = history.WithFileEvent(ctx)
+	}
+
+	queue := make([]copyPair, 0, len(files))
+	for _, f := range files {
+		queue = append(queue, copyPair{parent: destFolder, source: f})
+	}
+
+	topLevel := make([]*file_model.WeblensFileImpl, 0, len(files))
+	copied := make([]*file_model.WeblensFileImpl, 0, len(files))
+	actions := make([]history.FileAction, 0, len(files))
+
+	// Contents are copied before anything is written to the database, so a large copy does not hold a transaction open
+	err = func() error {
+		for len(queue) > 0 {
+			if ctx.Err() != nil {
+				return wlerrors.WithStack(ctx.Err())
+			}
+
+			current := queue[0]
+			queue = queue[1:]
+
+			destPath, err := MakeUniqueChildName(current.parent.GetPortablePath(), current.source.GetPortablePath().Filename(), current.source.IsDir())
+			if err != nil {
+				return err
+			}
+
+			newF, err := fs.copyOne(ctx, current.source, current.parent, destPath)
+			if err != nil {
+				return err
+			}
+
+			if current.parent == destFolder {
+				topLevel = append(topLevel, newF)
+			}
+
+			copied = append(copied, newF)
+			actions = append(actions, history.NewCreateAction(ctx, newF))
+
+			if current.source.IsDir() {
+				children, err := fs.GetChildren(ctx, current.source)
+				if err != nil {
+					return err
+				}
+
+				for _, child := range children {
+					queue = append(queue, copyPair{parent: newF, source: child})
+				}
+			}
+
+			if onCopied != nil {
+				onCopied(newF)
+			}
+		}
+
+		return nil
+	}()
+	if err != nil {
+		fs.removeCopies(ctx, topLevel)
+
+		return nil, err
+	}
+
+	err = db.WithTransaction(ctx, func(ctx context.Context) error {
+		_, skipJournal := ctx.Value(SkipJournalKey).(bool)
+		if !skipJournal {
+			err := history.SaveActions(ctx, actions)
+			if err != nil {
+				return err
+			}
+		}
+
+		return addCopiesToMedia(ctx, copied)
+	})
+	if err != nil {
+		fs.removeCopies(ctx, topLevel)
+
+		return nil, err
+	}
+
+	err = fs.finishRestore(ctx, destFolder, topLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	return topLevel, nil
+}
+
+// copyOne copies the single file source to destPath, without its children, and adds the copy to the file tree.
+func (fs *ServiceImpl) copyOne(ctx context.Context, source, parent *file_model.WeblensFileImpl, destPath file_system.Filepath) (*file_model.WeblensFileImpl, error) {
+	if !source.IsDir() && source.Size() != 0 && source.GetContentID() == "" {
+		_, err := file_model.GenerateContentID(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	if source.IsDir() {
+		err = os.Mkdir(destPath.ToAbsolute(), os.ModePerm)
+	} else {
+		err = copyContent(source.GetPortablePath(), destPath)
+	}
+
+	if err != nil {
+		return nil, wlerrors.Wrapf(err, "failed to copy [%s] to [%s]", source.GetPortablePath(), destPath)
+	}
+
+	newF := file_model.NewWeblensFile(file_model.NewFileOptions{
+		Path:       destPath,
+		ContentID:  source.GetContentID(),
+		Size:       source.Size(),
+		GenerateID: true,
+	})
+
+	err = newF.SetParent(parent)
+	if err != nil {
+		return nil, err
+	}
+
+	err = parent.AddChild(newF)
+	if err != nil {
+		return nil, err
+	}
+
+	err = fs.AddFile(ctx, newF)
+	if err != nil {
+		return nil, err
+	}
+
+	return newF, nil
+}
+
+// removeCopies undoes a copy that failed part way, removing the copies made so far from disk and the file tree.
+func (fs *ServiceImpl) removeCopies(ctx context.Context, topLevel []*file_model.WeblensFileImpl) {
+	for _, f := range topLevel {
+		_ = f.RecursiveMap(func(wfi *file_model.WeblensFileImpl) error {
+			return fs.removeFileByID(ctx, wfi.ID())
+		})
+
+		if parent := f.GetParent(); parent != nil {
+			_ = parent.RemoveChild(f.GetPortablePath().Filename())
+		}
+
+		err := remove(f.GetPortablePath())
+		if err != nil {
+			context_mod.ToZ(ctx).Log().Error().Stack().Err(err).Msgf("Failed to clean up partial copy [%s]", f.GetPortablePath())
+		}
+	}
+}
+
+// addCopiesToMedia adds copied files to the media of the content they share with the file they were copied from.
+func addCopiesToMedia(ctx context.Context, copied []*file_model.WeblensFileImpl) error {
+	for _, f := range copied {
+		if f.IsDir() || f.GetContentID() == "" {
+			continue
+		}
+
+		m, err := media_model.GetMediaByContentID(ctx, f.GetContentID())
+		if db.IsNotFound(err) {
+			// The source was never indexed, so there is no media to share
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		err = m.AddFileToMedia(ctx, f.ID())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func copyContent(src, dest file_system.Filepath) error {
+	in, err := os.Open(src.ToAbsolute())
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	defer in.Close() //nolint:errcheck
+
+	out, err := os.OpenFile(dest.ToAbsolute(), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o666)
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		_ = out.Close()
+
+		return wlerrors.WithStack(err)
+	}
+
+	return wlerrors.WithStack(out.Close())
+}
@@ -0,0 +1,94 @@
+package file //nolint:testpackage
+
+import (
+	"os"
+	"testing"
+
+	file_model "github.com/ethanrous/weblens/models/file"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	ctxservice "github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileService_CopyFiles_Integration(t *testing.T) {
+	t.Run("copies nested folder and keeps the source", func(t *testing.T) {
+		ctx, _ := newIntegrationTestContext(t)
+		appCtx, ok := ctxservice.FromContext(ctx)
+		require.True(t, ok)
+
+		fs := appCtx.GetFileService()
+
+		userHome, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true))
+		require.NoError(t, err)
+
+		source := createTestFolder(t, ctx, fs, userHome, "source")
+		nested := createTestFolder(t, ctx, fs, source, "nested")
+		file := createTestFile(t, ctx, fs, nested, "file.txt", []byte("content"))
+
+		dest := createTestFolder(t, ctx, fs, userHome, "dest")
+
+		copiedCount := 0
+
+		copies, err := fs.CopyFiles(ctx, []*file_model.WeblensFileImpl{source}, dest, func(*file_model.WeblensFileImpl) {
+			copiedCount++
+		})
+		require.NoError(t, err)
+		require.Len(t, copies, 1)
+		assert.Equal(t, 3, copiedCount)
+		assert.NotEqual(t, source.ID(), copies[0].ID())
+
+		newPath := file_model.UsersRootPath.Child("testuser/dest/source/nested/file.txt", false)
+		copied, err := fs.GetFileByFilepath(ctx, newPath)
+		require.NoError(t, err)
+		assert.NotEqual(t, file.ID(), copied.ID())
+		assert.Equal(t, file.GetContentID(), copied.GetContentID())
+
+		content, err := os.ReadFile(newPath.ToAbsolute())
+		require.NoError(t, err)
+		assert.Equal(t, "content", string(content))
+
+		// The source is left in place
+		assertFileExistsOnDisk(t, file.GetPortablePath())
+	})
+
+	t.Run("renames copy when destination has conflict", func(t *testing.T) {
+		ctx, _ := newIntegrationTestContext(t)
+		appCtx, ok := ctxservice.FromContext(ctx)
+		require.True(t, ok)
+
+		fs := appCtx.GetFileService()
+
+		userHome, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true))
+		require.NoError(t, err)
+
+		sourceFile := createTestFile(t, ctx, fs, userHome, "source.txt", []byte("source content"))
+
+		copies, err := fs.CopyFiles(ctx, []*file_model.WeblensFileImpl{sourceFile}, userHome, nil)
+		require.NoError(t, err)
+		require.Len(t, copies, 1)
+
+		expectedPath := file_model.UsersRootPath.Child("testuser/source.txt (1)", false)
+		assert.Equal(t, expectedPath, copies[0].GetPortablePath())
+		assertFileExistsOnDisk(t, expectedPath)
+	})
+
+	t.Run("rejects copying a folder into itself", func(t *testing.T) {
+		ctx, _ := newIntegrationTestContext(t)
+		appCtx, ok := ctxservice.FromContext(ctx)
+		require.True(t, ok)
+
+		fs := appCtx.GetFileService()
+
+		userHome, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true))
+		require.NoError(t, err)
+
+		source := createTestFolder(t, ctx, fs, userHome, "source")
+		nested := createTestFolder(t, ctx, fs, source, "nested")
+
+		_, err = fs.CopyFiles(ctx, []*file_model.WeblensFileImpl{source}, nested, nil)
+		assert.True(t, wlerrors.Is(err, ErrCopyIntoSelf))
+
+		assertFileNotExistsOnDisk(t, file_model.UsersRootPath.Child("testuser/source/nested/source", true))
+	})
+}
@@ -0,0 +1,149 @@
+package file
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/ethanrous/weblens/models/embedding"
+	file_model "github.com/ethanrous/weblens/models/file"
+	media_model "github.com/ethanrous/weblens/models/media"
+)
+
+// DefaultSimilarityThreshold is the cosine similarity of image embeddings at or above which two photos are considered
+// near-duplicates, when no other threshold is given.
+const DefaultSimilarityThreshold = 0.95
+
+// DuplicateGroup is a set of files that hold the same content, or that hold photos which look alike.
+type DuplicateGroup struct {
+	// ContentID is the content ID shared by every file in the group. It is empty for a group of near-duplicates.
+	ContentID string
+
+	// Files are the files of the group, ordered by path.
+	Files []*file_model.WeblensFileImpl
+
+	// ReclaimableBytes is the space freed by deleting every file of the group except for the largest one.
+	ReclaimableBytes int64
+}
+
+// FindDuplicates groups the files inside of roots by content ID, returning every group of more than one file. Folders,
+// empty files, and files in the trash are ignored. Groups are ordered by how many bytes they could reclaim, largest first.
+func FindDuplicates(roots ...*file_model.WeblensFileImpl) ([]DuplicateGroup, error) {
+	byContentID, err := collectDuplicateCandidates(roots)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []DuplicateGroup{}
+
+	for contentID, files := range byContentID {
+		if len(files) < 2 {
+			continue
+		}
+
+		groups = append(groups, newDuplicateGroup(contentID, files))
+	}
+
+	sortDuplicateGroups(groups)
+
+	return groups, nil
+}
+
+// FindSimilarPhotos groups the photos inside of roots whose image embeddings have a cosine similarity of at least
+// threshold. Photos with identical content are reported by FindDuplicates instead, so every group returned here holds
+// at least two different content IDs. Photos that have not been embedded yet are ignored.
+func FindSimilarPhotos(ctx context.Context, threshold float64, roots ...*file_model.WeblensFileImpl) ([]DuplicateGroup, error) {
+	byContentID, err := collectDuplicateCandidates(roots)
+	if err != nil {
+		return nil, err
+	}
+
+	contentIDs := make([]string, 0, len(byContentID))
+
+	for contentID, files := range byContentID {
+		if media_model.ParseExtension(strings.ToLower(files[0].GetPortablePath().Ext())).SupportsImgRecog() {
+			contentIDs = append(contentIDs, contentID)
+		}
+	}
+
+	vectors, err := embedding.GetImageVectors(ctx, contentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	similar := embedding.GroupSimilar(vectors, threshold)
+	groups := make([]DuplicateGroup, 0, len(similar))
+
+	for _, similarIDs := range similar {
+		files := []*file_model.WeblensFileImpl{}
+		for _, contentID := range similarIDs {
+			files = append(files, byContentID[contentID]...)
+		}
+
+		groups = append(groups, newDuplicateGroup("", files))
+	}
+
+	sortDuplicateGroups(groups)
+
+	return groups, nil
+}
+
+// collectDuplicateCandidates walks roots and maps content IDs to the files that hold them, skipping anything that
+// should never be reported as a duplicate.
+func collectDuplicateCandidates(roots []*file_model.WeblensFileImpl) (map[string][]*file_model.WeblensFileImpl, error) {
+	byContentID := make(map[string][]*file_model.WeblensFileImpl)
+	seen := make(map[string]struct{})
+
+	for _, root := range roots {
+		err := root.RecursiveMap(func(f *file_model.WeblensFileImpl) error {
+			if f.IsDir() || f.Size() == 0 || f.GetContentID() == "" || file_model.IsFileInTrash(f) {
+				return nil
+			}
+
+			// Roots may overlap, so make sure each file is only counted once
+			if _, ok := seen[f.ID()]; ok {
+				return nil
+			}
+
+			seen[f.ID()] = struct{}{}
+			byContentID[f.GetContentID()] = append(byContentID[f.GetContentID()], f)
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return byContentID, nil
+}
+
+func newDuplicateGroup(contentID string, files []*file_model.WeblensFileImpl) DuplicateGroup {
+	slices.SortFunc(files, func(a, b *file_model.WeblensFileImpl) int {
+		return cmp.Compare(a.GetPortablePath().ToPortable(), b.GetPortablePath().ToPortable())
+	})
+
+	var total, largest int64
+
+	for _, f := range files {
+		total += f.Size()
+		largest = max(largest, f.Size())
+	}
+
+	return DuplicateGroup{
+		ContentID:        contentID,
+		Files:            files,
+		ReclaimableBytes: total - largest,
+	}
+}
+
+func sortDuplicateGroups(groups []DuplicateGroup) {
+	slices.SortFunc(groups, func(a, b DuplicateGroup) int {
+		if c := cmp.Compare(b.ReclaimableBytes, a.ReclaimableBytes); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a.Files[0].GetPortablePath().ToPortable(), b.Files[0].GetPortablePath().ToPortable())
+	})
+}
@@ -0,0 +1,99 @@
+package file //nolint:testpackage
+
+import (
+	"testing"
+
+	file_model "github.com/ethanrous/weblens/models/file"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	ctxservice "github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	ctx, _ := newIntegrationTestContext(t)
+	appCtx, ok := ctxservice.FromContext(ctx)
+	require.True(t, ok)
+
+	fs := appCtx.GetFileService()
+
+	userHome, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true))
+	require.NoError(t, err)
+
+	folder := createTestFolder(t, ctx, fs, userHome, "folder")
+
+	small := createTestFile(t, ctx, fs, userHome, "small.txt", []byte("small"))
+	smallCopy := createTestFile(t, ctx, fs, folder, "small copy.txt", []byte("small"))
+	large := createTestFile(t, ctx, fs, userHome, "large.txt", []byte("a much larger file"))
+	largeCopy := createTestFile(t, ctx, fs, folder, "large copy.txt", []byte("a much larger file"))
+	unique := createTestFile(t, ctx, fs, userHome, "unique.txt", []byte("unique"))
+
+	small.SetContentID("small")
+	smallCopy.SetContentID("small")
+	large.SetContentID("large")
+	largeCopy.SetContentID("large")
+	unique.SetContentID("unique")
+
+	trash, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true).Child(file_model.UserTrashDirName, true))
+	require.NoError(t, err)
+
+	trashed := createTestFile(t, ctx, fs, trash, "trashed.txt", []byte("unique"))
+	trashed.SetContentID("unique")
+
+	groups, err := FindDuplicates(userHome)
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+
+	// Largest reclaimable group first, files ordered by path
+	assert.Equal(t, "large", groups[0].ContentID)
+	assert.Equal(t, []*file_model.WeblensFileImpl{largeCopy, large}, groups[0].Files)
+	assert.Equal(t, large.Size(), groups[0].ReclaimableBytes)
+
+	assert.Equal(t, "small", groups[1].ContentID)
+	assert.Equal(t, small.Size(), groups[1].ReclaimableBytes)
+}
+
+func TestFileService_TrashFiles_Integration(t *testing.T) {
+	t.Run("moves files from different folders into the trash", func(t *testing.T) {
+		ctx, _ := newIntegrationTestContext(t)
+		appCtx, ok := ctxservice.FromContext(ctx)
+		require.True(t, ok)
+
+		fs := appCtx.GetFileService()
+
+		userHome, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true))
+		require.NoError(t, err)
+
+		folder := createTestFolder(t, ctx, fs, userHome, "folder")
+		keep := createTestFile(t, ctx, fs, userHome, "keep.txt", []byte("content"))
+		first := createTestFile(t, ctx, fs, userHome, "first.txt", []byte("content"))
+		second := createTestFile(t, ctx, fs, folder, "second.txt", []byte("content"))
+
+		err = fs.TrashFiles(ctx, first, second)
+		require.NoError(t, err)
+
+		assert.True(t, file_model.IsFileInTrash(first))
+		assert.True(t, file_model.IsFileInTrash(second))
+		assert.False(t, file_model.IsFileInTrash(keep))
+
+		assertFileExistsOnDisk(t, first.GetPortablePath())
+		assertFileExistsOnDisk(t, second.GetPortablePath())
+		assertFileNotExistsOnDisk(t, file_model.UsersRootPath.Child("testuser/folder/second.txt", false))
+	})
+
+	t.Run("rejects files already in the trash", func(t *testing.T) {
+		ctx, _ := newIntegrationTestContext(t)
+		appCtx, ok := ctxservice.FromContext(ctx)
+		require.True(t, ok)
+
+		fs := appCtx.GetFileService()
+
+		trash, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true).Child(file_model.UserTrashDirName, true))
+		require.NoError(t, err)
+
+		trashed := createTestFile(t, ctx, fs, trash, "trashed.txt", []byte("content"))
+
+		err = fs.TrashFiles(ctx, trashed)
+		assert.True(t, wlerrors.Is(err, ErrAlreadyInTrash))
+	})
+}
@@ -4,6 +4,7 @@ package file
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"slices"
@@ -33,10 +34,14 @@ import (
 
 var _ file_model.Service = &ServiceImpl{}
 
+// ErrAlreadyInTrash is returned when trashing a file that is already in the trash.
+var ErrAlreadyInTrash = wlerrors.Statusf(http.StatusBadRequest, "file is already in the trash")
+
 // ServiceImpl implements the FileService interface for managing files and directories.
 type ServiceImpl struct {
 	contentIDCache map[string]*file_model.WeblensFileImpl
 	contentIDLock  sync.RWMutex
+	diskChanges    diskChanges
 	fileTaskLink   map[string][]*task_model.Task
 	fileTaskLock   sync.RWMutex
 	files          map[string]*file_model.WeblensFileImpl
@@ -208,8 +213,21 @@ func (fs *ServiceImpl) CreateFile(ctx context.Context, parent *file_model.Weblen
 		return nil, wlerrors.Errorf("invalid filename: %w", err)
 	}
 
+	var dataBytes int64
+	for _, d := range data {
+		dataBytes += int64(len(d))
+	}
+
+	if dataBytes != 0 {
+		if err := CheckQuota(ctx, parent, dataBytes); err != nil {
+			return nil, err
+		}
+	}
+
 	childPath := parent.GetPortablePath().Child(filename, false)
 
+	defer fs.beginDiskChange(childPath)()
+
 	newF, err := touch(childPath)
 	if err != nil {
 		return nil, err
@@ -239,6 +257,89 @@ func (fs *ServiceImpl) CreateFile(ctx context.Context, parent *file_model.Weblen
 	return newF, nil
 }
 
+// CreateFileFromPath creates a new file in parent whose content is the file at contentPath. The content is moved
+// into place and hashed before the file is added to the tree and the journal, so the file is never seen without its
+// content. If the file can not be created, the content is moved back to contentPath and nothing is added to the tree.
+func (fs *ServiceImpl) CreateFileFromPath(ctx context.Context, parent *file_model.WeblensFileImpl, filename, contentPath string) (
+	*file_model.WeblensFileImpl, error,
+) {
+	if err := cryptography.ValidateFilename(filename); err != nil {
+		return nil, wlerrors.Errorf("invalid filename: %w", err)
+	}
+
+	if child, _ := parent.GetChild(filename); child != nil {
+		return nil, wlerrors.WithStack(file_model.ErrFileAlreadyExists)
+	}
+
+	stat, err := os.Stat(contentPath)
+	if err != nil {
+		return nil, wlerrors.WithStack(err)
+	}
+
+	if stat.Size() != 0 {
+		if err := CheckQuota(ctx, parent, stat.Size()); err != nil {
+			return nil, err
+		}
+	}
+
+	childPath := parent.GetPortablePath().Child(filename, false)
+
+	defer fs.beginDiskChange(childPath)()
+
+	if exists(childPath) {
+		return nil, wlerrors.WithStack(file_model.ErrFileAlreadyExists)
+	}
+
+	err = moveIntoPlace(contentPath, childPath.ToAbsolute())
+	if err != nil {
+		return nil, err
+	}
+
+	newF, err := fs.addMovedFile(ctx, parent, childPath)
+	if err != nil {
+		_ = parent.RemoveChild(filename)
+
+		if moveErr := moveIntoPlace(childPath.ToAbsolute(), contentPath); moveErr != nil {
+			wlog.FromContext(ctx).Error().Stack().Err(moveErr).Msgf("Failed to move content of [%s] back to [%s]", childPath, contentPath)
+		}
+
+		return nil, err
+	}
+
+	return newF, nil
+}
+
+// addMovedFile adds the file that was just moved to path on disk to the tree under parent, once its content ID is known.
+func (fs *ServiceImpl) addMovedFile(ctx context.Context, parent *file_model.WeblensFileImpl, path file_system.Filepath) (*file_model.WeblensFileImpl, error) {
+	stat, err := os.Stat(path.ToAbsolute())
+	if err != nil {
+		return nil, wlerrors.WithStack(err)
+	}
+
+	newF := file_model.NewWeblensFile(file_model.NewFileOptions{Path: path})
+	newF.SetSize(stat.Size())
+	newF.SetModifiedTime(stat.ModTime())
+
+	if stat.Size() != 0 {
+		_, err = file_model.GenerateContentID(ctx, newF)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = fs.createCommon(ctx, newF, parent)
+	if err != nil {
+		return nil, err
+	}
+
+	err = fs.ResizeUp(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+
+	return newF, nil
+}
+
 // CreateFolder creates a new folder in the specified parent directory.
 func (fs *ServiceImpl) CreateFolder(ctx context.Context, parent *file_model.WeblensFileImpl, folderName string) (*file_model.WeblensFileImpl, error) {
 	if err := cryptography.ValidateFilename(folderName); err != nil {
@@ -247,6 +348,8 @@ func (fs *ServiceImpl) CreateFolder(ctx context.Context, parent *file_model.Webl
 
 	childPath := parent.GetPortablePath().Child(folderName, true)
 
+	defer fs.beginDiskChange(childPath)()
+
 	dir, err := mkdir(childPath)
 	if err != nil {
 		return nil, err
@@ -313,6 +416,13 @@ func (fs *ServiceImpl) ReturnFilesFromTrash(_ context.Context, trashFiles []*fil
 
 // MoveFiles moves one or more files to a destination folder.
 func (fs *ServiceImpl) MoveFiles(ctx context.Context, files []*file_model.WeblensFileImpl, destFolder *file_model.WeblensFileImpl) error {
+	changing := make([]file_system.Filepath, 0, len(files)*2)
+	for _, f := range files {
+		changing = append(changing, f.GetPortablePath(), destFolder.GetPortablePath().Child(f.GetPortablePath().Filename(), f.IsDir()))
+	}
+
+	defer fs.beginDiskChange(changing...)()
+
 	err := db.WithTransaction(ctx, func(ctx context.Context) error {
 		return fs.moveFilesWithTransaction(ctx, files, destFolder)
 	})
@@ -333,6 +443,13 @@ func (fs *ServiceImpl) DeleteFiles(ctx context.Context, files ...*file_model.Web
 		}
 	}
 
+	changing := make([]file_system.Filepath, 0, len(files))
+	for _, f := range files {
+		changing = append(changing, f.GetPortablePath())
+	}
+
+	defer fs.beginDiskChange(changing...)()
+
 	err := db.WithTransaction(ctx, func(ctx context.Context) error {
 		return fs.deleteFilesWithTransaction(ctx, files)
 	})
@@ -343,6 +460,58 @@ func (fs *ServiceImpl) DeleteFiles(ctx context.Context, files ...*file_model.Web
 	return nil
 }
 
+// TrashFiles moves files into the trash of the user who owns each of them. The moves are made in a single
+// transaction, so they are journaled as a single event.
+func (fs *ServiceImpl) TrashFiles(ctx context.Context, files ...*file_model.WeblensFileImpl) error {
+	byOwner := make(map[string][]*file_model.WeblensFileImpl)
+	changing := make([]file_system.Filepath, 0, len(files)+1)
+
+	for _, f := range files {
+		if f.GetPortablePath().Dir().IsRoot() {
+			return wlerrors.Errorf("cannot trash user home directory [%s]", f.GetPortablePath())
+		} else if f.GetPortablePath().Filename() == file_model.UserTrashDirName {
+			return wlerrors.Errorf("cannot trash user trash directory [%s]", f.GetPortablePath())
+		} else if file_model.IsFileInTrash(f) {
+			return wlerrors.Wrapf(ErrAlreadyInTrash, "[%s]", f.GetPortablePath())
+		}
+
+		owner, err := file_model.GetFileOwnerName(ctx, f)
+		if err != nil {
+			return err
+		}
+
+		byOwner[owner] = append(byOwner[owner], f)
+		changing = append(changing, f.GetPortablePath())
+	}
+
+	for owner := range byOwner {
+		changing = append(changing, userTrashPath(owner))
+	}
+
+	defer fs.beginDiskChange(changing...)()
+
+	return db.WithTransaction(ctx, func(ctx context.Context) error {
+		for owner, ownerFiles := range byOwner {
+			trash, err := fs.GetFileByFilepath(ctx, userTrashPath(owner))
+			if err != nil {
+				return err
+			}
+
+			err = fs.moveFilesWithTransaction(ctx, ownerFiles, trash)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// userTrashPath returns the path of the trash folder of the user called username.
+func userTrashPath(username string) file_system.Filepath {
+	return file_model.UsersRootPath.Child(username, true).Child(file_model.UserTrashDirName, true)
+}
+
 // restorePair tracks a file to be restored along with its destination parent.
 type restorePair struct {
 	parent *file_model.WeblensFileImpl
@@ -352,16 +521,31 @@ type restorePair struct {
 // RestoreFiles restores files to a previous state from their history at the specified time.
 // It reconstructs past file states and hard-links content from the RESTORE tree back into the USERS tree.
 func (fs *ServiceImpl) RestoreFiles(ctx context.Context, ids []string, newParent *file_model.WeblensFileImpl, restoreTime time.Time) error {
+	var restoreBytes int64
+
 	queue := make([]restorePair, 0, len(ids))
 	for _, id := range ids {
+		pastFile, err := journal.GetPastFileByID(ctx, id, restoreTime)
+		if err != nil {
+			return wlerrors.Wrapf(err, "failed to get past file [%s] at time [%s]", id, restoreTime)
+		}
+
+		restoreBytes += pastFile.Size()
 		queue = append(queue, restorePair{parent: newParent, fileID: id})
 	}
 
+	err := CheckQuota(ctx, newParent, restoreBytes)
+	if err != nil {
+		return err
+	}
+
+	defer fs.beginDiskChange(newParent.GetPortablePath())()
+
 	actions := make([]history.FileAction, 0)
 	restoredFiles := make([]*file_model.WeblensFileImpl, 0)
 
 	// Actions created during a transaction automatically get an event ID and timestamp, so we don't need to set those manually here
-	err := db.WithTransaction(ctx, func(ctx context.Context) error {
+	err = db.WithTransaction(ctx, func(ctx context.Context) error {
 		for len(queue) > 0 {
 			current := queue[0]
 			queue = queue[1:]
@@ -413,7 +597,86 @@ func (fs *ServiceImpl) RestoreFiles(ctx context.Context, ids []string, newParent
 		return err
 	}
 
-	err = fs.ResizeUp(ctx, newParent)
+	return fs.finishRestore(ctx, newParent, restoredFiles)
+}
+
+// pastFilePair tracks a past file, already resolved from the journal of a backup, along with its destination parent.
+type pastFilePair struct {
+	parent   *file_model.WeblensFileImpl
+	pastFile *file_model.WeblensFileImpl
+}
+
+// RestoreFilesFromBackup restores past files, as recorded in the journal of a backup tower, into newParent. Children
+// of past folders are restored with them. The contents of each file are read from the backup with openContent.
+func (fs *ServiceImpl) RestoreFilesFromBackup(ctx context.Context, pastFiles []*file_model.WeblensFileImpl, newParent *file_model.WeblensFileImpl, openContent func(contentID string) (io.ReadCloser, error)) error {
+	var restoreBytes int64
+
+	queue := make([]pastFilePair, 0, len(pastFiles))
+	for _, pastFile := range pastFiles {
+		restoreBytes += pastFile.Size()
+		queue = append(queue, pastFilePair{parent: newParent, pastFile: pastFile})
+	}
+
+	err := CheckQuota(ctx, newParent, restoreBytes)
+	if err != nil {
+		return err
+	}
+
+	defer fs.beginDiskChange(newParent.GetPortablePath())()
+
+	actions := make([]history.FileAction, 0)
+	restoredFiles := make([]*file_model.WeblensFileImpl, 0)
+
+	err = db.WithTransaction(ctx, func(ctx context.Context) error {
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+
+			destPath, err := MakeUniqueChildName(current.parent.GetPortablePath(), current.pastFile.GetPortablePath().Filename(), current.pastFile.IsDir())
+			if err != nil {
+				return err
+			}
+
+			if current.pastFile.IsDir() {
+				restoreDir, restoreAction, err := fs.restoreDirectory(ctx, current.pastFile, current.parent, destPath)
+				if err != nil {
+					return err
+				}
+
+				actions = append(actions, restoreAction)
+				restoredFiles = append(restoredFiles, restoreDir)
+
+				for _, child := range current.pastFile.GetChildren() {
+					queue = append(queue, pastFilePair{parent: restoreDir, pastFile: child})
+				}
+			} else {
+				restoredFile, restoreAction, err := fs.restoreBackupFile(ctx, current.pastFile, current.parent, destPath, openContent)
+				if err != nil {
+					return err
+				}
+
+				actions = append(actions, restoreAction)
+				restoredFiles = append(restoredFiles, restoredFile)
+			}
+		}
+
+		err := history.SaveActions(ctx, actions)
+		if err != nil {
+			return wlerrors.Wrap(err, "failed to save restore actions")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return fs.finishRestore(ctx, newParent, restoredFiles)
+}
+
+// finishRestore updates the size of newParent after files were restored into it, and notifies clients of the new files.
+func (fs *ServiceImpl) finishRestore(ctx context.Context, newParent *file_model.WeblensFileImpl, restoredFiles []*file_model.WeblensFileImpl) error {
+	err := fs.ResizeUp(ctx, newParent)
 	if err != nil {
 		return err
 	}
@@ -440,6 +703,8 @@ func (fs *ServiceImpl) RestoreFiles(ctx context.Context, ids []string, newParent
 
 	appCtx.Notify(ctx, notifs...)
 
+	NotifyQuotaUsage(ctx, newParent)
+
 	return nil
 }
 
@@ -521,21 +786,40 @@ func (fs *ServiceImpl) RenameFile(ctx context.Context, file *file_model.WeblensF
 	oldPath := file.GetPortablePath()
 	newPath := oldPath.Dir().Child(newName, file.GetPortablePath().IsDir())
 
+	defer fs.beginDiskChange(oldPath, newPath)()
+
 	err := rename(file.GetPortablePath(), newPath)
 	if err != nil {
 		return err
 	}
 
-	err = file.RecursiveMap(func(wfi *file_model.WeblensFileImpl) error {
-		// Update the file's path to the new path
-		newFilePath, err := wfi.GetPortablePath().ReplacePrefix(oldPath, newPath)
+	_, skipJournal := ctx.Value(SkipJournalKey).(bool)
+
+	// A rename is recorded in the journal as a move of the file, and all of its descendants, to the new path.
+	// Without this, lookups that resolve a file by its latest journaled path would still point at the old name.
+	err = db.WithTransaction(ctx, func(ctx context.Context) error {
+		actions := []history.FileAction{}
+
+		err := file.RecursiveMap(func(wfi *file_model.WeblensFileImpl) error {
+			// Update the file's path to the new path
+			newFilePath, err := wfi.GetPortablePath().ReplacePrefix(oldPath, newPath)
+			if err != nil {
+				return err
+			}
+
+			if !skipJournal {
+				actions = append(actions, history.NewMoveAction(ctx, wfi.GetPortablePath(), newFilePath, wfi))
+			}
+
+			wfi.SetPortablePath(newFilePath)
+
+			return nil
+		})
 		if err != nil {
 			return err
 		}
 
-		wfi.SetPortablePath(newFilePath)
-
-		return nil
+		return history.SaveActions(ctx, actions)
 	})
 	if err != nil {
 		return err
@@ -1136,6 +1420,56 @@ func (fs *ServiceImpl) restoreRegularFile(ctx context.Context, pastFile, parent
 	return restoreFile, action, nil
 }
 
+// restoreBackupFile writes the content of a past file, read from a backup tower, to the destination.
+func (fs *ServiceImpl) restoreBackupFile(ctx context.Context, pastFile, parent *file_model.WeblensFileImpl, destPath file_system.Filepath, openContent func(contentID string) (io.ReadCloser, error)) (restoreFile *file_model.WeblensFileImpl, action history.FileAction, err error) {
+	restoreFile = file_model.NewWeblensFile(file_model.NewFileOptions{
+		Path:      destPath,
+		ContentID: pastFile.GetContentID(),
+		Size:      pastFile.Size(),
+	})
+
+	err = restoreFile.SetParent(parent)
+	if err != nil {
+		return nil, action, err
+	}
+
+	err = parent.AddChild(restoreFile)
+	if err != nil {
+		return nil, action, err
+	}
+
+	out, err := os.Create(destPath.ToAbsolute())
+	if err != nil {
+		return nil, action, wlerrors.WithStack(err)
+	}
+
+	defer out.Close() //nolint:errcheck
+
+	// Empty files are never given a content ID, so there is nothing to read from the backup
+	if pastFile.GetContentID() != "" {
+		content, err := openContent(pastFile.GetContentID())
+		if err != nil {
+			return nil, action, wlerrors.Wrapf(err, "failed to read backup content for file [%s]", pastFile.ID())
+		}
+
+		defer content.Close() //nolint:errcheck
+
+		_, err = io.Copy(out, content)
+		if err != nil {
+			return nil, action, wlerrors.Wrapf(err, "failed to write restored file [%s]", destPath)
+		}
+	}
+
+	action = history.NewRestoreAction(ctx, restoreFile, pastFile.ID())
+
+	err = fs.AddFile(ctx, restoreFile)
+	if err != nil {
+		return nil, action, err
+	}
+
+	return restoreFile, action, nil
+}
+
 // findRestoreSource locates the content for a file being restored,
 // checking the RESTORE tree first, then falling back to the live USERS tree.
 func (fs *ServiceImpl) findRestoreSource(ctx context.Context, fileID, contentID string) (string, error) {
@@ -26,6 +26,7 @@ type doFileCreationContextKey struct{}
 
 func init() {
 	startup.RegisterHook(loadFs)
+	startup.RegisterHook(startFileWatcher)
 }
 
 // LoadFilesRecursively loads a directory and all its subdirectories into the file service.
@@ -115,7 +116,7 @@ func loadFs(ctx context.Context, cnf config.Provider) error {
 
 	start := time.Now()
 
-	for _, root := range []file_system.Filepath{file_model.CacheRootPath, file_model.ThumbsDirPath, file_model.RestoreDirPath, file_model.ZipsDirPath} {
+	for _, root := range []file_system.Filepath{file_model.CacheRootPath, file_model.ThumbsDirPath, file_model.RestoreDirPath, file_model.ZipsDirPath, file_model.UploadsDirPath} {
 		if err := fs.makeRoot(ctx, root); err != nil {
 			return err
 		}
@@ -137,6 +138,12 @@ func loadFs(ctx context.Context, cnf config.Provider) error {
 		if err != nil {
 			return err
 		}
+	} else if tower_model.Role(cnf.InitRole) == tower_model.RoleRestore {
+		// Files are written straight to disk while restoring, and are loaded once the tower starts as core
+		err := file_system.RegisterAbsolutePrefix(file_model.UsersTreeKey, filepath.Join(cnf.DataPath, "users"))
+		if err != nil {
+			return err
+		}
 	} else if tower_model.Role(cnf.InitRole) == tower_model.RoleBackup {
 		err := file_system.RegisterAbsolutePrefix(file_model.BackupTreeKey, filepath.Join(cnf.DataPath, "backup"))
 		if err != nil {
@@ -391,7 +398,7 @@ func loadLifetimes(ctx context_service.AppContext) (map[file_system.Filepath]his
 			continue
 		}
 
-		a.ContentID = lt.Actions[0].ContentID
+		a.ContentID = lt.ContentID()
 
 		path := a.GetRelevantPath()
 
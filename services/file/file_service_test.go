@@ -2,6 +2,9 @@ package file //nolint:testpackage
 
 import (
 	"context"
+	"io"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,6 +15,7 @@ import (
 	tower_model "github.com/ethanrous/weblens/models/tower"
 	"github.com/ethanrous/weblens/modules/wlog"
 	ctxservice "github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/ethanrous/weblens/services/journal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -386,6 +390,32 @@ func TestFileService_RenameFile_Integration(t *testing.T) {
 		assertFileExistsOnDisk(t, newFilePath)
 	})
 
+	t.Run("journals rename as a move", func(t *testing.T) {
+		ctx, _ := newIntegrationTestContext(t)
+		appCtx, ok := ctxservice.FromContext(ctx)
+		require.True(t, ok)
+
+		fs := appCtx.GetFileService()
+
+		userHome, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true))
+		require.NoError(t, err)
+
+		folder := createTestFolder(t, ctx, fs, userHome, "before")
+		child := createTestFile(t, ctx, fs, folder, "child.txt", []byte("content"))
+
+		err = fs.RenameFile(ctx, folder, "after")
+		require.NoError(t, err)
+
+		// Verify: journal resolves both the folder and its child to their new paths
+		latestFolderPath, err := journal.GetLatestPathByID(ctx, folder.ID())
+		require.NoError(t, err)
+		assert.Equal(t, file_model.UsersRootPath.Child("testuser/after", true), latestFolderPath)
+
+		latestChildPath, err := journal.GetLatestPathByID(ctx, child.ID())
+		require.NoError(t, err)
+		assert.Equal(t, file_model.UsersRootPath.Child("testuser/after/child.txt", false), latestChildPath)
+	})
+
 	t.Run("fails when name already exists", func(t *testing.T) {
 		ctx, _ := newIntegrationTestContext(t)
 		appCtx, ok := ctxservice.FromContext(ctx)
@@ -1644,3 +1674,61 @@ func TestFileService_RestoreFiles_Integration(t *testing.T) {
 		assertFileExistsOnDisk(t, restoredFile.GetPortablePath())
 	})
 }
+
+func TestFileService_RestoreFilesFromBackup_Integration(t *testing.T) {
+	t.Run("restores a folder with its contents from the backup", func(t *testing.T) {
+		ctx, _ := newIntegrationTestContext(t)
+		appCtx, ok := ctxservice.FromContext(ctx)
+		require.True(t, ok)
+
+		fs := appCtx.GetFileService()
+
+		userHome, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true))
+		require.NoError(t, err)
+
+		// Past files as described by the journal of a backup, which were never on this tower
+		folderPath := userHome.GetPortablePath().Child("from-backup", true)
+		pastFolder := file_model.NewWeblensFile(file_model.NewFileOptions{Path: folderPath, FileID: "past-folder", IsPastFile: true})
+		pastChild := file_model.NewWeblensFile(file_model.NewFileOptions{
+			Path:       folderPath.Child("child.txt", false),
+			FileID:     "past-child",
+			ContentID:  "backup-content",
+			Size:       int64(len("backed up content")),
+			IsPastFile: true,
+		})
+		pastEmpty := file_model.NewWeblensFile(file_model.NewFileOptions{Path: folderPath.Child("empty.txt", false), FileID: "past-empty", IsPastFile: true})
+
+		for _, child := range []*file_model.WeblensFileImpl{pastChild, pastEmpty} {
+			require.NoError(t, child.SetParent(pastFolder))
+			require.NoError(t, pastFolder.AddChild(child))
+		}
+
+		requested := []string{}
+		openContent := func(contentID string) (io.ReadCloser, error) {
+			requested = append(requested, contentID)
+
+			return io.NopCloser(strings.NewReader("backed up content")), nil
+		}
+
+		err = fs.RestoreFilesFromBackup(ctx, []*file_model.WeblensFileImpl{pastFolder}, userHome, openContent)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"backup-content"}, requested)
+
+		restoredChild, err := fs.GetFileByFilepath(ctx, folderPath.Child("child.txt", false))
+		require.NoError(t, err)
+
+		bs, err := os.ReadFile(restoredChild.GetPortablePath().ToAbsolute())
+		require.NoError(t, err)
+		assert.Equal(t, "backed up content", string(bs))
+
+		restoredEmpty, err := fs.GetFileByFilepath(ctx, folderPath.Child("empty.txt", false))
+		require.NoError(t, err)
+		assertFileExistsOnDisk(t, restoredEmpty.GetPortablePath())
+
+		action, err := history.GetActionAtFilepath(ctx, restoredChild.GetPortablePath())
+		require.NoError(t, err)
+		assert.Equal(t, history.FileRestore, action.ActionType)
+		assert.Equal(t, "past-child", action.OldFileID)
+	})
+}
@@ -0,0 +1,668 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethanrous/weblens/models/featureflags"
+	file_model "github.com/ethanrous/weblens/models/file"
+	"github.com/ethanrous/weblens/models/history"
+	tower_model "github.com/ethanrous/weblens/models/tower"
+	"github.com/ethanrous/weblens/modules/config"
+	websocket_mod "github.com/ethanrous/weblens/modules/websocket"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	file_system "github.com/ethanrous/weblens/modules/wlfs"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/ethanrous/weblens/services/notify"
+	"github.com/ethanrous/weblens/services/reshape"
+	"github.com/fsnotify/fsnotify"
+)
+
+// fileWatchMaxWait is the longest a steady stream of filesystem events can hold off processing the changes already seen.
+const fileWatchMaxWait = time.Second * 10
+
+// Watcher watches the users tree on disk, and brings the file service and the journal up to date with any files that
+// are created, moved or deleted by something other than Weblens.
+//
+// Events are not acted on directly. Once the filesystem has been quiet for the debounce period, each path that saw an
+// event is compared against the in-memory tree, and only the differences are applied. Changes made by Weblens itself
+// are already reflected in the tree once they are done, so they are ignored. Paths Weblens is still part way through
+// changing are left until it has finished, as the tree does not match the disk until then.
+type Watcher struct {
+	fs       *ServiceImpl
+	watcher  *fsnotify.Watcher
+	debounce time.Duration
+	maxDepth int
+
+	// pending holds the absolute paths that have seen an event since the changes were last processed.
+	pending map[string]struct{}
+}
+
+// NewWatcher creates a watcher over the users tree. Folders deeper than maxDepth below the root of the users tree are
+// not watched.
+func NewWatcher(fs *ServiceImpl, debounce time.Duration, maxDepth int) (*Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, wlerrors.WithStack(err)
+	}
+
+	return &Watcher{
+		fs:       fs,
+		watcher:  watcher,
+		debounce: debounce,
+		maxDepth: maxDepth,
+		pending:  make(map[string]struct{}),
+	}, nil
+}
+
+// diskChanges tracks the paths that Weblens itself is changing on disk.
+type diskChanges struct {
+	// active counts, per absolute path, the changes in progress at that path
+	active map[string]int
+	mu     sync.Mutex
+}
+
+// beginDiskChange marks paths as being changed by Weblens, so the file watcher leaves them alone until the returned
+// function is called. It must be called before anything at paths is changed on disk, and the returned function only
+// once the tree and the journal are up to date with the change.
+func (fs *ServiceImpl) beginDiskChange(paths ...file_system.Filepath) func() {
+	absPaths := make([]string, 0, len(paths))
+	for _, path := range paths {
+		absPaths = append(absPaths, filepath.Clean(path.ToAbsolute()))
+	}
+
+	fs.diskChanges.mu.Lock()
+	defer fs.diskChanges.mu.Unlock()
+
+	if fs.diskChanges.active == nil {
+		fs.diskChanges.active = make(map[string]int)
+	}
+
+	for _, absPath := range absPaths {
+		fs.diskChanges.active[absPath]++
+	}
+
+	return func() {
+		fs.diskChanges.mu.Lock()
+		defer fs.diskChanges.mu.Unlock()
+
+		for _, absPath := range absPaths {
+			fs.diskChanges.active[absPath]--
+			if fs.diskChanges.active[absPath] <= 0 {
+				delete(fs.diskChanges.active, absPath)
+			}
+		}
+	}
+}
+
+// isChanging returns true if Weblens is changing absPath, or anything above or below it. The caller must hold mu.
+func (c *diskChanges) isChanging(absPath string) bool {
+	sep := string(filepath.Separator)
+
+	for changing := range c.active {
+		if changing == absPath || strings.HasPrefix(absPath, changing+sep) || strings.HasPrefix(changing, absPath+sep) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func startFileWatcher(ctx context.Context, cnf config.Provider) error {
+	if !cnf.DoFileWatch || tower_model.Role(cnf.InitRole) != tower_model.RoleCore {
+		return nil
+	}
+
+	appCtx, ok := context_service.FromContext(ctx)
+	if !ok {
+		return wlerrors.WithStack(context_service.ErrNoContext)
+	}
+
+	flags, err := featureflags.GetFlags(appCtx)
+	if err != nil {
+		return err
+	}
+
+	if !flags.EnableFileWatch {
+		return nil
+	}
+
+	w, err := NewWatcher(appCtx.FileService.(*ServiceImpl), cnf.FileWatchDebounce, cnf.FileWatchMaxDepth)
+	if err != nil {
+		return err
+	}
+
+	w.watchRecursively(appCtx, file_model.UsersRootPath.ToAbsolute())
+
+	go w.Run(appCtx)
+
+	return nil
+}
+
+// Run processes filesystem events until ctx is cancelled, at which point the watcher is closed.
+func (w *Watcher) Run(ctx context_service.AppContext) {
+	defer w.watcher.Close() //nolint:errcheck
+
+	ctx.Log().Debug().Msgf("Watching [%s] for changes", file_model.UsersRootPath.ToAbsolute())
+
+	debounceTimer := time.NewTimer(w.debounce)
+	debounceTimer.Stop()
+
+	var firstPending time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+
+			// Permission changes do not affect the tree
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+
+			if len(w.pending) == 0 {
+				firstPending = time.Now()
+			}
+
+			w.pending[filepath.Clean(event.Name)] = struct{}{}
+
+			if time.Since(firstPending) > fileWatchMaxWait {
+				debounceTimer.Reset(0)
+			} else {
+				debounceTimer.Reset(w.debounce)
+			}
+		case <-debounceTimer.C:
+			// Turning the watcher off takes effect right away, turning it on takes effect the next time the server starts
+			if flags, err := featureflags.GetFlags(ctx); err == nil && !flags.EnableFileWatch {
+				ctx.Log().Debug().Msg("File watching has been turned off, no longer watching for changes")
+
+				return
+			}
+
+			paths := make([]string, 0, len(w.pending))
+			for path := range w.pending {
+				paths = append(paths, path)
+			}
+
+			clear(w.pending)
+
+			deferred, err := w.fs.applyDiskChanges(ctx, paths)
+			if err != nil {
+				ctx.Log().Error().Stack().Err(err).Msg("Failed to apply changes seen by file watcher")
+			}
+
+			w.updateWatches(ctx, paths)
+
+			// Paths Weblens was still changing are checked again once it is likely to have finished
+			if len(deferred) != 0 {
+				if len(w.pending) == 0 {
+					firstPending = time.Now()
+				}
+
+				for _, path := range deferred {
+					w.pending[path] = struct{}{}
+				}
+
+				debounceTimer.Reset(w.debounce)
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			ctx.Log().Error().Stack().Err(err).Msg("File watcher error")
+		}
+	}
+}
+
+// watchRecursively adds a watch for dir and every folder below it, down to the max depth.
+func (w *Watcher) watchRecursively(ctx context_service.AppContext, dir string) {
+	root := filepath.Clean(file_model.UsersRootPath.ToAbsolute())
+
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			// The folder may have been removed since the event was seen, there is nothing to watch
+			return filepath.SkipDir
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		if watchDepth(root, path) > w.maxDepth {
+			return filepath.SkipDir
+		}
+
+		err = w.watcher.Add(path)
+		if err != nil {
+			ctx.Log().Warn().Err(err).Msgf("Failed to watch [%s]", path)
+
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+	if err != nil {
+		ctx.Log().Warn().Err(err).Msgf("Failed to watch [%s]", dir)
+	}
+}
+
+// updateWatches keeps the set of watched folders in line with the folders that exist on disk after the given paths
+// have changed. Watches follow the folder, not the path, so a moved folder must be unwatched at its old path and
+// watched again at its new one.
+func (w *Watcher) updateWatches(ctx context_service.AppContext, paths []string) {
+	watched := w.watcher.WatchList()
+
+	for _, path := range paths {
+		stat, err := os.Stat(path)
+		if err == nil && stat.IsDir() {
+			w.watchRecursively(ctx, path)
+
+			continue
+		}
+
+		for _, watchedPath := range watched {
+			if watchedPath == path || strings.HasPrefix(watchedPath, path+string(filepath.Separator)) {
+				_ = w.watcher.Remove(watchedPath)
+			}
+		}
+	}
+}
+
+func watchDepth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+// diskChange describes a file that is in the tree but no longer on disk (gone), or on disk but not yet in the tree (appeared).
+type diskChange struct {
+	file *file_model.WeblensFileImpl
+	path file_system.Filepath
+	size int64
+}
+
+// applyDiskChanges compares each of the given absolute paths on disk against the in-memory tree, and journals any
+// differences. A file that disappeared and a file that appeared in the same batch are treated as a move when they
+// can be paired unambiguously, otherwise they are recorded as a delete and a create. Paths Weblens is part way through
+// changing are not compared, and are returned so they can be checked again later.
+func (fs *ServiceImpl) applyDiskChanges(ctx context.Context, absPaths []string) (deferred []string, err error) {
+	appCtx, ok := context_service.FromContext(ctx)
+	if !ok {
+		return nil, wlerrors.WithStack(context_service.ErrNoContext)
+	}
+
+	// Only held while picking out the paths to compare, hashing and journaling the changes must not hold up Weblens
+	// starting changes of its own
+	ready := make([]string, 0, len(absPaths))
+
+	fs.diskChanges.mu.Lock()
+
+	for _, absPath := range absPaths {
+		if fs.diskChanges.isChanging(absPath) {
+			deferred = append(deferred, absPath)
+		} else {
+			ready = append(ready, absPath)
+		}
+	}
+
+	fs.diskChanges.mu.Unlock()
+
+	ctx = history.WithFileEvent(ctx)
+
+	var gone, appeared []diskChange
+
+	for _, absPath := range ready {
+		path, err := file_system.NewFilePath(file_model.UsersTreeKey, absPath)
+		if err != nil {
+			return deferred, err
+		}
+
+		if path.IsRoot() {
+			continue
+		}
+
+		stat, statErr := os.Stat(absPath)
+
+		switch {
+		case statErr == nil:
+			if stat.IsDir() {
+				path.RelPath += "/"
+			}
+
+			if _, inTree := fs.getLoadedFile(path); inTree {
+				continue
+			}
+
+			// Changes inside a folder that has not been loaded will be picked up when it is
+			parent, inTree := fs.getLoadedFile(path.Dir())
+			if !inTree || !parent.ChildrenLoaded() {
+				continue
+			}
+
+			appeared = append(appeared, diskChange{path: path, size: stat.Size()})
+		case os.IsNotExist(statErr):
+			// There is nothing left on disk to tell if this was a file or a folder, so look for both
+			f, inTree := fs.getLoadedFile(path)
+			if !inTree {
+				dirPath := path
+				dirPath.RelPath += "/"
+				f, inTree = fs.getLoadedFile(dirPath)
+			}
+
+			if !inTree {
+				continue
+			}
+
+			gone = append(gone, diskChange{file: f, path: f.GetPortablePath(), size: f.Size()})
+		default:
+			return deferred, wlerrors.WithStack(statErr)
+		}
+	}
+
+	// Handle parents before their children, a child of a folder that has already been handled is skipped below
+	sortChanges := func(a, b diskChange) int { return strings.Compare(a.path.RelPath, b.path.RelPath) }
+	slices.SortFunc(gone, sortChanges)
+	slices.SortFunc(appeared, sortChanges)
+
+	notifs := []websocket_mod.WsResponseInfo{}
+
+	for i := 0; i < len(appeared); i++ {
+		j := matchMove(gone, appeared[i])
+		if j == -1 {
+			continue
+		}
+
+		oldParent := gone[j].file.GetParent()
+
+		moveNotifs, err := fs.applyMove(ctx, gone[j].file, appeared[i].path)
+		if err != nil {
+			return deferred, err
+		}
+
+		err = fs.resizeAfterDiskChange(ctx, oldParent, gone[j].file.GetParent())
+		if err != nil {
+			return deferred, err
+		}
+
+		notifs = append(notifs, moveNotifs...)
+
+		gone = slices.Delete(gone, j, j+1)
+		appeared = slices.Delete(appeared, i, i+1)
+		i--
+	}
+
+	for _, change := range gone {
+		if _, inTree := fs.getLoadedFile(change.path); !inTree {
+			continue
+		}
+
+		parent := change.file.GetParent()
+
+		deleteNotifs, err := fs.applyDelete(ctx, change.file)
+		if err != nil {
+			return deferred, err
+		}
+
+		err = fs.resizeAfterDiskChange(ctx, parent)
+		if err != nil {
+			return deferred, err
+		}
+
+		notifs = append(notifs, deleteNotifs...)
+	}
+
+	for _, change := range appeared {
+		if _, inTree := fs.getLoadedFile(change.path); inTree {
+			continue
+		}
+
+		parent, inTree := fs.getLoadedFile(change.path.Dir())
+		if !inTree {
+			continue
+		}
+
+		err := fs.applyCreate(ctx, parent, change.path)
+		if err != nil {
+			return deferred, err
+		}
+
+		err = fs.resizeAfterDiskChange(ctx, parent)
+		if err != nil {
+			return deferred, err
+		}
+	}
+
+	appCtx.Notify(ctx, notifs...)
+
+	return deferred, nil
+}
+
+// resizeAfterDiskChange brings the sizes of folders, and so the quota usage of their owners, up to date after files
+// inside of them were changed outside of Weblens.
+func (fs *ServiceImpl) resizeAfterDiskChange(ctx context.Context, folders ...*file_model.WeblensFileImpl) error {
+	for _, folder := range folders {
+		err := fs.ResizeUp(ctx, folder)
+		if err != nil {
+			return err
+		}
+
+		NotifyQuotaUsage(ctx, folder)
+	}
+
+	return nil
+}
+
+// matchMove finds the one file in gone that could have become the file that appeared, returning -1 if there is no
+// such file, or if there are several and it is not clear which moved. Moves are either a rename within a folder, or
+// a move to another folder which keeps the same name.
+func matchMove(gone []diskChange, appeared diskChange) int {
+	match := -1
+
+	for i, g := range gone {
+		if g.path.IsDir() != appeared.path.IsDir() {
+			continue
+		}
+
+		if !appeared.path.IsDir() && g.size != appeared.size {
+			continue
+		}
+
+		if g.path.Filename() != appeared.path.Filename() && g.path.Dir() != appeared.path.Dir() {
+			continue
+		}
+
+		if match != -1 {
+			return -1
+		}
+
+		match = i
+	}
+
+	return match
+}
+
+// getLoadedFile finds the file at path in the in-memory tree, without loading anything from disk.
+func (fs *ServiceImpl) getLoadedFile(path file_system.Filepath) (*file_model.WeblensFileImpl, bool) {
+	f, ok := fs.getFileInternal(path.RootName())
+	if !ok {
+		return nil, false
+	}
+
+	for child := range strings.SplitSeq(path.RelPath, "/") {
+		if child == "" {
+			continue
+		}
+
+		if !f.ChildrenLoaded() {
+			return nil, false
+		}
+
+		var err error
+
+		f, err = f.GetChild(child)
+		if err != nil {
+			return nil, false
+		}
+	}
+
+	if f.GetPortablePath().IsDir() != path.IsDir() {
+		return nil, false
+	}
+
+	return f, true
+}
+
+// applyCreate adds the file at path, which exists on disk but not in the tree, to the tree and the journal. Folders
+// are added along with everything inside of them.
+func (fs *ServiceImpl) applyCreate(ctx context.Context, parent *file_model.WeblensFileImpl, path file_system.Filepath) error {
+	newF := file_model.NewWeblensFile(file_model.NewFileOptions{Path: path})
+
+	if needsContentID(newF) {
+		_, err := file_model.GenerateContentID(ctx, newF)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := fs.createCommon(ctx, newF, parent)
+	if err != nil {
+		return err
+	}
+
+	if !newF.IsDir() {
+		return nil
+	}
+
+	newF.InitChildren()
+
+	childPaths, err := getChildFilepaths(path)
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	for _, childPath := range childPaths {
+		err = fs.applyCreate(ctx, newF, childPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyMove moves file, which is no longer at its path on disk, to newPath in the tree and the journal.
+func (fs *ServiceImpl) applyMove(ctx context.Context, file *file_model.WeblensFileImpl, newPath file_system.Filepath) ([]websocket_mod.WsResponseInfo, error) {
+	newParent, ok := fs.getLoadedFile(newPath.Dir())
+	if !ok {
+		return nil, wlerrors.Errorf("parent of [%s] is not loaded", newPath)
+	}
+
+	oldPath := file.GetPortablePath()
+	oldParent := file.GetParent()
+
+	err := oldParent.RemoveChild(oldPath.Filename())
+	if err != nil {
+		return nil, err
+	}
+
+	actions := []history.FileAction{}
+
+	err = file.RecursiveMap(func(wfi *file_model.WeblensFileImpl) error {
+		newFilePath, err := wfi.GetPortablePath().ReplacePrefix(oldPath, newPath)
+		if err != nil {
+			return err
+		}
+
+		actions = append(actions, history.NewMoveAction(ctx, wfi.GetPortablePath(), newFilePath, wfi))
+
+		wfi.SetPortablePath(newFilePath)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = history.SaveActions(ctx, actions)
+	if err != nil {
+		return nil, err
+	}
+
+	err = file.SetParent(newParent)
+	if err != nil {
+		return nil, err
+	}
+
+	err = newParent.AddChild(file)
+	if err != nil {
+		return nil, err
+	}
+
+	fInfo, err := reshape.WeblensFileToFileInfo(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	return notify.NewFileNotification(ctx, fInfo, websocket_mod.FileMovedEvent, notify.FileNotificationOptions{PreMoveParentID: oldParent.ID()}), nil
+}
+
+// applyDelete removes file, which is no longer on disk, and everything inside of it from the tree and records
+// the deletion in the journal. Unlike a delete made through Weblens, the contents are already gone and so cannot be
+// kept for restoring later.
+func (fs *ServiceImpl) applyDelete(ctx context.Context, file *file_model.WeblensFileImpl) ([]websocket_mod.WsResponseInfo, error) {
+	actions := []history.FileAction{}
+	notifs := []websocket_mod.WsResponseInfo{}
+
+	err := file.RecursiveMap(func(f *file_model.WeblensFileImpl) error {
+		err := rmFileMedia(ctx, f)
+		if err != nil {
+			return err
+		}
+
+		actions = append(actions, history.NewDeleteAction(ctx, f))
+
+		fInfo, err := reshape.WeblensFileToFileInfo(ctx, f)
+		if err != nil {
+			return err
+		}
+
+		notifs = append(notifs, notify.NewFileNotification(ctx, fInfo, websocket_mod.FileDeletedEvent)...)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = history.SaveActions(ctx, actions)
+	if err != nil {
+		return nil, err
+	}
+
+	err = file.GetParent().RemoveChild(file.GetPortablePath().Filename())
+	if err != nil {
+		return nil, err
+	}
+
+	err = file.RecursiveMap(func(wfi *file_model.WeblensFileImpl) error {
+		return fs.removeFileByID(ctx, wfi.ID())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return notifs, nil
+}
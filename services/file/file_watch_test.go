@@ -0,0 +1,266 @@
+package file //nolint:testpackage
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethanrous/weblens/models/featureflags"
+	file_model "github.com/ethanrous/weblens/models/file"
+	"github.com/ethanrous/weblens/models/history"
+	ctxservice "github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileService_ApplyDiskChanges(t *testing.T) {
+	t.Run("journals file created on disk", func(t *testing.T) {
+		ctx, _ := newIntegrationTestContext(t)
+		appCtx, ok := ctxservice.FromContext(ctx)
+		require.True(t, ok)
+
+		fs := appCtx.FileService.(*ServiceImpl)
+
+		userHome, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true))
+		require.NoError(t, err)
+
+		newPath := userHome.GetPortablePath().Child("outside.txt", false)
+		require.NoError(t, os.WriteFile(newPath.ToAbsolute(), []byte("written outside weblens"), 0o600))
+
+		_, err = fs.applyDiskChanges(ctx, []string{newPath.ToAbsolute()})
+		require.NoError(t, err)
+
+		created, ok := fs.getLoadedFile(newPath)
+		require.True(t, ok)
+		assert.NotEmpty(t, created.GetContentID())
+
+		action, err := history.GetActionAtFilepath(ctx, newPath)
+		require.NoError(t, err)
+		assert.Equal(t, history.FileCreate, action.ActionType)
+		assert.Equal(t, created.ID(), action.FileID)
+	})
+
+	t.Run("journals folder created on disk with its contents", func(t *testing.T) {
+		ctx, _ := newIntegrationTestContext(t)
+		appCtx, ok := ctxservice.FromContext(ctx)
+		require.True(t, ok)
+
+		fs := appCtx.FileService.(*ServiceImpl)
+
+		userHome, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true))
+		require.NoError(t, err)
+
+		folderPath := userHome.GetPortablePath().Child("outside", true)
+		require.NoError(t, os.MkdirAll(folderPath.Child("nested", true).ToAbsolute(), 0o755))
+		require.NoError(t, os.WriteFile(folderPath.Child("nested/file.txt", false).ToAbsolute(), []byte("nested"), 0o600))
+
+		_, err = fs.applyDiskChanges(ctx, []string{folderPath.AsFile().ToAbsolute()})
+		require.NoError(t, err)
+
+		_, ok = fs.getLoadedFile(folderPath)
+		assert.True(t, ok)
+
+		_, ok = fs.getLoadedFile(folderPath.Child("nested/file.txt", false))
+		assert.True(t, ok)
+	})
+
+	t.Run("journals file renamed on disk as a move", func(t *testing.T) {
+		ctx, _ := newIntegrationTestContext(t)
+		appCtx, ok := ctxservice.FromContext(ctx)
+		require.True(t, ok)
+
+		fs := appCtx.FileService.(*ServiceImpl)
+
+		userHome, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true))
+		require.NoError(t, err)
+
+		original := createTestFile(t, ctx, fs, userHome, "before.txt", []byte("rename me"))
+		oldPath := original.GetPortablePath()
+		newPath := userHome.GetPortablePath().Child("after.txt", false)
+
+		require.NoError(t, os.Rename(oldPath.ToAbsolute(), newPath.ToAbsolute()))
+
+		_, err = fs.applyDiskChanges(ctx, []string{oldPath.ToAbsolute(), newPath.ToAbsolute()})
+		require.NoError(t, err)
+
+		moved, ok := fs.getLoadedFile(newPath)
+		require.True(t, ok)
+		assert.Equal(t, original.ID(), moved.ID())
+
+		_, ok = fs.getLoadedFile(oldPath)
+		assert.False(t, ok)
+
+		action, err := history.GetActionAtFilepath(ctx, newPath)
+		require.NoError(t, err)
+		assert.Equal(t, history.FileMove, action.ActionType)
+		assert.Equal(t, oldPath, action.OriginPath)
+	})
+
+	t.Run("journals file removed from disk", func(t *testing.T) {
+		ctx, _ := newIntegrationTestContext(t)
+		appCtx, ok := ctxservice.FromContext(ctx)
+		require.True(t, ok)
+
+		fs := appCtx.FileService.(*ServiceImpl)
+
+		userHome, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true))
+		require.NoError(t, err)
+
+		removed := createTestFile(t, ctx, fs, userHome, "removed.txt", []byte("delete me"))
+
+		require.NoError(t, os.Remove(removed.GetPortablePath().ToAbsolute()))
+
+		_, err = fs.applyDiskChanges(ctx, []string{removed.GetPortablePath().ToAbsolute()})
+		require.NoError(t, err)
+
+		assertFileNotInService(t, ctx, fs, removed.ID())
+
+		action, err := history.GetActionAtFilepath(ctx, removed.GetPortablePath())
+		require.NoError(t, err)
+		assert.Equal(t, history.FileDelete, action.ActionType)
+	})
+
+	t.Run("ignores changes made through the file service", func(t *testing.T) {
+		ctx, _ := newIntegrationTestContext(t)
+		appCtx, ok := ctxservice.FromContext(ctx)
+		require.True(t, ok)
+
+		fs := appCtx.FileService.(*ServiceImpl)
+
+		userHome, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true))
+		require.NoError(t, err)
+
+		created := createTestFile(t, ctx, fs, userHome, "known.txt", []byte("already known"))
+
+		_, err = fs.applyDiskChanges(ctx, []string{created.GetPortablePath().ToAbsolute()})
+		require.NoError(t, err)
+
+		existing, ok := fs.getLoadedFile(created.GetPortablePath())
+		require.True(t, ok)
+		assert.Equal(t, created.ID(), existing.ID())
+
+		action, err := history.GetActionAtFilepath(ctx, created.GetPortablePath())
+		require.NoError(t, err)
+		assert.Equal(t, created.ID(), action.FileID)
+	})
+
+	t.Run("leaves paths being changed by weblens for later", func(t *testing.T) {
+		ctx, _ := newIntegrationTestContext(t)
+		appCtx, ok := ctxservice.FromContext(ctx)
+		require.True(t, ok)
+
+		fs := appCtx.FileService.(*ServiceImpl)
+
+		userHome, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true))
+		require.NoError(t, err)
+
+		newPath := userHome.GetPortablePath().Child("in_progress.txt", false)
+
+		done := fs.beginDiskChange(newPath)
+		require.NoError(t, os.WriteFile(newPath.ToAbsolute(), []byte("not finished yet"), 0o600))
+
+		deferred, err := fs.applyDiskChanges(ctx, []string{newPath.ToAbsolute()})
+		require.NoError(t, err)
+		assert.Equal(t, []string{newPath.ToAbsolute()}, deferred)
+
+		_, ok = fs.getLoadedFile(newPath)
+		assert.False(t, ok)
+
+		done()
+
+		deferred, err = fs.applyDiskChanges(ctx, []string{newPath.ToAbsolute()})
+		require.NoError(t, err)
+		assert.Empty(t, deferred)
+
+		_, ok = fs.getLoadedFile(newPath)
+		assert.True(t, ok)
+	})
+}
+
+func TestWatcher_InProcessMove(t *testing.T) {
+	ctx, _ := newIntegrationTestContext(t)
+	appCtx, ok := ctxservice.FromContext(ctx)
+	require.True(t, ok)
+
+	require.NoError(t, featureflags.SaveFlags(appCtx, featureflags.Bundle{EnableFileWatch: true}))
+	t.Cleanup(func() {
+		_ = featureflags.SaveFlags(appCtx, featureflags.Default())
+	})
+
+	fs := appCtx.FileService.(*ServiceImpl)
+
+	userHome, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true))
+	require.NoError(t, err)
+
+	moved := createTestFile(t, ctx, fs, userHome, "moved.txt", []byte("moved while watched"))
+	oldPath := moved.GetPortablePath()
+
+	dest, err := fs.CreateFolder(ctx, userHome, "dest")
+	require.NoError(t, err)
+
+	const debounce = 20 * time.Millisecond
+
+	w, err := NewWatcher(fs, debounce, 10)
+	require.NoError(t, err)
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	runCtx, ok := ctxservice.FromContext(watchCtx)
+	require.True(t, ok)
+
+	w.watchRecursively(runCtx, file_model.UsersRootPath.ToAbsolute())
+
+	go w.Run(runCtx)
+
+	before := time.Now()
+
+	require.NoError(t, fs.MoveFiles(ctx, []*file_model.WeblensFileImpl{moved}, dest))
+
+	// Give the watcher time to see, and pass over, the events of the move
+	time.Sleep(debounce * 10)
+
+	newPath := dest.GetPortablePath().Child("moved.txt", false)
+
+	got, ok := fs.getLoadedFile(newPath)
+	require.True(t, ok)
+	assert.Equal(t, moved.ID(), got.ID())
+
+	actions, err := history.GetActionsAtPathAfter(ctx, newPath, before)
+	require.NoError(t, err)
+	require.Len(t, actions, 1)
+	assert.Equal(t, history.FileMove, actions[0].ActionType)
+
+	// The only action at the old path is the move itself, the watcher must not have journaled a delete
+	actions, err = history.GetActionsAtPathAfter(ctx, oldPath, before)
+	require.NoError(t, err)
+	require.Len(t, actions, 1)
+	assert.Equal(t, history.FileMove, actions[0].ActionType)
+}
+
+func TestMatchMove(t *testing.T) {
+	home := file_model.UsersRootPath.Child("testuser", true)
+
+	gone := []diskChange{
+		{path: home.Child("a.txt", false), size: 10},
+		{path: home.Child("folder", true)},
+	}
+
+	t.Run("matches rename in the same folder", func(t *testing.T) {
+		assert.Equal(t, 0, matchMove(gone, diskChange{path: home.Child("b.txt", false), size: 10}))
+	})
+
+	t.Run("matches move keeping the same name", func(t *testing.T) {
+		assert.Equal(t, 1, matchMove(gone, diskChange{path: home.Child("other/folder", true)}))
+	})
+
+	t.Run("does not match a file of a different size", func(t *testing.T) {
+		assert.Equal(t, -1, matchMove(gone, diskChange{path: home.Child("b.txt", false), size: 11}))
+	})
+
+	t.Run("does not match an ambiguous move", func(t *testing.T) {
+		ambiguous := []diskChange{gone[0], gone[1], {path: home.Child("c.txt", false), size: 10}}
+		assert.Equal(t, -1, matchMove(ambiguous, diskChange{path: home.Child("d.txt", false), size: 10}))
+	})
+}
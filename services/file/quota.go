@@ -0,0 +1,202 @@
+package file
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/ethanrous/weblens/models/featureflags"
+	file_model "github.com/ethanrous/weblens/models/file"
+	websocket_mod "github.com/ethanrous/weblens/modules/websocket"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlog"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/ethanrous/weblens/services/notify"
+)
+
+// ErrQuotaExceeded is returned when a write would take a user past their storage quota.
+var ErrQuotaExceeded = wlerrors.Statusf(http.StatusInsufficientStorage, "storage quota exceeded")
+
+// GetUsage returns how many bytes the files of username take up, including the files in their trash.
+func GetUsage(ctx context.Context, username string) (int64, error) {
+	appCtx, ok := context_service.FromContext(ctx)
+	if !ok {
+		return 0, wlerrors.WithStack(context_service.ErrNoContext)
+	}
+
+	home, err := appCtx.FileService.GetFileByFilepath(ctx, file_model.UsersRootPath.Child(username, true))
+	if err != nil {
+		return 0, err
+	}
+
+	return home.Size(), nil
+}
+
+// quotaReservation is space set aside in the quota of a user for a write that is still in progress.
+type quotaReservation struct {
+	// counted is the file being written, if it is already in the tree. Its current size is already part of the usage
+	// of the user, so only what the write adds past it is held back.
+	counted  *file_model.WeblensFileImpl
+	username string
+	bytes    int64
+}
+
+// pending returns how many bytes of the reservation are not yet part of the usage of the user.
+func (r quotaReservation) pending() int64 {
+	if r.counted == nil {
+		return r.bytes
+	}
+
+	return max(0, r.bytes-r.counted.Size())
+}
+
+// Writes that arrive over several requests reserve the space they will need up front, so that concurrent writes can
+// not each pass the quota check and together go past the quota.
+var (
+	quotaReservations   = map[string]quotaReservation{}
+	quotaReservationsMu sync.Mutex
+)
+
+// CheckQuota returns ErrQuotaExceeded if writing additionalBytes inside of folder would take the owner of folder past
+// their storage quota. Space is charged to the owner of the folder rather than to whoever is writing, so edits made
+// through a share count against the user who shared the folder. Space reserved by writes still in progress is counted
+// as used.
+func CheckQuota(ctx context.Context, folder *file_model.WeblensFileImpl, additionalBytes int64) error {
+	quotaReservationsMu.Lock()
+	defer quotaReservationsMu.Unlock()
+
+	_, err := checkQuota(ctx, folder, "", additionalBytes)
+
+	return err
+}
+
+// ReserveQuota sets aside space in the quota of the owner of folder for a write, identified by key, that will leave
+// bytes in place once it is done. Reserving again under the same key replaces the earlier reservation, so a write can
+// reserve again as it goes, or after the server restarts. counted is the file being written, if it is already in the
+// tree, and may be nil. ErrQuotaExceeded is returned, and nothing is reserved, if the space is not available.
+func ReserveQuota(ctx context.Context, folder *file_model.WeblensFileImpl, key string, bytes int64, counted *file_model.WeblensFileImpl) error {
+	quotaReservationsMu.Lock()
+	defer quotaReservationsMu.Unlock()
+
+	reservation := quotaReservation{counted: counted, bytes: bytes}
+
+	username, err := checkQuota(ctx, folder, key, reservation.pending())
+	if err != nil {
+		return err
+	}
+
+	if username == "" {
+		delete(quotaReservations, key)
+
+		return nil
+	}
+
+	reservation.username = username
+	quotaReservations[key] = reservation
+
+	return nil
+}
+
+// ReleaseQuota gives back the space reserved under key. It must be called once the write is finished, and the file
+// it wrote is counted in the tree, or once the write is abandoned.
+func ReleaseQuota(key string) {
+	quotaReservationsMu.Lock()
+	defer quotaReservationsMu.Unlock()
+
+	delete(quotaReservations, key)
+}
+
+// checkQuota returns ErrQuotaExceeded if writing additionalBytes inside of folder would take its owner past their
+// quota, leaving out the reservation under skipKey. The name of the owner is returned if they have a quota.
+// quotaReservationsMu must be held.
+func checkQuota(ctx context.Context, folder *file_model.WeblensFileImpl, skipKey string, additionalBytes int64) (string, error) {
+	if folder.GetPortablePath().RootName() != file_model.UsersTreeKey || folder.GetPortablePath().IsRoot() {
+		return "", nil
+	}
+
+	owner, err := GetFileOwner(ctx, folder)
+	if err != nil {
+		return "", err
+	}
+
+	if !owner.HasQuota() {
+		return "", nil
+	}
+
+	usage, err := GetUsage(ctx, owner.GetUsername())
+	if err != nil {
+		return "", err
+	}
+
+	var reserved int64
+
+	for key, reservation := range quotaReservations {
+		if key != skipKey && reservation.username == owner.GetUsername() {
+			reserved += reservation.pending()
+		}
+	}
+
+	if usage+reserved+additionalBytes > owner.QuotaBytes {
+		return "", wlerrors.Wrapf(
+			ErrQuotaExceeded, "[%s] is using %d of %d bytes, with %d more reserved, and cannot store %d more",
+			owner.GetUsername(), usage, owner.QuotaBytes, reserved, additionalBytes,
+		)
+	}
+
+	return owner.GetUsername(), nil
+}
+
+// NotifyQuotaUsage warns the owner of folder over the websocket if they have used more of their storage quota than the
+// server's warning threshold. It is meant to be called after files are written, and failures are only logged.
+func NotifyQuotaUsage(ctx context.Context, folder *file_model.WeblensFileImpl) {
+	if folder.GetPortablePath().RootName() != file_model.UsersTreeKey || folder.GetPortablePath().IsRoot() {
+		return
+	}
+
+	log := wlog.FromContext(ctx)
+
+	owner, err := GetFileOwner(ctx, folder)
+	if err != nil {
+		log.Error().Stack().Err(err).Msgf("Failed to get owner of [%s] to check their quota", folder.GetPortablePath())
+
+		return
+	}
+
+	if !owner.HasQuota() {
+		return
+	}
+
+	flags, err := featureflags.GetFlags(ctx)
+	if err != nil {
+		log.Error().Stack().Err(err).Msg("Failed to get feature flags to check quota usage")
+
+		return
+	}
+
+	if flags.QuotaWarningPercent <= 0 {
+		return
+	}
+
+	usage, err := GetUsage(ctx, owner.GetUsername())
+	if err != nil {
+		log.Error().Stack().Err(err).Msgf("Failed to get storage usage of [%s]", owner.GetUsername())
+
+		return
+	}
+
+	if usage*100 < owner.QuotaBytes*int64(flags.QuotaWarningPercent) {
+		return
+	}
+
+	appCtx, ok := context_service.FromContext(ctx)
+	if !ok {
+		return
+	}
+
+	notif := notify.NewUserNotification(
+		owner.GetUsername(),
+		websocket_mod.QuotaWarningEvent,
+		websocket_mod.WsData{"usedBytes": usage, "quotaBytes": owner.QuotaBytes, "warningPercent": flags.QuotaWarningPercent},
+	)
+	appCtx.Notify(ctx, notif)
+}
@@ -0,0 +1,64 @@
+package file //nolint:testpackage
+
+import (
+	"testing"
+
+	file_model "github.com/ethanrous/weblens/models/file"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	ctxservice "github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckQuota_Integration(t *testing.T) {
+	t.Run("allows writes when the user has no quota", func(t *testing.T) {
+		ctx, _ := newIntegrationTestContext(t)
+		appCtx, ok := ctxservice.FromContext(ctx)
+		require.True(t, ok)
+
+		fs := appCtx.GetFileService()
+
+		userHome, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true))
+		require.NoError(t, err)
+
+		err = CheckQuota(ctx, userHome, 1<<40)
+		assert.NoError(t, err)
+	})
+
+	t.Run("allows writes that fit inside the quota", func(t *testing.T) {
+		ctx, _ := newIntegrationTestContext(t, withQuota(1<<20))
+		appCtx, ok := ctxservice.FromContext(ctx)
+		require.True(t, ok)
+
+		fs := appCtx.GetFileService()
+
+		userHome, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true))
+		require.NoError(t, err)
+
+		source := createTestFile(t, ctx, fs, userHome, "source.txt", []byte("12345678"))
+		dest := createTestFolder(t, ctx, fs, userHome, "dest")
+
+		copies, err := fs.CopyFiles(ctx, []*file_model.WeblensFileImpl{source}, dest, nil)
+		require.NoError(t, err)
+		assert.Len(t, copies, 1)
+	})
+
+	t.Run("rejects a copy that would exceed the quota", func(t *testing.T) {
+		ctx, _ := newIntegrationTestContext(t, withQuota(12))
+		appCtx, ok := ctxservice.FromContext(ctx)
+		require.True(t, ok)
+
+		fs := appCtx.GetFileService()
+
+		userHome, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true))
+		require.NoError(t, err)
+
+		source := createTestFile(t, ctx, fs, userHome, "source.txt", []byte("12345678"))
+		dest := createTestFolder(t, ctx, fs, userHome, "dest")
+
+		_, err = fs.CopyFiles(ctx, []*file_model.WeblensFileImpl{source}, dest, nil)
+		assert.True(t, wlerrors.Is(err, ErrQuotaExceeded))
+
+		assertFileNotExistsOnDisk(t, file_model.UsersRootPath.Child("testuser/dest/source.txt", false))
+	})
+}
@@ -0,0 +1,251 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	file_model "github.com/ethanrous/weblens/models/file"
+	"github.com/ethanrous/weblens/models/history"
+	websocket_mod "github.com/ethanrous/weblens/modules/websocket"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	file_system "github.com/ethanrous/weblens/modules/wlfs"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/ethanrous/weblens/services/notify"
+	"github.com/ethanrous/weblens/services/reshape"
+)
+
+// ErrRevisionUnavailable is returned when the content of a past revision is no longer kept in the restore tree.
+var ErrRevisionUnavailable = wlerrors.Statusf(http.StatusGone, "content of revision is no longer available")
+
+// ReplaceFileContent makes the file at newContentPath the new content of file, keeping its ID and path. The content
+// being replaced is linked into the restore tree first, so it can be read or restored later, and the change is
+// journaled as a revision of the file. newContentPath is moved, not copied, and must not be used again afterwards.
+func (fs *ServiceImpl) ReplaceFileContent(ctx context.Context, file *file_model.WeblensFileImpl, newContentPath string) error {
+	if file.IsDir() {
+		return wlerrors.WithStack(file_model.ErrDirectoryNotAllowed)
+	}
+
+	appCtx, ok := context_service.FromContext(ctx)
+	if !ok {
+		return wlerrors.WithStack(context_service.ErrNoContext)
+	}
+
+	newStat, err := os.Stat(newContentPath)
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	// The replaced content is kept in the restore tree, which is not charged to the owner, so only growth counts
+	if growth := newStat.Size() - file.Size(); growth > 0 {
+		err = CheckQuota(ctx, file.GetParent(), growth)
+		if err != nil {
+			return err
+		}
+	}
+
+	defer fs.beginDiskChange(file.GetPortablePath())()
+
+	if file.Size() > 0 {
+		err := linkToRestore(ctx, file)
+		if err != nil {
+			return err
+		}
+	}
+
+	oldContentID := file.GetContentID()
+
+	// The old content is still linked from the restore tree, so it must be replaced with a rename, never written over
+	err = moveIntoPlace(newContentPath, file.GetPortablePath().ToAbsolute())
+	if err != nil {
+		return err
+	}
+
+	stat, err := os.Stat(file.GetPortablePath().ToAbsolute())
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	// The media and embeddings of the old content no longer describe this file
+	err = rmFileMedia(ctx, file)
+	if err != nil {
+		return err
+	}
+
+	file.SetSize(stat.Size())
+	file.SetModifiedTime(stat.ModTime())
+	file.SetContentID("")
+
+	if stat.Size() > 0 {
+		_, err = file_model.GenerateContentID(ctx, file)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, skipJournal := ctx.Value(SkipJournalKey).(bool); !skipJournal {
+		if _, ok := history.FileEventFromContext(ctx); !ok {
+			ctx = history.WithFileEvent(ctx)
+		}
+
+		action := history.NewRevisionAction(ctx, file, oldContentID)
+
+		err = history.SaveAction(ctx, &action)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = fs.ResizeUp(ctx, file.GetParent())
+	if err != nil {
+		return err
+	}
+
+	fInfo, err := reshape.WeblensFileToFileInfo(ctx, file)
+	if err != nil {
+		return err
+	}
+
+	appCtx.Notify(ctx, notify.NewFileNotification(ctx, fInfo, websocket_mod.FileUpdatedEvent)...)
+
+	NotifyQuotaUsage(ctx, file.GetParent())
+
+	return nil
+}
+
+// OpenRevision opens the content file had as of revision. The live file is opened if revision gave file the content
+// it has now, otherwise the content is read from the restore tree.
+func OpenRevision(file *file_model.WeblensFileImpl, revision history.FileAction) (io.ReadSeekCloser, error) {
+	if revision.ContentID == "" {
+		return nopSeekCloser{bytes.NewReader(nil)}, nil
+	}
+
+	path := file_system.BuildFilePath(file_model.RestoreTreeKey, revision.ContentID)
+	if revision.ContentID == file.GetContentID() {
+		path = file.GetPortablePath()
+	}
+
+	f, err := os.Open(path.ToAbsolute())
+	if os.IsNotExist(err) {
+		return nil, wlerrors.WithStack(ErrRevisionUnavailable)
+	} else if err != nil {
+		return nil, wlerrors.WithStack(err)
+	}
+
+	return f, nil
+}
+
+// IsRevisionAvailable returns true if the content file had as of revision can still be read.
+func IsRevisionAvailable(file *file_model.WeblensFileImpl, revision history.FileAction) bool {
+	if revision.ContentID == "" || revision.ContentID == file.GetContentID() {
+		return true
+	}
+
+	return exists(file_system.BuildFilePath(file_model.RestoreTreeKey, revision.ContentID))
+}
+
+// RestoreFileRevision makes the content file had as of revision its current content again. The restore is itself
+// journaled as a new revision, so the content it replaces can be restored in turn.
+func RestoreFileRevision(ctx context.Context, file *file_model.WeblensFileImpl, revision history.FileAction) error {
+	if revision.ContentID != "" && revision.ContentID == file.GetContentID() {
+		return nil
+	}
+
+	appCtx, ok := context_service.FromContext(ctx)
+	if !ok {
+		return wlerrors.WithStack(context_service.ErrNoContext)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file.GetPortablePath().ToAbsolute()), ".weblens-revision-*")
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	tmpPath := tmp.Name()
+
+	err = tmp.Close()
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	if revision.ContentID != "" {
+		// The restore tree keeps its own link to the content, so the restored file can share it rather than copy it
+		restorePath := file_system.BuildFilePath(file_model.RestoreTreeKey, revision.ContentID).ToAbsolute()
+
+		err = os.Remove(tmpPath)
+		if err != nil {
+			return wlerrors.WithStack(err)
+		}
+
+		err = os.Link(restorePath, tmpPath)
+		if os.IsNotExist(err) {
+			return wlerrors.WithStack(ErrRevisionUnavailable)
+		} else if err != nil {
+			return wlerrors.WithStack(err)
+		}
+	}
+
+	err = appCtx.FileService.ReplaceFileContent(ctx, file, tmpPath)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+
+		return err
+	}
+
+	return nil
+}
+
+// moveIntoPlace renames src over dst. If src is on a different filesystem, it is first copied next to dst, so dst is
+// still replaced in a single rename and never left partially written.
+func moveIntoPlace(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+	defer in.Close() //nolint:errcheck
+
+	out, err := os.CreateTemp(filepath.Dir(dst), ".weblens-revision-*")
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		_ = out.Close()
+		_ = os.Remove(out.Name())
+
+		return wlerrors.WithStack(err)
+	}
+
+	err = out.Close()
+	if err != nil {
+		_ = os.Remove(out.Name())
+
+		return wlerrors.WithStack(err)
+	}
+
+	err = os.Rename(out.Name(), dst)
+	if err != nil {
+		_ = os.Remove(out.Name())
+
+		return wlerrors.WithStack(err)
+	}
+
+	return wlerrors.WithStack(os.Remove(src))
+}
+
+// nopSeekCloser adds a no-op Close to a reader that needs no cleanup.
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error {
+	return nil
+}
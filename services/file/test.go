@@ -0,0 +1,114 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethanrous/weblens/models/db"
+	file_model "github.com/ethanrous/weblens/models/file"
+	"github.com/ethanrous/weblens/models/history"
+	tower_model "github.com/ethanrous/weblens/models/tower"
+	user_model "github.com/ethanrous/weblens/models/usermodel"
+	"github.com/ethanrous/weblens/modules/wlfs"
+	"github.com/ethanrous/weblens/modules/wlog"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/ethanrous/weblens/services/notify"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SetupTestFileService creates a file service over a temporary directory for each of the file trees, for tests of
+// packages that change files through it. dbCtx must come from db.SetupTestDB. The local tower is a core, and username
+// is given a home folder, a trash, and quotaBytes of storage, where 0 is no limit. The returned context carries an
+// AppContext with the file service, and a file event for journaling.
+func SetupTestFileService(t *testing.T, dbCtx context.Context, username string, quotaBytes int64) context.Context {
+	t.Helper()
+
+	for _, collectionKey := range []string{history.FileHistoryCollectionKey, tower_model.TowerCollectionKey, user_model.UserCollectionKey} {
+		col, err := db.GetCollection[any](dbCtx, collectionKey)
+		require.NoError(t, err)
+		require.NoError(t, col.Drop(dbCtx))
+
+		t.Cleanup(func() {
+			_ = col.Drop(dbCtx)
+		})
+	}
+
+	tempDir := t.TempDir()
+
+	for treeKey, dirName := range map[string]string{
+		file_model.UsersTreeKey:   "USERS",
+		file_model.RestoreTreeKey: "RESTORE",
+		file_model.BackupTreeKey:  "BACKUP",
+		file_model.CachesTreeKey:  "CACHES",
+	} {
+		dir := filepath.Join(tempDir, dirName)
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		require.NoError(t, wlfs.RegisterAbsolutePrefix(treeKey, dir))
+	}
+
+	require.NoError(t, os.MkdirAll(file_model.UploadsDirPath.ToAbsolute(), 0o755))
+
+	towerID := primitive.NewObjectID().Hex()
+
+	towerCol, err := db.GetCollection[any](dbCtx, tower_model.TowerCollectionKey)
+	require.NoError(t, err)
+
+	_, err = towerCol.GetCollection().InsertOne(dbCtx, tower_model.Instance{
+		TowerID:     towerID,
+		Name:        "Test Tower",
+		Role:        tower_model.RoleCore,
+		IsThisTower: true,
+		DbID:        primitive.NewObjectID(),
+	})
+	require.NoError(t, err)
+
+	userCol, err := db.GetCollection[any](dbCtx, user_model.UserCollectionKey)
+	require.NoError(t, err)
+
+	_, err = userCol.GetCollection().InsertOne(dbCtx, user_model.User{
+		ID:         primitive.NewObjectID(),
+		Username:   username,
+		UserPerms:  user_model.UserPermissionBasic,
+		Activated:  true,
+		QuotaBytes: quotaBytes,
+	})
+	require.NoError(t, err)
+
+	database, _ := dbCtx.Value(db.DatabaseContextKey).(*mongo.Database)
+
+	appCtx := context_service.NewAppContext(context_service.NewBasicContext(dbCtx, wlog.NewZeroLogger()))
+	appCtx.DB = database
+	appCtx.LocalTowerID = towerID
+	appCtx.ClientService = notify.NewClientManager(appCtx.WithContext(dbCtx))
+
+	fs, err := NewFileService(appCtx.WithContext(dbCtx))
+	require.NoError(t, err)
+
+	appCtx.FileService = fs
+
+	for _, rootPath := range []wlfs.Filepath{file_model.UsersRootPath, file_model.RestoreDirPath, file_model.BackupRootPath, file_model.CacheRootPath} {
+		root := file_model.NewWeblensFile(file_model.NewFileOptions{Path: rootPath, CreateNow: true, GenerateID: true})
+		if root != nil {
+			fs.setFileInternal(root.ID(), root)
+		}
+	}
+
+	ctx := appCtx.WithContext(dbCtx)
+	ctx = context.WithValue(ctx, "towerID", towerID) //nolint:revive
+	ctx = history.WithFileEvent(ctx)
+
+	usersRoot, err := fs.GetFileByID(ctx, file_model.UsersTreeKey)
+	require.NoError(t, err)
+
+	home, err := fs.CreateFolder(ctx, usersRoot, username)
+	require.NoError(t, err)
+
+	_, err = fs.CreateFolder(ctx, home, file_model.UserTrashDirName)
+	require.NoError(t, err)
+
+	return ctx
+}
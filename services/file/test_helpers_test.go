@@ -11,6 +11,7 @@ import (
 	"github.com/ethanrous/weblens/models/history"
 	media_model "github.com/ethanrous/weblens/models/media"
 	tower_model "github.com/ethanrous/weblens/models/tower"
+	user_model "github.com/ethanrous/weblens/models/usermodel"
 	"github.com/ethanrous/weblens/modules/wlfs"
 	"github.com/ethanrous/weblens/modules/wlog"
 	"github.com/ethanrous/weblens/services/ctxservice"
@@ -59,8 +60,9 @@ func (c *integrationTestCleanup) cleanup(t *testing.T) {
 
 // testContextOptions configures the integration test context.
 type testContextOptions struct {
-	towerRole tower_model.Role
-	username  string
+	towerRole  tower_model.Role
+	username   string
+	quotaBytes int64
 }
 
 // testContextOption is a functional option for configuring integration test context.
@@ -73,6 +75,13 @@ func withTowerRole(role tower_model.Role) testContextOption {
 	}
 }
 
+// withQuota sets the storage quota of the test user (default: no limit).
+func withQuota(quotaBytes int64) testContextOption {
+	return func(opts *testContextOptions) {
+		opts.quotaBytes = quotaBytes
+	}
+}
+
 // newIntegrationTestContext creates a complete integration test context with:
 // - Real MongoDB connection with test collections
 // - Temporary filesystem with USERS/, RESTORE/, BACKUP/ structure
@@ -146,7 +155,23 @@ func newIntegrationTestContext(t *testing.T, opts ...testContextOption) (context
 	_, err = towerCol.GetCollection().InsertOne(dbCtx, tower)
 	require.NoError(t, err)
 
-	// 4. Note: User directories will be created via file service after it's initialized
+	// 4. Create user document in database. User directories will be created via file service after it's initialized
+	userCol, err := db.GetCollection[any](dbCtx, user_model.UserCollectionKey)
+	require.NoError(t, err)
+	err = userCol.Drop(dbCtx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = userCol.Drop(dbCtx)
+	})
+
+	_, err = userCol.GetCollection().InsertOne(dbCtx, user_model.User{
+		ID:         primitive.NewObjectID(),
+		Username:   options.username,
+		UserPerms:  user_model.UserPermissionBasic,
+		Activated:  true,
+		QuotaBytes: options.quotaBytes,
+	})
+	require.NoError(t, err)
 
 	// 5. Create logger and basic context
 	logger := wlog.NewZeroLogger()
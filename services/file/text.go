@@ -0,0 +1,81 @@
+package file
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	file_model "github.com/ethanrous/weblens/models/file"
+	media_model "github.com/ethanrous/weblens/models/media"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+)
+
+// MaxTextSize is the largest text, in bytes, that can be written to a file as text. Larger files must be uploaded.
+const MaxTextSize = 16 << 20
+
+// ErrNotTextFile is returned when a file that is not plain text is read or written as text.
+var ErrNotTextFile = wlerrors.Statusf(http.StatusBadRequest, "file is not a text file")
+
+// ErrStaleContent is returned when new content is written to a file based on content the file no longer has.
+var ErrStaleContent = wlerrors.Statusf(http.StatusConflict, "file has changed since it was read")
+
+// IsTextFile returns true if file is plain text, judged by its extension.
+func IsTextFile(file *file_model.WeblensFileImpl) bool {
+	return !file.IsDir() && media_model.ParseExtension(file.GetPortablePath().Ext()).IsText()
+}
+
+// WriteFileText makes text the new content of file, if file still has the content with baseContentID. The content
+// being replaced is kept as a past revision of the file. ErrStaleContent is returned if the file has been changed since
+// baseContentID was read, so edits made in the meantime are not silently overwritten.
+func WriteFileText(ctx context.Context, file *file_model.WeblensFileImpl, baseContentID string, text []byte) error {
+	if !IsTextFile(file) {
+		return wlerrors.WithStack(ErrNotTextFile)
+	}
+
+	appCtx, ok := context_service.FromContext(ctx)
+	if !ok {
+		return wlerrors.WithStack(context_service.ErrNoContext)
+	}
+
+	// Written next to the file, so it can be moved into place with a single rename
+	tmp, err := os.CreateTemp(filepath.Dir(file.GetPortablePath().ToAbsolute()), ".weblens-revision-*")
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	_, err = tmp.Write(text)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+
+		return wlerrors.WithStack(err)
+	}
+
+	// The content ID is checked and replaced under the file lock, so two writes based on the same content can not both
+	// succeed
+	err = file.WithLock(func() error {
+		if file.Size() > 0 && file.GetContentID() == "" {
+			if _, err := file_model.GenerateContentID(ctx, file); err != nil {
+				return err
+			}
+		}
+
+		if file.GetContentID() != baseContentID {
+			return wlerrors.WithStack(ErrStaleContent)
+		}
+
+		return appCtx.FileService.ReplaceFileContent(ctx, file, tmp.Name())
+	})
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+
+		return err
+	}
+
+	return nil
+}
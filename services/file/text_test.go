@@ -0,0 +1,95 @@
+package file //nolint:testpackage
+
+import (
+	"os"
+	"testing"
+
+	file_model "github.com/ethanrous/weblens/models/file"
+	"github.com/ethanrous/weblens/models/history"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlfs"
+	ctxservice "github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileText_Integration(t *testing.T) {
+	t.Run("replaces text and keeps the old revision", func(t *testing.T) {
+		ctx, _ := newIntegrationTestContext(t)
+		appCtx, ok := ctxservice.FromContext(ctx)
+		require.True(t, ok)
+
+		fs := appCtx.GetFileService()
+
+		userHome, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true))
+		require.NoError(t, err)
+
+		file := createTestFile(t, ctx, fs, userHome, "README.md", []byte("old text"))
+
+		oldContentID, err := file_model.GenerateContentID(ctx, file)
+		require.NoError(t, err)
+
+		err = WriteFileText(ctx, file, oldContentID, []byte("new text"))
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(file.GetPortablePath().ToAbsolute())
+		require.NoError(t, err)
+		assert.Equal(t, "new text", string(content))
+
+		assert.NotEqual(t, oldContentID, file.GetContentID())
+		assert.Equal(t, int64(len("new text")), file.Size())
+
+		// The old text is kept in the restore tree, untouched by the write
+		restored, err := os.ReadFile(wlfs.BuildFilePath(file_model.RestoreTreeKey, oldContentID).ToAbsolute())
+		require.NoError(t, err)
+		assert.Equal(t, "old text", string(restored))
+
+		revisions, err := history.GetRevisionActions(ctx, file.ID())
+		require.NoError(t, err)
+		require.NotEmpty(t, revisions)
+		assert.Equal(t, history.FileSizeChange, revisions[0].ActionType)
+		assert.Equal(t, file.GetContentID(), revisions[0].ContentID)
+		assert.Equal(t, oldContentID, revisions[0].OldContentID)
+	})
+
+	t.Run("rejects stale writes", func(t *testing.T) {
+		ctx, _ := newIntegrationTestContext(t)
+		appCtx, ok := ctxservice.FromContext(ctx)
+		require.True(t, ok)
+
+		fs := appCtx.GetFileService()
+
+		userHome, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true))
+		require.NoError(t, err)
+
+		file := createTestFile(t, ctx, fs, userHome, "notes.txt", []byte("first"))
+
+		baseContentID, err := file_model.GenerateContentID(ctx, file)
+		require.NoError(t, err)
+
+		require.NoError(t, WriteFileText(ctx, file, baseContentID, []byte("second")))
+
+		err = WriteFileText(ctx, file, baseContentID, []byte("third"))
+		assert.True(t, wlerrors.Is(err, ErrStaleContent))
+
+		content, err := os.ReadFile(file.GetPortablePath().ToAbsolute())
+		require.NoError(t, err)
+		assert.Equal(t, "second", string(content))
+	})
+
+	t.Run("rejects files that are not text", func(t *testing.T) {
+		ctx, _ := newIntegrationTestContext(t)
+		appCtx, ok := ctxservice.FromContext(ctx)
+		require.True(t, ok)
+
+		fs := appCtx.GetFileService()
+
+		userHome, err := fs.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("testuser", true))
+		require.NoError(t, err)
+
+		file := createTestFile(t, ctx, fs, userHome, "photo.jpg", []byte("not really a photo"))
+
+		err = WriteFileText(ctx, file, file.GetContentID(), []byte("text"))
+		assert.True(t, wlerrors.Is(err, ErrNotTextFile))
+	})
+}
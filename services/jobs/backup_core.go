@@ -3,12 +3,11 @@ package jobs
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"slices"
 	"time"
 
-	token_model "github.com/ethanrous/weblens/models/auth"
+	backup_model "github.com/ethanrous/weblens/models/backup"
 	"github.com/ethanrous/weblens/models/db"
 	file_model "github.com/ethanrous/weblens/models/file"
 	"github.com/ethanrous/weblens/models/history"
@@ -23,6 +22,7 @@ import (
 	websocket_mod "github.com/ethanrous/weblens/modules/websocket"
 	context_mod "github.com/ethanrous/weblens/modules/wlcontext"
 	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlstructs"
 	context_service "github.com/ethanrous/weblens/services/ctxservice"
 	file_service "github.com/ethanrous/weblens/services/file"
 	"github.com/ethanrous/weblens/services/notify"
@@ -31,6 +31,9 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// backupPageSize is about how many actions of the file history of a core are backed up at a time.
+const backupPageSize = 1000
+
 func init() {
 	startup.RegisterHook(func(ctx context.Context, cp config.Provider) error {
 		if !cp.DoAutomaticBackup {
@@ -104,7 +107,9 @@ func BackupOne(ctx context.Context, core tower_model.Instance) (*task.Task, erro
 	return appCtx.DispatchJob(job.BackupTask, meta, nil)
 }
 
-// DoBackup executes the backup task for a core server, downloading all changed data and metadata.
+// DoBackup executes the backup task for a core server, downloading all changed data and metadata. The file history of
+// the core is read a page at a time, and the position reached is saved after each page, so a backup that is interrupted
+// resumes where it stopped rather than starting over.
 func DoBackup(tsk *task.Task) {
 	meta := tsk.GetMeta().(job.BackupMeta)
 
@@ -166,200 +171,341 @@ func DoBackup(tsk *task.Task) {
 		return
 	}
 
-	// Find most recent action timestamp
-	latestTime := time.UnixMilli(0)
+	// The local record of the core, which holds how far it has been backed up
+	remote, err := tower_model.GetTowerByID(ctx, meta.Core.TowerID)
+	if err != nil {
+		tsk.Fail(err)
+
+		return
+	}
 
-	latestAction, err := history_model.GetLatestActionByTowerID(ctx, meta.Core.TowerID)
-	if err != nil && !db.IsNotFound(err) {
+	cursor, err := getBackupCursor(ctx, remote)
+	if err != nil {
 		tsk.Fail(err)
-	} else if err == nil {
-		latestTime = latestAction.Timestamp
+
+		return
 	}
 
-	tsk.Log().Trace().Msgf("Backup latest action was at [%s]", latestTime)
+	tsk.Log().Trace().Msgf("Backing up history of [%s] from cursor [%s]", meta.Core.Name, cursor)
 
-	backupResponse, err := tower_service.GetBackup(ctx, meta.Core, latestTime)
+	metadata, err := tower_service.GetBackupMetadata(ctx, meta.Core, remote.BackupMetadataTime)
 	if err != nil {
 		tsk.Fail(err)
 
 		return
 	}
 
-	ctx.Log().Debug().Func(func(e *zerolog.Event) {
-		res, _ := json.Marshal(backupResponse)
-		e.Msgf("Received backup response from core [%s]: %s",
-			meta.Core.Name,
-			res,
-		)
-	})
-
 	tsk.SetTimeout(time.Now().Add(5 * time.Minute))
 
 	err = db.WithTransaction(ctx, func(ctx context.Context) error {
-		appCtx, ok := context_service.FromContext(ctx)
-		if !ok {
-			return wlerrors.New("Failed to cast context to AppContext")
-		}
+		return saveBackupMetadata(ctx, metadata, meta.Core)
+	})
+	if err != nil {
+		tsk.Fail(err)
 
-		appCtx.Log().Trace().Msgf("Got %d users from core", len(backupResponse.Users))
-		// Write new the users to db
-		for _, userInfo := range backupResponse.Users {
-			u := reshape.UserInfoArchiveToUser(userInfo)
+		return
+	}
 
-			_, err = user_model.GetUserByUsername(tsk.Ctx, u.Username)
-			if err == nil {
-				continue
-			}
+	remoteDataDir, err := ctx.FileService.InitBackupDirectory(tsk.Ctx, meta.Core)
+	if err != nil {
+		tsk.Fail(err)
 
-			u.CreatedBy = meta.Core.TowerID
+		return
+	}
 
-			err = user_model.SaveUser(tsk.Ctx, u)
-			if err != nil {
-				return err
-			}
-		}
+	// Move events are only applied once, by their first action. The rest of the files in the event move along with it.
+	moveEvents := set.New[string]()
 
-		appCtx.Log().Trace().Msgf("Got %d tokens from core", len(backupResponse.Tokens))
-		// Write new keys to db
-		for _, key := range backupResponse.Tokens {
-			token, err := reshape.TokenInfoToToken(tsk.Ctx, key)
-			if err != nil {
-				return err
-			}
+	for {
+		page, err := tower_service.GetBackupActions(ctx, meta.Core, cursor, backupPageSize)
+		if err != nil {
+			tsk.Fail(err)
 
-			// Check if token already exists
-			_, err = token_model.GetTokenByID(tsk.Ctx, token.ID)
-			if err != nil {
-				if wlerrors.Is(err, token_model.ErrTokenNotFound) {
-					continue
-				}
+			return
+		}
 
-				return err
+		ctx.Log().Trace().Msgf("Got %d actions from core after cursor [%s]", len(page.Actions), cursor)
+
+		// The new position is saved along with the actions, so they are either both kept or both rolled back
+		err = db.WithTransaction(ctx, func(ctx context.Context) error {
+			appCtx, ok := context_service.FromContext(ctx)
+			if !ok {
+				return wlerrors.New("Failed to cast context to AppContext")
 			}
 
-			err = token_model.SaveToken(tsk.Ctx, token)
+			err := backupActions(appCtx, page.Actions, meta.Core, moveEvents, tsk)
 			if err != nil {
 				return err
 			}
+
+			return tower_model.SetBackupCursor(ctx, meta.Core.TowerID, page.NextCursor)
+		})
+		if err != nil {
+			tsk.Fail(err)
+
+			return
 		}
 
-		appCtx.Log().Trace().Msgf("Got %d towers from core", len(backupResponse.Instances))
-		// Write new towers to db
-		for _, serverInfo := range backupResponse.Instances {
-			// Check if we already have this tower
-			_, err := tower_model.GetBackupTowerByID(tsk.Ctx, serverInfo.ID, meta.Core.TowerID)
-			if err == nil {
-				continue
-			} else if !db.IsNotFound(err) {
-				return err
-			}
+		cursor = page.NextCursor
 
-			instance := reshape.TowerInfoToTower(serverInfo)
-			instance.CreatedBy = meta.Core.TowerID
+		tsk.AtomicSetResult(func(currentResult task.Result) task.Result {
+			actionsBackedUp, _ := currentResult["actionsBackedUp"].(int)
+			currentResult["actionsBackedUp"] = actionsBackedUp + len(page.Actions)
 
-			err = tower_model.SaveTower(tsk.Ctx, instance)
-			if err != nil {
-				return err
-			}
+			return currentResult
+		})
+
+		// Each page is given its own time to finish
+		tsk.SetTimeout(time.Now().Add(5 * time.Minute))
+
+		if !page.HasMore {
+			break
 		}
+	}
+
+	_, err = remoteDataDir.LoadStat()
+	if err != nil {
+		tsk.Fail(err)
+
+		return
+	}
+
+	err = tower_model.SetLastBackup(tsk.Ctx, meta.Core.TowerID, time.Now(), remoteDataDir.Size())
+	if err != nil {
+		tsk.Fail(err)
+
+		return
+	}
+
+	r := tsk.GetResult()
+	r["backupSize"] = remoteDataDir.Size()
+	r["totalTime"] = tsk.ExeTime()
+	r["complete"] = true
+
+	notif = notify.NewTaskNotification(
+		tsk,
+		websocket_mod.BackupCompleteEvent,
+		r,
+	)
+	ctx.Notify(ctx, notif)
+
+	tsk.Success()
+}
+
+// getBackupCursor returns the position in the file history of the core remote to continue backing up from. Towers that
+// were backed up before the position was saved continue after the latest action they have from the core.
+func getBackupCursor(ctx context_service.AppContext, remote tower_model.Instance) (string, error) {
+	if remote.BackupCursor != "" {
+		return remote.BackupCursor, nil
+	}
+
+	latestAction, err := history_model.GetLatestActionByTowerID(ctx, remote.TowerID)
+	if db.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	return history_model.ActionCursor{Timestamp: latestAction.Timestamp}.String(), nil
+}
 
-		appCtx.Log().Trace().Msgf("Got %d actions from core", len(backupResponse.FileHistory))
+// saveBackupMetadata saves the users and towers of core that the local tower does not have yet, and brings the backed up
+// tokens, shares, tags, covers and media of core up to date.
+func saveBackupMetadata(ctx context.Context, metadata *wlstructs.BackupInfo, core tower_model.Instance) error {
+	log := context_mod.ToZ(ctx).Log()
 
-		actions := make([]history_model.FileAction, 0, len(backupResponse.FileHistory))
+	log.Trace().Msgf("Got %d users from core", len(metadata.Users))
+	// Write new the users to db
+	for _, userInfo := range metadata.Users {
+		u := reshape.UserInfoArchiveToUser(userInfo)
 
-		for _, action := range backupResponse.FileHistory {
-			newAction := reshape.FileActionInfoToFileAction(action)
-			newAction.TowerID = meta.Core.TowerID
-			actions = append(actions, newAction)
+		_, err := user_model.GetUserByUsername(ctx, u.Username)
+		if err == nil {
+			continue
 		}
 
-		err = history.SaveActions(tsk.Ctx, actions)
+		u.CreatedBy = core.TowerID
+
+		// The archived password is already hashed, and must be kept as-is to be restored later
+		err = user_model.RestoreUser(ctx, u)
 		if err != nil {
 			return err
 		}
+	}
 
-		// Sort lifetimes so that files created or moved most recently are updated last.
-		// This is to make sure parent directories are created before their children
-		slices.SortFunc(actions, history_model.ActionSorter)
+	log.Trace().Msgf("Got %d tokens from core", len(metadata.Tokens))
+	// Tokens are kept apart from the local tokens, so they can not be used to access the backup tower itself
+	tokenIDs := make([]string, 0, len(metadata.Tokens))
 
-		filteredActions := make([]history_model.FileAction, 0, len(actions))
-		events := set.New[string]()
+	for _, token := range metadata.Tokens {
+		err := backup_model.SaveRecord(ctx, core.TowerID, backup_model.KindToken, token.ID, token)
+		if err != nil {
+			return err
+		}
 
-		for _, a := range actions {
-			if a.ActionType == history_model.FileMove {
-				if events.Has(a.EventID) {
-					continue
-				}
+		tokenIDs = append(tokenIDs, token.ID)
+	}
 
-				events.Add(a.EventID)
-			}
+	// Every token is sent each time, so those not sent have been deleted
+	err := backup_model.PruneRecords(ctx, core.TowerID, backup_model.KindToken, tokenIDs)
+	if err != nil {
+		return err
+	}
 
-			filteredActions = append(filteredActions, a)
+	log.Trace().Msgf("Got %d towers from core", len(metadata.Instances))
+	// Write new towers to db
+	for _, serverInfo := range metadata.Instances {
+		// Check if we already have this tower
+		_, err := tower_model.GetBackupTowerByID(ctx, serverInfo.ID, core.TowerID)
+		if err == nil {
+			continue
+		} else if !db.IsNotFound(err) {
+			return err
 		}
 
-		remoteDataDir, err := appCtx.FileService.InitBackupDirectory(tsk.Ctx, meta.Core)
+		instance := reshape.TowerInfoToTower(serverInfo)
+		instance.CreatedBy = core.TowerID
+
+		err = tower_model.SaveTower(ctx, instance)
 		if err != nil {
 			return err
 		}
+	}
+
+	err = saveBackupOrganization(ctx, metadata, core)
+	if err != nil {
+		return err
+	}
+
+	// Cores that do not send when they read the metadata are asked for all of it again next time
+	if metadata.Timestamp != 0 {
+		return tower_model.SetBackupMetadataTime(ctx, core.TowerID, metadata.Timestamp)
+	}
+
+	return nil
+}
+
+// saveBackupOrganization saves the shares, tags, covers and media of core that have changed since they were last backed
+// up, and deletes those that no longer exist on core.
+func saveBackupOrganization(ctx context.Context, metadata *wlstructs.BackupInfo, core tower_model.Instance) error {
+	log := context_mod.ToZ(ctx).Log()
 
-		// Create a task pool to keep track of copy file tasks,
-		// and set it as a child of the main backup task (this task)
-		pool, err := appCtx.TaskService.NewTaskPool(true, tsk)
+	log.Trace().Msgf("Got %d shares, %d tags, %d covers and %d media from core", len(metadata.Shares), len(metadata.Tags), len(metadata.Covers), len(metadata.Media))
+
+	for _, share := range metadata.Shares {
+		err := backup_model.SaveRecord(ctx, core.TowerID, backup_model.KindShare, share.ShareID, share)
 		if err != nil {
 			return err
 		}
+	}
 
-		tsk.SetChildTaskPool(pool)
-
-		for _, a := range filteredActions {
-			// Check if the file already exists on the server and copy/move/delete it if it is in the wrong place
-			err = handleFileAction(appCtx, a, meta.Core, tsk, pool)
-			if err != nil {
-				return err
-			}
+	for _, tag := range metadata.Tags {
+		err := backup_model.SaveRecord(ctx, core.TowerID, backup_model.KindTag, tag.ID, tag)
+		if err != nil {
+			return err
 		}
+	}
 
-		tsk.Log().Debug().Func(func(e *zerolog.Event) { e.Msgf("Waiting for %d copy file tasks", pool.Status().Total) })
-
-		// Wait for all copy file tasks to finish
-		pool.SignalAllQueued()
-		pool.Wait(true, tsk)
-
-		if len(pool.Errors()) != 0 {
-			return wlerrors.Errorf("%d of %d backup file copies have failed", len(pool.Errors()), pool.Status().Total)
+	for _, cover := range metadata.Covers {
+		err := backup_model.SaveRecord(ctx, core.TowerID, backup_model.KindCover, cover.FolderID, cover)
+		if err != nil {
+			return err
 		}
+	}
 
-		_, err = remoteDataDir.LoadStat()
+	for _, media := range metadata.Media {
+		err := backup_model.SaveRecord(ctx, core.TowerID, backup_model.KindMedia, media.ContentID, media)
 		if err != nil {
 			return err
 		}
+	}
 
-		err = tower_model.SetLastBackup(tsk.Ctx, meta.Core.TowerID, time.Now(), remoteDataDir.Size())
+	if metadata.Keys == nil {
+		return nil
+	}
+
+	prune := []struct {
+		kind backup_model.Kind
+		keep []string
+	}{
+		{backup_model.KindShare, metadata.Keys.ShareIDs},
+		{backup_model.KindTag, metadata.Keys.TagIDs},
+		{backup_model.KindCover, metadata.Keys.CoverFolderIDs},
+		{backup_model.KindMedia, metadata.Keys.MediaContentIDs},
+	}
+
+	for _, p := range prune {
+		err := backup_model.PruneRecords(ctx, core.TowerID, p.kind, p.keep)
 		if err != nil {
 			return err
 		}
+	}
+
+	return nil
+}
 
-		r := tsk.GetResult()
-		r["backupSize"] = remoteDataDir.Size()
-		r["totalTime"] = tsk.ExeTime()
-		r["complete"] = true
+// backupActions saves a page of actions from the history of core to the local journal, and brings the files in the
+// backup directory of core up to date with them, waiting for any file contents they need to be copied from core.
+func backupActions(ctx context_service.AppContext, actionInfos []wlstructs.FileActionInfo, core tower_model.Instance, moveEvents set.Set[string], tsk *task.Task) error {
+	actions := make([]history_model.FileAction, 0, len(actionInfos))
 
-		notif := notify.NewTaskNotification(
-			tsk,
-			websocket_mod.BackupCompleteEvent,
-			r,
-		)
-		appCtx.Notify(ctx, notif)
+	for _, action := range actionInfos {
+		newAction := reshape.FileActionInfoToFileAction(action)
+		newAction.TowerID = core.TowerID
+		actions = append(actions, newAction)
+	}
 
-		return nil
-	})
+	err := history.SaveActions(ctx, actions)
 	if err != nil {
-		tsk.Fail(err)
+		return err
+	}
 
-		return
+	// Sort lifetimes so that files created or moved most recently are updated last.
+	// This is to make sure parent directories are created before their children
+	slices.SortFunc(actions, history_model.ActionSorter)
+
+	filteredActions := make([]history_model.FileAction, 0, len(actions))
+
+	for _, a := range actions {
+		if a.ActionType == history_model.FileMove {
+			if moveEvents.Has(a.EventID) {
+				continue
+			}
+
+			moveEvents.Add(a.EventID)
+		}
+
+		filteredActions = append(filteredActions, a)
 	}
 
-	tsk.Success()
+	// Create a task pool to keep track of copy file tasks,
+	// and set it as a child of the main backup task (this task)
+	pool, err := ctx.TaskService.NewTaskPool(true, tsk)
+	if err != nil {
+		return err
+	}
+
+	tsk.SetChildTaskPool(pool)
+
+	for _, a := range filteredActions {
+		// Check if the file already exists on the server and copy/move/delete it if it is in the wrong place
+		err = handleFileAction(ctx, a, core, tsk, pool)
+		if err != nil {
+			return err
+		}
+	}
+
+	tsk.Log().Debug().Func(func(e *zerolog.Event) { e.Msgf("Waiting for %d copy file tasks", pool.Status().Total) })
+
+	// Wait for all copy file tasks to finish
+	pool.SignalAllQueued()
+	pool.Wait(true, tsk)
+
+	if len(pool.Errors()) != 0 {
+		return wlerrors.Errorf("%d of %d backup file copies have failed", len(pool.Errors()), pool.Status().Total)
+	}
+
+	return nil
 }
 
 func getExistingFile(ctx context_service.AppContext, a history_model.FileAction, core tower_model.Instance) (*file_model.WeblensFileImpl, error) {
@@ -375,8 +521,9 @@ func getExistingFile(ctx context_service.AppContext, a history_model.FileAction,
 
 	ctx.Log().Trace().Func(func(e *zerolog.Event) { e.Msgf("File %s exists: %v", path, existingFile != nil) })
 
-	// If the file already exists, but is the wrong size, an earlier copy most likely failed. Delete it and copy it again.
-	if existingFile != nil && !existingFile.IsDir() && existingFile.Size() != a.Size {
+	// If the file already exists, but is the wrong size, an earlier copy most likely failed, or the file has since been
+	// given new content. Delete it and copy it again.
+	if existingFile != nil && !existingFile.IsDir() && (existingFile.Size() != a.Size || isStaleRevision(existingFile, a)) {
 		err = ctx.FileService.DeleteFiles(ctx, existingFile)
 		if err != nil {
 			return nil, err
@@ -388,6 +535,11 @@ func getExistingFile(ctx context_service.AppContext, a history_model.FileAction,
 	return existingFile, nil
 }
 
+// isStaleRevision returns true if a gave existingFile new content, which the backup does not have yet.
+func isStaleRevision(existingFile *file_model.WeblensFileImpl, a history_model.FileAction) bool {
+	return a.ActionType == history_model.FileSizeChange && a.ContentID != "" && existingFile.GetContentID() != a.ContentID
+}
+
 func handleFileAction(ctx context_service.AppContext, a history_model.FileAction, core tower_model.Instance, callingTask *task.Task, pool *task.Pool) error {
 	existingFile, err := getExistingFile(ctx, a, core)
 	if err != nil {
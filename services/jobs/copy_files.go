@@ -0,0 +1,118 @@
+package jobs
+
+import (
+	"time"
+
+	file_model "github.com/ethanrous/weblens/models/file"
+	"github.com/ethanrous/weblens/models/job"
+	"github.com/ethanrous/weblens/models/task"
+	"github.com/ethanrous/weblens/modules/websocket"
+	context_mod "github.com/ethanrous/weblens/modules/wlcontext"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/ethanrous/weblens/services/notify"
+)
+
+// copyProgressInterval is the minimum time between progress updates sent while copying files.
+const copyProgressInterval = 500 * time.Millisecond
+
+// CopyFiles is a task that copies the files in the task metadata, and everything inside of any folders among them,
+// into the destination folder. Progress is reported to subscribers of the task as files are copied.
+func CopyFiles(tsk *task.Task) {
+	ctx, ok := ctxservice.FromContext(tsk.Ctx)
+	if !ok {
+		tsk.Fail(wlerrors.WithStack(ctxservice.ErrNoContext))
+
+		return
+	}
+
+	meta := tsk.GetMeta().(job.CopyFilesMeta)
+
+	ctx = ctx.WithValue(context_mod.RequestDoerKey, meta.Requester.GetUsername())
+
+	var (
+		totalFiles int
+		bytesTotal int64
+	)
+
+	for _, f := range meta.Files {
+		err := f.RecursiveMap(func(child *file_model.WeblensFileImpl) error {
+			totalFiles++
+
+			if !child.IsDir() {
+				bytesTotal += child.Size()
+			}
+
+			return nil
+		})
+		if err != nil {
+			tsk.Fail(err)
+
+			return
+		}
+	}
+
+	notif := notify.NewTaskNotification(tsk, websocket.TaskCreatedEvent, task.Result{"totalFiles": totalFiles, "bytesTotal": bytesTotal})
+	ctx.Notify(ctx, notif)
+
+	tsk.OnResult(func(result task.Result) {
+		notif := notify.NewTaskNotification(tsk, websocket.CopyFilesProgressEvent, result)
+		ctx.Notify(ctx, notif)
+	})
+
+	var (
+		completedFiles int
+		bytesSoFar     int64
+		lastUpdate     = time.Now()
+	)
+
+	// Called from the copy itself, one file at a time
+	onCopied := func(f *file_model.WeblensFileImpl) {
+		completedFiles++
+
+		if !f.IsDir() {
+			bytesSoFar += f.Size()
+		}
+
+		if time.Since(lastUpdate) < copyProgressInterval {
+			return
+		}
+
+		lastUpdate = time.Now()
+
+		tsk.SetResult(task.Result{
+			"completedFiles": completedFiles,
+			"totalFiles":     totalFiles,
+			"bytesSoFar":     bytesSoFar,
+			"bytesTotal":     bytesTotal,
+		})
+	}
+
+	copies, err := ctx.FileService.CopyFiles(ctx, meta.Files, meta.Destination, onCopied)
+	if err != nil {
+		tsk.Fail(err)
+
+		return
+	}
+
+	tsk.ClearOnResult()
+
+	newFileIDs := make([]string, 0, len(copies))
+	for _, f := range copies {
+		newFileIDs = append(newFileIDs, f.ID())
+	}
+
+	tsk.SetResult(task.Result{
+		"fileIDs":        newFileIDs,
+		"destinationID":  meta.Destination.ID(),
+		"completedFiles": totalFiles,
+		"totalFiles":     totalFiles,
+		"bytesSoFar":     bytesTotal,
+		"bytesTotal":     bytesTotal,
+	})
+
+	notif = notify.NewTaskNotification(tsk, websocket.CopyFilesCompleteEvent, tsk.GetResults())
+	ctx.Notify(ctx, notif)
+
+	tsk.Success()
+}
@@ -89,11 +89,18 @@ func ExtractAndEmbedFile(tsk *task.Task) {
 
 	switch {
 	case err == nil:
+		// A task resumed after a restart may have already re-encoded the image, which is slow enough not to repeat
+		if tsk.GetCheckpoint()["imageEmbedded"] == true {
+			break
+		}
+
 		if err := writeImageEmbedding(ctx, m, meta.ForceReIndex); err != nil {
 			tsk.Fail(err)
 
 			return
 		}
+
+		tsk.Checkpoint(task.Result{"imageEmbedded": true})
 	case !db.IsNotFound(err):
 		tsk.Fail(err)
 
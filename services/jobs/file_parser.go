@@ -137,13 +137,19 @@ func IndexDirectory(t *task.Task) {
 		mediaMap[m.ContentID] = m
 	}
 
-	if meta.ForceReIndex {
+	// A forced scan that is resumed after a restart has already cleared the index, and anything indexed since then
+	// is current, so it carries on as a regular scan
+	forceReIndex := meta.ForceReIndex && t.GetCheckpoint()["indexCleared"] != true
+
+	if forceReIndex {
 		err = clearExistingIndex(ctx, medias, discoveredFileIDs.ToSlice())
 		if err != nil {
 			t.Fail(err)
 
 			return
 		}
+
+		t.Checkpoint(task.Result{"indexCleared": true})
 	}
 
 	doEmbed := flags.EnableEmbed && !embed.Default().ServiceUnavailable()
@@ -151,7 +157,7 @@ func IndexDirectory(t *task.Task) {
 	for _, discoFile := range discoveredFiles {
 		media := mediaMap[discoFile.GetContentID()]
 
-		err = queueFileIndexIfNeeded(ctx, t, discoFile, media, doEmbed, meta.ForceReIndex, &alreadyFiles, &alreadyMedia, pool)
+		err = queueFileIndexIfNeeded(ctx, t, discoFile, media, doEmbed, forceReIndex, &alreadyFiles, &alreadyMedia, pool)
 		if err != nil {
 			t.Fail(wlerrors.WithStack(err))
 
@@ -0,0 +1,81 @@
+package jobs
+
+import (
+	"github.com/ethanrous/weblens/models/job"
+	"github.com/ethanrous/weblens/models/task"
+	"github.com/ethanrous/weblens/modules/websocket"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlstructs"
+	"github.com/ethanrous/weblens/services/ctxservice"
+	file_service "github.com/ethanrous/weblens/services/file"
+	"github.com/ethanrous/weblens/services/notify"
+	"github.com/ethanrous/weblens/services/reshape"
+)
+
+// FindDuplicates is a task that groups the files inside of the roots in the task metadata by their content, or, when
+// looking for near-duplicates, groups photos by how alike their image embeddings are. The groups, and the bytes that
+// could be reclaimed by keeping only one file of each, are sent to subscribers of the task once done.
+func FindDuplicates(tsk *task.Task) {
+	ctx, ok := ctxservice.FromContext(tsk.Ctx)
+	if !ok {
+		tsk.Fail(wlerrors.WithStack(ctxservice.ErrNoContext))
+
+		return
+	}
+
+	meta := tsk.GetMeta().(job.FindDuplicatesMeta)
+
+	var (
+		groups []file_service.DuplicateGroup
+		err    error
+	)
+
+	if meta.Similar {
+		groups, err = file_service.FindSimilarPhotos(ctx, meta.Threshold, meta.Roots...)
+	} else {
+		groups, err = file_service.FindDuplicates(meta.Roots...)
+	}
+
+	if err != nil {
+		tsk.Fail(err)
+
+		return
+	}
+
+	groupInfos := make([]wlstructs.DuplicateGroupInfo, 0, len(groups))
+
+	var reclaimableBytes int64
+
+	for _, group := range groups {
+		groupInfo := wlstructs.DuplicateGroupInfo{
+			ContentID:        group.ContentID,
+			Files:            make([]wlstructs.FileInfo, 0, len(group.Files)),
+			ReclaimableBytes: group.ReclaimableBytes,
+		}
+
+		for _, f := range group.Files {
+			fInfo, err := reshape.WeblensFileToFileInfo(ctx, f)
+			if err != nil {
+				tsk.Fail(err)
+
+				return
+			}
+
+			groupInfo.Files = append(groupInfo.Files, fInfo)
+		}
+
+		reclaimableBytes += group.ReclaimableBytes
+		groupInfos = append(groupInfos, groupInfo)
+	}
+
+	tsk.SetResult(task.Result{
+		"similar":          meta.Similar,
+		"groups":           groupInfos,
+		"reclaimableBytes": reclaimableBytes,
+	})
+
+	notif := notify.NewTaskNotification(tsk, websocket.DuplicatesFoundEvent, tsk.GetResults())
+	ctx.Notify(ctx, notif)
+
+	tsk.Success()
+}
@@ -0,0 +1,227 @@
+package jobs
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ethanrous/weblens/models/featureflags"
+	file_model "github.com/ethanrous/weblens/models/file"
+	"github.com/ethanrous/weblens/models/history"
+	job_model "github.com/ethanrous/weblens/models/job"
+	schedule_model "github.com/ethanrous/weblens/models/schedule"
+	"github.com/ethanrous/weblens/models/task"
+	tower_model "github.com/ethanrous/weblens/models/tower"
+	"github.com/ethanrous/weblens/modules/config"
+	"github.com/ethanrous/weblens/modules/startup"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+	file_service "github.com/ethanrous/weblens/services/file"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// defaultHistoryGCCron is when the history gc schedule created for new core towers runs, weekly at 5am on Sunday.
+const defaultHistoryGCCron = "0 5 * * 0"
+
+// historyGCBatchSize is how many expired actions are removed from the journal at once.
+const historyGCBatchSize = 1000
+
+// HistoryGCReport describes what a history garbage collection removed, or would remove on a dry run.
+type HistoryGCReport struct {
+	DryRun         bool  `json:"dryRun"`
+	ActionsRemoved int   `json:"actionsRemoved"`
+	BlobsRemoved   int   `json:"blobsRemoved"`
+	BytesReclaimed int64 `json:"bytesReclaimed"`
+}
+
+func init() {
+	startup.RegisterHook(ensureHistoryGCSchedule)
+}
+
+// ensureHistoryGCSchedule creates the weekly history gc schedule on core towers that do not have one yet. It can be
+// disabled or moved to another time like any other schedule, but is created again on the next start if deleted.
+func ensureHistoryGCSchedule(ctx context.Context, _ config.Provider) error {
+	local, err := tower_model.GetLocal(ctx)
+	if wlerrors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// Backup towers keep the history of their cores as it was sent to them
+	if local.Role != tower_model.RoleCore {
+		return nil
+	}
+
+	existing, err := schedule_model.GetSchedulesByJobName(ctx, job_model.GCHistoryTask)
+	if err != nil || len(existing) != 0 {
+		return err
+	}
+
+	s, err := schedule_model.NewSchedule("Compact file history", job_model.GCHistoryTask, defaultHistoryGCCron, nil, local.TowerID)
+	if err != nil {
+		return err
+	}
+
+	return schedule_model.SaveSchedule(ctx, s)
+}
+
+// GCHistory is a task that compacts the file history down to what the server's retention keeps, and removes the
+// content in the RESTORE tree that is no longer referenced by the remaining history or by any live file.
+func GCHistory(tsk *task.Task) {
+	meta := tsk.GetMeta().(job_model.GCHistoryMeta)
+
+	ctx, ok := context_service.FromContext(tsk.Ctx)
+	if !ok {
+		tsk.Fail(wlerrors.WithStack(context_service.ErrNoContext))
+
+		return
+	}
+
+	flags, err := featureflags.GetFlags(ctx)
+	if err != nil {
+		tsk.Fail(err)
+
+		return
+	}
+
+	report, err := CollectHistoryGarbage(ctx, HistoryRetention(flags), time.Now(), meta.DryRun)
+	if err != nil {
+		tsk.Fail(err)
+
+		return
+	}
+
+	verb := "Removed"
+	if meta.DryRun {
+		verb = "Would remove"
+	}
+
+	tsk.Log().Info().Msgf(
+		"%s %d history actions and %d restore files (%d bytes)", verb, report.ActionsRemoved, report.BlobsRemoved, report.BytesReclaimed,
+	)
+
+	tsk.SetResult(task.Result{"report": report})
+	tsk.Success()
+}
+
+// HistoryRetention returns the file history retention policy set by flags.
+func HistoryRetention(flags featureflags.Bundle) history.RetentionPolicy {
+	day := 24 * time.Hour
+
+	return history.RetentionPolicy{
+		FullHistory:    time.Duration(max(flags.HistoryFullRetentionDays, 0)) * day,
+		VersionHistory: time.Duration(max(flags.HistoryVersionRetentionDays, 0)) * day,
+	}
+}
+
+// CollectHistoryGarbage removes the file actions that have outlived policy, then the content in the RESTORE tree that
+// neither the remaining actions nor any live file reference. If dryRun is true, nothing is removed, and the report
+// describes what would have been.
+func CollectHistoryGarbage(ctx context_service.AppContext, policy history.RetentionPolicy, now time.Time, dryRun bool) (HistoryGCReport, error) {
+	report := HistoryGCReport{DryRun: dryRun}
+
+	// References held by the actions that would have been removed, only counted on a dry run
+	expiredRefs := map[string]int{}
+
+	err := history.WalkExpiredActions(ctx, policy, now, historyGCBatchSize, func(batch []history.FileAction) error {
+		if dryRun {
+			report.ActionsRemoved += len(batch)
+
+			for _, a := range batch {
+				if a.ContentID != "" {
+					expiredRefs[a.ContentID]++
+				}
+			}
+
+			return nil
+		}
+
+		removed, err := history.DeleteActions(ctx, batch)
+		if err != nil {
+			return err
+		}
+
+		report.ActionsRemoved += int(removed)
+
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	// Counted after the expired actions are removed, so content deleted while the gc runs is still referenced
+	refs, err := history.CountContentIDReferences(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	for contentID, count := range expiredRefs {
+		refs[contentID] -= count
+	}
+
+	live, err := getLiveContentIDs(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	isReferenced := func(contentID string) bool {
+		_, isLive := live[contentID]
+
+		return isLive || refs[contentID] > 0
+	}
+
+	report.BlobsRemoved, report.BytesReclaimed, err = file_service.RemoveUnreferencedRestoreContent(isReferenced, dryRun)
+	if err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// getLiveContentIDs returns the content IDs of every file in the users tree, including those in the trash.
+func getLiveContentIDs(ctx context_service.AppContext) (map[string]struct{}, error) {
+	root, err := ctx.FileService.GetFileByID(ctx, file_model.UsersTreeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	live := map[string]struct{}{}
+
+	err = root.RecursiveMap(func(f *file_model.WeblensFileImpl) error {
+		if !f.IsDir() && f.GetContentID() != "" {
+			live[f.GetContentID()] = struct{}{}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return live, nil
+}
+
+// buildHistoryGCSchedule compacts the file history, only reporting what would be removed if the "dryRun" param is true.
+func buildHistoryGCSchedule(ctx context_service.AppContext, params map[string]string) (task.Metadata, error) {
+	local, err := tower_model.GetLocal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if local.Role != tower_model.RoleCore {
+		return nil, wlerrors.Statusf(http.StatusBadRequest, "file history can only be compacted on core towers")
+	}
+
+	dryRun := false
+
+	if params["dryRun"] != "" {
+		dryRun, err = strconv.ParseBool(params["dryRun"])
+		if err != nil {
+			return nil, wlerrors.Statusf(http.StatusBadRequest, "dryRun param must be true or false")
+		}
+	}
+
+	return job_model.GCHistoryMeta{DryRun: dryRun}, nil
+}
@@ -90,14 +90,18 @@ func GatherFilesystemStats(tsk *task.Task) {
 
 // RegisterJobs registers all available job handlers with the worker pool.
 func RegisterJobs(workerPool *task.WorkerPool) {
-	workerPool.RegisterJob(job_model.ScanDirectoryTask, IndexDirectory, task.Options{Priority: task.PriorityMedium})
+	workerPool.RegisterJob(job_model.ScanDirectoryTask, IndexDirectory, task.Options{Priority: task.PriorityMedium, LoadMeta: loadIndexMeta})
 	workerPool.RegisterJob(job_model.IndexFileTask, IndexFile, task.Options{Priority: task.PriorityHigh})
 	workerPool.RegisterJob(job_model.UploadFilesTask, HandleFileUploads, task.Options{Persistent: true, Unique: true, Priority: task.PriorityXHigh})
 	workerPool.RegisterJob(job_model.CreateZipTask, CreateZip, task.Options{Persistent: true, Unique: false, Priority: task.PriorityXHigh})
 	workerPool.RegisterJob(job_model.GatherFsStatsTask, GatherFilesystemStats)
-	workerPool.RegisterJob(job_model.BackupTask, DoBackup)
+	workerPool.RegisterJob(job_model.BackupTask, DoBackup, task.Options{LoadMeta: loadBackupMeta})
 	workerPool.RegisterJob(job_model.CopyFileFromCoreTask, CopyFileFromCore)
 	workerPool.RegisterJob(job_model.RestoreCoreTask, RestoreCore)
 	workerPool.RegisterJob(job_model.LoadFilesystemTask, LoadAtPath, task.Options{Priority: task.PriorityXHigh})
-	workerPool.RegisterJob(job_model.ExtractAndEmbedTask, ExtractAndEmbedFile, task.Options{Priority: task.PriorityBackground})
+	workerPool.RegisterJob(job_model.ExtractAndEmbedTask, ExtractAndEmbedFile, task.Options{Priority: task.PriorityBackground, LoadMeta: loadExtractAndEmbedMeta})
+	workerPool.RegisterJob(job_model.CopyFilesTask, CopyFiles, task.Options{Priority: task.PriorityHigh})
+	workerPool.RegisterJob(job_model.FindDuplicatesTask, FindDuplicates, task.Options{Unique: true, Priority: task.PriorityBackground})
+	workerPool.RegisterJob(job_model.PurgeTrashTask, PurgeTrash, task.Options{Unique: true, Priority: task.PriorityBackground})
+	workerPool.RegisterJob(job_model.GCHistoryTask, GCHistory, task.Options{Unique: true, Priority: task.PriorityBackground})
 }
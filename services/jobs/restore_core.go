@@ -1,203 +1,248 @@
 package jobs
 
-import "github.com/ethanrous/weblens/models/task"
-
-// "time"
-//
-// openapi "github.com/ethanrous/weblens/api"
-// "github.com/ethanrous/weblens/models/auth"
-// "github.com/ethanrous/weblens/models/job"
-// "github.com/ethanrous/weblens/models/task"
-// tower_model "github.com/ethanrous/weblens/models/tower"
-// user_model "github.com/ethanrous/weblens/models/user"
-// "github.com/ethanrous/weblens/modules/structs"
-// websocket_mod "github.com/ethanrous/weblens/modules/websocket"
-// "github.com/ethanrous/weblens/modules/wlerrors"
-// "github.com/ethanrous/weblens/services/ctxservice"
-// "github.com/ethanrous/weblens/services/journal"
-// "github.com/ethanrous/weblens/services/notify"
-// "github.com/ethanrous/weblens/services/proxy"
-// "github.com/ethanrous/weblens/services/reshape"
-// "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	backup_model "github.com/ethanrous/weblens/models/backup"
+	file_model "github.com/ethanrous/weblens/models/file"
+	history_model "github.com/ethanrous/weblens/models/history"
+	"github.com/ethanrous/weblens/models/job"
+	"github.com/ethanrous/weblens/models/task"
+	tower_model "github.com/ethanrous/weblens/models/tower"
+	user_model "github.com/ethanrous/weblens/models/usermodel"
+	websocket_mod "github.com/ethanrous/weblens/modules/websocket"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlstructs"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/ethanrous/weblens/services/notify"
+	"github.com/ethanrous/weblens/services/reshape"
+	tower_service "github.com/ethanrous/weblens/services/tower"
+)
+
+// RestoreOne initiates a task restoring core, from its backup on the local tower, onto the tower at core's address.
+func RestoreOne(ctx context.Context, core, local tower_model.Instance) (*task.Task, error) {
+	meta := job.RestoreCoreMeta{
+		Core:  &core,
+		Local: &local,
+	}
+
+	appCtx, ok := context_service.FromContext(ctx)
+	if !ok {
+		return nil, wlerrors.New("Failed to cast context to AppContext")
+	}
+
+	return appCtx.DispatchJob(job.RestoreCoreTask, meta, nil)
+}
 
 // RestoreCore restores a core server from backup data.
-func RestoreCore(_ *task.Task) {
-	// meta := tsk.GetMeta().(job.RestoreCoreMeta)
-	//
-	// ctx, ok := tsk.Ctx.(ctxservice.AppContext)
-	// if !ok {
-	// 	tsk.Fail(wlerrors.New("Failed to cast context to FilerContext"))
-	//
-	// 	return
-	// }
-	//
-	// type restoreInitParams struct {
-	// 	Name     string           `json:"name"`
-	// 	Role     tower_model.Role `json:"role"`
-	// 	RemoteID string           `json:"remoteID"`
-	// 	LocalID  string           `json:"localID"`
-	// 	Key      *auth.Token      `json:"usingKeyInfo"`
-	// }
-	//
-	// // Notify client of restore failure, if any
-	// tsk.SetErrorCleanup(
-	// 	func(errTsk *task.Task) {
-	// 		ctx.Notify(ctx, notify.NewTaskNotification(errTsk, websocket_mod.RestoreFailedEvent, task.Result{"error": errTsk.ReadError().Error()}))
-	// 	},
-	// )
-	//
-	// // Prime server to be restored. This will fail if the server is already initialized
-	//
-	// ctx.Notify(ctx, notify.NewTaskNotification(
-	// 	tsk, websocket_mod.RestoreProgressEvent, task.Result{"stage": "Connecting to remote", "timestamp": time.Now().UnixMilli()},
-	// ))
-	//
-	// tokenID, err := primitive.ObjectIDFromHex(meta.Core.OutgoingKey)
-	// if err != nil {
-	// 	tsk.Fail(err)
-	//
-	// 	return
-	// }
-	//
-	// token, err := auth.GetTokenByID(ctx, tokenID)
-	// if err != nil {
-	// 	tsk.Fail(err)
-	//
-	// 	return
-	// }
-	//
-	// client, err := proxy.ApiClientFromTower(*meta.Core)
-	// if err != nil {
-	// 	tsk.Fail(err)
-	//
-	// 	return
-	// }
-	//
-	// tokenStr := string(token.Token[:])
-	// _, _, err = client.TowersAPI.InitializeTower(ctx).Request(openapi.StructsInitServerParams{
-	// 	Name:     meta.Core.Name,
-	// 	Role:     string(tower_model.RoleRestore),
-	// 	CoreKey:  &tokenStr,
-	// 	RemoteID: &meta.Local.TowerID,
-	// 	LocalID:  &meta.Core.TowerID,
-	// }).Execute()
-	// if err != nil {
-	// 	tsk.Fail(err)
-	//
-	// 	return
-	// }
-	//
-	// // Restore journal
-	// ctx.Notify(ctx,
-	// 	notify.NewTaskNotification(tsk, websocket_mod.RestoreProgressEvent, task.Result{"stage": "Restoring file history", "timestamp": time.Now().UnixMilli()}),
-	// )
-	//
-	// actions, err := journal.GetAllActionsByTowerID(ctx, meta.Core.TowerID)
-	// if err != nil {
-	// 	tsk.Fail(err)
-	//
-	// 	return
-	// }
-	//
-	// // TODO: Move this to use generated client
-	// _, err = proxy.NewCoreRequest(meta.Core, "POST", "/restore/history").WithBody(actions).Call()
-	// tsk.ReqNoErr(err)
-	//
-	// // Restore users
-	// ctx.Notify(ctx, notify.NewTaskNotification(tsk, websocket_mod.RestoreProgressEvent, task.Result{"stage": "Restoring users", "timestamp": time.Now().UnixMilli()}))
-	//
-	// users, err := user_model.GetAllUsers(tsk.Ctx)
-	// if err != nil {
-	// 	tsk.Fail(err)
-	//
-	// 	return
-	// }
-	//
-	// userInfos := make([]structs.UserInfo, 0, len(users))
-	// for _, u := range users {
-	// 	userInfos = append(userInfos, reshape.UserToUserInfo(tsk.Ctx, u))
-	// }
-	//
-	// _, err = proxy.NewCoreRequest(meta.Core, "POST", "/restore/users").WithBody(users).Call()
-	// tsk.ReqNoErr(err)
-	//
-	// // Restore keys
-	// ctx.Notify(ctx, notify.NewTaskNotification(tsk, websocket_mod.RestoreProgressEvent, task.Result{"stage": "Restoring api keys", "timestamp": time.Now().UnixMilli()}))
-	//
-	// tokens, err := auth.GetAllTokensByTowerID(ctx, meta.Core.TowerID)
-	// if err != nil {
-	// 	tsk.Fail(err)
-	//
-	// 	return
-	// }
-	//
-	// _, err = proxy.NewCoreRequest(meta.Core, "POST", "/restore/keys").WithBody(tokens).Call()
-	// tsk.ReqNoErr(err)
-	//
-	// // Restore instances
-	// ctx.Notify(ctx, notify.NewTaskNotification(
-	// 	tsk, websocket_mod.RestoreProgressEvent, task.Result{"stage": "Restoring api keys", "timestamp": time.Now().UnixMilli()},
-	// ))
-	//
-	// towers, err := tower_model.GetAllTowersByTowerID(ctx, meta.Core.TowerID)
-	// if err != nil {
-	// 	tsk.Fail(err)
-	//
-	// 	return
-	// }
-	//
-	// _, err = proxy.NewCoreRequest(meta.Core, "POST", "/restore/instances").WithBody(towers).Call()
-	// tsk.ReqNoErr(err)
-	//
-	// // Restore files
-	// // ctx.Notify(ctx, notify.NewTaskNotification(
-	// // 	t, websocket_mod.RestoreProgressEvent, *task.TaskResult{"stage": "Restoring files", "timestamp": time.Now().UnixMilli()},
-	// // ))
-	// // for i, lt := range lts {
-	// // 	latest := lt.GetLatestAction()
-	// // 	if latest.GetActionType() == history.FileDelete {
-	// // 		continue
-	// // 	}
-	// // 	portable := fs.ParsePortable(latest.GetDestinationPath())
-	// // 	if portable.IsDir() {
-	// // 		continue
-	// // 	}
-	// //
-	// // 	f, err := fileService.GetFileByContentID(lt.GetContentID())
-	// // 	if err != nil {
-	// // 		t.Log().Error().Stack().Err(err).Msg("")
-	// // 		continue
-	// // 	}
-	// // 	if f == nil {
-	// // 		t.Log().Error().Msgf("File not found for contentID [%s]", lt.GetContentID())
-	// // 		continue
-	// // 	}
-	// //
-	// // 	bs, err := f.ReadAll()
-	// // 	if err != nil {
-	// // 		t.ReqNoErr(err)
-	// // 	}
-	// // 	_, err = proxy.NewCoreRequest(meta.Core, "POST", "/restore/file").WithBodyBytes(bs).WithQuery(
-	// // 		"fileID", lt.ID(),
-	// // 	).Call()
-	// // 	if err != nil {
-	// // 		t.ReqNoErr(err)
-	// // 	}
-	// //
-	// // 	ctx.Notify(ctx, notify.NewTaskNotification(t, websocket_mod.RestoreProgressEvent, *task.TaskResult{"filesTotal": len(lts), "filesRestored": i}))
-	// // }
-	//
-	// _, err = proxy.NewCoreRequest(meta.Core, "POST", "/restore/complete").Call()
-	// if err != nil {
-	// 	tsk.ReqNoErr(err)
-	// }
-	//
-	// meta.Core.SetReportedRole(tower_model.RoleCore)
-	// ctx.Notify(ctx, notify.NewTaskNotification(tsk, websocket_mod.RestoreCompleteEvent, nil))
-	//
-	// // Disconnect the core client to force a reconnection
-	// // coreClient := meta.Pack.ClientService.GetClientByServerID(meta.Core.TowerID)
-	// // meta.Pack.ClientService.ClientDisconnect(coreClient)
-	//
-	// tsk.Success()
+func RestoreCore(tsk *task.Task) {
+	meta := tsk.GetMeta().(job.RestoreCoreMeta)
+
+	ctx, ok := context_service.FromContext(tsk.Ctx)
+	if !ok {
+		tsk.Fail(wlerrors.New("Failed to cast context to FilerContext"))
+
+		return
+	}
+
+	core := *meta.Core
+
+	tsk.SetErrorCleanup(
+		func(errTsk *task.Task) {
+			err := errTsk.ReadError()
+			notif := notify.NewTaskNotification(tsk, websocket_mod.RestoreFailedEvent, task.Result{"coreID": core.TowerID, "error": err.Error()})
+			ctx.Notify(errTsk.Ctx, notif)
+		},
+	)
+
+	if meta.Local.Role != tower_model.RoleBackup {
+		tsk.Fail(tower_model.ErrTowerNotBackup)
+
+		return
+	}
+
+	tsk.Log().Info().Msgf("Starting restore of [%s] to address [%s]", core.Name, core.Address)
+
+	notif := notify.NewTaskNotification(tsk, websocket_mod.RestoreStartedEvent, task.Result{"coreID": core.TowerID})
+	ctx.Notify(ctx, notif)
+
+	tsk.SetResult(task.Result{
+		"coreID": core.TowerID,
+	})
+
+	tsk.OnResult(
+		func(r task.Result) {
+			notif := notify.NewTaskNotification(
+				tsk,
+				websocket_mod.RestoreProgressEvent,
+				r,
+			)
+			ctx.Notify(ctx, notif)
+		},
+	)
+
+	// Prime the tower to be restored, or resume a restore that was interrupted
+	tsk.SetResult(task.Result{"stage": "Connecting to remote"})
+
+	err := tower_service.StartCoreRestore(ctx, core)
+	if err != nil {
+		tsk.Fail(err)
+
+		return
+	}
+
+	tsk.SetResult(task.Result{"stage": "Restoring users, shares, tags and file history"})
+
+	archive, err := getRestoreArchive(ctx, core)
+	if err != nil {
+		tsk.Fail(err)
+
+		return
+	}
+
+	err = tower_service.SendRestoreArchive(ctx, core, archive)
+	if err != nil {
+		tsk.Fail(err)
+
+		return
+	}
+
+	lifetimes, err := history_model.GetLifetimes(ctx, history_model.GetLifetimesOptions{ActiveOnly: true, TowerID: core.TowerID})
+	if err != nil {
+		tsk.Fail(err)
+
+		return
+	}
+
+	files := make([]history_model.FileAction, 0, len(lifetimes))
+
+	for _, lt := range lifetimes {
+		latest := lt.Actions[len(lt.Actions)-1]
+		if latest.GetRelevantPath().IsDir() {
+			continue
+		}
+
+		// Moves and deletes may not carry the content ID, so it is taken from the actions that set the content
+		latest.ContentID = lt.ContentID()
+		files = append(files, latest)
+	}
+
+	tsk.SetResult(task.Result{"stage": "Restoring files", "filesTotal": len(files), "filesRestored": 0})
+
+	for i, a := range files {
+		tsk.SetTimeout(time.Now().Add(5 * time.Minute))
+
+		err = sendRestoreFile(ctx, core, a)
+		if err != nil {
+			tsk.Fail(err)
+
+			return
+		}
+
+		tsk.SetResult(task.Result{"filesRestored": i + 1})
+	}
+
+	tsk.SetResult(task.Result{"stage": "Starting core"})
+
+	err = tower_service.SendRestoreComplete(ctx, core)
+	if err != nil {
+		tsk.Fail(err)
+
+		return
+	}
+
+	r := tsk.GetResult()
+	r["totalTime"] = tsk.ExeTime()
+	r["complete"] = true
+
+	notif = notify.NewTaskNotification(tsk, websocket_mod.RestoreCompleteEvent, r)
+	ctx.Notify(ctx, notif)
+
+	tsk.Success()
+}
+
+// getRestoreArchive gathers everything the local backup tower holds about core, other than file contents.
+func getRestoreArchive(ctx context_service.AppContext, core tower_model.Instance) (archive wlstructs.BackupInfo, err error) {
+	actions, err := history_model.GetActionsByTowerID(ctx, core.TowerID)
+	if err != nil {
+		return archive, err
+	}
+
+	fileHistory := make([]history_model.FileAction, 0, len(actions))
+	for _, a := range actions {
+		fileHistory = append(fileHistory, *a)
+	}
+
+	allUsers, err := user_model.GetAllUsers(ctx)
+	if err != nil {
+		return archive, err
+	}
+
+	users := make([]*user_model.User, 0, len(allUsers))
+
+	for _, u := range allUsers {
+		if u.CreatedBy == core.TowerID {
+			users = append(users, u)
+		}
+	}
+
+	towers, err := tower_model.GetAllTowersByTowerID(ctx, core.TowerID)
+	if err != nil {
+		return archive, err
+	}
+
+	archive = reshape.NewBackupInfo(ctx, fileHistory, users, towers, nil)
+
+	archive.Tokens, err = backup_model.GetRecords[wlstructs.TokenInfo](ctx, core.TowerID, backup_model.KindToken)
+	if err != nil {
+		return archive, err
+	}
+
+	archive.Shares, err = backup_model.GetRecords[wlstructs.ShareInfoArchive](ctx, core.TowerID, backup_model.KindShare)
+	if err != nil {
+		return archive, err
+	}
+
+	archive.Tags, err = backup_model.GetRecords[wlstructs.TagInfo](ctx, core.TowerID, backup_model.KindTag)
+	if err != nil {
+		return archive, err
+	}
+
+	archive.Covers, err = backup_model.GetRecords[wlstructs.CoverInfo](ctx, core.TowerID, backup_model.KindCover)
+	if err != nil {
+		return archive, err
+	}
+
+	archive.Media, err = backup_model.GetRecords[wlstructs.MediaInfo](ctx, core.TowerID, backup_model.KindMedia)
+	if err != nil {
+		return archive, err
+	}
+
+	return archive, nil
+}
+
+func sendRestoreFile(ctx context_service.AppContext, core tower_model.Instance, a history_model.FileAction) error {
+	// Empty files have no contents to back up
+	if a.ContentID == "" {
+		return tower_service.SendRestoreFile(ctx, core, a.FileID, strings.NewReader(""), 0)
+	}
+
+	contentPath := file_model.RestoreDirPath.Child(core.TowerID, true).Child(a.ContentID, false)
+
+	f, err := os.Open(contentPath.ToAbsolute())
+	if err != nil {
+		return wlerrors.Wrapf(err, "failed to open backup of file [%s]", a.FileID)
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	stat, err := f.Stat()
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	return tower_service.SendRestoreFile(ctx, core, a.FileID, f, stat.Size())
 }
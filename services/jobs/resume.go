@@ -0,0 +1,107 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	job_model "github.com/ethanrous/weblens/models/job"
+	"github.com/ethanrous/weblens/models/task"
+	tower_model "github.com/ethanrous/weblens/models/tower"
+	"github.com/ethanrous/weblens/modules/config"
+	"github.com/ethanrous/weblens/modules/startup"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+)
+
+func init() {
+	startup.RegisterHook(resumeTasks)
+}
+
+// resumeTasks dispatches the durable tasks that were cut off when the server last stopped, and drops task history
+// older than task.RecordRetention.
+func resumeTasks(ctx context.Context, cnf config.Provider) error {
+	// Durable tasks all work on the filesystem, which is only loaded once the tower is initialized
+	role := tower_model.Role(cnf.InitRole)
+	if role != tower_model.RoleCore && role != tower_model.RoleBackup {
+		return nil
+	}
+
+	appCtx, ok := context_service.FromContext(ctx)
+	if !ok {
+		return wlerrors.WithStack(context_service.ErrNoContext)
+	}
+
+	err := task.DeleteFinishedRecords(ctx, time.Now().Add(-task.RecordRetention))
+	if err != nil {
+		return err
+	}
+
+	return appCtx.TaskService.ResumeTasks(ctx)
+}
+
+// loadIndexMeta rebuilds the metadata of a directory scan from its task record.
+func loadIndexMeta(ctx context.Context, data []byte) (task.Metadata, error) {
+	record, err := decodeMetaRecord[job_model.FileMetaRecord](data)
+	if err != nil {
+		return nil, err
+	}
+
+	appCtx, ok := context_service.FromContext(ctx)
+	if !ok {
+		return nil, wlerrors.WithStack(context_service.ErrNoContext)
+	}
+
+	f, err := appCtx.FileService.GetFileByID(ctx, record.FileID)
+	if err != nil {
+		return nil, err
+	}
+
+	return job_model.IndexMeta{File: f, ForceReIndex: record.ForceReIndex}, nil
+}
+
+// loadExtractAndEmbedMeta rebuilds the metadata of an extract-and-embed task from its task record.
+func loadExtractAndEmbedMeta(ctx context.Context, data []byte) (task.Metadata, error) {
+	record, err := decodeMetaRecord[job_model.FileMetaRecord](data)
+	if err != nil {
+		return nil, err
+	}
+
+	appCtx, ok := context_service.FromContext(ctx)
+	if !ok {
+		return nil, wlerrors.WithStack(context_service.ErrNoContext)
+	}
+
+	f, err := appCtx.FileService.GetFileByID(ctx, record.FileID)
+	if err != nil {
+		return nil, err
+	}
+
+	return job_model.ExtractAndEmbedMeta{File: f, ForceReIndex: record.ForceReIndex}, nil
+}
+
+// loadBackupMeta rebuilds the metadata of a backup from its task record.
+func loadBackupMeta(ctx context.Context, data []byte) (task.Metadata, error) {
+	record, err := decodeMetaRecord[job_model.BackupMetaRecord](data)
+	if err != nil {
+		return nil, err
+	}
+
+	core, err := tower_model.GetTowerByID(ctx, record.CoreID)
+	if err != nil {
+		return nil, err
+	}
+
+	return job_model.BackupMeta{Core: core}, nil
+}
+
+func decodeMetaRecord[T any](data []byte) (T, error) {
+	var record T
+
+	err := json.Unmarshal(data, &record)
+	if err != nil {
+		return record, wlerrors.WithStack(err)
+	}
+
+	return record, nil
+}
@@ -0,0 +1,231 @@
+package jobs
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	file_model "github.com/ethanrous/weblens/models/file"
+	job_model "github.com/ethanrous/weblens/models/job"
+	schedule_model "github.com/ethanrous/weblens/models/schedule"
+	"github.com/ethanrous/weblens/models/task"
+	tower_model "github.com/ethanrous/weblens/models/tower"
+	"github.com/ethanrous/weblens/modules/config"
+	"github.com/ethanrous/weblens/modules/startup"
+	context_mod "github.com/ethanrous/weblens/modules/wlcontext"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+)
+
+// schedulerInterval is how often the scheduler checks for due schedules. Cron expressions have a resolution of one
+// minute, so checking more often would not run anything sooner.
+const schedulerInterval = time.Minute
+
+// ErrJobNotSchedulable indicates a schedule was given a job that cannot be run on a schedule.
+var ErrJobNotSchedulable = wlerrors.Statusf(http.StatusBadRequest, "job cannot be scheduled")
+
+// ScheduleBuilder builds the metadata of the job a schedule runs, from the params of the schedule.
+type ScheduleBuilder func(ctx context_service.AppContext, params map[string]string) (task.Metadata, error)
+
+// schedulableJobs are the jobs that can be run on a schedule, with the builder for the metadata of each.
+var schedulableJobs = map[string]ScheduleBuilder{
+	job_model.ScanDirectoryTask: buildScanSchedule,
+	job_model.GatherFsStatsTask: buildFsStatsSchedule,
+	job_model.BackupTask:        buildBackupSchedule,
+	job_model.PurgeTrashTask:    buildTrashPurgeSchedule,
+	job_model.GCHistoryTask:     buildHistoryGCSchedule,
+}
+
+func init() {
+	startup.RegisterHook(func(ctx context.Context, _ config.Provider) error {
+		go SchedulerD(context_mod.ToZ(ctx), schedulerInterval)
+
+		return nil
+	})
+}
+
+// SchedulableJobs returns the names of the jobs that can be run on a schedule.
+func SchedulableJobs() []string {
+	return slices.Sorted(maps.Keys(schedulableJobs))
+}
+
+// VerifySchedule checks that the job of s can be scheduled, and that its params are valid for that job.
+func VerifySchedule(ctx context_service.AppContext, s *schedule_model.Schedule) error {
+	build, ok := schedulableJobs[s.JobName]
+	if !ok {
+		return wlerrors.Wrapf(ErrJobNotSchedulable, "[%s]", s.JobName)
+	}
+
+	meta, err := build(ctx, s.Params)
+	if err != nil {
+		return err
+	}
+
+	return meta.Verify()
+}
+
+// SchedulerD runs the scheduler daemon, which dispatches the jobs of schedules as they become due.
+func SchedulerD(ctx context_mod.Z, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := RunDueSchedules(ctx, time.Now())
+		if err != nil {
+			ctx.Log().Error().Stack().Err(err).Msg("Failed to run due schedules")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			ctx.Log().Debug().Msg("SchedulerD exiting")
+
+			return
+		}
+	}
+}
+
+// RunDueSchedules dispatches the job of every schedule that is due at now. A schedule whose job cannot be dispatched
+// is moved on to its next run, with the reason recorded as its last error.
+func RunDueSchedules(ctx context.Context, now time.Time) error {
+	local, err := tower_model.GetLocal(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Scheduled jobs work on the filesystem, which is only loaded once the tower is initialized
+	if local.Role != tower_model.RoleCore && local.Role != tower_model.RoleBackup {
+		return nil
+	}
+
+	schedules, err := schedule_model.GetDueSchedules(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range schedules {
+		_, err = RunSchedule(ctx, s, now)
+		if err == nil {
+			continue
+		}
+
+		context_mod.ToZ(ctx).Log().Warn().Err(err).Msgf("Could not run schedule [%s]", s.Name)
+
+		err = s.RecordSkipped(ctx, err, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RunSchedule dispatches the job of s, records the run, and moves s on to its next run. The outcome of the task is
+// recorded on s once the task exits.
+func RunSchedule(ctx context.Context, s *schedule_model.Schedule, now time.Time) (*task.Task, error) {
+	appCtx, ok := context_service.FromContext(ctx)
+	if !ok {
+		return nil, wlerrors.WithStack(context_service.ErrNoContext)
+	}
+
+	build, ok := schedulableJobs[s.JobName]
+	if !ok {
+		return nil, wlerrors.Wrapf(ErrJobNotSchedulable, "[%s]", s.JobName)
+	}
+
+	meta, err := build(appCtx, s.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	tsk, err := appCtx.DispatchJob(s.JobName, meta, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.RecordRun(ctx, tsk.ID(), now)
+	if err != nil {
+		return nil, err
+	}
+
+	appCtx.Log().Debug().Msgf("Schedule [%s] dispatched [%s] task [%s], next run at [%s]", s.Name, s.JobName, tsk.ID(), s.NextRun)
+
+	go func() {
+		tsk.Wait()
+
+		_, status := tsk.Status()
+
+		err := s.RecordResult(context.WithoutCancel(ctx), string(status), tsk.ReadError())
+		if err != nil {
+			appCtx.Log().Error().Stack().Err(err).Msgf("Failed to record result of schedule [%s]", s.Name)
+		}
+	}()
+
+	return tsk, nil
+}
+
+// buildScanSchedule scans the folder given by the "folderID" param, forcing a re-index if "forceReindex" is true.
+func buildScanSchedule(ctx context_service.AppContext, params map[string]string) (task.Metadata, error) {
+	folder, err := getScheduleFolder(ctx, params["folderID"])
+	if err != nil {
+		return nil, err
+	}
+
+	forceReIndex, _ := strconv.ParseBool(params["forceReindex"])
+
+	return job_model.IndexMeta{File: folder, ForceReIndex: forceReIndex}, nil
+}
+
+// buildFsStatsSchedule gathers statistics for the folder given by the "folderID" param, or for every user if it is
+// not set.
+func buildFsStatsSchedule(ctx context_service.AppContext, params map[string]string) (task.Metadata, error) {
+	folderID := params["folderID"]
+	if folderID == "" {
+		folderID = file_model.UsersTreeKey
+	}
+
+	folder, err := getScheduleFolder(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return job_model.FsStatMeta{RootDir: folder}, nil
+}
+
+// buildBackupSchedule backs up the core given by the "coreID" param.
+func buildBackupSchedule(ctx context_service.AppContext, params map[string]string) (task.Metadata, error) {
+	if params["coreID"] == "" {
+		return nil, wlerrors.Statusf(http.StatusBadRequest, "backup schedules require a coreID param")
+	}
+
+	core, err := tower_model.GetTowerByID(ctx, params["coreID"])
+	if err != nil {
+		return nil, wlerrors.Statusf(http.StatusBadRequest, "tower [%s] not found", params["coreID"])
+	}
+
+	if !core.IsCore() {
+		return nil, wlerrors.Statusf(http.StatusBadRequest, "tower [%s] is not a core", core.TowerID)
+	}
+
+	return job_model.BackupMeta{Core: core}, nil
+}
+
+func getScheduleFolder(ctx context_service.AppContext, folderID string) (*file_model.WeblensFileImpl, error) {
+	if folderID == "" {
+		return nil, wlerrors.Statusf(http.StatusBadRequest, "schedule requires a folderID param")
+	}
+
+	folder, err := ctx.FileService.GetFileByID(ctx, folderID)
+	if err != nil {
+		return nil, wlerrors.Statusf(http.StatusBadRequest, "folder [%s] not found", folderID)
+	}
+
+	if !folder.IsDir() {
+		return nil, wlerrors.Statusf(http.StatusBadRequest, "[%s] is not a folder", folderID)
+	}
+
+	return folder, nil
+}
@@ -0,0 +1,43 @@
+package jobs_test
+
+import (
+	"context"
+	"testing"
+
+	job_model "github.com/ethanrous/weblens/models/job"
+	schedule_model "github.com/ethanrous/weblens/models/schedule"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/ethanrous/weblens/services/jobs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedulableJobs(t *testing.T) {
+	assert.Equal(t, []string{job_model.BackupTask, job_model.GatherFsStatsTask, job_model.GCHistoryTask, job_model.PurgeTrashTask, job_model.ScanDirectoryTask}, jobs.SchedulableJobs())
+}
+
+func TestVerifySchedule(t *testing.T) {
+	ctx := ctxservice.NewTestContext(context.Background())
+
+	t.Run("rejects jobs that cannot be scheduled", func(t *testing.T) {
+		s, err := schedule_model.NewSchedule("upload", job_model.UploadFilesTask, "@daily", nil, "admin")
+		require.NoError(t, err)
+
+		assert.True(t, wlerrors.Is(jobs.VerifySchedule(ctx, s), jobs.ErrJobNotSchedulable))
+	})
+
+	t.Run("requires a folder for scans", func(t *testing.T) {
+		s, err := schedule_model.NewSchedule("scan", job_model.ScanDirectoryTask, "@daily", nil, "admin")
+		require.NoError(t, err)
+
+		assert.Error(t, jobs.VerifySchedule(ctx, s))
+	})
+
+	t.Run("requires a core for backups", func(t *testing.T) {
+		s, err := schedule_model.NewSchedule("backup", job_model.BackupTask, "@daily", nil, "admin")
+		require.NoError(t, err)
+
+		assert.Error(t, jobs.VerifySchedule(ctx, s))
+	})
+}
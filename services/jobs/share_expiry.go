@@ -0,0 +1,95 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	share_model "github.com/ethanrous/weblens/models/share"
+	tower_model "github.com/ethanrous/weblens/models/tower"
+	"github.com/ethanrous/weblens/modules/config"
+	"github.com/ethanrous/weblens/modules/startup"
+	websocket_mod "github.com/ethanrous/weblens/modules/websocket"
+	context_mod "github.com/ethanrous/weblens/modules/wlcontext"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/ethanrous/weblens/services/notify"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// shareExpiryInterval is how often the share expiry daemon checks for expired shares.
+const shareExpiryInterval = time.Minute
+
+func init() {
+	startup.RegisterHook(func(ctx context.Context, _ config.Provider) error {
+		go ShareExpiryD(context_mod.ToZ(ctx), shareExpiryInterval)
+
+		return nil
+	})
+}
+
+// ShareExpiryD runs the share expiry daemon that periodically disables shares which have passed their expiration time.
+func ShareExpiryD(ctx context_mod.Z, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := DisableExpiredShares(ctx)
+		if err != nil {
+			ctx.Log().Error().Stack().Err(err).Msg("Failed to disable expired shares")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			ctx.Log().Debug().Msg("ShareExpiryD exiting")
+
+			return
+		}
+	}
+}
+
+// DisableExpiredShares disables every enabled share whose expiration time has passed, and notifies each share's
+// owner that it has expired. Access through an expired share is denied regardless, this keeps the stored state in
+// line with that and lets the owner know.
+func DisableExpiredShares(ctx context.Context) error {
+	local, err := tower_model.GetLocal(ctx)
+	if wlerrors.Is(err, mongo.ErrNoDocuments) {
+		// The server has not been initialized yet, so there are no shares to expire
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// Backup towers only hold copies of their cores' shares, the core is responsible for expiring them
+	if local.Role != tower_model.RoleCore {
+		return nil
+	}
+
+	appCtx, ok := context_service.FromContext(ctx)
+	if !ok {
+		return wlerrors.New("Failed to cast context to AppContext")
+	}
+
+	shares, err := share_model.GetExpiredShares(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, share := range shares {
+		err = share.UpdateEnabled(ctx, false)
+		if err != nil {
+			return err
+		}
+
+		appCtx.Log().Debug().Msgf("Disabled expired share [%s]", share.ShareID.Hex())
+
+		notif := notify.NewUserNotification(
+			share.GetOwner(),
+			websocket_mod.ShareExpiredEvent,
+			websocket_mod.WsData{"shareID": share.ShareID.Hex(), "fileID": share.FileID},
+		)
+		appCtx.Notify(ctx, notif)
+	}
+
+	return nil
+}
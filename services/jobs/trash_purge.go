@@ -0,0 +1,310 @@
+package jobs
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	album_model "github.com/ethanrous/weblens/models/album"
+	"github.com/ethanrous/weblens/models/db"
+	"github.com/ethanrous/weblens/models/featureflags"
+	file_model "github.com/ethanrous/weblens/models/file"
+	"github.com/ethanrous/weblens/models/history"
+	job_model "github.com/ethanrous/weblens/models/job"
+	media_model "github.com/ethanrous/weblens/models/media"
+	schedule_model "github.com/ethanrous/weblens/models/schedule"
+	tag_model "github.com/ethanrous/weblens/models/tag"
+	"github.com/ethanrous/weblens/models/task"
+	tower_model "github.com/ethanrous/weblens/models/tower"
+	user_model "github.com/ethanrous/weblens/models/usermodel"
+	"github.com/ethanrous/weblens/modules/config"
+	"github.com/ethanrous/weblens/modules/startup"
+	websocket_mod "github.com/ethanrous/weblens/modules/websocket"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+	file_service "github.com/ethanrous/weblens/services/file"
+	"github.com/ethanrous/weblens/services/notify"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// defaultTrashPurgeCron is when the trash purge schedule created for new core towers runs, daily at 4am.
+const defaultTrashPurgeCron = "0 4 * * *"
+
+// TrashPurgeSummary describes the items purged from the trash of one user.
+type TrashPurgeSummary struct {
+	Username   string `json:"username"`
+	ItemsCount int    `json:"itemsCount"`
+	// BytesPurged is the total size of the purged items. Deleted files are kept in the restore tree until their history
+	// is garbage collected, so this much space is only freed on disk once that happens.
+	BytesPurged int64 `json:"bytesPurged"`
+}
+
+func init() {
+	startup.RegisterHook(ensureTrashPurgeSchedule)
+}
+
+// ensureTrashPurgeSchedule creates the daily trash purge schedule on core towers that do not have one yet. It can be
+// disabled or moved to another time like any other schedule, but is created again on the next start if deleted.
+func ensureTrashPurgeSchedule(ctx context.Context, _ config.Provider) error {
+	local, err := tower_model.GetLocal(ctx)
+	if wlerrors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// Backup towers delete files outright, and have no trash to purge
+	if local.Role != tower_model.RoleCore {
+		return nil
+	}
+
+	existing, err := schedule_model.GetSchedulesByJobName(ctx, job_model.PurgeTrashTask)
+	if err != nil || len(existing) != 0 {
+		return err
+	}
+
+	s, err := schedule_model.NewSchedule("Purge expired trash", job_model.PurgeTrashTask, defaultTrashPurgeCron, nil, local.TowerID)
+	if err != nil {
+		return err
+	}
+
+	return schedule_model.SaveSchedule(ctx, s)
+}
+
+// PurgeTrash is a task that permanently deletes the items that have been in the trash longer than their owner's
+// retention, and notifies each owner of what was purged.
+func PurgeTrash(tsk *task.Task) {
+	meta := tsk.GetMeta().(job_model.PurgeTrashMeta)
+
+	ctx, ok := context_service.FromContext(tsk.Ctx)
+	if !ok {
+		tsk.Fail(wlerrors.WithStack(context_service.ErrNoContext))
+
+		return
+	}
+
+	flags, err := featureflags.GetFlags(ctx)
+	if err != nil {
+		tsk.Fail(err)
+
+		return
+	}
+
+	var users []*user_model.User
+
+	if meta.Username != "" {
+		u, err := user_model.GetUserByUsername(ctx, meta.Username)
+		if err != nil {
+			tsk.Fail(err)
+
+			return
+		}
+
+		users = append(users, u)
+	} else {
+		users, err = user_model.GetAllUsers(ctx)
+		if err != nil {
+			tsk.Fail(err)
+
+			return
+		}
+	}
+
+	now := time.Now()
+	summaries := []TrashPurgeSummary{}
+
+	for _, u := range users {
+		if tsk.Ctx.Err() != nil {
+			tsk.Fail(tsk.Ctx.Err())
+
+			return
+		}
+
+		retention := TrashRetention(u, flags)
+		if u.IsSystemUser() || u.TrashID == "" || retention == 0 {
+			continue
+		}
+
+		summary, err := PurgeUserTrash(ctx, u, now.Add(-retention))
+		if err != nil {
+			tsk.Fail(wlerrors.Wrapf(err, "failed to purge trash of [%s]", u.Username))
+
+			return
+		}
+
+		if summary.ItemsCount == 0 {
+			continue
+		}
+
+		summaries = append(summaries, summary)
+
+		tsk.Log().Info().Msgf("Purged %d expired items (%d bytes) from the trash of [%s]", summary.ItemsCount, summary.BytesPurged, u.Username)
+
+		notif := notify.NewUserNotification(
+			u.Username,
+			websocket_mod.TrashPurgedEvent,
+			websocket_mod.WsData{"itemsCount": summary.ItemsCount, "bytesPurged": summary.BytesPurged, "retentionDays": int(retention / (24 * time.Hour))},
+		)
+		ctx.Notify(ctx, notif)
+	}
+
+	tsk.SetResult(task.Result{"purged": summaries})
+	tsk.Success()
+}
+
+// TrashRetention returns how long items stay in the trash of u before they are purged, or 0 if they are kept until
+// deleted by hand.
+func TrashRetention(u *user_model.User, flags featureflags.Bundle) time.Duration {
+	days := u.TrashRetentionDays
+	if days == 0 {
+		days = flags.TrashRetentionDays
+	}
+
+	if days <= 0 {
+		return 0
+	}
+
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// PurgeUserTrash permanently deletes the items in the trash of u that were moved there before olderThan. Media left
+// without any files, and their cache files, are removed along with them. The deletions are journaled like any other,
+// so the content of the items stays in the restore tree until the history garbage collection removes it.
+func PurgeUserTrash(ctx context_service.AppContext, u *user_model.User, olderThan time.Time) (TrashPurgeSummary, error) {
+	summary := TrashPurgeSummary{Username: u.Username}
+
+	trash, err := ctx.FileService.GetFileByID(ctx, u.TrashID)
+	if err != nil {
+		return summary, err
+	}
+
+	children, err := ctx.FileService.GetChildren(ctx, trash)
+	if err != nil {
+		return summary, err
+	}
+
+	expired := []*file_model.WeblensFileImpl{}
+	contentIDs := []string{}
+
+	for _, child := range children {
+		trashedAt, err := getTrashedTime(ctx, child)
+		if err != nil {
+			return summary, err
+		}
+
+		if !trashedAt.Before(olderThan) {
+			continue
+		}
+
+		expired = append(expired, child)
+		summary.ItemsCount++
+		summary.BytesPurged += child.Size()
+
+		err = child.RecursiveMap(func(f *file_model.WeblensFileImpl) error {
+			if !f.IsDir() && f.GetContentID() != "" {
+				contentIDs = append(contentIDs, f.GetContentID())
+			}
+
+			return nil
+		})
+		if err != nil {
+			return summary, err
+		}
+	}
+
+	if len(expired) == 0 {
+		return summary, nil
+	}
+
+	err = ctx.FileService.DeleteFiles(ctx, expired...)
+	if err != nil {
+		return summary, err
+	}
+
+	for _, f := range expired {
+		err = tag_model.RemoveFileFromAllTags(ctx, f.ID())
+		if err != nil {
+			ctx.Log().Error().Err(err).Msgf("Failed to remove file %s from tags", f.ID())
+		}
+	}
+
+	err = removeOrphanedMedia(ctx, contentIDs)
+	if err != nil {
+		return summary, err
+	}
+
+	return summary, nil
+}
+
+// getTrashedTime returns when f was moved to the trash, which is the time of the last action in its history.
+func getTrashedTime(ctx context.Context, f *file_model.WeblensFileImpl) (time.Time, error) {
+	action, err := history.GetLastActionByFileIDBefore(ctx, f.ID(), time.Now())
+	if db.IsNotFound(err) {
+		// Files with no history are only expected if the journal was cleared, fall back to their modification time
+		return f.ModTime(), nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+
+	return action.GetTimestamp(), nil
+}
+
+// removeOrphanedMedia deletes the media of contentIDs that no longer belong to any file, and their cache files.
+func removeOrphanedMedia(ctx context_service.AppContext, contentIDs []string) error {
+	orphaned := []*media_model.Media{}
+	orphanedIDs := []string{}
+
+	for _, contentID := range contentIDs {
+		m, err := media_model.GetMediaByContentID(ctx, contentID)
+		if db.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if len(m.FileIDs) != 0 {
+			continue
+		}
+
+		orphaned = append(orphaned, m)
+		orphanedIDs = append(orphanedIDs, contentID)
+	}
+
+	if len(orphaned) == 0 {
+		return nil
+	}
+
+	err := media_model.DeleteMedias(ctx, orphaned...)
+	if err != nil {
+		return err
+	}
+
+	err = album_model.RemoveMediaFromAllAlbums(ctx, orphanedIDs...)
+	if err != nil {
+		return err
+	}
+
+	return file_service.RemoveCacheFilesWithFilter(ctx, orphanedIDs)
+}
+
+// buildTrashPurgeSchedule purges the trash of the user given by the "username" param, or of every user if it is not
+// set.
+func buildTrashPurgeSchedule(ctx context_service.AppContext, params map[string]string) (task.Metadata, error) {
+	local, err := tower_model.GetLocal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if local.Role != tower_model.RoleCore {
+		return nil, wlerrors.Statusf(http.StatusBadRequest, "trash can only be purged on core towers")
+	}
+
+	if username := params["username"]; username != "" {
+		_, err = user_model.GetUserByUsername(ctx, username)
+		if err != nil {
+			return nil, wlerrors.Statusf(http.StatusBadRequest, "user [%s] not found", username)
+		}
+	}
+
+	return job_model.PurgeTrashMeta{Username: params["username"]}, nil
+}
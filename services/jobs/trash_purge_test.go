@@ -0,0 +1,32 @@
+package jobs_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethanrous/weblens/models/featureflags"
+	user_model "github.com/ethanrous/weblens/models/usermodel"
+	"github.com/ethanrous/weblens/services/jobs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrashRetention(t *testing.T) {
+	day := 24 * time.Hour
+	flags := featureflags.Bundle{TrashRetentionDays: 30}
+
+	t.Run("uses the server retention by default", func(t *testing.T) {
+		assert.Equal(t, 30*day, jobs.TrashRetention(&user_model.User{}, flags))
+	})
+
+	t.Run("user retention overrides the server retention", func(t *testing.T) {
+		assert.Equal(t, 7*day, jobs.TrashRetention(&user_model.User{TrashRetentionDays: 7}, flags))
+	})
+
+	t.Run("negative user retention keeps items", func(t *testing.T) {
+		assert.Zero(t, jobs.TrashRetention(&user_model.User{TrashRetentionDays: -1}, flags))
+	})
+
+	t.Run("server retention of 0 keeps items", func(t *testing.T) {
+		assert.Zero(t, jobs.TrashRetention(&user_model.User{}, featureflags.Bundle{}))
+	})
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/ethanrous/weblens/modules/wlerrors"
 	slices_mod "github.com/ethanrous/weblens/modules/wlslices"
 	context_service "github.com/ethanrous/weblens/services/ctxservice"
+	file_service "github.com/ethanrous/weblens/services/file"
 	"github.com/ethanrous/weblens/services/notify"
 	"github.com/ethanrous/weblens/services/reshape"
 	"github.com/rs/zerolog"
@@ -49,10 +50,12 @@ func uploadCleanup(tsk *task.Task, rootFile *file_model.WeblensFileImpl, topLeve
 		return wlerrors.New("failed to get context")
 	}
 
-	for _, f := range fileMap {
+	for fileID, f := range fileMap {
 		tsk.Log().Debug().Func(func(e *zerolog.Event) {
 			e.Msgf("Cleaning up file [%+v]", *f)
 		})
+
+		file_service.ReleaseQuota(fileID)
 	}
 	// e.Msgf("Upload fileMap has %d remaining - and chunk stream has %d remaining", len(fileMap), len(meta.ChunkStream))
 
@@ -229,6 +232,7 @@ WriterLoop:
 			// the specific file has had an error or been canceled, and should be removed.
 			if total == -1 {
 				delete(fileMap, chunk.FileID)
+				file_service.ReleaseQuota(chunk.FileID)
 
 				continue
 			}
@@ -300,6 +304,7 @@ WriterLoop:
 				}
 
 				delete(fileMap, chunk.FileID)
+				file_service.ReleaseQuota(chunk.FileID)
 			}
 
 			tsk.Log().Trace().Func(func(e *zerolog.Event) {
@@ -319,5 +324,8 @@ WriterLoop:
 	}
 
 	tsk.Log().Debug().Func(func(e *zerolog.Event) { e.Msgf("Finished writing upload files for %s", rootFile.GetPortablePath()) })
+
+	file_service.NotifyQuotaUsage(appCtx, rootFile)
+
 	tsk.Success()
 }
@@ -290,3 +290,41 @@ func GetLatestPathByID(ctx context.Context, fileID string) (wlfs.Filepath, error
 
 	return wlfs.ParsePortable(result.Filepath)
 }
+
+// GetPastFolderActions retrieves the latest action of each file that was in the folder at path at the given time,
+// as recorded by the tower with towerID. Files that had been deleted, or moved out of the folder, by then are left out.
+func GetPastFolderActions(ctx context.Context, path wlfs.Filepath, timestamp time.Time, towerID string) ([]history.FileAction, error) {
+	if !path.IsDir() {
+		return nil, wlerrors.Wrapf(file_model.ErrDirectoryRequired, "cannot get past children of non-directory [%s]", path)
+	}
+
+	actions, err := history.GetActionsAtPathBefore(ctx, path, timestamp, history.GetActionsOptions{IncludeChildren: true, TowerID: towerID})
+	if err != nil {
+		return nil, err
+	}
+
+	// Actions are sorted newest first, so the first action seen for each file is the one that decides where it was
+	seen := make(map[string]struct{}, len(actions))
+	children := make([]history.FileAction, 0, len(actions))
+
+	for _, action := range actions {
+		if _, ok := seen[action.FileID]; ok {
+			continue
+		}
+
+		relevantPath := action.GetRelevantPath()
+		if relevantPath.IsZero() {
+			continue
+		}
+
+		seen[action.FileID] = struct{}{}
+
+		if action.ActionType == history.FileDelete || relevantPath == path || relevantPath.Dir() != path {
+			continue
+		}
+
+		children = append(children, action)
+	}
+
+	return children, nil
+}
@@ -231,3 +231,86 @@ func TestGetLatestPathByID(t *testing.T) {
 		}
 	})
 }
+
+func TestGetPastFolderActions(t *testing.T) {
+	ctx := db.SetupTestDB(t, history.FileHistoryCollectionKey)
+
+	now := time.Now()
+
+	folder := wlfs.BuildFilePath("USERS", "testuser/docs/")
+	kept := wlfs.BuildFilePath("USERS", "testuser/docs/kept.txt")
+	deleted := wlfs.BuildFilePath("USERS", "testuser/docs/deleted.txt")
+	movedOut := wlfs.BuildFilePath("USERS", "testuser/docs/moved.txt")
+	nested := wlfs.BuildFilePath("USERS", "testuser/docs/sub/nested.txt")
+
+	actions := []history.FileAction{
+		*createTestAction(t, testActionOptions{Timestamp: now, Filepath: kept, TowerID: "core1", FileID: "kept", ContentID: "kept-content"}),
+		*createTestAction(t, testActionOptions{Timestamp: now, Filepath: deleted, TowerID: "core1", FileID: "deleted"}),
+		*createTestAction(t, testActionOptions{Timestamp: now, Filepath: movedOut, TowerID: "core1", FileID: "moved"}),
+		*createTestAction(t, testActionOptions{Timestamp: now, Filepath: nested, TowerID: "core1", FileID: "nested"}),
+		*createTestAction(t, testActionOptions{Timestamp: now, Filepath: kept, TowerID: "core2", FileID: "other-core"}),
+		*createTestAction(t, testActionOptions{
+			Timestamp:  now.Add(time.Minute),
+			ActionType: history.FileDelete,
+			OriginPath: deleted,
+			TowerID:    "core1",
+			FileID:     "deleted",
+		}),
+		*createTestAction(t, testActionOptions{
+			Timestamp:       now.Add(time.Minute),
+			ActionType:      history.FileMove,
+			OriginPath:      movedOut,
+			DestinationPath: wlfs.BuildFilePath("USERS", "testuser/moved.txt"),
+			TowerID:         "core1",
+			FileID:          "moved",
+		}),
+	}
+
+	err := history.SaveActions(ctx, actions)
+	if err != nil {
+		t.Fatalf("failed to save actions: %v", err)
+	}
+
+	fileIDs := func(actions []history.FileAction) []string {
+		ids := make([]string, 0, len(actions))
+		for _, a := range actions {
+			ids = append(ids, a.FileID)
+		}
+
+		return ids
+	}
+
+	t.Run("lists files in the folder at the time", func(t *testing.T) {
+		result, err := journal.GetPastFolderActions(ctx, folder, now.Add(2*time.Minute), "core1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		ids := fileIDs(result)
+		if len(ids) != 1 || ids[0] != "kept" {
+			t.Fatalf("expected only [kept], got %v", ids)
+		}
+
+		if result[0].ContentID != "kept-content" {
+			t.Errorf("expected content ID kept-content, got %s", result[0].ContentID)
+		}
+	})
+
+	t.Run("includes files removed after the time", func(t *testing.T) {
+		result, err := journal.GetPastFolderActions(ctx, folder, now.Add(30*time.Second), "core1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(result) != 3 {
+			t.Fatalf("expected 3 files, got %v", fileIDs(result))
+		}
+	})
+
+	t.Run("rejects a file path", func(t *testing.T) {
+		_, err := journal.GetPastFolderActions(ctx, kept, now, "core1")
+		if err == nil {
+			t.Error("expected error for file path, got nil")
+		}
+	})
+}
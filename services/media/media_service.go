@@ -35,6 +35,7 @@ const (
 var extraMimes = []struct{ ext, mime string }{
 	{ext: ".m3u8", mime: "application/vnd.apple.mpegurl"},
 	{ext: ".mp4", mime: "video/mp4"},
+	{ext: ".ts", mime: "video/mp2t"},
 }
 
 func init() {
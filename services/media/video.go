@@ -5,15 +5,36 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	file_model "github.com/ethanrous/weblens/models/file"
 	media_model "github.com/ethanrous/weblens/models/media"
+	"github.com/ethanrous/weblens/modules/config"
 	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlog"
 	context_service "github.com/ethanrous/weblens/services/ctxservice"
 	ffmpeg "github.com/u2takey/ffmpeg-go"
 )
 
+// videoEvictInterval is the least time between two evictions of cached video renditions.
+const videoEvictInterval = 10 * time.Minute
+
+var lastVideoEviction atomic.Int64
+
+// videoStreamDir is a directory of cached video renditions found while evicting them.
+type videoStreamDir struct {
+	path     string
+	lastUsed time.Time
+	size     int64
+}
+
 // StreamVideo returns the video streamer object for the given media.
 func StreamVideo(ctx context.Context, m *media_model.Media) (*media_model.VideoStreamer, error) {
 	appCtx, ok := context_service.FromContext(ctx)
@@ -41,9 +62,119 @@ func StreamVideo(ctx context.Context, m *media_model.Media) (*media_model.VideoS
 
 	cache.Set(m.ID(), streamer)
 
+	// A new streamer may be about to transcode, so make room for it in the cache
+	if now := time.Now(); now.Sub(time.Unix(0, lastVideoEviction.Load())) > videoEvictInterval {
+		lastVideoEviction.Store(now.UnixNano())
+
+		go func() {
+			err := EvictVideoStreams(context.WithoutCancel(ctx))
+			if err != nil {
+				wlog.FromContext(ctx).Error().Stack().Err(err).Msg("Failed to evict cached video renditions")
+			}
+		}()
+	}
+
 	return streamer, nil
 }
 
+// EvictVideoStreams removes the transcoded renditions of videos that have not been streamed within the configured max
+// age, then those of the least recently streamed videos until the rest fit in the configured cache size. Videos that
+// are being transcoded are never evicted.
+func EvictVideoStreams(ctx context.Context) error {
+	appCtx, ok := context_service.FromContext(ctx)
+	if !ok {
+		return wlerrors.WithStack(context_service.ErrNoContext)
+	}
+
+	cnf := config.GetConfig()
+	cache := appCtx.GetCache(videoStreamerContextKey)
+
+	entries, err := os.ReadDir(file_model.ThumbsDirPath.ToAbsolute())
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	streamDirs := make([]videoStreamDir, 0, len(entries))
+
+	var totalSize int64
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !media_model.IsStreamDir(entry.Name()) {
+			continue
+		}
+
+		contentID := strings.TrimSuffix(entry.Name(), media_model.StreamDirSuffix)
+		if streamer, ok := cache.Get(contentID); ok && streamer.(*media_model.VideoStreamer).IsTranscoding() {
+			continue
+		}
+
+		streamDir, err := statVideoStreamDir(filepath.Join(file_model.ThumbsDirPath.ToAbsolute(), entry.Name()))
+		if err != nil {
+			return err
+		}
+
+		streamDirs = append(streamDirs, streamDir)
+		totalSize += streamDir.size
+	}
+
+	// Least recently used first
+	slices.SortFunc(streamDirs, func(a, b videoStreamDir) int {
+		return a.lastUsed.Compare(b.lastUsed)
+	})
+
+	expiry := time.Now().Add(-cnf.VideoCacheMaxAge)
+
+	for _, streamDir := range streamDirs {
+		if totalSize <= cnf.VideoCacheSize && streamDir.lastUsed.After(expiry) {
+			break
+		}
+
+		wlog.FromContext(ctx).Debug().Msgf("Evicting cached video renditions %s", streamDir.path)
+
+		err := os.RemoveAll(streamDir.path)
+		if err != nil {
+			return wlerrors.WithStack(err)
+		}
+
+		cache.Delete(strings.TrimSuffix(filepath.Base(streamDir.path), media_model.StreamDirSuffix))
+
+		totalSize -= streamDir.size
+	}
+
+	return nil
+}
+
+// statVideoStreamDir finds the size of a directory of cached renditions, and when it was last streamed from.
+func statVideoStreamDir(path string) (videoStreamDir, error) {
+	streamDir := videoStreamDir{path: path}
+
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if info.ModTime().After(streamDir.lastUsed) {
+			streamDir.lastUsed = info.ModTime()
+		}
+
+		if !d.IsDir() {
+			streamDir.size += info.Size()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return videoStreamDir{}, wlerrors.WithStack(err)
+	}
+
+	return streamDir, nil
+}
+
 func generateVideoThumbnail(filepath string) ([]byte, error) {
 	const frameNum = 10
 
@@ -71,6 +71,19 @@ func NewSystemNotification(event websocket_mod.WsEvent, data websocket_mod.WsDat
 	return msg
 }
 
+// NewUserNotification creates a websocket notification sent to every client the given user is connected on.
+func NewUserNotification(username string, event websocket_mod.WsEvent, data websocket_mod.WsData) websocket_mod.WsResponseInfo {
+	msg := websocket_mod.WsResponseInfo{
+		SubscribeKey:    username,
+		EventTag:        event,
+		Content:         data,
+		BroadcastType:   websocket_mod.UserSubscribe,
+		ConstructedTime: time.Now().UnixMilli(),
+	}
+
+	return msg
+}
+
 // NewFileNotification creates websocket notifications for a file event, including notifications for the file,
 // its parent folder, and optionally a pre-move parent if the file was moved.
 func NewFileNotification(
@@ -0,0 +1,35 @@
+package reshape
+
+import (
+	"slices"
+
+	album_model "github.com/ethanrous/weblens/models/album"
+	share_model "github.com/ethanrous/weblens/models/share"
+	"github.com/ethanrous/weblens/modules/wlstructs"
+)
+
+// AlbumToAlbumInfo converts an Album model to an AlbumInfo transfer object. share is the share of the album, or nil
+// if it is not shared.
+func AlbumToAlbumInfo(a *album_model.Album, share *share_model.FileShare) wlstructs.AlbumInfo {
+	shareID := ""
+	if share != nil {
+		shareID = share.ShareID.Hex()
+	}
+
+	mediaIDs := slices.Clone(a.Medias)
+	if mediaIDs == nil {
+		mediaIDs = []string{}
+	}
+
+	return wlstructs.AlbumInfo{
+		AlbumID:     a.ID(),
+		Title:       a.Title,
+		Description: a.Description,
+		Owner:       a.Owner,
+		Cover:       a.CoverID(),
+		MediaIDs:    mediaIDs,
+		ShareID:     shareID,
+		Created:     a.Created.UnixMilli(),
+		Updated:     a.Updated.UnixMilli(),
+	}
+}
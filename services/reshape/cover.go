@@ -0,0 +1,14 @@
+package reshape
+
+import (
+	cover_model "github.com/ethanrous/weblens/models/cover"
+	"github.com/ethanrous/weblens/modules/wlstructs"
+)
+
+// CoverToCoverInfo converts a cover Photo model to a CoverInfo transfer object.
+func CoverToCoverInfo(c cover_model.Photo) wlstructs.CoverInfo {
+	return wlstructs.CoverInfo{
+		FolderID:     c.FolderID,
+		CoverPhotoID: c.CoverPhotoID,
+	}
+}
@@ -21,6 +21,7 @@ func FileActionToFileActionInfo(fa history.FileAction, liveParentID string) wlst
 		Timestamp:       fa.Timestamp.UnixMilli(),
 		TowerID:         fa.TowerID,
 		ContentID:       fa.ContentID,
+		OldContentID:    fa.OldContentID,
 		LiveParentID:    liveParentID,
 	}
 }
@@ -42,5 +43,19 @@ func FileActionInfoToFileAction(info wlstructs.FileActionInfo) history.FileActio
 		Timestamp:       time.UnixMilli(info.Timestamp),
 		TowerID:         info.TowerID,
 		ContentID:       info.ContentID,
+		OldContentID:    info.OldContentID,
+	}
+}
+
+// FileActionToFileRevisionInfo converts an action that gave a file new content to a FileRevisionInfo.
+func FileActionToFileRevisionInfo(fa history.FileAction, isCurrent, available bool) wlstructs.FileRevisionInfo {
+	return wlstructs.FileRevisionInfo{
+		RevisionID: fa.ID.Hex(),
+		ContentID:  fa.ContentID,
+		Size:       fa.Size,
+		Timestamp:  fa.Timestamp.UnixMilli(),
+		Doer:       fa.Doer,
+		IsCurrent:  isCurrent,
+		Available:  available,
 	}
 }
@@ -1,6 +1,8 @@
 package reshape
 
 import (
+	"time"
+
 	media_model "github.com/ethanrous/weblens/models/media"
 	"github.com/ethanrous/weblens/modules/wlstructs"
 )
@@ -31,6 +33,31 @@ func MediaToMediaInfo(m *media_model.Media) wlstructs.MediaInfo {
 	}
 }
 
+// MediaInfoToMedia converts a MediaInfo transfer object to a Media model.
+func MediaInfoToMedia(info wlstructs.MediaInfo) *media_model.Media {
+	m := &media_model.Media{
+		ContentID:  info.ContentID,
+		FileIDs:    info.FileIDs,
+		CreateDate: time.UnixMilli(info.CreateDate),
+		Owner:      info.Owner,
+		Width:      info.Width,
+		Height:     info.Height,
+		PageCount:  info.PageCount,
+		Duration:   info.Duration,
+		MimeType:   info.MimeType,
+		Location:   info.Location,
+		Hidden:     info.Hidden,
+		Enabled:    info.Enabled,
+		LikedBy:    info.LikedBy,
+	}
+
+	if m.LikedBy == nil {
+		m.LikedBy = []string{}
+	}
+
+	return m
+}
+
 // NewMediaBatchInfo creates a batch media information object from a slice of Media models.
 func NewMediaBatchInfo(m []*media_model.Media, opts ...MediaBatchOptions) wlstructs.MediaBatchInfo {
 	options := MediaBatchOptions{}
@@ -61,6 +88,23 @@ func NewMediaBatchInfo(m []*media_model.Media, opts ...MediaBatchOptions) wlstru
 	}
 }
 
+// NewMediaMapInfo creates the map information object for clusters of media.
+func NewMediaMapInfo(clusters []media_model.MediaCluster) wlstructs.MediaMapInfo {
+	info := wlstructs.MediaMapInfo{Clusters: make([]wlstructs.MediaClusterInfo, 0, len(clusters))}
+
+	for _, c := range clusters {
+		info.Clusters = append(info.Clusters, wlstructs.MediaClusterInfo{
+			Lat:     c.Lat,
+			Lng:     c.Lng,
+			Count:   c.Count,
+			CoverID: c.CoverID,
+		})
+		info.MediaCount += c.Count
+	}
+
+	return info
+}
+
 // MediaTypeToMediaTypeInfo converts a MediaType model to a MediaTypeInfo transfer object.
 func MediaTypeToMediaTypeInfo(mt media_model.MType) wlstructs.MediaTypeInfo {
 	return wlstructs.MediaTypeInfo{
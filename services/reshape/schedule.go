@@ -0,0 +1,45 @@
+package reshape
+
+import (
+	"time"
+
+	schedule_model "github.com/ethanrous/weblens/models/schedule"
+	"github.com/ethanrous/weblens/modules/wlstructs"
+)
+
+// SchedulesToScheduleInfos converts a slice of Schedule models to ScheduleInfo transfer objects.
+func SchedulesToScheduleInfos(schedules []*schedule_model.Schedule) []wlstructs.ScheduleInfo {
+	infos := make([]wlstructs.ScheduleInfo, 0, len(schedules))
+	for _, s := range schedules {
+		infos = append(infos, ScheduleToScheduleInfo(s))
+	}
+
+	return infos
+}
+
+// ScheduleToScheduleInfo converts a Schedule model to a ScheduleInfo transfer object.
+func ScheduleToScheduleInfo(s *schedule_model.Schedule) wlstructs.ScheduleInfo {
+	return wlstructs.ScheduleInfo{
+		ScheduleID: s.ID.Hex(),
+		Name:       s.Name,
+		JobName:    s.JobName,
+		Cron:       s.Cron,
+		Params:     s.Params,
+		Enabled:    s.Enabled,
+		CreatedBy:  s.CreatedBy,
+		Created:    s.Created.UnixMilli(),
+		NextRun:    unixMilliOrZero(s.NextRun),
+		LastRun:    unixMilliOrZero(s.LastRun),
+		LastTaskID: s.LastTaskID,
+		LastStatus: s.LastStatus,
+		LastError:  s.LastError,
+	}
+}
+
+func unixMilliOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixMilli()
+}
@@ -0,0 +1,23 @@
+package reshape
+
+import (
+	"context"
+
+	auth_model "github.com/ethanrous/weblens/models/auth"
+	"github.com/ethanrous/weblens/modules/wlstructs"
+)
+
+// SessionToSessionInfo converts a login session to a SessionInfo for API responses. currentSessionID is the ID of the
+// session of the requester, so it can be marked as current.
+func SessionToSessionInfo(_ context.Context, s *auth_model.Session, currentSessionID string) wlstructs.SessionInfo {
+	return wlstructs.SessionInfo{
+		ID:          s.ID,
+		Username:    s.Username,
+		Device:      s.Device,
+		IP:          s.IP,
+		CreatedTime: s.CreatedTime.UnixMilli(),
+		LastUsed:    s.LastUsed.UnixMilli(),
+		Expires:     s.Expires.UnixMilli(),
+		Current:     s.ID == currentSessionID,
+	}
+}
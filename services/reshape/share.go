@@ -2,11 +2,14 @@ package reshape
 
 import (
 	"context"
+	"time"
 
 	share_model "github.com/ethanrous/weblens/models/share"
 	user_model "github.com/ethanrous/weblens/models/usermodel"
+	"github.com/ethanrous/weblens/modules/wlerrors"
 	"github.com/ethanrous/weblens/modules/wlog"
 	"github.com/ethanrous/weblens/modules/wlstructs"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // ShareToShareInfo converts a FileShare model to a ShareInfo transfer object.
@@ -29,9 +32,16 @@ func ShareToShareInfo(ctx context.Context, s *share_model.FileShare, isDir bool)
 		id = ""
 	}
 
+	shareType := "file"
+	if s.IsAlbumShare() {
+		shareType = "album"
+	}
+
 	return wlstructs.ShareInfo{
 		ShareID:      id,
 		FileID:       s.FileID,
+		AlbumID:      s.AlbumID,
+		ShareType:    shareType,
 		IsDir:        isDir,
 		ShareName:    s.ShareName,
 		Owner:        s.Owner,
@@ -43,9 +53,72 @@ func ShareToShareInfo(ctx context.Context, s *share_model.FileShare, isDir bool)
 		Enabled:      s.Enabled,
 		Expires:      s.Expires.UnixMilli(),
 		Updated:      s.Updated.UnixMilli(),
+
+		PasswordProtected: s.HasPassword(),
+	}
+}
+
+// ShareToShareInfoArchive converts a FileShare model to a ShareInfoArchive, to be backed up.
+func ShareToShareInfoArchive(_ context.Context, s *share_model.FileShare) wlstructs.ShareInfoArchive {
+	return wlstructs.ShareInfoArchive{
+		ShareID:      s.ShareID.Hex(),
+		FileID:       s.FileID,
+		AlbumID:      s.AlbumID,
+		ShareName:    s.ShareName,
+		Owner:        s.Owner,
+		Accessors:    s.Accessors,
+		Permissions:  permissionsToPermissionsInfoArchive(s.Permissions),
+		Expires:      unixMilliOrZero(s.Expires),
+		Updated:      s.Updated.UnixMilli(),
+		Public:       s.Public,
+		Wormhole:     s.Wormhole,
+		TimelineOnly: s.TimelineOnly,
+		Enabled:      s.Enabled,
+		PasswordHash: s.PasswordHash,
 	}
 }
 
+// ShareInfoArchiveToShare converts a backed up ShareInfoArchive back to a FileShare model.
+func ShareInfoArchiveToShare(info wlstructs.ShareInfoArchive) (*share_model.FileShare, error) {
+	shareID, err := primitive.ObjectIDFromHex(info.ShareID)
+	if err != nil {
+		return nil, wlerrors.Wrapf(err, "invalid share id [%s]", info.ShareID)
+	}
+
+	perms := make(map[string]*share_model.Permissions, len(info.Permissions))
+	for username, p := range info.Permissions {
+		perms[username] = &share_model.Permissions{
+			CanViewMedia: p.CanViewMedia,
+			CanView:      p.CanView,
+			CanEdit:      p.CanEdit,
+			CanDownload:  p.CanDownload,
+			CanDelete:    p.CanDelete,
+		}
+	}
+
+	var expires time.Time
+	if info.Expires != 0 {
+		expires = time.UnixMilli(info.Expires)
+	}
+
+	return &share_model.FileShare{
+		ShareID:      shareID,
+		FileID:       info.FileID,
+		AlbumID:      info.AlbumID,
+		ShareName:    info.ShareName,
+		Owner:        info.Owner,
+		Accessors:    info.Accessors,
+		Permissions:  perms,
+		Expires:      expires,
+		Updated:      time.UnixMilli(info.Updated),
+		Public:       info.Public,
+		Wormhole:     info.Wormhole,
+		TimelineOnly: info.TimelineOnly,
+		Enabled:      info.Enabled,
+		PasswordHash: info.PasswordHash,
+	}, nil
+}
+
 // PermissionsToPermissionsInfo converts a map of Permissions models to PermissionsInfo transfer objects.
 func PermissionsToPermissionsInfo(_ context.Context, perms map[string]*share_model.Permissions) map[string]wlstructs.PermissionsInfo {
 	permsInfo := make(map[string]wlstructs.PermissionsInfo, len(perms))
@@ -56,6 +129,18 @@ func PermissionsToPermissionsInfo(_ context.Context, perms map[string]*share_mod
 	return permsInfo
 }
 
+func permissionsToPermissionsInfoArchive(perms map[string]*share_model.Permissions) map[string]wlstructs.PermissionsInfoArchive {
+	permsInfo := make(map[string]wlstructs.PermissionsInfoArchive, len(perms))
+	for k, v := range perms {
+		permsInfo[k] = wlstructs.PermissionsInfoArchive{
+			PermissionsInfo: toPermissionInfo(*v),
+			CanViewMedia:    v.CanViewMedia,
+		}
+	}
+
+	return permsInfo
+}
+
 func toPermissionInfo(perms share_model.Permissions) wlstructs.PermissionsInfo {
 	return wlstructs.PermissionsInfo{
 		CanView:     perms.CanView,
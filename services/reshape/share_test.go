@@ -0,0 +1,52 @@
+package reshape_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	share_model "github.com/ethanrous/weblens/models/share"
+	"github.com/ethanrous/weblens/services/reshape"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestShareInfoArchiveRoundTrip(t *testing.T) {
+	share := &share_model.FileShare{
+		ShareID:   primitive.NewObjectID(),
+		FileID:    "file-1",
+		ShareName: "Holiday",
+		Owner:     "alice",
+		Accessors: []string{"bob"},
+		Permissions: map[string]*share_model.Permissions{
+			"bob":    share_model.NewPermissions(),
+			"public": share_model.NewEmptyPermissions(),
+		},
+		Expires:      time.UnixMilli(1700000000000),
+		Updated:      time.UnixMilli(1690000000000),
+		Public:       true,
+		Enabled:      true,
+		PasswordHash: "hash",
+	}
+
+	restored, err := reshape.ShareInfoArchiveToShare(reshape.ShareToShareInfoArchive(context.Background(), share))
+	require.NoError(t, err)
+
+	assert.Equal(t, share.ShareID, restored.ShareID)
+	assert.Equal(t, share.Accessors, restored.Accessors)
+	assert.Equal(t, share.Permissions, restored.Permissions)
+	assert.True(t, share.Expires.Equal(restored.Expires))
+	assert.True(t, share.Updated.Equal(restored.Updated))
+	assert.Equal(t, share.PasswordHash, restored.PasswordHash)
+	assert.True(t, restored.Public)
+	assert.True(t, restored.Enabled)
+}
+
+func TestShareInfoArchiveNeverExpires(t *testing.T) {
+	share := &share_model.FileShare{ShareID: primitive.NewObjectID()}
+
+	restored, err := reshape.ShareInfoArchiveToShare(reshape.ShareToShareInfoArchive(context.Background(), share))
+	require.NoError(t, err)
+	assert.True(t, restored.Expires.IsZero())
+}
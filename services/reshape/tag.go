@@ -0,0 +1,41 @@
+package reshape
+
+import (
+	"time"
+
+	tag_model "github.com/ethanrous/weblens/models/tag"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlstructs"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TagToTagInfo converts a Tag model to a TagInfo transfer object.
+func TagToTagInfo(t *tag_model.Tag) wlstructs.TagInfo {
+	return wlstructs.TagInfo{
+		ID:      t.TagID.Hex(),
+		Name:    t.Name,
+		Color:   t.Color,
+		Owner:   t.Owner,
+		FileIDs: t.FileIDs,
+		Created: t.Created.UnixMilli(),
+		Updated: t.Updated.UnixMilli(),
+	}
+}
+
+// TagInfoToTag converts a TagInfo transfer object to a Tag model.
+func TagInfoToTag(info wlstructs.TagInfo) (*tag_model.Tag, error) {
+	tagID, err := primitive.ObjectIDFromHex(info.ID)
+	if err != nil {
+		return nil, wlerrors.Wrapf(err, "invalid tag id [%s]", info.ID)
+	}
+
+	return &tag_model.Tag{
+		TagID:   tagID,
+		Name:    info.Name,
+		Color:   info.Color,
+		Owner:   info.Owner,
+		FileIDs: info.FileIDs,
+		Created: time.UnixMilli(info.Created),
+		Updated: time.UnixMilli(info.Updated),
+	}, nil
+}
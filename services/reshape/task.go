@@ -65,3 +65,35 @@ func TaskToTaskInfo(t *task.Task) wlstructs.TaskInfo {
 		CompletedChildTasks: completedChildTasks,
 	}
 }
+
+// TaskRecordsToTaskInfos converts a slice of saved task records to TaskInfo transfer objects.
+func TaskRecordsToTaskInfos(records []*task.Record) []wlstructs.TaskInfo {
+	taskInfos := make([]wlstructs.TaskInfo, 0, len(records))
+	for _, r := range records {
+		taskInfos = append(taskInfos, TaskRecordToTaskInfo(r))
+	}
+
+	return taskInfos
+}
+
+// TaskRecordToTaskInfo converts the saved record of a durable task to a TaskInfo transfer object.
+func TaskRecordToTaskInfo(r *task.Record) wlstructs.TaskInfo {
+	var result any
+	if res := r.GetResult(); len(res) != 0 {
+		result = res
+	}
+
+	return wlstructs.TaskInfo{
+		TaskID:     r.TaskID,
+		JobName:    r.JobName,
+		Status:     string(r.ExitStatus),
+		State:      r.State.String(),
+		Completed:  r.State == task.Exited,
+		WorkerID:   -1,
+		Result:     result,
+		Error:      r.Error,
+		StartTime:  r.StartTime,
+		QueueTime:  r.QueueTime,
+		FinishTime: r.FinishTime,
+	}
+}
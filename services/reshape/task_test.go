@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/ethanrous/weblens/models/task"
 	"github.com/ethanrous/weblens/services/reshape"
@@ -54,3 +55,28 @@ func TestTaskToTaskInfo_Error(t *testing.T) {
 	assert.Equal(t, "error", info.Status)
 	assert.Contains(t, info.Error, "boom: something broke", "the failing task's error message should be on the task info")
 }
+
+func TestTaskRecordToTaskInfo(t *testing.T) {
+	finished := time.Now()
+
+	record := &task.Record{
+		TaskID:     "record-task",
+		JobName:    "record-job",
+		Result:     `{"filesRestored":3}`,
+		Error:      "boom",
+		State:      task.Exited,
+		ExitStatus: task.TaskError,
+		FinishTime: finished,
+	}
+
+	info := reshape.TaskRecordToTaskInfo(record)
+
+	assert.Equal(t, "record-task", info.TaskID)
+	assert.Equal(t, "record-job", info.JobName)
+	assert.Equal(t, "error", info.Status)
+	assert.Equal(t, task.Exited.String(), info.State)
+	assert.True(t, info.Completed)
+	assert.Equal(t, "boom", info.Error)
+	assert.Equal(t, finished, info.FinishTime)
+	assert.Equal(t, task.Result{"filesRestored": float64(3)}, info.Result)
+}
@@ -14,6 +14,16 @@ import (
 func TokenToTokenInfo(_ context.Context, t *auth_model.Token) wlstructs.TokenInfo {
 	tokenStr := base64.StdEncoding.EncodeToString(t.Token[:])
 
+	var expires int64
+	if !t.Expires.IsZero() {
+		expires = t.Expires.UnixMilli()
+	}
+
+	scopes := make([]string, 0, len(t.Scopes))
+	for _, scope := range t.Scopes {
+		scopes = append(scopes, string(scope))
+	}
+
 	return wlstructs.TokenInfo{
 		ID:          t.ID.Hex(),
 		CreatedTime: t.CreatedTime.UnixMilli(),
@@ -23,6 +33,9 @@ func TokenToTokenInfo(_ context.Context, t *auth_model.Token) wlstructs.TokenInf
 		RemoteUsing: t.RemoteUsing,
 		CreatedBy:   t.CreatedBy,
 		Token:       tokenStr,
+		Expires:     expires,
+		Scopes:      scopes,
+		FolderID:    t.FolderID,
 	}
 }
 
@@ -39,6 +52,16 @@ func TokenInfoToToken(_ context.Context, t wlstructs.TokenInfo) (*auth_model.Tok
 
 	copy(token[:], tokenSlice)
 
+	var expires time.Time
+	if t.Expires != 0 {
+		expires = time.UnixMilli(t.Expires)
+	}
+
+	scopes := make([]auth_model.Scope, 0, len(t.Scopes))
+	for _, scope := range t.Scopes {
+		scopes = append(scopes, auth_model.Scope(scope))
+	}
+
 	return &auth_model.Token{
 		ID:          id,
 		CreatedTime: time.UnixMilli(t.CreatedTime),
@@ -48,6 +71,9 @@ func TokenInfoToToken(_ context.Context, t wlstructs.TokenInfo) (*auth_model.Tok
 		RemoteUsing: t.RemoteUsing,
 		CreatedBy:   t.CreatedBy,
 		Token:       token,
+		Expires:     expires,
+		Scopes:      scopes,
+		FolderID:    t.FolderID,
 	}, nil
 }
 
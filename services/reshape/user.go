@@ -22,6 +22,10 @@ func UserToUserInfo(ctx context.Context, u *user_model.User) wlstructs.UserInfo
 		Activated:       u.Activated,
 		IsOnline:        userIsOnline,
 		UpdatedAt:       u.UpdatedAt,
+
+		TrashRetentionDays: u.TrashRetentionDays,
+		QuotaBytes:         u.QuotaBytes,
+		TOTPEnabled:        u.HasTOTP(),
 	}
 }
 
@@ -43,8 +47,14 @@ func UserToUserInfoArchive(ctx context.Context, u *usermodel.User) wlstructs.Use
 			Activated:       u.IsActive(),
 			IsOnline:        userIsOnline,
 			UpdatedAt:       u.UpdatedAt,
+
+			TrashRetentionDays: u.TrashRetentionDays,
+			QuotaBytes:         u.QuotaBytes,
+			TOTPEnabled:        u.HasTOTP(),
 		},
-		Password: u.Password,
+		Password:      u.Password,
+		TOTPSecret:    u.TOTPSecret,
+		RecoveryCodes: u.RecoveryCodes,
 	}
 
 	return info
@@ -53,13 +63,21 @@ func UserToUserInfoArchive(ctx context.Context, u *usermodel.User) wlstructs.Use
 // UserInfoArchiveToUser converts a UserInfoArchive transfer object to a User model for restoration.
 func UserInfoArchiveToUser(uInfo wlstructs.UserInfoArchive) *usermodel.User {
 	u := &usermodel.User{
-		Username:  uInfo.Username,
-		Password:  uInfo.Password,
-		Activated: uInfo.Activated,
-		UserPerms: user_model.Permissions(uInfo.PermissionLevel),
-		HomeID:    uInfo.HomeID,
-		TrashID:   uInfo.TrashID,
-		UpdatedAt: uInfo.UpdatedAt,
+		Username:    uInfo.Username,
+		Password:    uInfo.Password,
+		DisplayName: uInfo.FullName,
+		Activated:   uInfo.Activated,
+		UserPerms:   user_model.Permissions(uInfo.PermissionLevel),
+		HomeID:      uInfo.HomeID,
+		TrashID:     uInfo.TrashID,
+		UpdatedAt:   uInfo.UpdatedAt,
+
+		TrashRetentionDays: uInfo.TrashRetentionDays,
+		QuotaBytes:         uInfo.QuotaBytes,
+
+		TOTPSecret:    uInfo.TOTPSecret,
+		TOTPEnabled:   uInfo.TOTPEnabled && uInfo.TOTPSecret != "",
+		RecoveryCodes: uInfo.RecoveryCodes,
 	}
 
 	return u
@@ -0,0 +1,69 @@
+package tower
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	tower_model "github.com/ethanrous/weblens/models/tower"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlstructs"
+)
+
+// GetBackupMetadata asks the core for everything it keeps but the file history. Only the shares, tags, covers and media
+// updated after since, in milliseconds since epoch on the core, are sent, or all of them if since is 0.
+func GetBackupMetadata(ctx context.Context, core tower_model.Instance, since int64) (*wlstructs.BackupInfo, error) {
+	query := url.Values{}
+	query.Set("since", strconv.FormatInt(since, 10))
+
+	req, err := newTowerRequest(ctx, core, http.MethodGet, "/tower/backup/metadata?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := doTowerRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	info := &wlstructs.BackupInfo{}
+
+	err = json.NewDecoder(resp.Body).Decode(info)
+	if err != nil {
+		return nil, wlerrors.WithStack(err)
+	}
+
+	return info, nil
+}
+
+// GetBackupActions asks the core for the page of its file history after cursor, of about limit actions.
+func GetBackupActions(ctx context.Context, core tower_model.Instance, cursor string, limit int) (*wlstructs.BackupActionsPage, error) {
+	query := url.Values{}
+	query.Set("cursor", cursor)
+	query.Set("limit", strconv.Itoa(limit))
+
+	req, err := newTowerRequest(ctx, core, http.MethodGet, "/tower/backup/actions?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := doTowerRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	page := &wlstructs.BackupActionsPage{}
+
+	err = json.NewDecoder(resp.Body).Decode(page)
+	if err != nil {
+		return nil, wlerrors.WithStack(err)
+	}
+
+	return page, nil
+}
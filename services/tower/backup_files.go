@@ -0,0 +1,158 @@
+package tower
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	file_model "github.com/ethanrous/weblens/models/file"
+	"github.com/ethanrous/weblens/models/history"
+	tower_model "github.com/ethanrous/weblens/models/tower"
+	"github.com/ethanrous/weblens/modules/option"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlfs"
+	"github.com/ethanrous/weblens/modules/wlstructs"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/ethanrous/weblens/services/reshape"
+)
+
+// GetBackupHistory asks the backup tower for the files that were in the folder at path at the given time, according to
+// the journal it keeps of the local tower.
+func GetBackupHistory(ctx context.Context, backup tower_model.Instance, path wlfs.Filepath, timestamp time.Time) ([]wlstructs.FileActionInfo, error) {
+	query := url.Values{}
+	query.Set("path", path.ToPortable())
+	query.Set("timestamp", strconv.FormatInt(timestamp.UnixMilli(), 10))
+
+	req, err := newTowerRequest(ctx, backup, http.MethodGet, "/tower/backup/history?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := doTowerRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	var actions []wlstructs.FileActionInfo
+
+	err = json.NewDecoder(resp.Body).Decode(&actions)
+	if err != nil {
+		return nil, wlerrors.WithStack(err)
+	}
+
+	return actions, nil
+}
+
+// OpenBackupContent opens the contents with contentID that the backup tower keeps for the local tower.
+// The caller must close the returned reader.
+func OpenBackupContent(ctx context.Context, backup tower_model.Instance, contentID string) (io.ReadCloser, error) {
+	req, err := newTowerRequest(ctx, backup, http.MethodGet, "/tower/backup/content/"+url.PathEscape(contentID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := doTowerRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// RestoreFromBackup restores the files with fileIDs, as they were in the folder at path at the given time according to
+// the backup tower, into newParent. Folders are restored along with everything that was in them at that time.
+func RestoreFromBackup(ctx context.Context, backup tower_model.Instance, path wlfs.Filepath, timestamp time.Time, fileIDs []string, newParent *file_model.WeblensFileImpl) error {
+	appCtx, ok := context_service.FromContext(ctx)
+	if !ok {
+		return wlerrors.WithStack(context_service.ErrNoContext)
+	}
+
+	if !backup.IsBackup() {
+		return wlerrors.Statusf(http.StatusBadRequest, "tower [%s] is not a backup", backup.TowerID)
+	}
+
+	children, err := getBackupPastChildren(ctx, backup, path, timestamp)
+	if err != nil {
+		return err
+	}
+
+	pastFiles := make([]*file_model.WeblensFileImpl, 0, len(fileIDs))
+
+	for _, child := range children {
+		if slices.Contains(fileIDs, child.ID()) {
+			pastFiles = append(pastFiles, child)
+		}
+	}
+
+	if len(pastFiles) != len(fileIDs) {
+		return wlerrors.Statusf(http.StatusNotFound, "%d of %d files were not in [%s] at that time", len(fileIDs)-len(pastFiles), len(fileIDs), path)
+	}
+
+	// Fill in the contents of every folder being restored, down to the bottom of the tree
+	queue := slices.Clone(pastFiles)
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+
+		if !dir.IsDir() {
+			continue
+		}
+
+		dirChildren, err := getBackupPastChildren(ctx, backup, dir.GetPortablePath(), timestamp)
+		if err != nil {
+			return err
+		}
+
+		for _, child := range dirChildren {
+			err = child.SetParent(dir)
+			if err != nil {
+				return err
+			}
+
+			err = dir.AddChild(child)
+			if err != nil {
+				return err
+			}
+		}
+
+		queue = append(queue, dirChildren...)
+	}
+
+	return appCtx.FileService.RestoreFilesFromBackup(ctx, pastFiles, newParent, func(contentID string) (io.ReadCloser, error) {
+		return OpenBackupContent(ctx, backup, contentID)
+	})
+}
+
+func getBackupPastChildren(ctx context.Context, backup tower_model.Instance, path wlfs.Filepath, timestamp time.Time) ([]*file_model.WeblensFileImpl, error) {
+	actionInfos, err := GetBackupHistory(ctx, backup, path, timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make([]*file_model.WeblensFileImpl, 0, len(actionInfos))
+
+	for _, info := range actionInfos {
+		action := reshape.FileActionInfoToFileAction(info)
+		if action.ActionType == history.FileDelete {
+			continue
+		}
+
+		children = append(children, file_model.NewWeblensFile(file_model.NewFileOptions{
+			Path:         action.GetRelevantPath(),
+			FileID:       action.FileID,
+			IsPastFile:   true,
+			Size:         action.Size,
+			ContentID:    action.ContentID,
+			ModifiedDate: option.Of(action.Timestamp),
+		}))
+	}
+
+	return children, nil
+}
@@ -0,0 +1,106 @@
+package tower
+
+import (
+	"context"
+	"time"
+
+	token_model "github.com/ethanrous/weblens/models/auth"
+	cover_model "github.com/ethanrous/weblens/models/cover"
+	media_model "github.com/ethanrous/weblens/models/media"
+	share_model "github.com/ethanrous/weblens/models/share"
+	tag_model "github.com/ethanrous/weblens/models/tag"
+	tower_model "github.com/ethanrous/weblens/models/tower"
+	user_model "github.com/ethanrous/weblens/models/usermodel"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlstructs"
+	"github.com/ethanrous/weblens/services/reshape"
+)
+
+// CollectBackupMetadata gathers everything a backup tower keeps for the local tower with localTowerID, other than the
+// file history. Users, towers and API tokens are always sent in full. Shares, tags, covers and media are only sent if
+// they were updated after since, or all of them if since is zero, but the keys of all of them are sent either way.
+func CollectBackupMetadata(ctx context.Context, localTowerID string, since time.Time) (wlstructs.BackupInfo, error) {
+	// Read before anything else, so changes made while collecting are sent again next time rather than missed
+	now := time.Now()
+
+	users, err := user_model.GetAllUsers(ctx)
+	if err != nil {
+		return wlstructs.BackupInfo{}, wlerrors.Wrap(err, "failed to get users")
+	}
+
+	towers, err := tower_model.GetAllTowersByTowerID(ctx, localTowerID)
+	if err != nil {
+		return wlstructs.BackupInfo{}, wlerrors.Wrap(err, "failed to get towers")
+	}
+
+	tokens, err := token_model.GetAllTokensByTowerID(ctx, localTowerID)
+	if err != nil {
+		return wlstructs.BackupInfo{}, wlerrors.Wrap(err, "failed to get tokens")
+	}
+
+	info := reshape.NewBackupInfo(ctx, nil, users, towers, tokens)
+	info.Timestamp = now.UnixMilli()
+	info.Keys = &wlstructs.BackupKeys{}
+
+	shares, err := share_model.GetSharesUpdatedSince(ctx, since)
+	if err != nil {
+		return wlstructs.BackupInfo{}, err
+	}
+
+	info.Shares = make([]wlstructs.ShareInfoArchive, 0, len(shares))
+	for _, s := range shares {
+		info.Shares = append(info.Shares, reshape.ShareToShareInfoArchive(ctx, s))
+	}
+
+	info.Keys.ShareIDs, err = share_model.GetAllShareIDs(ctx)
+	if err != nil {
+		return wlstructs.BackupInfo{}, err
+	}
+
+	tags, err := tag_model.GetTagsUpdatedSince(ctx, since)
+	if err != nil {
+		return wlstructs.BackupInfo{}, err
+	}
+
+	info.Tags = make([]wlstructs.TagInfo, 0, len(tags))
+	for _, t := range tags {
+		info.Tags = append(info.Tags, reshape.TagToTagInfo(t))
+	}
+
+	info.Keys.TagIDs, err = tag_model.GetAllTagIDs(ctx)
+	if err != nil {
+		return wlstructs.BackupInfo{}, err
+	}
+
+	covers, err := cover_model.GetCoversUpdatedSince(ctx, since)
+	if err != nil {
+		return wlstructs.BackupInfo{}, err
+	}
+
+	info.Covers = make([]wlstructs.CoverInfo, 0, len(covers))
+	for _, c := range covers {
+		info.Covers = append(info.Covers, reshape.CoverToCoverInfo(c))
+	}
+
+	info.Keys.CoverFolderIDs, err = cover_model.GetAllCoverFolderIDs(ctx)
+	if err != nil {
+		return wlstructs.BackupInfo{}, err
+	}
+
+	medias, err := media_model.GetMediaUpdatedSince(ctx, since)
+	if err != nil {
+		return wlstructs.BackupInfo{}, err
+	}
+
+	info.Media = make([]wlstructs.MediaInfo, 0, len(medias))
+	for _, m := range medias {
+		info.Media = append(info.Media, reshape.MediaToMediaInfo(m))
+	}
+
+	info.Keys.MediaContentIDs, err = media_model.GetAllContentIDs(ctx)
+	if err != nil {
+		return wlstructs.BackupInfo{}, err
+	}
+
+	return info, nil
+}
@@ -5,15 +5,11 @@ import (
 	"context"
 	"fmt"
 	"net/url"
-	"strconv"
-	"time"
 
 	api "github.com/ethanrous/weblens/api"
 	tower_model "github.com/ethanrous/weblens/models/tower"
-	"github.com/ethanrous/weblens/modules/netwrk"
 	context_mod "github.com/ethanrous/weblens/modules/wlcontext"
 	"github.com/ethanrous/weblens/modules/wlerrors"
-	"github.com/ethanrous/weblens/modules/wlstructs"
 	context_service "github.com/ethanrous/weblens/services/ctxservice"
 )
 
@@ -70,119 +66,6 @@ func Ping(ctx context.Context, tower tower_model.Instance) (*api.TowerInfo, erro
 	return towerInfo, nil
 }
 
-// GetBackup retrieves backup information from a tower for all changes since the specified time.
-func GetBackup(ctx context.Context, tower tower_model.Instance, since time.Time) (*wlstructs.BackupInfo, error) {
-	client, err := getAPIClient(ctx, tower, clientOpts{})
-	if err != nil {
-		return nil, err
-	}
-
-	apiBackupInfo, resp, err := client.TowersAPI.GetBackupInfo(ctx).Timestamp(strconv.FormatInt(since.UnixMilli(), 10)).Execute()
-	if err != nil {
-		return nil, netwrk.ReadError(ctx, resp, wlerrors.WithStack(err))
-	}
-
-	// Convert API backup info to internal BackupInfo struct
-	backupInfo := &wlstructs.BackupInfo{}
-
-	// Convert FileHistory
-	backupInfo.FileHistory = make([]wlstructs.FileActionInfo, len(apiBackupInfo.FileHistory))
-	for i, apiAction := range apiBackupInfo.FileHistory {
-		backupInfo.FileHistory[i] = wlstructs.FileActionInfo{
-			FileID:     apiAction.FileID,
-			ActionType: apiAction.ActionType,
-			EventID:    apiAction.EventID,
-			TowerID:    apiAction.TowerID,
-			Timestamp:  apiAction.Timestamp,
-			Size:       apiAction.Size,
-		}
-		// Handle optional pointer fields
-		if apiAction.Filepath != nil {
-			backupInfo.FileHistory[i].Filepath = *apiAction.Filepath
-		}
-
-		if apiAction.OriginPath != nil {
-			backupInfo.FileHistory[i].OriginPath = *apiAction.OriginPath
-		}
-
-		if apiAction.DestinationPath != nil {
-			backupInfo.FileHistory[i].DestinationPath = *apiAction.DestinationPath
-		}
-
-		if apiAction.ContentID != nil {
-			backupInfo.FileHistory[i].ContentID = *apiAction.ContentID
-		}
-	}
-
-	// Convert Users
-	backupInfo.Users = make([]wlstructs.UserInfoArchive, len(apiBackupInfo.Users))
-
-	for i, apiUser := range apiBackupInfo.Users {
-		isOnline := false
-		if apiUser.IsOnline != nil {
-			isOnline = *apiUser.IsOnline
-		}
-
-		token := ""
-		if apiUser.Token != nil {
-			token = *apiUser.Token
-		}
-
-		password := ""
-		if apiUser.Password != nil {
-			password = *apiUser.Password
-		}
-
-		backupInfo.Users[i] = wlstructs.UserInfoArchive{
-			UserInfo: wlstructs.UserInfo{
-				Activated:       apiUser.Activated,
-				FullName:        apiUser.FullName,
-				HomeID:          apiUser.HomeID,
-				PermissionLevel: int(apiUser.PermissionLevel),
-				Token:           token,
-				TrashID:         apiUser.TrashID,
-				Username:        apiUser.Username,
-				IsOnline:        isOnline,
-			},
-			Password: password,
-		}
-	}
-
-	// Convert Instances (towers)
-	backupInfo.Instances = make([]wlstructs.TowerInfo, len(apiBackupInfo.Instances))
-	for i, apiTower := range apiBackupInfo.Instances {
-		backupInfo.Instances[i] = wlstructs.TowerInfo{
-			ID:      apiTower.Id,
-			Name:    apiTower.Name,
-			Role:    apiTower.Role,
-			Address: apiTower.CoreAddress,
-			// UsingKey is not available from the API response, will need to be set separately if needed
-		}
-	}
-
-	// Convert Tokens
-	backupInfo.Tokens = make([]wlstructs.TokenInfo, len(apiBackupInfo.Tokens))
-	for i, apiToken := range apiBackupInfo.Tokens {
-		backupInfo.Tokens[i] = wlstructs.TokenInfo{
-			ID:          apiToken.Id,
-			CreatedTime: apiToken.CreatedTime,
-			LastUsed:    apiToken.LastUsed,
-			Nickname:    apiToken.Nickname,
-			Owner:       apiToken.Owner,
-			RemoteUsing: apiToken.RemoteUsing,
-			CreatedBy:   apiToken.CreatedBy,
-			Token:       apiToken.Token,
-		}
-	}
-
-	// Convert LifetimesCount
-	if apiBackupInfo.LifetimesCount != nil {
-		backupInfo.LifetimesCount = int(*apiBackupInfo.LifetimesCount)
-	}
-
-	return backupInfo, nil
-}
-
 // AttachToCore registers this tower as a remote with a core tower.
 func AttachToCore(ctx context.Context, core tower_model.Instance) error {
 	client, err := getAPIClient(ctx, core, clientOpts{noTowerIDHeader: true})
@@ -0,0 +1,51 @@
+package tower
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	tower_model "github.com/ethanrous/weblens/models/tower"
+	"github.com/ethanrous/weblens/modules/netwrk"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+)
+
+// newTowerRequest builds a request to the API of tower, authenticated with the key we use for it. The generated API
+// client is not used here, since it cannot stream request or response bodies.
+func newTowerRequest(ctx context.Context, tower tower_model.Instance, method, path string, body io.Reader) (*http.Request, error) {
+	if tower.Address == "" {
+		return nil, wlerrors.New("tower address is empty")
+	}
+
+	appCtx, ok := context_service.FromContext(ctx)
+	if !ok {
+		return nil, wlerrors.WithStack(context_service.ErrNoContext)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(tower.Address, "/")+"/api/v1"+path, body)
+	if err != nil {
+		return nil, wlerrors.WithStack(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+tower.OutgoingKey)
+	req.Header.Set(TowerIDHeader, appCtx.LocalTowerID)
+
+	return req, nil
+}
+
+// doTowerRequest sends req, and returns the response if it was successful. The caller must close the response body.
+func doTowerRequest(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, wlerrors.WithStack(err)
+	}
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, netwrk.ReadError(req.Context(), resp, wlerrors.Errorf("request to [%s] failed with status %s", req.URL.Path, strconv.Itoa(resp.StatusCode)))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,376 @@
+package tower
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	token_model "github.com/ethanrous/weblens/models/auth"
+	cover_model "github.com/ethanrous/weblens/models/cover"
+	"github.com/ethanrous/weblens/models/db"
+	"github.com/ethanrous/weblens/models/history"
+	media_model "github.com/ethanrous/weblens/models/media"
+	share_model "github.com/ethanrous/weblens/models/share"
+	tag_model "github.com/ethanrous/weblens/models/tag"
+	tower_model "github.com/ethanrous/weblens/models/tower"
+	user_model "github.com/ethanrous/weblens/models/usermodel"
+	"github.com/ethanrous/weblens/modules/config"
+	"github.com/ethanrous/weblens/modules/startup"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlstructs"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/ethanrous/weblens/services/reshape"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNotRestoring is returned when restore data is sent to a tower that is not being restored.
+var ErrNotRestoring = wlerrors.Statusf(http.StatusConflict, "tower is not being restored")
+
+// ErrRestoreMissingOwner is returned when a restore is completed without the server owner having been restored.
+var ErrRestoreMissingOwner = wlerrors.Statusf(http.StatusConflict, "restore did not include the server owner")
+
+// RestoreArchive writes the journal, users, API tokens, tower records, shares, tags, covers and media from a backup of a
+// core into the local tower.
+// Records that already exist are kept, and the journal is replaced as a whole, so the same archive can be sent again
+// when resuming an interrupted restore.
+func RestoreArchive(ctx context.Context, archive wlstructs.BackupInfo) error {
+	local, err := getRestoringLocal(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.WithTransaction(ctx, func(ctx context.Context) error {
+		for _, userInfo := range archive.Users {
+			// System users are archived as empty records
+			if userInfo.Username == "" {
+				continue
+			}
+
+			_, err := user_model.GetUserByUsername(ctx, userInfo.Username)
+			if err == nil {
+				continue
+			} else if !db.IsNotFound(err) {
+				return err
+			}
+
+			u := reshape.UserInfoArchiveToUser(userInfo)
+			u.CreatedBy = local.TowerID
+
+			err = user_model.RestoreUser(ctx, u)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, tokenInfo := range archive.Tokens {
+			token, err := reshape.TokenInfoToToken(ctx, tokenInfo)
+			if err != nil {
+				return err
+			}
+
+			if token.ID.IsZero() {
+				token.ID = primitive.NewObjectID()
+			} else if _, err = token_model.GetTokenByID(ctx, token.ID); err == nil {
+				continue
+			} else if !wlerrors.Is(err, token_model.ErrTokenNotFound) {
+				return err
+			}
+
+			err = token_model.SaveToken(ctx, token)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, towerInfo := range archive.Instances {
+			if towerInfo.ID == local.TowerID {
+				continue
+			}
+
+			existing, err := tower_model.GetTowerByID(ctx, towerInfo.ID)
+			if err == nil {
+				// Keep the keys of towers we already know, such as the backup performing the restore
+				existing.Name = towerInfo.Name
+
+				err = tower_model.SaveTower(ctx, &existing)
+				if err != nil {
+					return err
+				}
+
+				continue
+			} else if !wlerrors.Is(err, tower_model.ErrTowerNotFound) {
+				return err
+			}
+
+			instance := reshape.TowerInfoToTower(towerInfo)
+			instance.IsThisTower = false
+			instance.CreatedBy = local.TowerID
+
+			err = tower_model.SaveTower(ctx, instance)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := restoreOrganization(ctx, archive)
+		if err != nil {
+			return err
+		}
+
+		actions := make([]history.FileAction, 0, len(archive.FileHistory))
+		for _, actionInfo := range archive.FileHistory {
+			action := reshape.FileActionInfoToFileAction(actionInfo)
+			action.TowerID = local.TowerID
+			actions = append(actions, action)
+		}
+
+		err = history.DeleteActionsByTowerID(ctx, local.TowerID)
+		if err != nil {
+			return err
+		}
+
+		return history.SaveActions(ctx, actions)
+	})
+}
+
+// restoreOrganization writes the shares, tags, covers and media from a backup of a core into the local tower. Like the
+// rest of the archive, records that already exist are kept.
+func restoreOrganization(ctx context.Context, archive wlstructs.BackupInfo) error {
+	for _, shareInfo := range archive.Shares {
+		share, err := reshape.ShareInfoArchiveToShare(shareInfo)
+		if err != nil {
+			return err
+		}
+
+		_, err = share_model.GetShareByID(ctx, share.ShareID)
+		if err == nil {
+			continue
+		} else if !db.IsNotFound(err) {
+			return err
+		}
+
+		err = share_model.SaveFileShare(ctx, share)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, tagInfo := range archive.Tags {
+		tag, err := reshape.TagInfoToTag(tagInfo)
+		if err != nil {
+			return err
+		}
+
+		_, err = tag_model.GetTagByID(ctx, tag.TagID)
+		if err == nil {
+			continue
+		} else if !db.IsNotFound(err) {
+			return err
+		}
+
+		err = tag_model.SaveTag(ctx, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, coverInfo := range archive.Covers {
+		err := cover_model.UpsertCoverByFolderID(ctx, coverInfo.FolderID, coverInfo.CoverPhotoID)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, mediaInfo := range archive.Media {
+		_, err := media_model.GetMediaByContentID(ctx, mediaInfo.ContentID)
+		if err == nil {
+			continue
+		} else if !db.IsNotFound(err) {
+			return err
+		}
+
+		m := reshape.MediaInfoToMedia(mediaInfo)
+
+		// The media is left without files, so the first scan once the restore is complete finds each file again and
+		// rebuilds the thumbnails, which are not backed up. Until then, media without files are not shown.
+		m.FileIDs = []string{}
+
+		err = media_model.SaveMedia(ctx, m)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RestoreFile writes size bytes read from contents to the path the file with fileID was last at in the restored journal.
+// If a file of the same size is already there, left by an earlier attempt at the restore, contents is not read at all.
+func RestoreFile(ctx context.Context, fileID string, contents io.Reader, size int64) error {
+	_, err := getRestoringLocal(ctx)
+	if err != nil {
+		return err
+	}
+
+	action, err := history.GetLastActionByFileIDBefore(ctx, fileID, time.Now())
+	if db.IsNotFound(err) {
+		return wlerrors.Statusf(http.StatusNotFound, "file [%s] is not in the restored journal", fileID)
+	} else if err != nil {
+		return err
+	}
+
+	path := action.GetRelevantPath()
+
+	if action.ActionType == history.FileDelete {
+		return wlerrors.Statusf(http.StatusBadRequest, "file [%s] was deleted", fileID)
+	} else if path.IsDir() {
+		return wlerrors.Statusf(http.StatusBadRequest, "file [%s] is a folder", fileID)
+	}
+
+	absPath := path.ToAbsolute()
+
+	if stat, err := os.Stat(absPath); err == nil && stat.Size() == size {
+		return nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(absPath), 0o755)
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	// Written in place, a file cut short by an interrupted restore will have the wrong size and be written again
+	out, err := os.Create(absPath)
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	written, err := io.Copy(out, io.LimitReader(contents, size))
+	if err != nil {
+		_ = out.Close()
+
+		return wlerrors.WithStack(err)
+	}
+
+	err = out.Close()
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	if written != size {
+		return wlerrors.Statusf(http.StatusBadRequest, "received %d of %d bytes for file [%s]", written, size, fileID)
+	}
+
+	return nil
+}
+
+// CompleteRestore finishes restoring the local tower. The folders in the restored journal are created, the backup tower
+// with backupTowerID is given an API token for the key it already uses, and the tower is started as a core.
+func CompleteRestore(ctx context.Context, backupTowerID string, cnf config.Provider) error {
+	appCtx, ok := context_service.FromContext(ctx)
+	if !ok {
+		return wlerrors.WithStack(context_service.ErrNoContext)
+	}
+
+	local, err := getRestoringLocal(ctx)
+	if err != nil {
+		return err
+	}
+
+	owner, err := user_model.GetServerOwner(ctx)
+	if db.IsNotFound(err) {
+		return wlerrors.WithStack(ErrRestoreMissingOwner)
+	} else if err != nil {
+		return err
+	}
+
+	lifetimes, err := history.GetLifetimes(ctx, history.GetLifetimesOptions{ActiveOnly: true, TowerID: local.TowerID})
+	if err != nil {
+		return err
+	}
+
+	// Empty folders have no file contents sent to create them, so every folder is created here
+	for _, lt := range lifetimes {
+		latest := lt.Actions[len(lt.Actions)-1]
+		if latest.ActionType == history.FileDelete || !latest.GetRelevantPath().IsDir() {
+			continue
+		}
+
+		err = os.MkdirAll(latest.GetRelevantPath().ToAbsolute(), 0o755)
+		if err != nil {
+			return wlerrors.WithStack(err)
+		}
+	}
+
+	err = restoreBackupToken(ctx, local, backupTowerID, owner.Username)
+	if err != nil {
+		return err
+	}
+
+	local.Role = tower_model.RoleCore
+
+	err = tower_model.UpdateTower(ctx, &local)
+	if err != nil {
+		return err
+	}
+
+	appCtx.ClearCache()
+
+	cnf.InitRole = string(tower_model.RoleCore)
+
+	return startup.RunStartups(appCtx, cnf)
+}
+
+// restoreBackupToken makes sure the key the backup tower authenticates with is a valid API token once the restore is
+// complete. Tokens are only archived by the backup if they were sent to it, so the key may not have been restored.
+func restoreBackupToken(ctx context.Context, local tower_model.Instance, backupTowerID, owner string) error {
+	backup, err := tower_model.GetTowerByID(ctx, backupTowerID)
+	if err != nil {
+		return err
+	}
+
+	keyBytes, err := base64.StdEncoding.DecodeString(backup.IncomingKey)
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	var key [32]byte
+
+	copy(key[:], keyBytes)
+
+	_, err = token_model.GetToken(ctx, key)
+	if err == nil {
+		return nil
+	} else if !db.IsNotFound(err) {
+		return err
+	}
+
+	now := time.Now()
+
+	return token_model.SaveToken(ctx, &token_model.Token{
+		ID:          primitive.NewObjectID(),
+		CreatedTime: now,
+		LastUsed:    now,
+		Nickname:    "Restored backup key",
+		Owner:       owner,
+		RemoteUsing: backup.TowerID,
+		CreatedBy:   local.TowerID,
+		Token:       key,
+	})
+}
+
+func getRestoringLocal(ctx context.Context) (tower_model.Instance, error) {
+	local, err := tower_model.GetLocal(ctx)
+	if err != nil {
+		return local, err
+	}
+
+	if local.Role != tower_model.RoleRestore {
+		return local, wlerrors.WithStack(ErrNotRestoring)
+	}
+
+	return local, nil
+}
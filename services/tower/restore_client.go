@@ -0,0 +1,106 @@
+package tower
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	tower_model "github.com/ethanrous/weblens/models/tower"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/modules/wlstructs"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+)
+
+// StartCoreRestore prepares the tower at core's address to be restored as core. A tower which is already being restored
+// as core, from an earlier attempt that was interrupted, is left as it is so the restore can pick up where it stopped.
+func StartCoreRestore(ctx context.Context, core tower_model.Instance) error {
+	appCtx, ok := context_service.FromContext(ctx)
+	if !ok {
+		return wlerrors.WithStack(context_service.ErrNoContext)
+	}
+
+	towerInfo, err := Ping(ctx, core)
+	if err != nil {
+		return err
+	}
+
+	switch tower_model.Role(towerInfo.GetRole()) {
+	case tower_model.RoleUninitialized:
+	case tower_model.RoleRestore:
+		if towerInfo.GetId() == core.TowerID {
+			return nil
+		}
+
+		fallthrough
+	default:
+		return wlerrors.Errorf("tower at [%s] is already initialized as [%s]", core.Address, towerInfo.GetRole())
+	}
+
+	return sendRestoreRequest(ctx, core, "/tower/init", wlstructs.InitServerParams{
+		Name:     core.Name,
+		Role:     string(tower_model.RoleRestore),
+		CoreKey:  core.OutgoingKey,
+		RemoteID: appCtx.LocalTowerID,
+		LocalID:  core.TowerID,
+	})
+}
+
+// SendRestoreArchive sends the journal, users, API tokens and tower records of core to the tower being restored.
+func SendRestoreArchive(ctx context.Context, core tower_model.Instance, archive wlstructs.BackupInfo) error {
+	return sendRestoreRequest(ctx, core, "/tower/restore", archive)
+}
+
+// SendRestoreFile sends size bytes read from contents to the tower being restored, as the contents of fileID.
+func SendRestoreFile(ctx context.Context, core tower_model.Instance, fileID string, contents io.Reader, size int64) error {
+	req, err := newTowerRequest(ctx, core, http.MethodPost, "/tower/restore/files/"+fileID, contents)
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	// The core replies without reading the body if it already has the file, so it is not sent again when resuming
+	if size > 0 {
+		req.Header.Set("Expect", "100-continue")
+	}
+
+	return doRestoreRequest(req)
+}
+
+// SendRestoreComplete tells the tower being restored that everything has been sent, and it can start as core.
+func SendRestoreComplete(ctx context.Context, core tower_model.Instance) error {
+	req, err := newTowerRequest(ctx, core, http.MethodPost, "/tower/restore/complete", nil)
+	if err != nil {
+		return err
+	}
+
+	return doRestoreRequest(req)
+}
+
+func sendRestoreRequest(ctx context.Context, core tower_model.Instance, path string, body any) error {
+	bs, err := json.Marshal(body)
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	req, err := newTowerRequest(ctx, core, http.MethodPost, path, bytes.NewReader(bs))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	return doRestoreRequest(req)
+}
+
+func doRestoreRequest(req *http.Request) error {
+	resp, err := doTowerRequest(req)
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
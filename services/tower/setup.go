@@ -266,3 +266,59 @@ func InitializeBackupServer(ctx context.Context, initBody wlstructs.InitServerPa
 
 	return nil
 }
+
+// InitializeRestoreServer prepares a freshly started tower to be restored from a backup of a core. The tower takes back
+// the ID the core had before, and will only accept restore requests from the backup tower until the restore is complete.
+func InitializeRestoreServer(ctx context.Context, initBody wlstructs.InitServerParams, cnf config.Provider) error {
+	if initBody.LocalID == "" || initBody.RemoteID == "" || initBody.CoreKey == "" {
+		return wlerrors.Statusf(http.StatusBadRequest, "missing required fields for restore server initialization")
+	}
+
+	appCtx, ok := context_service.FromContext(ctx)
+	if !ok {
+		return wlerrors.WithStack(context_service.ErrNoContext)
+	}
+
+	wlog.FromContext(ctx).Info().Msgf("Initializing server as RESTORE of core [%s] from backup [%s]", initBody.LocalID, initBody.RemoteID)
+
+	local, err := tower_model.GetLocal(ctx)
+	if err != nil {
+		return err
+	}
+
+	local.TowerID = initBody.LocalID
+	local.Role = tower_model.RoleRestore
+	local.Name = initBody.Name
+
+	err = tower_model.UpdateTower(ctx, &local)
+	if err != nil {
+		return err
+	}
+
+	appCtx = appCtx.SetLocalTowerID(local.TowerID)
+
+	// The backup may already be known if an earlier attempt at the restore was interrupted
+	backup, err := tower_model.GetTowerByID(ctx, initBody.RemoteID)
+	if err != nil && !wlerrors.Is(err, tower_model.ErrTowerNotFound) {
+		return err
+	}
+
+	if backup.Name == "" {
+		// The real name arrives with the rest of the tower records later in the restore
+		backup.Name = initBody.RemoteID
+	}
+
+	backup.TowerID = initBody.RemoteID
+	backup.Role = tower_model.RoleBackup
+	backup.IncomingKey = initBody.CoreKey
+	backup.CreatedBy = local.TowerID
+
+	err = tower_model.SaveTower(ctx, &backup)
+	if err != nil {
+		return err
+	}
+
+	cnf.InitRole = string(tower_model.RoleRestore)
+
+	return startup.RunStartups(appCtx, cnf)
+}
@@ -73,3 +73,37 @@ func TestInitializeCoreServer_Validation(t *testing.T) {
 		assert.Contains(t, err.Error(), "missing required fields")
 	})
 }
+
+func TestInitializeRestoreServer_Validation(t *testing.T) {
+	t.Run("returns error when backup tower is missing", func(t *testing.T) {
+		logger := wlog.NewZeroLogger()
+		basicCtx := ctxservice.NewBasicContext(context.Background(), logger)
+		appCtx := ctxservice.NewAppContext(basicCtx)
+
+		params := wlstructs.InitServerParams{
+			Name:    "Restored Core",
+			LocalID: "core-id",
+			CoreKey: "key",
+		}
+
+		err := tower.InitializeRestoreServer(appCtx, params, config.Provider{})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "missing required fields")
+	})
+
+	t.Run("returns error when key is missing", func(t *testing.T) {
+		logger := wlog.NewZeroLogger()
+		basicCtx := ctxservice.NewBasicContext(context.Background(), logger)
+		appCtx := ctxservice.NewAppContext(basicCtx)
+
+		params := wlstructs.InitServerParams{
+			Name:     "Restored Core",
+			LocalID:  "core-id",
+			RemoteID: "backup-id",
+		}
+
+		err := tower.InitializeRestoreServer(appCtx, params, config.Provider{})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "missing required fields")
+	})
+}
@@ -0,0 +1,58 @@
+package tus
+
+import (
+	"context"
+	"time"
+
+	tus_model "github.com/ethanrous/weblens/models/tus"
+	"github.com/ethanrous/weblens/modules/config"
+	"github.com/ethanrous/weblens/modules/startup"
+	"github.com/ethanrous/weblens/modules/wlog"
+)
+
+// staleUploadInterval is how often abandoned uploads are looked for and removed.
+const staleUploadInterval = time.Hour
+
+func init() {
+	startup.RegisterHook(func(ctx context.Context, _ config.Provider) error {
+		go staleUploadLoop(ctx, staleUploadInterval)
+
+		return nil
+	})
+}
+
+func staleUploadLoop(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			err := RemoveStaleUploads(ctx)
+			if err != nil {
+				wlog.FromContext(ctx).Error().Stack().Err(err).Msg("Failed to remove stale uploads")
+			}
+		}
+	}
+}
+
+// RemoveStaleUploads terminates every upload that has not received any data within tus_model.UploadLifetime.
+func RemoveStaleUploads(ctx context.Context) error {
+	uploads, err := tus_model.GetStaleUploads(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, upload := range uploads {
+		err = TerminateUpload(ctx, upload)
+		if err != nil {
+			return err
+		}
+
+		wlog.FromContext(ctx).Debug().Msgf("Removed stale upload [%s] of [%s]", upload.ID.Hex(), upload.FileName)
+	}
+
+	return nil
+}
@@ -0,0 +1,356 @@
+// Package tus implements resumable uploads using the tus protocol (https://tus.io/protocols/resumable-upload).
+package tus
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	file_model "github.com/ethanrous/weblens/models/file"
+	"github.com/ethanrous/weblens/models/history"
+	"github.com/ethanrous/weblens/models/job"
+	media_model "github.com/ethanrous/weblens/models/media"
+	tus_model "github.com/ethanrous/weblens/models/tus"
+	"github.com/ethanrous/weblens/modules/cryptography"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/ethanrous/weblens/services/embed"
+	file_service "github.com/ethanrous/weblens/services/file"
+)
+
+// Version is the version of the tus protocol implemented by this package.
+const Version = "1.0.0"
+
+// Extensions is the list of tus protocol extensions supported by this package.
+const Extensions = "creation,creation-with-upload,termination,expiration"
+
+// MaxSize is the largest upload, in bytes, that may be created.
+const MaxSize int64 = 1 << 40
+
+// ErrOffsetMismatch is returned when a chunk does not start where the previous one left off.
+var ErrOffsetMismatch = wlerrors.Statusf(http.StatusConflict, "upload offset does not match the number of bytes received")
+
+// ErrUploadTooLarge is returned when a chunk would write past the declared size of the upload.
+var ErrUploadTooLarge = wlerrors.Statusf(http.StatusRequestEntityTooLarge, "chunk exceeds the declared upload length")
+
+// ErrUploadLocked is returned when a chunk is sent for an upload which is already receiving another chunk.
+var ErrUploadLocked = wlerrors.Statusf(http.StatusLocked, "upload is already receiving data")
+
+// ErrFileExists is returned when an upload would overwrite an existing file.
+var ErrFileExists = wlerrors.Statusf(http.StatusConflict, "file with the same name already exists in folder")
+
+// Uploads being written to are locked so that two requests can never write to the same partial file at once.
+var (
+	activeUploads   = map[string]struct{}{}
+	activeUploadsMu sync.Mutex
+)
+
+// PartialPath returns the absolute path of the file holding the bytes received so far for upload.
+func PartialPath(upload *tus_model.Upload) string {
+	return file_model.UploadsDirPath.Child(upload.ID.Hex(), false).ToAbsolute()
+}
+
+// CreateUpload creates a new resumable upload of size bytes, which will become fileName inside parent once complete.
+// shareID is the share the upload is made through, if any.
+func CreateUpload(ctx context.Context, owner, shareID string, parent *file_model.WeblensFileImpl, fileName string, size int64, metadata map[string]string) (*tus_model.Upload, error) {
+	if !parent.IsDir() {
+		return nil, wlerrors.Statusf(http.StatusBadRequest, "upload parent must be a folder")
+	}
+
+	// Checked now, rather than once every byte has been received
+	if err := cryptography.ValidateFilename(fileName); err != nil {
+		return nil, wlerrors.WrapStatus(http.StatusBadRequest, err)
+	}
+
+	if size < 0 || size > MaxSize {
+		return nil, wlerrors.Statusf(http.StatusRequestEntityTooLarge, "upload length must be between 0 and %d bytes", MaxSize)
+	}
+
+	if child, _ := parent.GetChild(fileName); child != nil {
+		return nil, wlerrors.WithStack(ErrFileExists)
+	}
+
+	upload := tus_model.NewUpload(owner, parent.ID(), fileName, size, metadata)
+	upload.ShareID = shareID
+
+	// Reserved now, so concurrent uploads can not together go past the quota of the owner of parent
+	err := file_service.ReserveQuota(ctx, parent, upload.ID.Hex(), size, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return createPartial(ctx, upload)
+}
+
+// CreateRevisionUpload creates a new resumable upload of size bytes, which will replace the content of file once
+// complete. The content it replaces is kept as a past revision of the file. shareID is the share the upload is made
+// through, if any.
+func CreateRevisionUpload(ctx context.Context, owner, shareID string, file *file_model.WeblensFileImpl, size int64, metadata map[string]string) (*tus_model.Upload, error) {
+	if file.IsDir() {
+		return nil, wlerrors.Statusf(http.StatusBadRequest, "cannot upload new content to a folder")
+	}
+
+	if size < 0 || size > MaxSize {
+		return nil, wlerrors.Statusf(http.StatusRequestEntityTooLarge, "upload length must be between 0 and %d bytes", MaxSize)
+	}
+
+	upload := tus_model.NewUpload(owner, file.GetParent().ID(), file.GetPortablePath().Filename(), size, metadata)
+	upload.FileID = file.ID()
+	upload.ShareID = shareID
+
+	err := file_service.ReserveQuota(ctx, file.GetParent(), upload.ID.Hex(), size, file)
+	if err != nil {
+		return nil, err
+	}
+
+	return createPartial(ctx, upload)
+}
+
+// createPartial creates the empty partial file for upload, and saves it. The quota reserved for upload is released if
+// it can not be created.
+func createPartial(ctx context.Context, upload *tus_model.Upload) (*tus_model.Upload, error) {
+	partial, err := os.Create(PartialPath(upload))
+	if err != nil {
+		file_service.ReleaseQuota(upload.ID.Hex())
+
+		return nil, wlerrors.WithStack(err)
+	}
+
+	err = partial.Close()
+	if err != nil {
+		file_service.ReleaseQuota(upload.ID.Hex())
+
+		return nil, wlerrors.WithStack(err)
+	}
+
+	err = tus_model.SaveUpload(ctx, upload)
+	if err != nil {
+		_ = os.Remove(PartialPath(upload))
+
+		file_service.ReleaseQuota(upload.ID.Hex())
+
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+// reserveQuota reserves the space upload will take once complete in the quota of the owner of its destination. This
+// is done again for every chunk, as reservations are not kept across restarts of the server.
+func reserveQuota(ctx context.Context, upload *tus_model.Upload) error {
+	appCtx, ok := context_service.FromContext(ctx)
+	if !ok {
+		return wlerrors.WithStack(context_service.ErrNoContext)
+	}
+
+	parent, err := appCtx.FileService.GetFileByID(ctx, upload.ParentID)
+	if err != nil {
+		return err
+	}
+
+	var counted *file_model.WeblensFileImpl
+
+	if upload.FileID != "" {
+		counted, err = appCtx.FileService.GetFileByID(ctx, upload.FileID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return file_service.ReserveQuota(ctx, parent, upload.ID.Hex(), upload.Size, counted)
+}
+
+// WriteChunk appends the bytes read from r to upload, which must currently have received exactly offset bytes.
+// The new offset is persisted even if reading r fails part way through, so the client can resume from
+// wherever the data stopped. Once the final byte is written the upload is finished, and the new file is returned.
+func WriteChunk(ctx context.Context, upload *tus_model.Upload, offset int64, r io.Reader) (*file_model.WeblensFileImpl, error) {
+	if !lockUpload(upload) {
+		return nil, wlerrors.WithStack(ErrUploadLocked)
+	}
+	defer unlockUpload(upload)
+
+	// Another request may have written to the upload between when it was loaded and when the lock was taken
+	current, err := tus_model.GetUploadByID(ctx, upload.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	*upload = *current
+
+	if offset != upload.Offset {
+		return nil, wlerrors.WithStack(ErrOffsetMismatch)
+	}
+
+	err = reserveQuota(ctx, upload)
+	if err != nil {
+		return nil, err
+	}
+
+	partial, err := os.OpenFile(PartialPath(upload), os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, wlerrors.WithStack(err)
+	}
+
+	_, err = partial.Seek(offset, io.SeekStart)
+	if err != nil {
+		_ = partial.Close()
+
+		return nil, wlerrors.WithStack(err)
+	}
+
+	// Read one byte past the end of the upload, so a client sending too much data can be detected
+	written, copyErr := io.Copy(partial, io.LimitReader(r, upload.Size-offset+1))
+
+	closeErr := partial.Close()
+
+	if offset+written > upload.Size {
+		// Drop the extra byte, so the partial file is still valid if the client retries correctly
+		_ = os.Truncate(PartialPath(upload), upload.Size)
+
+		return nil, wlerrors.WithStack(ErrUploadTooLarge)
+	}
+
+	// The client may have disconnected part way through, which cancels the request. The bytes that did make it
+	// must still be recorded so the upload can be resumed from there.
+	err = upload.SetOffset(context.WithoutCancel(ctx), offset+written)
+	if err != nil {
+		return nil, err
+	}
+
+	if copyErr != nil {
+		return nil, wlerrors.WithStack(copyErr)
+	} else if closeErr != nil {
+		return nil, wlerrors.WithStack(closeErr)
+	}
+
+	if !upload.IsComplete() {
+		return nil, nil
+	}
+
+	return FinishUpload(ctx, upload)
+}
+
+// FinishUpload moves the completed contents of upload into a new file in its destination folder, or over the content of
+// the existing file it was created for. The file is changed through the file service and recorded in the journal the
+// same way as any other upload.
+func FinishUpload(ctx context.Context, upload *tus_model.Upload) (*file_model.WeblensFileImpl, error) {
+	appCtx, ok := context_service.FromContext(ctx)
+	if !ok {
+		return nil, wlerrors.WithStack(context_service.ErrNoContext)
+	}
+
+	if upload.FileID != "" {
+		return finishRevisionUpload(ctx, appCtx, upload)
+	}
+
+	parent, err := appCtx.FileService.GetFileByID(ctx, upload.ParentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if child, _ := parent.GetChild(upload.FileName); child != nil {
+		return nil, wlerrors.WithStack(ErrFileExists)
+	}
+
+	ctx = history.WithFileEvent(ctx)
+
+	// Creating the file checks the quota again, and must not count the space reserved for this upload twice
+	file_service.ReleaseQuota(upload.ID.Hex())
+
+	// The partial file is only taken if the new file is created, so a failed finish can be retried
+	newFile, err := appCtx.FileService.CreateFileFromPath(ctx, parent, upload.FileName, PartialPath(upload))
+	if err != nil {
+		return nil, err
+	}
+
+	err = tus_model.DeleteUpload(ctx, upload.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	dispatchFileJobs(appCtx, newFile)
+
+	return newFile, nil
+}
+
+// finishRevisionUpload makes the completed contents of upload the new content of the existing file it was created for.
+// The content being replaced is kept as a past revision of the file.
+func finishRevisionUpload(ctx context.Context, appCtx context_service.AppContext, upload *tus_model.Upload) (*file_model.WeblensFileImpl, error) {
+	file, err := appCtx.FileService.GetFileByID(ctx, upload.FileID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = history.WithFileEvent(ctx)
+
+	// Replacing the content checks the quota again, and must not count the space reserved for this upload twice
+	file_service.ReleaseQuota(upload.ID.Hex())
+
+	err = appCtx.FileService.ReplaceFileContent(ctx, file, PartialPath(upload))
+	if err != nil {
+		return nil, err
+	}
+
+	err = tus_model.DeleteUpload(ctx, upload.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	dispatchFileJobs(appCtx, file)
+
+	return file, nil
+}
+
+// dispatchFileJobs starts indexing the media of file, or extracting and embedding its text, if its type supports it.
+func dispatchFileJobs(appCtx context_service.AppContext, file *file_model.WeblensFileImpl) {
+	ext := file.GetPortablePath().Ext()
+
+	if media_model.ParseExtension(ext).Displayable {
+		if _, err := appCtx.DispatchJob(job.IndexFileTask, job.IndexMeta{File: file}, nil); err != nil {
+			appCtx.Log().Error().Stack().Err(err).Msgf("Failed to dispatch index task for [%s]", file.GetPortablePath())
+		}
+	} else if media_model.EmbedEligible(ext) && !embed.Default().ServiceUnavailable() {
+		if _, err := appCtx.DispatchJob(job.ExtractAndEmbedTask, job.ExtractAndEmbedMeta{File: file}, nil); err != nil {
+			appCtx.Log().Warn().Err(err).Msgf("Failed to dispatch ExtractAndEmbedTask for %s", file.GetPortablePath())
+		}
+	}
+}
+
+// TerminateUpload abandons upload, removing its record and any bytes received so far.
+func TerminateUpload(ctx context.Context, upload *tus_model.Upload) error {
+	if !lockUpload(upload) {
+		return wlerrors.WithStack(ErrUploadLocked)
+	}
+	defer unlockUpload(upload)
+
+	err := os.Remove(PartialPath(upload))
+	if err != nil && !os.IsNotExist(err) {
+		return wlerrors.WithStack(err)
+	}
+
+	file_service.ReleaseQuota(upload.ID.Hex())
+
+	return tus_model.DeleteUpload(ctx, upload.ID)
+}
+
+func lockUpload(upload *tus_model.Upload) bool {
+	activeUploadsMu.Lock()
+	defer activeUploadsMu.Unlock()
+
+	if _, ok := activeUploads[upload.ID.Hex()]; ok {
+		return false
+	}
+
+	activeUploads[upload.ID.Hex()] = struct{}{}
+
+	return true
+}
+
+func unlockUpload(upload *tus_model.Upload) {
+	activeUploadsMu.Lock()
+	defer activeUploadsMu.Unlock()
+
+	delete(activeUploads, upload.ID.Hex())
+}
@@ -0,0 +1,79 @@
+package tus_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethanrous/weblens/models/db"
+	file_model "github.com/ethanrous/weblens/models/file"
+	tus_model "github.com/ethanrous/weblens/models/tus"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	ctxservice "github.com/ethanrous/weblens/services/ctxservice"
+	file_service "github.com/ethanrous/weblens/services/file"
+	"github.com/ethanrous/weblens/services/tus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadQuota(t *testing.T) {
+	dbCtx := db.SetupTestDB(t, tus_model.UploadCollectionKey)
+	ctx := file_service.SetupTestFileService(t, dbCtx, "tususer", 100)
+
+	appCtx, ok := ctxservice.FromContext(ctx)
+	require.True(t, ok)
+
+	home, err := appCtx.FileService.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("tususer", true))
+	require.NoError(t, err)
+
+	t.Run("refuses an upload larger than the quota", func(t *testing.T) {
+		_, err := tus.CreateUpload(ctx, "tususer", "", home, "too_big.bin", 101, nil)
+		assert.True(t, wlerrors.Is(err, file_service.ErrQuotaExceeded))
+	})
+
+	t.Run("counts uploads still in progress", func(t *testing.T) {
+		first, err := tus.CreateUpload(ctx, "tususer", "", home, "first.bin", 60, nil)
+		require.NoError(t, err)
+
+		_, err = tus.CreateUpload(ctx, "tususer", "", home, "second.bin", 60, nil)
+		assert.True(t, wlerrors.Is(err, file_service.ErrQuotaExceeded))
+
+		// Other writes are checked against the space the upload holds, too
+		err = file_service.CheckQuota(ctx, home, 60)
+		assert.True(t, wlerrors.Is(err, file_service.ErrQuotaExceeded))
+
+		require.NoError(t, tus.TerminateUpload(ctx, first))
+
+		second, err := tus.CreateUpload(ctx, "tususer", "", home, "second.bin", 60, nil)
+		require.NoError(t, err)
+		require.NoError(t, tus.TerminateUpload(ctx, second))
+	})
+
+	t.Run("releases the space of a finished upload", func(t *testing.T) {
+		upload, err := tus.CreateUpload(ctx, "tususer", "", home, "done.bin", 40, nil)
+		require.NoError(t, err)
+
+		done, err := tus.WriteChunk(ctx, upload, 0, bytes.NewReader(make([]byte, 40)))
+		require.NoError(t, err)
+		require.NotNil(t, done)
+		assert.Equal(t, int64(40), done.Size())
+
+		// The finished file is counted once, as part of the home folder
+		require.NoError(t, file_service.CheckQuota(ctx, home, 60))
+
+		err = file_service.CheckQuota(ctx, home, 61)
+		assert.True(t, wlerrors.Is(err, file_service.ErrQuotaExceeded))
+	})
+
+	t.Run("refuses a revision that grows past the quota", func(t *testing.T) {
+		existing, err := home.GetChild("done.bin")
+		require.NoError(t, err)
+
+		_, err = tus.CreateRevisionUpload(ctx, "tususer", "", existing, 101, nil)
+		assert.True(t, wlerrors.Is(err, file_service.ErrQuotaExceeded))
+
+		// Only the growth of the file is charged, as the old content moves to the restore tree
+		upload, err := tus.CreateRevisionUpload(ctx, "tususer", "", existing, 100, nil)
+		require.NoError(t, err)
+		require.NoError(t, tus.TerminateUpload(ctx, upload))
+	})
+}
@@ -1,104 +1,573 @@
 // Package services provides WebDAV filesystem implementation for Weblens.
 package services
 
-// import "golang.org/x/net/webdav"
-
-// var _ webdav.FileSystem = (*WebdavFs)(nil)
-
-// WebdavFs is a placeholder type for WebDAV filesystem functionality.
-type WebdavFs struct{}
-
-// func (w WebdavFs) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
-// 	unescapeName, err := url.QueryUnescape(name)
-// 	if err != nil {
-// 		return err
-// 	}
-//
-// 	parent, err := w.WeblensFs.PathToFile(filepath.Dir(unescapeName))
-// 	if err != nil {
-// 		return err
-// 	}
-//
-// 	// TODO: add event
-// 	_, err = w.WeblensFs.CreateFile(parent, filepath.Base(unescapeName), nil)
-// 	if err != nil {
-// 		return err
-// 	}
-//
-// 	return nil
-// }
-//
-// func (w WebdavFs) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
-// 	unescapeName, err := url.QueryUnescape(name)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-//
-// 	// filename := filepath.Base(unescapeName)
-// 	// if strings.HasPrefix(filename, "._") {
-// 	// 	filename = filename[2:]
-// 	// 	unescapeName = filepath.Dir(unescapeName) + "/" + filename
-// 	// }
-// 	// if unescapeName == "." {
-// 	// 	unescapeName = "/"
-// 	// }
-//
-// 	f, err := w.WeblensFs.PathToFile(unescapeName)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	return f, nil
-// }
-//
-// func (w WebdavFs) RemoveAll(ctx context.Context, name string) error {
-//
-// 	panic("implement me")
-// }
-//
-// func (w WebdavFs) Rename(ctx context.Context, oldName, newName string) error {
-// 	unescapeOldName, err := url.QueryUnescape(oldName)
-// 	if err != nil {
-// 		return err
-// 	}
-// 	unescapeNewName, err := url.QueryUnescape(newName)
-// 	if err != nil {
-// 		return err
-// 	}
-//
-// 	oldFile, err := w.WeblensFs.PathToFile(unescapeOldName)
-// 	if err != nil {
-// 		return err
-// 	}
-//
-// 	newParent, err := w.WeblensFs.PathToFile(filepath.Dir(unescapeNewName))
-// 	if err != nil {
-// 		return err
-// 	}
-//
-// 	err = w.WeblensFs.MoveFiles([]*fileTree.WeblensFileImpl{oldFile}, newParent, "USERS", w.Caster)
-// 	if err != nil {
-// 		return err
-// 	}
-//
-// 	return nil
-// }
-//
-// func (w WebdavFs) Stat(ctx context.Context, name string) (os.FileInfo, error) {
-// 	unescapeName, err := url.QueryUnescape(name)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-//
-// 	filename := filepath.Base(unescapeName)
-// 	if strings.HasPrefix(filename, "._") {
-// 		filename = filename[2:]
-// 		unescapeName = filepath.Dir(unescapeName) + "/" + filename
-// 	}
-//
-// 	f, err := w.WeblensFs.PathToFile(unescapeName)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-//
-// 	return f, nil
-// }
+import (
+	"context"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	file_model "github.com/ethanrous/weblens/models/file"
+	"github.com/ethanrous/weblens/models/history"
+	"github.com/ethanrous/weblens/models/job"
+	media_model "github.com/ethanrous/weblens/models/media"
+	share_model "github.com/ethanrous/weblens/models/share"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/services/auth"
+	context_service "github.com/ethanrous/weblens/services/ctxservice"
+	"github.com/ethanrous/weblens/services/embed"
+	file_service "github.com/ethanrous/weblens/services/file"
+	"golang.org/x/net/webdav"
+)
+
+var _ webdav.FileSystem = (*WebdavFs)(nil)
+
+var _ webdav.File = (*webdavFile)(nil)
+
+// ErrWebdavRootImmutable is returned when a WebDAV client attempts to remove or rename the root of the mount.
+var ErrWebdavRootImmutable = wlerrors.New("cannot modify the root of a webdav mount")
+
+// ErrWebdavWriteIncomplete is returned when a file written over WebDAV is closed before all of its content arrived.
+var ErrWebdavWriteIncomplete = wlerrors.New("webdav write did not receive all of its content")
+
+// WebdavFs exposes a subtree of the Weblens file tree over WebDAV. Every change is made through the
+// file service, so it is journaled and broadcast to clients like any change made in the web UI, and
+// every access is checked against the requester and, when mounted through a share, that share's permissions.
+type WebdavFs struct {
+	ctx  context_service.RequestContext
+	root *file_model.WeblensFileImpl
+}
+
+// NewWebdavFs creates a WebDAV filesystem rooted at root, acting on behalf of the requester (and share, if any) in ctx.
+func NewWebdavFs(ctx context_service.RequestContext, root *file_model.WeblensFileImpl) *WebdavFs {
+	return &WebdavFs{ctx: ctx, root: root}
+}
+
+// Mkdir creates a new folder at name.
+func (w *WebdavFs) Mkdir(_ context.Context, name string, _ os.FileMode) error {
+	if _, err := w.resolve(name); err == nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+
+	parent, err := w.resolveParent("mkdir", name)
+	if err != nil {
+		return err
+	}
+
+	if err := w.checkAccess("mkdir", name, parent, share_model.SharePermissionEdit); err != nil {
+		return err
+	}
+
+	_, err = w.ctx.FileService.CreateFolder(w.ctx, parent, path.Base(cleanName(name)))
+	if err != nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+
+	return nil
+}
+
+// OpenFile opens the file at name for reading, or for writing if flag requests it. Writes go to a temporary file,
+// which only becomes the content at name once the file is closed after a successful write. If a file already exists at
+// name, the new content is journaled as a revision of it, keeping its ID and its previous content in the restore tree.
+func (w *WebdavFs) OpenFile(_ context.Context, name string, flag int, _ os.FileMode) (webdav.File, error) {
+	f, err := w.resolve(name)
+
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) == 0 {
+		if err != nil {
+			return nil, err
+		}
+
+		return w.openForRead(name, f)
+	}
+
+	if err == nil {
+		if flag&os.O_EXCL != 0 {
+			return nil, &os.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+		}
+
+		if f.IsDir() {
+			return nil, &os.PathError{Op: "open", Path: name, Err: file_model.ErrDirectoryNotAllowed}
+		}
+	} else if flag&os.O_CREATE == 0 {
+		return nil, err
+	}
+
+	parent, err := w.resolveParent("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.checkAccess("open", name, parent, share_model.SharePermissionEdit); err != nil {
+		return nil, err
+	}
+
+	if f != nil {
+		if err := w.checkAccess("open", name, f, share_model.SharePermissionEdit, share_model.SharePermissionDelete); err != nil {
+			return nil, err
+		}
+	}
+
+	// The size of a PUT is known up front, so a write that can not fit is refused before anything is replaced. Writes
+	// are checked against the quota again as they arrive, as the length is not always sent.
+	if w.ctx.Req.Method == http.MethodPut && w.ctx.Req.ContentLength > 0 {
+		growth := w.ctx.Req.ContentLength
+		if f != nil {
+			growth -= f.Size()
+		}
+
+		if err := file_service.CheckQuota(w.ctx, parent, growth); err != nil {
+			return nil, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+
+	// Kept out of the users tree while it is written, so a write that never finishes leaves nothing behind in it
+	tmp, err := os.CreateTemp(file_model.UploadsDirPath.ToAbsolute(), "webdav-*")
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	expected := int64(-1)
+	if w.ctx.Req.Method == http.MethodPut {
+		expected = w.ctx.Req.ContentLength
+	}
+
+	filename := path.Base(cleanName(name))
+
+	return &webdavFile{
+		// Stands in for the file until it is committed, so writes are never seen on the file that is being replaced
+		file:     file_model.NewWeblensFile(file_model.NewFileOptions{Path: parent.GetPortablePath().Child(filename, false)}),
+		osFile:   tmp,
+		ctx:      w.ctx,
+		writable: true,
+		parent:   parent,
+		existing: f,
+		expected: expected,
+	}, nil
+}
+
+// RemoveAll deletes the file or folder at name. Deleted content is kept in the restore tree.
+func (w *WebdavFs) RemoveAll(_ context.Context, name string) error {
+	f, err := w.resolve(name)
+	if err != nil {
+		return err
+	}
+
+	if f == w.root {
+		return &os.PathError{Op: "remove", Path: name, Err: ErrWebdavRootImmutable}
+	}
+
+	if err := w.checkAccess("remove", name, f, share_model.SharePermissionDelete); err != nil {
+		return err
+	}
+
+	err = w.ctx.FileService.DeleteFiles(w.ctx, f)
+	if err != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: err}
+	}
+
+	return nil
+}
+
+// Rename moves the file at oldName to newName, renaming it if the filename changes.
+func (w *WebdavFs) Rename(_ context.Context, oldName, newName string) error {
+	f, err := w.resolve(oldName)
+	if err != nil {
+		return err
+	}
+
+	if f == w.root {
+		return &os.PathError{Op: "rename", Path: oldName, Err: ErrWebdavRootImmutable}
+	}
+
+	if _, err := w.resolve(newName); err == nil {
+		return &os.PathError{Op: "rename", Path: newName, Err: fs.ErrExist}
+	}
+
+	newParent, err := w.resolveParent("rename", newName)
+	if err != nil {
+		return err
+	}
+
+	if err := w.checkAccess("rename", oldName, f, share_model.SharePermissionEdit); err != nil {
+		return err
+	}
+
+	if err := w.checkAccess("rename", newName, newParent, share_model.SharePermissionEdit); err != nil {
+		return err
+	}
+
+	newFilename := path.Base(cleanName(newName))
+
+	// If the destination folder already has a child with the file's current name, the move gives
+	// the file a unique name, which is then replaced by the requested one below.
+	if f.GetParent() != newParent {
+		err = w.ctx.FileService.MoveFiles(w.ctx, []*file_model.WeblensFileImpl{f}, newParent)
+		if err != nil {
+			return &os.PathError{Op: "rename", Path: oldName, Err: err}
+		}
+	}
+
+	if f.GetPortablePath().Filename() != newFilename {
+		err = w.ctx.FileService.RenameFile(w.ctx, f, newFilename)
+		if err != nil {
+			return &os.PathError{Op: "rename", Path: oldName, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// Stat returns the file info for the file at name.
+func (w *WebdavFs) Stat(_ context.Context, name string) (os.FileInfo, error) {
+	f, err := w.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.checkAccess("stat", name, f, share_model.SharePermissionView); err != nil {
+		return nil, err
+	}
+
+	return webdavFileInfo{f}, nil
+}
+
+func (w *WebdavFs) openForRead(name string, f *file_model.WeblensFileImpl) (webdav.File, error) {
+	if f.IsDir() {
+		if err := w.checkAccess("open", name, f, share_model.SharePermissionView); err != nil {
+			return nil, err
+		}
+
+		if _, err := w.ctx.FileService.GetChildren(w.ctx, f); err != nil {
+			return nil, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+
+		return &webdavFile{file: f, ctx: w.ctx}, nil
+	}
+
+	// Directory listings never open regular files (see webdavFileInfo.ContentType), so reading one means its content is being downloaded
+	if err := w.checkAccess("open", name, f, share_model.SharePermissionView, share_model.SharePermissionDownload); err != nil {
+		return nil, err
+	}
+
+	osFile, err := os.Open(f.GetPortablePath().ToAbsolute())
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	return &webdavFile{file: f, osFile: osFile, ctx: w.ctx}, nil
+}
+
+// resolve walks the tree from the root of the mount to the file at name, loading folders as needed.
+func (w *WebdavFs) resolve(name string) (*file_model.WeblensFileImpl, error) {
+	f := w.root
+
+	for part := range strings.SplitSeq(cleanName(name), "/") {
+		if part == "" {
+			continue
+		}
+
+		if !f.IsDir() {
+			return nil, &os.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+		}
+
+		if _, err := w.ctx.FileService.GetChildren(w.ctx, f); err != nil {
+			return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+		}
+
+		child, err := f.GetChild(part)
+		if err != nil {
+			return nil, &os.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+		}
+
+		f = child
+	}
+
+	return f, nil
+}
+
+func (w *WebdavFs) resolveParent(op, name string) (*file_model.WeblensFileImpl, error) {
+	parent, err := w.resolve(path.Dir(cleanName(name)))
+	if err != nil {
+		return nil, err
+	}
+
+	if !parent.IsDir() {
+		return nil, &os.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+
+	return parent, nil
+}
+
+func (w *WebdavFs) checkAccess(op, name string, f *file_model.WeblensFileImpl, perms ...share_model.Permission) error {
+	if _, err := auth.CanUserAccessFile(w.ctx, w.ctx.Requester, f, w.ctx.Share, perms...); err != nil {
+		w.ctx.Log().Debug().Err(err).Msgf("Denying webdav %s on [%s]", op, name)
+
+		return &os.PathError{Op: op, Path: name, Err: fs.ErrPermission}
+	}
+
+	return nil
+}
+
+func cleanName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// webdavFileInfo adds the optional WebDAV property interfaces to a Weblens file, so directory
+// listings do not need to open and sniff every file.
+type webdavFileInfo struct {
+	*file_model.WeblensFileImpl
+}
+
+// Mode returns the file mode bits, which WebDAV uses to tell folders apart from regular files.
+func (fi webdavFileInfo) Mode() os.FileMode {
+	if fi.IsDir() {
+		return fs.ModeDir | 0o755
+	}
+
+	return 0o644
+}
+
+// ContentType returns the MIME type of the file based on its extension.
+func (fi webdavFileInfo) ContentType(_ context.Context) (string, error) {
+	if ctype := mime.TypeByExtension(path.Ext(fi.Name())); ctype != "" {
+		return ctype, nil
+	}
+
+	return "application/octet-stream", nil
+}
+
+// ETag returns the content ID of the file, which changes whenever its content does.
+func (fi webdavFileInfo) ETag(_ context.Context) (string, error) {
+	if fi.IsDir() || fi.GetContentID() == "" {
+		return "", webdav.ErrNotImplemented
+	}
+
+	return `"` + fi.GetContentID() + `"`, nil
+}
+
+// webdavFile is an open handle to a Weblens file. Regular files are backed by the real file on disk,
+// folders list their children from the file tree.
+type webdavFile struct {
+	file   *file_model.WeblensFileImpl
+	osFile *os.File
+	ctx    context_service.RequestContext
+
+	dirOffset int
+	// reserved is how large the file may grow before more of the quota of its owner must be reserved
+	reserved int64
+	writable bool
+	written  bool
+
+	// parent is the folder a file opened for writing is committed to, and existing the file it replaces, if any
+	parent   *file_model.WeblensFileImpl
+	existing *file_model.WeblensFileImpl
+	// expected is the length of the content being written, or -1 if it was not sent
+	expected int64
+	// failed is set once a write has failed, so the file is discarded rather than committed when it is closed
+	failed bool
+}
+
+// webdavQuotaStep is how much more of the quota is reserved at once as a file written over WebDAV grows, so the quota
+// is not checked on every write.
+const webdavQuotaStep = 8 << 20
+
+func (f *webdavFile) Read(p []byte) (int, error) {
+	if f.osFile == nil {
+		return 0, &os.PathError{Op: "read", Path: f.file.Name(), Err: file_model.ErrDirectoryNotAllowed}
+	}
+
+	return f.osFile.Read(p)
+}
+
+func (f *webdavFile) Seek(offset int64, whence int) (int64, error) {
+	if f.osFile == nil {
+		if offset == 0 && whence == io.SeekStart {
+			f.dirOffset = 0
+
+			return 0, nil
+		}
+
+		return 0, &os.PathError{Op: "seek", Path: f.file.Name(), Err: file_model.ErrDirectoryNotAllowed}
+	}
+
+	return f.osFile.Seek(offset, whence)
+}
+
+func (f *webdavFile) Write(p []byte) (int, error) {
+	if !f.writable {
+		return 0, &os.PathError{Op: "write", Path: f.file.Name(), Err: fs.ErrPermission}
+	}
+
+	offset, err := f.osFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+
+	if end := offset + int64(len(p)); end > f.reserved {
+		err = f.reserveQuota(end)
+		if err != nil {
+			f.failed = true
+
+			return 0, &os.PathError{Op: "write", Path: f.file.Name(), Err: err}
+		}
+	}
+
+	f.written = true
+
+	n, err := f.osFile.Write(p)
+	if err != nil {
+		f.failed = true
+	}
+
+	return n, err
+}
+
+// reserveQuota reserves enough of the quota of the owner of the file for it to grow to size bytes, so files being
+// written concurrently can not together go past the quota. Some room past size is reserved when it is available.
+func (f *webdavFile) reserveQuota(size int64) error {
+	ahead := (size/webdavQuotaStep + 1) * webdavQuotaStep
+
+	err := file_service.ReserveQuota(f.ctx, f.parent, f.osFile.Name(), ahead, f.existing)
+	if err == nil {
+		f.reserved = ahead
+
+		return nil
+	} else if !wlerrors.Is(err, file_service.ErrQuotaExceeded) {
+		return err
+	}
+
+	err = file_service.ReserveQuota(f.ctx, f.parent, f.osFile.Name(), size, f.existing)
+	if err != nil {
+		return err
+	}
+
+	f.reserved = size
+
+	return nil
+}
+
+// Readdir returns the children of the folder, count at a time, following the semantics of os.File.Readdir.
+func (f *webdavFile) Readdir(count int) ([]fs.FileInfo, error) {
+	if !f.file.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: f.file.Name(), Err: file_model.ErrDirectoryRequired}
+	}
+
+	children := f.file.GetChildren()
+	if f.dirOffset >= len(children) {
+		if count > 0 {
+			return nil, io.EOF
+		}
+
+		return []fs.FileInfo{}, nil
+	}
+
+	children = children[f.dirOffset:]
+	if count > 0 && count < len(children) {
+		children = children[:count]
+	}
+
+	f.dirOffset += len(children)
+
+	infos := make([]fs.FileInfo, 0, len(children))
+	for _, child := range children {
+		infos = append(infos, webdavFileInfo{child})
+	}
+
+	return infos, nil
+}
+
+func (f *webdavFile) Stat() (fs.FileInfo, error) {
+	if f.writable {
+		stat, err := f.osFile.Stat()
+		if err != nil {
+			return nil, err
+		}
+
+		f.file.SetSize(stat.Size())
+	}
+
+	return webdavFileInfo{f.file}, nil
+}
+
+// Close releases the underlying file. For files opened for writing, this commits the written content: it becomes the
+// new content of the file being replaced, or a new file, and is journaled and broadcast to clients. If the write failed
+// or is incomplete, the written content is discarded and nothing is changed.
+func (f *webdavFile) Close() error {
+	if f.osFile == nil {
+		return nil
+	}
+
+	err := f.osFile.Close()
+	if !f.writable {
+		return err
+	}
+
+	// Committing checks the quota again, and must not count the space reserved for this file twice
+	file_service.ReleaseQuota(f.osFile.Name())
+
+	if err == nil {
+		err = f.commit()
+	}
+
+	if err != nil {
+		if rmErr := os.Remove(f.osFile.Name()); rmErr != nil && !os.IsNotExist(rmErr) {
+			f.ctx.Log().Warn().Err(rmErr).Msgf("Failed to remove incomplete webdav write [%s]", f.osFile.Name())
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (f *webdavFile) commit() error {
+	stat, err := os.Stat(f.osFile.Name())
+	if err != nil {
+		return wlerrors.WithStack(err)
+	}
+
+	// A client that goes away part way through a write still closes the file, which must not replace the old content
+	if f.failed || f.ctx.Req.Context().Err() != nil || (f.expected >= 0 && stat.Size() != f.expected) {
+		return wlerrors.WithStack(ErrWebdavWriteIncomplete)
+	}
+
+	ctx := history.WithFileEvent(f.ctx)
+
+	if f.existing != nil {
+		err = f.ctx.FileService.ReplaceFileContent(ctx, f.existing, f.osFile.Name())
+		if err != nil {
+			return err
+		}
+
+		f.file = f.existing
+	} else {
+		newF, err := f.ctx.FileService.CreateFileFromPath(ctx, f.parent, f.file.Name(), f.osFile.Name())
+		if err != nil {
+			return err
+		}
+
+		f.file = newF
+	}
+
+	if !f.written {
+		return nil
+	}
+
+	ext := f.file.GetPortablePath().Ext()
+
+	if media_model.ParseExtension(ext).Displayable {
+		if _, err := f.ctx.DispatchJob(job.IndexFileTask, job.IndexMeta{File: f.file}, nil); err != nil {
+			f.ctx.Log().Error().Stack().Err(err).Msgf("Failed to dispatch index task for [%s]", f.file.GetPortablePath())
+		}
+	} else if media_model.EmbedEligible(ext) && !embed.Default().ServiceUnavailable() {
+		if _, err := f.ctx.DispatchJob(job.ExtractAndEmbedTask, job.ExtractAndEmbedMeta{File: f.file}, nil); err != nil {
+			f.ctx.Log().Warn().Err(err).Msgf("Failed to dispatch ExtractAndEmbedTask for %s", f.file.GetPortablePath())
+		}
+	}
+
+	return nil
+}
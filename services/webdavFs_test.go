@@ -0,0 +1,159 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/ethanrous/weblens/models/db"
+	file_model "github.com/ethanrous/weblens/models/file"
+	user_model "github.com/ethanrous/weblens/models/usermodel"
+	"github.com/ethanrous/weblens/modules/wlerrors"
+	"github.com/ethanrous/weblens/services"
+	ctxservice "github.com/ethanrous/weblens/services/ctxservice"
+	file_service "github.com/ethanrous/weblens/services/file"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const webdavWriteFlags = os.O_RDWR | os.O_CREATE | os.O_TRUNC
+
+func newWebdavTestFs(t *testing.T, ctx context.Context, req *http.Request) *services.WebdavFs {
+	t.Helper()
+
+	appCtx, ok := ctxservice.FromContext(ctx)
+	require.True(t, ok)
+
+	requester, err := user_model.GetUserByUsername(ctx, "davuser")
+	require.NoError(t, err)
+
+	home, err := appCtx.FileService.GetFileByFilepath(ctx, file_model.UsersRootPath.Child("davuser", true))
+	require.NoError(t, err)
+
+	reqCtx := ctxservice.RequestContext{
+		AppContext: appCtx,
+		Req:        req,
+		ReqCtx:     req.Context(),
+		Requester:  requester,
+		IsLoggedIn: true,
+	}
+
+	return services.NewWebdavFs(reqCtx, home)
+}
+
+func TestWebdavFs_Quota(t *testing.T) {
+	dbCtx := db.SetupTestDB(t, user_model.UserCollectionKey)
+	ctx := file_service.SetupTestFileService(t, dbCtx, "davuser", 100)
+
+	t.Run("refuses a put larger than the quota", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/too_big.bin", bytes.NewReader(make([]byte, 101)))
+		fs := newWebdavTestFs(t, ctx, req)
+
+		_, err := fs.OpenFile(ctx, "/too_big.bin", webdavWriteFlags, 0)
+		assert.True(t, wlerrors.Is(err, file_service.ErrQuotaExceeded))
+
+		_, err = fs.Stat(ctx, "/too_big.bin")
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("counts writes still in progress", func(t *testing.T) {
+		// Without a content length, the quota can only be checked as the content arrives
+		req := httptest.NewRequest(http.MethodPut, "/", nil)
+		req.ContentLength = -1
+		fs := newWebdavTestFs(t, ctx, req)
+
+		first, err := fs.OpenFile(ctx, "/first.bin", webdavWriteFlags, 0)
+		require.NoError(t, err)
+
+		second, err := fs.OpenFile(ctx, "/second.bin", webdavWriteFlags, 0)
+		require.NoError(t, err)
+
+		_, err = first.Write(make([]byte, 60))
+		require.NoError(t, err)
+
+		_, err = second.Write(make([]byte, 60))
+		assert.True(t, wlerrors.Is(err, file_service.ErrQuotaExceeded))
+
+		require.NoError(t, first.Close())
+
+		// A failed write is never committed, so the space it asked for is not taken
+		assert.True(t, wlerrors.Is(second.Close(), services.ErrWebdavWriteIncomplete))
+
+		_, err = fs.Stat(ctx, "/second.bin")
+		assert.True(t, os.IsNotExist(err))
+
+		third, err := fs.OpenFile(ctx, "/third.bin", webdavWriteFlags, 0)
+		require.NoError(t, err)
+
+		_, err = third.Write(make([]byte, 40))
+		require.NoError(t, err)
+		require.NoError(t, third.Close())
+
+		// Both written files are now counted as part of the home folder, and fill the quota
+		home, err := fs.Stat(ctx, "/")
+		require.NoError(t, err)
+		assert.Equal(t, int64(100), home.Size())
+	})
+}
+
+func TestWebdavFs_IncompleteWrite(t *testing.T) {
+	dbCtx := db.SetupTestDB(t, user_model.UserCollectionKey)
+	ctx := file_service.SetupTestFileService(t, dbCtx, "davuser", 1000)
+
+	req := httptest.NewRequest(http.MethodPut, "/", nil)
+	req.ContentLength = -1
+	fs := newWebdavTestFs(t, ctx, req)
+
+	f, err := fs.OpenFile(ctx, "/doc.txt", webdavWriteFlags, 0)
+	require.NoError(t, err)
+
+	_, err = f.Write([]byte("original"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	appCtx, ok := ctxservice.FromContext(ctx)
+	require.True(t, ok)
+
+	docPath := file_model.UsersRootPath.Child("davuser", true).Child("doc.txt", false)
+
+	before, err := appCtx.FileService.GetFileByFilepath(ctx, docPath)
+	require.NoError(t, err)
+
+	beforeSize := before.Size()
+
+	t.Run("keeps the existing file when the content does not all arrive", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/doc.txt", nil)
+		req.ContentLength = 100
+		fs := newWebdavTestFs(t, ctx, req)
+
+		f, err := fs.OpenFile(ctx, "/doc.txt", webdavWriteFlags, 0)
+		require.NoError(t, err)
+
+		_, err = f.Write([]byte("partial"))
+		require.NoError(t, err)
+
+		assert.True(t, wlerrors.Is(f.Close(), services.ErrWebdavWriteIncomplete))
+
+		after, err := appCtx.FileService.GetFileByFilepath(ctx, docPath)
+		require.NoError(t, err)
+		assert.Equal(t, beforeSize, after.Size())
+		assert.Equal(t, before.ID(), after.ID())
+	})
+
+	t.Run("replaces the content of the existing file in place", func(t *testing.T) {
+		f, err := fs.OpenFile(ctx, "/doc.txt", webdavWriteFlags, 0)
+		require.NoError(t, err)
+
+		_, err = f.Write([]byte("replaced content"))
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		after, err := appCtx.FileService.GetFileByFilepath(ctx, docPath)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len("replaced content")), after.Size())
+		assert.Equal(t, before.ID(), after.ID())
+	})
+}